@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/jobs"
+	"github.com/spf13/cobra"
+)
+
+var jobCmd = &cobra.Command{
+	Use:   "job",
+	Short: "Inspect and requeue background jobs",
+}
+
+var jobListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List jobs by status, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		status, _ := cmd.Flags().GetString("status")
+		limit, _ := cmd.Flags().GetInt32("limit")
+		if limit < 1 {
+			return fmt.Errorf("invalid limit %d", limit)
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		list, err := store.ListJobsByStatus(cmd.Context(), db.ListJobsByStatusParams{
+			Status: status,
+			ID:     math.MaxInt64,
+			Limit:  limit,
+		})
+		if err != nil {
+			return err
+		}
+		views := make([]jobView, len(list))
+		for i, job := range list {
+			views[i] = newJobView(job)
+		}
+		return printJSON(cmd.OutOrStdout(), views)
+	},
+}
+
+var jobRetryCmd = &cobra.Command{
+	Use:   "retry JOB_ID",
+	Short: "Requeue a dead job, with its attempts reset",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid job id %q", args[0])
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		job, err := store.RequeueJob(cmd.Context(), db.RequeueJobParams{ID: id, RunAt: time.Now().UTC()})
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return fmt.Errorf("no dead job with id %d", id)
+		}
+		if err != nil {
+			return err
+		}
+		return printJSON(cmd.OutOrStdout(), newJobView(job))
+	},
+}
+
+// jobView is a job as the commands print it
+type jobView struct {
+	ID          int64           `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	UniqueKey   string          `json:"unique_key,omitempty"`
+	Status      string          `json:"status"`
+	Attempts    int32           `json:"attempts"`
+	MaxAttempts int32           `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LastError   string          `json:"last_error,omitempty"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func newJobView(job db.Job) jobView {
+	v := jobView{
+		ID:          job.ID,
+		Kind:        job.Kind,
+		Payload:     job.Payload,
+		UniqueKey:   job.UniqueKey.String,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt,
+		LastError:   job.LastError,
+		CreatedAt:   job.CreatedAt,
+	}
+	if job.FinishedAt.Valid {
+		v.FinishedAt = &job.FinishedAt.Time
+	}
+	return v
+}
+
+func init() {
+	jobListCmd.Flags().String("status", jobs.StatusDead, "list the jobs with this status: pending, running, succeeded or dead")
+	jobListCmd.Flags().Int32("limit", 20, "list at most this many jobs")
+
+	jobCmd.AddCommand(jobListCmd, jobRetryCmd)
+	rootCmd.AddCommand(jobCmd)
+}
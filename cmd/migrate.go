@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/PenginAction/go-BulletinBoard/config"
+	"github.com/PenginAction/go-BulletinBoard/db/migration"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply or inspect database schema migrations",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	Args:  cobra.NoArgs,
+	RunE: withMigrator(func(mg *migration.Migrator, args []string) error {
+		return mg.Up()
+	}),
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [N]",
+	Short: "Roll back the last N migrations (default 1)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: withMigrator(func(mg *migration.Migrator, args []string) error {
+		steps := 1
+		if len(args) > 0 {
+			var err error
+			steps, err = strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid number of steps %q: %w", args[0], err)
+			}
+		}
+		return mg.Down(steps)
+	}),
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the current schema version",
+	Args:  cobra.NoArgs,
+	RunE: withMigrator(func(mg *migration.Migrator, args []string) error {
+		return nil
+	}),
+}
+
+var migrateForceCmd = &cobra.Command{
+	Use:   "force VERSION",
+	Short: "Mark VERSION as applied and clear the dirty flag",
+	Args:  cobra.ExactArgs(1),
+	RunE: withMigrator(func(mg *migration.Migrator, args []string) error {
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid version %q: %w", args[0], err)
+		}
+		return mg.Force(version)
+	}),
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd, migrateForceCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
+
+// withMigrator opens a Migrator for the configured database, runs fn and
+// prints the resulting schema status
+func withMigrator(fn func(mg *migration.Migrator, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		mg, err := migration.New(cfg.DBDriver, cfg.DBSource)
+		if err != nil {
+			return err
+		}
+		defer mg.Close()
+
+		if err := fn(mg, args); err != nil {
+			return err
+		}
+
+		status, err := mg.Status()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "schema version=%d latest=%d dirty=%t\n", status.Version, status.Latest, status.Dirty)
+		return nil
+	}
+}
+
+// prepareSchema runs pending migrations when AUTO_MIGRATE is set and refuses
+// to start against a schema this binary does not know about
+func prepareSchema(cfg config.Config) error {
+	if cfg.DBDriver == "memory" {
+		return nil
+	}
+
+	mg, err := migration.New(cfg.DBDriver, cfg.DBSource)
+	if err != nil {
+		return err
+	}
+	defer mg.Close()
+
+	if cfg.AutoMigrate {
+		if err := mg.Up(); err != nil {
+			return fmt.Errorf("cannot migrate db: %w", err)
+		}
+	}
+	return mg.Check()
+}
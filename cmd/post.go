@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/jobs"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/spf13/cobra"
+)
+
+var postCmd = &cobra.Command{
+	Use:   "post",
+	Short: "Manage posts",
+}
+
+var postPurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Delete all posts of a user or all posts created before a date",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		req := dto.PurgePostsRequest{}
+		req.UserStrID, _ = cmd.Flags().GetString("user")
+		before, _ := cmd.Flags().GetString("before")
+		if before != "" {
+			var err error
+			req.Before, err = parseTime(before)
+			if err != nil {
+				return err
+			}
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		if async, _ := cmd.Flags().GetBool("async"); async {
+			job, err := jobs.Enqueue(cmd.Context(), store, jobs.Job{Kind: usecase.JobPurgePosts, Payload: req})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "queued job %d\n", job.ID)
+			return nil
+		}
+		n, err := usecase.NewPostUsecase(store, usecase.JobDispatchWebhooks).PurgePosts(cmd.Context(), req)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "deleted %d posts\n", n)
+		return nil
+	},
+}
+
+func init() {
+	postPurgeCmd.Flags().String("user", "", "delete the posts of this user_str_id")
+	postPurgeCmd.Flags().String("before", "", "delete posts created before this time (RFC 3339 or YYYY-MM-DD)")
+	postPurgeCmd.Flags().Bool("async", false, "queue the purge for a worker instead of running it")
+	postPurgeCmd.MarkFlagsOneRequired("user", "before")
+	postPurgeCmd.MarkFlagsMutuallyExclusive("user", "before")
+
+	postCmd.AddCommand(postPurgeCmd)
+	rootCmd.AddCommand(postCmd)
+}
+
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, want RFC 3339 or YYYY-MM-DD", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,60 @@
+// Package cmd implements the command line interface of the server binary:
+// serving the API plus the admin tasks ops would otherwise do in raw SQL.
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/PenginAction/go-BulletinBoard/config"
+	"github.com/PenginAction/go-BulletinBoard/logging"
+	"github.com/spf13/cobra"
+)
+
+var configPath string
+
+var rootCmd = &cobra.Command{
+	Use:   "main",
+	Short: "Bulletin board API server and admin tools",
+	// running the binary without a subcommand keeps starting the server
+	RunE:          runServe,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", ".", "directory containing app.env")
+}
+
+// Execute runs the command selected by the command line arguments
+func Execute() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func loadConfig() (config.Config, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return cfg, fmt.Errorf("cannot load config: %w", err)
+	}
+
+	// logs go to stderr so command output on stdout stays machine readable
+	logger, err := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		return cfg, err
+	}
+	slog.SetDefault(logger)
+	return cfg, nil
+}
+
+// printJSON writes v to w as indented JSON
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
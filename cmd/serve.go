@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/config"
+	"github.com/PenginAction/go-BulletinBoard/controller"
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/db/sqlite"
+	"github.com/PenginAction/go-BulletinBoard/graph"
+	"github.com/PenginAction/go-BulletinBoard/health"
+	"github.com/PenginAction/go-BulletinBoard/metrics"
+	"github.com/PenginAction/go-BulletinBoard/pubsub"
+	"github.com/PenginAction/go-BulletinBoard/realtime"
+	"github.com/PenginAction/go-BulletinBoard/router"
+	"github.com/PenginAction/go-BulletinBoard/rpc"
+	"github.com/PenginAction/go-BulletinBoard/tracing"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	_ "github.com/lib/pq"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Start the HTTP API server",
+	Args:  cobra.NoArgs,
+	RunE:  runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := prepareSchema(cfg); err != nil {
+		return fmt.Errorf("cannot start with current db schema: %w", err)
+	}
+
+	store, conn, err := newStore(cfg)
+	if err != nil {
+		return fmt.Errorf("cannot connect to db: %w", err)
+	}
+	if conn != nil {
+		// deferred first so it runs last, after in-flight requests finished
+		defer conn.Close()
+		if err := metrics.RegisterDB(conn, cfg.DBDriver); err != nil {
+			return err
+		}
+	}
+	store = tracing.NewStore(metrics.NewStore(store), cfg.DBDriver)
+
+	shutdownTracing, err := tracing.Setup(cmd.Context(), cfg)
+	if err != nil {
+		return fmt.Errorf("cannot set up tracing: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("cannot flush traces", slog.String("error", err.Error()))
+		}
+	}()
+
+	checker := health.NewChecker()
+	if conn != nil {
+		checker.Register("database", time.Second, health.DBPing(conn))
+		checker.Register("schema", 2*time.Second, health.SchemaVersion(conn, cfg.DBDriver))
+	}
+
+	ps, closePubSub := newPubSub(cfg, conn)
+	defer closePubSub()
+	publisher, err := usecase.NewEventPublisher(ps)
+	if err != nil {
+		return fmt.Errorf("cannot subscribe to events: %w", err)
+	}
+	defer publisher.Close()
+
+	webhookUsecase := usecase.NewWebhookUsecase(store)
+	notificationUsecase := usecase.NewNotificationUsecase(store, publisher)
+	tagUsecase := usecase.NewTagUsecase(store)
+	// changes queue their webhook dispatch in the transaction making them
+	userUsecase := usecase.NewUserUsecase(store, usecase.JobDispatchWebhooks)
+	// every API changes posts through the event log and the feed, so
+	// streams wake up for all changes, those made on other instances included
+	postEvents := usecase.NewPostEventLog(usecase.NewPostUsecase(store, usecase.JobDispatchWebhooks, usecase.JobNotify), store, publisher)
+	postUsecase := usecase.NewPostFeed(postEvents, publisher)
+	userController := controller.NewUserController(userUsecase)
+	postController := controller.NewPostController(postUsecase)
+	postStreamController := controller.NewPostStreamController(postEvents, cfg.StreamHeartbeat, cfg.StreamWriteTimeout)
+	healthController := controller.NewHealthController(checker)
+	graphServer, err := graph.NewServer(userUsecase, postUsecase, graph.Limits{
+		MaxDepth:      cfg.GraphQLMaxDepth,
+		MaxComplexity: cfg.GraphQLMaxComplexity,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot build graphql schema: %w", err)
+	}
+	graphQLController := controller.NewGraphQLController(graphServer)
+	hub := realtime.NewHub(ps, postEvents, postUsecase)
+	go func() {
+		if err := hub.Run(cmd.Context()); err != nil {
+			slog.Error("realtime hub stopped", slog.String("error", err.Error()))
+		}
+	}()
+	// notifications are created by the workers of any instance
+	unsubscribeNotifications := publisher.Subscribe(hub.Notify)
+	defer unsubscribeNotifications()
+	realtimeController := controller.NewRealtimeController(hub, userUsecase, cfg.FE_URL)
+	webhookController := controller.NewWebhookController(webhookUsecase)
+	notificationController := controller.NewNotificationController(notificationUsecase)
+	tagController := controller.NewTagController(tagUsecase)
+
+	// every instance running workers runs jobs; claimed ones are leased to
+	// one of them
+	workerCtx, stopWorkers := context.WithCancel(cmd.Context())
+	defer stopWorkers()
+	workersDone := make(chan struct{})
+	go func() {
+		defer close(workersDone)
+		if !cfg.RunWorkers {
+			return
+		}
+		if err := runWorkers(workerCtx, cfg, store, webhookUsecase, postUsecase, notificationUsecase); err != nil {
+			slog.Error("workers stopped", slog.String("error", err.Error()))
+		}
+	}()
+
+	e := router.NewRouter(userController, postController, postStreamController, graphQLController, realtimeController, webhookController, notificationController, tagController, healthController, cfg)
+	e.HideBanner = true
+	e.HidePort = true
+
+	// configure e.Server itself: e.Shutdown only knows about that one
+	srv := e.Server
+	srv.Addr = cfg.ListenAddr
+	srv.ReadTimeout = cfg.ReadTimeout
+	srv.ReadHeaderTimeout = cfg.ReadHeaderTimeout
+	srv.WriteTimeout = cfg.WriteTimeout
+	srv.IdleTimeout = cfg.IdleTimeout
+	srv.MaxHeaderBytes = cfg.MaxHeaderBytes
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- e.StartServer(srv)
+	}()
+	slog.Info("server started", slog.String("addr", cfg.ListenAddr), slog.String("db_driver", cfg.DBDriver))
+
+	var grpcServer *grpc.Server
+	grpcErrc := make(chan error, 1)
+	if cfg.GRPCListenAddr != "" {
+		lis, err := net.Listen("tcp", cfg.GRPCListenAddr)
+		if err != nil {
+			return fmt.Errorf("cannot listen for grpc: %w", err)
+		}
+		grpcServer = rpc.NewServer(userUsecase, postUsecase, postUsecase, cfg.SECRET)
+		go func() {
+			grpcErrc <- grpcServer.Serve(lis)
+		}()
+		slog.Info("grpc server started", slog.String("addr", cfg.GRPCListenAddr))
+	}
+
+	select {
+	case err := <-errc:
+		return err
+	case err := <-grpcErrc:
+		return fmt.Errorf("grpc server stopped: %w", err)
+	case <-ctx.Done():
+	}
+	// a second signal kills the process without waiting
+	stop()
+
+	return shutdown(e, grpcServer, checker, cfg, errc, func() {
+		// end the streams of post changes, which would otherwise keep the
+		// servers from stopping gracefully
+		postEvents.Close()
+		postUsecase.Close()
+		// WebSockets are hijacked, so the HTTP server does not wait for them
+		hub.Close()
+		// attempts in flight are finished, the rest left to other instances
+		// or the next start
+		stopWorkers()
+		<-workersDone
+	})
+}
+
+// shutdown drains the server: /readyz starts failing, then after the drain
+// delay closeStreams ends the long-lived streams, new connections are
+// refused and in-flight requests get up to ShutdownTimeout to complete. The
+// gRPC server is stopped even if the HTTP one fails to.
+func shutdown(e *echo.Echo, grpcServer *grpc.Server, checker *health.Checker, cfg config.Config, errc <-chan error, closeStreams func()) error {
+	slog.Info("shutting down", slog.Duration("drain_delay", cfg.ShutdownDrainDelay))
+	checker.Shutdown()
+	time.Sleep(cfg.ShutdownDrainDelay)
+	closeStreams()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	var errs []error
+	if err := e.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("cannot shut down server: %w", err))
+	} else if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, err)
+	}
+	if grpcServer != nil {
+		if err := stopGRPC(ctx, grpcServer); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	slog.Info("server stopped")
+	return nil
+}
+
+// newStore returns the Store selected by DB_DRIVER and the connection behind
+// it, which is nil for "memory".
+// "memory" keeps everything in process and is lost on exit,
+// "sqlite" expects DB_SOURCE to be a file path or a file: URI.
+func newStore(cfg config.Config) (db.Store, *sql.DB, error) {
+	switch cfg.DBDriver {
+	case "memory":
+		return memory.NewStore(), nil, nil
+	case sqlite.DriverName:
+		conn, err := sqlite.Open(cfg.DBSource)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sqlite.NewStore(conn), conn, nil
+	}
+
+	conn, err := sql.Open(cfg.DBDriver, cfg.DBSource)
+	if err != nil {
+		return nil, nil, err
+	}
+	return db.NewStore(conn), conn, nil
+}
+
+// newPubSub returns the PubSub connecting the instances sharing the database,
+// and a function closing it. Only Postgres databases can be shared; with the
+// others messages stay within this process.
+func newPubSub(cfg config.Config, conn *sql.DB) (pubsub.PubSub, func()) {
+	if cfg.DBDriver != "postgres" {
+		return pubsub.NewMemory(), func() {}
+	}
+	ps := pubsub.NewPostgres(conn, cfg.DBSource)
+	return ps, func() {
+		if err := ps.Close(); err != nil {
+			slog.Error("cannot stop listening for notifications", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// openStore is used by the admin commands: it refuses to touch a schema this
+// binary does not know about, but never migrates on its own
+func openStore() (db.Store, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.AutoMigrate = false
+	if err := prepareSchema(cfg); err != nil {
+		return nil, err
+	}
+	store, _, err := newStore(cfg)
+	return store, err
+}
+
+// stopGRPC waits for in-flight calls to finish, cancelling those still
+// running when ctx is done
+func stopGRPC(ctx context.Context, s *grpc.Server) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.Stop()
+		return fmt.Errorf("cannot shut down grpc server: %w", ctx.Err())
+	}
+}
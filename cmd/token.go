@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage access tokens",
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke USER_STR_ID",
+	Short: "Invalidate every token issued to a user so far",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		uu, err := newUserUsecase()
+		if err != nil {
+			return err
+		}
+		user, err := uu.RevokeTokens(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		return printJSON(cmd.OutOrStdout(), user)
+	},
+}
+
+func init() {
+	tokenCmd.AddCommand(tokenRevokeCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/spf13/cobra"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage user accounts",
+}
+
+var userCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a user; the password is read from stdin when --password is omitted",
+	Args:  cobra.NoArgs,
+	RunE:  runUserCreate,
+}
+
+var userPromoteCmd = &cobra.Command{
+	Use:   "promote USER_STR_ID",
+	Short: "Change the role of a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		uu, err := newUserUsecase()
+		if err != nil {
+			return err
+		}
+		role, _ := cmd.Flags().GetString("role")
+		user, err := uu.SetRole(cmd.Context(), args[0], role)
+		if err != nil {
+			return err
+		}
+		return printJSON(cmd.OutOrStdout(), user)
+	},
+}
+
+var userLockCmd = &cobra.Command{
+	Use:   "lock USER_STR_ID",
+	Short: "Lock a user out of login and revoke access of existing tokens",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		uu, err := newUserUsecase()
+		if err != nil {
+			return err
+		}
+		unlock, _ := cmd.Flags().GetBool("unlock")
+		user, err := uu.SetLocked(cmd.Context(), args[0], !unlock)
+		if err != nil {
+			return err
+		}
+		return printJSON(cmd.OutOrStdout(), user)
+	},
+}
+
+var userDeleteCmd = &cobra.Command{
+	Use:   "delete USER_STR_ID",
+	Short: "Delete a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		uu, err := newUserUsecase()
+		if err != nil {
+			return err
+		}
+		purgePosts, _ := cmd.Flags().GetBool("purge-posts")
+		err = uu.DeleteUser(cmd.Context(), args[0], purgePosts)
+		if errors.Is(err, usecase.ErrUserHasPosts) {
+			return fmt.Errorf("%w, use --purge-posts to delete them too", err)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "deleted user %s\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	userCreateCmd.Flags().String("user-str-id", "", "public id of the new user")
+	userCreateCmd.Flags().String("email", "", "email address of the new user")
+	userCreateCmd.Flags().String("password", "", "password of the new user")
+	userCreateCmd.Flags().String("role", dto.RoleUser, "role of the new user (user, moderator or admin)")
+	userCreateCmd.MarkFlagRequired("user-str-id")
+	userCreateCmd.MarkFlagRequired("email")
+
+	userPromoteCmd.Flags().String("role", dto.RoleModerator, "new role (user, moderator or admin)")
+	userLockCmd.Flags().Bool("unlock", false, "unlock the user instead")
+	userDeleteCmd.Flags().Bool("purge-posts", false, "delete the user's posts as well")
+
+	userCmd.AddCommand(userCreateCmd, userPromoteCmd, userLockCmd, userDeleteCmd)
+	rootCmd.AddCommand(userCmd)
+}
+
+func runUserCreate(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+	req := dto.CreateUserRequest{}
+	req.UserStrID, _ = flags.GetString("user-str-id")
+	req.Email, _ = flags.GetString("email")
+	req.Password, _ = flags.GetString("password")
+	role, _ := flags.GetString("role")
+
+	if req.Password == "" {
+		// keeps the password out of shell history and the process list
+		line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("cannot read password from stdin: %w", err)
+		}
+		req.Password = strings.TrimRight(line, "\r\n")
+	}
+
+	// same rules as POST /signup
+	if err := validator.New().Struct(req); err != nil {
+		return err
+	}
+
+	uu, err := newUserUsecase()
+	if err != nil {
+		return err
+	}
+
+	created, err := uu.SignUp(cmd.Context(), req)
+	if err != nil {
+		return err
+	}
+	user, err := uu.SetRole(cmd.Context(), created.UserStrID, role)
+	if err != nil {
+		return err
+	}
+	return printJSON(cmd.OutOrStdout(), user)
+}
+
+func newUserUsecase() (usecase.IUserUsecase, error) {
+	store, err := openStore()
+	if err != nil {
+		return nil, err
+	}
+	return usecase.NewUserUsecase(store, usecase.JobDispatchWebhooks), nil
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/config"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/jobs"
+	"github.com/PenginAction/go-BulletinBoard/metrics"
+	"github.com/PenginAction/go-BulletinBoard/tracing"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/PenginAction/go-BulletinBoard/webhook"
+	"github.com/spf13/cobra"
+)
+
+// jobsPurgeSchedule is when succeeded jobs past JOB_RETENTION are deleted
+const jobsPurgeSchedule = "0 3 * * *"
+
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Run the background jobs and webhook deliveries without serving the API",
+	Args:  cobra.NoArgs,
+	RunE:  runWorker,
+}
+
+func init() {
+	rootCmd.AddCommand(workerCmd)
+}
+
+func runWorker(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.DBDriver == "memory" {
+		return errors.New("a worker cannot share the memory store of a server, use RUN_WORKERS instead")
+	}
+
+	if err := prepareSchema(cfg); err != nil {
+		return fmt.Errorf("cannot start with current db schema: %w", err)
+	}
+
+	store, conn, err := newStore(cfg)
+	if err != nil {
+		return fmt.Errorf("cannot connect to db: %w", err)
+	}
+	defer conn.Close()
+	store = tracing.NewStore(metrics.NewStore(store), cfg.DBDriver)
+
+	shutdownTracing, err := tracing.Setup(cmd.Context(), cfg)
+	if err != nil {
+		return fmt.Errorf("cannot set up tracing: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("cannot flush traces", slog.String("error", err.Error()))
+		}
+	}()
+
+	// notifications are published to the servers, which deliver them live
+	ps, closePubSub := newPubSub(cfg, conn)
+	defer closePubSub()
+	publisher, err := usecase.NewEventPublisher(ps)
+	if err != nil {
+		return fmt.Errorf("cannot subscribe to events: %w", err)
+	}
+	defer publisher.Close()
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// the posts purged are announced, so the servers' streams catch up at once
+	posts := usecase.NewPostEventLog(usecase.NewPostUsecase(store, usecase.JobDispatchWebhooks), store, publisher)
+	defer posts.Close()
+
+	slog.Info("worker started", slog.String("db_driver", cfg.DBDriver))
+	err = runWorkers(ctx, cfg, store, usecase.NewWebhookUsecase(store), posts, usecase.NewNotificationUsecase(store, publisher))
+	slog.Info("worker stopped")
+	return err
+}
+
+// runWorkers runs the job worker and the webhook delivery worker until ctx
+// is done, then waits for the attempts in flight. Any number of them may
+// run against the same database: what each claims is leased to it.
+func runWorkers(ctx context.Context, cfg config.Config, store db.Store, webhooks usecase.IWebhookUsecase, posts usecase.IPostUsecase, notifications usecase.INotificationUsecase) error {
+	worker := jobs.NewWorker(store, jobs.Options{})
+	concurrency := jobs.HandlerOptions{Concurrency: cfg.WorkerConcurrency}
+	worker.Handle(usecase.JobDispatchWebhooks, usecase.HandleDispatchWebhooks(webhooks), concurrency)
+	worker.Handle(usecase.JobNotify, usecase.HandleNotify(notifications), concurrency)
+	// purges are big deletes, better not run side by side
+	worker.Handle(usecase.JobPurgePosts, usecase.HandlePurgePosts(posts), jobs.HandlerOptions{Timeout: 10 * time.Minute})
+	worker.Handle(jobs.KindPurge, jobs.Purge(store, cfg.JobRetention), jobs.HandlerOptions{})
+	if err := worker.Schedule(jobs.KindPurge, jobsPurgeSchedule, jobs.Job{Kind: jobs.KindPurge}); err != nil {
+		return err
+	}
+
+	deliveries := webhook.NewWorker(store, webhook.Options{
+		Timeout:              cfg.WebhookTimeout,
+		AllowPrivateNetworks: cfg.WebhookAllowPrivateNetworks,
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := deliveries.Run(ctx); err != nil {
+			slog.Error("webhook worker stopped", slog.String("error", err.Error()))
+		}
+	}()
+	err := worker.Run(ctx)
+	wg.Wait()
+	return err
+}
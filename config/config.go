@@ -1,14 +1,75 @@
 package config
 
 import (
+	"reflect"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	DBDriver string `mapstructure:"DB_DRIVER"`
-	DBSource string `mapstructure:"DB_SOURCE"`
-	SECRET   string `mapstructure:"SECRET"`
-	FE_URL   string `mapstructure:"FE_URL"`
+	DBDriver    string `mapstructure:"DB_DRIVER"`
+	DBSource    string `mapstructure:"DB_SOURCE"`
+	SECRET      string `mapstructure:"SECRET"`
+	FE_URL      string `mapstructure:"FE_URL"`
+	AutoMigrate bool   `mapstructure:"AUTO_MIGRATE"`
+
+	ListenAddr        string        `mapstructure:"LISTEN_ADDR"`
+	ReadTimeout       time.Duration `mapstructure:"READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `mapstructure:"READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `mapstructure:"WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `mapstructure:"IDLE_TIMEOUT"`
+	MaxHeaderBytes    int           `mapstructure:"MAX_HEADER_BYTES"`
+	// ShutdownDrainDelay is how long /readyz fails before the server stops
+	// accepting connections, so load balancers can take the instance out first
+	ShutdownDrainDelay time.Duration `mapstructure:"SHUTDOWN_DRAIN_DELAY"`
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+
+	// GRPCListenAddr is where the gRPC API is served. Empty disables it.
+	GRPCListenAddr string `mapstructure:"GRPC_LISTEN_ADDR"`
+
+	// TracingExporter is one of none, stdout or otlp
+	TracingExporter    string  `mapstructure:"TRACING_EXPORTER"`
+	TracingSampleRatio float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
+	// OTLPEndpoint is the host:port of the OTLP/HTTP collector. When empty
+	// the exporter falls back to OTEL_EXPORTER_OTLP_ENDPOINT.
+	OTLPEndpoint string `mapstructure:"OTLP_ENDPOINT"`
+	OTLPInsecure bool   `mapstructure:"OTLP_INSECURE"`
+
+	// LogLevel is one of debug, info, warn or error; LogFormat json or text
+	LogLevel  string `mapstructure:"LOG_LEVEL"`
+	LogFormat string `mapstructure:"LOG_FORMAT"`
+
+	// LegacyAPISunset is announced in the Sunset header of the unversioned
+	// API paths, as an RFC 3339 time. Empty omits the header.
+	LegacyAPISunset time.Time `mapstructure:"LEGACY_API_SUNSET"`
+
+	// GraphQLMaxDepth and GraphQLMaxComplexity bound the queries /graphql
+	// executes. Zero disables a limit.
+	GraphQLMaxDepth      int `mapstructure:"GRAPHQL_MAX_DEPTH"`
+	GraphQLMaxComplexity int `mapstructure:"GRAPHQL_MAX_COMPLEXITY"`
+
+	// StreamHeartbeat is how often idle event streams get a comment, which
+	// keeps proxies from closing them. StreamWriteTimeout disconnects
+	// clients that stop reading.
+	StreamHeartbeat    time.Duration `mapstructure:"STREAM_HEARTBEAT_INTERVAL"`
+	StreamWriteTimeout time.Duration `mapstructure:"STREAM_WRITE_TIMEOUT"`
+
+	// WebhookTimeout bounds each attempt to deliver to a webhook.
+	// WebhookAllowPrivateNetworks lets webhooks call loopback and private
+	// addresses, which is only safe when every user is trusted.
+	WebhookTimeout              time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	WebhookAllowPrivateNetworks bool          `mapstructure:"WEBHOOK_ALLOW_PRIVATE_NETWORKS"`
+
+	// RunWorkers runs the background jobs and webhook deliveries in the
+	// server. Turn it off to leave them to separate worker processes.
+	RunWorkers bool `mapstructure:"RUN_WORKERS"`
+	// WorkerConcurrency is how many jobs of a kind a worker runs at once
+	WorkerConcurrency int `mapstructure:"WORKER_CONCURRENCY"`
+	// JobRetention is how long succeeded jobs are kept
+	JobRetention time.Duration `mapstructure:"JOB_RETENTION"`
 }
 
 func LoadConfig(path string) (config Config, err error) {
@@ -16,6 +77,32 @@ func LoadConfig(path string) (config Config, err error) {
 	viper.SetConfigName("app")
 	viper.SetConfigType("env")
 
+	viper.SetDefault("LISTEN_ADDR", ":8080")
+	viper.SetDefault("GRPC_LISTEN_ADDR", ":9090")
+	viper.SetDefault("READ_TIMEOUT", 10*time.Second)
+	viper.SetDefault("READ_HEADER_TIMEOUT", 5*time.Second)
+	viper.SetDefault("WRITE_TIMEOUT", 30*time.Second)
+	viper.SetDefault("IDLE_TIMEOUT", 2*time.Minute)
+	viper.SetDefault("MAX_HEADER_BYTES", 1<<20)
+	viper.SetDefault("SHUTDOWN_DRAIN_DELAY", 5*time.Second)
+	viper.SetDefault("SHUTDOWN_TIMEOUT", 20*time.Second)
+	viper.SetDefault("TRACING_EXPORTER", "none")
+	viper.SetDefault("TRACING_SAMPLE_RATIO", 1.0)
+	viper.SetDefault("OTLP_ENDPOINT", "")
+	viper.SetDefault("OTLP_INSECURE", false)
+	viper.SetDefault("LOG_LEVEL", "info")
+	viper.SetDefault("LOG_FORMAT", "json")
+	viper.SetDefault("LEGACY_API_SUNSET", "2027-04-30T00:00:00Z")
+	viper.SetDefault("GRAPHQL_MAX_DEPTH", 10)
+	viper.SetDefault("GRAPHQL_MAX_COMPLEXITY", 1000)
+	viper.SetDefault("STREAM_HEARTBEAT_INTERVAL", 15*time.Second)
+	viper.SetDefault("STREAM_WRITE_TIMEOUT", 10*time.Second)
+	viper.SetDefault("WEBHOOK_TIMEOUT", 10*time.Second)
+	viper.SetDefault("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false)
+	viper.SetDefault("RUN_WORKERS", true)
+	viper.SetDefault("WORKER_CONCURRENCY", 4)
+	viper.SetDefault("JOB_RETENTION", 7*24*time.Hour)
+
 	viper.AutomaticEnv()
 
 	err = viper.ReadInConfig()
@@ -23,6 +110,22 @@ func LoadConfig(path string) (config Config, err error) {
 		return
 	}
 
-	err = viper.Unmarshal(&config)
+	err = viper.Unmarshal(&config, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		stringToTimeHook,
+	)))
 	return
 }
+
+// stringToTimeHook decodes RFC 3339 strings into time.Time, treating an empty
+// string as the zero time
+func stringToTimeHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(time.Time{}) {
+		return data, nil
+	}
+	if data.(string) == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, data.(string))
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/i18n"
+	"github.com/PenginAction/go-BulletinBoard/logging"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/labstack/echo/v4"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// statusCodes is the code reported for errors that only carry an HTTP
+// status, such as routing and JWT failures raised by echo itself
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            "bad_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusConflict:              "conflict",
+	http.StatusRequestEntityTooLarge: "payload_too_large",
+	http.StatusUnsupportedMediaType:  "unsupported_media_type",
+	http.StatusTooManyRequests:       "too_many_requests",
+	http.StatusServiceUnavailable:    "service_unavailable",
+}
+
+// HTTPErrorHandler writes every error returned by a handler or middleware as
+// an RFC 7807 problem details body, with messages in the language picked from
+// Accept-Language. Errors it does not recognise are logged and reported as a
+// bare 500 so internals never reach the client.
+func HTTPErrorHandler(err error, ctx echo.Context) {
+	if ctx.Response().Committed {
+		return
+	}
+
+	trans := i18n.FromAcceptLanguage(ctx.Request().Header.Get("Accept-Language"))
+	p := problemFor(err, trans)
+	p.Instance = ctx.Request().URL.Path
+	p.RequestID = logging.RequestID(ctx.Request().Context())
+	if p.Status >= http.StatusInternalServerError {
+		slog.ErrorContext(ctx.Request().Context(), "request failed",
+			slog.String("route", ctx.Path()),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	ctx.Response().Header().Set("Content-Language", trans.Locale())
+	ctx.Response().Header().Add(echo.HeaderVary, "Accept-Language")
+	if ctx.Request().Method == http.MethodHead {
+		err = ctx.NoContent(p.Status)
+	} else {
+		err = writeProblem(ctx, p)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx.Request().Context(), "writing error response failed", slog.String("error", err.Error()))
+	}
+}
+
+func writeProblem(ctx echo.Context, p dto.Problem) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return ctx.Blob(p.Status, dto.MIMEApplicationProblemJSON, body)
+}
+
+// problemFor describes err to the client, with messages translated by trans
+func problemFor(err error, trans ut.Translator) dto.Problem {
+	if e, ok := usecase.AsError(err); ok {
+		var fields []dto.FieldError
+		for _, f := range e.Fields {
+			f.Message = i18n.FieldMessage(trans, f)
+			fields = append(fields, f)
+		}
+		return newProblem(kindStatus(e.Kind), e.Code, i18n.Message(trans, e.Code, e.Message), fields)
+	}
+
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		fields := make([]dto.FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			fields = append(fields, dto.FieldError{
+				Field:   fe.Field(),
+				Code:    fe.Tag(),
+				Message: fe.Translate(trans),
+			})
+		}
+		detail := i18n.Message(trans, "validation_failed", "request validation failed")
+		return newProblem(http.StatusBadRequest, "validation_failed", detail, fields)
+	}
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		code, ok := statusCodes[he.Code]
+		if !ok {
+			code = "error"
+		}
+		detail := ""
+		if he.Code < http.StatusInternalServerError {
+			detail = fmt.Sprint(he.Message)
+		}
+		return newProblem(he.Code, code, detail, nil)
+	}
+
+	return newProblem(http.StatusInternalServerError, "internal_error", "", nil)
+}
+
+func newProblem(status int, code, detail string, fields []dto.FieldError) dto.Problem {
+	return dto.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Errors: fields,
+	}
+}
+
+func kindStatus(kind usecase.Kind) int {
+	switch kind {
+	case usecase.KindNotFound:
+		return http.StatusNotFound
+	case usecase.KindConflict:
+		return http.StatusConflict
+	case usecase.KindForbidden:
+		return http.StatusForbidden
+	case usecase.KindUnauthorized:
+		return http.StatusUnauthorized
+	case usecase.KindValidation:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    func() error
+		status int
+		code   string
+		check  func(t *testing.T, problem dto.Problem)
+	}{
+		{
+			name:   "not found",
+			err:    func() error { return usecase.ErrUserNotFound.With(errors.New("sql: no rows in result set")) },
+			status: http.StatusNotFound,
+			code:   "user_not_found",
+			check: func(t *testing.T, problem dto.Problem) {
+				require.Equal(t, "user not found", problem.Detail)
+			},
+		},
+		{
+			name:   "conflict",
+			err:    func() error { return usecase.ErrUserHasPosts },
+			status: http.StatusConflict,
+			code:   "user_has_posts",
+		},
+		{
+			name:   "forbidden",
+			err:    func() error { return usecase.ErrUserLocked },
+			status: http.StatusForbidden,
+			code:   "user_locked",
+		},
+		{
+			name: "validator errors",
+			err: func() error {
+				return utils.Validator().Struct(dto.CreateUserRequest{UserStrID: "a-b", Email: "nope", Password: "123"})
+			},
+			status: http.StatusBadRequest,
+			code:   "validation_failed",
+			check: func(t *testing.T, problem dto.Problem) {
+				require.Equal(t, []dto.FieldError{
+					{Field: "user_str_id", Code: "alphanum", Message: "user_str_id can only contain alphanumeric characters"},
+					{Field: "email", Code: "email", Message: "email must be a valid email address"},
+					{Field: "password", Code: "min", Message: "password must be at least 6 characters in length"},
+				}, problem.Errors)
+			},
+		},
+		{
+			name:   "bound broken",
+			err:    func() error { return usecase.NewValidationError("limit", "maximum", "limit must be at most 100", "100") },
+			status: http.StatusBadRequest,
+			code:   "validation_failed",
+			check: func(t *testing.T, problem dto.Problem) {
+				require.Equal(t, []dto.FieldError{
+					{Field: "limit", Code: "maximum", Message: "limit must be at most 100"},
+				}, problem.Errors)
+			},
+		},
+		{
+			name:   "echo error",
+			err:    func() error { return echo.NewHTTPError(http.StatusUnauthorized, "missing or malformed jwt") },
+			status: http.StatusUnauthorized,
+			code:   "unauthorized",
+			check: func(t *testing.T, problem dto.Problem) {
+				require.Equal(t, "missing or malformed jwt", problem.Detail)
+			},
+		},
+		{
+			name:   "internal error",
+			err:    func() error { return errors.New("dial tcp 10.0.0.1:5432: connection refused") },
+			status: http.StatusInternalServerError,
+			code:   "internal_error",
+			check: func(t *testing.T, problem dto.Problem) {
+				require.Empty(t, problem.Detail)
+			},
+		},
+	}
+
+	e := echo.New()
+	for i := range cases {
+		tc := cases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/posts/1", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			HTTPErrorHandler(tc.err(), c)
+
+			require.Equal(t, tc.status, rec.Code)
+			require.Equal(t, dto.MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+			require.NotContains(t, rec.Body.String(), "10.0.0.1")
+
+			var problem dto.Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			require.Equal(t, "about:blank", problem.Type)
+			require.Equal(t, http.StatusText(tc.status), problem.Title)
+			require.Equal(t, tc.status, problem.Status)
+			require.Equal(t, tc.code, problem.Code)
+			require.Equal(t, "/posts/1", problem.Instance)
+			if tc.check != nil {
+				tc.check(t, problem)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorHandlerCommitted(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	require.NoError(t, c.String(http.StatusOK, "partial"))
+
+	HTTPErrorHandler(errors.New("boom"), c)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "partial", rec.Body.String())
+}
+
+func TestHTTPErrorHandlerLocalized(t *testing.T) {
+	cases := []struct {
+		name           string
+		acceptLanguage string
+		locale         string
+		detail         string
+		field          string
+	}{
+		{
+			name:           "japanese",
+			acceptLanguage: "ja-JP,ja;q=0.9,en;q=0.8",
+			locale:         "ja",
+			detail:         "リクエストの検証に失敗しました",
+			field:          "emailは正しいメールアドレスでなければなりません",
+		},
+		{
+			name:           "english preferred",
+			acceptLanguage: "en-US,ja;q=0.5",
+			locale:         "en",
+			detail:         "request validation failed",
+			field:          "email must be a valid email address",
+		},
+		{
+			name:           "unsupported language",
+			acceptLanguage: "fr",
+			locale:         "en",
+			detail:         "request validation failed",
+			field:          "email must be a valid email address",
+		},
+		{
+			name:   "no header",
+			locale: "en",
+			detail: "request validation failed",
+			field:  "email must be a valid email address",
+		},
+	}
+
+	e := echo.New()
+	for i := range cases {
+		tc := cases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/login", nil)
+			if tc.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tc.acceptLanguage)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := utils.Validator().Struct(dto.LoginRequest{Email: "nope", Password: "123456"})
+			HTTPErrorHandler(err, c)
+
+			require.Equal(t, tc.locale, rec.Header().Get("Content-Language"))
+
+			var problem dto.Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			require.Equal(t, "validation_failed", problem.Code)
+			require.Equal(t, tc.detail, problem.Detail)
+			require.Len(t, problem.Errors, 1)
+			require.Equal(t, tc.field, problem.Errors[0].Message)
+		})
+	}
+}
+
+func TestHTTPErrorHandlerLocalizedDomainError(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/signup", nil)
+	req.Header.Set("Accept-Language", "ja")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := usecase.ErrAlreadyExists.With(errors.New("duplicate key"))
+	err.Fields = []dto.FieldError{{Field: "email", Code: "taken", Message: "is already taken"}}
+	HTTPErrorHandler(err, c)
+
+	require.Equal(t, http.StatusConflict, rec.Code)
+
+	var problem dto.Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	require.Equal(t, "already_exists", problem.Code)
+	require.Equal(t, "既に存在します", problem.Detail)
+	require.Equal(t, []dto.FieldError{{Field: "email", Code: "taken", Message: "emailは既に使用されています"}}, problem.Errors)
+}
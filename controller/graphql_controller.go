@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/graph"
+	"github.com/PenginAction/go-BulletinBoard/i18n"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+type IGraphQLController interface {
+	Query(ctx echo.Context) error
+}
+
+type graphQLController struct {
+	server *graph.Server
+}
+
+func NewGraphQLController(server *graph.Server) IGraphQLController {
+	return &graphQLController{server}
+}
+
+// Query executes a GraphQL request. As GraphQL over HTTP expects, errors
+// raised while executing the query are reported in the body with status
+// 200; only requests that are not GraphQL at all get a problem response.
+func (gc *graphQLController) Query(ctx echo.Context) error {
+	userValue := ctx.Get("user")
+	if userValue == nil {
+		return usecase.ErrUnauthorized
+	}
+	user := userValue.(*jwt.Token)
+	claims := user.Claims.(*dto.JwtCustomClaims)
+
+	var req graph.Request
+	if err := ctx.Bind(&req); err != nil {
+		return err
+	}
+	if req.Query == "" {
+		return usecase.NewValidationError("query", "required", "query is required")
+	}
+
+	trans := i18n.FromAcceptLanguage(ctx.Request().Header.Get("Accept-Language"))
+	res := gc.server.Execute(ctx.Request().Context(), claims, trans, req)
+	ctx.Response().Header().Set("Content-Language", trans.Locale())
+	ctx.Response().Header().Add(echo.HeaderVary, "Accept-Language")
+	return ctx.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/graph"
+	mock_usecase "github.com/PenginAction/go-BulletinBoard/usecase/mock"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphQLQuery(t *testing.T) {
+	cases := []struct {
+		name          string
+		requestBody   map[string]interface{}
+		language      string
+		noUser        bool
+		buildStubs    func(uu *mock_usecase.MockIUserUsecase)
+		checkResponse func(rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:        "valid request",
+			requestBody: map[string]interface{}{"query": "{ viewer { userStrId } }"},
+			buildStubs: func(uu *mock_usecase.MockIUserUsecase) {
+				uu.EXPECT().
+					GetUsersByIDs(gomock.Any(), gomock.Eq([]uint{1})).
+					Times(1).
+					Return([]dto.UserResponse{{ID: 1, UserStrID: "alice"}}, nil)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.JSONEq(t, `{"data":{"viewer":{"userStrId":"alice"}}}`, rec.Body.String())
+			},
+		},
+		{
+			name:        "query error",
+			requestBody: map[string]interface{}{"query": "{ nope }"},
+			language:    "ja",
+			buildStubs: func(uu *mock_usecase.MockIUserUsecase) {
+				uu.EXPECT().GetUsersByIDs(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				// GraphQL reports errors of the query in the body
+				require.Equal(t, http.StatusOK, rec.Code)
+				require.Equal(t, "ja", rec.Header().Get("Content-Language"))
+
+				var res map[string]interface{}
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+				require.Len(t, res["errors"], 1)
+			},
+		},
+		{
+			name:        "missing query",
+			requestBody: map[string]interface{}{},
+			buildStubs: func(uu *mock_usecase.MockIUserUsecase) {
+				uu.EXPECT().GetUsersByIDs(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+				require.Equal(t, dto.MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+			},
+		},
+		{
+			name:        "no user info",
+			requestBody: map[string]interface{}{"query": "{ viewer { userStrId } }"},
+			noUser:      true,
+			buildStubs: func(uu *mock_usecase.MockIUserUsecase) {
+				uu.EXPECT().GetUsersByIDs(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+	}
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Validator = &utils.CustomValidator{Validator: utils.Validator()}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uu := mock_usecase.NewMockIUserUsecase(ctrl)
+	pu := mock_usecase.NewMockIPostUsecase(ctrl)
+	server, err := graph.NewServer(uu, pu, graph.Limits{MaxDepth: 10, MaxComplexity: 1000})
+	require.NoError(t, err)
+	gc := NewGraphQLController(server)
+
+	for i := range cases {
+		tc := cases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			tc.buildStubs(uu)
+
+			body, _ := json.Marshal(tc.requestBody)
+			req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if tc.language != "" {
+				req.Header.Set("Accept-Language", tc.language)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			if !tc.noUser {
+				user := &jwt.Token{Claims: &dto.JwtCustomClaims{ID: 1}}
+				c.Set("user", user)
+			}
+			if err := gc.Query(c); err != nil {
+				e.HTTPErrorHandler(err, c)
+			}
+
+			tc.checkResponse(rec)
+		})
+	}
+}
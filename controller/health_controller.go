@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/PenginAction/go-BulletinBoard/health"
+	"github.com/labstack/echo/v4"
+)
+
+type IHealthController interface {
+	Healthz(ctx echo.Context) error
+	Readyz(ctx echo.Context) error
+}
+
+type healthController struct {
+	checker *health.Checker
+}
+
+func NewHealthController(checker *health.Checker) IHealthController {
+	return &healthController{checker}
+}
+
+// Healthz reports that the process is up and serving requests
+func (hc *healthController) Healthz(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, health.Report{Status: health.StatusOK, Checks: map[string]health.Result{}})
+}
+
+// Readyz runs the readiness checks and answers 503 if any of them fails
+func (hc *healthController) Readyz(ctx echo.Context) error {
+	report := hc.checker.Run(ctx.Request().Context())
+	if report.Status != health.StatusOK {
+		return ctx.JSON(http.StatusServiceUnavailable, report)
+	}
+	return ctx.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PenginAction/go-BulletinBoard/health"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthz(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Register("database", 0, func(ctx context.Context) error { return errors.New("down") })
+	hc := NewHealthController(checker)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	require.NoError(t, hc.Healthz(ctx))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestReadyz(t *testing.T) {
+	cases := []struct {
+		name          string
+		checkErr      error
+		checkResponse func(rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "ready",
+			checkErr: nil,
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name:     "database down",
+			checkErr: errors.New("connection refused"),
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := health.NewChecker()
+			checker.Register("database", 0, func(ctx context.Context) error { return tc.checkErr })
+			hc := NewHealthController(checker)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+
+			require.NoError(t, hc.Readyz(ctx))
+			tc.checkResponse(rec)
+
+			var report health.Report
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+			require.Contains(t, report.Checks, "database")
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/labstack/echo/v4"
+)
+
+// defaultNotificationsLimit is how many notifications are listed when the
+// client does not say
+const defaultNotificationsLimit = 20
+
+type INotificationController interface {
+	ListNotifications(ctx echo.Context) error
+	CountUnread(ctx echo.Context) error
+	MarkRead(ctx echo.Context) error
+	MarkAllRead(ctx echo.Context) error
+}
+
+type notificationController struct {
+	notificationUsecase usecase.INotificationUsecase
+}
+
+func NewNotificationController(nu usecase.INotificationUsecase) INotificationController {
+	return &notificationController{nu}
+}
+
+func (nc *notificationController) ListNotifications(ctx echo.Context) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := dto.ListNotificationsRequest{UserID: userID}
+	if s := ctx.QueryParam("before"); s != "" {
+		before, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || before < 1 {
+			return usecase.NewValidationError("before", "positive_integer", "before must be a positive integer")
+		}
+		req.Before = before
+	}
+	if req.Limit, err = limitParam(ctx, defaultNotificationsLimit); err != nil {
+		return err
+	}
+
+	notifications, err := nc.notificationUsecase.ListNotifications(ctx.Request().Context(), req)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, notifications)
+}
+
+func (nc *notificationController) CountUnread(ctx echo.Context) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	count, err := nc.notificationUsecase.CountUnread(ctx.Request().Context(), userID)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, dto.UnreadNotificationsResponse{Count: count})
+}
+
+func (nc *notificationController) MarkRead(ctx echo.Context) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	id, err := idParam(ctx, "notificationId")
+	if err != nil {
+		return err
+	}
+
+	notification, err := nc.notificationUsecase.MarkRead(ctx.Request().Context(), userID, id)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, notification)
+}
+
+func (nc *notificationController) MarkAllRead(ctx echo.Context) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := nc.notificationUsecase.MarkAllRead(ctx.Request().Context(), userID); err != nil {
+		return err
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
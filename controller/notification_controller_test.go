@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	mock_usecase "github.com/PenginAction/go-BulletinBoard/usecase/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListNotifications(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	nu := mock_usecase.NewMockINotificationUsecase(ctrl)
+	nc := NewNotificationController(nu)
+
+	nu.EXPECT().
+		ListNotifications(gomock.Any(), dto.ListNotificationsRequest{UserID: 3, Limit: defaultNotificationsLimit}).
+		Return([]dto.NotificationResponse{{ID: 2}, {ID: 1}}, nil)
+	rec := webhookRequest(t, nc.ListNotifications, http.MethodGet, "/me/notifications", nil, 3)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var res []dto.NotificationResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Len(t, res, 2)
+
+	nu.EXPECT().
+		ListNotifications(gomock.Any(), dto.ListNotificationsRequest{UserID: 3, Before: 2, Limit: 1}).
+		Return([]dto.NotificationResponse{{ID: 1}}, nil)
+	rec = webhookRequest(t, nc.ListNotifications, http.MethodGet, "/me/notifications?before=2&limit=1", nil, 3)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = webhookRequest(t, nc.ListNotifications, http.MethodGet, "/me/notifications?limit=x", nil, 3)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = webhookRequest(t, nc.ListNotifications, http.MethodGet, "/me/notifications", nil, 0)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestCountUnreadNotifications(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	nu := mock_usecase.NewMockINotificationUsecase(ctrl)
+	nc := NewNotificationController(nu)
+
+	nu.EXPECT().CountUnread(gomock.Any(), uint(3)).Return(int64(4), nil)
+	rec := webhookRequest(t, nc.CountUnread, http.MethodGet, "/me/notifications/unread-count", nil, 3)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"count":4}`, rec.Body.String())
+}
+
+func TestMarkNotificationRead(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	nu := mock_usecase.NewMockINotificationUsecase(ctrl)
+	nc := NewNotificationController(nu)
+
+	nu.EXPECT().MarkRead(gomock.Any(), uint(3), int64(7)).Return(dto.NotificationResponse{ID: 7, Read: true}, nil)
+	rec := webhookRequest(t, nc.MarkRead, http.MethodPost, "/me/notifications/7/read", nil, 3, "notificationId", "7")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	nu.EXPECT().MarkRead(gomock.Any(), uint(3), int64(7)).Return(dto.NotificationResponse{}, usecase.ErrNotificationNotFound)
+	rec = webhookRequest(t, nc.MarkRead, http.MethodPost, "/me/notifications/7/read", nil, 3, "notificationId", "7")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = webhookRequest(t, nc.MarkRead, http.MethodPost, "/me/notifications/0/read", nil, 3, "notificationId", "0")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestMarkAllNotificationsRead(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	nu := mock_usecase.NewMockINotificationUsecase(ctrl)
+	nc := NewNotificationController(nu)
+
+	nu.EXPECT().MarkAllRead(gomock.Any(), uint(3)).Return(int64(2), nil)
+	rec := webhookRequest(t, nc.MarkAllRead, http.MethodPost, "/me/notifications/read", nil, 3)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	nu.EXPECT().MarkAllRead(gomock.Any(), uint(3)).Return(int64(0), errors.New("db down"))
+	rec = webhookRequest(t, nc.MarkAllRead, http.MethodPost, "/me/notifications/read", nil, 3)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -29,26 +29,27 @@ func NewPostController(pu usecase.IPostUsecase) IPostController {
 func (pc *postController) CreatePost(ctx echo.Context) error {
 	userValue := ctx.Get("user")
 	if userValue == nil {
-		return ctx.JSON(http.StatusUnauthorized, "Unauthorized")
+		return usecase.ErrUnauthorized
 	}
 	user := userValue.(*jwt.Token)
 	claims := user.Claims.(*dto.JwtCustomClaims)
 	userId := claims.ID
 
 	var req dto.CreatePostRequest
-	req.UserID = userId
 	if err := ctx.Bind(&req); err != nil {
-		return ctx.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
+	// set after binding, so the body cannot post as someone else
+	req.UserID = userId
 
 	if err := ctx.Validate(req); err != nil {
-		return ctx.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
 
 	c := ctx.Request().Context()
 	postRes, err := pc.postUsecase.CreatePost(c, req)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	return ctx.JSON(http.StatusCreated, postRes)
@@ -57,22 +58,24 @@ func (pc *postController) CreatePost(ctx echo.Context) error {
 func (pc *postController) GetPostById(ctx echo.Context) error {
 	userValue := ctx.Get("user")
 	if userValue == nil {
-		return ctx.JSON(http.StatusUnauthorized, "Unauthorized")
+		return usecase.ErrUnauthorized
 	}
 	user := userValue.(*jwt.Token)
 	claims := user.Claims.(*dto.JwtCustomClaims)
 	userId := claims.ID
-	Id := ctx.Param("postId")
-	postId, _ := strconv.Atoi(Id)
+	postId, err := postIDParam(ctx)
+	if err != nil {
+		return err
+	}
 
 	c := ctx.Request().Context()
-	postRes, err := pc.postUsecase.GetPostById(c, uint(postId))
+	postRes, err := pc.postUsecase.GetPostById(c, postId)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
-	if postRes.UserID != userId {
-		return ctx.JSON(http.StatusUnauthorized, "Unauthorized")
+	if postRes.UserID != userId && !claims.IsModerator() {
+		return usecase.ErrNotPostOwner
 	}
 
 	return ctx.JSON(http.StatusOK, postRes)
@@ -83,20 +86,20 @@ func (pc *postController) GetAllPosts(ctx echo.Context) error {
 
 	pageID, err := strconv.Atoi(ctx.QueryParam("page_id"))
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, err.Error())
+		return usecase.NewValidationError("page_id", "integer", "page_id must be an integer")
 	}
 	req.PageID = int32(pageID)
 
 	pageSize, err := strconv.Atoi(ctx.QueryParam("page_size"))
 	if err != nil {
-		return ctx.JSON(http.StatusBadRequest, err.Error())
+		return usecase.NewValidationError("page_size", "integer", "page_size must be an integer")
 	}
 	req.PageSize = int32(pageSize)
 
 	c := ctx.Request().Context()
 	postRes, err := pc.postUsecase.GetAllPosts(c, req)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	return ctx.JSON(http.StatusOK, postRes)
@@ -105,33 +108,41 @@ func (pc *postController) GetAllPosts(ctx echo.Context) error {
 func (pc *postController) UpdatePost(ctx echo.Context) error {
 	userValue := ctx.Get("user")
 	if userValue == nil {
-		return ctx.JSON(http.StatusUnauthorized, "Unauthorized")
+		return usecase.ErrUnauthorized
 	}
 	user := userValue.(*jwt.Token)
 	claims := user.Claims.(*dto.JwtCustomClaims)
 	userId := claims.ID
-	id := ctx.Param("postId")
-	postId, _ := strconv.Atoi(id)
+	postId, err := postIDParam(ctx)
+	if err != nil {
+		return err
+	}
 
 	var req dto.UpdatePostRequest
-	req.ID = uint(postId)
 	if err := ctx.Bind(&req); err != nil {
-		return ctx.JSON(http.StatusBadRequest, err.Error())
+		return err
+	}
+	// set after binding, so the body cannot name a post other than the one
+	// whose owner is checked
+	req.ID = postId
+
+	if err := ctx.Validate(req); err != nil {
+		return err
 	}
 
 	c := ctx.Request().Context()
-	postRes, err := pc.postUsecase.GetPostById(c, uint(postId))
+	postRes, err := pc.postUsecase.GetPostById(c, postId)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	if postRes.UserID != userId {
-		return ctx.JSON(http.StatusUnauthorized, "Unauthorized")
+		return usecase.ErrNotPostOwner
 	}
 
 	newPost, err := pc.postUsecase.UpdatePost(c, req)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	return ctx.JSON(http.StatusOK, newPost)
@@ -140,27 +151,38 @@ func (pc *postController) UpdatePost(ctx echo.Context) error {
 func (pc *postController) DeletePost(ctx echo.Context) error {
 	userValue := ctx.Get("user")
 	if userValue == nil {
-		return ctx.JSON(http.StatusUnauthorized, "Unauthorized")
+		return usecase.ErrUnauthorized
 	}
 	user := userValue.(*jwt.Token)
 	claims := user.Claims.(*dto.JwtCustomClaims)
 	userId := claims.ID
-	id := ctx.Param("postId")
-	postId, _ := strconv.Atoi(id)
+	postId, err := postIDParam(ctx)
+	if err != nil {
+		return err
+	}
 
 	c := ctx.Request().Context()
-	postRes, err := pc.postUsecase.GetPostById(c, uint(postId))
+	postRes, err := pc.postUsecase.GetPostById(c, postId)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
-	if postRes.UserID != userId {
-		return ctx.JSON(http.StatusUnauthorized, "Unauthorized")
+	if postRes.UserID != userId && !claims.IsModerator() {
+		return usecase.ErrNotPostOwner
 	}
 
-	if err := pc.postUsecase.DeletePost(c, uint(postId)); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, err.Error())
+	if err := pc.postUsecase.DeletePost(c, postId); err != nil {
+		return err
 	}
 
 	return ctx.NoContent(http.StatusNoContent)
 }
+
+// postIDParam parses the :postId path parameter
+func postIDParam(ctx echo.Context) (uint, error) {
+	id, err := strconv.ParseUint(ctx.Param("postId"), 10, 64)
+	if err != nil || id == 0 {
+		return 0, usecase.NewValidationError("postId", "positive_integer", "postId must be a positive integer")
+	}
+	return uint(id), nil
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,9 +13,9 @@ import (
 	"testing"
 
 	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
 	mock_usecase "github.com/PenginAction/go-BulletinBoard/usecase/mock"
 	"github.com/PenginAction/go-BulletinBoard/utils"
-	"github.com/go-playground/validator"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
@@ -58,11 +59,32 @@ func TestCreatePost(t *testing.T) {
 			},
 		},
 		{
-			name: "request bind error",
+			name: "user_id in the body is ignored",
 			requestBody: map[string]interface{}{
-				"user_id": "invalid user id",
+				"user_id": utils.RandomInt(1, 100),
 				"text":    utils.RandomString(6),
 			},
+			buildStubs: func(pu *mock_usecase.MockIPostUsecase, requestBody map[string]interface{}) {
+				// the token is someone else's, who is the author
+				expectedPost := dto.CreatePostRequest{
+					UserID: requestBody["user_id"].(uint) + 1,
+					Text:   requestBody["text"].(string),
+				}
+				pu.EXPECT().
+					CreatePost(context.Background(), expectedPost).
+					Times(1).
+					Return(dto.PostResponse{}, nil)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, rec.Code)
+			},
+		},
+		{
+			name: "request bind error",
+			requestBody: map[string]interface{}{
+				"user_id": utils.RandomInt(1, 100),
+				"text":    12345,
+			},
 			buildStubs: func(pu *mock_usecase.MockIPostUsecase, requestBody map[string]interface{}) {
 			},
 			checkResponse: func(rec *httptest.ResponseRecorder) {
@@ -104,7 +126,8 @@ func TestCreatePost(t *testing.T) {
 	}
 
 	e := echo.New()
-	e.Validator = &utils.CustomValidator{Validator: validator.New()}
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Validator = &utils.CustomValidator{Validator: utils.Validator()}
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -122,6 +145,9 @@ func TestCreatePost(t *testing.T) {
 			if id, ok := tc.requestBody["user_id"].(uint); ok {
 				userID = id
 			}
+			if tc.name == "user_id in the body is ignored" {
+				userID++
+			}
 
 			token, err := utils.CreateValidToken(userID)
 			require.NoError(t, err)
@@ -137,8 +163,9 @@ func TestCreatePost(t *testing.T) {
 				user := &jwt.Token{Claims: &dto.JwtCustomClaims{ID: userID}}
 				c.Set("user", user)
 			}
-			err = pc.CreatePost(c)
-			require.NoError(t, err)
+			if err := pc.CreatePost(c); err != nil {
+				e.HTTPErrorHandler(err, c)
+			}
 
 			tc.checkResponse(rec)
 		})
@@ -235,12 +262,56 @@ func TestGetPostById(t *testing.T) {
 					Return(expectedPostRes, nil)
 			},
 			checkResponse: func(rec *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, rec.Code)
+				require.Equal(t, http.StatusForbidden, rec.Code)
+			},
+		},
+		{
+			name:   "post not found",
+			postID: utils.RandomInt(1, 100),
+			expectedPostRes: dto.PostResponse{
+				UserID: utils.RandomInt(1, 100),
+			},
+			buildStubs: func(pu *mock_usecase.MockIPostUsecase, postID uint, requestBody map[string]interface{}, expectedPostRes dto.PostResponse) {
+				pu.EXPECT().
+					GetPostById(context.Background(), postID).
+					Times(1).
+					Return(dto.PostResponse{}, usecase.ErrPostNotFound.With(sql.ErrNoRows))
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, rec.Code)
+				require.Equal(t, dto.MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+
+				var problem dto.Problem
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+				require.Equal(t, "post_not_found", problem.Code)
+				require.Equal(t, http.StatusNotFound, problem.Status)
+			},
+		},
+		{
+			name:   "invalid post id",
+			postID: 0,
+			expectedPostRes: dto.PostResponse{
+				UserID: utils.RandomInt(1, 100),
+			},
+			buildStubs: func(pu *mock_usecase.MockIPostUsecase, postID uint, requestBody map[string]interface{}, expectedPostRes dto.PostResponse) {
+				pu.EXPECT().
+					GetPostById(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+
+				var problem dto.Problem
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+				require.Equal(t, "validation_failed", problem.Code)
+				require.Len(t, problem.Errors, 1)
+				require.Equal(t, "postId", problem.Errors[0].Field)
 			},
 		},
 	}
 	e := echo.New()
-	e.Validator = &utils.CustomValidator{Validator: validator.New()}
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Validator = &utils.CustomValidator{Validator: utils.Validator()}
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -274,8 +345,9 @@ func TestGetPostById(t *testing.T) {
 				c.Set("user", user)
 			}
 
-			err = pc.GetPostById(c)
-			require.NoError(t, err)
+			if err := pc.GetPostById(c); err != nil {
+				e.HTTPErrorHandler(err, c)
+			}
 
 			tc.checkResponse(rec)
 		})
@@ -357,7 +429,8 @@ func TestGetAllPosts(t *testing.T) {
 		},
 	}
 	e := echo.New()
-	e.Validator = &utils.CustomValidator{Validator: validator.New()}
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Validator = &utils.CustomValidator{Validator: utils.Validator()}
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -375,8 +448,9 @@ func TestGetAllPosts(t *testing.T) {
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
 
-			err := pc.GetAllPosts(c)
-			require.NoError(t, err)
+			if err := pc.GetAllPosts(c); err != nil {
+				e.HTTPErrorHandler(err, c)
+			}
 
 			tc.checkResponse(rec)
 		})
@@ -406,7 +480,7 @@ func TestUpdatePost(t *testing.T) {
 			},
 			buildStubs: func(pu *mock_usecase.MockIPostUsecase, postID uint, requestBody map[string]interface{}, expectedPostRes dto.PostResponse) {
 				newPost := dto.UpdatePostRequest{
-					ID:   requestBody["id"].(uint),
+					ID:   postID,
 					Text: requestBody["text"].(string),
 				}
 				pu.EXPECT().
@@ -445,8 +519,8 @@ func TestUpdatePost(t *testing.T) {
 			name:   "request bind error",
 			postID: utils.RandomInt(1, 100),
 			requestBody: map[string]interface{}{
-				"id":   "invalid id",
-				"text": utils.RandomString(6),
+				"id":   utils.RandomInt(1, 100),
+				"text": 12345,
 			},
 			expectedPostRes: dto.PostResponse{
 				ID:     utils.RandomInt(1, 100),
@@ -473,7 +547,7 @@ func TestUpdatePost(t *testing.T) {
 			},
 			buildStubs: func(pu *mock_usecase.MockIPostUsecase, postID uint, requestBody map[string]interface{}, expectedPostRes dto.PostResponse) {
 				newPost := dto.UpdatePostRequest{
-					ID:   requestBody["id"].(uint),
+					ID:   postID,
 					Text: requestBody["text"].(string),
 				}
 				pu.EXPECT().
@@ -504,7 +578,7 @@ func TestUpdatePost(t *testing.T) {
 			},
 			buildStubs: func(pu *mock_usecase.MockIPostUsecase, postID uint, requestBody map[string]interface{}, expectedPostRes dto.PostResponse) {
 				newPost := dto.UpdatePostRequest{
-					ID:   requestBody["id"].(uint),
+					ID:   postID,
 					Text: requestBody["text"].(string),
 				}
 				pu.EXPECT().
@@ -520,6 +594,78 @@ func TestUpdatePost(t *testing.T) {
 				require.Equal(t, http.StatusInternalServerError, rec.Code)
 			},
 		},
+		{
+			// the post of the path is checked and updated, whatever the body says
+			name:   "body id of another user's post",
+			postID: utils.RandomInt(1, 100),
+			requestBody: map[string]interface{}{
+				"id":   utils.RandomInt(101, 200),
+				"text": utils.RandomString(6),
+			},
+			expectedPostRes: dto.PostResponse{
+				ID:     utils.RandomInt(1, 100),
+				UserID: utils.RandomInt(1, 100),
+				Text:   utils.RandomString(6),
+			},
+			buildStubs: func(pu *mock_usecase.MockIPostUsecase, postID uint, requestBody map[string]interface{}, expectedPostRes dto.PostResponse) {
+				pu.EXPECT().
+					GetPostById(context.Background(), postID).
+					Times(1).
+					Return(expectedPostRes, nil)
+
+				pu.EXPECT().
+					UpdatePost(context.Background(), dto.UpdatePostRequest{ID: postID, Text: requestBody["text"].(string)}).
+					Times(1).
+					Return(expectedPostRes, nil)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			// the body cannot point the ownership check at a post of the caller
+			name:   "body id of own post on another user's post",
+			postID: utils.RandomInt(1, 100),
+			requestBody: map[string]interface{}{
+				"id":   utils.RandomInt(101, 200),
+				"text": utils.RandomString(6),
+			},
+			expectedPostRes: dto.PostResponse{
+				ID:     utils.RandomInt(1, 100),
+				UserID: utils.RandomInt(1, 100) + 1,
+				Text:   utils.RandomString(6),
+			},
+			buildStubs: func(pu *mock_usecase.MockIPostUsecase, postID uint, requestBody map[string]interface{}, expectedPostRes dto.PostResponse) {
+				pu.EXPECT().
+					GetPostById(context.Background(), postID).
+					Times(1).
+					Return(expectedPostRes, nil)
+
+				pu.EXPECT().
+					UpdatePost(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+			},
+		},
+		{
+			name:   "validation error",
+			postID: utils.RandomInt(1, 100),
+			requestBody: map[string]interface{}{
+				"text": "",
+			},
+			expectedPostRes: dto.PostResponse{
+				ID:     utils.RandomInt(1, 100),
+				UserID: utils.RandomInt(1, 100),
+				Text:   utils.RandomString(6),
+			},
+			buildStubs: func(pu *mock_usecase.MockIPostUsecase, postID uint, requestBody map[string]interface{}, expectedPostRes dto.PostResponse) {
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, rec.Code)
+			},
+		},
 		{
 			name:   "post user id does not match token user id",
 			postID: utils.RandomInt(1, 100),
@@ -534,7 +680,7 @@ func TestUpdatePost(t *testing.T) {
 			},
 			buildStubs: func(pu *mock_usecase.MockIPostUsecase, postID uint, requestBody map[string]interface{}, expectedPostRes dto.PostResponse) {
 				newPost := dto.UpdatePostRequest{
-					ID:   requestBody["id"].(uint),
+					ID:   postID,
 					Text: requestBody["text"].(string),
 				}
 				pu.EXPECT().
@@ -548,13 +694,14 @@ func TestUpdatePost(t *testing.T) {
 
 			},
 			checkResponse: func(rec *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, rec.Code)
+				require.Equal(t, http.StatusForbidden, rec.Code)
 			},
 		},
 	}
 
 	e := echo.New()
-	e.Validator = &utils.CustomValidator{Validator: validator.New()}
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Validator = &utils.CustomValidator{Validator: utils.Validator()}
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -585,13 +732,14 @@ func TestUpdatePost(t *testing.T) {
 				user := &jwt.Token{Claims: &dto.JwtCustomClaims{ID: tc.expectedPostRes.UserID}}
 				c.Set("user", user)
 			}
-			if tc.name == "post user id does not match token user id" {
+			if tc.name == "post user id does not match token user id" || tc.name == "body id of own post on another user's post" {
 				user := &jwt.Token{Claims: &dto.JwtCustomClaims{ID: tc.expectedPostRes.UserID + 1}}
 				c.Set("user", user)
 			}
 
-			err = pc.UpdatePost(c)
-			require.NoError(t, err)
+			if err := pc.UpdatePost(c); err != nil {
+				e.HTTPErrorHandler(err, c)
+			}
 
 			tc.checkResponse(rec)
 		})
@@ -685,7 +833,30 @@ func TestDeletePost(t *testing.T) {
 					Times(0)
 			},
 			checkResponse: func(rec *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, rec.Code)
+				require.Equal(t, http.StatusForbidden, rec.Code)
+			},
+		},
+		{
+			name:   "moderator deletes other user's post",
+			postID: utils.RandomInt(1, 100),
+			expectedPostRes: dto.PostResponse{
+				ID:     utils.RandomInt(1, 100),
+				UserID: utils.RandomInt(1, 100),
+				Text:   utils.RandomString(6),
+			},
+			buildStubs: func(pu *mock_usecase.MockIPostUsecase, postID uint, expectedPostRes dto.PostResponse) {
+				pu.EXPECT().
+					GetPostById(context.Background(), postID).
+					Times(1).
+					Return(expectedPostRes, nil)
+
+				pu.EXPECT().
+					DeletePost(context.Background(), postID).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, rec.Code)
 			},
 		},
 		{
@@ -712,7 +883,8 @@ func TestDeletePost(t *testing.T) {
 		},
 	}
 	e := echo.New()
-	e.Validator = &utils.CustomValidator{Validator: validator.New()}
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Validator = &utils.CustomValidator{Validator: utils.Validator()}
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -744,9 +916,14 @@ func TestDeletePost(t *testing.T) {
 				user := &jwt.Token{Claims: &dto.JwtCustomClaims{ID: tc.expectedPostRes.UserID + 1}}
 				c.Set("user", user)
 			}
+			if tc.name == "moderator deletes other user's post" {
+				user := &jwt.Token{Claims: &dto.JwtCustomClaims{ID: tc.expectedPostRes.UserID + 1, Role: dto.RoleModerator}}
+				c.Set("user", user)
+			}
 
-			err = pc.DeletePost(c)
-			require.NoError(t, err)
+			if err := pc.DeletePost(c); err != nil {
+				e.HTTPErrorHandler(err, c)
+			}
 
 			tc.checkResponse(rec)
 		})
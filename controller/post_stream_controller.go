@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/labstack/echo/v4"
+)
+
+// HeaderLastEventID is sent by SSE clients when they reconnect
+const HeaderLastEventID = "Last-Event-ID"
+
+// streamBatch is how many events are read from the log at a time
+const streamBatch = 100
+
+// streamRetry is how long clients wait before reconnecting
+const streamRetry = 3 * time.Second
+
+type IPostStreamController interface {
+	Stream(ctx echo.Context) error
+}
+
+type postStreamController struct {
+	eventUsecase usecase.IPostEventUsecase
+	heartbeat    time.Duration
+	writeTimeout time.Duration
+}
+
+// NewPostStreamController returns a controller streaming the post event log.
+// A comment is sent every heartbeat so proxies keep idle streams open, and a
+// client that does not take a write within writeTimeout is disconnected.
+func NewPostStreamController(eu usecase.IPostEventUsecase, heartbeat, writeTimeout time.Duration) IPostStreamController {
+	return &postStreamController{eu, heartbeat, writeTimeout}
+}
+
+// Stream sends post events as Server-Sent Events. A client resuming with
+// Last-Event-ID (or the last_event_id query parameter) first receives every
+// event it missed; other clients only receive events logged from now on.
+// With the thread query parameter, only the events of that post and of its
+// replies are sent.
+//
+// Events are read from the log at the pace the client takes them, so a slow
+// client only holds up its own stream. The log is read again whenever this
+// process logs an event and at every heartbeat, which also picks up events
+// logged by other instances.
+func (sc *postStreamController) Stream(ctx echo.Context) error {
+	c := ctx.Request().Context()
+
+	lastID, err := lastEventID(ctx)
+	if err != nil {
+		return err
+	}
+	thread, err := threadParam(ctx)
+	if err != nil {
+		return err
+	}
+	if lastID < 0 {
+		if lastID, err = sc.eventUsecase.LastPostEventID(c); err != nil {
+			return err
+		}
+	}
+
+	res := ctx.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	// stop nginx from buffering the stream
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(res)
+	send := func(format string, args ...interface{}) error {
+		// extends the server's WriteTimeout, which would end the stream
+		if err := rc.SetWriteDeadline(time.Now().Add(sc.writeTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		if _, err := fmt.Fprintf(res, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	if err := send("retry: %d\n\n", streamRetry.Milliseconds()); err != nil {
+		return nil
+	}
+
+	heartbeat := time.NewTicker(sc.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		// taken before reading so an event logged meanwhile is not missed
+		wake := sc.eventUsecase.Wait()
+
+		events, err := sc.eventUsecase.ListPostEvents(c, lastID, streamBatch)
+		if err != nil {
+			if c.Err() == nil {
+				slog.ErrorContext(c, "cannot read post events", slog.String("error", err.Error()))
+			}
+			// the status has been sent; the client reconnects and resumes
+			return nil
+		}
+		for _, e := range events {
+			if thread != 0 && !inThread(e, thread) {
+				lastID = e.ID
+				continue
+			}
+			if err := send("id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data); err != nil {
+				return nil
+			}
+			lastID = e.ID
+		}
+		if len(events) == streamBatch {
+			continue
+		}
+
+		select {
+		case <-c.Done():
+			return nil
+		case <-sc.eventUsecase.Done():
+			return nil
+		case <-wake:
+		case <-heartbeat.C:
+			if err := send(": heartbeat\n\n"); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// threadParam returns the post whose thread the client follows, or 0 if it
+// follows every post
+func threadParam(ctx echo.Context) (uint, error) {
+	s := ctx.QueryParam("thread")
+	if s == "" {
+		return 0, nil
+	}
+	thread, err := strconv.ParseUint(s, 10, 0)
+	if err != nil || thread < 1 {
+		return 0, usecase.NewValidationError("thread", "positive_integer", "thread must be a positive integer")
+	}
+	return uint(thread), nil
+}
+
+// inThread reports whether e is an event of the post thread or of one of
+// its replies
+func inThread(e dto.PostEvent, thread uint) bool {
+	if e.PostID == thread {
+		return true
+	}
+	var post struct {
+		ParentID uint `json:"parent_id"`
+	}
+	json.Unmarshal(e.Data, &post)
+	return post.ParentID == thread
+}
+
+// lastEventID returns the id of the last event the client has seen, or -1 if
+// it is not resuming
+func lastEventID(ctx echo.Context) (int64, error) {
+	field, value := HeaderLastEventID, ctx.Request().Header.Get(HeaderLastEventID)
+	if value == "" {
+		field, value = "last_event_id", ctx.QueryParam("last_event_id")
+	}
+	if value == "" {
+		return -1, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, usecase.NewValidationError(field, "integer", field+" must be a non-negative integer")
+	}
+	return id, nil
+}
@@ -0,0 +1,243 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/pubsub"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// sseEvent is one block of a text/event-stream, comments included
+type sseEvent struct {
+	id, event, data, comment string
+}
+
+type streamTest struct {
+	events *usecase.PostEventLog
+	posts  usecase.IPostUsecase
+	user   db.User
+	server *httptest.Server
+}
+
+func newStreamTest(t *testing.T, heartbeat time.Duration) *streamTest {
+	store := memory.NewStore()
+	user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		UserStrID: utils.RandomUserStrID(),
+		Email:     utils.RandomEmail(),
+		Password:  utils.RandomString(60),
+	})
+	require.NoError(t, err)
+
+	publisher, err := usecase.NewEventPublisher(pubsub.NewMemory())
+	require.NoError(t, err)
+	events := usecase.NewPostEventLog(usecase.NewPostUsecase(store), store, publisher)
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.GET("/posts/stream", NewPostStreamController(events, heartbeat, time.Second).Stream)
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+
+	return &streamTest{events: events, posts: events, user: user, server: server}
+}
+
+func (st *streamTest) createPost(t *testing.T) dto.PostResponse {
+	post, err := st.posts.CreatePost(context.Background(), dto.CreatePostRequest{UserID: st.user.ID, Text: utils.RandomString(10)})
+	require.NoError(t, err)
+	return post
+}
+
+// connect opens the stream and reads up to the retry advice, after which
+// the server has decided where the stream starts
+func (st *streamTest) connect(t *testing.T, lastEventID string) *bufio.Reader {
+	return st.connectQuery(t, "", lastEventID)
+}
+
+// connectQuery opens the stream with the query parameters query, as connect
+func (st *streamTest) connectQuery(t *testing.T, query string, lastEventID string) *bufio.Reader {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, st.server.URL+"/posts/stream?"+query, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set(HeaderLastEventID, lastEventID)
+	}
+	res, err := st.server.Client().Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { res.Body.Close() })
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "text/event-stream", res.Header.Get(echo.HeaderContentType))
+
+	r := bufio.NewReader(res.Body)
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "retry: 3000\n", line)
+	_, err = r.ReadString('\n')
+	require.NoError(t, err)
+	return r
+}
+
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	var e sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return e
+		}
+		if comment, ok := strings.CutPrefix(line, ": "); ok {
+			e.comment = comment
+			continue
+		}
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			e.id = value
+		case "event":
+			e.event = value
+		case "data":
+			e.data = value
+		}
+	}
+}
+
+func TestStreamSendsNewEvents(t *testing.T) {
+	st := newStreamTest(t, time.Minute)
+	// logged before connecting, so not sent
+	st.createPost(t)
+
+	r := st.connect(t, "")
+	post := st.createPost(t)
+
+	e := readEvent(t, r)
+	require.Equal(t, "2", e.id)
+	require.Equal(t, usecase.PostCreated, e.event)
+	var got dto.PostResponse
+	require.NoError(t, json.Unmarshal([]byte(e.data), &got))
+	require.Equal(t, post.ID, got.ID)
+	require.Equal(t, post.Text, got.Text)
+}
+
+func TestStreamResumes(t *testing.T) {
+	st := newStreamTest(t, time.Minute)
+	post := st.createPost(t)
+	_, err := st.posts.UpdatePost(context.Background(), dto.UpdatePostRequest{ID: post.ID, Text: "updated"})
+	require.NoError(t, err)
+	require.NoError(t, st.posts.DeletePost(context.Background(), post.ID))
+
+	r := st.connect(t, "1")
+
+	e := readEvent(t, r)
+	require.Equal(t, "2", e.id)
+	require.Equal(t, usecase.PostUpdated, e.event)
+	require.Contains(t, e.data, `"text":"updated"`)
+
+	e = readEvent(t, r)
+	require.Equal(t, "3", e.id)
+	require.Equal(t, usecase.PostDeleted, e.event)
+	require.JSONEq(t, `{"id":`+strconv.Itoa(int(post.ID))+`}`, e.data)
+}
+
+func TestStreamCatchesUpInBatches(t *testing.T) {
+	st := newStreamTest(t, time.Minute)
+	n := streamBatch*2 + 5
+	for i := 0; i < n; i++ {
+		st.createPost(t)
+	}
+
+	r := st.connect(t, "0")
+	for i := 1; i <= n; i++ {
+		require.Equal(t, strconv.Itoa(i), readEvent(t, r).id)
+	}
+}
+
+func TestStreamFiltersThread(t *testing.T) {
+	st := newStreamTest(t, time.Minute)
+	parent := st.createPost(t)
+	other := st.createPost(t)
+
+	r := st.connectQuery(t, "thread="+strconv.Itoa(int(parent.ID)), "0")
+
+	// the other thread and its replies are skipped
+	_, err := st.posts.CreatePost(context.Background(), dto.CreatePostRequest{UserID: st.user.ID, Text: "elsewhere", ParentID: other.ID})
+	require.NoError(t, err)
+	reply, err := st.posts.CreatePost(context.Background(), dto.CreatePostRequest{UserID: st.user.ID, Text: "in thread", ParentID: parent.ID})
+	require.NoError(t, err)
+	_, err = st.posts.UpdatePost(context.Background(), dto.UpdatePostRequest{ID: other.ID, Text: "updated"})
+	require.NoError(t, err)
+	_, err = st.posts.UpdatePost(context.Background(), dto.UpdatePostRequest{ID: parent.ID, Text: "updated"})
+	require.NoError(t, err)
+
+	e := readEvent(t, r)
+	require.Equal(t, "1", e.id)
+	require.Equal(t, usecase.PostCreated, e.event)
+
+	e = readEvent(t, r)
+	require.Equal(t, "4", e.id)
+	require.Equal(t, usecase.PostCreated, e.event)
+	require.Contains(t, e.data, `"id":`+strconv.Itoa(int(reply.ID)))
+
+	e = readEvent(t, r)
+	require.Equal(t, "6", e.id)
+	require.Equal(t, usecase.PostUpdated, e.event)
+	require.Contains(t, e.data, `"id":`+strconv.Itoa(int(parent.ID)))
+
+	require.NoError(t, st.posts.DeletePost(context.Background(), reply.ID))
+	e = readEvent(t, r)
+	require.Equal(t, "7", e.id)
+	require.Equal(t, usecase.PostDeleted, e.event)
+	require.JSONEq(t, fmt.Sprintf(`{"id":%d,"parent_id":%d}`, reply.ID, parent.ID), e.data)
+}
+
+func TestStreamHeartbeat(t *testing.T) {
+	st := newStreamTest(t, 10*time.Millisecond)
+	r := st.connect(t, "")
+
+	require.Equal(t, "heartbeat", readEvent(t, r).comment)
+}
+
+func TestStreamEndsWhenLogCloses(t *testing.T) {
+	st := newStreamTest(t, time.Minute)
+	r := st.connect(t, "")
+
+	st.events.Close()
+	_, err := r.ReadString('\n')
+	require.Error(t, err)
+}
+
+func TestStreamInvalidParams(t *testing.T) {
+	st := newStreamTest(t, time.Minute)
+
+	for _, id := range []string{"abc", "-1"} {
+		req, err := http.NewRequest(http.MethodGet, st.server.URL+"/posts/stream", nil)
+		require.NoError(t, err)
+		req.Header.Set(HeaderLastEventID, id)
+		res, err := st.server.Client().Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusBadRequest, res.StatusCode, id)
+	}
+
+	for _, query := range []string{"last_event_id=x", "thread=0", "thread=x"} {
+		res, err := st.server.Client().Get(st.server.URL + "/posts/stream?" + query)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusBadRequest, res.StatusCode, query)
+	}
+}
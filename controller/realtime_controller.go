@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/i18n"
+	"github.com/PenginAction/go-BulletinBoard/realtime"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+type IRealtimeController interface {
+	Connect(ctx echo.Context) error
+}
+
+type realtimeController struct {
+	hub         *realtime.Hub
+	userUsecase usecase.IUserUsecase
+	upgrader    websocket.Upgrader
+}
+
+// NewRealtimeController returns a controller handing WebSocket connections
+// to hub. Browsers may only connect from allowedOrigin, the front end.
+func NewRealtimeController(hub *realtime.Hub, uu usecase.IUserUsecase, allowedOrigin string) IRealtimeController {
+	return &realtimeController{
+		hub:         hub,
+		userUsecase: uu,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin: func(r *http.Request) bool {
+				// clients other than browsers send no Origin
+				origin := r.Header.Get(echo.HeaderOrigin)
+				return origin == "" || origin == allowedOrigin
+			},
+		},
+	}
+}
+
+// Connect upgrades the request to a WebSocket and serves it until either
+// side closes it
+func (rc *realtimeController) Connect(ctx echo.Context) error {
+	userValue := ctx.Get("user")
+	if userValue == nil {
+		return usecase.ErrUnauthorized
+	}
+	user := userValue.(*jwt.Token)
+	claims := user.Claims.(*dto.JwtCustomClaims)
+
+	c := ctx.Request().Context()
+	users, err := rc.userUsecase.GetUsersByIDs(c, []uint{claims.ID})
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return usecase.ErrUnauthorized
+	}
+
+	trans := i18n.FromAcceptLanguage(ctx.Request().Header.Get("Accept-Language"))
+	conn, err := rc.upgrader.Upgrade(ctx.Response(), ctx.Request(), nil)
+	if err != nil {
+		// the upgrader has responded already
+		return nil
+	}
+	rc.hub.Serve(c, conn, realtime.User{ID: users[0].ID, UserStrID: users[0].UserStrID}, trans)
+	return nil
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/pubsub"
+	"github.com/PenginAction/go-BulletinBoard/realtime"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+const testOrigin = "https://app.example.com"
+
+type realtimeTest struct {
+	posts  usecase.IPostUsecase
+	user   db.User
+	server *httptest.Server
+}
+
+// newRealtimeTest serves /ws to the user whose id is in the user_id query
+// parameter, as the socket auth middleware would after checking the token
+func newRealtimeTest(t *testing.T) *realtimeTest {
+	store := memory.NewStore()
+	user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		UserStrID: utils.RandomUserStrID(),
+		Email:     utils.RandomEmail(),
+		Password:  utils.RandomString(60),
+	})
+	require.NoError(t, err)
+
+	ps := pubsub.NewMemory()
+	publisher, err := usecase.NewEventPublisher(ps)
+	require.NoError(t, err)
+	events := usecase.NewPostEventLog(usecase.NewPostUsecase(store), store, publisher)
+	t.Cleanup(events.Close)
+	hub := realtime.NewHub(ps, events, events)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go hub.Run(ctx)
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	auth := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if id, err := strconv.Atoi(ctx.QueryParam("user_id")); err == nil {
+				ctx.Set("user", &jwt.Token{Claims: &dto.JwtCustomClaims{ID: uint(id)}})
+			}
+			return next(ctx)
+		}
+	}
+	e.GET("/ws", NewRealtimeController(hub, usecase.NewUserUsecase(store), testOrigin).Connect, auth)
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+	t.Cleanup(hub.Close)
+
+	return &realtimeTest{posts: events, user: user, server: server}
+}
+
+func (rt *realtimeTest) dial(userID uint, origin string) (*websocket.Conn, *http.Response, error) {
+	url := "ws" + strings.TrimPrefix(rt.server.URL, "http") + "/ws?user_id=" + strconv.Itoa(int(userID))
+	header := http.Header{}
+	if origin != "" {
+		header.Set(echo.HeaderOrigin, origin)
+	}
+	return websocket.DefaultDialer.Dial(url, header)
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) realtime.Message {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg realtime.Message
+	require.NoError(t, conn.ReadJSON(&msg))
+	return msg
+}
+
+func TestRealtimeConnect(t *testing.T) {
+	rt := newRealtimeTest(t)
+	post, err := rt.posts.CreatePost(context.Background(), dto.CreatePostRequest{UserID: rt.user.ID, Text: utils.RandomString(10)})
+	require.NoError(t, err)
+
+	conn, _, err := rt.dial(rt.user.ID, testOrigin)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	require.NoError(t, conn.WriteJSON(realtime.Message{Type: realtime.TypeSubscribe, Thread: post.ID}))
+	msg := readMessage(t, conn)
+	require.Equal(t, realtime.TypeSubscribed, msg.Type)
+	require.Equal(t, post.ID, msg.Thread)
+	var presence realtime.Presence
+	require.NoError(t, json.Unmarshal(msg.Data, &presence))
+	require.Equal(t, []realtime.User{{ID: rt.user.ID, UserStrID: rt.user.UserStrID}}, presence.Viewers)
+
+	_, err = rt.posts.UpdatePost(context.Background(), dto.UpdatePostRequest{ID: post.ID, Text: "updated"})
+	require.NoError(t, err)
+	for {
+		msg = readMessage(t, conn)
+		// presence may be sent again as the subscription settles
+		if msg.Type != realtime.TypePresence {
+			break
+		}
+	}
+	require.Equal(t, usecase.PostUpdated, msg.Type)
+	require.Equal(t, post.ID, msg.Thread)
+	var got dto.PostResponse
+	require.NoError(t, json.Unmarshal(msg.Data, &got))
+	require.Equal(t, "updated", got.Text)
+}
+
+func TestRealtimeConnectRejected(t *testing.T) {
+	rt := newRealtimeTest(t)
+
+	testCases := []struct {
+		name   string
+		userID uint
+		origin string
+		status int
+	}{
+		{"no user", 0, "", http.StatusUnauthorized},
+		{"unknown user", rt.user.ID + 1, "", http.StatusUnauthorized},
+		{"other origin", rt.user.ID, "https://evil.example.com", http.StatusForbidden},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, res, err := rt.dial(tc.userID, tc.origin)
+			require.ErrorIs(t, err, websocket.ErrBadHandshake)
+			require.Equal(t, tc.status, res.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// defaultTagPostsLimit is how many posts with a tag are listed when the
+	// client does not say
+	defaultTagPostsLimit = 20
+	// defaultTagsLimit is how many tags are suggested or trending when the
+	// client does not say
+	defaultTagsLimit = 10
+	// defaultTrendingWindow is how far back trending tags are counted when
+	// the client does not say
+	defaultTrendingWindow = 24 * time.Hour
+)
+
+type ITagController interface {
+	ListPosts(ctx echo.Context) error
+	Autocomplete(ctx echo.Context) error
+	Trending(ctx echo.Context) error
+}
+
+type tagController struct {
+	tagUsecase usecase.ITagUsecase
+}
+
+func NewTagController(tu usecase.ITagUsecase) ITagController {
+	return &tagController{tu}
+}
+
+func (tc *tagController) ListPosts(ctx echo.Context) error {
+	req := dto.ListTagPostsRequest{Tag: ctx.Param("tag")}
+	if s := ctx.QueryParam("before"); s != "" {
+		before, err := strconv.ParseUint(s, 10, 63)
+		if err != nil || before < 1 {
+			return usecase.NewValidationError("before", "positive_integer", "before must be a positive integer")
+		}
+		req.Before = uint(before)
+	}
+	var err error
+	if req.Limit, err = limitParam(ctx, defaultTagPostsLimit); err != nil {
+		return err
+	}
+
+	posts, err := tc.tagUsecase.ListPostsByTag(ctx.Request().Context(), req)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, posts)
+}
+
+func (tc *tagController) Autocomplete(ctx echo.Context) error {
+	limit, err := limitParam(ctx, defaultTagsLimit)
+	if err != nil {
+		return err
+	}
+
+	tags, err := tc.tagUsecase.SearchTags(ctx.Request().Context(), dto.SearchTagsRequest{
+		Prefix: ctx.QueryParam("prefix"),
+		Limit:  limit,
+	})
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, tags)
+}
+
+func (tc *tagController) Trending(ctx echo.Context) error {
+	req := dto.TrendingTagsRequest{Window: defaultTrendingWindow}
+	if s := ctx.QueryParam("window"); s != "" {
+		window, err := time.ParseDuration(s)
+		if err != nil {
+			return usecase.NewValidationError("window", "duration", "window must be a duration, such as 24h")
+		}
+		req.Window = window
+	}
+	var err error
+	if req.Limit, err = limitParam(ctx, defaultTagsLimit); err != nil {
+		return err
+	}
+
+	tags, err := tc.tagUsecase.TrendingTags(ctx.Request().Context(), req)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, tags)
+}
+
+// limitParam returns the limit query parameter, or def if there is none
+func limitParam(ctx echo.Context, def int32) (int32, error) {
+	s := ctx.QueryParam("limit")
+	if s == "" {
+		return def, nil
+	}
+	limit, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return 0, usecase.NewValidationError("limit", "integer", "limit must be an integer")
+	}
+	return int32(limit), nil
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	mock_usecase "github.com/PenginAction/go-BulletinBoard/usecase/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListTagPosts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	tu := mock_usecase.NewMockITagUsecase(ctrl)
+	tc := NewTagController(tu)
+
+	tu.EXPECT().
+		ListPostsByTag(gomock.Any(), dto.ListTagPostsRequest{Tag: "go", Limit: defaultTagPostsLimit}).
+		Return([]dto.PostResponse{{ID: 2, Tags: []string{"go"}}, {ID: 1, Tags: []string{"go"}}}, nil)
+	rec := webhookRequest(t, tc.ListPosts, http.MethodGet, "/tags/go/posts", nil, 3, "tag", "go")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var res []dto.PostResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Len(t, res, 2)
+
+	tu.EXPECT().
+		ListPostsByTag(gomock.Any(), dto.ListTagPostsRequest{Tag: "go", Before: 2, Limit: 1}).
+		Return([]dto.PostResponse{{ID: 1}}, nil)
+	rec = webhookRequest(t, tc.ListPosts, http.MethodGet, "/tags/go/posts?before=2&limit=1", nil, 3, "tag", "go")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = webhookRequest(t, tc.ListPosts, http.MethodGet, "/tags/go/posts?before=x", nil, 3, "tag", "go")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	tu.EXPECT().
+		ListPostsByTag(gomock.Any(), dto.ListTagPostsRequest{Tag: "a-b", Limit: defaultTagPostsLimit}).
+		Return([]dto.PostResponse{}, usecase.NewValidationError("tag", "invalid_tag", "invalid"))
+	rec = webhookRequest(t, tc.ListPosts, http.MethodGet, "/tags/a-b/posts", nil, 3, "tag", "a-b")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestAutocompleteTags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	tu := mock_usecase.NewMockITagUsecase(ctrl)
+	tc := NewTagController(tu)
+
+	tu.EXPECT().
+		SearchTags(gomock.Any(), dto.SearchTagsRequest{Prefix: "go", Limit: defaultTagsLimit}).
+		Return([]dto.TagResponse{{Name: "golang", PostCount: 2}}, nil)
+	rec := webhookRequest(t, tc.Autocomplete, http.MethodGet, "/tags/autocomplete?prefix=go", nil, 3)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `[{"name":"golang","post_count":2}]`, rec.Body.String())
+
+	rec = webhookRequest(t, tc.Autocomplete, http.MethodGet, "/tags/autocomplete?prefix=go&limit=x", nil, 3)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestTrendingTags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	tu := mock_usecase.NewMockITagUsecase(ctrl)
+	tc := NewTagController(tu)
+
+	tu.EXPECT().
+		TrendingTags(gomock.Any(), dto.TrendingTagsRequest{Window: defaultTrendingWindow, Limit: defaultTagsLimit}).
+		Return([]dto.TagResponse{{Name: "go", PostCount: 3}}, nil)
+	rec := webhookRequest(t, tc.Trending, http.MethodGet, "/tags/trending", nil, 3)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	tu.EXPECT().
+		TrendingTags(gomock.Any(), dto.TrendingTagsRequest{Window: 90 * time.Minute, Limit: 5}).
+		Return([]dto.TagResponse{}, nil)
+	rec = webhookRequest(t, tc.Trending, http.MethodGet, "/tags/trending?window=1h30m&limit=5", nil, 3)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `[]`, rec.Body.String())
+
+	rec = webhookRequest(t, tc.Trending, http.MethodGet, "/tags/trending?window=day", nil, 3)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
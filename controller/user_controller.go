@@ -5,12 +5,14 @@ import (
 
 	"github.com/PenginAction/go-BulletinBoard/dto"
 	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 )
 
 type IUserController interface {
 	Signup(ctx echo.Context) error
 	Login(ctx echo.Context) error
+	Authenticate(next echo.HandlerFunc) echo.HandlerFunc
 }
 
 type userController struct {
@@ -24,17 +26,17 @@ func NewUserController(userUsecase usecase.IUserUsecase) IUserController {
 func (uc *userController) Signup(ctx echo.Context) error {
 	var req dto.CreateUserRequest
 	if err := ctx.Bind(&req); err != nil {
-		return ctx.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
 
 	if err := ctx.Validate(req); err != nil {
-		return ctx.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
 
 	c := ctx.Request().Context()
 	userRes, err := uc.userUsecase.SignUp(c, req)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	return ctx.JSON(http.StatusCreated, userRes)
@@ -43,18 +45,43 @@ func (uc *userController) Signup(ctx echo.Context) error {
 func (uc *userController) Login(ctx echo.Context) error {
 	var req dto.LoginRequest
 	if err := ctx.Bind(&req); err != nil {
-		return ctx.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
 	c := ctx.Request().Context()
 	if err := ctx.Validate(req); err != nil {
-		return ctx.JSON(http.StatusBadRequest, err.Error())
+		return err
 	}
 	t, err := uc.userUsecase.Login(c, req)
 	if err != nil {
-		return ctx.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 
-	return ctx.JSON(http.StatusOK, echo.Map{
-		"token": t,
-	})
+	return ctx.JSON(http.StatusOK, dto.LoginResponse{Token: t})
+}
+
+// Authenticate runs after the JWT middleware and rejects tokens of users that
+// have been deleted, locked or had their tokens revoked
+func (uc *userController) Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		user, ok := ctx.Get("user").(*jwt.Token)
+		if !ok {
+			return usecase.ErrUnauthorized
+		}
+		claims, ok := user.Claims.(*dto.JwtCustomClaims)
+		if !ok {
+			return usecase.ErrUnauthorized
+		}
+
+		c := ctx.Request().Context()
+		if err := uc.userUsecase.Authenticate(c, claims); err != nil {
+			// a locked user is told only that the token no longer works;
+			// anything that is not a domain error is an outage, not a 401
+			if e, ok := usecase.AsError(err); ok && e.Kind != usecase.KindUnauthorized {
+				return usecase.ErrUnauthorized.With(err)
+			}
+			return err
+		}
+
+		return next(ctx)
+	}
 }
@@ -10,9 +10,10 @@ import (
 	"testing"
 
 	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
 	mock_usecase "github.com/PenginAction/go-BulletinBoard/usecase/mock"
 	"github.com/PenginAction/go-BulletinBoard/utils"
-	"github.com/go-playground/validator"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/require"
@@ -151,6 +152,31 @@ func TestSignUp(t *testing.T) {
 				require.Equal(t, http.StatusBadRequest, rec.Code)
 			},
 		},
+		{
+			name: "email already taken",
+			requestBody: map[string]interface{}{
+				"user_str_id": utils.RandomUserStrID(),
+				"email":       utils.RandomEmail(),
+				"password":    utils.RandomString(6),
+			},
+			buildStubs: func(uu *mock_usecase.MockIUserUsecase) {
+				err := usecase.ErrAlreadyExists.With(errors.New("duplicate key"))
+				err.Fields = []dto.FieldError{{Field: "email", Code: "taken", Message: "is already taken"}}
+				uu.EXPECT().
+					SignUp(context.Background(), gomock.Any()).
+					Times(1).
+					Return(dto.CreateUserResponse{}, err)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, rec.Code)
+
+				var problem dto.Problem
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+				require.Equal(t, "already_exists", problem.Code)
+				require.Equal(t, []dto.FieldError{{Field: "email", Code: "taken", Message: "email is already taken"}}, problem.Errors)
+				require.NotContains(t, rec.Body.String(), "duplicate key")
+			},
+		},
 		{
 			name: "internal server error",
 			requestBody: map[string]interface{}{
@@ -186,7 +212,8 @@ func TestSignUp(t *testing.T) {
 	}
 
 	e := echo.New()
-	e.Validator = &utils.CustomValidator{Validator: validator.New()}
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Validator = &utils.CustomValidator{Validator: utils.Validator()}
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -207,8 +234,9 @@ func TestSignUp(t *testing.T) {
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
 
-			err = uc.Signup(c)
-			require.NoError(t, err)
+			if err := uc.Signup(c); err != nil {
+				e.HTTPErrorHandler(err, c)
+			}
 
 			tc.checkResponse(rec)
 		})
@@ -299,6 +327,38 @@ func TestLogin(t *testing.T) {
 				require.Equal(t, http.StatusBadRequest, rec.Code)
 			},
 		},
+		{
+			name: "invalid credentials",
+			requestBody: map[string]interface{}{
+				"email":    utils.RandomEmail(),
+				"password": utils.RandomString(6),
+			},
+			buildStubs: func(uu *mock_usecase.MockIUserUsecase) {
+				uu.EXPECT().
+					Login(context.Background(), gomock.Any()).
+					Times(1).
+					Return("", usecase.ErrInvalidCredentials)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name: "locked user",
+			requestBody: map[string]interface{}{
+				"email":    utils.RandomEmail(),
+				"password": utils.RandomString(6),
+			},
+			buildStubs: func(uu *mock_usecase.MockIUserUsecase) {
+				uu.EXPECT().
+					Login(context.Background(), gomock.Any()).
+					Times(1).
+					Return("", usecase.ErrUserLocked)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, rec.Code)
+			},
+		},
 		{
 			name: "internal server error",
 			requestBody: map[string]interface{}{
@@ -318,7 +378,8 @@ func TestLogin(t *testing.T) {
 	}
 
 	e := echo.New()
-	e.Validator = &utils.CustomValidator{Validator: validator.New()}
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Validator = &utils.CustomValidator{Validator: utils.Validator()}
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -338,11 +399,104 @@ func TestLogin(t *testing.T) {
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
 
-			err = uc.Login(c)
-			require.NoError(t, err)
+			if err := uc.Login(c); err != nil {
+				e.HTTPErrorHandler(err, c)
+			}
 
 			tc.checkResponse(rec)
 		})
 	}
 
 }
+
+func TestAuthenticate(t *testing.T) {
+	cases := []struct {
+		name          string
+		token         *jwt.Token
+		buildStubs    func(uu *mock_usecase.MockIUserUsecase)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "valid token",
+			token: &jwt.Token{Claims: &dto.JwtCustomClaims{ID: utils.RandomInt(1, 100)}},
+			buildStubs: func(uu *mock_usecase.MockIUserUsecase) {
+				uu.EXPECT().
+					Authenticate(context.Background(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, rec.Code)
+			},
+		},
+		{
+			name:  "no user info",
+			token: nil,
+			buildStubs: func(uu *mock_usecase.MockIUserUsecase) {
+				uu.EXPECT().
+					Authenticate(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:  "locked user",
+			token: &jwt.Token{Claims: &dto.JwtCustomClaims{ID: utils.RandomInt(1, 100)}},
+			buildStubs: func(uu *mock_usecase.MockIUserUsecase) {
+				uu.EXPECT().
+					Authenticate(context.Background(), gomock.Any()).
+					Times(1).
+					Return(usecase.ErrUserLocked)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+		{
+			name:  "revoked token",
+			token: &jwt.Token{Claims: &dto.JwtCustomClaims{ID: utils.RandomInt(1, 100)}},
+			buildStubs: func(uu *mock_usecase.MockIUserUsecase) {
+				uu.EXPECT().
+					Authenticate(context.Background(), gomock.Any()).
+					Times(1).
+					Return(usecase.ErrTokenRevoked)
+			},
+			checkResponse: func(rec *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, rec.Code)
+			},
+		},
+	}
+
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uu := mock_usecase.NewMockIUserUsecase(ctrl)
+	uc := NewUserController(uu)
+	handler := uc.Authenticate(func(ctx echo.Context) error {
+		return ctx.NoContent(http.StatusOK)
+	})
+
+	for i := range cases {
+		tc := cases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			tc.buildStubs(uu)
+
+			req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			if tc.token != nil {
+				c.Set("user", tc.token)
+			}
+
+			if err := handler(c); err != nil {
+				e.HTTPErrorHandler(err, c)
+			}
+
+			tc.checkResponse(rec)
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// defaultDeliveriesLimit is how many deliveries are listed when the client
+// does not say
+const defaultDeliveriesLimit = 20
+
+type IWebhookController interface {
+	ListWebhooks(ctx echo.Context) error
+	CreateWebhook(ctx echo.Context) error
+	GetWebhook(ctx echo.Context) error
+	UpdateWebhook(ctx echo.Context) error
+	DeleteWebhook(ctx echo.Context) error
+	ListDeliveries(ctx echo.Context) error
+	Redeliver(ctx echo.Context) error
+}
+
+type webhookController struct {
+	webhookUsecase usecase.IWebhookUsecase
+}
+
+func NewWebhookController(wu usecase.IWebhookUsecase) IWebhookController {
+	return &webhookController{wu}
+}
+
+func (wc *webhookController) ListWebhooks(ctx echo.Context) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	webhooks, err := wc.webhookUsecase.ListWebhooks(ctx.Request().Context(), userID)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, webhooks)
+}
+
+func (wc *webhookController) CreateWebhook(ctx echo.Context) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var req dto.CreateWebhookRequest
+	if err := ctx.Bind(&req); err != nil {
+		return err
+	}
+	req.UserID = userID
+	if err := ctx.Validate(req); err != nil {
+		return err
+	}
+
+	webhook, err := wc.webhookUsecase.CreateWebhook(ctx.Request().Context(), req)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusCreated, webhook)
+}
+
+func (wc *webhookController) GetWebhook(ctx echo.Context) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	id, err := idParam(ctx, "webhookId")
+	if err != nil {
+		return err
+	}
+
+	webhook, err := wc.webhookUsecase.GetWebhook(ctx.Request().Context(), userID, id)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, webhook)
+}
+
+func (wc *webhookController) UpdateWebhook(ctx echo.Context) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	id, err := idParam(ctx, "webhookId")
+	if err != nil {
+		return err
+	}
+
+	var req dto.UpdateWebhookRequest
+	if err := ctx.Bind(&req); err != nil {
+		return err
+	}
+	req.ID = id
+	req.UserID = userID
+	if err := ctx.Validate(req); err != nil {
+		return err
+	}
+
+	webhook, err := wc.webhookUsecase.UpdateWebhook(ctx.Request().Context(), req)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, webhook)
+}
+
+func (wc *webhookController) DeleteWebhook(ctx echo.Context) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	id, err := idParam(ctx, "webhookId")
+	if err != nil {
+		return err
+	}
+
+	if err := wc.webhookUsecase.DeleteWebhook(ctx.Request().Context(), userID, id); err != nil {
+		return err
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (wc *webhookController) ListDeliveries(ctx echo.Context) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	id, err := idParam(ctx, "webhookId")
+	if err != nil {
+		return err
+	}
+
+	req := dto.ListWebhookDeliveriesRequest{
+		WebhookID: id,
+		UserID:    userID,
+		Limit:     defaultDeliveriesLimit,
+	}
+	if s := ctx.QueryParam("before"); s != "" {
+		before, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || before < 1 {
+			return usecase.NewValidationError("before", "positive_integer", "before must be a positive integer")
+		}
+		req.Before = before
+	}
+	if s := ctx.QueryParam("limit"); s != "" {
+		limit, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return usecase.NewValidationError("limit", "integer", "limit must be an integer")
+		}
+		req.Limit = int32(limit)
+	}
+
+	deliveries, err := wc.webhookUsecase.ListWebhookDeliveries(ctx.Request().Context(), req)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, deliveries)
+}
+
+func (wc *webhookController) Redeliver(ctx echo.Context) error {
+	userID, err := currentUserID(ctx)
+	if err != nil {
+		return err
+	}
+	id, err := idParam(ctx, "webhookId")
+	if err != nil {
+		return err
+	}
+	deliveryID, err := idParam(ctx, "deliveryId")
+	if err != nil {
+		return err
+	}
+
+	delivery, err := wc.webhookUsecase.Redeliver(ctx.Request().Context(), userID, id, deliveryID)
+	if err != nil {
+		return err
+	}
+	// queued, not yet delivered
+	return ctx.JSON(http.StatusAccepted, delivery)
+}
+
+// currentUserID returns the id of the user the request is authenticated as
+func currentUserID(ctx echo.Context) (uint, error) {
+	userValue := ctx.Get("user")
+	if userValue == nil {
+		return 0, usecase.ErrUnauthorized
+	}
+	user := userValue.(*jwt.Token)
+	claims := user.Claims.(*dto.JwtCustomClaims)
+	return claims.ID, nil
+}
+
+// idParam parses a path parameter holding a bigserial id
+func idParam(ctx echo.Context, name string) (int64, error) {
+	id, err := strconv.ParseInt(ctx.Param(name), 10, 64)
+	if err != nil || id < 1 {
+		return 0, usecase.NewValidationError(name, "positive_integer", name+" must be a positive integer")
+	}
+	return id, nil
+}
@@ -0,0 +1,226 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	mock_usecase "github.com/PenginAction/go-BulletinBoard/usecase/mock"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookRequest runs handler on a request of the user with id userID, or an
+// anonymous one if it is 0. params are path parameter names and values.
+func webhookRequest(t *testing.T, handler echo.HandlerFunc, method, target string, body interface{}, userID uint, params ...string) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Validator = &utils.CustomValidator{Validator: utils.Validator()}
+
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		require.NoError(t, err)
+	}
+	req := httptest.NewRequest(method, target, bytes.NewReader(data))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if userID != 0 {
+		c.Set("user", &jwt.Token{Claims: &dto.JwtCustomClaims{ID: userID}})
+	}
+	var names, values []string
+	for i := 0; i+1 < len(params); i += 2 {
+		names = append(names, params[i])
+		values = append(values, params[i+1])
+	}
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+
+	if err := handler(c); err != nil {
+		e.HTTPErrorHandler(err, c)
+	}
+	return rec
+}
+
+func TestCreateWebhook(t *testing.T) {
+	userID := utils.RandomInt(1, 100)
+	valid := map[string]interface{}{
+		"url":    "https://example.com/hook",
+		"events": []string{usecase.PostCreated},
+	}
+	cases := []struct {
+		name       string
+		userID     uint
+		body       interface{}
+		buildStubs func(wu *mock_usecase.MockIWebhookUsecase)
+		status     int
+	}{
+		{
+			name:   "valid request",
+			userID: userID,
+			body:   valid,
+			buildStubs: func(wu *mock_usecase.MockIWebhookUsecase) {
+				wu.EXPECT().
+					CreateWebhook(gomock.Any(), dto.CreateWebhookRequest{
+						UserID: userID,
+						URL:    "https://example.com/hook",
+						Events: []string{usecase.PostCreated},
+					}).
+					Return(dto.WebhookResponse{ID: 1, Secret: "s"}, nil)
+			},
+			status: http.StatusCreated,
+		},
+		{
+			name:       "no user info",
+			body:       valid,
+			buildStubs: func(wu *mock_usecase.MockIWebhookUsecase) {},
+			status:     http.StatusUnauthorized,
+		},
+		{
+			name:       "no events",
+			userID:     userID,
+			body:       map[string]interface{}{"url": "https://example.com/hook", "events": []string{}},
+			buildStubs: func(wu *mock_usecase.MockIWebhookUsecase) {},
+			status:     http.StatusBadRequest,
+		},
+		{
+			name:       "not a url",
+			userID:     userID,
+			body:       map[string]interface{}{"url": "example", "events": []string{usecase.PostCreated}},
+			buildStubs: func(wu *mock_usecase.MockIWebhookUsecase) {},
+			status:     http.StatusBadRequest,
+		},
+		{
+			name:   "admin only event",
+			userID: userID,
+			body:   valid,
+			buildStubs: func(wu *mock_usecase.MockIWebhookUsecase) {
+				wu.EXPECT().
+					CreateWebhook(gomock.Any(), gomock.Any()).
+					Return(dto.WebhookResponse{}, usecase.NewValidationError("events", "admin_only", "events names an event only admins may subscribe to"))
+			},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			wu := mock_usecase.NewMockIWebhookUsecase(ctrl)
+			tc.buildStubs(wu)
+
+			rec := webhookRequest(t, NewWebhookController(wu).CreateWebhook, http.MethodPost, "/webhooks", tc.body, tc.userID)
+			require.Equal(t, tc.status, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestGetWebhook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	wu := mock_usecase.NewMockIWebhookUsecase(ctrl)
+	wc := NewWebhookController(wu)
+
+	wu.EXPECT().GetWebhook(gomock.Any(), uint(3), int64(7)).Return(dto.WebhookResponse{ID: 7, Active: true}, nil)
+	rec := webhookRequest(t, wc.GetWebhook, http.MethodGet, "/webhooks/7", nil, 3, "webhookId", "7")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var res dto.WebhookResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.EqualValues(t, 7, res.ID)
+
+	wu.EXPECT().GetWebhook(gomock.Any(), uint(3), int64(8)).Return(dto.WebhookResponse{}, usecase.ErrWebhookNotFound)
+	rec = webhookRequest(t, wc.GetWebhook, http.MethodGet, "/webhooks/8", nil, 3, "webhookId", "8")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = webhookRequest(t, wc.GetWebhook, http.MethodGet, "/webhooks/x", nil, 3, "webhookId", "x")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	wu := mock_usecase.NewMockIWebhookUsecase(ctrl)
+	wc := NewWebhookController(wu)
+
+	active := true
+	wu.EXPECT().
+		UpdateWebhook(gomock.Any(), dto.UpdateWebhookRequest{
+			ID:     7,
+			UserID: 3,
+			URL:    "https://example.com/hook",
+			Events: []string{usecase.PostDeleted},
+			Active: &active,
+		}).
+		Return(dto.WebhookResponse{ID: 7, Active: true}, nil)
+	body := map[string]interface{}{
+		"url":    "https://example.com/hook",
+		"events": []string{usecase.PostDeleted},
+		"active": true,
+	}
+	rec := webhookRequest(t, wc.UpdateWebhook, http.MethodPut, "/webhooks/7", body, 3, "webhookId", "7")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	wu := mock_usecase.NewMockIWebhookUsecase(ctrl)
+	wc := NewWebhookController(wu)
+
+	wu.EXPECT().DeleteWebhook(gomock.Any(), uint(3), int64(7)).Return(nil)
+	rec := webhookRequest(t, wc.DeleteWebhook, http.MethodDelete, "/webhooks/7", nil, 3, "webhookId", "7")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	wu.EXPECT().DeleteWebhook(gomock.Any(), uint(3), int64(7)).Return(errors.New("db down"))
+	rec = webhookRequest(t, wc.DeleteWebhook, http.MethodDelete, "/webhooks/7", nil, 3, "webhookId", "7")
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestListDeliveries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	wu := mock_usecase.NewMockIWebhookUsecase(ctrl)
+	wc := NewWebhookController(wu)
+
+	wu.EXPECT().
+		ListWebhookDeliveries(gomock.Any(), dto.ListWebhookDeliveriesRequest{WebhookID: 7, UserID: 3, Limit: defaultDeliveriesLimit}).
+		Return([]dto.WebhookDeliveryResponse{{ID: 2}, {ID: 1}}, nil)
+	rec := webhookRequest(t, wc.ListDeliveries, http.MethodGet, "/webhooks/7/deliveries", nil, 3, "webhookId", "7")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var res []dto.WebhookDeliveryResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	require.Len(t, res, 2)
+
+	wu.EXPECT().
+		ListWebhookDeliveries(gomock.Any(), dto.ListWebhookDeliveriesRequest{WebhookID: 7, UserID: 3, Before: 2, Limit: 1}).
+		Return([]dto.WebhookDeliveryResponse{{ID: 1}}, nil)
+	rec = webhookRequest(t, wc.ListDeliveries, http.MethodGet, "/webhooks/7/deliveries?before=2&limit=1", nil, 3, "webhookId", "7")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = webhookRequest(t, wc.ListDeliveries, http.MethodGet, "/webhooks/7/deliveries?before=-1", nil, 3, "webhookId", "7")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestRedeliver(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	wu := mock_usecase.NewMockIWebhookUsecase(ctrl)
+	wc := NewWebhookController(wu)
+
+	wu.EXPECT().Redeliver(gomock.Any(), uint(3), int64(7), int64(9)).Return(dto.WebhookDeliveryResponse{ID: 10, Status: usecase.DeliveryPending}, nil)
+	rec := webhookRequest(t, wc.Redeliver, http.MethodPost, "/webhooks/7/deliveries/9/redeliver", nil, 3, "webhookId", "7", "deliveryId", "9")
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	wu.EXPECT().Redeliver(gomock.Any(), uint(3), int64(7), int64(9)).Return(dto.WebhookDeliveryResponse{}, usecase.ErrWebhookDisabled)
+	rec = webhookRequest(t, wc.Redeliver, http.MethodPost, "/webhooks/7/deliveries/9/redeliver", nil, 3, "webhookId", "7", "deliveryId", "9")
+	require.Equal(t, http.StatusConflict, rec.Code)
+
+	wu.EXPECT().Redeliver(gomock.Any(), uint(3), int64(7), int64(9)).Return(dto.WebhookDeliveryResponse{}, usecase.ErrDeliveryNotFound)
+	rec = webhookRequest(t, wc.Redeliver, http.MethodPost, "/webhooks/7/deliveries/9/redeliver", nil, 3, "webhookId", "7", "deliveryId", "9")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,1356 @@
+// Package memory provides an in-memory implementation of db.Store.
+//
+// It is meant for local development (DB_DRIVER=memory) and fast tests. It
+// mirrors the behaviour of the Postgres schema closely enough that callers
+// cannot tell the difference: ids are assigned sequentially, uniqueness and
+// foreign keys are enforced and reported as *pq.Error with the same codes and
+// constraint names Postgres uses.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/lib/pq"
+)
+
+// Store is a thread-safe in-memory db.Store
+type Store struct {
+	mu sync.RWMutex
+	data
+	now func() time.Time
+	// inTx is set on the copies ExecTx hands to its callback
+	inTx bool
+}
+
+// data is what a Store holds, and a transaction copies
+type data struct {
+	users       map[uint]db.User
+	userByEmail map[string]uint
+	userByStrID map[string]uint
+	posts       map[uint]db.Post
+	// postEvents is append only, so its ids are sorted
+	postEvents []db.PostEvent
+	webhooks   map[int64]db.Webhook
+	deliveries map[int64]db.WebhookDelivery
+	jobs       map[int64]db.Job
+	jobByKey   map[string]int64
+	// notifications are unique by notificationKey
+	notifications     map[int64]db.Notification
+	notificationByKey map[notificationKey]int64
+	postMentions      map[postMentionKey]db.PostMention
+	tags              map[int64]db.Tag
+	tagByName         map[string]int64
+	postTags          map[db.PostTag]bool
+	lastUserID        uint
+	lastPostID        uint
+	// lastWebhookID, lastDeliveryID, lastJobID, lastNotificationID and
+	// lastTagID are bigserial, so int64
+	lastWebhookID      int64
+	lastDeliveryID     int64
+	lastJobID          int64
+	lastNotificationID int64
+	lastTagID          int64
+}
+
+// postMentionKey is the primary key of post mentions
+type postMentionKey struct {
+	postID      uint
+	startOffset int32
+}
+
+type notificationKey struct {
+	userID uint
+	typ    string
+	postID uint
+}
+
+var _ db.Store = (*Store)(nil)
+
+// NewStore returns an empty in-memory store
+func NewStore() db.Store {
+	return &Store{
+		data: data{
+			users:       map[uint]db.User{},
+			userByEmail: map[string]uint{},
+			userByStrID: map[string]uint{},
+			posts:       map[uint]db.Post{},
+			webhooks:    map[int64]db.Webhook{},
+			deliveries:  map[int64]db.WebhookDelivery{},
+			jobs:        map[int64]db.Job{},
+			jobByKey:    map[string]int64{},
+
+			notifications:     map[int64]db.Notification{},
+			notificationByKey: map[notificationKey]int64{},
+			postMentions:      map[postMentionKey]db.PostMention{},
+			tags:              map[int64]db.Tag{},
+			tagByName:         map[string]int64{},
+			postTags:          map[db.PostTag]bool{},
+		},
+		now: func() time.Time {
+			// timestamptz has microsecond precision
+			return time.Now().Truncate(time.Microsecond)
+		},
+	}
+}
+
+// ExecTx runs fn on a copy of the data, which replaces the data once fn
+// returns nil. The store stays locked meanwhile, so transactions are
+// serializable and see no other writes.
+func (s *Store) ExecTx(ctx context.Context, fn func(db.Store) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &Store{data: s.data.clone(), now: s.now, inTx: true}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.data = tx.data
+	return nil
+}
+
+// clone returns a deep copy of d. Rows are values, so copying the maps is
+// enough.
+func (d data) clone() data {
+	c := d
+	c.users = maps.Clone(d.users)
+	c.userByEmail = maps.Clone(d.userByEmail)
+	c.userByStrID = maps.Clone(d.userByStrID)
+	c.posts = maps.Clone(d.posts)
+	c.postEvents = slices.Clone(d.postEvents)
+	c.webhooks = maps.Clone(d.webhooks)
+	c.deliveries = maps.Clone(d.deliveries)
+	c.jobs = maps.Clone(d.jobs)
+	c.jobByKey = maps.Clone(d.jobByKey)
+	c.notifications = maps.Clone(d.notifications)
+	c.notificationByKey = maps.Clone(d.notificationByKey)
+	c.postMentions = maps.Clone(d.postMentions)
+	c.tags = maps.Clone(d.tags)
+	c.tagByName = maps.Clone(d.tagByName)
+	c.postTags = maps.Clone(d.postTags)
+	return c
+}
+
+func (s *Store) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkUserUnique(0, arg.UserStrID, arg.Email); err != nil {
+		return db.User{}, err
+	}
+
+	s.lastUserID++
+	user := db.User{
+		ID:        s.lastUserID,
+		UserStrID: arg.UserStrID,
+		Email:     arg.Email,
+		Password:  arg.Password,
+		CreatedAt: s.now(),
+		Role:      "user",
+	}
+	s.users[user.ID] = user
+	s.userByEmail[user.Email] = user.ID
+	s.userByStrID[user.UserStrID] = user.ID
+	return user, nil
+}
+
+func (s *Store) GetUser(ctx context.Context, id uint) (db.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return db.User{}, db.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.userByEmail[email]
+	if !ok {
+		return db.User{}, db.ErrRecordNotFound
+	}
+	return s.users[id], nil
+}
+
+func (s *Store) GetUserByUserStrId(ctx context.Context, userStrID string) (db.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.userByStrID[userStrID]
+	if !ok {
+		return db.User{}, db.ErrRecordNotFound
+	}
+	return s.users[id], nil
+}
+
+func (s *Store) GetUserStrIdById(ctx context.Context, id uint) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return "", db.ErrRecordNotFound
+	}
+	return user.UserStrID, nil
+}
+
+func (s *Store) ListReplies(ctx context.Context, arg db.ListRepliesParams) ([]db.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := checkPage(arg.Limit, arg.Offset); err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	for _, id := range sortedKeys(s.posts) {
+		if parent := s.posts[id].ParentID; parent.Valid && parent == arg.ParentID {
+			ids = append(ids, id)
+		}
+	}
+	items := []db.Post{}
+	for _, id := range page(ids, arg.Limit, arg.Offset) {
+		items = append(items, s.posts[id])
+	}
+	return items, nil
+}
+
+func (s *Store) ListRepliesByParentIDs(ctx context.Context, arg db.ListRepliesByParentIDsParams) ([]db.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := checkPage(arg.Limit, arg.Offset); err != nil {
+		return nil, err
+	}
+
+	replies := map[int64][]uint{}
+	for _, id := range sortedKeys(s.posts) {
+		if parent := s.posts[id].ParentID; parent.Valid {
+			replies[parent.Int64] = append(replies[parent.Int64], id)
+		}
+	}
+	parentIDs := slices.Clone(arg.ParentIds)
+	slices.Sort(parentIDs)
+	items := []db.Post{}
+	for _, parentID := range slices.Compact(parentIDs) {
+		for _, id := range page(replies[parentID], arg.Limit, arg.Offset) {
+			items = append(items, s.posts[id])
+		}
+	}
+	return items, nil
+}
+
+func (s *Store) ListUsers(ctx context.Context, arg db.ListUsersParams) ([]db.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := checkPage(arg.Limit, arg.Offset); err != nil {
+		return nil, err
+	}
+
+	ids := sortedKeys(s.users)
+	items := []db.User{}
+	for _, id := range page(ids, arg.Limit, arg.Offset) {
+		items = append(items, s.users[id])
+	}
+	return items, nil
+}
+
+func (s *Store) ListUsersByIDs(ctx context.Context, ids []int64) ([]db.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := map[uint]bool{}
+	items := []db.User{}
+	for _, id := range ids {
+		user, ok := s.users[uint(id)]
+		if !ok || seen[user.ID] {
+			continue
+		}
+		seen[user.ID] = true
+		items = append(items, user)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items, nil
+}
+
+func (s *Store) UpdateUser(ctx context.Context, arg db.UpdateUserParams) (db.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[arg.ID]
+	if !ok {
+		return db.User{}, db.ErrRecordNotFound
+	}
+	if err := s.checkUserUnique(user.ID, arg.UserStrID, arg.Email); err != nil {
+		return db.User{}, err
+	}
+
+	delete(s.userByEmail, user.Email)
+	delete(s.userByStrID, user.UserStrID)
+	user.UserStrID = arg.UserStrID
+	user.Email = arg.Email
+	user.Password = arg.Password
+	s.users[user.ID] = user
+	s.userByEmail[user.Email] = user.ID
+	s.userByStrID[user.UserStrID] = user.ID
+	return user, nil
+}
+
+func (s *Store) UpdateUserRole(ctx context.Context, arg db.UpdateUserRoleParams) (db.User, error) {
+	return s.updateUser(arg.ID, func(user *db.User) {
+		user.Role = arg.Role
+	})
+}
+
+func (s *Store) UpdateUserLockedAt(ctx context.Context, arg db.UpdateUserLockedAtParams) (db.User, error) {
+	return s.updateUser(arg.ID, func(user *db.User) {
+		user.LockedAt = arg.LockedAt
+	})
+}
+
+func (s *Store) RevokeUserTokens(ctx context.Context, id uint) (db.User, error) {
+	return s.updateUser(id, func(user *db.User) {
+		user.TokensRevokedAt = sql.NullTime{Time: s.now(), Valid: true}
+	})
+}
+
+// updateUser applies fn to the user with the given id under the write lock
+func (s *Store) updateUser(id uint, fn func(user *db.User)) (db.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return db.User{}, db.ErrRecordNotFound
+	}
+	fn(&user)
+	s.users[id] = user
+	return user, nil
+}
+
+func (s *Store) DeleteUser(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil
+	}
+	for _, post := range s.posts {
+		if post.UserID == id {
+			return &pq.Error{
+				Code:       db.ForeignKeyViolation,
+				Message:    `update or delete on table "users" violates foreign key constraint "posts_user_id_fkey" on table "posts"`,
+				Table:      "posts",
+				Constraint: "posts_user_id_fkey",
+			}
+		}
+	}
+
+	delete(s.users, id)
+	delete(s.userByEmail, user.Email)
+	delete(s.userByStrID, user.UserStrID)
+	// webhooks and notifications are deleted on cascade
+	for _, webhook := range s.webhooks {
+		if webhook.UserID == id {
+			s.deleteWebhook(webhook.ID)
+		}
+	}
+	s.deleteNotifications(func(notification db.Notification) bool {
+		return notification.UserID == id || notification.ActorID == id
+	})
+	for key, mention := range s.postMentions {
+		if mention.UserID == id {
+			delete(s.postMentions, key)
+		}
+	}
+	return nil
+}
+
+func (s *Store) CreatePost(ctx context.Context, arg db.CreatePostParams) (db.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return db.Post{}, &pq.Error{
+			Code:       db.ForeignKeyViolation,
+			Message:    `insert or update on table "posts" violates foreign key constraint "posts_user_id_fkey"`,
+			Table:      "posts",
+			Constraint: "posts_user_id_fkey",
+		}
+	}
+	if _, ok := s.posts[uint(arg.ParentID.Int64)]; arg.ParentID.Valid && !ok {
+		return db.Post{}, foreignKeyViolation("posts", "posts_parent_id_fkey")
+	}
+
+	s.lastPostID++
+	post := db.Post{
+		ID:        s.lastPostID,
+		UserID:    arg.UserID,
+		Text:      arg.Text,
+		CreatedAt: s.now(),
+		ParentID:  arg.ParentID,
+	}
+	s.posts[post.ID] = post
+	return post, nil
+}
+
+func (s *Store) GetPost(ctx context.Context, id uint) (db.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	post, ok := s.posts[id]
+	if !ok {
+		return db.Post{}, db.ErrRecordNotFound
+	}
+	return post, nil
+}
+
+func (s *Store) ListPosts(ctx context.Context, arg db.ListPostsParams) ([]db.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := checkPage(arg.Limit, arg.Offset); err != nil {
+		return nil, err
+	}
+
+	ids := sortedKeys(s.posts)
+	items := []db.Post{}
+	for _, id := range page(ids, arg.Limit, arg.Offset) {
+		items = append(items, s.posts[id])
+	}
+	return items, nil
+}
+
+func (s *Store) UpdatePost(ctx context.Context, arg db.UpdatePostParams) (db.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.posts[arg.ID]
+	if !ok {
+		return db.Post{}, db.ErrRecordNotFound
+	}
+	post.Text = arg.Text
+	s.posts[post.ID] = post
+	return post, nil
+}
+
+func (s *Store) DeletePost(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.posts[id]; ok {
+		s.deletePost(id)
+	}
+	return nil
+}
+
+func (s *Store) DeletePostsByUser(ctx context.Context, userID uint) ([]db.Post, error) {
+	return s.deletePosts(func(post db.Post) bool {
+		return post.UserID == userID
+	}), nil
+}
+
+func (s *Store) DeletePostsCreatedBefore(ctx context.Context, createdAt time.Time) ([]db.Post, error) {
+	return s.deletePosts(func(post db.Post) bool {
+		return post.CreatedAt.Before(createdAt)
+	}), nil
+}
+
+func (s *Store) CreatePostEvent(ctx context.Context, arg db.CreatePostEventParams) (db.PostEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event := db.PostEvent{
+		ID:        int64(len(s.postEvents)) + 1,
+		Type:      arg.Type,
+		PostID:    arg.PostID,
+		Payload:   append(json.RawMessage(nil), arg.Payload...),
+		CreatedAt: s.now(),
+	}
+	s.postEvents = append(s.postEvents, event)
+	return event, nil
+}
+
+func (s *Store) ListPostEventsAfter(ctx context.Context, arg db.ListPostEventsAfterParams) ([]db.PostEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := checkPage(arg.Limit, 0); err != nil {
+		return nil, err
+	}
+
+	i := sort.Search(len(s.postEvents), func(i int) bool {
+		return s.postEvents[i].ID > arg.ID
+	})
+	end := min(len(s.postEvents), i+int(arg.Limit))
+	return append([]db.PostEvent{}, s.postEvents[i:end]...), nil
+}
+
+func (s *Store) GetLastPostEventID(ctx context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(s.postEvents)), nil
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, arg db.CreateWebhookParams) (db.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return db.Webhook{}, &pq.Error{
+			Code:       db.ForeignKeyViolation,
+			Message:    `insert or update on table "webhooks" violates foreign key constraint "webhooks_user_id_fkey"`,
+			Table:      "webhooks",
+			Constraint: "webhooks_user_id_fkey",
+		}
+	}
+
+	s.lastWebhookID++
+	webhook := db.Webhook{
+		ID:        s.lastWebhookID,
+		UserID:    arg.UserID,
+		Url:       arg.Url,
+		Secret:    arg.Secret,
+		Events:    arg.Events,
+		CreatedAt: s.now(),
+	}
+	s.webhooks[webhook.ID] = webhook
+	return webhook, nil
+}
+
+func (s *Store) GetWebhook(ctx context.Context, id int64) (db.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return db.Webhook{}, db.ErrRecordNotFound
+	}
+	return webhook, nil
+}
+
+func (s *Store) ListWebhooksByUser(ctx context.Context, userID uint) ([]db.Webhook, error) {
+	return s.listWebhooks(func(webhook db.Webhook) bool {
+		return webhook.UserID == userID
+	}), nil
+}
+
+func (s *Store) ListEnabledWebhooks(ctx context.Context) ([]db.Webhook, error) {
+	return s.listWebhooks(func(webhook db.Webhook) bool {
+		return !webhook.DisabledAt.Valid
+	}), nil
+}
+
+func (s *Store) UpdateWebhook(ctx context.Context, arg db.UpdateWebhookParams) (db.Webhook, error) {
+	return s.updateWebhook(arg.ID, func(webhook *db.Webhook) {
+		webhook.Url = arg.Url
+		webhook.Events = arg.Events
+		webhook.DisabledAt = truncateNullTime(arg.DisabledAt)
+		webhook.FailureCount = arg.FailureCount
+	})
+}
+
+func (s *Store) RecordWebhookSuccess(ctx context.Context, id int64) (db.Webhook, error) {
+	return s.updateWebhook(id, func(webhook *db.Webhook) {
+		webhook.FailureCount = 0
+	})
+}
+
+func (s *Store) RecordWebhookFailure(ctx context.Context, arg db.RecordWebhookFailureParams) (db.Webhook, error) {
+	return s.updateWebhook(arg.ID, func(webhook *db.Webhook) {
+		webhook.FailureCount++
+		if !webhook.DisabledAt.Valid && webhook.FailureCount >= arg.DisableAfter {
+			webhook.DisabledAt = sql.NullTime{Time: s.now(), Valid: true}
+		}
+	})
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteWebhook(id)
+	return nil
+}
+
+func (s *Store) CreateWebhookDelivery(ctx context.Context, arg db.CreateWebhookDeliveryParams) (db.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[arg.WebhookID]; !ok {
+		return db.WebhookDelivery{}, &pq.Error{
+			Code:       db.ForeignKeyViolation,
+			Message:    `insert or update on table "webhook_deliveries" violates foreign key constraint "webhook_deliveries_webhook_id_fkey"`,
+			Table:      "webhook_deliveries",
+			Constraint: "webhook_deliveries_webhook_id_fkey",
+		}
+	}
+
+	s.lastDeliveryID++
+	now := s.now()
+	delivery := db.WebhookDelivery{
+		ID:            s.lastDeliveryID,
+		WebhookID:     arg.WebhookID,
+		EventType:     arg.EventType,
+		Payload:       append(json.RawMessage(nil), arg.Payload...),
+		Status:        "pending",
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	s.deliveries[delivery.ID] = delivery
+	return delivery, nil
+}
+
+func (s *Store) GetWebhookDelivery(ctx context.Context, id int64) (db.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	delivery, ok := s.deliveries[id]
+	if !ok {
+		return db.WebhookDelivery{}, db.ErrRecordNotFound
+	}
+	return delivery, nil
+}
+
+func (s *Store) ListWebhookDeliveries(ctx context.Context, arg db.ListWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := checkPage(arg.Limit, 0); err != nil {
+		return nil, err
+	}
+
+	items := []db.WebhookDelivery{}
+	for _, delivery := range s.deliveries {
+		if delivery.WebhookID == arg.WebhookID && delivery.ID < arg.ID {
+			items = append(items, delivery)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID > items[j].ID })
+	if int(arg.Limit) < len(items) {
+		items = items[:arg.Limit]
+	}
+	return items, nil
+}
+
+func (s *Store) ClaimWebhookDeliveries(ctx context.Context, arg db.ClaimWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := checkPage(arg.MaxCount, 0); err != nil {
+		return nil, err
+	}
+
+	due := []db.WebhookDelivery{}
+	for _, delivery := range s.deliveries {
+		if delivery.Status == "pending" && !delivery.NextAttemptAt.After(arg.Now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	if int(arg.MaxCount) < len(due) {
+		due = due[:arg.MaxCount]
+	}
+	for i := range due {
+		due[i].NextAttemptAt = arg.LeaseUntil.Truncate(time.Microsecond)
+		s.deliveries[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (s *Store) UpdateWebhookDelivery(ctx context.Context, arg db.UpdateWebhookDeliveryParams) (db.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, ok := s.deliveries[arg.ID]
+	if !ok {
+		return db.WebhookDelivery{}, db.ErrRecordNotFound
+	}
+	delivery.Status = arg.Status
+	delivery.Attempts = arg.Attempts
+	delivery.NextAttemptAt = arg.NextAttemptAt.Truncate(time.Microsecond)
+	delivery.ResponseStatus = arg.ResponseStatus
+	delivery.LastError = arg.LastError
+	delivery.DeliveredAt = truncateNullTime(arg.DeliveredAt)
+	s.deliveries[delivery.ID] = delivery
+	return delivery, nil
+}
+
+func (s *Store) CreateJob(ctx context.Context, arg db.CreateJobParams) (db.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if arg.UniqueKey.Valid {
+		if _, ok := s.jobByKey[arg.UniqueKey.String]; ok {
+			// ON CONFLICT DO NOTHING returns no row
+			return db.Job{}, db.ErrRecordNotFound
+		}
+	}
+
+	s.lastJobID++
+	job := db.Job{
+		ID:          s.lastJobID,
+		Kind:        arg.Kind,
+		Payload:     append(json.RawMessage(nil), arg.Payload...),
+		UniqueKey:   arg.UniqueKey,
+		Status:      "pending",
+		MaxAttempts: arg.MaxAttempts,
+		RunAt:       arg.RunAt.Truncate(time.Microsecond),
+		CreatedAt:   s.now(),
+	}
+	s.jobs[job.ID] = job
+	if job.UniqueKey.Valid {
+		s.jobByKey[job.UniqueKey.String] = job.ID
+	}
+	return job, nil
+}
+
+func (s *Store) GetJob(ctx context.Context, id int64) (db.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return db.Job{}, db.ErrRecordNotFound
+	}
+	return job, nil
+}
+
+func (s *Store) ListJobsByStatus(ctx context.Context, arg db.ListJobsByStatusParams) ([]db.Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := checkPage(arg.Limit, 0); err != nil {
+		return nil, err
+	}
+
+	items := []db.Job{}
+	for _, job := range s.jobs {
+		if job.Status == arg.Status && job.ID < arg.ID {
+			items = append(items, job)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID > items[j].ID })
+	if int(arg.Limit) < len(items) {
+		items = items[:arg.Limit]
+	}
+	return items, nil
+}
+
+func (s *Store) ClaimJobs(ctx context.Context, arg db.ClaimJobsParams) ([]db.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := checkPage(arg.MaxCount, 0); err != nil {
+		return nil, err
+	}
+
+	due := []db.Job{}
+	for _, job := range s.jobs {
+		if job.Kind == arg.Kind && (job.Status == "pending" || job.Status == "running") && !job.RunAt.After(arg.Now) {
+			due = append(due, job)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].RunAt.Equal(due[j].RunAt) {
+			return due[i].RunAt.Before(due[j].RunAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	if int(arg.MaxCount) < len(due) {
+		due = due[:arg.MaxCount]
+	}
+	for i := range due {
+		due[i].Status = "running"
+		due[i].Attempts++
+		due[i].RunAt = arg.LeaseUntil.Truncate(time.Microsecond)
+		s.jobs[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (s *Store) ReleaseJob(ctx context.Context, arg db.ReleaseJobParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[arg.ID]
+	if !ok || job.Attempts != arg.Attempts || job.Status != "running" {
+		return nil
+	}
+	job.Status = arg.Status
+	job.RunAt = arg.RunAt.Truncate(time.Microsecond)
+	job.LastError = arg.LastError
+	job.FinishedAt = truncateNullTime(arg.FinishedAt)
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *Store) RequeueJob(ctx context.Context, arg db.RequeueJobParams) (db.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[arg.ID]
+	if !ok || job.Status != "dead" {
+		return db.Job{}, db.ErrRecordNotFound
+	}
+	job.Status = "pending"
+	job.Attempts = 0
+	job.RunAt = arg.RunAt.Truncate(time.Microsecond)
+	job.LastError = ""
+	job.FinishedAt = sql.NullTime{}
+	s.jobs[job.ID] = job
+	return job, nil
+}
+
+func (s *Store) DeleteSucceededJobs(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for id, job := range s.jobs {
+		if job.Status == "succeeded" && job.FinishedAt.Valid && job.FinishedAt.Time.Before(before) {
+			delete(s.jobs, id)
+			if job.UniqueKey.Valid {
+				delete(s.jobByKey, job.UniqueKey.String)
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) CreateNotification(ctx context.Context, arg db.CreateNotificationParams) (db.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return db.Notification{}, foreignKeyViolation("notifications", "notifications_user_id_fkey")
+	}
+	if _, ok := s.users[arg.ActorID]; !ok {
+		return db.Notification{}, foreignKeyViolation("notifications", "notifications_actor_id_fkey")
+	}
+	if _, ok := s.posts[arg.PostID]; !ok {
+		return db.Notification{}, foreignKeyViolation("notifications", "notifications_post_id_fkey")
+	}
+	key := notificationKey{arg.UserID, arg.Type, arg.PostID}
+	if _, ok := s.notificationByKey[key]; ok {
+		// ON CONFLICT DO NOTHING returns no row
+		return db.Notification{}, db.ErrRecordNotFound
+	}
+
+	s.lastNotificationID++
+	notification := db.Notification{
+		ID:        s.lastNotificationID,
+		UserID:    arg.UserID,
+		Type:      arg.Type,
+		ActorID:   arg.ActorID,
+		PostID:    arg.PostID,
+		CreatedAt: s.now(),
+	}
+	s.notifications[notification.ID] = notification
+	s.notificationByKey[key] = notification.ID
+	return notification, nil
+}
+
+func (s *Store) GetNotification(ctx context.Context, id int64) (db.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	notification, ok := s.notifications[id]
+	if !ok {
+		return db.Notification{}, db.ErrRecordNotFound
+	}
+	return notification, nil
+}
+
+func (s *Store) ListNotifications(ctx context.Context, arg db.ListNotificationsParams) ([]db.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := checkPage(arg.Limit, 0); err != nil {
+		return nil, err
+	}
+	items := []db.Notification{}
+	for _, notification := range s.notifications {
+		if notification.UserID == arg.UserID && notification.ID < arg.ID {
+			items = append(items, notification)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID > items[j].ID })
+	if int(arg.Limit) < len(items) {
+		items = items[:arg.Limit]
+	}
+	return items, nil
+}
+
+func (s *Store) CountUnreadNotifications(ctx context.Context, userID uint) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var n int64
+	for _, notification := range s.notifications {
+		if notification.UserID == userID && !notification.ReadAt.Valid {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) MarkNotificationRead(ctx context.Context, arg db.MarkNotificationReadParams) (db.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notification, ok := s.notifications[arg.ID]
+	if !ok {
+		return db.Notification{}, db.ErrRecordNotFound
+	}
+	if !notification.ReadAt.Valid {
+		notification.ReadAt = sql.NullTime{Time: arg.ReadAt.Truncate(time.Microsecond), Valid: true}
+		s.notifications[notification.ID] = notification
+	}
+	return notification, nil
+}
+
+func (s *Store) MarkAllNotificationsRead(ctx context.Context, arg db.MarkAllNotificationsReadParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for id, notification := range s.notifications {
+		if notification.UserID == arg.UserID && !notification.ReadAt.Valid {
+			notification.ReadAt = sql.NullTime{Time: arg.ReadAt.Truncate(time.Microsecond), Valid: true}
+			s.notifications[id] = notification
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) CreatePostMention(ctx context.Context, arg db.CreatePostMentionParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.posts[arg.PostID]; !ok {
+		return foreignKeyViolation("post_mentions", "post_mentions_post_id_fkey")
+	}
+	if _, ok := s.users[arg.UserID]; !ok {
+		return foreignKeyViolation("post_mentions", "post_mentions_user_id_fkey")
+	}
+	key := postMentionKey{arg.PostID, arg.StartOffset}
+	if _, ok := s.postMentions[key]; ok {
+		return &pq.Error{
+			Code:       db.UniqueViolation,
+			Message:    `duplicate key value violates unique constraint "post_mentions_pkey"`,
+			Table:      "post_mentions",
+			Constraint: "post_mentions_pkey",
+		}
+	}
+	s.postMentions[key] = db.PostMention(arg)
+	return nil
+}
+
+func (s *Store) ListPostMentions(ctx context.Context, postIds []int64) ([]db.PostMention, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wanted := map[uint]bool{}
+	for _, id := range postIds {
+		wanted[uint(id)] = true
+	}
+	items := []db.PostMention{}
+	for _, mention := range s.postMentions {
+		if wanted[mention.PostID] {
+			items = append(items, mention)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].PostID != items[j].PostID {
+			return items[i].PostID < items[j].PostID
+		}
+		return items[i].StartOffset < items[j].StartOffset
+	})
+	return items, nil
+}
+
+func (s *Store) DeletePostMentions(ctx context.Context, postID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deletePostMentions(postID)
+	return nil
+}
+
+func (s *Store) UpsertTag(ctx context.Context, name string) (db.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, ok := s.tagByName[name]; ok {
+		return s.tags[id], nil
+	}
+	s.lastTagID++
+	tag := db.Tag{
+		ID:        s.lastTagID,
+		Name:      name,
+		CreatedAt: s.now(),
+	}
+	s.tags[tag.ID] = tag
+	s.tagByName[name] = tag.ID
+	return tag, nil
+}
+
+func (s *Store) CreatePostTag(ctx context.Context, arg db.CreatePostTagParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.posts[arg.PostID]; !ok {
+		return foreignKeyViolation("post_tags", "post_tags_post_id_fkey")
+	}
+	if _, ok := s.tags[arg.TagID]; !ok {
+		return foreignKeyViolation("post_tags", "post_tags_tag_id_fkey")
+	}
+	s.postTags[db.PostTag(arg)] = true
+	return nil
+}
+
+func (s *Store) ListPostTags(ctx context.Context, postIds []int64) ([]db.ListPostTagsRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wanted := map[uint]bool{}
+	for _, id := range postIds {
+		wanted[uint(id)] = true
+	}
+	items := []db.ListPostTagsRow{}
+	for postTag := range s.postTags {
+		if wanted[postTag.PostID] {
+			items = append(items, db.ListPostTagsRow{PostID: postTag.PostID, Name: s.tags[postTag.TagID].Name})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].PostID != items[j].PostID {
+			return items[i].PostID < items[j].PostID
+		}
+		return items[i].Name < items[j].Name
+	})
+	return items, nil
+}
+
+func (s *Store) DeletePostTags(ctx context.Context, postID uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deletePostTags(postID)
+	return nil
+}
+
+func (s *Store) ListPostsByTag(ctx context.Context, arg db.ListPostsByTagParams) ([]db.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := checkPage(arg.Limit, 0); err != nil {
+		return nil, err
+	}
+	items := []db.Post{}
+	tagID, ok := s.tagByName[arg.Name]
+	if !ok {
+		return items, nil
+	}
+	for postTag := range s.postTags {
+		if postTag.TagID == tagID && postTag.PostID < arg.ID {
+			items = append(items, s.posts[postTag.PostID])
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID > items[j].ID })
+	if int(arg.Limit) < len(items) {
+		items = items[:arg.Limit]
+	}
+	return items, nil
+}
+
+func (s *Store) SearchTags(ctx context.Context, arg db.SearchTagsParams) ([]db.SearchTagsRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := checkPage(arg.Limit, 0); err != nil {
+		return nil, err
+	}
+	pattern := likePattern(arg.Name)
+	counts := s.countTags(func(postTag db.PostTag) bool {
+		return pattern.MatchString(s.tags[postTag.TagID].Name)
+	})
+	items := []db.SearchTagsRow{}
+	for id, n := range counts {
+		items = append(items, db.SearchTagsRow{Name: s.tags[id].Name, PostCount: n})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].PostCount != items[j].PostCount {
+			return items[i].PostCount > items[j].PostCount
+		}
+		return items[i].Name < items[j].Name
+	})
+	if int(arg.Limit) < len(items) {
+		items = items[:arg.Limit]
+	}
+	return items, nil
+}
+
+func (s *Store) ListTrendingTags(ctx context.Context, arg db.ListTrendingTagsParams) ([]db.ListTrendingTagsRow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := checkPage(arg.Limit, 0); err != nil {
+		return nil, err
+	}
+	counts := s.countTags(func(postTag db.PostTag) bool {
+		return !s.posts[postTag.PostID].CreatedAt.Before(arg.CreatedAt)
+	})
+	items := []db.ListTrendingTagsRow{}
+	for id, n := range counts {
+		items = append(items, db.ListTrendingTagsRow{Name: s.tags[id].Name, PostCount: n})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].PostCount != items[j].PostCount {
+			return items[i].PostCount > items[j].PostCount
+		}
+		return items[i].Name < items[j].Name
+	})
+	if int(arg.Limit) < len(items) {
+		items = items[:arg.Limit]
+	}
+	return items, nil
+}
+
+// countTags counts the posts of every tag among the post tags matching fn.
+// Callers must hold s.mu.
+func (s *Store) countTags(fn func(postTag db.PostTag) bool) map[int64]int64 {
+	counts := map[int64]int64{}
+	for postTag := range s.postTags {
+		if fn(postTag) {
+			counts[postTag.TagID]++
+		}
+	}
+	return counts
+}
+
+// listWebhooks returns the webhooks matching fn, ordered by id
+func (s *Store) listWebhooks(fn func(webhook db.Webhook) bool) []db.Webhook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := []db.Webhook{}
+	for _, webhook := range s.webhooks {
+		if fn(webhook) {
+			items = append(items, webhook)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items
+}
+
+// updateWebhook applies fn to the webhook with the given id under the write
+// lock
+func (s *Store) updateWebhook(id int64, fn func(webhook *db.Webhook)) (db.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return db.Webhook{}, db.ErrRecordNotFound
+	}
+	fn(&webhook)
+	s.webhooks[id] = webhook
+	return webhook, nil
+}
+
+// deleteWebhook removes a webhook and, on cascade, its deliveries. Callers
+// must hold s.mu.
+func (s *Store) deleteWebhook(id int64) {
+	delete(s.webhooks, id)
+	for deliveryID, delivery := range s.deliveries {
+		if delivery.WebhookID == id {
+			delete(s.deliveries, deliveryID)
+		}
+	}
+}
+
+// deletePosts removes every post matching fn and returns how many were removed
+// deletePosts deletes the posts fn matches and returns them as they were
+// before any was deleted, ordered by id
+func (s *Store) deletePosts(fn func(post db.Post) bool) []db.Post {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := []db.Post{}
+	for _, post := range s.posts {
+		if fn(post) {
+			deleted = append(deleted, post)
+		}
+	}
+	for _, post := range deleted {
+		s.deletePost(post.ID)
+	}
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i].ID < deleted[j].ID })
+	return deleted
+}
+
+// deletePost removes a post, orphans its replies and deletes, on cascade,
+// its mentions, tags and notifications. Callers must hold s.mu.
+func (s *Store) deletePost(id uint) {
+	delete(s.posts, id)
+	s.deletePostMentions(id)
+	s.deletePostTags(id)
+	for replyID, reply := range s.posts {
+		if reply.ParentID.Valid && uint(reply.ParentID.Int64) == id {
+			reply.ParentID = sql.NullInt64{}
+			s.posts[replyID] = reply
+		}
+	}
+	s.deleteNotifications(func(notification db.Notification) bool {
+		return notification.PostID == id
+	})
+}
+
+// deletePostMentions removes the mentions of a post. Callers must hold s.mu.
+func (s *Store) deletePostMentions(postID uint) {
+	for key := range s.postMentions {
+		if key.postID == postID {
+			delete(s.postMentions, key)
+		}
+	}
+}
+
+// deletePostTags removes the tags of a post. Callers must hold s.mu.
+func (s *Store) deletePostTags(postID uint) {
+	for postTag := range s.postTags {
+		if postTag.PostID == postID {
+			delete(s.postTags, postTag)
+		}
+	}
+}
+
+// deleteNotifications removes every notification matching fn. Callers must
+// hold s.mu.
+func (s *Store) deleteNotifications(fn func(notification db.Notification) bool) {
+	for id, notification := range s.notifications {
+		if fn(notification) {
+			delete(s.notifications, id)
+			delete(s.notificationByKey, notificationKey{notification.UserID, notification.Type, notification.PostID})
+		}
+	}
+}
+
+// checkUserUnique reports a unique violation if userStrID or email already
+// belong to a user other than self. Callers must hold s.mu.
+func (s *Store) checkUserUnique(self uint, userStrID, email string) error {
+	if id, ok := s.userByStrID[userStrID]; ok && id != self {
+		return uniqueViolation("users_user_str_id_key", "user_str_id", userStrID)
+	}
+	if id, ok := s.userByEmail[email]; ok && id != self {
+		return uniqueViolation("users_email_key", "email", email)
+	}
+	return nil
+}
+
+func foreignKeyViolation(table, constraint string) error {
+	return &pq.Error{
+		Code:       db.ForeignKeyViolation,
+		Message:    fmt.Sprintf("insert or update on table %q violates foreign key constraint %q", table, constraint),
+		Table:      table,
+		Constraint: constraint,
+	}
+}
+
+func uniqueViolation(constraint, column, value string) error {
+	return &pq.Error{
+		Code:       db.UniqueViolation,
+		Message:    fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		Detail:     fmt.Sprintf("Key (%s)=(%s) already exists.", column, value),
+		Table:      "users",
+		Constraint: constraint,
+	}
+}
+
+// truncateNullTime drops what timestamptz cannot store
+func truncateNullTime(t sql.NullTime) sql.NullTime {
+	t.Time = t.Time.Truncate(time.Microsecond)
+	return t
+}
+
+func checkPage(limit, offset int32) error {
+	if limit < 0 {
+		return &pq.Error{Code: db.InvalidRowCountInLimitClause, Message: "LIMIT must not be negative"}
+	}
+	if offset < 0 {
+		return &pq.Error{Code: db.InvalidRowCountInResultOffset, Message: "OFFSET must not be negative"}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[uint]V) []uint {
+	keys := make([]uint, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func page(ids []uint, limit, offset int32) []uint {
+	if int(offset) >= len(ids) {
+		return nil
+	}
+	ids = ids[offset:]
+	if int(limit) < len(ids) {
+		ids = ids[:limit]
+	}
+	return ids
+}
+
+// likePattern compiles a LIKE pattern, with \ escaping, to a regexp
+func likePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
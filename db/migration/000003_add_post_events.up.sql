@@ -0,0 +1,7 @@
+CREATE TABLE "post_events" (
+  "id" bigserial PRIMARY KEY,
+  "type" varchar NOT NULL,
+  "post_id" bigint NOT NULL,
+  "payload" jsonb NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
@@ -0,0 +1,29 @@
+CREATE TABLE "webhooks" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "url" varchar NOT NULL,
+  "secret" varchar NOT NULL,
+  "events" varchar NOT NULL,
+  "failure_count" integer NOT NULL DEFAULT 0,
+  "disabled_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "webhooks" ("user_id");
+
+CREATE TABLE "webhook_deliveries" (
+  "id" bigserial PRIMARY KEY,
+  "webhook_id" bigint NOT NULL REFERENCES "webhooks" ("id") ON DELETE CASCADE,
+  "event_type" varchar NOT NULL,
+  "payload" jsonb NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "attempts" integer NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+  "response_status" integer NOT NULL DEFAULT 0,
+  "last_error" varchar NOT NULL DEFAULT '',
+  "delivered_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "webhook_deliveries" ("webhook_id", "id");
+CREATE INDEX ON "webhook_deliveries" ("next_attempt_at") WHERE "status" = 'pending';
@@ -0,0 +1,17 @@
+CREATE TABLE "jobs" (
+  "id" bigserial PRIMARY KEY,
+  "kind" varchar NOT NULL,
+  "payload" jsonb NOT NULL,
+  "unique_key" varchar,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "attempts" integer NOT NULL DEFAULT 0,
+  "max_attempts" integer NOT NULL,
+  "run_at" timestamptz NOT NULL DEFAULT (now()),
+  "last_error" varchar NOT NULL DEFAULT '',
+  "finished_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "jobs" ("unique_key");
+CREATE INDEX ON "jobs" ("kind", "run_at") WHERE "status" IN ('pending', 'running');
+CREATE INDEX ON "jobs" ("status", "id");
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "notifications";
+
+ALTER TABLE "posts" DROP COLUMN IF EXISTS "parent_id";
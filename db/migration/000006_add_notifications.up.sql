@@ -0,0 +1,18 @@
+ALTER TABLE "posts" ADD COLUMN "parent_id" bigint REFERENCES "posts" ("id") ON DELETE SET NULL;
+
+CREATE INDEX ON "posts" ("parent_id");
+
+CREATE TABLE "notifications" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "type" varchar NOT NULL,
+  "actor_id" bigint NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "post_id" bigint NOT NULL REFERENCES "posts" ("id") ON DELETE CASCADE,
+  "read_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "notifications" ("user_id", "type", "post_id");
+CREATE INDEX ON "notifications" ("user_id", "id");
+CREATE INDEX ON "notifications" ("user_id") WHERE "read_at" IS NULL;
+CREATE INDEX ON "notifications" ("post_id");
@@ -0,0 +1,9 @@
+CREATE TABLE "post_mentions" (
+  "post_id" bigint NOT NULL REFERENCES "posts" ("id") ON DELETE CASCADE,
+  "user_id" bigint NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "start_offset" integer NOT NULL,
+  "end_offset" integer NOT NULL,
+  PRIMARY KEY ("post_id", "start_offset")
+);
+
+CREATE INDEX ON "post_mentions" ("user_id");
@@ -0,0 +1,13 @@
+CREATE TABLE "tags" (
+  "id" bigserial PRIMARY KEY,
+  "name" varchar UNIQUE NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "post_tags" (
+  "post_id" bigint NOT NULL REFERENCES "posts" ("id") ON DELETE CASCADE,
+  "tag_id" bigint NOT NULL REFERENCES "tags" ("id") ON DELETE CASCADE,
+  PRIMARY KEY ("post_id", "tag_id")
+);
+
+CREATE INDEX ON "post_tags" ("tag_id", "post_id");
@@ -0,0 +1,228 @@
+// Package migration embeds the schema migrations and applies them with
+// golang-migrate, so the server binary does not depend on an external
+// migrate tool or on the migration files being present on disk.
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/PenginAction/go-BulletinBoard/db/sqlite"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+
+	_ "github.com/lib/pq"
+)
+
+//go:embed *.sql
+var postgresMigrations embed.FS
+
+// ErrSchemaTooNew is returned by Check when the database has been migrated
+// by a newer release than this binary
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary supports")
+
+// ErrSchemaTooOld is returned by Verify when migrations are pending
+var ErrSchemaTooOld = errors.New("database schema has pending migrations")
+
+// ErrDirty is returned by Check when a previous migration failed half way
+var ErrDirty = errors.New("database schema is dirty, fix it and run migrate force")
+
+// Status describes the schema version of a database
+type Status struct {
+	Version uint `json:"version"`
+	Dirty   bool `json:"dirty"`
+	Latest  uint `json:"latest"`
+}
+
+// Migrator applies the embedded migrations for one database
+type Migrator struct {
+	m      *migrate.Migrate
+	latest uint
+}
+
+// New opens a dedicated connection to the database described by driver and
+// dsn. The connection is closed by Close.
+func New(driver, dsn string) (*Migrator, error) {
+	var instance database.Driver
+
+	files, err := migrationFiles(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	switch driver {
+	case "postgres":
+		conn, err := sql.Open(driver, dsn)
+		if err != nil {
+			return nil, err
+		}
+		// the postgres driver takes pg_advisory_lock around every run, so
+		// replicas migrating on startup at the same time wait for each other
+		instance, err = postgres.WithInstance(conn, &postgres.Config{})
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	case sqlite.DriverName:
+		conn, err := sqlite.Open(dsn)
+		if err != nil {
+			return nil, err
+		}
+		instance, err = migratesqlite.WithInstance(conn, &migratesqlite.Config{})
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	src, err := iofs.New(files, ".")
+	if err != nil {
+		instance.Close()
+		return nil, err
+	}
+	latest, err := latestVersion(src)
+	if err != nil {
+		instance.Close()
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, driver, instance)
+	if err != nil {
+		instance.Close()
+		return nil, err
+	}
+	return &Migrator{m: m, latest: latest}, nil
+}
+
+// Verify compares the schema version recorded in conn with the migrations
+// embedded for driver. Unlike Check it reuses an existing connection and
+// takes no locks, so it is cheap enough for readiness probes.
+func Verify(ctx context.Context, conn *sql.DB, driver string) (Status, error) {
+	files, err := migrationFiles(driver)
+	if err != nil {
+		return Status{}, err
+	}
+	src, err := iofs.New(files, ".")
+	if err != nil {
+		return Status{}, err
+	}
+	defer src.Close()
+	latest, err := latestVersion(src)
+	if err != nil {
+		return Status{}, err
+	}
+
+	status := Status{Latest: latest}
+	err = conn.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&status.Version, &status.Dirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return status, err
+	}
+
+	switch {
+	case status.Dirty:
+		return status, fmt.Errorf("%w (version %d)", ErrDirty, status.Version)
+	case status.Version > status.Latest:
+		return status, fmt.Errorf("%w (database at %d, binary at %d)", ErrSchemaTooNew, status.Version, status.Latest)
+	case status.Version < status.Latest:
+		return status, fmt.Errorf("%w (database at %d, binary at %d)", ErrSchemaTooOld, status.Version, status.Latest)
+	}
+	return status, nil
+}
+
+// Up applies all pending migrations
+func (mg *Migrator) Up() error {
+	if err := mg.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Down rolls back the given number of migrations
+func (mg *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps must be positive, got %d", steps)
+	}
+	if err := mg.m.Steps(-steps); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Force marks version as applied and clears the dirty flag without running
+// any migration
+func (mg *Migrator) Force(version int) error {
+	return mg.m.Force(version)
+}
+
+// Status reports the current and latest known schema versions
+func (mg *Migrator) Status() (Status, error) {
+	version, dirty, err := mg.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return Status{}, err
+	}
+	return Status{Version: version, Dirty: dirty, Latest: mg.latest}, nil
+}
+
+// Check refuses to run against a schema that is dirty or newer than the
+// migrations embedded in this binary
+func (mg *Migrator) Check() error {
+	status, err := mg.Status()
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return fmt.Errorf("%w (version %d)", ErrDirty, status.Version)
+	}
+	if status.Version > status.Latest {
+		return fmt.Errorf("%w (database at %d, binary at %d)", ErrSchemaTooNew, status.Version, status.Latest)
+	}
+	return nil
+}
+
+// Close releases the connection opened by New
+func (mg *Migrator) Close() error {
+	srcErr, dbErr := mg.m.Close()
+	if srcErr != nil {
+		return srcErr
+	}
+	return dbErr
+}
+
+func migrationFiles(driver string) (fs.FS, error) {
+	switch driver {
+	case "postgres":
+		return postgresMigrations, nil
+	case sqlite.DriverName:
+		return fs.Sub(sqlite.Migrations, "migration")
+	}
+	return nil, fmt.Errorf("no migrations for driver %q", driver)
+}
+
+func latestVersion(src source.Driver) (uint, error) {
+	version, err := src.First()
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
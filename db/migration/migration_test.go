@@ -0,0 +1,105 @@
+package migration
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/PenginAction/go-BulletinBoard/db/sqlite"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQLiteMigrator(t *testing.T) *Migrator {
+	mg, err := New(sqlite.DriverName, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { mg.Close() })
+	return mg
+}
+
+func TestPostgresMigrationsEmbedded(t *testing.T) {
+	files, err := postgresMigrations.ReadDir(".")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+}
+
+func TestUpDown(t *testing.T) {
+	mg := newSQLiteMigrator(t)
+
+	status, err := mg.Status()
+	require.NoError(t, err)
+	require.Zero(t, status.Version)
+	require.NotZero(t, status.Latest)
+
+	require.NoError(t, mg.Up())
+	status, err = mg.Status()
+	require.NoError(t, err)
+	require.Equal(t, status.Latest, status.Version)
+	require.False(t, status.Dirty)
+	require.NoError(t, mg.Check())
+
+	// running up again is a no-op
+	require.NoError(t, mg.Up())
+
+	require.NoError(t, mg.Down(1))
+	status, err = mg.Status()
+	require.NoError(t, err)
+	require.Equal(t, status.Latest-1, status.Version)
+
+	require.Error(t, mg.Down(0))
+}
+
+func TestCheckSchemaTooNew(t *testing.T) {
+	mg := newSQLiteMigrator(t)
+	require.NoError(t, mg.Up())
+
+	status, err := mg.Status()
+	require.NoError(t, err)
+	require.NoError(t, mg.Force(int(status.Latest)+1))
+
+	require.ErrorIs(t, mg.Check(), ErrSchemaTooNew)
+}
+
+func TestCheckDirty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	mg, err := New(sqlite.DriverName, path)
+	require.NoError(t, err)
+	defer mg.Close()
+	require.NoError(t, mg.Up())
+
+	// simulate a migration that failed half way
+	conn, err := sqlite.Open(path)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Exec("UPDATE schema_migrations SET dirty = 1")
+	require.NoError(t, err)
+
+	require.ErrorIs(t, mg.Check(), ErrDirty)
+}
+
+func TestUnknownDriver(t *testing.T) {
+	_, err := New("memory", "")
+	require.Error(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	mg, err := New(sqlite.DriverName, path)
+	require.NoError(t, err)
+	defer mg.Close()
+
+	conn, err := sqlite.Open(path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = Verify(context.Background(), conn, sqlite.DriverName)
+	require.ErrorIs(t, err, ErrSchemaTooOld)
+
+	require.NoError(t, mg.Up())
+	status, err := Verify(context.Background(), conn, sqlite.DriverName)
+	require.NoError(t, err)
+	require.Equal(t, status.Latest, status.Version)
+
+	require.NoError(t, mg.Force(int(status.Latest)+1))
+	_, err = Verify(context.Background(), conn, sqlite.DriverName)
+	require.ErrorIs(t, err, ErrSchemaTooNew)
+}
@@ -7,6 +7,7 @@ package mockdb
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
 	gomock "github.com/golang/mock/gomock"
@@ -35,6 +36,81 @@ func (m *MockStore) EXPECT() *MockStoreMockRecorder {
 	return m.recorder
 }
 
+// ClaimJobs mocks base method.
+func (m *MockStore) ClaimJobs(arg0 context.Context, arg1 db.ClaimJobsParams) ([]db.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimJobs", arg0, arg1)
+	ret0, _ := ret[0].([]db.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimJobs indicates an expected call of ClaimJobs.
+func (mr *MockStoreMockRecorder) ClaimJobs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimJobs", reflect.TypeOf((*MockStore)(nil).ClaimJobs), arg0, arg1)
+}
+
+// ClaimWebhookDeliveries mocks base method.
+func (m *MockStore) ClaimWebhookDeliveries(arg0 context.Context, arg1 db.ClaimWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimWebhookDeliveries", arg0, arg1)
+	ret0, _ := ret[0].([]db.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimWebhookDeliveries indicates an expected call of ClaimWebhookDeliveries.
+func (mr *MockStoreMockRecorder) ClaimWebhookDeliveries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimWebhookDeliveries", reflect.TypeOf((*MockStore)(nil).ClaimWebhookDeliveries), arg0, arg1)
+}
+
+// CountUnreadNotifications mocks base method.
+func (m *MockStore) CountUnreadNotifications(arg0 context.Context, arg1 uint) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUnreadNotifications", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUnreadNotifications indicates an expected call of CountUnreadNotifications.
+func (mr *MockStoreMockRecorder) CountUnreadNotifications(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUnreadNotifications", reflect.TypeOf((*MockStore)(nil).CountUnreadNotifications), arg0, arg1)
+}
+
+// CreateJob mocks base method.
+func (m *MockStore) CreateJob(arg0 context.Context, arg1 db.CreateJobParams) (db.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateJob", arg0, arg1)
+	ret0, _ := ret[0].(db.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateJob indicates an expected call of CreateJob.
+func (mr *MockStoreMockRecorder) CreateJob(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJob", reflect.TypeOf((*MockStore)(nil).CreateJob), arg0, arg1)
+}
+
+// CreateNotification mocks base method.
+func (m *MockStore) CreateNotification(arg0 context.Context, arg1 db.CreateNotificationParams) (db.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateNotification", arg0, arg1)
+	ret0, _ := ret[0].(db.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateNotification indicates an expected call of CreateNotification.
+func (mr *MockStoreMockRecorder) CreateNotification(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNotification", reflect.TypeOf((*MockStore)(nil).CreateNotification), arg0, arg1)
+}
+
 // CreatePost mocks base method.
 func (m *MockStore) CreatePost(arg0 context.Context, arg1 db.CreatePostParams) (db.Post, error) {
 	m.ctrl.T.Helper()
@@ -50,6 +126,49 @@ func (mr *MockStoreMockRecorder) CreatePost(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePost", reflect.TypeOf((*MockStore)(nil).CreatePost), arg0, arg1)
 }
 
+// CreatePostEvent mocks base method.
+func (m *MockStore) CreatePostEvent(arg0 context.Context, arg1 db.CreatePostEventParams) (db.PostEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePostEvent", arg0, arg1)
+	ret0, _ := ret[0].(db.PostEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePostEvent indicates an expected call of CreatePostEvent.
+func (mr *MockStoreMockRecorder) CreatePostEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePostEvent", reflect.TypeOf((*MockStore)(nil).CreatePostEvent), arg0, arg1)
+}
+
+// CreatePostMention mocks base method.
+func (m *MockStore) CreatePostMention(arg0 context.Context, arg1 db.CreatePostMentionParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePostMention", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePostMention indicates an expected call of CreatePostMention.
+func (mr *MockStoreMockRecorder) CreatePostMention(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePostMention", reflect.TypeOf((*MockStore)(nil).CreatePostMention), arg0, arg1)
+}
+
+// CreatePostTag mocks base method.
+func (m *MockStore) CreatePostTag(arg0 context.Context, arg1 db.CreatePostTagParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePostTag", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePostTag indicates an expected call of CreatePostTag.
+func (mr *MockStoreMockRecorder) CreatePostTag(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePostTag", reflect.TypeOf((*MockStore)(nil).CreatePostTag), arg0, arg1)
+}
+
 // CreateUser mocks base method.
 func (m *MockStore) CreateUser(arg0 context.Context, arg1 db.CreateUserParams) (db.User, error) {
 	m.ctrl.T.Helper()
@@ -65,6 +184,36 @@ func (mr *MockStoreMockRecorder) CreateUser(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockStore)(nil).CreateUser), arg0, arg1)
 }
 
+// CreateWebhook mocks base method.
+func (m *MockStore) CreateWebhook(arg0 context.Context, arg1 db.CreateWebhookParams) (db.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhook", arg0, arg1)
+	ret0, _ := ret[0].(db.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhook indicates an expected call of CreateWebhook.
+func (mr *MockStoreMockRecorder) CreateWebhook(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhook", reflect.TypeOf((*MockStore)(nil).CreateWebhook), arg0, arg1)
+}
+
+// CreateWebhookDelivery mocks base method.
+func (m *MockStore) CreateWebhookDelivery(arg0 context.Context, arg1 db.CreateWebhookDeliveryParams) (db.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhookDelivery", arg0, arg1)
+	ret0, _ := ret[0].(db.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhookDelivery indicates an expected call of CreateWebhookDelivery.
+func (mr *MockStoreMockRecorder) CreateWebhookDelivery(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhookDelivery", reflect.TypeOf((*MockStore)(nil).CreateWebhookDelivery), arg0, arg1)
+}
+
 // DeletePost mocks base method.
 func (m *MockStore) DeletePost(arg0 context.Context, arg1 uint) error {
 	m.ctrl.T.Helper()
@@ -79,6 +228,79 @@ func (mr *MockStoreMockRecorder) DeletePost(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePost", reflect.TypeOf((*MockStore)(nil).DeletePost), arg0, arg1)
 }
 
+// DeletePostMentions mocks base method.
+func (m *MockStore) DeletePostMentions(arg0 context.Context, arg1 uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePostMentions", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePostMentions indicates an expected call of DeletePostMentions.
+func (mr *MockStoreMockRecorder) DeletePostMentions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePostMentions", reflect.TypeOf((*MockStore)(nil).DeletePostMentions), arg0, arg1)
+}
+
+// DeletePostTags mocks base method.
+func (m *MockStore) DeletePostTags(arg0 context.Context, arg1 uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePostTags", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePostTags indicates an expected call of DeletePostTags.
+func (mr *MockStoreMockRecorder) DeletePostTags(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePostTags", reflect.TypeOf((*MockStore)(nil).DeletePostTags), arg0, arg1)
+}
+
+// DeletePostsByUser mocks base method.
+func (m *MockStore) DeletePostsByUser(arg0 context.Context, arg1 uint) ([]db.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePostsByUser", arg0, arg1)
+	ret0, _ := ret[0].([]db.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeletePostsByUser indicates an expected call of DeletePostsByUser.
+func (mr *MockStoreMockRecorder) DeletePostsByUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePostsByUser", reflect.TypeOf((*MockStore)(nil).DeletePostsByUser), arg0, arg1)
+}
+
+// DeletePostsCreatedBefore mocks base method.
+func (m *MockStore) DeletePostsCreatedBefore(arg0 context.Context, arg1 time.Time) ([]db.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePostsCreatedBefore", arg0, arg1)
+	ret0, _ := ret[0].([]db.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeletePostsCreatedBefore indicates an expected call of DeletePostsCreatedBefore.
+func (mr *MockStoreMockRecorder) DeletePostsCreatedBefore(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePostsCreatedBefore", reflect.TypeOf((*MockStore)(nil).DeletePostsCreatedBefore), arg0, arg1)
+}
+
+// DeleteSucceededJobs mocks base method.
+func (m *MockStore) DeleteSucceededJobs(arg0 context.Context, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSucceededJobs", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteSucceededJobs indicates an expected call of DeleteSucceededJobs.
+func (mr *MockStoreMockRecorder) DeleteSucceededJobs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSucceededJobs", reflect.TypeOf((*MockStore)(nil).DeleteSucceededJobs), arg0, arg1)
+}
+
 // DeleteUser mocks base method.
 func (m *MockStore) DeleteUser(arg0 context.Context, arg1 uint) error {
 	m.ctrl.T.Helper()
@@ -93,6 +315,79 @@ func (mr *MockStoreMockRecorder) DeleteUser(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockStore)(nil).DeleteUser), arg0, arg1)
 }
 
+// DeleteWebhook mocks base method.
+func (m *MockStore) DeleteWebhook(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockStoreMockRecorder) DeleteWebhook(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockStore)(nil).DeleteWebhook), arg0, arg1)
+}
+
+// ExecTx mocks base method.
+func (m *MockStore) ExecTx(arg0 context.Context, arg1 func(db.Store) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExecTx", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExecTx indicates an expected call of ExecTx.
+func (mr *MockStoreMockRecorder) ExecTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecTx", reflect.TypeOf((*MockStore)(nil).ExecTx), arg0, arg1)
+}
+
+// GetJob mocks base method.
+func (m *MockStore) GetJob(arg0 context.Context, arg1 int64) (db.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJob", arg0, arg1)
+	ret0, _ := ret[0].(db.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetJob indicates an expected call of GetJob.
+func (mr *MockStoreMockRecorder) GetJob(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJob", reflect.TypeOf((*MockStore)(nil).GetJob), arg0, arg1)
+}
+
+// GetLastPostEventID mocks base method.
+func (m *MockStore) GetLastPostEventID(arg0 context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLastPostEventID", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLastPostEventID indicates an expected call of GetLastPostEventID.
+func (mr *MockStoreMockRecorder) GetLastPostEventID(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastPostEventID", reflect.TypeOf((*MockStore)(nil).GetLastPostEventID), arg0)
+}
+
+// GetNotification mocks base method.
+func (m *MockStore) GetNotification(arg0 context.Context, arg1 int64) (db.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNotification", arg0, arg1)
+	ret0, _ := ret[0].(db.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNotification indicates an expected call of GetNotification.
+func (mr *MockStoreMockRecorder) GetNotification(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNotification", reflect.TypeOf((*MockStore)(nil).GetNotification), arg0, arg1)
+}
+
 // GetPost mocks base method.
 func (m *MockStore) GetPost(arg0 context.Context, arg1 uint) (db.Post, error) {
 	m.ctrl.T.Helper()
@@ -138,6 +433,21 @@ func (mr *MockStoreMockRecorder) GetUserByEmail(arg0, arg1 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockStore)(nil).GetUserByEmail), arg0, arg1)
 }
 
+// GetUserByUserStrId mocks base method.
+func (m *MockStore) GetUserByUserStrId(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByUserStrId", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByUserStrId indicates an expected call of GetUserByUserStrId.
+func (mr *MockStoreMockRecorder) GetUserByUserStrId(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUserStrId", reflect.TypeOf((*MockStore)(nil).GetUserByUserStrId), arg0, arg1)
+}
+
 // GetUserStrIdById mocks base method.
 func (m *MockStore) GetUserStrIdById(arg0 context.Context, arg1 uint) (string, error) {
 	m.ctrl.T.Helper()
@@ -153,6 +463,126 @@ func (mr *MockStoreMockRecorder) GetUserStrIdById(arg0, arg1 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserStrIdById", reflect.TypeOf((*MockStore)(nil).GetUserStrIdById), arg0, arg1)
 }
 
+// GetWebhook mocks base method.
+func (m *MockStore) GetWebhook(arg0 context.Context, arg1 int64) (db.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhook", arg0, arg1)
+	ret0, _ := ret[0].(db.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhook indicates an expected call of GetWebhook.
+func (mr *MockStoreMockRecorder) GetWebhook(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhook", reflect.TypeOf((*MockStore)(nil).GetWebhook), arg0, arg1)
+}
+
+// GetWebhookDelivery mocks base method.
+func (m *MockStore) GetWebhookDelivery(arg0 context.Context, arg1 int64) (db.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhookDelivery", arg0, arg1)
+	ret0, _ := ret[0].(db.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhookDelivery indicates an expected call of GetWebhookDelivery.
+func (mr *MockStoreMockRecorder) GetWebhookDelivery(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhookDelivery", reflect.TypeOf((*MockStore)(nil).GetWebhookDelivery), arg0, arg1)
+}
+
+// ListEnabledWebhooks mocks base method.
+func (m *MockStore) ListEnabledWebhooks(arg0 context.Context) ([]db.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEnabledWebhooks", arg0)
+	ret0, _ := ret[0].([]db.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEnabledWebhooks indicates an expected call of ListEnabledWebhooks.
+func (mr *MockStoreMockRecorder) ListEnabledWebhooks(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEnabledWebhooks", reflect.TypeOf((*MockStore)(nil).ListEnabledWebhooks), arg0)
+}
+
+// ListJobsByStatus mocks base method.
+func (m *MockStore) ListJobsByStatus(arg0 context.Context, arg1 db.ListJobsByStatusParams) ([]db.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListJobsByStatus", arg0, arg1)
+	ret0, _ := ret[0].([]db.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListJobsByStatus indicates an expected call of ListJobsByStatus.
+func (mr *MockStoreMockRecorder) ListJobsByStatus(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListJobsByStatus", reflect.TypeOf((*MockStore)(nil).ListJobsByStatus), arg0, arg1)
+}
+
+// ListNotifications mocks base method.
+func (m *MockStore) ListNotifications(arg0 context.Context, arg1 db.ListNotificationsParams) ([]db.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNotifications", arg0, arg1)
+	ret0, _ := ret[0].([]db.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNotifications indicates an expected call of ListNotifications.
+func (mr *MockStoreMockRecorder) ListNotifications(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNotifications", reflect.TypeOf((*MockStore)(nil).ListNotifications), arg0, arg1)
+}
+
+// ListPostEventsAfter mocks base method.
+func (m *MockStore) ListPostEventsAfter(arg0 context.Context, arg1 db.ListPostEventsAfterParams) ([]db.PostEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPostEventsAfter", arg0, arg1)
+	ret0, _ := ret[0].([]db.PostEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPostEventsAfter indicates an expected call of ListPostEventsAfter.
+func (mr *MockStoreMockRecorder) ListPostEventsAfter(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPostEventsAfter", reflect.TypeOf((*MockStore)(nil).ListPostEventsAfter), arg0, arg1)
+}
+
+// ListPostMentions mocks base method.
+func (m *MockStore) ListPostMentions(arg0 context.Context, arg1 []int64) ([]db.PostMention, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPostMentions", arg0, arg1)
+	ret0, _ := ret[0].([]db.PostMention)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPostMentions indicates an expected call of ListPostMentions.
+func (mr *MockStoreMockRecorder) ListPostMentions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPostMentions", reflect.TypeOf((*MockStore)(nil).ListPostMentions), arg0, arg1)
+}
+
+// ListPostTags mocks base method.
+func (m *MockStore) ListPostTags(arg0 context.Context, arg1 []int64) ([]db.ListPostTagsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPostTags", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListPostTagsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPostTags indicates an expected call of ListPostTags.
+func (mr *MockStoreMockRecorder) ListPostTags(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPostTags", reflect.TypeOf((*MockStore)(nil).ListPostTags), arg0, arg1)
+}
+
 // ListPosts mocks base method.
 func (m *MockStore) ListPosts(arg0 context.Context, arg1 db.ListPostsParams) ([]db.Post, error) {
 	m.ctrl.T.Helper()
@@ -168,6 +598,66 @@ func (mr *MockStoreMockRecorder) ListPosts(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPosts", reflect.TypeOf((*MockStore)(nil).ListPosts), arg0, arg1)
 }
 
+// ListPostsByTag mocks base method.
+func (m *MockStore) ListPostsByTag(arg0 context.Context, arg1 db.ListPostsByTagParams) ([]db.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPostsByTag", arg0, arg1)
+	ret0, _ := ret[0].([]db.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPostsByTag indicates an expected call of ListPostsByTag.
+func (mr *MockStoreMockRecorder) ListPostsByTag(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPostsByTag", reflect.TypeOf((*MockStore)(nil).ListPostsByTag), arg0, arg1)
+}
+
+// ListReplies mocks base method.
+func (m *MockStore) ListReplies(arg0 context.Context, arg1 db.ListRepliesParams) ([]db.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListReplies", arg0, arg1)
+	ret0, _ := ret[0].([]db.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListReplies indicates an expected call of ListReplies.
+func (mr *MockStoreMockRecorder) ListReplies(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListReplies", reflect.TypeOf((*MockStore)(nil).ListReplies), arg0, arg1)
+}
+
+// ListRepliesByParentIDs mocks base method.
+func (m *MockStore) ListRepliesByParentIDs(arg0 context.Context, arg1 db.ListRepliesByParentIDsParams) ([]db.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRepliesByParentIDs", arg0, arg1)
+	ret0, _ := ret[0].([]db.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRepliesByParentIDs indicates an expected call of ListRepliesByParentIDs.
+func (mr *MockStoreMockRecorder) ListRepliesByParentIDs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRepliesByParentIDs", reflect.TypeOf((*MockStore)(nil).ListRepliesByParentIDs), arg0, arg1)
+}
+
+// ListTrendingTags mocks base method.
+func (m *MockStore) ListTrendingTags(arg0 context.Context, arg1 db.ListTrendingTagsParams) ([]db.ListTrendingTagsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTrendingTags", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListTrendingTagsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTrendingTags indicates an expected call of ListTrendingTags.
+func (mr *MockStoreMockRecorder) ListTrendingTags(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTrendingTags", reflect.TypeOf((*MockStore)(nil).ListTrendingTags), arg0, arg1)
+}
+
 // ListUsers mocks base method.
 func (m *MockStore) ListUsers(arg0 context.Context, arg1 db.ListUsersParams) ([]db.User, error) {
 	m.ctrl.T.Helper()
@@ -183,6 +673,170 @@ func (mr *MockStoreMockRecorder) ListUsers(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockStore)(nil).ListUsers), arg0, arg1)
 }
 
+// ListUsersByIDs mocks base method.
+func (m *MockStore) ListUsersByIDs(arg0 context.Context, arg1 []int64) ([]db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsersByIDs", arg0, arg1)
+	ret0, _ := ret[0].([]db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsersByIDs indicates an expected call of ListUsersByIDs.
+func (mr *MockStoreMockRecorder) ListUsersByIDs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsersByIDs", reflect.TypeOf((*MockStore)(nil).ListUsersByIDs), arg0, arg1)
+}
+
+// ListWebhookDeliveries mocks base method.
+func (m *MockStore) ListWebhookDeliveries(arg0 context.Context, arg1 db.ListWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhookDeliveries", arg0, arg1)
+	ret0, _ := ret[0].([]db.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhookDeliveries indicates an expected call of ListWebhookDeliveries.
+func (mr *MockStoreMockRecorder) ListWebhookDeliveries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhookDeliveries", reflect.TypeOf((*MockStore)(nil).ListWebhookDeliveries), arg0, arg1)
+}
+
+// ListWebhooksByUser mocks base method.
+func (m *MockStore) ListWebhooksByUser(arg0 context.Context, arg1 uint) ([]db.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhooksByUser", arg0, arg1)
+	ret0, _ := ret[0].([]db.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhooksByUser indicates an expected call of ListWebhooksByUser.
+func (mr *MockStoreMockRecorder) ListWebhooksByUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhooksByUser", reflect.TypeOf((*MockStore)(nil).ListWebhooksByUser), arg0, arg1)
+}
+
+// MarkAllNotificationsRead mocks base method.
+func (m *MockStore) MarkAllNotificationsRead(arg0 context.Context, arg1 db.MarkAllNotificationsReadParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkAllNotificationsRead", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkAllNotificationsRead indicates an expected call of MarkAllNotificationsRead.
+func (mr *MockStoreMockRecorder) MarkAllNotificationsRead(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAllNotificationsRead", reflect.TypeOf((*MockStore)(nil).MarkAllNotificationsRead), arg0, arg1)
+}
+
+// MarkNotificationRead mocks base method.
+func (m *MockStore) MarkNotificationRead(arg0 context.Context, arg1 db.MarkNotificationReadParams) (db.Notification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkNotificationRead", arg0, arg1)
+	ret0, _ := ret[0].(db.Notification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkNotificationRead indicates an expected call of MarkNotificationRead.
+func (mr *MockStoreMockRecorder) MarkNotificationRead(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkNotificationRead", reflect.TypeOf((*MockStore)(nil).MarkNotificationRead), arg0, arg1)
+}
+
+// RecordWebhookFailure mocks base method.
+func (m *MockStore) RecordWebhookFailure(arg0 context.Context, arg1 db.RecordWebhookFailureParams) (db.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordWebhookFailure", arg0, arg1)
+	ret0, _ := ret[0].(db.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordWebhookFailure indicates an expected call of RecordWebhookFailure.
+func (mr *MockStoreMockRecorder) RecordWebhookFailure(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordWebhookFailure", reflect.TypeOf((*MockStore)(nil).RecordWebhookFailure), arg0, arg1)
+}
+
+// RecordWebhookSuccess mocks base method.
+func (m *MockStore) RecordWebhookSuccess(arg0 context.Context, arg1 int64) (db.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordWebhookSuccess", arg0, arg1)
+	ret0, _ := ret[0].(db.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordWebhookSuccess indicates an expected call of RecordWebhookSuccess.
+func (mr *MockStoreMockRecorder) RecordWebhookSuccess(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordWebhookSuccess", reflect.TypeOf((*MockStore)(nil).RecordWebhookSuccess), arg0, arg1)
+}
+
+// ReleaseJob mocks base method.
+func (m *MockStore) ReleaseJob(arg0 context.Context, arg1 db.ReleaseJobParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseJob", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseJob indicates an expected call of ReleaseJob.
+func (mr *MockStoreMockRecorder) ReleaseJob(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseJob", reflect.TypeOf((*MockStore)(nil).ReleaseJob), arg0, arg1)
+}
+
+// RequeueJob mocks base method.
+func (m *MockStore) RequeueJob(arg0 context.Context, arg1 db.RequeueJobParams) (db.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequeueJob", arg0, arg1)
+	ret0, _ := ret[0].(db.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequeueJob indicates an expected call of RequeueJob.
+func (mr *MockStoreMockRecorder) RequeueJob(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequeueJob", reflect.TypeOf((*MockStore)(nil).RequeueJob), arg0, arg1)
+}
+
+// RevokeUserTokens mocks base method.
+func (m *MockStore) RevokeUserTokens(arg0 context.Context, arg1 uint) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserTokens", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeUserTokens indicates an expected call of RevokeUserTokens.
+func (mr *MockStoreMockRecorder) RevokeUserTokens(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserTokens", reflect.TypeOf((*MockStore)(nil).RevokeUserTokens), arg0, arg1)
+}
+
+// SearchTags mocks base method.
+func (m *MockStore) SearchTags(arg0 context.Context, arg1 db.SearchTagsParams) ([]db.SearchTagsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchTags", arg0, arg1)
+	ret0, _ := ret[0].([]db.SearchTagsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchTags indicates an expected call of SearchTags.
+func (mr *MockStoreMockRecorder) SearchTags(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchTags", reflect.TypeOf((*MockStore)(nil).SearchTags), arg0, arg1)
+}
+
 // UpdatePost mocks base method.
 func (m *MockStore) UpdatePost(arg0 context.Context, arg1 db.UpdatePostParams) (db.Post, error) {
 	m.ctrl.T.Helper()
@@ -212,3 +866,78 @@ func (mr *MockStoreMockRecorder) UpdateUser(arg0, arg1 interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockStore)(nil).UpdateUser), arg0, arg1)
 }
+
+// UpdateUserLockedAt mocks base method.
+func (m *MockStore) UpdateUserLockedAt(arg0 context.Context, arg1 db.UpdateUserLockedAtParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserLockedAt", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUserLockedAt indicates an expected call of UpdateUserLockedAt.
+func (mr *MockStoreMockRecorder) UpdateUserLockedAt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserLockedAt", reflect.TypeOf((*MockStore)(nil).UpdateUserLockedAt), arg0, arg1)
+}
+
+// UpdateUserRole mocks base method.
+func (m *MockStore) UpdateUserRole(arg0 context.Context, arg1 db.UpdateUserRoleParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserRole", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUserRole indicates an expected call of UpdateUserRole.
+func (mr *MockStoreMockRecorder) UpdateUserRole(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserRole", reflect.TypeOf((*MockStore)(nil).UpdateUserRole), arg0, arg1)
+}
+
+// UpdateWebhook mocks base method.
+func (m *MockStore) UpdateWebhook(arg0 context.Context, arg1 db.UpdateWebhookParams) (db.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebhook", arg0, arg1)
+	ret0, _ := ret[0].(db.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateWebhook indicates an expected call of UpdateWebhook.
+func (mr *MockStoreMockRecorder) UpdateWebhook(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebhook", reflect.TypeOf((*MockStore)(nil).UpdateWebhook), arg0, arg1)
+}
+
+// UpdateWebhookDelivery mocks base method.
+func (m *MockStore) UpdateWebhookDelivery(arg0 context.Context, arg1 db.UpdateWebhookDeliveryParams) (db.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebhookDelivery", arg0, arg1)
+	ret0, _ := ret[0].(db.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateWebhookDelivery indicates an expected call of UpdateWebhookDelivery.
+func (mr *MockStoreMockRecorder) UpdateWebhookDelivery(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebhookDelivery", reflect.TypeOf((*MockStore)(nil).UpdateWebhookDelivery), arg0, arg1)
+}
+
+// UpsertTag mocks base method.
+func (m *MockStore) UpsertTag(arg0 context.Context, arg1 string) (db.Tag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpsertTag", arg0, arg1)
+	ret0, _ := ret[0].(db.Tag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpsertTag indicates an expected call of UpsertTag.
+func (mr *MockStoreMockRecorder) UpsertTag(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpsertTag", reflect.TypeOf((*MockStore)(nil).UpsertTag), arg0, arg1)
+}
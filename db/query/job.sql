@@ -0,0 +1,56 @@
+-- name: CreateJob :one
+-- queues a job. A job with the unique key of one queued before is not
+-- queued, and no row is returned.
+INSERT INTO jobs (
+ kind,
+ payload,
+ unique_key,
+ max_attempts,
+ run_at
+) VALUES (
+ $1, $2, $3, $4, $5
+)
+ON CONFLICT (unique_key) DO NOTHING
+RETURNING *;
+
+-- name: GetJob :one
+SELECT * FROM jobs
+WHERE id = $1 LIMIT 1;
+
+-- name: ListJobsByStatus :many
+SELECT * FROM jobs
+WHERE status = $1 AND id < $2
+ORDER BY id DESC
+LIMIT $3;
+
+-- name: ClaimJobs :many
+-- leases up to max_count due jobs of kind until lease_until, skipping those
+-- another worker holds. Running jobs whose lease ran out are due again.
+UPDATE jobs
+SET status = 'running', attempts = attempts + 1, run_at = sqlc.arg(lease_until)
+WHERE id IN (
+ SELECT j.id FROM jobs j
+ WHERE j.kind = sqlc.arg(kind) AND j.status IN ('pending', 'running') AND j.run_at <= sqlc.arg(now)
+ ORDER BY j.run_at, j.id
+ LIMIT sqlc.arg(max_count)
+ FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ReleaseJob :exec
+-- ends the lease of a claimed job, unless it ran out and the job was
+-- claimed again since
+UPDATE jobs
+SET status = $3, run_at = $4, last_error = $5, finished_at = $6
+WHERE id = $1 AND attempts = $2 AND status = 'running';
+
+-- name: RequeueJob :one
+-- queues a dead job again, with its attempts reset
+UPDATE jobs
+SET status = 'pending', attempts = 0, run_at = $2, last_error = '', finished_at = NULL
+WHERE id = $1 AND status = 'dead'
+RETURNING *;
+
+-- name: DeleteSucceededJobs :execrows
+DELETE FROM jobs
+WHERE status = 'succeeded' AND finished_at < sqlc.arg(before)::timestamptz;
@@ -1,9 +1,10 @@
 -- name: CreatePost :one
 INSERT INTO posts (
  user_id,
- text
+ text,
+ parent_id
 ) VALUES (
- $1, $2
+ $1, $2, $3
 ) RETURNING *;
 
 -- name: GetPost :one
@@ -16,6 +17,26 @@ ORDER BY id
 LIMIT $1
 OFFSET $2;
 
+-- name: ListReplies :many
+SELECT * FROM posts
+WHERE parent_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: ListRepliesByParentIDs :many
+-- the same window of replies to each of the parents
+SELECT * FROM posts
+WHERE id IN (
+  SELECT numbered.id FROM (
+    SELECT replies.id, row_number() OVER (PARTITION BY replies.parent_id ORDER BY replies.id) AS n
+    FROM posts AS replies
+    WHERE replies.parent_id = ANY(sqlc.arg(parent_ids)::bigint[])
+  ) AS numbered
+  WHERE numbered.n - sqlc.arg('offset')::int BETWEEN 1 AND sqlc.arg('limit')::int
+)
+ORDER BY parent_id, id;
+
 -- name: UpdatePost :one
 UPDATE posts
   set text = $2
@@ -24,4 +45,14 @@ RETURNING *;
 
 -- name: DeletePost :exec
 DELETE FROM posts
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: DeletePostsByUser :many
+DELETE FROM posts
+WHERE user_id = $1
+RETURNING *;
+
+-- name: DeletePostsCreatedBefore :many
+DELETE FROM posts
+WHERE created_at < $1
+RETURNING *;
@@ -0,0 +1,23 @@
+-- name: CreatePostEvent :one
+-- The lock is held until the transaction ends, so events get their ids in
+-- the order their transactions commit: a reader that has seen an event has
+-- seen every event with a smaller id.
+WITH lock AS (
+  SELECT pg_advisory_xact_lock(hashtext('post_events'))
+)
+INSERT INTO post_events (
+ type,
+ post_id,
+ payload
+)
+SELECT $1, $2, $3 FROM lock
+RETURNING *;
+
+-- name: ListPostEventsAfter :many
+SELECT * FROM post_events
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+
+-- name: GetLastPostEventID :one
+SELECT COALESCE(MAX(id), 0)::bigint FROM post_events;
@@ -0,0 +1,18 @@
+-- name: CreatePostMention :exec
+INSERT INTO post_mentions (
+ post_id,
+ user_id,
+ start_offset,
+ end_offset
+) VALUES (
+ $1, $2, $3, $4
+);
+
+-- name: ListPostMentions :many
+SELECT * FROM post_mentions
+WHERE post_id = ANY(sqlc.arg(post_ids)::bigint[])
+ORDER BY post_id, start_offset;
+
+-- name: DeletePostMentions :exec
+DELETE FROM post_mentions
+WHERE post_id = $1;
@@ -0,0 +1,52 @@
+-- name: UpsertTag :one
+INSERT INTO tags (
+ name
+) VALUES (
+ $1
+)
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: CreatePostTag :exec
+INSERT INTO post_tags (
+ post_id,
+ tag_id
+) VALUES (
+ $1, $2
+)
+ON CONFLICT DO NOTHING;
+
+-- name: ListPostTags :many
+SELECT post_tags.post_id, tags.name FROM post_tags
+JOIN tags ON tags.id = post_tags.tag_id
+WHERE post_tags.post_id = ANY(sqlc.arg(post_ids)::bigint[])
+ORDER BY post_tags.post_id, tags.name;
+
+-- name: DeletePostTags :exec
+DELETE FROM post_tags
+WHERE post_id = $1;
+
+-- name: ListPostsByTag :many
+SELECT posts.* FROM posts
+JOIN post_tags ON post_tags.post_id = posts.id
+JOIN tags ON tags.id = post_tags.tag_id
+WHERE tags.name = $1 AND posts.id < $2
+ORDER BY posts.id DESC
+LIMIT $3;
+
+-- name: SearchTags :many
+SELECT tags.name, COUNT(*) AS post_count FROM tags
+JOIN post_tags ON post_tags.tag_id = tags.id
+WHERE tags.name LIKE $1
+GROUP BY tags.id, tags.name
+ORDER BY post_count DESC, tags.name
+LIMIT $2;
+
+-- name: ListTrendingTags :many
+SELECT tags.name, COUNT(*) AS post_count FROM post_tags
+JOIN tags ON tags.id = post_tags.tag_id
+JOIN posts ON posts.id = post_tags.post_id
+WHERE posts.created_at >= $1
+GROUP BY tags.id, tags.name
+ORDER BY post_count DESC, tags.name
+LIMIT $2;
@@ -25,6 +25,11 @@ ORDER BY id
 LIMIT $1
 OFFSET $2;
 
+-- name: ListUsersByIDs :many
+SELECT * FROM users
+WHERE id = ANY(sqlc.arg(ids)::bigint[])
+ORDER BY id;
+
 -- name: UpdateUser :one
 UPDATE users
   set user_str_id = $2,
@@ -36,3 +41,25 @@ RETURNING *;
 -- name: DeleteUser :exec
 DELETE FROM users
 WHERE id = $1;
+
+-- name: GetUserByUserStrId :one
+SELECT * FROM users
+WHERE user_str_id = $1 LIMIT 1;
+
+-- name: UpdateUserRole :one
+UPDATE users
+  set role = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateUserLockedAt :one
+UPDATE users
+  set locked_at = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: RevokeUserTokens :one
+UPDATE users
+  set tokens_revoked_at = now()
+WHERE id = $1
+RETURNING *;
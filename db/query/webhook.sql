@@ -0,0 +1,89 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (
+ user_id,
+ url,
+ secret,
+ events
+) VALUES (
+ $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks
+WHERE id = $1 LIMIT 1;
+
+-- name: ListWebhooksByUser :many
+SELECT * FROM webhooks
+WHERE user_id = $1
+ORDER BY id;
+
+-- name: ListEnabledWebhooks :many
+SELECT * FROM webhooks
+WHERE disabled_at IS NULL
+ORDER BY id;
+
+-- name: UpdateWebhook :one
+UPDATE webhooks
+SET url = $2, events = $3, disabled_at = $4, failure_count = $5
+WHERE id = $1
+RETURNING *;
+
+-- name: RecordWebhookSuccess :one
+UPDATE webhooks
+SET failure_count = 0
+WHERE id = $1
+RETURNING *;
+
+-- name: RecordWebhookFailure :one
+-- counts a failed attempt, disabling the webhook when it reaches disable_after
+UPDATE webhooks
+SET failure_count = failure_count + 1,
+ disabled_at = CASE
+  WHEN disabled_at IS NULL AND failure_count + 1 >= sqlc.arg(disable_after)::integer THEN now()
+  ELSE disabled_at
+ END
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+WHERE id = $1;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (
+ webhook_id,
+ event_type,
+ payload
+) VALUES (
+ $1, $2, $3
+) RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1 LIMIT 1;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = $1 AND id < $2
+ORDER BY id DESC
+LIMIT $3;
+
+-- name: ClaimWebhookDeliveries :many
+-- leases up to max_count due deliveries until lease_until, skipping those
+-- another worker holds
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg(lease_until)
+WHERE id IN (
+ SELECT d.id FROM webhook_deliveries d
+ WHERE d.status = 'pending' AND d.next_attempt_at <= sqlc.arg(now)
+ ORDER BY d.next_attempt_at, d.id
+ LIMIT sqlc.arg(max_count)
+ FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: UpdateWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = $2, attempts = $3, next_attempt_at = $4, response_status = $5, last_error = $6, delivered_at = $7
+WHERE id = $1
+RETURNING *;
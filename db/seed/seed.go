@@ -0,0 +1,193 @@
+// Package seed generates synthetic users and posts in realistic volumes so
+// performance problems can be reproduced locally.
+//
+// The output depends only on Config: running twice with the same seed and
+// sizes produces the same rows, apart from ids assigned by the database.
+// The schema has no boards, threads or reactions yet, so only users and posts
+// are generated.
+package seed
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+)
+
+// Config controls what Run generates
+type Config struct {
+	// Seed makes the generated data reproducible
+	Seed int64
+	// Users and Posts are the number of rows to generate
+	Users int
+	Posts int
+	// BatchSize is the number of rows handed to the Writer at once
+	BatchSize int
+	// Password is the plain text password of every generated user
+	Password string
+	// Posts are spread evenly over [Start, Start+Span)
+	Start time.Time
+	Span  time.Duration
+}
+
+// DefaultConfig is small enough to seed a development database in seconds
+func DefaultConfig() Config {
+	return Config{
+		Seed:      1,
+		Users:     100,
+		Posts:     1000,
+		BatchSize: 5000,
+		Password:  "password",
+		Start:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Span:      365 * 24 * time.Hour,
+	}
+}
+
+// Writer stores generated rows. IDs and CreatedAt of the rows passed in are
+// ignored unless the Writer says otherwise.
+type Writer interface {
+	// WriteUsers stores users and returns their ids in the same order
+	WriteUsers(ctx context.Context, users []db.User) ([]uint, error)
+	WritePosts(ctx context.Context, posts []db.Post) error
+}
+
+// Stats reports what Run wrote
+type Stats struct {
+	Users int
+	Posts int
+}
+
+// Run generates the data described by cfg and hands it to w in batches
+func Run(ctx context.Context, w Writer, cfg Config) (Stats, error) {
+	if cfg.Users <= 0 && cfg.Posts > 0 {
+		return Stats{}, fmt.Errorf("cannot generate %d posts without users", cfg.Posts)
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultConfig().BatchSize
+	}
+
+	// bcrypt is deliberately slow, so every user shares one hash
+	hash, err := utils.HashPassword(cfg.Password)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	g := newGenerator(cfg)
+	stats := Stats{}
+
+	userIDs := make([]uint, 0, cfg.Users)
+	for start := 0; start < cfg.Users; start += cfg.BatchSize {
+		n := min(cfg.BatchSize, cfg.Users-start)
+		users := make([]db.User, n)
+		for i := range users {
+			users[i] = g.user(start+i, hash)
+		}
+		ids, err := w.WriteUsers(ctx, users)
+		if err != nil {
+			return stats, fmt.Errorf("cannot write users: %w", err)
+		}
+		userIDs = append(userIDs, ids...)
+		stats.Users += n
+	}
+
+	for start := 0; start < cfg.Posts; start += cfg.BatchSize {
+		n := min(cfg.BatchSize, cfg.Posts-start)
+		posts := make([]db.Post, n)
+		for i := range posts {
+			posts[i] = g.post(start+i, userIDs)
+		}
+		if err := w.WritePosts(ctx, posts); err != nil {
+			return stats, fmt.Errorf("cannot write posts: %w", err)
+		}
+		stats.Posts += n
+	}
+	return stats, nil
+}
+
+var words = strings.Fields(`
+	the a an and or but so because if when while after before
+	board thread post reply question answer idea issue bug fix release update
+	today yesterday tomorrow morning evening weekend week month year
+	think know guess hope wonder agree disagree like love hate need want try
+	go golang postgres sqlite docker server client api request response cache
+	fast slow broken working great terrible interesting weird simple hard
+	anyone someone everyone nobody here there again still already just really
+	thanks please sorry lol hmm wow nice cool
+`)
+
+const alphabet = "abcdefghijkmnopqrstuvwxyz"
+
+// generator draws all randomness from one seeded source so the sequence of
+// rows only depends on Config
+type generator struct {
+	cfg  Config
+	rnd  *rand.Rand
+	zipf *rand.Zipf
+}
+
+func newGenerator(cfg Config) *generator {
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	g := &generator{cfg: cfg, rnd: rnd}
+	if cfg.Users > 1 {
+		// a few users write most of the posts, like on a real board
+		g.zipf = rand.NewZipf(rnd, 1.2, 1, uint64(cfg.Users-1))
+	}
+	return g
+}
+
+func (g *generator) user(i int, passwordHash string) db.User {
+	// the index suffix keeps user_str_id and email unique
+	userStrID := fmt.Sprintf("%s%d", g.name(), i)
+	return db.User{
+		UserStrID: userStrID,
+		Email:     userStrID + "@example.com",
+		Password:  passwordHash,
+		CreatedAt: g.cfg.Start,
+	}
+}
+
+func (g *generator) post(i int, userIDs []uint) db.Post {
+	author := 0
+	if g.zipf != nil {
+		author = int(g.zipf.Uint64())
+	}
+
+	// keep created_at increasing with the id, jittered within one slot
+	slot := g.cfg.Span / time.Duration(max(g.cfg.Posts, 1))
+	createdAt := g.cfg.Start.Add(time.Duration(i) * slot)
+	if slot > 0 {
+		createdAt = createdAt.Add(time.Duration(g.rnd.Int63n(int64(slot))))
+	}
+
+	return db.Post{
+		UserID:    userIDs[author],
+		Text:      g.text(),
+		CreatedAt: createdAt.Truncate(time.Microsecond),
+	}
+}
+
+func (g *generator) name() string {
+	n := 4 + g.rnd.Intn(6)
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		sb.WriteByte(alphabet[g.rnd.Intn(len(alphabet))])
+	}
+	return sb.String()
+}
+
+// text returns a sentence of mostly short posts with a long tail
+func (g *generator) text() string {
+	n := 3 + int(g.rnd.ExpFloat64()*12)
+	if n > 200 {
+		n = 200
+	}
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = words[g.rnd.Intn(len(words))]
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,119 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/config"
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.Users = 20
+	cfg.Posts = 150
+	cfg.BatchSize = 7
+	return cfg
+}
+
+func seedMemory(t *testing.T, cfg Config) ([]db.User, []db.Post) {
+	store := memory.NewStore()
+	stats, err := Run(context.Background(), NewStoreWriter(store), cfg)
+	require.NoError(t, err)
+	require.Equal(t, Stats{Users: cfg.Users, Posts: cfg.Posts}, stats)
+
+	users, err := store.ListUsers(context.Background(), db.ListUsersParams{Limit: int32(cfg.Users + 1)})
+	require.NoError(t, err)
+	posts, err := store.ListPosts(context.Background(), db.ListPostsParams{Limit: int32(cfg.Posts + 1)})
+	require.NoError(t, err)
+	return users, posts
+}
+
+func TestRunDeterministic(t *testing.T) {
+	cfg := testConfig()
+	users1, posts1 := seedMemory(t, cfg)
+	users2, posts2 := seedMemory(t, cfg)
+
+	require.Len(t, users1, cfg.Users)
+	require.Len(t, posts1, cfg.Posts)
+	for i := range users1 {
+		require.Equal(t, users1[i].UserStrID, users2[i].UserStrID)
+		require.Equal(t, users1[i].Email, users2[i].Email)
+	}
+	for i := range posts1 {
+		require.Equal(t, posts1[i].UserID, posts2[i].UserID)
+		require.Equal(t, posts1[i].Text, posts2[i].Text)
+	}
+
+	cfg.Seed++
+	users3, _ := seedMemory(t, cfg)
+	require.NotEqual(t, users1[0].UserStrID, users3[0].UserStrID)
+}
+
+func TestRunSkewedAuthors(t *testing.T) {
+	cfg := testConfig()
+	_, posts := seedMemory(t, cfg)
+
+	perUser := map[uint]int{}
+	for _, post := range posts {
+		perUser[post.UserID]++
+	}
+	top := 0
+	for _, n := range perUser {
+		top = max(top, n)
+	}
+	require.Greater(t, top, cfg.Posts/cfg.Users*2)
+}
+
+func TestGeneratorCreatedAt(t *testing.T) {
+	cfg := testConfig()
+	g := newGenerator(cfg)
+	userIDs := make([]uint, cfg.Users)
+
+	prev := time.Time{}
+	for i := 0; i < cfg.Posts; i++ {
+		post := g.post(i, userIDs)
+		require.False(t, post.CreatedAt.Before(cfg.Start))
+		require.True(t, post.CreatedAt.Before(cfg.Start.Add(cfg.Span)))
+		require.False(t, post.CreatedAt.Before(prev))
+		prev = post.CreatedAt
+	}
+}
+
+func TestRunPostsWithoutUsers(t *testing.T) {
+	cfg := testConfig()
+	cfg.Users = 0
+	_, err := Run(context.Background(), NewStoreWriter(memory.NewStore()), cfg)
+	require.Error(t, err)
+}
+
+func TestCopyWriter(t *testing.T) {
+	cfg, err := config.LoadConfig("../..")
+	require.NoError(t, err)
+	conn, err := sql.Open(cfg.DBDriver, cfg.DBSource)
+	require.NoError(t, err)
+	defer conn.Close()
+	if err := conn.Ping(); err != nil {
+		t.Skip("postgres not available:", err)
+	}
+
+	seedCfg := testConfig()
+	seedCfg.Seed = time.Now().UnixNano()
+	stats, err := Run(context.Background(), NewCopyWriter(conn), seedCfg)
+	require.NoError(t, err)
+	require.Equal(t, Stats{Users: seedCfg.Users, Posts: seedCfg.Posts}, stats)
+
+	// ids taken for COPY must not collide with normal inserts
+	store := db.NewStore(conn)
+	user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		UserStrID: "copywriter" + time.Now().Format("150405.000000"),
+		Email:     time.Now().Format("150405.000000") + "@copywriter.test",
+		Password:  "secret",
+	})
+	require.NoError(t, err)
+	require.NotZero(t, user.ID)
+}
@@ -0,0 +1,131 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/lib/pq"
+)
+
+// StoreWriter writes rows one at a time through a db.Store. It works with
+// every backend but is slow, and created_at is set by the store.
+type StoreWriter struct {
+	store db.Store
+}
+
+// NewStoreWriter returns a Writer inserting through store
+func NewStoreWriter(store db.Store) *StoreWriter {
+	return &StoreWriter{store: store}
+}
+
+func (w *StoreWriter) WriteUsers(ctx context.Context, users []db.User) ([]uint, error) {
+	ids := make([]uint, 0, len(users))
+	for _, u := range users {
+		user, err := w.store.CreateUser(ctx, db.CreateUserParams{
+			UserStrID: u.UserStrID,
+			Email:     u.Email,
+			Password:  u.Password,
+		})
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
+func (w *StoreWriter) WritePosts(ctx context.Context, posts []db.Post) error {
+	for _, p := range posts {
+		_, err := w.store.CreatePost(ctx, db.CreatePostParams{
+			UserID: p.UserID,
+			Text:   p.Text,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyWriter bulk loads rows into Postgres with COPY, one transaction per
+// batch. Unlike StoreWriter it keeps the generated created_at.
+type CopyWriter struct {
+	conn *sql.DB
+}
+
+// NewCopyWriter returns a Writer loading into the Postgres database conn
+func NewCopyWriter(conn *sql.DB) *CopyWriter {
+	return &CopyWriter{conn: conn}
+}
+
+func (w *CopyWriter) WriteUsers(ctx context.Context, users []db.User) ([]uint, error) {
+	// COPY does not return the generated ids, so take them from the
+	// sequence up front and insert them explicitly
+	ids, err := w.reserveIDs(ctx, "users", len(users))
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.copyIn(ctx, "users", []string{"id", "user_str_id", "email", "password", "created_at"}, len(users), func(i int) []interface{} {
+		u := users[i]
+		return []interface{}{ids[i], u.UserStrID, u.Email, u.Password, u.CreatedAt}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (w *CopyWriter) WritePosts(ctx context.Context, posts []db.Post) error {
+	return w.copyIn(ctx, "posts", []string{"user_id", "text", "created_at"}, len(posts), func(i int) []interface{} {
+		p := posts[i]
+		return []interface{}{p.UserID, p.Text, p.CreatedAt}
+	})
+}
+
+func (w *CopyWriter) reserveIDs(ctx context.Context, table string, n int) ([]uint, error) {
+	rows, err := w.conn.QueryContext(ctx, "SELECT nextval(pg_get_serial_sequence($1, 'id')) FROM generate_series(1, $2)", table, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uint, 0, n)
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (w *CopyWriter) copyIn(ctx context.Context, table string, columns []string, n int, row func(i int) []interface{}) error {
+	tx, err := w.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if _, err := stmt.ExecContext(ctx, row(i)...); err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+	// an Exec without arguments flushes the buffered rows
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error codes the application cares about.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	ForeignKeyViolation           = "23503"
+	UniqueViolation               = "23505"
+	InvalidRowCountInLimitClause  = "2201W"
+	InvalidRowCountInResultOffset = "2201X"
+)
+
+// ErrRecordNotFound is returned by single-row queries that match nothing
+var ErrRecordNotFound = sql.ErrNoRows
+
+// ErrorCode returns the Postgres error code of err, or "" if err is not a *pq.Error
+func ErrorCode(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
+	}
+	return ""
+}
+
+// ErrorConstraint returns the name of the constraint violated by err, if any
+func ErrorConstraint(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Constraint
+	}
+	return ""
+}
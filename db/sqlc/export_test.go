@@ -0,0 +1,8 @@
+package db
+
+import "database/sql"
+
+// SharedTestDB exposes the connection opened by TestMain to the db_test package
+func SharedTestDB() *sql.DB {
+	return testDB
+}
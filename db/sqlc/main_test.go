@@ -11,18 +11,19 @@ import (
 )
 
 var testQueries *Queries
+var testDB *sql.DB
 
 func TestMain(m *testing.M) {
 	cfg, err := config.LoadConfig("../..")
 	if err != nil {
 		log.Fatal("cannot load config:", err)
 	}
-	conn, err := sql.Open(cfg.DBDriver, cfg.DBSource)
+	testDB, err = sql.Open(cfg.DBDriver, cfg.DBSource)
 	if err != nil {
 		log.Fatal("cannot connect to db:", err)
 	}
 
-	testQueries = New(conn)
+	testQueries = New(testDB)
 
 	os.Exit(m.Run())
 }
@@ -0,0 +1,176 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: notification.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const countUnreadNotifications = `-- name: CountUnreadNotifications :one
+SELECT count(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL
+`
+
+func (q *Queries) CountUnreadNotifications(ctx context.Context, userID uint) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUnreadNotifications, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createNotification = `-- name: CreateNotification :one
+INSERT INTO notifications (
+ user_id,
+ type,
+ actor_id,
+ post_id
+) VALUES (
+ $1, $2, $3, $4
+)
+ON CONFLICT (user_id, type, post_id) DO NOTHING
+RETURNING id, user_id, type, actor_id, post_id, read_at, created_at
+`
+
+type CreateNotificationParams struct {
+	UserID  uint   `json:"user_id"`
+	Type    string `json:"type"`
+	ActorID uint   `json:"actor_id"`
+	PostID  uint   `json:"post_id"`
+}
+
+// notifies a user. A notification of the same type about the same post is
+// not created again, and no row is returned.
+func (q *Queries) CreateNotification(ctx context.Context, arg CreateNotificationParams) (Notification, error) {
+	row := q.db.QueryRowContext(ctx, createNotification,
+		arg.UserID,
+		arg.Type,
+		arg.ActorID,
+		arg.PostID,
+	)
+	var i Notification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Type,
+		&i.ActorID,
+		&i.PostID,
+		&i.ReadAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getNotification = `-- name: GetNotification :one
+SELECT id, user_id, type, actor_id, post_id, read_at, created_at FROM notifications
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetNotification(ctx context.Context, id int64) (Notification, error) {
+	row := q.db.QueryRowContext(ctx, getNotification, id)
+	var i Notification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Type,
+		&i.ActorID,
+		&i.PostID,
+		&i.ReadAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listNotifications = `-- name: ListNotifications :many
+SELECT id, user_id, type, actor_id, post_id, read_at, created_at FROM notifications
+WHERE user_id = $1 AND id < $2
+ORDER BY id DESC
+LIMIT $3
+`
+
+type ListNotificationsParams struct {
+	UserID uint  `json:"user_id"`
+	ID     int64 `json:"id"`
+	Limit  int32 `json:"limit"`
+}
+
+func (q *Queries) ListNotifications(ctx context.Context, arg ListNotificationsParams) ([]Notification, error) {
+	rows, err := q.db.QueryContext(ctx, listNotifications, arg.UserID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Notification{}
+	for rows.Next() {
+		var i Notification
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Type,
+			&i.ActorID,
+			&i.PostID,
+			&i.ReadAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markAllNotificationsRead = `-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = $1::timestamptz
+WHERE user_id = $2 AND read_at IS NULL
+`
+
+type MarkAllNotificationsReadParams struct {
+	ReadAt time.Time `json:"read_at"`
+	UserID uint      `json:"user_id"`
+}
+
+func (q *Queries) MarkAllNotificationsRead(ctx context.Context, arg MarkAllNotificationsReadParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markAllNotificationsRead, arg.ReadAt, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const markNotificationRead = `-- name: MarkNotificationRead :one
+UPDATE notifications
+SET read_at = COALESCE(read_at, $1::timestamptz)
+WHERE id = $2
+RETURNING id, user_id, type, actor_id, post_id, read_at, created_at
+`
+
+type MarkNotificationReadParams struct {
+	ReadAt time.Time `json:"read_at"`
+	ID     int64     `json:"id"`
+}
+
+// keeps the time a notification was first read
+func (q *Queries) MarkNotificationRead(ctx context.Context, arg MarkNotificationReadParams) (Notification, error) {
+	row := q.db.QueryRowContext(ctx, markNotificationRead, arg.ReadAt, arg.ID)
+	var i Notification
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Type,
+		&i.ActorID,
+		&i.PostID,
+		&i.ReadAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -7,30 +7,37 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 const createPost = `-- name: CreatePost :one
 INSERT INTO posts (
  user_id,
- text
+ text,
+ parent_id
 ) VALUES (
- $1, $2
-) RETURNING id, user_id, text, created_at
+ $1, $2, $3
+) RETURNING id, user_id, text, created_at, parent_id
 `
 
 type CreatePostParams struct {
-	UserID uint   `json:"user_id"`
-	Text   string `json:"text"`
+	UserID   uint          `json:"user_id"`
+	Text     string        `json:"text"`
+	ParentID sql.NullInt64 `json:"parent_id"`
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost, arg.UserID, arg.Text)
+	row := q.db.QueryRowContext(ctx, createPost, arg.UserID, arg.Text, arg.ParentID)
 	var i Post
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
 		&i.Text,
 		&i.CreatedAt,
+		&i.ParentID,
 	)
 	return i, err
 }
@@ -45,8 +52,78 @@ func (q *Queries) DeletePost(ctx context.Context, id uint) error {
 	return err
 }
 
+const deletePostsByUser = `-- name: DeletePostsByUser :many
+DELETE FROM posts
+WHERE user_id = $1
+RETURNING id, user_id, text, created_at, parent_id
+`
+
+func (q *Queries) DeletePostsByUser(ctx context.Context, userID uint) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, deletePostsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Post{}
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Text,
+			&i.CreatedAt,
+			&i.ParentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deletePostsCreatedBefore = `-- name: DeletePostsCreatedBefore :many
+DELETE FROM posts
+WHERE created_at < $1
+RETURNING id, user_id, text, created_at, parent_id
+`
+
+func (q *Queries) DeletePostsCreatedBefore(ctx context.Context, createdAt time.Time) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, deletePostsCreatedBefore, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Post{}
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Text,
+			&i.CreatedAt,
+			&i.ParentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPost = `-- name: GetPost :one
-SELECT id, user_id, text, created_at FROM posts
+SELECT id, user_id, text, created_at, parent_id FROM posts
 WHERE id = $1 LIMIT 1
 `
 
@@ -58,12 +135,13 @@ func (q *Queries) GetPost(ctx context.Context, id uint) (Post, error) {
 		&i.UserID,
 		&i.Text,
 		&i.CreatedAt,
+		&i.ParentID,
 	)
 	return i, err
 }
 
 const listPosts = `-- name: ListPosts :many
-SELECT id, user_id, text, created_at FROM posts
+SELECT id, user_id, text, created_at, parent_id FROM posts
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -88,6 +166,99 @@ func (q *Queries) ListPosts(ctx context.Context, arg ListPostsParams) ([]Post, e
 			&i.UserID,
 			&i.Text,
 			&i.CreatedAt,
+			&i.ParentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReplies = `-- name: ListReplies :many
+SELECT id, user_id, text, created_at, parent_id FROM posts
+WHERE parent_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3
+`
+
+type ListRepliesParams struct {
+	ParentID sql.NullInt64 `json:"parent_id"`
+	Limit    int32         `json:"limit"`
+	Offset   int32         `json:"offset"`
+}
+
+func (q *Queries) ListReplies(ctx context.Context, arg ListRepliesParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, listReplies, arg.ParentID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Post{}
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Text,
+			&i.CreatedAt,
+			&i.ParentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRepliesByParentIDs = `-- name: ListRepliesByParentIDs :many
+SELECT id, user_id, text, created_at, parent_id FROM posts
+WHERE id IN (
+  SELECT numbered.id FROM (
+    SELECT replies.id, row_number() OVER (PARTITION BY replies.parent_id ORDER BY replies.id) AS n
+    FROM posts AS replies
+    WHERE replies.parent_id = ANY($1::bigint[])
+  ) AS numbered
+  WHERE numbered.n - $2::int BETWEEN 1 AND $3::int
+)
+ORDER BY parent_id, id
+`
+
+type ListRepliesByParentIDsParams struct {
+	ParentIds []int64 `json:"parent_ids"`
+	Offset    int32   `json:"offset"`
+	Limit     int32   `json:"limit"`
+}
+
+// the same window of replies to each of the parents
+func (q *Queries) ListRepliesByParentIDs(ctx context.Context, arg ListRepliesByParentIDsParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, listRepliesByParentIDs, pq.Array(arg.ParentIds), arg.Offset, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Post{}
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Text,
+			&i.CreatedAt,
+			&i.ParentID,
 		); err != nil {
 			return nil, err
 		}
@@ -106,7 +277,7 @@ const updatePost = `-- name: UpdatePost :one
 UPDATE posts
   set text = $2
 WHERE id = $1
-RETURNING id, user_id, text, created_at
+RETURNING id, user_id, text, created_at, parent_id
 `
 
 type UpdatePostParams struct {
@@ -122,6 +293,7 @@ func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, e
 		&i.UserID,
 		&i.Text,
 		&i.CreatedAt,
+		&i.ParentID,
 	)
 	return i, err
 }
@@ -0,0 +1,84 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: post_mention.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+const createPostMention = `-- name: CreatePostMention :exec
+INSERT INTO post_mentions (
+ post_id,
+ user_id,
+ start_offset,
+ end_offset
+) VALUES (
+ $1, $2, $3, $4
+)
+`
+
+type CreatePostMentionParams struct {
+	PostID      uint  `json:"post_id"`
+	UserID      uint  `json:"user_id"`
+	StartOffset int32 `json:"start_offset"`
+	EndOffset   int32 `json:"end_offset"`
+}
+
+func (q *Queries) CreatePostMention(ctx context.Context, arg CreatePostMentionParams) error {
+	_, err := q.db.ExecContext(ctx, createPostMention,
+		arg.PostID,
+		arg.UserID,
+		arg.StartOffset,
+		arg.EndOffset,
+	)
+	return err
+}
+
+const deletePostMentions = `-- name: DeletePostMentions :exec
+DELETE FROM post_mentions
+WHERE post_id = $1
+`
+
+func (q *Queries) DeletePostMentions(ctx context.Context, postID uint) error {
+	_, err := q.db.ExecContext(ctx, deletePostMentions, postID)
+	return err
+}
+
+const listPostMentions = `-- name: ListPostMentions :many
+SELECT post_id, user_id, start_offset, end_offset FROM post_mentions
+WHERE post_id = ANY($1::bigint[])
+ORDER BY post_id, start_offset
+`
+
+func (q *Queries) ListPostMentions(ctx context.Context, postIds []int64) ([]PostMention, error) {
+	rows, err := q.db.QueryContext(ctx, listPostMentions, pq.Array(postIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PostMention{}
+	for rows.Next() {
+		var i PostMention
+		if err := rows.Scan(
+			&i.PostID,
+			&i.UserID,
+			&i.StartOffset,
+			&i.EndOffset,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
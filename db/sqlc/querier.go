@@ -6,21 +6,87 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
 type Querier interface {
+	// leases up to max_count due jobs of kind until lease_until, skipping those
+	// another worker holds. Running jobs whose lease ran out are due again.
+	ClaimJobs(ctx context.Context, arg ClaimJobsParams) ([]Job, error)
+	// leases up to max_count due deliveries until lease_until, skipping those
+	// another worker holds
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	CountUnreadNotifications(ctx context.Context, userID uint) (int64, error)
+	// queues a job. A job with the unique key of one queued before is not
+	// queued, and no row is returned.
+	CreateJob(ctx context.Context, arg CreateJobParams) (Job, error)
+	// notifies a user. A notification of the same type about the same post is
+	// not created again, and no row is returned.
+	CreateNotification(ctx context.Context, arg CreateNotificationParams) (Notification, error)
 	CreatePost(ctx context.Context, arg CreatePostParams) (Post, error)
+	// The lock is held until the transaction ends, so events get their ids in
+	// the order their transactions commit: a reader that has seen an event has
+	// seen every event with a smaller id.
+	CreatePostEvent(ctx context.Context, arg CreatePostEventParams) (PostEvent, error)
+	CreatePostMention(ctx context.Context, arg CreatePostMentionParams) error
+	CreatePostTag(ctx context.Context, arg CreatePostTagParams) error
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error)
 	DeletePost(ctx context.Context, id uint) error
+	DeletePostMentions(ctx context.Context, postID uint) error
+	DeletePostTags(ctx context.Context, postID uint) error
+	DeletePostsByUser(ctx context.Context, userID uint) ([]Post, error)
+	DeletePostsCreatedBefore(ctx context.Context, createdAt time.Time) ([]Post, error)
+	DeleteSucceededJobs(ctx context.Context, before time.Time) (int64, error)
 	DeleteUser(ctx context.Context, id uint) error
+	DeleteWebhook(ctx context.Context, id int64) error
+	GetJob(ctx context.Context, id int64) (Job, error)
+	GetLastPostEventID(ctx context.Context) (int64, error)
+	GetNotification(ctx context.Context, id int64) (Notification, error)
 	GetPost(ctx context.Context, id uint) (Post, error)
 	GetUser(ctx context.Context, id uint) (User, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserByUserStrId(ctx context.Context, userStrID string) (User, error)
 	GetUserStrIdById(ctx context.Context, id uint) (string, error)
+	GetWebhook(ctx context.Context, id int64) (Webhook, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	ListEnabledWebhooks(ctx context.Context) ([]Webhook, error)
+	ListJobsByStatus(ctx context.Context, arg ListJobsByStatusParams) ([]Job, error)
+	ListNotifications(ctx context.Context, arg ListNotificationsParams) ([]Notification, error)
+	ListPostEventsAfter(ctx context.Context, arg ListPostEventsAfterParams) ([]PostEvent, error)
+	ListPostMentions(ctx context.Context, postIds []int64) ([]PostMention, error)
+	ListPostTags(ctx context.Context, postIds []int64) ([]ListPostTagsRow, error)
 	ListPosts(ctx context.Context, arg ListPostsParams) ([]Post, error)
+	ListPostsByTag(ctx context.Context, arg ListPostsByTagParams) ([]Post, error)
+	ListReplies(ctx context.Context, arg ListRepliesParams) ([]Post, error)
+	// the same window of replies to each of the parents
+	ListRepliesByParentIDs(ctx context.Context, arg ListRepliesByParentIDsParams) ([]Post, error)
+	ListTrendingTags(ctx context.Context, arg ListTrendingTagsParams) ([]ListTrendingTagsRow, error)
 	ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error)
+	ListUsersByIDs(ctx context.Context, ids []int64) ([]User, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhooksByUser(ctx context.Context, userID uint) ([]Webhook, error)
+	MarkAllNotificationsRead(ctx context.Context, arg MarkAllNotificationsReadParams) (int64, error)
+	// keeps the time a notification was first read
+	MarkNotificationRead(ctx context.Context, arg MarkNotificationReadParams) (Notification, error)
+	// counts a failed attempt, disabling the webhook when it reaches disable_after
+	RecordWebhookFailure(ctx context.Context, arg RecordWebhookFailureParams) (Webhook, error)
+	RecordWebhookSuccess(ctx context.Context, id int64) (Webhook, error)
+	// ends the lease of a claimed job, unless it ran out and the job was
+	// claimed again since
+	ReleaseJob(ctx context.Context, arg ReleaseJobParams) error
+	// queues a dead job again, with its attempts reset
+	RequeueJob(ctx context.Context, arg RequeueJobParams) (Job, error)
+	RevokeUserTokens(ctx context.Context, id uint) (User, error)
+	SearchTags(ctx context.Context, arg SearchTagsParams) ([]SearchTagsRow, error)
 	UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserLockedAt(ctx context.Context, arg UpdateUserLockedAtParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpdateWebhook(ctx context.Context, arg UpdateWebhookParams) (Webhook, error)
+	UpdateWebhookDelivery(ctx context.Context, arg UpdateWebhookDeliveryParams) (WebhookDelivery, error)
+	UpsertTag(ctx context.Context, name string) (Tag, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -1,11 +1,17 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 )
 
 type Store interface {
 	Querier
+	// ExecTx runs fn in a transaction, committed if fn returns nil and
+	// rolled back otherwise. The Store fn is given runs its queries in the
+	// transaction; calling its ExecTx runs in the same transaction too.
+	ExecTx(ctx context.Context, fn func(Store) error) error
 }
 
 // Store provides all functions to execute DB queries
@@ -21,3 +27,26 @@ func NewStore(db *sql.DB) Store {
 		Queries: New(db),
 	}
 }
+
+func (s *SQLStore) ExecTx(ctx context.Context, fn func(Store) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(txStore{s.WithTx(tx)}); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// txStore runs its queries in a transaction already begun
+type txStore struct {
+	*Queries
+}
+
+func (s txStore) ExecTx(ctx context.Context, fn func(Store) error) error {
+	return fn(s)
+}
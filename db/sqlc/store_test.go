@@ -0,0 +1,14 @@
+package db_test
+
+import (
+	"testing"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/db/storetest"
+)
+
+func TestStoreConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) db.Store {
+		return db.NewStore(db.SharedTestDB())
+	})
+}
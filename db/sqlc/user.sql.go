@@ -7,6 +7,9 @@ package db
 
 import (
 	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -16,7 +19,7 @@ INSERT INTO users (
  password 
 ) VALUES (
  $1, $2, $3
-) RETURNING id, user_str_id, email, password, created_at
+) RETURNING id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at
 `
 
 type CreateUserParams struct {
@@ -34,6 +37,9 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.Password,
 		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
 	)
 	return i, err
 }
@@ -49,7 +55,7 @@ func (q *Queries) DeleteUser(ctx context.Context, id uint) error {
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, user_str_id, email, password, created_at FROM users
+SELECT id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at FROM users
 WHERE id = $1 LIMIT 1
 `
 
@@ -62,12 +68,15 @@ func (q *Queries) GetUser(ctx context.Context, id uint) (User, error) {
 		&i.Email,
 		&i.Password,
 		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, user_str_id, email, password, created_at FROM users
+SELECT id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at FROM users
 WHERE email = $1 LIMIT 1
 `
 
@@ -80,6 +89,30 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.Password,
 		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
+	)
+	return i, err
+}
+
+const getUserByUserStrId = `-- name: GetUserByUserStrId :one
+SELECT id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at FROM users
+WHERE user_str_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserByUserStrId(ctx context.Context, userStrID string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByUserStrId, userStrID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserStrID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
 	)
 	return i, err
 }
@@ -97,7 +130,7 @@ func (q *Queries) GetUserStrIdById(ctx context.Context, id uint) (string, error)
 }
 
 const listUsers = `-- name: ListUsers :many
-SELECT id, user_str_id, email, password, created_at FROM users
+SELECT id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at FROM users
 ORDER BY id
 LIMIT $1
 OFFSET $2
@@ -123,6 +156,9 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 			&i.Email,
 			&i.Password,
 			&i.CreatedAt,
+			&i.Role,
+			&i.LockedAt,
+			&i.TokensRevokedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -137,13 +173,74 @@ func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, e
 	return items, nil
 }
 
+const listUsersByIDs = `-- name: ListUsersByIDs :many
+SELECT id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at FROM users
+WHERE id = ANY($1::bigint[])
+ORDER BY id
+`
+
+func (q *Queries) ListUsersByIDs(ctx context.Context, ids []int64) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserStrID,
+			&i.Email,
+			&i.Password,
+			&i.CreatedAt,
+			&i.Role,
+			&i.LockedAt,
+			&i.TokensRevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeUserTokens = `-- name: RevokeUserTokens :one
+UPDATE users
+  set tokens_revoked_at = now()
+WHERE id = $1
+RETURNING id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at
+`
+
+func (q *Queries) RevokeUserTokens(ctx context.Context, id uint) (User, error) {
+	row := q.db.QueryRowContext(ctx, revokeUserTokens, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserStrID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
+	)
+	return i, err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
   set user_str_id = $2,
   email = $3,
   password = $4
 WHERE id = $1
-RETURNING id, user_str_id, email, password, created_at
+RETURNING id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at
 `
 
 type UpdateUserParams struct {
@@ -167,6 +264,65 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.Password,
 		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
+	)
+	return i, err
+}
+
+const updateUserLockedAt = `-- name: UpdateUserLockedAt :one
+UPDATE users
+  set locked_at = $2
+WHERE id = $1
+RETURNING id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at
+`
+
+type UpdateUserLockedAtParams struct {
+	ID       uint         `json:"id"`
+	LockedAt sql.NullTime `json:"locked_at"`
+}
+
+func (q *Queries) UpdateUserLockedAt(ctx context.Context, arg UpdateUserLockedAtParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserLockedAt, arg.ID, arg.LockedAt)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserStrID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+  set role = $2
+WHERE id = $1
+RETURNING id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at
+`
+
+type UpdateUserRoleParams struct {
+	ID   uint   `json:"id"`
+	Role string `json:"role"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserStrID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,459 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: webhook.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const claimWebhookDeliveries = `-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET next_attempt_at = $1
+WHERE id IN (
+ SELECT d.id FROM webhook_deliveries d
+ WHERE d.status = 'pending' AND d.next_attempt_at <= $2
+ ORDER BY d.next_attempt_at, d.id
+ LIMIT $3
+ FOR UPDATE SKIP LOCKED
+)
+RETURNING id, webhook_id, event_type, payload, status, attempts, next_attempt_at, response_status, last_error, delivered_at, created_at
+`
+
+type ClaimWebhookDeliveriesParams struct {
+	LeaseUntil time.Time `json:"lease_until"`
+	Now        time.Time `json:"now"`
+	MaxCount   int32     `json:"max_count"`
+}
+
+// leases up to max_count due deliveries until lease_until, skipping those
+// another worker holds
+func (q *Queries) ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, claimWebhookDeliveries, arg.LeaseUntil, arg.Now, arg.MaxCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookDelivery{}
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.EventType,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.ResponseStatus,
+			&i.LastError,
+			&i.DeliveredAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createWebhook = `-- name: CreateWebhook :one
+INSERT INTO webhooks (
+ user_id,
+ url,
+ secret,
+ events
+) VALUES (
+ $1, $2, $3, $4
+) RETURNING id, user_id, url, secret, events, failure_count, disabled_at, created_at
+`
+
+type CreateWebhookParams struct {
+	UserID uint   `json:"user_id"`
+	Url    string `json:"url"`
+	Secret string `json:"secret"`
+	Events string `json:"events"`
+}
+
+func (q *Queries) CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, createWebhook,
+		arg.UserID,
+		arg.Url,
+		arg.Secret,
+		arg.Events,
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.Events,
+		&i.FailureCount,
+		&i.DisabledAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createWebhookDelivery = `-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (
+ webhook_id,
+ event_type,
+ payload
+) VALUES (
+ $1, $2, $3
+) RETURNING id, webhook_id, event_type, payload, status, attempts, next_attempt_at, response_status, last_error, delivered_at, created_at
+`
+
+type CreateWebhookDeliveryParams struct {
+	WebhookID int64           `json:"webhook_id"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, createWebhookDelivery, arg.WebhookID, arg.EventType, arg.Payload)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.EventType,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.ResponseStatus,
+		&i.LastError,
+		&i.DeliveredAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteWebhook = `-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+WHERE id = $1
+`
+
+func (q *Queries) DeleteWebhook(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteWebhook, id)
+	return err
+}
+
+const getWebhook = `-- name: GetWebhook :one
+SELECT id, user_id, url, secret, events, failure_count, disabled_at, created_at FROM webhooks
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetWebhook(ctx context.Context, id int64) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, getWebhook, id)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.Events,
+		&i.FailureCount,
+		&i.DisabledAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getWebhookDelivery = `-- name: GetWebhookDelivery :one
+SELECT id, webhook_id, event_type, payload, status, attempts, next_attempt_at, response_status, last_error, delivered_at, created_at FROM webhook_deliveries
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookDelivery, id)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.EventType,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.ResponseStatus,
+		&i.LastError,
+		&i.DeliveredAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listEnabledWebhooks = `-- name: ListEnabledWebhooks :many
+SELECT id, user_id, url, secret, events, failure_count, disabled_at, created_at FROM webhooks
+WHERE disabled_at IS NULL
+ORDER BY id
+`
+
+func (q *Queries) ListEnabledWebhooks(ctx context.Context) ([]Webhook, error) {
+	rows, err := q.db.QueryContext(ctx, listEnabledWebhooks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Webhook{}
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Url,
+			&i.Secret,
+			&i.Events,
+			&i.FailureCount,
+			&i.DisabledAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookDeliveries = `-- name: ListWebhookDeliveries :many
+SELECT id, webhook_id, event_type, payload, status, attempts, next_attempt_at, response_status, last_error, delivered_at, created_at FROM webhook_deliveries
+WHERE webhook_id = $1 AND id < $2
+ORDER BY id DESC
+LIMIT $3
+`
+
+type ListWebhookDeliveriesParams struct {
+	WebhookID int64 `json:"webhook_id"`
+	ID        int64 `json:"id"`
+	Limit     int32 `json:"limit"`
+}
+
+func (q *Queries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, listWebhookDeliveries, arg.WebhookID, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookDelivery{}
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.WebhookID,
+			&i.EventType,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.NextAttemptAt,
+			&i.ResponseStatus,
+			&i.LastError,
+			&i.DeliveredAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhooksByUser = `-- name: ListWebhooksByUser :many
+SELECT id, user_id, url, secret, events, failure_count, disabled_at, created_at FROM webhooks
+WHERE user_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListWebhooksByUser(ctx context.Context, userID uint) ([]Webhook, error) {
+	rows, err := q.db.QueryContext(ctx, listWebhooksByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Webhook{}
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Url,
+			&i.Secret,
+			&i.Events,
+			&i.FailureCount,
+			&i.DisabledAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordWebhookFailure = `-- name: RecordWebhookFailure :one
+UPDATE webhooks
+SET failure_count = failure_count + 1,
+ disabled_at = CASE
+  WHEN disabled_at IS NULL AND failure_count + 1 >= $1::integer THEN now()
+  ELSE disabled_at
+ END
+WHERE id = $2
+RETURNING id, user_id, url, secret, events, failure_count, disabled_at, created_at
+`
+
+type RecordWebhookFailureParams struct {
+	DisableAfter int32 `json:"disable_after"`
+	ID           int64 `json:"id"`
+}
+
+// counts a failed attempt, disabling the webhook when it reaches disable_after
+func (q *Queries) RecordWebhookFailure(ctx context.Context, arg RecordWebhookFailureParams) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, recordWebhookFailure, arg.DisableAfter, arg.ID)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.Events,
+		&i.FailureCount,
+		&i.DisabledAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const recordWebhookSuccess = `-- name: RecordWebhookSuccess :one
+UPDATE webhooks
+SET failure_count = 0
+WHERE id = $1
+RETURNING id, user_id, url, secret, events, failure_count, disabled_at, created_at
+`
+
+func (q *Queries) RecordWebhookSuccess(ctx context.Context, id int64) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, recordWebhookSuccess, id)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.Events,
+		&i.FailureCount,
+		&i.DisabledAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const updateWebhook = `-- name: UpdateWebhook :one
+UPDATE webhooks
+SET url = $2, events = $3, disabled_at = $4, failure_count = $5
+WHERE id = $1
+RETURNING id, user_id, url, secret, events, failure_count, disabled_at, created_at
+`
+
+type UpdateWebhookParams struct {
+	ID           int64        `json:"id"`
+	Url          string       `json:"url"`
+	Events       string       `json:"events"`
+	DisabledAt   sql.NullTime `json:"disabled_at"`
+	FailureCount int32        `json:"failure_count"`
+}
+
+func (q *Queries) UpdateWebhook(ctx context.Context, arg UpdateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, updateWebhook,
+		arg.ID,
+		arg.Url,
+		arg.Events,
+		arg.DisabledAt,
+		arg.FailureCount,
+	)
+	var i Webhook
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Url,
+		&i.Secret,
+		&i.Events,
+		&i.FailureCount,
+		&i.DisabledAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const updateWebhookDelivery = `-- name: UpdateWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = $2, attempts = $3, next_attempt_at = $4, response_status = $5, last_error = $6, delivered_at = $7
+WHERE id = $1
+RETURNING id, webhook_id, event_type, payload, status, attempts, next_attempt_at, response_status, last_error, delivered_at, created_at
+`
+
+type UpdateWebhookDeliveryParams struct {
+	ID             int64        `json:"id"`
+	Status         string       `json:"status"`
+	Attempts       int32        `json:"attempts"`
+	NextAttemptAt  time.Time    `json:"next_attempt_at"`
+	ResponseStatus int32        `json:"response_status"`
+	LastError      string       `json:"last_error"`
+	DeliveredAt    sql.NullTime `json:"delivered_at"`
+}
+
+func (q *Queries) UpdateWebhookDelivery(ctx context.Context, arg UpdateWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, updateWebhookDelivery,
+		arg.ID,
+		arg.Status,
+		arg.Attempts,
+		arg.NextAttemptAt,
+		arg.ResponseStatus,
+		arg.LastError,
+		arg.DeliveredAt,
+	)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.WebhookID,
+		&i.EventType,
+		&i.Payload,
+		&i.Status,
+		&i.Attempts,
+		&i.NextAttemptAt,
+		&i.ResponseStatus,
+		&i.LastError,
+		&i.DeliveredAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
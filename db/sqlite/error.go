@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/lib/pq"
+	sqlitedriver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// uniqueColumns captures "users.email" from "UNIQUE constraint failed: users.email"
+var uniqueColumns = regexp.MustCompile(`UNIQUE constraint failed: ([\w.]+)`)
+
+// translateError turns SQLite constraint failures into the *pq.Error values
+// the Postgres store returns, so callers can use db.ErrorCode on any backend.
+// Other errors are returned unchanged.
+func translateError(err error) error {
+	var sqliteErr *sqlitedriver.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		pqErr := &pq.Error{
+			Code:    db.UniqueViolation,
+			Message: sqliteErr.Error(),
+		}
+		if m := uniqueColumns.FindStringSubmatch(sqliteErr.Error()); m != nil {
+			table, column, _ := strings.Cut(m[1], ".")
+			pqErr.Table = table
+			pqErr.Constraint = table + "_" + column + "_key"
+		}
+		return pqErr
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return &pq.Error{
+			Code:    db.ForeignKeyViolation,
+			Message: sqliteErr.Error(),
+		}
+	}
+	return err
+}
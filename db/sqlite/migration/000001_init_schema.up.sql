@@ -0,0 +1,17 @@
+CREATE TABLE users (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  user_str_id varchar NOT NULL,
+  email varchar NOT NULL,
+  password varchar NOT NULL,
+  created_at datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  CONSTRAINT users_user_str_id_key UNIQUE (user_str_id),
+  CONSTRAINT users_email_key UNIQUE (email)
+);
+
+CREATE TABLE posts (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  user_id integer NOT NULL,
+  text text NOT NULL,
+  created_at datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  CONSTRAINT posts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN tokens_revoked_at;
+ALTER TABLE users DROP COLUMN locked_at;
+ALTER TABLE users DROP COLUMN role;
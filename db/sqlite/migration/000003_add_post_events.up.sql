@@ -0,0 +1,7 @@
+CREATE TABLE post_events (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  type varchar NOT NULL,
+  post_id integer NOT NULL,
+  payload text NOT NULL,
+  created_at datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
@@ -0,0 +1,31 @@
+CREATE TABLE webhooks (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  user_id integer NOT NULL,
+  url varchar NOT NULL,
+  secret varchar NOT NULL,
+  events varchar NOT NULL,
+  failure_count integer NOT NULL DEFAULT 0,
+  disabled_at datetime,
+  created_at datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  CONSTRAINT webhooks_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX webhooks_user_id_idx ON webhooks (user_id);
+
+CREATE TABLE webhook_deliveries (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  webhook_id integer NOT NULL,
+  event_type varchar NOT NULL,
+  payload text NOT NULL,
+  status varchar NOT NULL DEFAULT 'pending',
+  attempts integer NOT NULL DEFAULT 0,
+  next_attempt_at datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  response_status integer NOT NULL DEFAULT 0,
+  last_error varchar NOT NULL DEFAULT '',
+  delivered_at datetime,
+  created_at datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  CONSTRAINT webhook_deliveries_webhook_id_fkey FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+);
+
+CREATE INDEX webhook_deliveries_webhook_id_id_idx ON webhook_deliveries (webhook_id, id);
+CREATE INDEX webhook_deliveries_next_attempt_at_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,17 @@
+CREATE TABLE jobs (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  kind varchar NOT NULL,
+  payload text NOT NULL,
+  unique_key varchar,
+  status varchar NOT NULL DEFAULT 'pending',
+  attempts integer NOT NULL DEFAULT 0,
+  max_attempts integer NOT NULL,
+  run_at datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  last_error varchar NOT NULL DEFAULT '',
+  finished_at datetime,
+  created_at datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now'))
+);
+
+CREATE UNIQUE INDEX jobs_unique_key_idx ON jobs (unique_key);
+CREATE INDEX jobs_kind_run_at_idx ON jobs (kind, run_at) WHERE status IN ('pending', 'running');
+CREATE INDEX jobs_status_id_idx ON jobs (status, id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS notifications;
+
+DROP INDEX IF EXISTS posts_parent_id_idx;
+ALTER TABLE posts DROP COLUMN parent_id;
@@ -0,0 +1,21 @@
+ALTER TABLE posts ADD COLUMN parent_id integer CONSTRAINT posts_parent_id_fkey REFERENCES posts (id) ON DELETE SET NULL;
+
+CREATE INDEX posts_parent_id_idx ON posts (parent_id);
+
+CREATE TABLE notifications (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  user_id integer NOT NULL,
+  type varchar NOT NULL,
+  actor_id integer NOT NULL,
+  post_id integer NOT NULL,
+  read_at datetime,
+  created_at datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  CONSTRAINT notifications_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+  CONSTRAINT notifications_actor_id_fkey FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE CASCADE,
+  CONSTRAINT notifications_post_id_fkey FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX notifications_user_id_type_post_id_idx ON notifications (user_id, type, post_id);
+CREATE INDEX notifications_user_id_id_idx ON notifications (user_id, id);
+CREATE INDEX notifications_user_id_idx ON notifications (user_id) WHERE read_at IS NULL;
+CREATE INDEX notifications_post_id_idx ON notifications (post_id);
@@ -0,0 +1,11 @@
+CREATE TABLE post_mentions (
+  post_id integer NOT NULL,
+  user_id integer NOT NULL,
+  start_offset integer NOT NULL,
+  end_offset integer NOT NULL,
+  PRIMARY KEY (post_id, start_offset),
+  CONSTRAINT post_mentions_post_id_fkey FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+  CONSTRAINT post_mentions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX post_mentions_user_id_idx ON post_mentions (user_id);
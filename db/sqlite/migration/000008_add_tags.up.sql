@@ -0,0 +1,16 @@
+CREATE TABLE tags (
+  id integer PRIMARY KEY AUTOINCREMENT,
+  name varchar NOT NULL,
+  created_at datetime NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f', 'now')),
+  CONSTRAINT tags_name_key UNIQUE (name)
+);
+
+CREATE TABLE post_tags (
+  post_id integer NOT NULL,
+  tag_id integer NOT NULL,
+  PRIMARY KEY (post_id, tag_id),
+  CONSTRAINT post_tags_post_id_fkey FOREIGN KEY (post_id) REFERENCES posts (id) ON DELETE CASCADE,
+  CONSTRAINT post_tags_tag_id_fkey FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+);
+
+CREATE INDEX post_tags_tag_id_post_id_idx ON post_tags (tag_id, post_id);
@@ -0,0 +1,55 @@
+-- name: CreateJob :one
+-- queues a job. A job with the unique key of one queued before is not
+-- queued, and no row is returned.
+INSERT INTO jobs (
+ kind,
+ payload,
+ unique_key,
+ max_attempts,
+ run_at
+) VALUES (
+ ?, ?, ?, ?, ?
+)
+ON CONFLICT (unique_key) DO NOTHING
+RETURNING *;
+
+-- name: GetJob :one
+SELECT * FROM jobs
+WHERE id = ? LIMIT 1;
+
+-- name: ListJobsByStatus :many
+SELECT * FROM jobs
+WHERE status = ? AND id < ?
+ORDER BY id DESC
+LIMIT ?;
+
+-- name: ClaimJobs :many
+-- leases up to max_count due jobs of kind until lease_until. SQLite has a
+-- single writer, so no other worker can claim them in the meantime.
+UPDATE jobs
+SET status = 'running', attempts = attempts + 1, run_at = sqlc.arg(lease_until)
+WHERE id IN (
+ SELECT j.id FROM jobs j
+ WHERE j.kind = sqlc.arg(kind) AND j.status IN ('pending', 'running') AND j.run_at <= sqlc.arg(now)
+ ORDER BY j.run_at, j.id
+ LIMIT sqlc.arg(max_count)
+)
+RETURNING *;
+
+-- name: ReleaseJob :exec
+-- ends the lease of a claimed job, unless it ran out and the job was
+-- claimed again since
+UPDATE jobs
+SET status = ?, run_at = ?, last_error = ?, finished_at = ?
+WHERE id = ? AND attempts = ? AND status = 'running';
+
+-- name: RequeueJob :one
+-- queues a dead job again, with its attempts reset
+UPDATE jobs
+SET status = 'pending', attempts = 0, run_at = ?, last_error = '', finished_at = NULL
+WHERE id = ? AND status = 'dead'
+RETURNING *;
+
+-- name: DeleteSucceededJobs :execrows
+DELETE FROM jobs
+WHERE status = 'succeeded' AND finished_at < ?;
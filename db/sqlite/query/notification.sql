@@ -0,0 +1,39 @@
+-- name: CreateNotification :one
+-- notifies a user. A notification of the same type about the same post is
+-- not created again, and no row is returned.
+INSERT INTO notifications (
+ user_id,
+ type,
+ actor_id,
+ post_id
+) VALUES (
+ ?, ?, ?, ?
+)
+ON CONFLICT (user_id, type, post_id) DO NOTHING
+RETURNING *;
+
+-- name: GetNotification :one
+SELECT * FROM notifications
+WHERE id = ? LIMIT 1;
+
+-- name: ListNotifications :many
+SELECT * FROM notifications
+WHERE user_id = ? AND id < ?
+ORDER BY id DESC
+LIMIT ?;
+
+-- name: CountUnreadNotifications :one
+SELECT count(*) FROM notifications
+WHERE user_id = ? AND read_at IS NULL;
+
+-- name: MarkNotificationRead :one
+-- keeps the time a notification was first read
+UPDATE notifications
+SET read_at = COALESCE(read_at, ?)
+WHERE id = ?
+RETURNING *;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = ?
+WHERE user_id = ? AND read_at IS NULL;
@@ -0,0 +1,60 @@
+-- name: CreatePost :one
+INSERT INTO posts (
+ user_id,
+ text,
+ parent_id
+) VALUES (
+ ?, ?, ?
+) RETURNING *;
+
+-- name: GetPost :one
+SELECT * FROM posts
+WHERE id = ? LIMIT 1;
+
+-- name: ListPosts :many
+SELECT * FROM posts
+ORDER BY id
+LIMIT ?
+OFFSET ?;
+
+-- name: ListReplies :many
+SELECT * FROM posts
+WHERE parent_id = ?
+ORDER BY id
+LIMIT ?
+OFFSET ?;
+
+-- name: ListRepliesByParentIDs :many
+-- the same window of replies to each of the parents: those numbered after
+-- the first bound and up to the second. The bounds are plain parameters, as
+-- sqlc numbers the named ones following a slice wrong.
+SELECT * FROM posts
+WHERE id IN (
+  SELECT numbered.id FROM (
+    SELECT replies.id, row_number() OVER (PARTITION BY replies.parent_id ORDER BY replies.id) AS n
+    FROM posts AS replies
+    WHERE replies.parent_id IN (sqlc.slice('parent_ids'))
+  ) AS numbered
+  WHERE numbered.n > CAST(? AS INTEGER) AND numbered.n <= CAST(? AS INTEGER)
+)
+ORDER BY parent_id, id;
+
+-- name: UpdatePost :one
+UPDATE posts
+  set text = ?2
+WHERE id = ?1
+RETURNING *;
+
+-- name: DeletePost :exec
+DELETE FROM posts
+WHERE id = ?;
+
+-- name: DeletePostsByUser :many
+DELETE FROM posts
+WHERE user_id = ?
+RETURNING *;
+
+-- name: DeletePostsCreatedBefore :many
+DELETE FROM posts
+WHERE julianday(created_at) < julianday(sqlc.arg(created_at))
+RETURNING *;
@@ -0,0 +1,19 @@
+-- name: CreatePostEvent :one
+-- SQLite lets one transaction write at a time, so events get their ids in
+-- the order their transactions commit.
+INSERT INTO post_events (
+ type,
+ post_id,
+ payload
+) VALUES (
+ ?, ?, ?
+) RETURNING *;
+
+-- name: ListPostEventsAfter :many
+SELECT * FROM post_events
+WHERE id > ?
+ORDER BY id
+LIMIT ?;
+
+-- name: GetLastPostEventID :one
+SELECT CAST(COALESCE(MAX(id), 0) AS INTEGER) FROM post_events;
@@ -0,0 +1,18 @@
+-- name: CreatePostMention :exec
+INSERT INTO post_mentions (
+ post_id,
+ user_id,
+ start_offset,
+ end_offset
+) VALUES (
+ ?, ?, ?, ?
+);
+
+-- name: ListPostMentions :many
+SELECT * FROM post_mentions
+WHERE post_id IN (sqlc.slice('post_ids'))
+ORDER BY post_id, start_offset;
+
+-- name: DeletePostMentions :exec
+DELETE FROM post_mentions
+WHERE post_id = ?;
@@ -0,0 +1,52 @@
+-- name: UpsertTag :one
+INSERT INTO tags (
+ name
+) VALUES (
+ ?
+)
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: CreatePostTag :exec
+INSERT INTO post_tags (
+ post_id,
+ tag_id
+) VALUES (
+ ?, ?
+)
+ON CONFLICT DO NOTHING;
+
+-- name: ListPostTags :many
+SELECT post_tags.post_id, tags.name FROM post_tags
+JOIN tags ON tags.id = post_tags.tag_id
+WHERE post_tags.post_id IN (sqlc.slice('post_ids'))
+ORDER BY post_tags.post_id, tags.name;
+
+-- name: DeletePostTags :exec
+DELETE FROM post_tags
+WHERE post_id = ?;
+
+-- name: ListPostsByTag :many
+SELECT posts.* FROM posts
+JOIN post_tags ON post_tags.post_id = posts.id
+JOIN tags ON tags.id = post_tags.tag_id
+WHERE tags.name = ? AND posts.id < ?
+ORDER BY posts.id DESC
+LIMIT ?;
+
+-- name: SearchTags :many
+SELECT tags.name, COUNT(*) AS post_count FROM tags
+JOIN post_tags ON post_tags.tag_id = tags.id
+WHERE tags.name LIKE ? ESCAPE '\'
+GROUP BY tags.id, tags.name
+ORDER BY post_count DESC, tags.name
+LIMIT ?;
+
+-- name: ListTrendingTags :many
+SELECT tags.name, COUNT(*) AS post_count FROM post_tags
+JOIN tags ON tags.id = post_tags.tag_id
+JOIN posts ON posts.id = post_tags.post_id
+WHERE julianday(posts.created_at) >= julianday(sqlc.arg(since))
+GROUP BY tags.id, tags.name
+ORDER BY post_count DESC, tags.name
+LIMIT sqlc.arg(max_count);
@@ -0,0 +1,65 @@
+-- name: CreateUser :one
+INSERT INTO users (
+ user_str_id,
+ email,
+ password 
+) VALUES (
+ ?, ?, ?
+) RETURNING *;
+
+-- name: GetUser :one
+SELECT * FROM users
+WHERE id = ? LIMIT 1;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = ? LIMIT 1;
+
+-- name: GetUserStrIdById :one
+SELECT user_str_id FROM users
+WHERE id = ? LIMIT 1;
+
+-- name: ListUsers :many
+SELECT * FROM users
+ORDER BY id
+LIMIT ?
+OFFSET ?;
+
+-- name: ListUsersByIDs :many
+SELECT * FROM users
+WHERE id IN (sqlc.slice('ids'))
+ORDER BY id;
+
+-- name: UpdateUser :one
+UPDATE users
+  set user_str_id = ?2,
+  email = ?3,
+  password = ?4
+WHERE id = ?1
+RETURNING *;
+
+-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = ?;
+
+-- name: GetUserByUserStrId :one
+SELECT * FROM users
+WHERE user_str_id = ? LIMIT 1;
+
+-- name: UpdateUserRole :one
+UPDATE users
+  set role = ?2
+WHERE id = ?1
+RETURNING *;
+
+-- name: UpdateUserLockedAt :one
+UPDATE users
+  set locked_at = ?2
+WHERE id = ?1
+RETURNING *;
+
+-- name: RevokeUserTokens :one
+UPDATE users
+  set tokens_revoked_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?
+RETURNING *;
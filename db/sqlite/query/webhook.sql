@@ -0,0 +1,88 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (
+ user_id,
+ url,
+ secret,
+ events
+) VALUES (
+ ?, ?, ?, ?
+) RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks
+WHERE id = ? LIMIT 1;
+
+-- name: ListWebhooksByUser :many
+SELECT * FROM webhooks
+WHERE user_id = ?
+ORDER BY id;
+
+-- name: ListEnabledWebhooks :many
+SELECT * FROM webhooks
+WHERE disabled_at IS NULL
+ORDER BY id;
+
+-- name: UpdateWebhook :one
+UPDATE webhooks
+SET url = ?, events = ?, disabled_at = ?, failure_count = ?
+WHERE id = ?
+RETURNING *;
+
+-- name: RecordWebhookSuccess :one
+UPDATE webhooks
+SET failure_count = 0
+WHERE id = ?
+RETURNING *;
+
+-- name: RecordWebhookFailure :one
+-- counts a failed attempt, disabling the webhook when it reaches disable_after
+UPDATE webhooks
+SET failure_count = failure_count + 1,
+ disabled_at = CASE
+  WHEN disabled_at IS NULL AND failure_count + 1 >= sqlc.arg(disable_after) THEN strftime('%Y-%m-%d %H:%M:%f', 'now')
+  ELSE disabled_at
+ END
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks
+WHERE id = ?;
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (
+ webhook_id,
+ event_type,
+ payload
+) VALUES (
+ ?, ?, ?
+) RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = ? LIMIT 1;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = ? AND id < ?
+ORDER BY id DESC
+LIMIT ?;
+
+-- name: ClaimWebhookDeliveries :many
+-- leases up to max_count due deliveries until lease_until, skipping those
+-- another worker holds
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg(lease_until)
+WHERE id IN (
+ SELECT d.id FROM webhook_deliveries d
+ WHERE d.status = 'pending' AND d.next_attempt_at <= sqlc.arg(now)
+ ORDER BY d.next_attempt_at, d.id
+ LIMIT sqlc.arg(max_count)
+)
+RETURNING *;
+
+-- name: UpdateWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = ?, attempts = ?, next_attempt_at = ?, response_status = ?, last_error = ?, delivered_at = ?
+WHERE id = ?
+RETURNING *;
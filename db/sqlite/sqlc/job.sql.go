@@ -0,0 +1,268 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: job.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const claimJobs = `-- name: ClaimJobs :many
+UPDATE jobs
+SET status = 'running', attempts = attempts + 1, run_at = ?1
+WHERE id IN (
+ SELECT j.id FROM jobs j
+ WHERE j.kind = ?2 AND j.status IN ('pending', 'running') AND j.run_at <= ?3
+ ORDER BY j.run_at, j.id
+ LIMIT ?4
+)
+RETURNING id, kind, payload, unique_key, status, attempts, max_attempts, run_at, last_error, finished_at, created_at
+`
+
+type ClaimJobsParams struct {
+	LeaseUntil time.Time `json:"lease_until"`
+	Kind       string    `json:"kind"`
+	Now        time.Time `json:"now"`
+	MaxCount   int64     `json:"max_count"`
+}
+
+// leases up to max_count due jobs of kind until lease_until. SQLite has a
+// single writer, so no other worker can claim them in the meantime.
+func (q *Queries) ClaimJobs(ctx context.Context, arg ClaimJobsParams) ([]Job, error) {
+	rows, err := q.db.QueryContext(ctx, claimJobs,
+		arg.LeaseUntil,
+		arg.Kind,
+		arg.Now,
+		arg.MaxCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Job{}
+	for rows.Next() {
+		var i Job
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.Payload,
+			&i.UniqueKey,
+			&i.Status,
+			&i.Attempts,
+			&i.MaxAttempts,
+			&i.RunAt,
+			&i.LastError,
+			&i.FinishedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createJob = `-- name: CreateJob :one
+INSERT INTO jobs (
+ kind,
+ payload,
+ unique_key,
+ max_attempts,
+ run_at
+) VALUES (
+ ?, ?, ?, ?, ?
+)
+ON CONFLICT (unique_key) DO NOTHING
+RETURNING id, kind, payload, unique_key, status, attempts, max_attempts, run_at, last_error, finished_at, created_at
+`
+
+type CreateJobParams struct {
+	Kind        string         `json:"kind"`
+	Payload     string         `json:"payload"`
+	UniqueKey   sql.NullString `json:"unique_key"`
+	MaxAttempts int64          `json:"max_attempts"`
+	RunAt       time.Time      `json:"run_at"`
+}
+
+// queues a job. A job with the unique key of one queued before is not
+// queued, and no row is returned.
+func (q *Queries) CreateJob(ctx context.Context, arg CreateJobParams) (Job, error) {
+	row := q.db.QueryRowContext(ctx, createJob,
+		arg.Kind,
+		arg.Payload,
+		arg.UniqueKey,
+		arg.MaxAttempts,
+		arg.RunAt,
+	)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Payload,
+		&i.UniqueKey,
+		&i.Status,
+		&i.Attempts,
+		&i.MaxAttempts,
+		&i.RunAt,
+		&i.LastError,
+		&i.FinishedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteSucceededJobs = `-- name: DeleteSucceededJobs :execrows
+DELETE FROM jobs
+WHERE status = 'succeeded' AND finished_at < ?
+`
+
+func (q *Queries) DeleteSucceededJobs(ctx context.Context, finishedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteSucceededJobs, finishedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getJob = `-- name: GetJob :one
+SELECT id, kind, payload, unique_key, status, attempts, max_attempts, run_at, last_error, finished_at, created_at FROM jobs
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetJob(ctx context.Context, id int64) (Job, error) {
+	row := q.db.QueryRowContext(ctx, getJob, id)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Payload,
+		&i.UniqueKey,
+		&i.Status,
+		&i.Attempts,
+		&i.MaxAttempts,
+		&i.RunAt,
+		&i.LastError,
+		&i.FinishedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listJobsByStatus = `-- name: ListJobsByStatus :many
+SELECT id, kind, payload, unique_key, status, attempts, max_attempts, run_at, last_error, finished_at, created_at FROM jobs
+WHERE status = ? AND id < ?
+ORDER BY id DESC
+LIMIT ?
+`
+
+type ListJobsByStatusParams struct {
+	Status string `json:"status"`
+	ID     int64  `json:"id"`
+	Limit  int64  `json:"limit"`
+}
+
+func (q *Queries) ListJobsByStatus(ctx context.Context, arg ListJobsByStatusParams) ([]Job, error) {
+	rows, err := q.db.QueryContext(ctx, listJobsByStatus, arg.Status, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Job{}
+	for rows.Next() {
+		var i Job
+		if err := rows.Scan(
+			&i.ID,
+			&i.Kind,
+			&i.Payload,
+			&i.UniqueKey,
+			&i.Status,
+			&i.Attempts,
+			&i.MaxAttempts,
+			&i.RunAt,
+			&i.LastError,
+			&i.FinishedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const releaseJob = `-- name: ReleaseJob :exec
+UPDATE jobs
+SET status = ?, run_at = ?, last_error = ?, finished_at = ?
+WHERE id = ? AND attempts = ? AND status = 'running'
+`
+
+type ReleaseJobParams struct {
+	Status     string       `json:"status"`
+	RunAt      time.Time    `json:"run_at"`
+	LastError  string       `json:"last_error"`
+	FinishedAt sql.NullTime `json:"finished_at"`
+	ID         int64        `json:"id"`
+	Attempts   int64        `json:"attempts"`
+}
+
+// ends the lease of a claimed job, unless it ran out and the job was
+// claimed again since
+func (q *Queries) ReleaseJob(ctx context.Context, arg ReleaseJobParams) error {
+	_, err := q.db.ExecContext(ctx, releaseJob,
+		arg.Status,
+		arg.RunAt,
+		arg.LastError,
+		arg.FinishedAt,
+		arg.ID,
+		arg.Attempts,
+	)
+	return err
+}
+
+const requeueJob = `-- name: RequeueJob :one
+UPDATE jobs
+SET status = 'pending', attempts = 0, run_at = ?, last_error = '', finished_at = NULL
+WHERE id = ? AND status = 'dead'
+RETURNING id, kind, payload, unique_key, status, attempts, max_attempts, run_at, last_error, finished_at, created_at
+`
+
+type RequeueJobParams struct {
+	RunAt time.Time `json:"run_at"`
+	ID    int64     `json:"id"`
+}
+
+// queues a dead job again, with its attempts reset
+func (q *Queries) RequeueJob(ctx context.Context, arg RequeueJobParams) (Job, error) {
+	row := q.db.QueryRowContext(ctx, requeueJob, arg.RunAt, arg.ID)
+	var i Job
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Payload,
+		&i.UniqueKey,
+		&i.Status,
+		&i.Attempts,
+		&i.MaxAttempts,
+		&i.RunAt,
+		&i.LastError,
+		&i.FinishedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,104 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+
+package sqlitedb
+
+import (
+	"database/sql"
+	"time"
+)
+
+type Job struct {
+	ID          int64          `json:"id"`
+	Kind        string         `json:"kind"`
+	Payload     string         `json:"payload"`
+	UniqueKey   sql.NullString `json:"unique_key"`
+	Status      string         `json:"status"`
+	Attempts    int64          `json:"attempts"`
+	MaxAttempts int64          `json:"max_attempts"`
+	RunAt       time.Time      `json:"run_at"`
+	LastError   string         `json:"last_error"`
+	FinishedAt  sql.NullTime   `json:"finished_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+type Notification struct {
+	ID        int64        `json:"id"`
+	UserID    uint         `json:"user_id"`
+	Type      string       `json:"type"`
+	ActorID   uint         `json:"actor_id"`
+	PostID    uint         `json:"post_id"`
+	ReadAt    sql.NullTime `json:"read_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type Post struct {
+	ID        uint          `json:"id"`
+	UserID    uint          `json:"user_id"`
+	Text      string        `json:"text"`
+	CreatedAt time.Time     `json:"created_at"`
+	ParentID  sql.NullInt64 `json:"parent_id"`
+}
+
+type PostEvent struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	PostID    uint      `json:"post_id"`
+	Payload   string    `json:"payload"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PostMention struct {
+	PostID      uint  `json:"post_id"`
+	UserID      uint  `json:"user_id"`
+	StartOffset int64 `json:"start_offset"`
+	EndOffset   int64 `json:"end_offset"`
+}
+
+type PostTag struct {
+	PostID uint  `json:"post_id"`
+	TagID  int64 `json:"tag_id"`
+}
+
+type Tag struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type User struct {
+	ID              uint         `json:"id"`
+	UserStrID       string       `json:"user_str_id"`
+	Email           string       `json:"email"`
+	Password        string       `json:"password"`
+	CreatedAt       time.Time    `json:"created_at"`
+	Role            string       `json:"role"`
+	LockedAt        sql.NullTime `json:"locked_at"`
+	TokensRevokedAt sql.NullTime `json:"tokens_revoked_at"`
+}
+
+type Webhook struct {
+	ID           int64        `json:"id"`
+	UserID       uint         `json:"user_id"`
+	Url          string       `json:"url"`
+	Secret       string       `json:"secret"`
+	Events       string       `json:"events"`
+	FailureCount int64        `json:"failure_count"`
+	DisabledAt   sql.NullTime `json:"disabled_at"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64        `json:"id"`
+	WebhookID      int64        `json:"webhook_id"`
+	EventType      string       `json:"event_type"`
+	Payload        string       `json:"payload"`
+	Status         string       `json:"status"`
+	Attempts       int64        `json:"attempts"`
+	NextAttemptAt  time.Time    `json:"next_attempt_at"`
+	ResponseStatus int64        `json:"response_status"`
+	LastError      string       `json:"last_error"`
+	DeliveredAt    sql.NullTime `json:"delivered_at"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
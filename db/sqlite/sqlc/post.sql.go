@@ -0,0 +1,311 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: post.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+const createPost = `-- name: CreatePost :one
+INSERT INTO posts (
+ user_id,
+ text,
+ parent_id
+) VALUES (
+ ?, ?, ?
+) RETURNING id, user_id, text, created_at, parent_id
+`
+
+type CreatePostParams struct {
+	UserID   uint          `json:"user_id"`
+	Text     string        `json:"text"`
+	ParentID sql.NullInt64 `json:"parent_id"`
+}
+
+func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, createPost, arg.UserID, arg.Text, arg.ParentID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Text,
+		&i.CreatedAt,
+		&i.ParentID,
+	)
+	return i, err
+}
+
+const deletePost = `-- name: DeletePost :exec
+DELETE FROM posts
+WHERE id = ?
+`
+
+func (q *Queries) DeletePost(ctx context.Context, id uint) error {
+	_, err := q.db.ExecContext(ctx, deletePost, id)
+	return err
+}
+
+const deletePostsByUser = `-- name: DeletePostsByUser :many
+DELETE FROM posts
+WHERE user_id = ?
+RETURNING id, user_id, text, created_at, parent_id
+`
+
+func (q *Queries) DeletePostsByUser(ctx context.Context, userID uint) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, deletePostsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Post{}
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Text,
+			&i.CreatedAt,
+			&i.ParentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deletePostsCreatedBefore = `-- name: DeletePostsCreatedBefore :many
+DELETE FROM posts
+WHERE julianday(created_at) < julianday(?1)
+RETURNING id, user_id, text, created_at, parent_id
+`
+
+func (q *Queries) DeletePostsCreatedBefore(ctx context.Context, createdAt interface{}) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, deletePostsCreatedBefore, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Post{}
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Text,
+			&i.CreatedAt,
+			&i.ParentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPost = `-- name: GetPost :one
+SELECT id, user_id, text, created_at, parent_id FROM posts
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetPost(ctx context.Context, id uint) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPost, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Text,
+		&i.CreatedAt,
+		&i.ParentID,
+	)
+	return i, err
+}
+
+const listPosts = `-- name: ListPosts :many
+SELECT id, user_id, text, created_at, parent_id FROM posts
+ORDER BY id
+LIMIT ?
+OFFSET ?
+`
+
+type ListPostsParams struct {
+	Limit  int64 `json:"limit"`
+	Offset int64 `json:"offset"`
+}
+
+func (q *Queries) ListPosts(ctx context.Context, arg ListPostsParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, listPosts, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Post{}
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Text,
+			&i.CreatedAt,
+			&i.ParentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReplies = `-- name: ListReplies :many
+SELECT id, user_id, text, created_at, parent_id FROM posts
+WHERE parent_id = ?
+ORDER BY id
+LIMIT ?
+OFFSET ?
+`
+
+type ListRepliesParams struct {
+	ParentID sql.NullInt64 `json:"parent_id"`
+	Limit    int64         `json:"limit"`
+	Offset   int64         `json:"offset"`
+}
+
+func (q *Queries) ListReplies(ctx context.Context, arg ListRepliesParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, listReplies, arg.ParentID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Post{}
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Text,
+			&i.CreatedAt,
+			&i.ParentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRepliesByParentIDs = `-- name: ListRepliesByParentIDs :many
+SELECT id, user_id, text, created_at, parent_id FROM posts
+WHERE id IN (
+  SELECT numbered.id FROM (
+    SELECT replies.id, row_number() OVER (PARTITION BY replies.parent_id ORDER BY replies.id) AS n
+    FROM posts AS replies
+    WHERE replies.parent_id IN (/*SLICE:parent_ids*/?)
+  ) AS numbered
+  WHERE numbered.n > CAST(? AS INTEGER) AND numbered.n <= CAST(? AS INTEGER)
+)
+ORDER BY parent_id, id
+`
+
+type ListRepliesByParentIDsParams struct {
+	ParentIds []sql.NullInt64 `json:"parent_ids"`
+	Column2   int64           `json:"column_2"`
+	Column3   int64           `json:"column_3"`
+}
+
+// the same window of replies to each of the parents: those numbered after
+// the first bound and up to the second. The bounds are plain parameters, as
+// sqlc numbers the named ones following a slice wrong.
+func (q *Queries) ListRepliesByParentIDs(ctx context.Context, arg ListRepliesByParentIDsParams) ([]Post, error) {
+	query := listRepliesByParentIDs
+	var queryParams []interface{}
+	if len(arg.ParentIds) > 0 {
+		for _, v := range arg.ParentIds {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:parent_ids*/?", strings.Repeat(",?", len(arg.ParentIds))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:parent_ids*/?", "NULL", 1)
+	}
+	queryParams = append(queryParams, arg.Column2)
+	queryParams = append(queryParams, arg.Column3)
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Post{}
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Text,
+			&i.CreatedAt,
+			&i.ParentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updatePost = `-- name: UpdatePost :one
+UPDATE posts
+  set text = ?2
+WHERE id = ?1
+RETURNING id, user_id, text, created_at, parent_id
+`
+
+type UpdatePostParams struct {
+	ID   uint   `json:"id"`
+	Text string `json:"text"`
+}
+
+func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, updatePost, arg.ID, arg.Text)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Text,
+		&i.CreatedAt,
+		&i.ParentID,
+	)
+	return i, err
+}
@@ -0,0 +1,93 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: post_event.sql
+
+package sqlitedb
+
+import (
+	"context"
+)
+
+const createPostEvent = `-- name: CreatePostEvent :one
+INSERT INTO post_events (
+ type,
+ post_id,
+ payload
+) VALUES (
+ ?, ?, ?
+) RETURNING id, type, post_id, payload, created_at
+`
+
+type CreatePostEventParams struct {
+	Type    string `json:"type"`
+	PostID  uint   `json:"post_id"`
+	Payload string `json:"payload"`
+}
+
+// SQLite lets one transaction write at a time, so events get their ids in
+// the order their transactions commit.
+func (q *Queries) CreatePostEvent(ctx context.Context, arg CreatePostEventParams) (PostEvent, error) {
+	row := q.db.QueryRowContext(ctx, createPostEvent, arg.Type, arg.PostID, arg.Payload)
+	var i PostEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Type,
+		&i.PostID,
+		&i.Payload,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLastPostEventID = `-- name: GetLastPostEventID :one
+SELECT CAST(COALESCE(MAX(id), 0) AS INTEGER) FROM post_events
+`
+
+func (q *Queries) GetLastPostEventID(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getLastPostEventID)
+	var column_1 int64
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const listPostEventsAfter = `-- name: ListPostEventsAfter :many
+SELECT id, type, post_id, payload, created_at FROM post_events
+WHERE id > ?
+ORDER BY id
+LIMIT ?
+`
+
+type ListPostEventsAfterParams struct {
+	ID    int64 `json:"id"`
+	Limit int64 `json:"limit"`
+}
+
+func (q *Queries) ListPostEventsAfter(ctx context.Context, arg ListPostEventsAfterParams) ([]PostEvent, error) {
+	rows, err := q.db.QueryContext(ctx, listPostEventsAfter, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PostEvent{}
+	for rows.Next() {
+		var i PostEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Type,
+			&i.PostID,
+			&i.Payload,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,93 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: post_mention.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"strings"
+)
+
+const createPostMention = `-- name: CreatePostMention :exec
+INSERT INTO post_mentions (
+ post_id,
+ user_id,
+ start_offset,
+ end_offset
+) VALUES (
+ ?, ?, ?, ?
+)
+`
+
+type CreatePostMentionParams struct {
+	PostID      uint  `json:"post_id"`
+	UserID      uint  `json:"user_id"`
+	StartOffset int64 `json:"start_offset"`
+	EndOffset   int64 `json:"end_offset"`
+}
+
+func (q *Queries) CreatePostMention(ctx context.Context, arg CreatePostMentionParams) error {
+	_, err := q.db.ExecContext(ctx, createPostMention,
+		arg.PostID,
+		arg.UserID,
+		arg.StartOffset,
+		arg.EndOffset,
+	)
+	return err
+}
+
+const deletePostMentions = `-- name: DeletePostMentions :exec
+DELETE FROM post_mentions
+WHERE post_id = ?
+`
+
+func (q *Queries) DeletePostMentions(ctx context.Context, postID uint) error {
+	_, err := q.db.ExecContext(ctx, deletePostMentions, postID)
+	return err
+}
+
+const listPostMentions = `-- name: ListPostMentions :many
+SELECT post_id, user_id, start_offset, end_offset FROM post_mentions
+WHERE post_id IN (/*SLICE:post_ids*/?)
+ORDER BY post_id, start_offset
+`
+
+func (q *Queries) ListPostMentions(ctx context.Context, postIds []uint) ([]PostMention, error) {
+	query := listPostMentions
+	var queryParams []interface{}
+	if len(postIds) > 0 {
+		for _, v := range postIds {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:post_ids*/?", strings.Repeat(",?", len(postIds))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:post_ids*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PostMention{}
+	for rows.Next() {
+		var i PostMention
+		if err := rows.Scan(
+			&i.PostID,
+			&i.UserID,
+			&i.StartOffset,
+			&i.EndOffset,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,232 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: tag.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"strings"
+)
+
+const createPostTag = `-- name: CreatePostTag :exec
+INSERT INTO post_tags (
+ post_id,
+ tag_id
+) VALUES (
+ ?, ?
+)
+ON CONFLICT DO NOTHING
+`
+
+type CreatePostTagParams struct {
+	PostID uint  `json:"post_id"`
+	TagID  int64 `json:"tag_id"`
+}
+
+func (q *Queries) CreatePostTag(ctx context.Context, arg CreatePostTagParams) error {
+	_, err := q.db.ExecContext(ctx, createPostTag, arg.PostID, arg.TagID)
+	return err
+}
+
+const deletePostTags = `-- name: DeletePostTags :exec
+DELETE FROM post_tags
+WHERE post_id = ?
+`
+
+func (q *Queries) DeletePostTags(ctx context.Context, postID uint) error {
+	_, err := q.db.ExecContext(ctx, deletePostTags, postID)
+	return err
+}
+
+const listPostTags = `-- name: ListPostTags :many
+SELECT post_tags.post_id, tags.name FROM post_tags
+JOIN tags ON tags.id = post_tags.tag_id
+WHERE post_tags.post_id IN (/*SLICE:post_ids*/?)
+ORDER BY post_tags.post_id, tags.name
+`
+
+type ListPostTagsRow struct {
+	PostID uint   `json:"post_id"`
+	Name   string `json:"name"`
+}
+
+func (q *Queries) ListPostTags(ctx context.Context, postIds []uint) ([]ListPostTagsRow, error) {
+	query := listPostTags
+	var queryParams []interface{}
+	if len(postIds) > 0 {
+		for _, v := range postIds {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:post_ids*/?", strings.Repeat(",?", len(postIds))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:post_ids*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPostTagsRow{}
+	for rows.Next() {
+		var i ListPostTagsRow
+		if err := rows.Scan(&i.PostID, &i.Name); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPostsByTag = `-- name: ListPostsByTag :many
+SELECT posts.id, posts.user_id, posts.text, posts.created_at, posts.parent_id FROM posts
+JOIN post_tags ON post_tags.post_id = posts.id
+JOIN tags ON tags.id = post_tags.tag_id
+WHERE tags.name = ? AND posts.id < ?
+ORDER BY posts.id DESC
+LIMIT ?
+`
+
+type ListPostsByTagParams struct {
+	Name  string `json:"name"`
+	ID    uint   `json:"id"`
+	Limit int64  `json:"limit"`
+}
+
+func (q *Queries) ListPostsByTag(ctx context.Context, arg ListPostsByTagParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, listPostsByTag, arg.Name, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Post{}
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Text,
+			&i.CreatedAt,
+			&i.ParentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTrendingTags = `-- name: ListTrendingTags :many
+SELECT tags.name, COUNT(*) AS post_count FROM post_tags
+JOIN tags ON tags.id = post_tags.tag_id
+JOIN posts ON posts.id = post_tags.post_id
+WHERE julianday(posts.created_at) >= julianday(?1)
+GROUP BY tags.id, tags.name
+ORDER BY post_count DESC, tags.name
+LIMIT ?2
+`
+
+type ListTrendingTagsParams struct {
+	Since    interface{} `json:"since"`
+	MaxCount int64       `json:"max_count"`
+}
+
+type ListTrendingTagsRow struct {
+	Name      string `json:"name"`
+	PostCount int64  `json:"post_count"`
+}
+
+func (q *Queries) ListTrendingTags(ctx context.Context, arg ListTrendingTagsParams) ([]ListTrendingTagsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTrendingTags, arg.Since, arg.MaxCount)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTrendingTagsRow{}
+	for rows.Next() {
+		var i ListTrendingTagsRow
+		if err := rows.Scan(&i.Name, &i.PostCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchTags = `-- name: SearchTags :many
+SELECT tags.name, COUNT(*) AS post_count FROM tags
+JOIN post_tags ON post_tags.tag_id = tags.id
+WHERE tags.name LIKE ? ESCAPE '\'
+GROUP BY tags.id, tags.name
+ORDER BY post_count DESC, tags.name
+LIMIT ?
+`
+
+type SearchTagsParams struct {
+	Name  string `json:"name"`
+	Limit int64  `json:"limit"`
+}
+
+type SearchTagsRow struct {
+	Name      string `json:"name"`
+	PostCount int64  `json:"post_count"`
+}
+
+func (q *Queries) SearchTags(ctx context.Context, arg SearchTagsParams) ([]SearchTagsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchTags, arg.Name, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchTagsRow{}
+	for rows.Next() {
+		var i SearchTagsRow
+		if err := rows.Scan(&i.Name, &i.PostCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const upsertTag = `-- name: UpsertTag :one
+INSERT INTO tags (
+ name
+) VALUES (
+ ?
+)
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING id, name, created_at
+`
+
+func (q *Queries) UpsertTag(ctx context.Context, name string) (Tag, error) {
+	row := q.db.QueryRowContext(ctx, upsertTag, name)
+	var i Tag
+	err := row.Scan(&i.ID, &i.Name, &i.CreatedAt)
+	return i, err
+}
@@ -0,0 +1,337 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.25.0
+// source: user.sql
+
+package sqlitedb
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+const createUser = `-- name: CreateUser :one
+INSERT INTO users (
+ user_str_id,
+ email,
+ password 
+) VALUES (
+ ?, ?, ?
+) RETURNING id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at
+`
+
+type CreateUserParams struct {
+	UserStrID string `json:"user_str_id"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+}
+
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, createUser, arg.UserStrID, arg.Email, arg.Password)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserStrID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
+	)
+	return i, err
+}
+
+const deleteUser = `-- name: DeleteUser :exec
+DELETE FROM users
+WHERE id = ?
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uint) error {
+	_, err := q.db.ExecContext(ctx, deleteUser, id)
+	return err
+}
+
+const getUser = `-- name: GetUser :one
+SELECT id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at FROM users
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetUser(ctx context.Context, id uint) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserStrID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at FROM users
+WHERE email = ? LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserStrID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
+	)
+	return i, err
+}
+
+const getUserByUserStrId = `-- name: GetUserByUserStrId :one
+SELECT id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at FROM users
+WHERE user_str_id = ? LIMIT 1
+`
+
+func (q *Queries) GetUserByUserStrId(ctx context.Context, userStrID string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByUserStrId, userStrID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserStrID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
+	)
+	return i, err
+}
+
+const getUserStrIdById = `-- name: GetUserStrIdById :one
+SELECT user_str_id FROM users
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetUserStrIdById(ctx context.Context, id uint) (string, error) {
+	row := q.db.QueryRowContext(ctx, getUserStrIdById, id)
+	var user_str_id string
+	err := row.Scan(&user_str_id)
+	return user_str_id, err
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at FROM users
+ORDER BY id
+LIMIT ?
+OFFSET ?
+`
+
+type ListUsersParams struct {
+	Limit  int64 `json:"limit"`
+	Offset int64 `json:"offset"`
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserStrID,
+			&i.Email,
+			&i.Password,
+			&i.CreatedAt,
+			&i.Role,
+			&i.LockedAt,
+			&i.TokensRevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersByIDs = `-- name: ListUsersByIDs :many
+SELECT id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at FROM users
+WHERE id IN (/*SLICE:ids*/?)
+ORDER BY id
+`
+
+func (q *Queries) ListUsersByIDs(ctx context.Context, ids []uint) ([]User, error) {
+	query := listUsersByIDs
+	var queryParams []interface{}
+	if len(ids) > 0 {
+		for _, v := range ids {
+			queryParams = append(queryParams, v)
+		}
+		query = strings.Replace(query, "/*SLICE:ids*/?", strings.Repeat(",?", len(ids))[1:], 1)
+	} else {
+		query = strings.Replace(query, "/*SLICE:ids*/?", "NULL", 1)
+	}
+	rows, err := q.db.QueryContext(ctx, query, queryParams...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserStrID,
+			&i.Email,
+			&i.Password,
+			&i.CreatedAt,
+			&i.Role,
+			&i.LockedAt,
+			&i.TokensRevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeUserTokens = `-- name: RevokeUserTokens :one
+UPDATE users
+  set tokens_revoked_at = strftime('%Y-%m-%d %H:%M:%f', 'now')
+WHERE id = ?
+RETURNING id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at
+`
+
+func (q *Queries) RevokeUserTokens(ctx context.Context, id uint) (User, error) {
+	row := q.db.QueryRowContext(ctx, revokeUserTokens, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserStrID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
+	)
+	return i, err
+}
+
+const updateUser = `-- name: UpdateUser :one
+UPDATE users
+  set user_str_id = ?2,
+  email = ?3,
+  password = ?4
+WHERE id = ?1
+RETURNING id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at
+`
+
+type UpdateUserParams struct {
+	ID        uint   `json:"id"`
+	UserStrID string `json:"user_str_id"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUser,
+		arg.ID,
+		arg.UserStrID,
+		arg.Email,
+		arg.Password,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserStrID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
+	)
+	return i, err
+}
+
+const updateUserLockedAt = `-- name: UpdateUserLockedAt :one
+UPDATE users
+  set locked_at = ?2
+WHERE id = ?1
+RETURNING id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at
+`
+
+type UpdateUserLockedAtParams struct {
+	ID       uint         `json:"id"`
+	LockedAt sql.NullTime `json:"locked_at"`
+}
+
+func (q *Queries) UpdateUserLockedAt(ctx context.Context, arg UpdateUserLockedAtParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserLockedAt, arg.ID, arg.LockedAt)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserStrID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+  set role = ?2
+WHERE id = ?1
+RETURNING id, user_str_id, email, password, created_at, role, locked_at, tokens_revoked_at
+`
+
+type UpdateUserRoleParams struct {
+	ID   uint   `json:"id"`
+	Role string `json:"role"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserStrID,
+		&i.Email,
+		&i.Password,
+		&i.CreatedAt,
+		&i.Role,
+		&i.LockedAt,
+		&i.TokensRevokedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,712 @@
+// Package sqlite implements db.Store on top of SQLite for single-node
+// installs and CI runs without a Postgres server.
+//
+// Queries are generated by sqlc into the sqlitedb package from
+// db/sqlite/query; this package adapts them to the db.Querier signatures and
+// reports constraint failures the same way the Postgres store does.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	sqlitedb "github.com/PenginAction/go-BulletinBoard/db/sqlite/sqlc"
+	"github.com/lib/pq"
+
+	_ "modernc.org/sqlite"
+)
+
+// DriverName is the database/sql driver name registered by modernc.org/sqlite
+const DriverName = "sqlite"
+
+// Migrations holds the SQLite schema migrations under migration/
+//
+//go:embed migration/*.sql
+var Migrations embed.FS
+
+// Open opens the SQLite database at dsn.
+// Foreign keys are enforced, writers wait for locks instead of failing
+// immediately with SQLITE_BUSY, and time values are written in a format
+// SQLite's date functions understand.
+func Open(dsn string) (*sql.DB, error) {
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	dsn += sep + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+
+	return sql.Open(DriverName, dsn)
+}
+
+// SQLStore provides all functions to execute SQLite queries
+type SQLStore struct {
+	q  *sqlitedb.Queries
+	db *sql.DB
+	// inTx is set on the stores ExecTx hands to its callback
+	inTx bool
+}
+
+var _ db.Store = (*SQLStore)(nil)
+
+// NewStore returns a db.Store backed by the SQLite connection conn
+func NewStore(conn *sql.DB) db.Store {
+	return &SQLStore{
+		q:  sqlitedb.New(conn),
+		db: conn,
+	}
+}
+
+func (s *SQLStore) ExecTx(ctx context.Context, fn func(db.Store) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(&SQLStore{q: s.q.WithTx(tx), db: s.db, inTx: true}); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	return translateError(tx.Commit())
+}
+
+func (s *SQLStore) ClaimJobs(ctx context.Context, arg db.ClaimJobsParams) ([]db.Job, error) {
+	if err := checkPage(arg.MaxCount, 0); err != nil {
+		return nil, err
+	}
+	jobs, err := s.q.ClaimJobs(ctx, sqlitedb.ClaimJobsParams{
+		LeaseUntil: arg.LeaseUntil.UTC(),
+		Kind:       arg.Kind,
+		Now:        arg.Now.UTC(),
+		MaxCount:   int64(arg.MaxCount),
+	})
+	return newJobs(jobs), translateError(err)
+}
+
+func (s *SQLStore) ClaimWebhookDeliveries(ctx context.Context, arg db.ClaimWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+	if err := checkPage(arg.MaxCount, 0); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.q.ClaimWebhookDeliveries(ctx, sqlitedb.ClaimWebhookDeliveriesParams{
+		LeaseUntil: arg.LeaseUntil.UTC(),
+		Now:        arg.Now.UTC(),
+		MaxCount:   int64(arg.MaxCount),
+	})
+	return newWebhookDeliveries(deliveries), translateError(err)
+}
+
+func (s *SQLStore) CountUnreadNotifications(ctx context.Context, userID uint) (int64, error) {
+	n, err := s.q.CountUnreadNotifications(ctx, userID)
+	return n, translateError(err)
+}
+
+func (s *SQLStore) CreateJob(ctx context.Context, arg db.CreateJobParams) (db.Job, error) {
+	job, err := s.q.CreateJob(ctx, sqlitedb.CreateJobParams{
+		Kind:        arg.Kind,
+		Payload:     string(arg.Payload),
+		UniqueKey:   arg.UniqueKey,
+		MaxAttempts: int64(arg.MaxAttempts),
+		RunAt:       arg.RunAt.UTC(),
+	})
+	return newJob(job), translateError(err)
+}
+
+func (s *SQLStore) CreateNotification(ctx context.Context, arg db.CreateNotificationParams) (db.Notification, error) {
+	notification, err := s.q.CreateNotification(ctx, sqlitedb.CreateNotificationParams(arg))
+	return db.Notification(notification), translateError(err)
+}
+
+func (s *SQLStore) CreatePost(ctx context.Context, arg db.CreatePostParams) (db.Post, error) {
+	post, err := s.q.CreatePost(ctx, sqlitedb.CreatePostParams(arg))
+	return db.Post(post), translateError(err)
+}
+
+func (s *SQLStore) CreatePostEvent(ctx context.Context, arg db.CreatePostEventParams) (db.PostEvent, error) {
+	event, err := s.q.CreatePostEvent(ctx, sqlitedb.CreatePostEventParams{
+		Type:    arg.Type,
+		PostID:  arg.PostID,
+		Payload: string(arg.Payload),
+	})
+	return newPostEvent(event), translateError(err)
+}
+
+func (s *SQLStore) CreatePostMention(ctx context.Context, arg db.CreatePostMentionParams) error {
+	return translateError(s.q.CreatePostMention(ctx, sqlitedb.CreatePostMentionParams{
+		PostID:      arg.PostID,
+		UserID:      arg.UserID,
+		StartOffset: int64(arg.StartOffset),
+		EndOffset:   int64(arg.EndOffset),
+	}))
+}
+
+func (s *SQLStore) CreatePostTag(ctx context.Context, arg db.CreatePostTagParams) error {
+	return translateError(s.q.CreatePostTag(ctx, sqlitedb.CreatePostTagParams(arg)))
+}
+
+func (s *SQLStore) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+	user, err := s.q.CreateUser(ctx, sqlitedb.CreateUserParams(arg))
+	return db.User(user), translateError(err)
+}
+
+func (s *SQLStore) CreateWebhook(ctx context.Context, arg db.CreateWebhookParams) (db.Webhook, error) {
+	webhook, err := s.q.CreateWebhook(ctx, sqlitedb.CreateWebhookParams(arg))
+	return newWebhook(webhook), translateError(err)
+}
+
+func (s *SQLStore) CreateWebhookDelivery(ctx context.Context, arg db.CreateWebhookDeliveryParams) (db.WebhookDelivery, error) {
+	delivery, err := s.q.CreateWebhookDelivery(ctx, sqlitedb.CreateWebhookDeliveryParams{
+		WebhookID: arg.WebhookID,
+		EventType: arg.EventType,
+		Payload:   string(arg.Payload),
+	})
+	return newWebhookDelivery(delivery), translateError(err)
+}
+
+func (s *SQLStore) DeletePost(ctx context.Context, id uint) error {
+	return translateError(s.q.DeletePost(ctx, id))
+}
+
+func (s *SQLStore) DeletePostMentions(ctx context.Context, postID uint) error {
+	return translateError(s.q.DeletePostMentions(ctx, postID))
+}
+
+func (s *SQLStore) DeletePostTags(ctx context.Context, postID uint) error {
+	return translateError(s.q.DeletePostTags(ctx, postID))
+}
+
+func (s *SQLStore) DeletePostsByUser(ctx context.Context, userID uint) ([]db.Post, error) {
+	posts, err := s.q.DeletePostsByUser(ctx, userID)
+	items := make([]db.Post, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, db.Post(post))
+	}
+	return items, translateError(err)
+}
+
+func (s *SQLStore) DeletePostsCreatedBefore(ctx context.Context, createdAt time.Time) ([]db.Post, error) {
+	posts, err := s.q.DeletePostsCreatedBefore(ctx, createdAt.UTC())
+	items := make([]db.Post, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, db.Post(post))
+	}
+	return items, translateError(err)
+}
+
+func (s *SQLStore) DeleteSucceededJobs(ctx context.Context, before time.Time) (int64, error) {
+	n, err := s.q.DeleteSucceededJobs(ctx, sql.NullTime{Time: before.UTC(), Valid: true})
+	return n, translateError(err)
+}
+
+func (s *SQLStore) DeleteUser(ctx context.Context, id uint) error {
+	return translateError(s.q.DeleteUser(ctx, id))
+}
+
+func (s *SQLStore) DeleteWebhook(ctx context.Context, id int64) error {
+	return translateError(s.q.DeleteWebhook(ctx, id))
+}
+
+func (s *SQLStore) GetJob(ctx context.Context, id int64) (db.Job, error) {
+	job, err := s.q.GetJob(ctx, id)
+	return newJob(job), translateError(err)
+}
+
+func (s *SQLStore) GetLastPostEventID(ctx context.Context) (int64, error) {
+	id, err := s.q.GetLastPostEventID(ctx)
+	return id, translateError(err)
+}
+
+func (s *SQLStore) GetNotification(ctx context.Context, id int64) (db.Notification, error) {
+	notification, err := s.q.GetNotification(ctx, id)
+	return db.Notification(notification), translateError(err)
+}
+
+func (s *SQLStore) GetPost(ctx context.Context, id uint) (db.Post, error) {
+	post, err := s.q.GetPost(ctx, id)
+	return db.Post(post), translateError(err)
+}
+
+func (s *SQLStore) GetUser(ctx context.Context, id uint) (db.User, error) {
+	user, err := s.q.GetUser(ctx, id)
+	return db.User(user), translateError(err)
+}
+
+func (s *SQLStore) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	user, err := s.q.GetUserByEmail(ctx, email)
+	return db.User(user), translateError(err)
+}
+
+func (s *SQLStore) GetUserByUserStrId(ctx context.Context, userStrID string) (db.User, error) {
+	user, err := s.q.GetUserByUserStrId(ctx, userStrID)
+	return db.User(user), translateError(err)
+}
+
+func (s *SQLStore) GetUserStrIdById(ctx context.Context, id uint) (string, error) {
+	userStrID, err := s.q.GetUserStrIdById(ctx, id)
+	return userStrID, translateError(err)
+}
+
+func (s *SQLStore) GetWebhook(ctx context.Context, id int64) (db.Webhook, error) {
+	webhook, err := s.q.GetWebhook(ctx, id)
+	return newWebhook(webhook), translateError(err)
+}
+
+func (s *SQLStore) GetWebhookDelivery(ctx context.Context, id int64) (db.WebhookDelivery, error) {
+	delivery, err := s.q.GetWebhookDelivery(ctx, id)
+	return newWebhookDelivery(delivery), translateError(err)
+}
+
+func (s *SQLStore) ListEnabledWebhooks(ctx context.Context) ([]db.Webhook, error) {
+	webhooks, err := s.q.ListEnabledWebhooks(ctx)
+	return newWebhooks(webhooks), translateError(err)
+}
+
+func (s *SQLStore) ListJobsByStatus(ctx context.Context, arg db.ListJobsByStatusParams) ([]db.Job, error) {
+	if err := checkPage(arg.Limit, 0); err != nil {
+		return nil, err
+	}
+	jobs, err := s.q.ListJobsByStatus(ctx, sqlitedb.ListJobsByStatusParams{
+		Status: arg.Status,
+		ID:     arg.ID,
+		Limit:  int64(arg.Limit),
+	})
+	return newJobs(jobs), translateError(err)
+}
+
+func (s *SQLStore) ListNotifications(ctx context.Context, arg db.ListNotificationsParams) ([]db.Notification, error) {
+	if err := checkPage(arg.Limit, 0); err != nil {
+		return nil, err
+	}
+	notifications, err := s.q.ListNotifications(ctx, sqlitedb.ListNotificationsParams{
+		UserID: arg.UserID,
+		ID:     arg.ID,
+		Limit:  int64(arg.Limit),
+	})
+	items := make([]db.Notification, 0, len(notifications))
+	for _, notification := range notifications {
+		items = append(items, db.Notification(notification))
+	}
+	return items, translateError(err)
+}
+
+func (s *SQLStore) ListPostEventsAfter(ctx context.Context, arg db.ListPostEventsAfterParams) ([]db.PostEvent, error) {
+	if err := checkPage(arg.Limit, 0); err != nil {
+		return nil, err
+	}
+	events, err := s.q.ListPostEventsAfter(ctx, sqlitedb.ListPostEventsAfterParams{
+		ID:    arg.ID,
+		Limit: int64(arg.Limit),
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	items := make([]db.PostEvent, 0, len(events))
+	for _, event := range events {
+		items = append(items, newPostEvent(event))
+	}
+	return items, nil
+}
+
+func (s *SQLStore) ListPostMentions(ctx context.Context, postIds []int64) ([]db.PostMention, error) {
+	ids := make([]uint, 0, len(postIds))
+	for _, id := range postIds {
+		ids = append(ids, uint(id))
+	}
+	mentions, err := s.q.ListPostMentions(ctx, ids)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	items := make([]db.PostMention, 0, len(mentions))
+	for _, mention := range mentions {
+		items = append(items, db.PostMention{
+			PostID:      mention.PostID,
+			UserID:      mention.UserID,
+			StartOffset: int32(mention.StartOffset),
+			EndOffset:   int32(mention.EndOffset),
+		})
+	}
+	return items, nil
+}
+
+func (s *SQLStore) ListPostTags(ctx context.Context, postIds []int64) ([]db.ListPostTagsRow, error) {
+	ids := make([]uint, 0, len(postIds))
+	for _, id := range postIds {
+		ids = append(ids, uint(id))
+	}
+	tags, err := s.q.ListPostTags(ctx, ids)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	items := make([]db.ListPostTagsRow, 0, len(tags))
+	for _, tag := range tags {
+		items = append(items, db.ListPostTagsRow(tag))
+	}
+	return items, nil
+}
+
+func (s *SQLStore) ListPosts(ctx context.Context, arg db.ListPostsParams) ([]db.Post, error) {
+	if err := checkPage(arg.Limit, arg.Offset); err != nil {
+		return nil, err
+	}
+	posts, err := s.q.ListPosts(ctx, sqlitedb.ListPostsParams{
+		Limit:  int64(arg.Limit),
+		Offset: int64(arg.Offset),
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	items := make([]db.Post, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, db.Post(post))
+	}
+	return items, nil
+}
+
+func (s *SQLStore) ListPostsByTag(ctx context.Context, arg db.ListPostsByTagParams) ([]db.Post, error) {
+	if err := checkPage(arg.Limit, 0); err != nil {
+		return nil, err
+	}
+	posts, err := s.q.ListPostsByTag(ctx, sqlitedb.ListPostsByTagParams{
+		Name:  arg.Name,
+		ID:    arg.ID,
+		Limit: int64(arg.Limit),
+	})
+	items := make([]db.Post, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, db.Post(post))
+	}
+	return items, translateError(err)
+}
+
+func (s *SQLStore) ListReplies(ctx context.Context, arg db.ListRepliesParams) ([]db.Post, error) {
+	if err := checkPage(arg.Limit, arg.Offset); err != nil {
+		return nil, err
+	}
+	posts, err := s.q.ListReplies(ctx, sqlitedb.ListRepliesParams{
+		ParentID: arg.ParentID,
+		Limit:    int64(arg.Limit),
+		Offset:   int64(arg.Offset),
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	items := make([]db.Post, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, db.Post(post))
+	}
+	return items, nil
+}
+
+func (s *SQLStore) ListRepliesByParentIDs(ctx context.Context, arg db.ListRepliesByParentIDsParams) ([]db.Post, error) {
+	if err := checkPage(arg.Limit, arg.Offset); err != nil {
+		return nil, err
+	}
+	parentIDs := make([]sql.NullInt64, 0, len(arg.ParentIds))
+	for _, id := range arg.ParentIds {
+		parentIDs = append(parentIDs, sql.NullInt64{Int64: id, Valid: true})
+	}
+	posts, err := s.q.ListRepliesByParentIDs(ctx, sqlitedb.ListRepliesByParentIDsParams{
+		ParentIds: parentIDs,
+		// the bounds of the window of each parent's replies
+		Column2: int64(arg.Offset),
+		Column3: int64(arg.Offset) + int64(arg.Limit),
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	items := make([]db.Post, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, db.Post(post))
+	}
+	return items, nil
+}
+
+func (s *SQLStore) ListTrendingTags(ctx context.Context, arg db.ListTrendingTagsParams) ([]db.ListTrendingTagsRow, error) {
+	if err := checkPage(arg.Limit, 0); err != nil {
+		return nil, err
+	}
+	tags, err := s.q.ListTrendingTags(ctx, sqlitedb.ListTrendingTagsParams{
+		Since:    arg.CreatedAt.UTC(),
+		MaxCount: int64(arg.Limit),
+	})
+	items := make([]db.ListTrendingTagsRow, 0, len(tags))
+	for _, tag := range tags {
+		items = append(items, db.ListTrendingTagsRow(tag))
+	}
+	return items, translateError(err)
+}
+
+func (s *SQLStore) ListUsers(ctx context.Context, arg db.ListUsersParams) ([]db.User, error) {
+	if err := checkPage(arg.Limit, arg.Offset); err != nil {
+		return nil, err
+	}
+	users, err := s.q.ListUsers(ctx, sqlitedb.ListUsersParams{
+		Limit:  int64(arg.Limit),
+		Offset: int64(arg.Offset),
+	})
+	if err != nil {
+		return nil, translateError(err)
+	}
+	items := make([]db.User, 0, len(users))
+	for _, user := range users {
+		items = append(items, db.User(user))
+	}
+	return items, nil
+}
+
+func (s *SQLStore) ListUsersByIDs(ctx context.Context, ids []int64) ([]db.User, error) {
+	uids := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		uids = append(uids, uint(id))
+	}
+	users, err := s.q.ListUsersByIDs(ctx, uids)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	items := make([]db.User, 0, len(users))
+	for _, user := range users {
+		items = append(items, db.User(user))
+	}
+	return items, nil
+}
+
+func (s *SQLStore) ListWebhookDeliveries(ctx context.Context, arg db.ListWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+	if err := checkPage(arg.Limit, 0); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.q.ListWebhookDeliveries(ctx, sqlitedb.ListWebhookDeliveriesParams{
+		WebhookID: arg.WebhookID,
+		ID:        arg.ID,
+		Limit:     int64(arg.Limit),
+	})
+	return newWebhookDeliveries(deliveries), translateError(err)
+}
+
+func (s *SQLStore) ListWebhooksByUser(ctx context.Context, userID uint) ([]db.Webhook, error) {
+	webhooks, err := s.q.ListWebhooksByUser(ctx, userID)
+	return newWebhooks(webhooks), translateError(err)
+}
+
+func (s *SQLStore) MarkAllNotificationsRead(ctx context.Context, arg db.MarkAllNotificationsReadParams) (int64, error) {
+	n, err := s.q.MarkAllNotificationsRead(ctx, sqlitedb.MarkAllNotificationsReadParams{
+		ReadAt: sql.NullTime{Time: arg.ReadAt.UTC(), Valid: true},
+		UserID: arg.UserID,
+	})
+	return n, translateError(err)
+}
+
+func (s *SQLStore) MarkNotificationRead(ctx context.Context, arg db.MarkNotificationReadParams) (db.Notification, error) {
+	notification, err := s.q.MarkNotificationRead(ctx, sqlitedb.MarkNotificationReadParams{
+		ReadAt: sql.NullTime{Time: arg.ReadAt.UTC(), Valid: true},
+		ID:     arg.ID,
+	})
+	return db.Notification(notification), translateError(err)
+}
+
+func (s *SQLStore) RecordWebhookFailure(ctx context.Context, arg db.RecordWebhookFailureParams) (db.Webhook, error) {
+	webhook, err := s.q.RecordWebhookFailure(ctx, sqlitedb.RecordWebhookFailureParams{
+		DisableAfter: int64(arg.DisableAfter),
+		ID:           arg.ID,
+	})
+	return newWebhook(webhook), translateError(err)
+}
+
+func (s *SQLStore) RecordWebhookSuccess(ctx context.Context, id int64) (db.Webhook, error) {
+	webhook, err := s.q.RecordWebhookSuccess(ctx, id)
+	return newWebhook(webhook), translateError(err)
+}
+
+func (s *SQLStore) ReleaseJob(ctx context.Context, arg db.ReleaseJobParams) error {
+	finishedAt := arg.FinishedAt
+	finishedAt.Time = finishedAt.Time.UTC()
+	return translateError(s.q.ReleaseJob(ctx, sqlitedb.ReleaseJobParams{
+		Status:     arg.Status,
+		RunAt:      arg.RunAt.UTC(),
+		LastError:  arg.LastError,
+		FinishedAt: finishedAt,
+		ID:         arg.ID,
+		Attempts:   int64(arg.Attempts),
+	}))
+}
+
+func (s *SQLStore) RequeueJob(ctx context.Context, arg db.RequeueJobParams) (db.Job, error) {
+	job, err := s.q.RequeueJob(ctx, sqlitedb.RequeueJobParams{
+		RunAt: arg.RunAt.UTC(),
+		ID:    arg.ID,
+	})
+	return newJob(job), translateError(err)
+}
+
+func (s *SQLStore) RevokeUserTokens(ctx context.Context, id uint) (db.User, error) {
+	user, err := s.q.RevokeUserTokens(ctx, id)
+	return db.User(user), translateError(err)
+}
+
+func (s *SQLStore) SearchTags(ctx context.Context, arg db.SearchTagsParams) ([]db.SearchTagsRow, error) {
+	if err := checkPage(arg.Limit, 0); err != nil {
+		return nil, err
+	}
+	tags, err := s.q.SearchTags(ctx, sqlitedb.SearchTagsParams{
+		Name:  arg.Name,
+		Limit: int64(arg.Limit),
+	})
+	items := make([]db.SearchTagsRow, 0, len(tags))
+	for _, tag := range tags {
+		items = append(items, db.SearchTagsRow(tag))
+	}
+	return items, translateError(err)
+}
+
+func (s *SQLStore) UpdatePost(ctx context.Context, arg db.UpdatePostParams) (db.Post, error) {
+	post, err := s.q.UpdatePost(ctx, sqlitedb.UpdatePostParams(arg))
+	return db.Post(post), translateError(err)
+}
+
+func (s *SQLStore) UpdateUser(ctx context.Context, arg db.UpdateUserParams) (db.User, error) {
+	user, err := s.q.UpdateUser(ctx, sqlitedb.UpdateUserParams(arg))
+	return db.User(user), translateError(err)
+}
+
+func (s *SQLStore) UpdateUserLockedAt(ctx context.Context, arg db.UpdateUserLockedAtParams) (db.User, error) {
+	user, err := s.q.UpdateUserLockedAt(ctx, sqlitedb.UpdateUserLockedAtParams(arg))
+	return db.User(user), translateError(err)
+}
+
+func (s *SQLStore) UpdateUserRole(ctx context.Context, arg db.UpdateUserRoleParams) (db.User, error) {
+	user, err := s.q.UpdateUserRole(ctx, sqlitedb.UpdateUserRoleParams(arg))
+	return db.User(user), translateError(err)
+}
+
+func (s *SQLStore) UpdateWebhook(ctx context.Context, arg db.UpdateWebhookParams) (db.Webhook, error) {
+	webhook, err := s.q.UpdateWebhook(ctx, sqlitedb.UpdateWebhookParams{
+		Url:          arg.Url,
+		Events:       arg.Events,
+		DisabledAt:   arg.DisabledAt,
+		FailureCount: int64(arg.FailureCount),
+		ID:           arg.ID,
+	})
+	return newWebhook(webhook), translateError(err)
+}
+
+func (s *SQLStore) UpdateWebhookDelivery(ctx context.Context, arg db.UpdateWebhookDeliveryParams) (db.WebhookDelivery, error) {
+	delivery, err := s.q.UpdateWebhookDelivery(ctx, sqlitedb.UpdateWebhookDeliveryParams{
+		Status:         arg.Status,
+		Attempts:       int64(arg.Attempts),
+		NextAttemptAt:  arg.NextAttemptAt.UTC(),
+		ResponseStatus: int64(arg.ResponseStatus),
+		LastError:      arg.LastError,
+		DeliveredAt:    arg.DeliveredAt,
+		ID:             arg.ID,
+	})
+	return newWebhookDelivery(delivery), translateError(err)
+}
+
+func (s *SQLStore) UpsertTag(ctx context.Context, name string) (db.Tag, error) {
+	tag, err := s.q.UpsertTag(ctx, name)
+	return db.Tag(tag), translateError(err)
+}
+
+// newPostEvent converts the payload, which SQLite stores as text
+func newPostEvent(event sqlitedb.PostEvent) db.PostEvent {
+	return db.PostEvent{
+		ID:        event.ID,
+		Type:      event.Type,
+		PostID:    event.PostID,
+		Payload:   json.RawMessage(event.Payload),
+		CreatedAt: event.CreatedAt,
+	}
+}
+
+// newJob converts the payload, which SQLite stores as text, and the
+// counters
+func newJob(job sqlitedb.Job) db.Job {
+	return db.Job{
+		ID:          job.ID,
+		Kind:        job.Kind,
+		Payload:     json.RawMessage(job.Payload),
+		UniqueKey:   job.UniqueKey,
+		Status:      job.Status,
+		Attempts:    int32(job.Attempts),
+		MaxAttempts: int32(job.MaxAttempts),
+		RunAt:       job.RunAt,
+		LastError:   job.LastError,
+		FinishedAt:  job.FinishedAt,
+		CreatedAt:   job.CreatedAt,
+	}
+}
+
+func newJobs(jobs []sqlitedb.Job) []db.Job {
+	items := make([]db.Job, 0, len(jobs))
+	for _, job := range jobs {
+		items = append(items, newJob(job))
+	}
+	return items
+}
+
+// newWebhook converts the counter, which SQLite returns as int64
+func newWebhook(webhook sqlitedb.Webhook) db.Webhook {
+	return db.Webhook{
+		ID:           webhook.ID,
+		UserID:       webhook.UserID,
+		Url:          webhook.Url,
+		Secret:       webhook.Secret,
+		Events:       webhook.Events,
+		FailureCount: int32(webhook.FailureCount),
+		DisabledAt:   webhook.DisabledAt,
+		CreatedAt:    webhook.CreatedAt,
+	}
+}
+
+func newWebhooks(webhooks []sqlitedb.Webhook) []db.Webhook {
+	items := make([]db.Webhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		items = append(items, newWebhook(webhook))
+	}
+	return items
+}
+
+// newWebhookDelivery converts the payload, which SQLite stores as text, and
+// the counters
+func newWebhookDelivery(delivery sqlitedb.WebhookDelivery) db.WebhookDelivery {
+	return db.WebhookDelivery{
+		ID:             delivery.ID,
+		WebhookID:      delivery.WebhookID,
+		EventType:      delivery.EventType,
+		Payload:        json.RawMessage(delivery.Payload),
+		Status:         delivery.Status,
+		Attempts:       int32(delivery.Attempts),
+		NextAttemptAt:  delivery.NextAttemptAt,
+		ResponseStatus: int32(delivery.ResponseStatus),
+		LastError:      delivery.LastError,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+	}
+}
+
+func newWebhookDeliveries(deliveries []sqlitedb.WebhookDelivery) []db.WebhookDelivery {
+	items := make([]db.WebhookDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		items = append(items, newWebhookDelivery(delivery))
+	}
+	return items
+}
+
+// checkPage rejects what Postgres rejects: SQLite treats a negative LIMIT as
+// "no limit" and a negative OFFSET as zero.
+func checkPage(limit, offset int32) error {
+	if limit < 0 {
+		return &pq.Error{Code: db.InvalidRowCountInLimitClause, Message: "LIMIT must not be negative"}
+	}
+	if offset < 0 {
+		return &pq.Error{Code: db.InvalidRowCountInResultOffset, Message: "OFFSET must not be negative"}
+	}
+	return nil
+}
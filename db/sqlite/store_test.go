@@ -0,0 +1,36 @@
+package sqlite
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/db/storetest"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStore opens a fresh database file and applies the up migrations
+func newTestStore(t *testing.T) db.Store {
+	conn, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	files, err := fs.Glob(Migrations, "migration/*.up.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	sort.Strings(files)
+	for _, file := range files {
+		schema, err := fs.ReadFile(Migrations, file)
+		require.NoError(t, err)
+		_, err = conn.Exec(string(schema))
+		require.NoError(t, err)
+	}
+
+	return NewStore(conn)
+}
+
+func TestStoreConformance(t *testing.T) {
+	storetest.Run(t, newTestStore)
+}
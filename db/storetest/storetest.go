@@ -0,0 +1,2002 @@
+// Package storetest is a conformance suite for db.Store implementations.
+//
+// Every backend (Postgres, in-memory, ...) runs the same suite so that the
+// usecases can rely on identical behaviour regardless of DB_DRIVER. The suite
+// never assumes an empty database, so it can run against a shared Postgres.
+package storetest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// Run runs the conformance suite against the store returned by newStore
+func Run(t *testing.T, newStore func(t *testing.T) db.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store db.Store)
+	}{
+		{"CreateUser", testCreateUser},
+		{"CreateUserDuplicateEmail", testCreateUserDuplicateEmail},
+		{"CreateUserDuplicateUserStrID", testCreateUserDuplicateUserStrID},
+		{"CreateUserConcurrentDuplicates", testCreateUserConcurrentDuplicates},
+		{"GetUser", testGetUser},
+		{"GetUserNotFound", testGetUserNotFound},
+		{"GetUserByEmail", testGetUserByEmail},
+		{"GetUserByUserStrId", testGetUserByUserStrId},
+		{"GetUserStrIdById", testGetUserStrIdById},
+		{"ListUsers", testListUsers},
+		{"ListUsersByIDs", testListUsersByIDs},
+		{"UpdateUser", testUpdateUser},
+		{"UpdateUserDuplicateEmail", testUpdateUserDuplicateEmail},
+		{"UpdateUserNotFound", testUpdateUserNotFound},
+		{"UpdateUserRole", testUpdateUserRole},
+		{"UpdateUserLockedAt", testUpdateUserLockedAt},
+		{"RevokeUserTokens", testRevokeUserTokens},
+		{"DeleteUser", testDeleteUser},
+		{"DeleteUserWithPosts", testDeleteUserWithPosts},
+		{"CreatePost", testCreatePost},
+		{"CreatePostUnknownUser", testCreatePostUnknownUser},
+		{"GetPost", testGetPost},
+		{"GetPostNotFound", testGetPostNotFound},
+		{"ListPosts", testListPosts},
+		{"UpdatePost", testUpdatePost},
+		{"UpdatePostNotFound", testUpdatePostNotFound},
+		{"DeletePost", testDeletePost},
+		{"DeletePostsByUser", testDeletePostsByUser},
+		{"DeletePostsCreatedBefore", testDeletePostsCreatedBefore},
+		{"PostEvents", testPostEvents},
+		{"Replies", testReplies},
+		{"ReplyUnknownParent", testReplyUnknownParent},
+		{"ListReplies", testListReplies},
+		{"ListRepliesByParentIDs", testListRepliesByParentIDs},
+		{"PostMentions", testPostMentions},
+		{"PostMentionUnknownUser", testPostMentionUnknownUser},
+		{"DeletePostMentionCascades", testDeletePostMentionCascades},
+		{"Tags", testTags},
+		{"PostTagUnknownTag", testPostTagUnknownTag},
+		{"ListPostsByTag", testListPostsByTag},
+		{"SearchTags", testSearchTags},
+		{"ListTrendingTags", testListTrendingTags},
+		{"DeletePostTagCascades", testDeletePostTagCascades},
+		{"Webhooks", testWebhooks},
+		{"WebhookUnknownUser", testWebhookUnknownUser},
+		{"WebhookFailures", testWebhookFailures},
+		{"WebhookFailuresConcurrently", testWebhookFailuresConcurrently},
+		{"WebhookDeliveries", testWebhookDeliveries},
+		{"ClaimWebhookDeliveries", testClaimWebhookDeliveries},
+		{"ClaimWebhookDeliveriesConcurrently", testClaimWebhookDeliveriesConcurrently},
+		{"DeleteWebhookCascades", testDeleteWebhookCascades},
+		{"ExecTxCommits", testExecTxCommits},
+		{"ExecTxRollsBack", testExecTxRollsBack},
+		{"Jobs", testJobs},
+		{"JobUniqueKey", testJobUniqueKey},
+		{"JobUniqueKeyConcurrently", testJobUniqueKeyConcurrently},
+		{"ClaimJobs", testClaimJobs},
+		{"ClaimJobsConcurrently", testClaimJobsConcurrently},
+		{"ReleaseJob", testReleaseJob},
+		{"RequeueJob", testRequeueJob},
+		{"DeleteSucceededJobs", testDeleteSucceededJobs},
+		{"Notifications", testNotifications},
+		{"NotificationUnknownRefs", testNotificationUnknownRefs},
+		{"MarkNotificationsRead", testMarkNotificationsRead},
+		{"DeleteNotificationCascades", testDeleteNotificationCascades},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newStore(t))
+		})
+	}
+}
+
+// randomUserParams skips bcrypt on purpose: stores treat the password as an
+// opaque string and hashing would dominate the suite's run time.
+func randomUserParams(t *testing.T) db.CreateUserParams {
+	return db.CreateUserParams{
+		UserStrID: utils.RandomString(12),
+		Email:     utils.RandomString(12) + "@email.com",
+		Password:  utils.RandomString(60),
+	}
+}
+
+func createRandomUser(t *testing.T, store db.Store) db.User {
+	arg := randomUserParams(t)
+
+	user, err := store.CreateUser(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, user.ID)
+	require.Equal(t, arg.UserStrID, user.UserStrID)
+	require.Equal(t, arg.Email, user.Email)
+	require.Equal(t, arg.Password, user.Password)
+	require.NotZero(t, user.CreatedAt)
+	require.Equal(t, "user", user.Role)
+	require.False(t, user.LockedAt.Valid)
+	require.False(t, user.TokensRevokedAt.Valid)
+
+	return user
+}
+
+func createRandomPost(t *testing.T, store db.Store, user db.User) db.Post {
+	arg := db.CreatePostParams{
+		UserID: user.ID,
+		Text:   utils.RandomString(9),
+	}
+
+	post, err := store.CreatePost(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, post.ID)
+	require.Equal(t, arg.UserID, post.UserID)
+	require.Equal(t, arg.Text, post.Text)
+	require.NotZero(t, post.CreatedAt)
+	require.False(t, post.ParentID.Valid)
+
+	return post
+}
+
+// missingUserID returns the id of a user that existed but has been deleted
+func missingUserID(t *testing.T, store db.Store) uint {
+	user := createRandomUser(t, store)
+	require.NoError(t, store.DeleteUser(context.Background(), user.ID))
+	return user.ID
+}
+
+// missingPostID returns the id of a post that existed but has been deleted
+func missingPostID(t *testing.T, store db.Store) uint {
+	post := createRandomPost(t, store, createRandomUser(t, store))
+	require.NoError(t, store.DeletePost(context.Background(), post.ID))
+	return post.ID
+}
+
+func testCreateUser(t *testing.T, store db.Store) {
+	user1 := createRandomUser(t, store)
+	user2 := createRandomUser(t, store)
+	require.Greater(t, user2.ID, user1.ID)
+}
+
+func testCreateUserDuplicateEmail(t *testing.T, store db.Store) {
+	user := createRandomUser(t, store)
+
+	arg := randomUserParams(t)
+	arg.Email = user.Email
+	_, err := store.CreateUser(context.Background(), arg)
+	require.Error(t, err)
+	require.Equal(t, db.UniqueViolation, db.ErrorCode(err))
+	require.Equal(t, "users_email_key", db.ErrorConstraint(err))
+}
+
+func testCreateUserDuplicateUserStrID(t *testing.T, store db.Store) {
+	user := createRandomUser(t, store)
+
+	arg := randomUserParams(t)
+	arg.UserStrID = user.UserStrID
+	_, err := store.CreateUser(context.Background(), arg)
+	require.Error(t, err)
+	require.Equal(t, db.UniqueViolation, db.ErrorCode(err))
+	require.Equal(t, "users_user_str_id_key", db.ErrorConstraint(err))
+}
+
+func testCreateUserConcurrentDuplicates(t *testing.T, store db.Store) {
+	arg := randomUserParams(t)
+
+	n := 10
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.CreateUser(context.Background(), arg)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.Equal(t, db.UniqueViolation, db.ErrorCode(err))
+	}
+	require.Equal(t, 1, succeeded)
+}
+
+func testGetUser(t *testing.T, store db.Store) {
+	user1 := createRandomUser(t, store)
+	user2, err := store.GetUser(context.Background(), user1.ID)
+	require.NoError(t, err)
+
+	require.Equal(t, user1.ID, user2.ID)
+	require.Equal(t, user1.UserStrID, user2.UserStrID)
+	require.Equal(t, user1.Email, user2.Email)
+	require.Equal(t, user1.Password, user2.Password)
+	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
+}
+
+func testGetUserNotFound(t *testing.T, store db.Store) {
+	id := missingUserID(t, store)
+
+	user, err := store.GetUser(context.Background(), id)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+	require.Empty(t, user)
+
+	_, err = store.GetUserStrIdById(context.Background(), id)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	_, err = store.GetUserByEmail(context.Background(), utils.RandomString(12)+"@email.com")
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testGetUserByEmail(t *testing.T, store db.Store) {
+	user1 := createRandomUser(t, store)
+	user2, err := store.GetUserByEmail(context.Background(), user1.Email)
+	require.NoError(t, err)
+
+	require.Equal(t, user1.ID, user2.ID)
+	require.Equal(t, user1.UserStrID, user2.UserStrID)
+}
+
+func testGetUserByUserStrId(t *testing.T, store db.Store) {
+	user1 := createRandomUser(t, store)
+	user2, err := store.GetUserByUserStrId(context.Background(), user1.UserStrID)
+	require.NoError(t, err)
+	require.Equal(t, user1.ID, user2.ID)
+	require.Equal(t, user1.Email, user2.Email)
+
+	_, err = store.GetUserByUserStrId(context.Background(), utils.RandomString(12))
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testGetUserStrIdById(t *testing.T, store db.Store) {
+	user := createRandomUser(t, store)
+	userStrID, err := store.GetUserStrIdById(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Equal(t, user.UserStrID, userStrID)
+}
+
+func testListUsers(t *testing.T, store db.Store) {
+	for i := 0; i < 10; i++ {
+		createRandomUser(t, store)
+	}
+
+	page1, err := store.ListUsers(context.Background(), db.ListUsersParams{Limit: 5, Offset: 0})
+	require.NoError(t, err)
+	require.Len(t, page1, 5)
+
+	page2, err := store.ListUsers(context.Background(), db.ListUsersParams{Limit: 5, Offset: 5})
+	require.NoError(t, err)
+	require.Len(t, page2, 5)
+
+	users := append(page1, page2...)
+	for i := 1; i < len(users); i++ {
+		require.Less(t, users[i-1].ID, users[i].ID)
+	}
+
+	empty, err := store.ListUsers(context.Background(), db.ListUsersParams{Limit: 0, Offset: 0})
+	require.NoError(t, err)
+	require.NotNil(t, empty)
+	require.Empty(t, empty)
+}
+
+func testListUsersByIDs(t *testing.T, store db.Store) {
+	user1 := createRandomUser(t, store)
+	user2 := createRandomUser(t, store)
+	user3 := createRandomUser(t, store)
+
+	// unknown ids are skipped, duplicates collapse and the result is ordered
+	ids := []int64{int64(user3.ID), int64(user1.ID), int64(user3.ID), int64(user3.ID) + 1000000}
+	users, err := store.ListUsersByIDs(context.Background(), ids)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	require.Equal(t, user1.ID, users[0].ID)
+	require.Equal(t, user3.ID, users[1].ID)
+	require.Equal(t, user3.Email, users[1].Email)
+	require.NotEqual(t, user2.ID, users[0].ID)
+
+	empty, err := store.ListUsersByIDs(context.Background(), nil)
+	require.NoError(t, err)
+	require.NotNil(t, empty)
+	require.Empty(t, empty)
+}
+
+func testUpdateUser(t *testing.T, store db.Store) {
+	user1 := createRandomUser(t, store)
+	arg := randomUserParams(t)
+
+	user2, err := store.UpdateUser(context.Background(), db.UpdateUserParams{
+		ID:        user1.ID,
+		UserStrID: arg.UserStrID,
+		Email:     arg.Email,
+		Password:  arg.Password,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user1.ID, user2.ID)
+	require.Equal(t, arg.UserStrID, user2.UserStrID)
+	require.Equal(t, arg.Email, user2.Email)
+	require.Equal(t, arg.Password, user2.Password)
+	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
+
+	// the old email is free again and the new one resolves to the user
+	_, err = store.GetUserByEmail(context.Background(), user1.Email)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+	user3, err := store.GetUserByEmail(context.Background(), arg.Email)
+	require.NoError(t, err)
+	require.Equal(t, user1.ID, user3.ID)
+
+	// updating a user with its own values is not a conflict
+	_, err = store.UpdateUser(context.Background(), db.UpdateUserParams{
+		ID:        user2.ID,
+		UserStrID: user2.UserStrID,
+		Email:     user2.Email,
+		Password:  user2.Password,
+	})
+	require.NoError(t, err)
+}
+
+func testUpdateUserDuplicateEmail(t *testing.T, store db.Store) {
+	user1 := createRandomUser(t, store)
+	user2 := createRandomUser(t, store)
+
+	_, err := store.UpdateUser(context.Background(), db.UpdateUserParams{
+		ID:        user2.ID,
+		UserStrID: user2.UserStrID,
+		Email:     user1.Email,
+		Password:  user2.Password,
+	})
+	require.Error(t, err)
+	require.Equal(t, db.UniqueViolation, db.ErrorCode(err))
+	require.Equal(t, "users_email_key", db.ErrorConstraint(err))
+}
+
+func testUpdateUserNotFound(t *testing.T, store db.Store) {
+	arg := randomUserParams(t)
+	_, err := store.UpdateUser(context.Background(), db.UpdateUserParams{
+		ID:        missingUserID(t, store),
+		UserStrID: arg.UserStrID,
+		Email:     arg.Email,
+		Password:  arg.Password,
+	})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testUpdateUserRole(t *testing.T, store db.Store) {
+	user1 := createRandomUser(t, store)
+
+	user2, err := store.UpdateUserRole(context.Background(), db.UpdateUserRoleParams{
+		ID:   user1.ID,
+		Role: "moderator",
+	})
+	require.NoError(t, err)
+	require.Equal(t, user1.ID, user2.ID)
+	require.Equal(t, "moderator", user2.Role)
+
+	user3, err := store.GetUser(context.Background(), user1.ID)
+	require.NoError(t, err)
+	require.Equal(t, "moderator", user3.Role)
+
+	_, err = store.UpdateUserRole(context.Background(), db.UpdateUserRoleParams{
+		ID:   missingUserID(t, store),
+		Role: "moderator",
+	})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testUpdateUserLockedAt(t *testing.T, store db.Store) {
+	user1 := createRandomUser(t, store)
+	lockedAt := time.Now()
+
+	user2, err := store.UpdateUserLockedAt(context.Background(), db.UpdateUserLockedAtParams{
+		ID:       user1.ID,
+		LockedAt: sql.NullTime{Time: lockedAt, Valid: true},
+	})
+	require.NoError(t, err)
+	require.True(t, user2.LockedAt.Valid)
+	require.WithinDuration(t, lockedAt, user2.LockedAt.Time, time.Millisecond)
+
+	user3, err := store.GetUser(context.Background(), user1.ID)
+	require.NoError(t, err)
+	require.True(t, user3.LockedAt.Valid)
+	require.WithinDuration(t, lockedAt, user3.LockedAt.Time, time.Millisecond)
+
+	user4, err := store.UpdateUserLockedAt(context.Background(), db.UpdateUserLockedAtParams{
+		ID: user1.ID,
+	})
+	require.NoError(t, err)
+	require.False(t, user4.LockedAt.Valid)
+}
+
+func testRevokeUserTokens(t *testing.T, store db.Store) {
+	user1 := createRandomUser(t, store)
+
+	user2, err := store.RevokeUserTokens(context.Background(), user1.ID)
+	require.NoError(t, err)
+	require.True(t, user2.TokensRevokedAt.Valid)
+	require.False(t, user2.TokensRevokedAt.Time.Before(user1.CreatedAt))
+	require.WithinDuration(t, time.Now(), user2.TokensRevokedAt.Time, time.Minute)
+
+	_, err = store.RevokeUserTokens(context.Background(), missingUserID(t, store))
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testDeleteUser(t *testing.T, store db.Store) {
+	user1 := createRandomUser(t, store)
+	require.NoError(t, store.DeleteUser(context.Background(), user1.ID))
+
+	user2, err := store.GetUser(context.Background(), user1.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+	require.Empty(t, user2)
+
+	// deleting twice is not an error
+	require.NoError(t, store.DeleteUser(context.Background(), user1.ID))
+
+	// the email and user_str_id can be reused
+	_, err = store.CreateUser(context.Background(), db.CreateUserParams{
+		UserStrID: user1.UserStrID,
+		Email:     user1.Email,
+		Password:  user1.Password,
+	})
+	require.NoError(t, err)
+}
+
+func testDeleteUserWithPosts(t *testing.T, store db.Store) {
+	user := createRandomUser(t, store)
+	post := createRandomPost(t, store, user)
+
+	err := store.DeleteUser(context.Background(), user.ID)
+	require.Error(t, err)
+	require.Equal(t, db.ForeignKeyViolation, db.ErrorCode(err))
+
+	_, err = store.GetUser(context.Background(), user.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, store.DeletePost(context.Background(), post.ID))
+	require.NoError(t, store.DeleteUser(context.Background(), user.ID))
+}
+
+func testCreatePost(t *testing.T, store db.Store) {
+	user := createRandomUser(t, store)
+	post1 := createRandomPost(t, store, user)
+	post2 := createRandomPost(t, store, user)
+	require.Greater(t, post2.ID, post1.ID)
+}
+
+func testCreatePostUnknownUser(t *testing.T, store db.Store) {
+	_, err := store.CreatePost(context.Background(), db.CreatePostParams{
+		UserID: missingUserID(t, store),
+		Text:   utils.RandomString(9),
+	})
+	require.Error(t, err)
+	require.Equal(t, db.ForeignKeyViolation, db.ErrorCode(err))
+}
+
+func testGetPost(t *testing.T, store db.Store) {
+	user := createRandomUser(t, store)
+	post1 := createRandomPost(t, store, user)
+
+	post2, err := store.GetPost(context.Background(), post1.ID)
+	require.NoError(t, err)
+	require.Equal(t, post1.ID, post2.ID)
+	require.Equal(t, post1.UserID, post2.UserID)
+	require.Equal(t, post1.Text, post2.Text)
+	require.WithinDuration(t, post1.CreatedAt, post2.CreatedAt, time.Second)
+}
+
+func testGetPostNotFound(t *testing.T, store db.Store) {
+	post, err := store.GetPost(context.Background(), missingPostID(t, store))
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+	require.Empty(t, post)
+}
+
+func testListPosts(t *testing.T, store db.Store) {
+	user := createRandomUser(t, store)
+	for i := 0; i < 10; i++ {
+		createRandomPost(t, store, user)
+	}
+
+	page1, err := store.ListPosts(context.Background(), db.ListPostsParams{Limit: 5, Offset: 0})
+	require.NoError(t, err)
+	require.Len(t, page1, 5)
+
+	page2, err := store.ListPosts(context.Background(), db.ListPostsParams{Limit: 5, Offset: 5})
+	require.NoError(t, err)
+	require.Len(t, page2, 5)
+
+	posts := append(page1, page2...)
+	for i := 1; i < len(posts); i++ {
+		require.Less(t, posts[i-1].ID, posts[i].ID)
+	}
+
+	_, err = store.ListPosts(context.Background(), db.ListPostsParams{Limit: -1, Offset: 0})
+	require.Error(t, err)
+}
+
+func testUpdatePost(t *testing.T, store db.Store) {
+	user := createRandomUser(t, store)
+	post1 := createRandomPost(t, store, user)
+
+	arg := db.UpdatePostParams{
+		ID:   post1.ID,
+		Text: utils.RandomString(9),
+	}
+	post2, err := store.UpdatePost(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, post1.ID, post2.ID)
+	require.Equal(t, post1.UserID, post2.UserID)
+	require.Equal(t, arg.Text, post2.Text)
+	require.WithinDuration(t, post1.CreatedAt, post2.CreatedAt, time.Second)
+
+	post3, err := store.GetPost(context.Background(), post1.ID)
+	require.NoError(t, err)
+	require.Equal(t, arg.Text, post3.Text)
+}
+
+func testUpdatePostNotFound(t *testing.T, store db.Store) {
+	_, err := store.UpdatePost(context.Background(), db.UpdatePostParams{
+		ID:   missingPostID(t, store),
+		Text: utils.RandomString(9),
+	})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testDeletePost(t *testing.T, store db.Store) {
+	user := createRandomUser(t, store)
+	post1 := createRandomPost(t, store, user)
+	require.NoError(t, store.DeletePost(context.Background(), post1.ID))
+
+	post2, err := store.GetPost(context.Background(), post1.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+	require.Empty(t, post2)
+
+	// deleting twice is not an error
+	require.NoError(t, store.DeletePost(context.Background(), post1.ID))
+}
+
+func testDeletePostsByUser(t *testing.T, store db.Store) {
+	user1 := createRandomUser(t, store)
+	user2 := createRandomUser(t, store)
+	var ids []uint
+	for i := 0; i < 3; i++ {
+		ids = append(ids, createRandomPost(t, store, user1).ID)
+	}
+	other := createRandomPost(t, store, user2)
+
+	// the posts deleted are returned
+	posts, err := store.DeletePostsByUser(context.Background(), user1.ID)
+	require.NoError(t, err)
+	require.Len(t, posts, 3)
+	var deleted []uint
+	for _, post := range posts {
+		require.Equal(t, user1.ID, post.UserID)
+		deleted = append(deleted, post.ID)
+	}
+	require.ElementsMatch(t, ids, deleted)
+
+	_, err = store.GetPost(context.Background(), other.ID)
+	require.NoError(t, err)
+
+	// the user has no posts left, so it can be deleted
+	require.NoError(t, store.DeleteUser(context.Background(), user1.ID))
+
+	posts, err = store.DeletePostsByUser(context.Background(), user1.ID)
+	require.NoError(t, err)
+	require.Empty(t, posts)
+}
+
+func testDeletePostsCreatedBefore(t *testing.T, store db.Store) {
+	user := createRandomUser(t, store)
+	post1 := createRandomPost(t, store, user)
+	time.Sleep(20 * time.Millisecond)
+	post2 := createRandomPost(t, store, user)
+	require.True(t, post2.CreatedAt.After(post1.CreatedAt))
+
+	cutoff := post1.CreatedAt.Add(5 * time.Millisecond)
+	posts, err := store.DeletePostsCreatedBefore(context.Background(), cutoff)
+	require.NoError(t, err)
+	require.NotEmpty(t, posts)
+	var deleted []uint
+	for _, post := range posts {
+		require.True(t, post.CreatedAt.Before(cutoff))
+		deleted = append(deleted, post.ID)
+	}
+	require.Contains(t, deleted, post1.ID)
+	require.NotContains(t, deleted, post2.ID)
+
+	_, err = store.GetPost(context.Background(), post1.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+	_, err = store.GetPost(context.Background(), post2.ID)
+	require.NoError(t, err)
+}
+
+func testPostEvents(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	last, err := store.GetLastPostEventID(ctx)
+	require.NoError(t, err)
+
+	var created []db.PostEvent
+	for i := 0; i < 3; i++ {
+		arg := db.CreatePostEventParams{
+			Type:    "post.created",
+			PostID:  utils.RandomInt(1, 1000),
+			Payload: json.RawMessage(`{"text": "` + utils.RandomString(6) + `"}`),
+		}
+		event, err := store.CreatePostEvent(ctx, arg)
+		require.NoError(t, err)
+		require.Greater(t, event.ID, last)
+		require.Equal(t, arg.Type, event.Type)
+		require.Equal(t, arg.PostID, event.PostID)
+		require.JSONEq(t, string(arg.Payload), string(event.Payload))
+		require.NotZero(t, event.CreatedAt)
+		created = append(created, event)
+	}
+
+	// other tests may log events concurrently on a shared database
+	id, err := store.GetLastPostEventID(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, id, created[2].ID)
+
+	events, err := store.ListPostEventsAfter(ctx, db.ListPostEventsAfterParams{ID: created[0].ID - 1, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	for i, event := range events {
+		require.Equal(t, created[i].ID, event.ID)
+		require.Equal(t, created[i].PostID, event.PostID)
+		require.JSONEq(t, string(created[i].Payload), string(event.Payload))
+		require.WithinDuration(t, created[i].CreatedAt, event.CreatedAt, time.Millisecond)
+	}
+
+	events, err = store.ListPostEventsAfter(ctx, db.ListPostEventsAfterParams{ID: created[1].ID, Limit: 10})
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	require.Equal(t, created[2].ID, events[0].ID)
+
+	events, err = store.ListPostEventsAfter(ctx, db.ListPostEventsAfterParams{ID: id + 1000000, Limit: 10})
+	require.NoError(t, err)
+	require.NotNil(t, events)
+	require.Empty(t, events)
+}
+
+func testReplies(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	user := createRandomUser(t, store)
+	parent := createRandomPost(t, store, user)
+
+	reply, err := store.CreatePost(ctx, db.CreatePostParams{
+		UserID:   user.ID,
+		Text:     utils.RandomString(9),
+		ParentID: sql.NullInt64{Int64: int64(parent.ID), Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, sql.NullInt64{Int64: int64(parent.ID), Valid: true}, reply.ParentID)
+	got, err := store.GetPost(ctx, reply.ID)
+	require.NoError(t, err)
+	require.Equal(t, reply.ParentID, got.ParentID)
+
+	// replies outlive their parent
+	require.NoError(t, store.DeletePost(ctx, parent.ID))
+	got, err = store.GetPost(ctx, reply.ID)
+	require.NoError(t, err)
+	require.False(t, got.ParentID.Valid)
+}
+
+func testReplyUnknownParent(t *testing.T, store db.Store) {
+	_, err := store.CreatePost(context.Background(), db.CreatePostParams{
+		UserID:   createRandomUser(t, store).ID,
+		Text:     utils.RandomString(9),
+		ParentID: sql.NullInt64{Int64: int64(missingPostID(t, store)), Valid: true},
+	})
+	require.Error(t, err)
+	require.Equal(t, db.ForeignKeyViolation, db.ErrorCode(err))
+}
+
+func testListReplies(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	user := createRandomUser(t, store)
+	parent := createRandomPost(t, store, user)
+	other := createRandomPost(t, store, user)
+
+	var replies []db.Post
+	for i := 0; i < 3; i++ {
+		reply, err := store.CreatePost(ctx, db.CreatePostParams{
+			UserID:   user.ID,
+			Text:     utils.RandomString(9),
+			ParentID: sql.NullInt64{Int64: int64(parent.ID), Valid: true},
+		})
+		require.NoError(t, err)
+		replies = append(replies, reply)
+	}
+	_, err := store.CreatePost(ctx, db.CreatePostParams{
+		UserID:   user.ID,
+		Text:     utils.RandomString(9),
+		ParentID: sql.NullInt64{Int64: int64(other.ID), Valid: true},
+	})
+	require.NoError(t, err)
+
+	arg := db.ListRepliesParams{
+		ParentID: sql.NullInt64{Int64: int64(parent.ID), Valid: true},
+		Limit:    2,
+	}
+	page, err := store.ListReplies(ctx, arg)
+	require.NoError(t, err)
+	require.Equal(t, replies[:2], page)
+	arg.Offset = 2
+	page, err = store.ListReplies(ctx, arg)
+	require.NoError(t, err)
+	require.Equal(t, replies[2:], page)
+
+	page, err = store.ListReplies(ctx, db.ListRepliesParams{
+		ParentID: sql.NullInt64{Int64: int64(replies[0].ID), Valid: true},
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, page)
+	require.Empty(t, page)
+}
+
+func testListRepliesByParentIDs(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	user := createRandomUser(t, store)
+	parents := []db.Post{createRandomPost(t, store, user), createRandomPost(t, store, user)}
+	lonely := createRandomPost(t, store, user)
+
+	// interleaved, so a window per parent differs from a window of all
+	replies := map[uint][]db.Post{}
+	for i := 0; i < 3; i++ {
+		for _, parent := range parents {
+			reply, err := store.CreatePost(ctx, db.CreatePostParams{
+				UserID:   user.ID,
+				Text:     utils.RandomString(9),
+				ParentID: sql.NullInt64{Int64: int64(parent.ID), Valid: true},
+			})
+			require.NoError(t, err)
+			replies[parent.ID] = append(replies[parent.ID], reply)
+		}
+	}
+
+	arg := db.ListRepliesByParentIDsParams{
+		ParentIds: []int64{int64(parents[1].ID), int64(lonely.ID), int64(parents[0].ID)},
+		Limit:     2,
+	}
+	page, err := store.ListRepliesByParentIDs(ctx, arg)
+	require.NoError(t, err)
+	// ordered by parent, then oldest first
+	want := append(slices.Clone(replies[parents[0].ID][:2]), replies[parents[1].ID][:2]...)
+	require.Equal(t, want, page)
+
+	arg.Offset = 2
+	page, err = store.ListRepliesByParentIDs(ctx, arg)
+	require.NoError(t, err)
+	want = append(slices.Clone(replies[parents[0].ID][2:]), replies[parents[1].ID][2:]...)
+	require.Equal(t, want, page)
+
+	page, err = store.ListRepliesByParentIDs(ctx, db.ListRepliesByParentIDsParams{
+		ParentIds: []int64{int64(lonely.ID)},
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, page)
+	require.Empty(t, page)
+}
+
+func createPostMention(t *testing.T, store db.Store, post db.Post, user db.User, start int32) db.PostMention {
+	arg := db.CreatePostMentionParams{
+		PostID:      post.ID,
+		UserID:      user.ID,
+		StartOffset: start,
+		EndOffset:   start + int32(len(user.UserStrID)) + 1,
+	}
+	require.NoError(t, store.CreatePostMention(context.Background(), arg))
+	return db.PostMention(arg)
+}
+
+func testPostMentions(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	author := createRandomUser(t, store)
+	user1, user2 := createRandomUser(t, store), createRandomUser(t, store)
+	post1 := createRandomPost(t, store, author)
+	post2 := createRandomPost(t, store, author)
+	post3 := createRandomPost(t, store, author)
+	m3 := createPostMention(t, store, post1, user1, 20)
+	m1 := createPostMention(t, store, post1, user2, 0)
+	m2 := createPostMention(t, store, post1, user1, 10)
+	m4 := createPostMention(t, store, post2, user2, 5)
+	createPostMention(t, store, post3, user2, 5)
+
+	// by post, then offset
+	mentions, err := store.ListPostMentions(ctx, []int64{int64(post2.ID), int64(post1.ID)})
+	require.NoError(t, err)
+	require.Equal(t, []db.PostMention{m1, m2, m3, m4}, mentions)
+
+	err = store.CreatePostMention(ctx, db.CreatePostMentionParams{PostID: post1.ID, UserID: user2.ID, StartOffset: 10, EndOffset: 12})
+	require.Equal(t, db.UniqueViolation, db.ErrorCode(err))
+
+	require.NoError(t, store.DeletePostMentions(ctx, post1.ID))
+	mentions, err = store.ListPostMentions(ctx, []int64{int64(post1.ID), int64(post2.ID)})
+	require.NoError(t, err)
+	require.Equal(t, []db.PostMention{m4}, mentions)
+
+	mentions, err = store.ListPostMentions(ctx, []int64{})
+	require.NoError(t, err)
+	require.Empty(t, mentions)
+}
+
+func testPostMentionUnknownUser(t *testing.T, store db.Store) {
+	post := createRandomPost(t, store, createRandomUser(t, store))
+	err := store.CreatePostMention(context.Background(), db.CreatePostMentionParams{
+		PostID:      post.ID,
+		UserID:      missingUserID(t, store),
+		StartOffset: 0,
+		EndOffset:   5,
+	})
+	require.Error(t, err)
+	require.Equal(t, db.ForeignKeyViolation, db.ErrorCode(err))
+}
+
+func testDeletePostMentionCascades(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	author := createRandomUser(t, store)
+	user := createRandomUser(t, store)
+	post1 := createRandomPost(t, store, author)
+	post2 := createRandomPost(t, store, author)
+	createPostMention(t, store, post1, user, 0)
+	createPostMention(t, store, post2, user, 0)
+	kept := createPostMention(t, store, post2, author, 10)
+
+	require.NoError(t, store.DeletePost(ctx, post1.ID))
+	require.NoError(t, store.DeleteUser(ctx, user.ID))
+	mentions, err := store.ListPostMentions(ctx, []int64{int64(post1.ID), int64(post2.ID)})
+	require.NoError(t, err)
+	require.Equal(t, []db.PostMention{kept}, mentions)
+}
+
+func createPostTag(t *testing.T, store db.Store, post db.Post, name string) db.Tag {
+	tag, err := store.UpsertTag(context.Background(), name)
+	require.NoError(t, err)
+	require.NoError(t, store.CreatePostTag(context.Background(), db.CreatePostTagParams{PostID: post.ID, TagID: tag.ID}))
+	return tag
+}
+
+// tagCounts returns the post counts of the tags in rows named in names
+func tagCounts[T db.SearchTagsRow | db.ListTrendingTagsRow](rows []T, names ...string) map[string]int64 {
+	counts := map[string]int64{}
+	for _, row := range rows {
+		r := db.SearchTagsRow(row)
+		for _, name := range names {
+			if r.Name == name {
+				counts[name] = r.PostCount
+			}
+		}
+	}
+	return counts
+}
+
+func testTags(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	name := utils.RandomString(12)
+	tag, err := store.UpsertTag(ctx, name)
+	require.NoError(t, err)
+	require.NotZero(t, tag.ID)
+	require.Equal(t, name, tag.Name)
+	require.NotZero(t, tag.CreatedAt)
+
+	// the same name is the same tag
+	again, err := store.UpsertTag(ctx, name)
+	require.NoError(t, err)
+	require.Equal(t, tag, again)
+
+	author := createRandomUser(t, store)
+	post1 := createRandomPost(t, store, author)
+	post2 := createRandomPost(t, store, author)
+	other := utils.RandomString(12)
+	createPostTag(t, store, post1, name)
+	createPostTag(t, store, post1, other)
+	createPostTag(t, store, post2, name)
+	// tagging twice is a no-op
+	createPostTag(t, store, post2, name)
+
+	// by post, then name
+	tags, err := store.ListPostTags(ctx, []int64{int64(post2.ID), int64(post1.ID)})
+	require.NoError(t, err)
+	first, second := name, other
+	if second < first {
+		first, second = second, first
+	}
+	require.Equal(t, []db.ListPostTagsRow{
+		{PostID: post1.ID, Name: first},
+		{PostID: post1.ID, Name: second},
+		{PostID: post2.ID, Name: name},
+	}, tags)
+
+	require.NoError(t, store.DeletePostTags(ctx, post1.ID))
+	tags, err = store.ListPostTags(ctx, []int64{int64(post1.ID), int64(post2.ID)})
+	require.NoError(t, err)
+	require.Equal(t, []db.ListPostTagsRow{{PostID: post2.ID, Name: name}}, tags)
+
+	tags, err = store.ListPostTags(ctx, []int64{})
+	require.NoError(t, err)
+	require.Empty(t, tags)
+}
+
+func testPostTagUnknownTag(t *testing.T, store db.Store) {
+	post := createRandomPost(t, store, createRandomUser(t, store))
+	err := store.CreatePostTag(context.Background(), db.CreatePostTagParams{PostID: post.ID, TagID: math.MaxInt32})
+	require.Error(t, err)
+	require.Equal(t, db.ForeignKeyViolation, db.ErrorCode(err))
+}
+
+func testListPostsByTag(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	author := createRandomUser(t, store)
+	name := utils.RandomString(12)
+	var posts []db.Post
+	for i := 0; i < 3; i++ {
+		post := createRandomPost(t, store, author)
+		createPostTag(t, store, post, name)
+		posts = append(posts, post)
+	}
+	// untagged
+	createRandomPost(t, store, author)
+
+	// newest first
+	page, err := store.ListPostsByTag(ctx, db.ListPostsByTagParams{Name: name, ID: math.MaxInt32, Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, []db.Post{posts[2], posts[1]}, page)
+	page, err = store.ListPostsByTag(ctx, db.ListPostsByTagParams{Name: name, ID: posts[1].ID, Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, []db.Post{posts[0]}, page)
+
+	page, err = store.ListPostsByTag(ctx, db.ListPostsByTagParams{Name: utils.RandomString(12), ID: math.MaxInt32, Limit: 2})
+	require.NoError(t, err)
+	require.Empty(t, page)
+}
+
+func testSearchTags(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	author := createRandomUser(t, store)
+	post1 := createRandomPost(t, store, author)
+	post2 := createRandomPost(t, store, author)
+	prefix := utils.RandomString(12)
+	createPostTag(t, store, post1, prefix+"b")
+	createPostTag(t, store, post1, prefix+"a")
+	createPostTag(t, store, post2, prefix+"b")
+	createPostTag(t, store, post2, prefix+"_x")
+	createPostTag(t, store, post2, prefix+"zx")
+	// tags no post uses are not suggested
+	_, err := store.UpsertTag(ctx, prefix+"c")
+	require.NoError(t, err)
+
+	// the most used first, then by name
+	tags, err := store.SearchTags(ctx, db.SearchTagsParams{Name: prefix + "%", Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, []db.SearchTagsRow{
+		{Name: prefix + "b", PostCount: 2},
+		{Name: prefix + "_x", PostCount: 1},
+		{Name: prefix + "a", PostCount: 1},
+		{Name: prefix + "zx", PostCount: 1},
+	}, tags)
+
+	tags, err = store.SearchTags(ctx, db.SearchTagsParams{Name: prefix + "%", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+
+	// \ escapes wildcards
+	tags, err = store.SearchTags(ctx, db.SearchTagsParams{Name: prefix + `\_%`, Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, []db.SearchTagsRow{{Name: prefix + "_x", PostCount: 1}}, tags)
+}
+
+func testListTrendingTags(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	author := createRandomUser(t, store)
+	old, recent := utils.RandomString(12), utils.RandomString(12)
+	post1 := createRandomPost(t, store, author)
+	createPostTag(t, store, post1, old)
+	time.Sleep(20 * time.Millisecond)
+	post2 := createRandomPost(t, store, author)
+	post3 := createRandomPost(t, store, author)
+	createPostTag(t, store, post2, old)
+	createPostTag(t, store, post2, recent)
+	createPostTag(t, store, post3, recent)
+
+	// only posts created since count
+	since := post1.CreatedAt.Add(5 * time.Millisecond)
+	tags, err := store.ListTrendingTags(ctx, db.ListTrendingTagsParams{CreatedAt: since, Limit: math.MaxInt32})
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{old: 1, recent: 2}, tagCounts(tags, old, recent))
+	for i := 1; i < len(tags); i++ {
+		require.GreaterOrEqual(t, tags[i-1].PostCount, tags[i].PostCount)
+	}
+
+	tags, err = store.ListTrendingTags(ctx, db.ListTrendingTagsParams{CreatedAt: post1.CreatedAt, Limit: math.MaxInt32})
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{old: 2, recent: 2}, tagCounts(tags, old, recent))
+
+	tags, err = store.ListTrendingTags(ctx, db.ListTrendingTagsParams{CreatedAt: since, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+}
+
+func testDeletePostTagCascades(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	author := createRandomUser(t, store)
+	post1 := createRandomPost(t, store, author)
+	post2 := createRandomPost(t, store, author)
+	name := utils.RandomString(12)
+	createPostTag(t, store, post1, name)
+	createPostTag(t, store, post2, name)
+
+	require.NoError(t, store.DeletePost(ctx, post1.ID))
+	tags, err := store.ListPostTags(ctx, []int64{int64(post1.ID), int64(post2.ID)})
+	require.NoError(t, err)
+	require.Equal(t, []db.ListPostTagsRow{{PostID: post2.ID, Name: name}}, tags)
+
+	// the tag stays, for the posts tagged later
+	again, err := store.UpsertTag(ctx, name)
+	require.NoError(t, err)
+	require.NoError(t, store.CreatePostTag(ctx, db.CreatePostTagParams{PostID: post2.ID, TagID: again.ID}))
+}
+
+func createRandomWebhook(t *testing.T, store db.Store, user db.User) db.Webhook {
+	arg := db.CreateWebhookParams{
+		UserID: user.ID,
+		Url:    "https://example.com/" + utils.RandomString(8),
+		Secret: utils.RandomString(32),
+		Events: "post.created,post.deleted",
+	}
+
+	webhook, err := store.CreateWebhook(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, webhook.ID)
+	require.Equal(t, arg.UserID, webhook.UserID)
+	require.Equal(t, arg.Url, webhook.Url)
+	require.Equal(t, arg.Secret, webhook.Secret)
+	require.Equal(t, arg.Events, webhook.Events)
+	require.Zero(t, webhook.FailureCount)
+	require.False(t, webhook.DisabledAt.Valid)
+	require.NotZero(t, webhook.CreatedAt)
+
+	return webhook
+}
+
+func createRandomDelivery(t *testing.T, store db.Store, webhook db.Webhook) db.WebhookDelivery {
+	arg := db.CreateWebhookDeliveryParams{
+		WebhookID: webhook.ID,
+		EventType: "post.created",
+		Payload:   json.RawMessage(`{"text": "` + utils.RandomString(6) + `"}`),
+	}
+
+	delivery, err := store.CreateWebhookDelivery(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, delivery.ID)
+	require.Equal(t, arg.WebhookID, delivery.WebhookID)
+	require.Equal(t, arg.EventType, delivery.EventType)
+	require.JSONEq(t, string(arg.Payload), string(delivery.Payload))
+	require.Equal(t, "pending", delivery.Status)
+	require.Zero(t, delivery.Attempts)
+	require.NotZero(t, delivery.NextAttemptAt)
+	require.Zero(t, delivery.ResponseStatus)
+	require.Empty(t, delivery.LastError)
+	require.False(t, delivery.DeliveredAt.Valid)
+	require.NotZero(t, delivery.CreatedAt)
+
+	return delivery
+}
+
+func testWebhooks(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	user := createRandomUser(t, store)
+	webhook1 := createRandomWebhook(t, store, user)
+	webhook2 := createRandomWebhook(t, store, user)
+	createRandomWebhook(t, store, createRandomUser(t, store))
+
+	webhook, err := store.GetWebhook(ctx, webhook1.ID)
+	require.NoError(t, err)
+	require.Equal(t, webhook1.Url, webhook.Url)
+	require.WithinDuration(t, webhook1.CreatedAt, webhook.CreatedAt, time.Millisecond)
+
+	webhooks, err := store.ListWebhooksByUser(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 2)
+	require.Equal(t, webhook1.ID, webhooks[0].ID)
+	require.Equal(t, webhook2.ID, webhooks[1].ID)
+
+	disabledAt := time.Now().UTC()
+	webhook, err = store.UpdateWebhook(ctx, db.UpdateWebhookParams{
+		ID:         webhook1.ID,
+		Url:        "https://example.org/hook",
+		Events:     "user.signed_up",
+		DisabledAt: sql.NullTime{Time: disabledAt, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "https://example.org/hook", webhook.Url)
+	require.Equal(t, "user.signed_up", webhook.Events)
+	require.Equal(t, webhook1.Secret, webhook.Secret)
+	require.True(t, webhook.DisabledAt.Valid)
+	require.WithinDuration(t, disabledAt, webhook.DisabledAt.Time, time.Millisecond)
+
+	enabled, err := store.ListEnabledWebhooks(ctx)
+	require.NoError(t, err)
+	ids := map[int64]bool{}
+	for _, webhook := range enabled {
+		ids[webhook.ID] = true
+	}
+	require.False(t, ids[webhook1.ID])
+	require.True(t, ids[webhook2.ID])
+
+	_, err = store.UpdateWebhook(ctx, db.UpdateWebhookParams{ID: webhook1.ID + 1000000})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	require.NoError(t, store.DeleteWebhook(ctx, webhook1.ID))
+	_, err = store.GetWebhook(ctx, webhook1.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+	// deleting twice is not an error
+	require.NoError(t, store.DeleteWebhook(ctx, webhook1.ID))
+
+	// webhooks go with their owner
+	require.NoError(t, store.DeleteUser(ctx, user.ID))
+	webhooks, err = store.ListWebhooksByUser(ctx, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, webhooks)
+	require.Empty(t, webhooks)
+}
+
+func testWebhookUnknownUser(t *testing.T, store db.Store) {
+	_, err := store.CreateWebhook(context.Background(), db.CreateWebhookParams{
+		UserID: missingUserID(t, store),
+		Url:    "https://example.com/hook",
+		Secret: utils.RandomString(32),
+		Events: "post.created",
+	})
+	require.Error(t, err)
+	require.Equal(t, db.ForeignKeyViolation, db.ErrorCode(err))
+}
+
+func testWebhookFailures(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	webhook := createRandomWebhook(t, store, createRandomUser(t, store))
+	arg := db.RecordWebhookFailureParams{ID: webhook.ID, DisableAfter: 3}
+
+	for i := 1; i <= 2; i++ {
+		webhook, err := store.RecordWebhookFailure(ctx, arg)
+		require.NoError(t, err)
+		require.EqualValues(t, i, webhook.FailureCount)
+		require.False(t, webhook.DisabledAt.Valid)
+	}
+
+	webhook, err := store.RecordWebhookSuccess(ctx, webhook.ID)
+	require.NoError(t, err)
+	require.Zero(t, webhook.FailureCount)
+
+	for i := 1; i <= 3; i++ {
+		webhook, err = store.RecordWebhookFailure(ctx, arg)
+		require.NoError(t, err)
+	}
+	require.EqualValues(t, 3, webhook.FailureCount)
+	require.True(t, webhook.DisabledAt.Valid)
+	disabledAt := webhook.DisabledAt.Time
+
+	// further failures keep the time it was disabled
+	webhook, err = store.RecordWebhookFailure(ctx, arg)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, webhook.FailureCount)
+	require.WithinDuration(t, disabledAt, webhook.DisabledAt.Time, time.Millisecond)
+
+	_, err = store.RecordWebhookFailure(ctx, db.RecordWebhookFailureParams{ID: webhook.ID + 1000000, DisableAfter: 3})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testWebhookFailuresConcurrently(t *testing.T, store db.Store) {
+	webhook := createRandomWebhook(t, store, createRandomUser(t, store))
+	arg := db.RecordWebhookFailureParams{ID: webhook.ID, DisableAfter: 5}
+
+	n := 10
+	results := make(chan db.Webhook, n)
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			webhook, err := store.RecordWebhookFailure(context.Background(), arg)
+			results <- webhook
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// no failure is lost, and every one from the limit on sees the webhook
+	// disabled at the same time
+	var counts []int
+	var disabledAt []time.Time
+	for webhook := range results {
+		counts = append(counts, int(webhook.FailureCount))
+		if webhook.FailureCount >= arg.DisableAfter {
+			require.True(t, webhook.DisabledAt.Valid)
+			disabledAt = append(disabledAt, webhook.DisabledAt.Time)
+		} else {
+			require.False(t, webhook.DisabledAt.Valid)
+		}
+	}
+	sort.Ints(counts)
+	require.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, counts)
+	for _, at := range disabledAt {
+		require.WithinDuration(t, disabledAt[0], at, time.Millisecond)
+	}
+}
+
+func testWebhookDeliveries(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	webhook := createRandomWebhook(t, store, createRandomUser(t, store))
+	var created []db.WebhookDelivery
+	for i := 0; i < 3; i++ {
+		created = append(created, createRandomDelivery(t, store, webhook))
+	}
+	createRandomDelivery(t, store, createRandomWebhook(t, store, createRandomUser(t, store)))
+
+	delivery, err := store.GetWebhookDelivery(ctx, created[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, created[0].WebhookID, delivery.WebhookID)
+	require.JSONEq(t, string(created[0].Payload), string(delivery.Payload))
+
+	// newest first
+	deliveries, err := store.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		WebhookID: webhook.ID,
+		ID:        math.MaxInt64,
+		Limit:     2,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	require.Equal(t, created[2].ID, deliveries[0].ID)
+	require.Equal(t, created[1].ID, deliveries[1].ID)
+
+	deliveries, err = store.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		WebhookID: webhook.ID,
+		ID:        created[1].ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, created[0].ID, deliveries[0].ID)
+
+	next := time.Now().UTC().Add(time.Minute)
+	deliveredAt := time.Now().UTC()
+	delivery, err = store.UpdateWebhookDelivery(ctx, db.UpdateWebhookDeliveryParams{
+		ID:             created[0].ID,
+		Status:         "succeeded",
+		Attempts:       2,
+		NextAttemptAt:  next,
+		ResponseStatus: 204,
+		LastError:      "timeout",
+		DeliveredAt:    sql.NullTime{Time: deliveredAt, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "succeeded", delivery.Status)
+	require.EqualValues(t, 2, delivery.Attempts)
+	require.WithinDuration(t, next, delivery.NextAttemptAt, time.Millisecond)
+	require.EqualValues(t, 204, delivery.ResponseStatus)
+	require.Equal(t, "timeout", delivery.LastError)
+	require.WithinDuration(t, deliveredAt, delivery.DeliveredAt.Time, time.Millisecond)
+
+	_, err = store.GetWebhookDelivery(ctx, created[2].ID+1000000)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+	_, err = store.UpdateWebhookDelivery(ctx, db.UpdateWebhookDeliveryParams{ID: created[2].ID + 1000000})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	_, err = store.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+		WebhookID: webhook.ID + 1000000,
+		EventType: "post.created",
+		Payload:   json.RawMessage(`{}`),
+	})
+	require.Error(t, err)
+	require.Equal(t, db.ForeignKeyViolation, db.ErrorCode(err))
+}
+
+func testClaimWebhookDeliveries(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	webhook := createRandomWebhook(t, store, createRandomUser(t, store))
+
+	// due at times long past, so no other delivery on a shared database is
+	// due before them
+	due := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(utils.RandomInt(0, 1000000)) * time.Second)
+	schedule := func(delivery db.WebhookDelivery, status string, at time.Time) db.WebhookDelivery {
+		delivery, err := store.UpdateWebhookDelivery(ctx, db.UpdateWebhookDeliveryParams{
+			ID:            delivery.ID,
+			Status:        status,
+			NextAttemptAt: at,
+		})
+		require.NoError(t, err)
+		return delivery
+	}
+	first := schedule(createRandomDelivery(t, store, webhook), "pending", due.Add(-2*time.Second))
+	second := schedule(createRandomDelivery(t, store, webhook), "pending", due.Add(-time.Second))
+	later := schedule(createRandomDelivery(t, store, webhook), "pending", due.Add(time.Hour))
+	done := schedule(createRandomDelivery(t, store, webhook), "succeeded", due.Add(-time.Hour))
+
+	claim := func(now time.Time, max int32) map[int64]db.WebhookDelivery {
+		deliveries, err := store.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+			LeaseUntil: now.Add(time.Minute),
+			Now:        now,
+			MaxCount:   max,
+		})
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(deliveries), int(max))
+		claimed := map[int64]db.WebhookDelivery{}
+		for _, delivery := range deliveries {
+			claimed[delivery.ID] = delivery
+		}
+		return claimed
+	}
+
+	// the earliest due first
+	claimed := claim(due, 1)
+	require.Len(t, claimed, 1)
+	require.Contains(t, claimed, first.ID)
+	require.WithinDuration(t, due.Add(time.Minute), claimed[first.ID].NextAttemptAt, time.Millisecond)
+
+	// leased deliveries are not claimed again until the lease runs out
+	claimed = claim(due, 1000)
+	require.Contains(t, claimed, second.ID)
+	require.NotContains(t, claimed, first.ID)
+	require.NotContains(t, claimed, later.ID)
+	require.NotContains(t, claimed, done.ID)
+
+	claimed = claim(due.Add(time.Minute), 1000)
+	require.Contains(t, claimed, first.ID)
+	require.Contains(t, claimed, second.ID)
+	require.NotContains(t, claimed, later.ID)
+	require.NotContains(t, claimed, done.ID)
+}
+
+func testClaimWebhookDeliveriesConcurrently(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	webhook := createRandomWebhook(t, store, createRandomUser(t, store))
+	due := time.Date(2002, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(utils.RandomInt(0, 1000000)) * time.Second)
+	ours := map[int64]bool{}
+	for i := 0; i < 20; i++ {
+		delivery, err := store.UpdateWebhookDelivery(ctx, db.UpdateWebhookDeliveryParams{
+			ID:            createRandomDelivery(t, store, webhook).ID,
+			Status:        "pending",
+			NextAttemptAt: due,
+		})
+		require.NoError(t, err)
+		ours[delivery.ID] = true
+	}
+
+	n := 5
+	claims := make(chan []db.WebhookDelivery, n)
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			deliveries, err := store.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+				LeaseUntil: due.Add(time.Minute),
+				Now:        due,
+				MaxCount:   1000,
+			})
+			claims <- deliveries
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(claims)
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// every delivery is claimed by exactly one worker
+	claimed := map[int64]int{}
+	for deliveries := range claims {
+		for _, delivery := range deliveries {
+			if ours[delivery.ID] {
+				claimed[delivery.ID]++
+			}
+		}
+	}
+	require.Len(t, claimed, len(ours))
+	for id, times := range claimed {
+		require.Equal(t, 1, times, "delivery %d", id)
+	}
+}
+
+func testDeleteWebhookCascades(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	user := createRandomUser(t, store)
+	webhook1 := createRandomWebhook(t, store, user)
+	webhook2 := createRandomWebhook(t, store, user)
+	delivery1 := createRandomDelivery(t, store, webhook1)
+	delivery2 := createRandomDelivery(t, store, webhook2)
+
+	require.NoError(t, store.DeleteWebhook(ctx, webhook1.ID))
+	_, err := store.GetWebhookDelivery(ctx, delivery1.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+	_, err = store.GetWebhookDelivery(ctx, delivery2.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, store.DeleteUser(ctx, user.ID))
+	_, err = store.GetWebhook(ctx, webhook2.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+	_, err = store.GetWebhookDelivery(ctx, delivery2.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testExecTxCommits(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	user := createRandomUser(t, store)
+
+	var post db.Post
+	err := store.ExecTx(ctx, func(tx db.Store) error {
+		var err error
+		post, err = tx.CreatePost(ctx, db.CreatePostParams{UserID: user.ID, Text: utils.RandomString(20)})
+		if err != nil {
+			return err
+		}
+		// visible within the transaction, nested or not
+		return tx.ExecTx(ctx, func(tx db.Store) error {
+			_, err := tx.GetPost(ctx, post.ID)
+			return err
+		})
+	})
+	require.NoError(t, err)
+
+	got, err := store.GetPost(ctx, post.ID)
+	require.NoError(t, err)
+	require.Equal(t, post.Text, got.Text)
+}
+
+func testExecTxRollsBack(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	user := createRandomUser(t, store)
+
+	var post db.Post
+	var job db.Job
+	err := store.ExecTx(ctx, func(tx db.Store) error {
+		var err error
+		post, err = tx.CreatePost(ctx, db.CreatePostParams{UserID: user.ID, Text: utils.RandomString(20)})
+		if err != nil {
+			return err
+		}
+		job = createRandomJob(t, tx, "test."+utils.RandomString(8))
+		// a failure rolls back what came before it
+		_, err = tx.CreatePost(ctx, db.CreatePostParams{UserID: missingUserID(t, tx), Text: utils.RandomString(20)})
+		return err
+	})
+	require.Equal(t, db.ForeignKeyViolation, db.ErrorCode(err))
+
+	_, err = store.GetPost(ctx, post.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+	_, err = store.GetJob(ctx, job.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	// the store is usable afterwards
+	createRandomPost(t, store, user)
+}
+
+func createRandomJob(t *testing.T, store db.Store, kind string) db.Job {
+	arg := db.CreateJobParams{
+		Kind:        kind,
+		Payload:     json.RawMessage(`{"text": "` + utils.RandomString(6) + `"}`),
+		MaxAttempts: 3,
+		RunAt:       time.Now().UTC(),
+	}
+
+	job, err := store.CreateJob(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, job.ID)
+	require.Equal(t, arg.Kind, job.Kind)
+	require.JSONEq(t, string(arg.Payload), string(job.Payload))
+	require.False(t, job.UniqueKey.Valid)
+	require.Equal(t, "pending", job.Status)
+	require.Zero(t, job.Attempts)
+	require.Equal(t, arg.MaxAttempts, job.MaxAttempts)
+	require.WithinDuration(t, arg.RunAt, job.RunAt, time.Millisecond)
+	require.Empty(t, job.LastError)
+	require.False(t, job.FinishedAt.Valid)
+	require.NotZero(t, job.CreatedAt)
+
+	return job
+}
+
+// claimJob claims the job of a kind no other job has
+func claimJob(t *testing.T, store db.Store, job db.Job) db.Job {
+	now := time.Now().UTC()
+	jobs, err := store.ClaimJobs(context.Background(), db.ClaimJobsParams{
+		LeaseUntil: now.Add(time.Minute),
+		Kind:       job.Kind,
+		Now:        now.Add(time.Second),
+		MaxCount:   10,
+	})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, job.ID, jobs[0].ID)
+	return jobs[0]
+}
+
+func testJobs(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	kind := "test." + utils.RandomString(8)
+	job1 := createRandomJob(t, store, kind)
+	job2 := createRandomJob(t, store, kind)
+
+	job, err := store.GetJob(ctx, job1.ID)
+	require.NoError(t, err)
+	require.Equal(t, job1.Kind, job.Kind)
+	require.JSONEq(t, string(job1.Payload), string(job.Payload))
+	require.WithinDuration(t, job1.RunAt, job.RunAt, time.Millisecond)
+
+	_, err = store.GetJob(ctx, job2.ID+1000000)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	// newest first
+	jobs, err := store.ListJobsByStatus(ctx, db.ListJobsByStatusParams{Status: "pending", ID: job2.ID + 1, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	require.Equal(t, job2.ID, jobs[0].ID)
+	require.Equal(t, job1.ID, jobs[1].ID)
+
+	jobs, err = store.ListJobsByStatus(ctx, db.ListJobsByStatusParams{Status: "pending", ID: job2.ID, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, job1.ID, jobs[0].ID)
+
+	jobs, err = store.ListJobsByStatus(ctx, db.ListJobsByStatusParams{Status: "dead", ID: job2.ID + 1, Limit: 1000})
+	require.NoError(t, err)
+	require.NotNil(t, jobs)
+	for _, job := range jobs {
+		require.NotEqual(t, job1.ID, job.ID)
+		require.NotEqual(t, job2.ID, job.ID)
+	}
+
+	_, err = store.ListJobsByStatus(ctx, db.ListJobsByStatusParams{Status: "pending", ID: job2.ID, Limit: -1})
+	require.Equal(t, db.InvalidRowCountInLimitClause, db.ErrorCode(err))
+}
+
+func testJobUniqueKey(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	arg := db.CreateJobParams{
+		Kind:        "test." + utils.RandomString(8),
+		Payload:     json.RawMessage(`{}`),
+		UniqueKey:   sql.NullString{String: utils.RandomString(16), Valid: true},
+		MaxAttempts: 3,
+		RunAt:       time.Now().UTC(),
+	}
+	job, err := store.CreateJob(ctx, arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.UniqueKey, job.UniqueKey)
+
+	// queued once, however often it is enqueued
+	_, err = store.CreateJob(ctx, arg)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	arg.UniqueKey.String = utils.RandomString(16)
+	_, err = store.CreateJob(ctx, arg)
+	require.NoError(t, err)
+}
+
+func testJobUniqueKeyConcurrently(t *testing.T, store db.Store) {
+	// as every worker queues a scheduled job due at the same time
+	runAt := time.Now().UTC().Truncate(time.Minute)
+	arg := db.CreateJobParams{
+		Kind:        "test." + utils.RandomString(8),
+		Payload:     json.RawMessage(`{}`),
+		UniqueKey:   sql.NullString{String: utils.RandomString(8) + "@" + strconv.FormatInt(runAt.Unix(), 10), Valid: true},
+		MaxAttempts: 3,
+		RunAt:       runAt,
+	}
+
+	n := 10
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.CreateJob(context.Background(), arg)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		require.ErrorIs(t, err, db.ErrRecordNotFound)
+	}
+	require.Equal(t, 1, succeeded)
+}
+
+func testClaimJobs(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	kind := "test." + utils.RandomString(8)
+	due := time.Now().UTC()
+	schedule := func(at time.Time) db.Job {
+		job, err := store.CreateJob(ctx, db.CreateJobParams{
+			Kind:        kind,
+			Payload:     json.RawMessage(`{}`),
+			MaxAttempts: 3,
+			RunAt:       at,
+		})
+		require.NoError(t, err)
+		return job
+	}
+	first := schedule(due.Add(-2 * time.Second))
+	second := schedule(due.Add(-time.Second))
+	later := schedule(due.Add(time.Hour))
+	other := createRandomJob(t, store, "test."+utils.RandomString(8))
+
+	claim := func(now time.Time, max int32) map[int64]db.Job {
+		jobs, err := store.ClaimJobs(ctx, db.ClaimJobsParams{
+			LeaseUntil: now.Add(time.Minute),
+			Kind:       kind,
+			Now:        now,
+			MaxCount:   max,
+		})
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(jobs), int(max))
+		claimed := map[int64]db.Job{}
+		for _, job := range jobs {
+			claimed[job.ID] = job
+		}
+		return claimed
+	}
+
+	// the earliest due first
+	claimed := claim(due, 1)
+	require.Len(t, claimed, 1)
+	require.Contains(t, claimed, first.ID)
+	require.Equal(t, "running", claimed[first.ID].Status)
+	require.EqualValues(t, 1, claimed[first.ID].Attempts)
+	require.WithinDuration(t, due.Add(time.Minute), claimed[first.ID].RunAt, time.Millisecond)
+
+	// leased jobs are not claimed again until the lease runs out, and only
+	// jobs of the kind asked for are
+	claimed = claim(due, 1000)
+	require.Len(t, claimed, 1)
+	require.Contains(t, claimed, second.ID)
+
+	claimed = claim(due.Add(time.Minute), 1000)
+	require.Len(t, claimed, 2)
+	require.EqualValues(t, 2, claimed[first.ID].Attempts)
+	require.EqualValues(t, 2, claimed[second.ID].Attempts)
+	require.NotContains(t, claimed, later.ID)
+	require.NotContains(t, claimed, other.ID)
+}
+
+func testClaimJobsConcurrently(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	kind := "test." + utils.RandomString(8)
+	var created []db.Job
+	for i := 0; i < 20; i++ {
+		created = append(created, createRandomJob(t, store, kind))
+	}
+
+	n := 5
+	now := time.Now().UTC().Add(time.Second)
+	claims := make(chan []db.Job, n)
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			jobs, err := store.ClaimJobs(ctx, db.ClaimJobsParams{
+				LeaseUntil: now.Add(time.Minute),
+				Kind:       kind,
+				Now:        now,
+				MaxCount:   1000,
+			})
+			claims <- jobs
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(claims)
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// every job is claimed by exactly one worker, once
+	claimed := map[int64]int{}
+	for jobs := range claims {
+		for _, job := range jobs {
+			claimed[job.ID]++
+			require.EqualValues(t, 1, job.Attempts)
+		}
+	}
+	require.Len(t, claimed, len(created))
+	for _, job := range created {
+		require.Equal(t, 1, claimed[job.ID], "job %d", job.ID)
+	}
+}
+
+func testReleaseJob(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	job := claimJob(t, store, createRandomJob(t, store, "test."+utils.RandomString(8)))
+
+	// a stale lease cannot release the job
+	runAt := time.Now().UTC().Add(time.Hour)
+	err := store.ReleaseJob(ctx, db.ReleaseJobParams{
+		ID:        job.ID,
+		Attempts:  job.Attempts - 1,
+		Status:    "pending",
+		RunAt:     runAt,
+		LastError: "stale",
+	})
+	require.NoError(t, err)
+	got, err := store.GetJob(ctx, job.ID)
+	require.NoError(t, err)
+	require.Equal(t, "running", got.Status)
+
+	err = store.ReleaseJob(ctx, db.ReleaseJobParams{
+		ID:        job.ID,
+		Attempts:  job.Attempts,
+		Status:    "pending",
+		RunAt:     runAt,
+		LastError: "boom",
+	})
+	require.NoError(t, err)
+	got, err = store.GetJob(ctx, job.ID)
+	require.NoError(t, err)
+	require.Equal(t, "pending", got.Status)
+	require.Equal(t, job.Attempts, got.Attempts)
+	require.Equal(t, "boom", got.LastError)
+	require.WithinDuration(t, runAt, got.RunAt, time.Millisecond)
+
+	// only running jobs are released
+	finishedAt := time.Now().UTC()
+	err = store.ReleaseJob(ctx, db.ReleaseJobParams{
+		ID:         job.ID,
+		Attempts:   job.Attempts,
+		Status:     "succeeded",
+		RunAt:      runAt,
+		FinishedAt: sql.NullTime{Time: finishedAt, Valid: true},
+	})
+	require.NoError(t, err)
+	got, err = store.GetJob(ctx, job.ID)
+	require.NoError(t, err)
+	require.Equal(t, "pending", got.Status)
+	require.False(t, got.FinishedAt.Valid)
+}
+
+func testRequeueJob(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	job := claimJob(t, store, createRandomJob(t, store, "test."+utils.RandomString(8)))
+
+	_, err := store.RequeueJob(ctx, db.RequeueJobParams{ID: job.ID, RunAt: time.Now().UTC()})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	err = store.ReleaseJob(ctx, db.ReleaseJobParams{
+		ID:         job.ID,
+		Attempts:   job.Attempts,
+		Status:     "dead",
+		RunAt:      job.RunAt,
+		LastError:  "boom",
+		FinishedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+	})
+	require.NoError(t, err)
+
+	runAt := time.Now().UTC()
+	requeued, err := store.RequeueJob(ctx, db.RequeueJobParams{ID: job.ID, RunAt: runAt})
+	require.NoError(t, err)
+	require.Equal(t, "pending", requeued.Status)
+	require.Zero(t, requeued.Attempts)
+	require.Empty(t, requeued.LastError)
+	require.False(t, requeued.FinishedAt.Valid)
+	require.WithinDuration(t, runAt, requeued.RunAt, time.Millisecond)
+
+	_, err = store.RequeueJob(ctx, db.RequeueJobParams{ID: job.ID + 1000000, RunAt: runAt})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testDeleteSucceededJobs(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	kind := "test." + utils.RandomString(8)
+	finish := func(job db.Job, status string, at time.Time) {
+		job = claimJob(t, store, job)
+		err := store.ReleaseJob(ctx, db.ReleaseJobParams{
+			ID:         job.ID,
+			Attempts:   job.Attempts,
+			Status:     status,
+			RunAt:      job.RunAt,
+			FinishedAt: sql.NullTime{Time: at, Valid: true},
+		})
+		require.NoError(t, err)
+	}
+	// finished long ago, so other tests on a shared database keep theirs
+	before := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(utils.RandomInt(0, 1000000)) * time.Second)
+	old := createRandomJob(t, store, kind)
+	finish(old, "succeeded", before.Add(-time.Second))
+	recent := createRandomJob(t, store, kind)
+	finish(recent, "succeeded", before.Add(time.Second))
+	dead := createRandomJob(t, store, kind)
+	finish(dead, "dead", before.Add(-time.Second))
+	pending := createRandomJob(t, store, kind)
+
+	n, err := store.DeleteSucceededJobs(ctx, before)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, n, int64(1))
+
+	_, err = store.GetJob(ctx, old.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+	for _, job := range []db.Job{recent, dead, pending} {
+		_, err = store.GetJob(ctx, job.ID)
+		require.NoError(t, err)
+	}
+}
+
+func createRandomNotification(t *testing.T, store db.Store, user db.User, post db.Post) db.Notification {
+	arg := db.CreateNotificationParams{
+		UserID:  user.ID,
+		Type:    "test." + utils.RandomString(8),
+		ActorID: post.UserID,
+		PostID:  post.ID,
+	}
+
+	notification, err := store.CreateNotification(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, notification.ID)
+	require.Equal(t, arg.UserID, notification.UserID)
+	require.Equal(t, arg.Type, notification.Type)
+	require.Equal(t, arg.ActorID, notification.ActorID)
+	require.Equal(t, arg.PostID, notification.PostID)
+	require.False(t, notification.ReadAt.Valid)
+	require.NotZero(t, notification.CreatedAt)
+
+	return notification
+}
+
+func testNotifications(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	user := createRandomUser(t, store)
+	post := createRandomPost(t, store, createRandomUser(t, store))
+	n1 := createRandomNotification(t, store, user, post)
+	n2 := createRandomNotification(t, store, user, post)
+	// someone else's
+	createRandomNotification(t, store, createRandomUser(t, store), post)
+
+	got, err := store.GetNotification(ctx, n1.ID)
+	require.NoError(t, err)
+	require.Equal(t, n1.ID, got.ID)
+	require.WithinDuration(t, n1.CreatedAt, got.CreatedAt, time.Second)
+
+	list, err := store.ListNotifications(ctx, db.ListNotificationsParams{UserID: user.ID, ID: math.MaxInt64, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, n2.ID, list[0].ID)
+	require.Equal(t, n1.ID, list[1].ID)
+	list, err = store.ListNotifications(ctx, db.ListNotificationsParams{UserID: user.ID, ID: n2.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, n1.ID, list[0].ID)
+
+	count, err := store.CountUnreadNotifications(ctx, user.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, count)
+
+	// notified once, however often it is created
+	_, err = store.CreateNotification(ctx, db.CreateNotificationParams{
+		UserID:  user.ID,
+		Type:    n1.Type,
+		ActorID: n1.ActorID,
+		PostID:  post.ID,
+	})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	_, err = store.GetNotification(ctx, math.MaxInt64)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func testNotificationUnknownRefs(t *testing.T, store db.Store) {
+	user := createRandomUser(t, store)
+	post := createRandomPost(t, store, user)
+	for _, arg := range []db.CreateNotificationParams{
+		{UserID: missingUserID(t, store), ActorID: user.ID, PostID: post.ID},
+		{UserID: user.ID, ActorID: missingUserID(t, store), PostID: post.ID},
+		{UserID: user.ID, ActorID: user.ID, PostID: missingPostID(t, store)},
+	} {
+		arg.Type = "test." + utils.RandomString(8)
+		_, err := store.CreateNotification(context.Background(), arg)
+		require.Error(t, err)
+		require.Equal(t, db.ForeignKeyViolation, db.ErrorCode(err))
+	}
+}
+
+func testMarkNotificationsRead(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	user := createRandomUser(t, store)
+	post := createRandomPost(t, store, createRandomUser(t, store))
+	n1 := createRandomNotification(t, store, user, post)
+	n2 := createRandomNotification(t, store, user, post)
+	n3 := createRandomNotification(t, store, user, post)
+	other := createRandomNotification(t, store, createRandomUser(t, store), post)
+
+	readAt := time.Now().UTC().Truncate(time.Second)
+	read, err := store.MarkNotificationRead(ctx, db.MarkNotificationReadParams{ReadAt: readAt, ID: n1.ID})
+	require.NoError(t, err)
+	require.True(t, read.ReadAt.Valid)
+	require.WithinDuration(t, readAt, read.ReadAt.Time, time.Second)
+
+	// read once, the first time
+	read, err = store.MarkNotificationRead(ctx, db.MarkNotificationReadParams{ReadAt: readAt.Add(time.Hour), ID: n1.ID})
+	require.NoError(t, err)
+	require.WithinDuration(t, readAt, read.ReadAt.Time, time.Second)
+
+	_, err = store.MarkNotificationRead(ctx, db.MarkNotificationReadParams{ReadAt: readAt, ID: math.MaxInt64})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	n, err := store.MarkAllNotificationsRead(ctx, db.MarkAllNotificationsReadParams{ReadAt: readAt, UserID: user.ID})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+	for _, notification := range []db.Notification{n2, n3} {
+		got, err := store.GetNotification(ctx, notification.ID)
+		require.NoError(t, err)
+		require.True(t, got.ReadAt.Valid)
+	}
+	count, err := store.CountUnreadNotifications(ctx, user.ID)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	got, err := store.GetNotification(ctx, other.ID)
+	require.NoError(t, err)
+	require.False(t, got.ReadAt.Valid)
+}
+
+func testDeleteNotificationCascades(t *testing.T, store db.Store) {
+	ctx := context.Background()
+	user := createRandomUser(t, store)
+	actor := createRandomUser(t, store)
+	post1 := createRandomPost(t, store, actor)
+	post2 := createRandomPost(t, store, createRandomUser(t, store))
+	byPost := createRandomNotification(t, store, user, post1)
+	kept := createRandomNotification(t, store, user, post2)
+
+	require.NoError(t, store.DeletePost(ctx, post1.ID))
+	_, err := store.GetNotification(ctx, byPost.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+	_, err = store.GetNotification(ctx, kept.ID)
+	require.NoError(t, err)
+
+	// the actor's notifications go with them too
+	post3 := createRandomPost(t, store, createRandomUser(t, store))
+	byActor, err := store.CreateNotification(ctx, db.CreateNotificationParams{
+		UserID:  user.ID,
+		Type:    "test." + utils.RandomString(8),
+		ActorID: actor.ID,
+		PostID:  post3.ID,
+	})
+	require.NoError(t, err)
+	require.NoError(t, store.DeleteUser(ctx, actor.ID))
+	_, err = store.GetNotification(ctx, byActor.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	require.NoError(t, store.DeleteUser(ctx, user.ID))
+	_, err = store.GetNotification(ctx, kept.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
@@ -0,0 +1,38 @@
+package dto
+
+import "time"
+
+// Types of notifications
+const (
+	// NotificationReply tells the author of a post of a reply to it
+	NotificationReply = "reply"
+	// NotificationMention tells a user they were mentioned in a post
+	NotificationMention = "mention"
+)
+
+// ListNotificationsRequest selects up to Limit notifications of a user
+// older than the notification with id Before, newest first. A zero Before
+// starts from the newest.
+type ListNotificationsRequest struct {
+	UserID uint
+	Before int64
+	Limit  int32
+}
+
+type NotificationResponse struct {
+	ID int64 `json:"id"`
+	// UserID is the user notified
+	UserID uint   `json:"user_id"`
+	Type   string `json:"type"`
+	// Actor is the user who replied or mentioned
+	ActorID        uint       `json:"actor_id"`
+	ActorUserStrID string     `json:"actor_user_str_id"`
+	PostID         uint       `json:"post_id"`
+	Read           bool       `json:"read"`
+	ReadAt         *time.Time `json:"read_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type UnreadNotificationsResponse struct {
+	Count int64 `json:"count"`
+}
@@ -1,10 +1,19 @@
 package dto
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type CreatePostRequest struct {
-	UserID uint   `json:"user_id" validate:"required"`
+	// UserID is the author, who is the caller and never read from the body
+	UserID uint   `json:"-" validate:"required"`
 	Text   string `json:"text" validate:"required,min=1"`
+	// ParentID makes the post a reply to the post with that id
+	ParentID uint `json:"parent_id"`
+	// Tags are tags of the post besides the #tags in Text, with or
+	// without the #
+	Tags []string `json:"tags"`
 }
 
 type AllPostsRequest struct {
@@ -12,15 +21,71 @@ type AllPostsRequest struct {
 	PageSize int32 `form:"page_size" validate:"required,min=1"`
 }
 
+// ListPostsRequest selects a window of posts, oldest first
+type ListPostsRequest struct {
+	// ParentID lists only the replies to the post with that id
+	ParentID uint
+	Limit    int32
+	Offset   int32
+}
+
+// ListRepliesRequest selects the same window of replies, oldest first, to
+// each of several posts
+type ListRepliesRequest struct {
+	ParentIDs []uint
+	Limit     int32
+	Offset    int32
+}
+
 type UpdatePostRequest struct {
-	ID   uint   `json:"id" validate:"required"`
+	// ID is taken from the path, never from the body
+	ID   uint   `json:"-" validate:"required"`
 	Text string `json:"text" validate:"required"`
 }
 
 type PostResponse struct {
-	ID        uint      `json:"id"`
-	UserID    uint      `json:"user_id"`
-	UserStrID string    `json:"user_str_id"`
-	Text      string    `json:"text"`
+	ID        uint   `json:"id"`
+	UserID    uint   `json:"user_id"`
+	UserStrID string `json:"user_str_id"`
+	Text      string `json:"text"`
+	// ParentID is the post replied to, if it still exists
+	ParentID uint `json:"parent_id,omitempty"`
+	// Mentions are the users mentioned in Text, in order
+	Mentions []MentionEntity `json:"mentions,omitempty"`
+	// Tags are the normalized tags of the post, sorted
+	Tags      []string  `json:"tags,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// MentionEntity is an @user_str_id in the text of a post, linked to the
+// user by id so that it survives renames. Start and End are offsets in
+// Unicode code points, the @ included and End excluded.
+type MentionEntity struct {
+	UserID uint `json:"user_id"`
+	Start  int  `json:"start"`
+	End    int  `json:"end"`
+}
+
+// PurgePostsRequest selects the posts to delete in bulk.
+// Exactly one of UserStrID and Before must be set.
+type PurgePostsRequest struct {
+	UserStrID string
+	Before    time.Time
+}
+
+// PostEvent is an entry of the post event log. Data is the post as of the
+// event, or only its id once it has been deleted.
+type PostEvent struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	PostID    uint            `json:"post_id"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// DeletedPost is the data of a post.deleted event. ParentID is set for a
+// reply, so the event reaches the thread it was in.
+type DeletedPost struct {
+	ID       uint `json:"id"`
+	ParentID uint `json:"parent_id,omitempty"`
+}
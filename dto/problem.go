@@ -0,0 +1,28 @@
+package dto
+
+// MIMEApplicationProblemJSON is the media type of Problem responses
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Problem is an RFC 7807 problem details body.
+// Code is a stable, machine readable identifier clients can switch on; the
+// title and detail are for humans and may change.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why one input field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Params fill in the translated message after the field name, such as
+	// the bound broken. They are only part of the message.
+	Params []string `json:"-"`
+}
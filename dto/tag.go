@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+// ListTagPostsRequest selects a page of the posts with a tag, newest first
+type ListTagPostsRequest struct {
+	Tag string
+	// Before is the id of the last post of the previous page, if any
+	Before uint
+	Limit  int32
+}
+
+// SearchTagsRequest asks for the tags starting with Prefix, most used
+// first
+type SearchTagsRequest struct {
+	Prefix string
+	Limit  int32
+}
+
+// TrendingTagsRequest asks for the tags of the most posts created within
+// Window, most used first
+type TrendingTagsRequest struct {
+	Window time.Duration
+	Limit  int32
+}
+
+type TagResponse struct {
+	Name      string `json:"name"`
+	PostCount int64  `json:"post_count"`
+}
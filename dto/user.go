@@ -17,14 +17,40 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required,min=6"`
 }
 
+type LoginResponse struct {
+	Token string `json:"token"`
+}
+
+// Roles a user can have. Moderators and admins may remove other users' posts.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type JwtCustomClaims struct {
-	ID uint `json:"id"`
+	ID   uint   `json:"id"`
+	Role string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// IsModerator reports whether the token holder may act on other users' posts
+func (c *JwtCustomClaims) IsModerator() bool {
+	return c.Role == RoleModerator || c.Role == RoleAdmin
+}
+
 type CreateUserResponse struct {
 	ID        uint      `json:"id"`
 	UserStrID string    `json:"user_str_id"`
 	Email     string    `json:"email"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+type UserResponse struct {
+	ID        uint       `json:"id"`
+	UserStrID string     `json:"user_str_id"`
+	Email     string     `json:"email"`
+	Role      string     `json:"role"`
+	LockedAt  *time.Time `json:"locked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,75 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type CreateWebhookRequest struct {
+	UserID uint     `json:"-"`
+	URL    string   `json:"url" validate:"required,url,max=2048"`
+	Events []string `json:"events" validate:"required,min=1,dive,required"`
+}
+
+type UpdateWebhookRequest struct {
+	ID     int64    `json:"-"`
+	UserID uint     `json:"-"`
+	URL    string   `json:"url" validate:"required,url,max=2048"`
+	Events []string `json:"events" validate:"required,min=1,dive,required"`
+	// Active disables the webhook, or enables it again with its failures
+	// forgotten. Left out, the webhook stays as it is.
+	Active *bool `json:"active"`
+}
+
+type WebhookResponse struct {
+	ID     int64    `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active bool     `json:"active"`
+	// FailureCount is the number of failed attempts since the last
+	// successful delivery
+	FailureCount int32      `json:"failure_count"`
+	DisabledAt   *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	// Secret signs the deliveries. It is only returned when the webhook is
+	// created.
+	Secret string `json:"secret,omitempty"`
+}
+
+// ListWebhookDeliveriesRequest selects up to Limit deliveries of a webhook
+// older than the delivery with id Before, newest first. A zero Before
+// starts from the newest.
+type ListWebhookDeliveriesRequest struct {
+	WebhookID int64
+	UserID    uint
+	Before    int64
+	Limit     int32
+}
+
+type WebhookDeliveryResponse struct {
+	ID        int64  `json:"id"`
+	WebhookID int64  `json:"webhook_id"`
+	Event     string `json:"event"`
+	// Status is pending until the delivery succeeded or ran out of attempts
+	Status   string `json:"status"`
+	Attempts int32  `json:"attempts"`
+	// ResponseStatus is the HTTP status of the last attempt, if it got one
+	ResponseStatus int32  `json:"response_status,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+	// NextAttemptAt is when a pending delivery is attempted next
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	// Payload is the body sent to the endpoint
+	Payload json.RawMessage `json:"payload"`
+}
+
+// WebhookEvent is the body of a webhook delivery. ID identifies the event:
+// the deliveries of an event to different webhooks, and redeliveries, share
+// it.
+type WebhookEvent struct {
+	ID        string          `json:"id"`
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
@@ -0,0 +1,57 @@
+package graph
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/i18n"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+)
+
+// Error is a GraphQL error carrying the same code and field errors the REST
+// API puts in problem details, under extensions
+type Error struct {
+	Code    string
+	Message string
+	Fields  []dto.FieldError
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Extensions implements gqlerrors.ExtendedError
+func (e *Error) Extensions() map[string]interface{} {
+	ext := map[string]interface{}{"code": e.Code}
+	if len(e.Fields) > 0 {
+		ext["fields"] = e.Fields
+	}
+	return ext
+}
+
+// resolveError turns an error returned by a usecase into what the client
+// sees. Internal failures are logged and reported without detail.
+func resolveError(ctx context.Context, err error) error {
+	trans := fromContext(ctx).trans
+
+	e, ok := usecase.AsError(err)
+	if !ok {
+		slog.ErrorContext(ctx, "graphql resolver failed", "err", err)
+		return &Error{Code: "internal_error", Message: "internal server error"}
+	}
+
+	var fields []dto.FieldError
+	for _, f := range e.Fields {
+		fields = append(fields, dto.FieldError{
+			Field:   f.Field,
+			Code:    f.Code,
+			Message: i18n.FieldMessage(trans, f),
+		})
+	}
+	return &Error{
+		Code:    e.Code,
+		Message: i18n.Message(trans, e.Code, e.Message),
+		Fields:  fields,
+	}
+}
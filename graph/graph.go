@@ -0,0 +1,107 @@
+// Package graph serves the GraphQL API alongside the REST one.
+//
+// Resolvers call the same usecases the REST controllers do, so ownership
+// rules and error codes are shared. Authors of posts, and the replies to
+// them, are fetched through per-request loaders that batch every post on a
+// page into a single lookup. Queries are checked against depth and complexity limits before
+// they are executed.
+//
+// Users and posts are exposed today, with the replies to a post as a
+// connection of its own, so a thread is read by nesting replies. Boards and
+// reactions will be added to the schema once the domain has them.
+package graph
+
+import (
+	"context"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/location"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Request is the body of a GraphQL request sent over HTTP
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Limits bounds how expensive a single query may be. Zero disables a limit.
+type Limits struct {
+	// MaxDepth is how deeply fields may be nested
+	MaxDepth int
+	// MaxComplexity is how many fields a query may resolve, counting the
+	// fields below a paginated connection once per requested item
+	MaxComplexity int
+}
+
+// Server executes GraphQL requests against the usecases
+type Server struct {
+	schema graphql.Schema
+	limits Limits
+	uu     usecase.IUserUsecase
+	pu     usecase.IPostUsecase
+}
+
+// NewServer builds the schema. It only fails if the schema is inconsistent.
+func NewServer(uu usecase.IUserUsecase, pu usecase.IPostUsecase, limits Limits) (*Server, error) {
+	s := &Server{limits: limits, uu: uu, pu: pu}
+	schema, err := s.newSchema()
+	if err != nil {
+		return nil, err
+	}
+	s.schema = schema
+	return s, nil
+}
+
+// Execute runs req on behalf of the holder of claims. Messages of domain
+// errors are translated with trans.
+func (s *Server) Execute(ctx context.Context, claims *dto.JwtCustomClaims, trans ut.Translator, req Request) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	if err := s.limits.check(s.schema, doc, req.OperationName, req.Variables, trans); err != nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{{
+			Message:    err.Message,
+			Locations:  []location.SourceLocation{},
+			Extensions: err.Extensions(),
+		}}}
+	}
+
+	rc := &requestContext{
+		claims:  claims,
+		trans:   trans,
+		users:   newUserLoader(s.uu),
+		replies: newReplyLoader(s.pu),
+	}
+	return graphql.Do(graphql.Params{
+		Schema:         s.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        context.WithValue(ctx, requestContextKey{}, rc),
+	})
+}
+
+// requestContext is what resolvers need to know about the request they are
+// serving
+type requestContext struct {
+	claims  *dto.JwtCustomClaims
+	trans   ut.Translator
+	users   *userLoader
+	replies *replyLoader
+}
+
+type requestContextKey struct{}
+
+func fromContext(ctx context.Context) *requestContext {
+	return ctx.Value(requestContextKey{}).(*requestContext)
+}
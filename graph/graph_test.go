@@ -0,0 +1,477 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/i18n"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	mock_usecase "github.com/PenginAction/go-BulletinBoard/usecase/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/graphql-go/graphql"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, limits Limits) (*Server, *mock_usecase.MockIUserUsecase, *mock_usecase.MockIPostUsecase) {
+	ctrl := gomock.NewController(t)
+	uu := mock_usecase.NewMockIUserUsecase(ctrl)
+	pu := mock_usecase.NewMockIPostUsecase(ctrl)
+	s, err := NewServer(uu, pu, limits)
+	require.NoError(t, err)
+	return s, uu, pu
+}
+
+func execute(s *Server, claims *dto.JwtCustomClaims, query string, variables map[string]interface{}) map[string]interface{} {
+	res := s.Execute(context.Background(), claims, i18n.Translator("en"), Request{Query: query, Variables: variables})
+	// round trip through JSON to compare what clients see
+	b, err := json.Marshal(res)
+	if err != nil {
+		panic(err)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		panic(err)
+	}
+	return out
+}
+
+func errorCodes(res map[string]interface{}) []string {
+	var codes []string
+	errs, _ := res["errors"].([]interface{})
+	for _, e := range errs {
+		ext, _ := e.(map[string]interface{})["extensions"].(map[string]interface{})
+		code, _ := ext["code"].(string)
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+func TestPostsBatchesAuthors(t *testing.T) {
+	s, uu, pu := newTestServer(t, Limits{})
+	now := time.Now().UTC().Truncate(time.Second)
+
+	posts := []dto.PostResponse{
+		{ID: 1, UserID: 2, Text: "a", CreatedAt: now},
+		{ID: 2, UserID: 1, Text: "b", CreatedAt: now},
+		{ID: 3, UserID: 3, Text: "c", CreatedAt: now},
+		// only tells that there is a next page
+		{ID: 4, UserID: 4, Text: "d", CreatedAt: now},
+	}
+	pu.EXPECT().
+		ListPosts(gomock.Any(), gomock.Eq(dto.ListPostsRequest{Limit: 4, Offset: 0})).
+		Times(1).
+		Return(posts, nil)
+	// user 3 has been deleted
+	uu.EXPECT().
+		GetUsersByIDs(gomock.Any(), gomock.Eq([]uint{1, 2, 3})).
+		Times(1).
+		Return([]dto.UserResponse{
+			{ID: 1, UserStrID: "alice", Role: dto.RoleUser, CreatedAt: now},
+			{ID: 2, UserStrID: "bob", Role: dto.RoleModerator, CreatedAt: now},
+		}, nil)
+
+	res := execute(s, &dto.JwtCustomClaims{ID: 1}, `{
+		posts(first: 3) {
+			edges { cursor node { id text author { userStrId } } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`, nil)
+	require.Nil(t, res["errors"])
+
+	conn := res["data"].(map[string]interface{})["posts"].(map[string]interface{})
+	edges := conn["edges"].([]interface{})
+	require.Len(t, edges, 3)
+	var authors []interface{}
+	for _, e := range edges {
+		node := e.(map[string]interface{})["node"].(map[string]interface{})
+		author, _ := node["author"].(map[string]interface{})
+		authors = append(authors, author["userStrId"])
+	}
+	require.Equal(t, []interface{}{"bob", "alice", nil}, authors)
+
+	pageInfo := conn["pageInfo"].(map[string]interface{})
+	require.Equal(t, true, pageInfo["hasNextPage"])
+	require.Equal(t, edges[2].(map[string]interface{})["cursor"], pageInfo["endCursor"])
+}
+
+func TestPostEntities(t *testing.T) {
+	s, uu, pu := newTestServer(t, Limits{})
+	now := time.Now().UTC().Truncate(time.Second)
+
+	pu.EXPECT().
+		GetPostById(gomock.Any(), uint(7)).
+		Times(1).
+		Return(dto.PostResponse{ID: 7, UserID: 1, Text: "@bob @gone #go", Mentions: []dto.MentionEntity{
+			{UserID: 2, Start: 0, End: 4},
+			{UserID: 3, Start: 5, End: 10},
+		}, Tags: []string{"go"}}, nil)
+	// the author and the users mentioned are looked up together
+	uu.EXPECT().
+		GetUsersByIDs(gomock.Any(), gomock.Eq([]uint{1, 2, 3})).
+		Times(1).
+		Return([]dto.UserResponse{
+			{ID: 1, UserStrID: "alice", Role: dto.RoleUser, CreatedAt: now},
+			{ID: 2, UserStrID: "bob", Role: dto.RoleUser, CreatedAt: now},
+		}, nil)
+
+	res := execute(s, &dto.JwtCustomClaims{ID: 1}, `{
+		post(id: "7") { author { userStrId } mentions { start end user { userStrId } } tags }
+	}`, nil)
+	require.Nil(t, res["errors"])
+	require.Equal(t, []interface{}{"go"}, res["data"].(map[string]interface{})["post"].(map[string]interface{})["tags"])
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"start": float64(0), "end": float64(4), "user": map[string]interface{}{"userStrId": "bob"}},
+		map[string]interface{}{"start": float64(5), "end": float64(10), "user": nil},
+	}, res["data"].(map[string]interface{})["post"].(map[string]interface{})["mentions"])
+}
+
+func TestPostsAfterCursor(t *testing.T) {
+	s, _, pu := newTestServer(t, Limits{})
+
+	pu.EXPECT().
+		ListPosts(gomock.Any(), gomock.Eq(dto.ListPostsRequest{Limit: 3, Offset: 5})).
+		Times(1).
+		Return([]dto.PostResponse{{ID: 6}}, nil)
+
+	res := execute(s, &dto.JwtCustomClaims{ID: 1}, `query($after: String) {
+		posts(first: 2, after: $after) { edges { node { id } } pageInfo { hasNextPage } }
+	}`, map[string]interface{}{"after": encodeCursor(5)})
+	require.Nil(t, res["errors"])
+
+	conn := res["data"].(map[string]interface{})["posts"].(map[string]interface{})
+	require.Len(t, conn["edges"], 1)
+	require.Equal(t, false, conn["pageInfo"].(map[string]interface{})["hasNextPage"])
+}
+
+func TestPostReplies(t *testing.T) {
+	s, _, pu := newTestServer(t, Limits{})
+
+	pu.EXPECT().
+		GetPostById(gomock.Any(), uint(7)).
+		Times(1).
+		Return(dto.PostResponse{ID: 7, UserID: 1}, nil)
+	pu.EXPECT().
+		ListReplies(gomock.Any(), gomock.Eq(dto.ListRepliesRequest{ParentIDs: []uint{7}, Limit: 3, Offset: 0})).
+		Times(1).
+		Return(map[uint][]dto.PostResponse{7: {{ID: 8, ParentID: 7}, {ID: 9, ParentID: 7}}}, nil)
+	// the replies to the replies are a page of their own, fetched at once
+	pu.EXPECT().
+		ListReplies(gomock.Any(), gomock.Eq(dto.ListRepliesRequest{ParentIDs: []uint{8, 9}, Limit: 2, Offset: 0})).
+		Times(1).
+		Return(map[uint][]dto.PostResponse{8: {{ID: 10, ParentID: 8}}}, nil)
+
+	res := execute(s, &dto.JwtCustomClaims{ID: 1}, `{
+		post(id: "7") {
+			replies(first: 2) {
+				edges { node { id parentId replies(first: 1) { edges { node { id } } } } }
+				pageInfo { hasNextPage }
+			}
+		}
+	}`, nil)
+	require.Nil(t, res["errors"])
+
+	replies := res["data"].(map[string]interface{})["post"].(map[string]interface{})["replies"].(map[string]interface{})
+	require.Equal(t, false, replies["pageInfo"].(map[string]interface{})["hasNextPage"])
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"node": map[string]interface{}{
+			"id":       "8",
+			"parentId": "7",
+			"replies": map[string]interface{}{"edges": []interface{}{
+				map[string]interface{}{"node": map[string]interface{}{"id": "10"}},
+			}},
+		}},
+		map[string]interface{}{"node": map[string]interface{}{
+			"id":       "9",
+			"parentId": "7",
+			"replies":  map[string]interface{}{"edges": []interface{}{}},
+		}},
+	}, replies["edges"])
+}
+
+func TestPostsBatchesReplies(t *testing.T) {
+	s, _, pu := newTestServer(t, Limits{})
+
+	pu.EXPECT().
+		ListPosts(gomock.Any(), gomock.Eq(dto.ListPostsRequest{Limit: 4, Offset: 0})).
+		Times(1).
+		Return([]dto.PostResponse{{ID: 1}, {ID: 2}, {ID: 3}}, nil)
+	pu.EXPECT().
+		ListReplies(gomock.Any(), gomock.Eq(dto.ListRepliesRequest{ParentIDs: []uint{1, 2, 3}, Limit: 2, Offset: 0})).
+		Times(1).
+		Return(map[uint][]dto.PostResponse{
+			1: {{ID: 4, ParentID: 1}, {ID: 5, ParentID: 1}},
+			3: {{ID: 6, ParentID: 3}},
+		}, nil)
+
+	res := execute(s, &dto.JwtCustomClaims{ID: 1}, `{
+		posts(first: 3) {
+			edges { node { id replies(first: 1) { edges { node { id } } pageInfo { hasNextPage } } } }
+		}
+	}`, nil)
+	require.Nil(t, res["errors"])
+
+	edges := res["data"].(map[string]interface{})["posts"].(map[string]interface{})["edges"].([]interface{})
+	var got []interface{}
+	for _, edge := range edges {
+		got = append(got, edge.(map[string]interface{})["node"].(map[string]interface{})["replies"])
+	}
+	require.Equal(t, []interface{}{
+		map[string]interface{}{
+			"edges":    []interface{}{map[string]interface{}{"node": map[string]interface{}{"id": "4"}}},
+			"pageInfo": map[string]interface{}{"hasNextPage": true},
+		},
+		map[string]interface{}{
+			"edges":    []interface{}{},
+			"pageInfo": map[string]interface{}{"hasNextPage": false},
+		},
+		map[string]interface{}{
+			"edges":    []interface{}{map[string]interface{}{"node": map[string]interface{}{"id": "6"}}},
+			"pageInfo": map[string]interface{}{"hasNextPage": false},
+		},
+	}, got)
+}
+
+func TestRepliesFailure(t *testing.T) {
+	s, _, pu := newTestServer(t, Limits{})
+
+	pu.EXPECT().
+		ListPosts(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]dto.PostResponse{{ID: 1}, {ID: 2}}, nil)
+	// one failed lookup for both posts
+	pu.EXPECT().
+		ListReplies(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil, errors.New("connection refused"))
+
+	res := execute(s, &dto.JwtCustomClaims{ID: 1}, `{ posts { edges { node { id replies { edges { node { id } } } } } } }`, nil)
+	errs := res["errors"].([]interface{})
+	require.Len(t, errs, 1)
+	// causes are never shown to clients
+	require.Equal(t, "internal server error", errs[0].(map[string]interface{})["message"])
+	require.Nil(t, res["data"])
+}
+
+func TestPostsInvalidArguments(t *testing.T) {
+	s, _, _ := newTestServer(t, Limits{})
+
+	for _, query := range []string{
+		`{ posts(first: 101) { edges { cursor } } }`,
+		`{ posts(first: -1) { edges { cursor } } }`,
+		`{ posts(after: "bm9wZQ==") { edges { cursor } } }`,
+	} {
+		res := execute(s, &dto.JwtCustomClaims{ID: 1}, query, nil)
+		require.Equal(t, []string{"validation_failed"}, errorCodes(res), query)
+	}
+}
+
+func TestPost(t *testing.T) {
+	post := dto.PostResponse{ID: 7, UserID: 1, Text: "hello"}
+
+	cases := []struct {
+		name       string
+		claims     *dto.JwtCustomClaims
+		id         string
+		buildStubs func(pu *mock_usecase.MockIPostUsecase)
+		code       string
+	}{
+		{
+			name:   "owner",
+			claims: &dto.JwtCustomClaims{ID: 1},
+			id:     "7",
+			buildStubs: func(pu *mock_usecase.MockIPostUsecase) {
+				pu.EXPECT().GetPostById(gomock.Any(), uint(7)).Times(1).Return(post, nil)
+			},
+		},
+		{
+			name:   "moderator",
+			claims: &dto.JwtCustomClaims{ID: 2, Role: dto.RoleModerator},
+			id:     "7",
+			buildStubs: func(pu *mock_usecase.MockIPostUsecase) {
+				pu.EXPECT().GetPostById(gomock.Any(), uint(7)).Times(1).Return(post, nil)
+			},
+		},
+		{
+			name:   "other user",
+			claims: &dto.JwtCustomClaims{ID: 2},
+			id:     "7",
+			buildStubs: func(pu *mock_usecase.MockIPostUsecase) {
+				pu.EXPECT().GetPostById(gomock.Any(), uint(7)).Times(1).Return(post, nil)
+			},
+			code: "not_post_owner",
+		},
+		{
+			name:   "not found",
+			claims: &dto.JwtCustomClaims{ID: 1},
+			id:     "8",
+			buildStubs: func(pu *mock_usecase.MockIPostUsecase) {
+				pu.EXPECT().GetPostById(gomock.Any(), uint(8)).Times(1).Return(dto.PostResponse{}, usecase.ErrPostNotFound)
+			},
+			code: "post_not_found",
+		},
+		{
+			name:   "invalid id",
+			claims: &dto.JwtCustomClaims{ID: 1},
+			id:     "x",
+			buildStubs: func(pu *mock_usecase.MockIPostUsecase) {
+				pu.EXPECT().GetPostById(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: "validation_failed",
+		},
+		{
+			name:   "internal error",
+			claims: &dto.JwtCustomClaims{ID: 1},
+			id:     "7",
+			buildStubs: func(pu *mock_usecase.MockIPostUsecase) {
+				pu.EXPECT().GetPostById(gomock.Any(), uint(7)).Times(1).Return(dto.PostResponse{}, errors.New("connection refused"))
+			},
+			code: "internal_error",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _, pu := newTestServer(t, Limits{})
+			tc.buildStubs(pu)
+
+			res := execute(s, tc.claims, `query($id: ID!) { post(id: $id) { id text } }`, map[string]interface{}{"id": tc.id})
+			if tc.code == "" {
+				require.Nil(t, res["errors"])
+				require.Equal(t, map[string]interface{}{"id": "7", "text": "hello"}, res["data"].(map[string]interface{})["post"])
+				return
+			}
+			require.Equal(t, []string{tc.code}, errorCodes(res))
+			require.Nil(t, res["data"].(map[string]interface{})["post"])
+			// causes are never shown to clients
+			require.NotContains(t, res["errors"].([]interface{})[0].(map[string]interface{})["message"], "connection refused")
+		})
+	}
+}
+
+func TestViewer(t *testing.T) {
+	s, uu, _ := newTestServer(t, Limits{})
+
+	uu.EXPECT().
+		GetUsersByIDs(gomock.Any(), gomock.Eq([]uint{5})).
+		Times(1).
+		Return([]dto.UserResponse{{ID: 5, UserStrID: "carol", Role: dto.RoleUser}}, nil)
+
+	res := execute(s, &dto.JwtCustomClaims{ID: 5}, `{ viewer { id userStrId role } }`, nil)
+	require.Nil(t, res["errors"])
+	require.Equal(t, map[string]interface{}{"id": "5", "userStrId": "carol", "role": "user"}, res["data"].(map[string]interface{})["viewer"])
+}
+
+func TestLimits(t *testing.T) {
+	cases := []struct {
+		name      string
+		limits    Limits
+		query     string
+		variables map[string]interface{}
+		code      string
+	}{
+		{
+			name:   "within limits",
+			limits: Limits{MaxDepth: 5, MaxComplexity: 51},
+			// posts 1 + 10 * (edges 1 + node 1 + id 1 + author 1 + author.id 1)
+			query: `{ posts(first: 10) { edges { node { id author { id } } } } }`,
+		},
+		{
+			name:   "too deep",
+			limits: Limits{MaxDepth: 3},
+			query:  `{ posts(first: 10) { edges { node { id } } } }`,
+			code:   "query_too_deep",
+		},
+		{
+			name:   "too deep through fragments",
+			limits: Limits{MaxDepth: 3},
+			query: `{ posts { ...conn } }
+				fragment conn on PostConnection { edges { ...edge } }
+				fragment edge on PostEdge { node { id } }`,
+			code: "query_too_deep",
+		},
+		{
+			name:   "too complex",
+			limits: Limits{MaxComplexity: 50},
+			query:  `{ posts(first: 10) { edges { node { id author { id } } } } }`,
+			code:   "query_too_complex",
+		},
+		{
+			name:      "page size from variables",
+			limits:    Limits{MaxComplexity: 50},
+			query:     `query($n: Int) { posts(first: $n) { edges { node { id author { id } } } } }`,
+			variables: map[string]interface{}{"n": float64(100)},
+			code:      "query_too_complex",
+		},
+		{
+			name:   "default page size",
+			limits: Limits{MaxComplexity: 50},
+			query:  `{ posts { edges { node { id author { id } } } } }`,
+			code:   "query_too_complex",
+		},
+		{
+			name:   "nested replies multiply",
+			limits: Limits{MaxComplexity: 100},
+			// posts 1 + 10 * (edges 1 + node 1 + replies 1 + 10 * (edges 1 + node 1 + id 1))
+			query: `{ posts(first: 10) { edges { node { replies(first: 10) { edges { node { id } } } } } } }`,
+			code:  "query_too_complex",
+		},
+		{
+			name:   "aliases add up",
+			limits: Limits{MaxComplexity: 5},
+			query:  `{ a: viewer { id } b: viewer { id } c: viewer { id } }`,
+			code:   "query_too_complex",
+		},
+		{
+			name:   "introspection is not counted",
+			limits: Limits{MaxDepth: 1, MaxComplexity: 1},
+			query:  `{ __schema { types { name fields { name type { ofType { name } } } } } }`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, uu, pu := newTestServer(t, tc.limits)
+			if tc.code != "" {
+				pu.EXPECT().ListPosts(gomock.Any(), gomock.Any()).Times(0)
+				uu.EXPECT().GetUsersByIDs(gomock.Any(), gomock.Any()).Times(0)
+			} else {
+				pu.EXPECT().ListPosts(gomock.Any(), gomock.Any()).AnyTimes().Return(nil, nil)
+				pu.EXPECT().ListReplies(gomock.Any(), gomock.Any()).AnyTimes().Return(nil, nil)
+			}
+
+			res := execute(s, &dto.JwtCustomClaims{ID: 1}, tc.query, tc.variables)
+			if tc.code == "" {
+				require.Nil(t, res["errors"])
+				return
+			}
+			require.Equal(t, []string{tc.code}, errorCodes(res))
+			require.Nil(t, res["data"])
+		})
+	}
+}
+
+func TestLimitsLocalized(t *testing.T) {
+	s, _, _ := newTestServer(t, Limits{MaxDepth: 1})
+
+	res := s.Execute(context.Background(), &dto.JwtCustomClaims{ID: 1}, i18n.Translator("ja"), Request{Query: `{ viewer { id } }`})
+	require.Len(t, res.Errors, 1)
+	require.Equal(t, "クエリのネストが1階層を超えています", res.Errors[0].Message)
+}
+
+func TestSchemaDocumentsMissingDomains(t *testing.T) {
+	s, _, _ := newTestServer(t, Limits{})
+
+	// boards, threads and reactions are not part of the domain yet; the
+	// schema must not pretend otherwise
+	for name := range s.schema.TypeMap() {
+		for _, missing := range []string{"Board", "Thread", "Reaction"} {
+			require.False(t, strings.HasPrefix(name, missing), name)
+		}
+	}
+	require.IsType(t, &graphql.Object{}, s.schema.Type("PostConnection"))
+}
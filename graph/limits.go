@@ -0,0 +1,195 @@
+package graph
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/PenginAction/go-BulletinBoard/i18n"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// check rejects the operation of doc named operationName if it is nested
+// too deeply or would resolve too many fields. It runs before validation, so
+// anything it does not understand is left for validation to report.
+//
+// Introspection fields are not counted: what they return is bounded by the
+// size of the schema.
+func (l Limits) check(schema graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}, trans ut.Translator) *Error {
+	var op *ast.OperationDefinition
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			name := ""
+			if def.Name != nil {
+				name = def.Name.Value
+			}
+			if op == nil && (operationName == "" || name == operationName) {
+				op = def
+			}
+		case *ast.FragmentDefinition:
+			fragments[def.Name.Value] = def
+		}
+	}
+	if op == nil || op.Operation != ast.OperationTypeQuery {
+		return nil
+	}
+
+	w := &walker{
+		schema:    schema,
+		fragments: fragments,
+		variables: variables,
+		done:      map[string]cost{},
+		visiting:  map[string]bool{},
+	}
+	c := w.selectionSet(schema.QueryType(), op.SelectionSet)
+
+	if l.MaxDepth > 0 && c.depth > l.MaxDepth {
+		limit := strconv.Itoa(l.MaxDepth)
+		return &Error{
+			Code:    "query_too_deep",
+			Message: i18n.Message(trans, "query_too_deep", "query is nested more than "+limit+" levels deep", limit),
+		}
+	}
+	if l.MaxComplexity > 0 && c.complexity > l.MaxComplexity {
+		got, limit := strconv.Itoa(c.complexity), strconv.Itoa(l.MaxComplexity)
+		return &Error{
+			Code:    "query_too_complex",
+			Message: i18n.Message(trans, "query_too_complex", "query complexity "+got+" exceeds the limit of "+limit, got, limit),
+		}
+	}
+	return nil
+}
+
+type cost struct {
+	depth      int
+	complexity int
+}
+
+// walker measures selection sets against the schema
+type walker struct {
+	schema    graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	// done memoizes fragments, so spreading a fragment many times does not
+	// make measuring the query itself expensive
+	done map[string]cost
+	// visiting guards against fragment cycles
+	visiting map[string]bool
+}
+
+// selectionSet measures set selected on a value of type t. Each field costs
+// one, plus the cost of its selections once per item it may return.
+func (w *walker) selectionSet(t *graphql.Object, set *ast.SelectionSet) cost {
+	var total cost
+	if t == nil || set == nil {
+		return total
+	}
+	for _, sel := range set.Selections {
+		var c cost
+		switch sel := sel.(type) {
+		case *ast.Field:
+			def, ok := t.Fields()[sel.Name.Value]
+			if !ok {
+				continue
+			}
+			c = w.selectionSet(namedObject(def.Type), sel.SelectionSet)
+			c.depth++
+			c.complexity = 1 + c.complexity*w.items(def, sel)
+		case *ast.InlineFragment:
+			c = w.selectionSet(w.condition(sel.TypeCondition, t), sel.SelectionSet)
+		case *ast.FragmentSpread:
+			c = w.fragment(sel.Name.Value, t)
+		}
+		total.depth = max(total.depth, c.depth)
+		// saturate rather than overflow on absurd queries
+		total.complexity = min(total.complexity+c.complexity, math.MaxInt32)
+	}
+	return total
+}
+
+func (w *walker) fragment(name string, t *graphql.Object) cost {
+	if c, ok := w.done[name]; ok {
+		return c
+	}
+	frag, ok := w.fragments[name]
+	if !ok || w.visiting[name] {
+		return cost{}
+	}
+	w.visiting[name] = true
+	c := w.selectionSet(w.condition(frag.TypeCondition, t), frag.SelectionSet)
+	delete(w.visiting, name)
+	w.done[name] = c
+	return c
+}
+
+// condition returns the type a fragment applies to, defaulting to t
+func (w *walker) condition(named *ast.Named, t *graphql.Object) *graphql.Object {
+	if named == nil {
+		return t
+	}
+	if obj, ok := w.schema.Type(named.Name.Value).(*graphql.Object); ok {
+		return obj
+	}
+	return t
+}
+
+// items is how many values field may return: the requested page size for
+// paginated fields, one for everything else
+func (w *walker) items(def *graphql.FieldDefinition, field *ast.Field) int {
+	for _, arg := range def.Args {
+		if arg.Name() != "first" {
+			continue
+		}
+		n, ok := arg.DefaultValue.(int)
+		if !ok {
+			n = defaultFirst
+		}
+		for _, a := range field.Arguments {
+			if a.Name.Value == "first" {
+				if v, ok := w.intValue(a.Value); ok {
+					n = v
+				}
+			}
+		}
+		// larger pages are rejected by the resolver anyway
+		return max(min(n, maxFirst), 1)
+	}
+	return 1
+}
+
+func (w *walker) intValue(v ast.Value) (int, bool) {
+	switch v := v.(type) {
+	case *ast.IntValue:
+		n, err := strconv.Atoi(v.Value)
+		return n, err == nil
+	case *ast.Variable:
+		switch n := w.variables[v.Name.Value].(type) {
+		case float64:
+			// variables decoded from JSON
+			return int(n), true
+		case int:
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// namedObject unwraps lists and non-null types down to an object type, or
+// returns nil for scalars
+func namedObject(t graphql.Type) *graphql.Object {
+	for {
+		switch u := t.(type) {
+		case *graphql.NonNull:
+			t = u.OfType
+		case *graphql.List:
+			t = u.OfType
+		case *graphql.Object:
+			return u
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package graph
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+)
+
+// userLoader batches user lookups within one request.
+//
+// load only records the id and returns a thunk. graphql-go resolves the
+// thunks of a level after every field on that level has been resolved, so
+// the first thunk to run fetches all ids recorded so far in one call and the
+// rest are answered from the cache.
+type userLoader struct {
+	uu usecase.IUserUsecase
+
+	mu      sync.Mutex
+	pending map[uint]bool
+	cache   map[uint]userResult
+}
+
+type userResult struct {
+	// user is nil if no user has the id
+	user *dto.UserResponse
+	err  error
+}
+
+func newUserLoader(uu usecase.IUserUsecase) *userLoader {
+	return &userLoader{
+		uu:      uu,
+		pending: map[uint]bool{},
+		cache:   map[uint]userResult{},
+	}
+}
+
+// load returns a thunk resolving to the user with the given id, or to nil if
+// there is none
+func (l *userLoader) load(ctx context.Context, id uint) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, ok := l.cache[id]; !ok {
+		l.pending[id] = true
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if _, ok := l.cache[id]; !ok {
+			l.pending[id] = true
+			l.flush(ctx)
+		}
+		res := l.cache[id]
+		if res.err != nil {
+			return nil, resolveError(ctx, res.err)
+		}
+		if res.user == nil {
+			return nil, nil
+		}
+		return *res.user, nil
+	}
+}
+
+// flush fetches every pending id. Callers must hold l.mu.
+func (l *userLoader) flush(ctx context.Context) {
+	ids := make([]uint, 0, len(l.pending))
+	for id := range l.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	l.pending = map[uint]bool{}
+
+	users, err := l.uu.GetUsersByIDs(ctx, ids)
+	for _, id := range ids {
+		l.cache[id] = userResult{err: err}
+	}
+	if err != nil {
+		return
+	}
+	for i := range users {
+		l.cache[users[i].ID] = userResult{user: &users[i]}
+	}
+}
+
+// replyLoader batches the lookups of replies within one request, the way
+// userLoader batches users: the first thunk to run fetches the replies to
+// every post recorded so far in one call. Posts whose replies are asked for
+// with different arguments are fetched in one call per window.
+type replyLoader struct {
+	pu usecase.IPostUsecase
+
+	mu      sync.Mutex
+	pending map[replyWindow]map[uint]bool
+	cache   map[replyKey]replyResult
+}
+
+// replyWindow is the page of replies asked for
+type replyWindow struct {
+	limit, offset int32
+}
+
+type replyKey struct {
+	window   replyWindow
+	parentID uint
+}
+
+type replyResult struct {
+	replies []dto.PostResponse
+	err     error
+}
+
+func newReplyLoader(pu usecase.IPostUsecase) *replyLoader {
+	return &replyLoader{
+		pu:      pu,
+		pending: map[replyWindow]map[uint]bool{},
+		cache:   map[replyKey]replyResult{},
+	}
+}
+
+// load returns a thunk resolving to up to limit replies to the post with id
+// parentID, after the first offset ones
+func (l *replyLoader) load(ctx context.Context, parentID uint, limit, offset int32) func() ([]dto.PostResponse, error) {
+	key := replyKey{replyWindow{limit, offset}, parentID}
+	l.mu.Lock()
+	l.record(key)
+	l.mu.Unlock()
+
+	return func() ([]dto.PostResponse, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if _, ok := l.cache[key]; !ok {
+			l.record(key)
+			l.flush(ctx, key.window)
+		}
+		res := l.cache[key]
+		return res.replies, res.err
+	}
+}
+
+// record marks key to be fetched unless it has been. Callers must hold l.mu.
+func (l *replyLoader) record(key replyKey) {
+	if _, ok := l.cache[key]; ok {
+		return
+	}
+	if l.pending[key.window] == nil {
+		l.pending[key.window] = map[uint]bool{}
+	}
+	l.pending[key.window][key.parentID] = true
+}
+
+// flush fetches every pending post of window. Callers must hold l.mu.
+func (l *replyLoader) flush(ctx context.Context, window replyWindow) {
+	ids := make([]uint, 0, len(l.pending[window]))
+	for id := range l.pending[window] {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	delete(l.pending, window)
+
+	replies, err := l.pu.ListReplies(ctx, dto.ListRepliesRequest{
+		ParentIDs: ids,
+		Limit:     window.limit,
+		Offset:    window.offset,
+	})
+	for _, id := range ids {
+		l.cache[replyKey{window, id}] = replyResult{replies: replies[id], err: err}
+	}
+}
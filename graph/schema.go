@@ -0,0 +1,346 @@
+package graph
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	// defaultFirst is the page size of a connection when first is omitted
+	defaultFirst = 20
+	// maxFirst is the largest page a connection returns
+	maxFirst = 100
+)
+
+func (s *Server) newSchema() (graphql.Schema, error) {
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return encodeID(p.Source.(dto.UserResponse).ID), nil
+				},
+			},
+			"userStrId": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(dto.UserResponse).UserStrID, nil
+				},
+			},
+			"role": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(dto.UserResponse).Role, nil
+				},
+			},
+			"createdAt": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.DateTime),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(dto.UserResponse).CreatedAt, nil
+				},
+			},
+		},
+	})
+
+	// mentionType is an @user_str_id in the text of a post, with offsets in
+	// code points
+	mentionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mention",
+		Fields: graphql.Fields{
+			"start": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(dto.MentionEntity).Start, nil
+				},
+			},
+			"end": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(dto.MentionEntity).End, nil
+				},
+			},
+			// user is null if the user has been deleted
+			"user": &graphql.Field{
+				Type: userType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					mention := p.Source.(dto.MentionEntity)
+					return fromContext(p.Context).users.load(p.Context, mention.UserID), nil
+				},
+			},
+		},
+	})
+
+	postType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Post",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return encodeID(p.Source.(dto.PostResponse).ID), nil
+				},
+			},
+			"text": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(dto.PostResponse).Text, nil
+				},
+			},
+			"createdAt": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.DateTime),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(dto.PostResponse).CreatedAt, nil
+				},
+			},
+			// parentId is null unless the post replies to one that still
+			// exists
+			"parentId": &graphql.Field{
+				Type: graphql.ID,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					post := p.Source.(dto.PostResponse)
+					if post.ParentID == 0 {
+						return nil, nil
+					}
+					return encodeID(post.ParentID), nil
+				},
+			},
+			"mentions": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(mentionType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					mentions := p.Source.(dto.PostResponse).Mentions
+					if mentions == nil {
+						mentions = []dto.MentionEntity{}
+					}
+					return mentions, nil
+				},
+			},
+			"tags": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					tags := p.Source.(dto.PostResponse).Tags
+					if tags == nil {
+						tags = []string{}
+					}
+					return tags, nil
+				},
+			},
+			// author is null if the user has been deleted
+			"author": &graphql.Field{
+				Type: userType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					post := p.Source.(dto.PostResponse)
+					return fromContext(p.Context).users.load(p.Context, post.UserID), nil
+				},
+			},
+		},
+	})
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"endCursor":   &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	postEdgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PostEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"node":   &graphql.Field{Type: graphql.NewNonNull(postType)},
+		},
+	})
+
+	postConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PostConnection",
+		Fields: graphql.Fields{
+			"edges":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(postEdgeType)))},
+			"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+		},
+	})
+
+	// added once the connection exists, which refers back to postType
+	postType.AddFieldConfig("replies", &graphql.Field{
+		Type:        graphql.NewNonNull(postConnectionType),
+		Description: "The direct replies to the post, oldest first",
+		Args: graphql.FieldConfigArgument{
+			"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultFirst},
+			"after": &graphql.ArgumentConfig{Type: graphql.String},
+		},
+		Resolve: s.resolveReplies,
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"viewer": &graphql.Field{
+				Type:        userType,
+				Description: "The authenticated user",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					rc := fromContext(p.Context)
+					return rc.users.load(p.Context, rc.claims.ID), nil
+				},
+			},
+			"post": &graphql.Field{
+				Type:        postType,
+				Description: "A post of the authenticated user. Moderators may read any post.",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: s.resolvePost,
+			},
+			"posts": &graphql.Field{
+				Type:        graphql.NewNonNull(postConnectionType),
+				Description: "All posts, oldest first",
+				Args: graphql.FieldConfigArgument{
+					"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultFirst},
+					"after": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: s.resolvePosts,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+func (s *Server) resolvePost(p graphql.ResolveParams) (interface{}, error) {
+	rc := fromContext(p.Context)
+
+	id, err := decodeID(p.Args["id"].(string))
+	if err != nil {
+		return nil, resolveError(p.Context, usecase.NewValidationError("id", "positive_integer", "id must be a positive integer"))
+	}
+	post, err := s.pu.GetPostById(p.Context, id)
+	if err != nil {
+		return nil, resolveError(p.Context, err)
+	}
+	// the same rule GET /posts/:postId applies
+	if post.UserID != rc.claims.ID && !rc.claims.IsModerator() {
+		return nil, resolveError(p.Context, usecase.ErrNotPostOwner)
+	}
+	return post, nil
+}
+
+// resolvePosts returns a page of posts
+func (s *Server) resolvePosts(p graphql.ResolveParams) (interface{}, error) {
+	first, offset, err := pageArgs(p)
+	if err != nil {
+		return nil, err
+	}
+	// one extra row tells whether there is a next page
+	posts, err := s.pu.ListPosts(p.Context, dto.ListPostsRequest{
+		Limit:  int32(first + 1),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return nil, resolveError(p.Context, err)
+	}
+	return connection(posts, first, offset), nil
+}
+
+// resolveReplies returns a page of the replies to a post, fetched along
+// with those to the other posts of the page
+func (s *Server) resolveReplies(p graphql.ResolveParams) (interface{}, error) {
+	first, offset, err := pageArgs(p)
+	if err != nil {
+		return nil, err
+	}
+	// one extra row tells whether there is a next page
+	load := fromContext(p.Context).replies.load(p.Context, p.Source.(dto.PostResponse).ID, int32(first+1), int32(offset))
+	return func() (interface{}, error) {
+		replies, err := load()
+		if err != nil {
+			return nil, resolveError(p.Context, err)
+		}
+		return connection(replies, first, offset), nil
+	}, nil
+}
+
+// pageArgs returns the number of posts asked for and how many to skip
+func pageArgs(p graphql.ResolveParams) (first, offset int, err error) {
+	first = p.Args["first"].(int)
+	if first < 0 {
+		return 0, 0, resolveError(p.Context, usecase.NewValidationError("first", "minimum", "first must not be negative", "0"))
+	}
+	if first > maxFirst {
+		return 0, 0, resolveError(p.Context, usecase.NewValidationError("first", "maximum", fmt.Sprintf("first must be at most %d", maxFirst), strconv.Itoa(maxFirst)))
+	}
+	if after, ok := p.Args["after"].(string); ok {
+		if offset, err = decodeCursor(after); err != nil {
+			return 0, 0, resolveError(p.Context, usecase.NewValidationError("after", "format", "after is not a valid cursor"))
+		}
+	}
+	return first, offset, nil
+}
+
+// connection returns the connection of posts, a page of first posts after
+// the first offset ones, and one more if there is a next page
+func connection(posts []dto.PostResponse, first, offset int) map[string]interface{} {
+	hasNextPage := len(posts) > first
+	if hasNextPage {
+		posts = posts[:first]
+	}
+
+	edges := make([]map[string]interface{}, 0, len(posts))
+	for i, post := range posts {
+		edges = append(edges, map[string]interface{}{
+			"cursor": encodeCursor(offset + i + 1),
+			"node":   post,
+		})
+	}
+	pageInfo := map[string]interface{}{"hasNextPage": hasNextPage}
+	if len(edges) > 0 {
+		pageInfo["endCursor"] = edges[len(edges)-1]["cursor"]
+	}
+	return map[string]interface{}{
+		"edges":    edges,
+		"pageInfo": pageInfo,
+	}
+}
+
+func encodeID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func decodeID(s string) (uint, error) {
+	id, err := strconv.ParseUint(s, 10, 0)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid id %q", s)
+	}
+	return uint(id), nil
+}
+
+// cursorPrefix names what a cursor holds, so the format can change without
+// old cursors being misread. Clients must treat cursors as opaque.
+const cursorPrefix = "offset:"
+
+// encodeCursor returns the cursor that makes the next page start after the
+// first offset posts
+func encodeCursor(offset int) string {
+	return base64.URLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	b, err := base64.URLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	s, ok := strings.CutPrefix(string(b), cursorPrefix)
+	if !ok {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	offset, err := strconv.Atoi(s)
+	// the offset of the next page must fit the int32 the store takes
+	if err != nil || offset < 0 || offset > math.MaxInt32-maxFirst-1 {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return offset, nil
+}
@@ -0,0 +1,24 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/PenginAction/go-BulletinBoard/db/migration"
+)
+
+// DBPing checks that a connection to the database can be used
+func DBPing(conn *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		return conn.PingContext(ctx)
+	}
+}
+
+// SchemaVersion checks that the database schema matches the migrations
+// embedded in this binary
+func SchemaVersion(conn *sql.DB, driver string) CheckFunc {
+	return func(ctx context.Context) error {
+		_, err := migration.Verify(ctx, conn, driver)
+		return err
+	}
+}
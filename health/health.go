@@ -0,0 +1,137 @@
+// Package health runs the readiness checks behind /readyz.
+//
+// Each check gets its own timeout and all checks run concurrently, so one
+// slow dependency cannot hold the probe past the caller's deadline.
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Check statuses used in Report
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// DefaultTimeout is used for checks registered without a timeout
+const DefaultTimeout = 2 * time.Second
+
+// CheckFunc reports a dependency as healthy by returning nil
+type CheckFunc func(ctx context.Context) error
+
+// Result is the outcome of one check
+type Result struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Report is the outcome of all checks. Status is ok only if every check is.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+}
+
+// ErrShuttingDown is reported by every check once Shutdown has been called
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// Checker holds the registered checks. It is safe for concurrent use.
+type Checker struct {
+	mu           sync.RWMutex
+	checks       []check
+	shuttingDown bool
+}
+
+// NewChecker returns a Checker without checks, which always reports ok
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+// Register adds a check. A timeout <= 0 means DefaultTimeout.
+func (c *Checker) Register(name string, timeout time.Duration, fn CheckFunc) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check{name: name, timeout: timeout, fn: fn})
+	sort.Slice(c.checks, func(i, j int) bool { return c.checks[i].name < c.checks[j].name })
+}
+
+// Shutdown makes every following Run report failure, so the instance is
+// taken out of rotation before it stops accepting connections
+func (c *Checker) Shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shuttingDown = true
+}
+
+// Run executes all checks concurrently and waits for them to finish or time out
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := c.checks
+	shuttingDown := c.shuttingDown
+	c.mu.RUnlock()
+
+	if shuttingDown {
+		return Report{
+			Status: StatusFail,
+			Checks: map[string]Result{"shutdown": {Status: StatusFail, Error: ErrShuttingDown.Error()}},
+		}
+	}
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Add(1)
+		go func(i int, chk check) {
+			defer wg.Done()
+			results[i] = run(ctx, chk)
+		}(i, chk)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	for i, chk := range checks {
+		report.Checks[chk.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func run(ctx context.Context, chk check) Result {
+	ctx, cancel := context.WithTimeout(ctx, chk.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() { errc <- chk.fn(ctx) }()
+
+	// a check that ignores ctx still cannot block the report
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	res := Result{Status: StatusOK, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
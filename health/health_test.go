@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/db/migration"
+	"github.com/PenginAction/go-BulletinBoard/db/sqlite"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckerEmpty(t *testing.T) {
+	report := NewChecker().Run(context.Background())
+	require.Equal(t, StatusOK, report.Status)
+	require.Empty(t, report.Checks)
+}
+
+func TestCheckerFailures(t *testing.T) {
+	c := NewChecker()
+	c.Register("ok", 0, func(ctx context.Context) error { return nil })
+	c.Register("broken", 0, func(ctx context.Context) error { return errors.New("boom") })
+	c.Register("slow", 20*time.Millisecond, func(ctx context.Context) error {
+		// ignores ctx on purpose
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	report := c.Run(context.Background())
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+
+	require.Equal(t, StatusFail, report.Status)
+	require.Equal(t, StatusOK, report.Checks["ok"].Status)
+	require.Equal(t, StatusFail, report.Checks["broken"].Status)
+	require.Equal(t, "boom", report.Checks["broken"].Error)
+	require.Equal(t, StatusFail, report.Checks["slow"].Status)
+	require.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+}
+
+func TestDBChecks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	conn, err := sqlite.Open(path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	c := NewChecker()
+	c.Register("database", 0, DBPing(conn))
+	c.Register("schema", 0, SchemaVersion(conn, sqlite.DriverName))
+
+	report := c.Run(context.Background())
+	require.Equal(t, StatusFail, report.Status)
+	require.Equal(t, StatusOK, report.Checks["database"].Status)
+	require.Equal(t, StatusFail, report.Checks["schema"].Status)
+
+	mg, err := migration.New(sqlite.DriverName, path)
+	require.NoError(t, err)
+	defer mg.Close()
+	require.NoError(t, mg.Up())
+
+	report = c.Run(context.Background())
+	require.Equal(t, StatusOK, report.Status)
+}
+
+func TestCheckerShutdown(t *testing.T) {
+	c := NewChecker()
+	c.Register("ok", 0, func(ctx context.Context) error { return nil })
+	require.Equal(t, StatusOK, c.Run(context.Background()).Status)
+
+	c.Shutdown()
+	report := c.Run(context.Background())
+	require.Equal(t, StatusFail, report.Status)
+	require.Equal(t, ErrShuttingDown.Error(), report.Checks["shutdown"].Error)
+}
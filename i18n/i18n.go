@@ -0,0 +1,111 @@
+// Package i18n translates validation and domain error messages into the
+// language the client asks for with Accept-Language.
+//
+// English and Japanese are supported. Messages are looked up by the stable
+// error codes of the usecase package; validator tags use the validator's own
+// translations.
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ja"
+	ut "github.com/go-playground/universal-translator"
+	"golang.org/x/text/language"
+	"gopkg.in/go-playground/validator.v9"
+	en_translations "gopkg.in/go-playground/validator.v9/translations/en"
+	ja_translations "gopkg.in/go-playground/validator.v9/translations/ja"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+)
+
+// DefaultLocale is used when the client accepts none of the supported languages
+const DefaultLocale = "en"
+
+var uni *ut.UniversalTranslator
+
+// paramCounts holds how many params the messages of the catalog take, by key
+var paramCounts = map[string]int{}
+
+var paramPattern = regexp.MustCompile(`\{(\d+)\}`)
+
+func init() {
+	uni = ut.New(en.New(), en.New(), ja.New())
+	for locale, messages := range catalog {
+		trans, _ := uni.GetTranslator(locale)
+		for key, text := range messages {
+			if err := trans.Add(key, text, false); err != nil {
+				panic(fmt.Sprintf("i18n: %s %q: %v", locale, key, err))
+			}
+			for _, m := range paramPattern.FindAllStringSubmatch(text, -1) {
+				n, _ := strconv.Atoi(m[1])
+				paramCounts[key] = max(paramCounts[key], n+1)
+			}
+		}
+	}
+}
+
+// RegisterValidator registers the validator's built-in tag messages in
+// every supported language, so validator.FieldError.Translate works with any
+// translator returned by this package
+func RegisterValidator(v *validator.Validate) error {
+	register := map[string]func(*validator.Validate, ut.Translator) error{
+		"en": en_translations.RegisterDefaultTranslations,
+		"ja": ja_translations.RegisterDefaultTranslations,
+	}
+	for locale, fn := range register {
+		trans, _ := uni.GetTranslator(locale)
+		if err := fn(v, trans); err != nil {
+			return fmt.Errorf("registering %s validator translations: %w", locale, err)
+		}
+	}
+	return nil
+}
+
+// Translator returns the translator for locale, or the default one if
+// locale is not supported
+func Translator(locale string) ut.Translator {
+	trans, _ := uni.GetTranslator(locale)
+	return trans
+}
+
+// FromAcceptLanguage returns the translator for the most preferred supported
+// language in an Accept-Language header. Regions are ignored, so ja-JP is
+// answered in Japanese.
+func FromAcceptLanguage(header string) ut.Translator {
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil {
+		return Translator(DefaultLocale)
+	}
+	for _, tag := range tags {
+		base, _ := tag.Base()
+		if trans, found := uni.GetTranslator(base.String()); found {
+			return trans
+		}
+	}
+	return Translator(DefaultLocale)
+}
+
+// Message returns the translation of key with params filled in, or fallback
+// if trans has no message for key or the message takes more params than
+// given
+func Message(trans ut.Translator, key, fallback string, params ...string) string {
+	if len(params) < paramCounts[key] {
+		return fallback
+	}
+	msg, err := trans.T(key, params...)
+	if err != nil {
+		return fallback
+	}
+	return msg
+}
+
+// FieldMessage returns the translation of a field error, with the field
+// name as {0} and the params of the error after it, or the message of the
+// error if there is none
+func FieldMessage(trans ut.Translator, f dto.FieldError) string {
+	return Message(trans, "field."+f.Code, f.Message, append([]string{f.Field}, f.Params...)...)
+}
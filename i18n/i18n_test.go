@@ -0,0 +1,64 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromAcceptLanguage(t *testing.T) {
+	cases := []struct {
+		header string
+		locale string
+	}{
+		{header: "", locale: "en"},
+		{header: "ja", locale: "ja"},
+		{header: "ja-JP", locale: "ja"},
+		{header: "fr-FR,ja;q=0.8,en;q=0.5", locale: "ja"},
+		{header: "en;q=0.9,ja;q=0.8", locale: "en"},
+		{header: "ja;q=0.1,en", locale: "en"},
+		{header: "de", locale: "en"},
+		{header: "*", locale: "en"},
+		{header: ";;;", locale: "en"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.header, func(t *testing.T) {
+			require.Equal(t, tc.locale, FromAcceptLanguage(tc.header).Locale())
+		})
+	}
+}
+
+func TestMessage(t *testing.T) {
+	require.Equal(t, "post not found", Message(Translator("en"), "post_not_found", "fallback"))
+	require.Equal(t, "投稿が見つかりません", Message(Translator("ja"), "post_not_found", "fallback"))
+	require.Equal(t, "emailは既に使用されています", Message(Translator("ja"), "field.taken", "fallback", "email"))
+	require.Equal(t, "fallback", Message(Translator("ja"), "no_such_code", "fallback"))
+	// too few params to fill in the message
+	require.Equal(t, "fallback", Message(Translator("en"), "query_too_complex", "fallback", "120"))
+}
+
+func TestFieldMessage(t *testing.T) {
+	f := dto.FieldError{Field: "limit", Code: "maximum", Message: "limit must be at most 100", Params: []string{"100"}}
+	require.Equal(t, "limit must be at most 100", FieldMessage(Translator("en"), f))
+	require.Equal(t, "limitは100以下でなければなりません", FieldMessage(Translator("ja"), f))
+
+	// without the bound the message of the error is kept
+	f.Params = nil
+	require.Equal(t, "limit must be at most 100", FieldMessage(Translator("ja"), f))
+	require.Equal(t, "emailは既に使用されています", FieldMessage(Translator("ja"), dto.FieldError{Field: "email", Code: "taken", Message: "fallback"}))
+}
+
+// TestCatalogComplete makes sure no message is only translated into some of
+// the supported languages
+func TestCatalogComplete(t *testing.T) {
+	for key := range catalog[DefaultLocale] {
+		for locale, messages := range catalog {
+			require.Contains(t, messages, key, "locale %s", locale)
+		}
+	}
+	for locale, messages := range catalog {
+		require.Len(t, messages, len(catalog[DefaultLocale]), "locale %s", locale)
+	}
+}
@@ -0,0 +1,97 @@
+package i18n
+
+// catalog holds the domain error messages per locale, keyed by the error
+// code clients see. Field messages are keyed by "field." plus the field
+// error code and take the field name as {0} and the params of the field
+// error, such as the bound broken, from {1} on.
+var catalog = map[string]map[string]string{
+	"en": {
+		"validation_failed":      "request validation failed",
+		"post_not_found":         "post not found",
+		"user_not_found":         "user not found",
+		"unauthorized":           "authentication required",
+		"invalid_credentials":    "email or password is incorrect",
+		"invalid_role":           "invalid role",
+		"user_locked":            "user is locked",
+		"token_revoked":          "token has been revoked",
+		"user_has_posts":         "user still has posts",
+		"invalid_filter":         "exactly one of user and before must be given",
+		"not_post_owner":         "post belongs to another user",
+		"already_exists":         "already exists",
+		"query_too_deep":         "query is nested more than {0} levels deep",
+		"query_too_complex":      "query complexity {0} exceeds the limit of {1}",
+		"too_many_subscriptions": "too many subscriptions",
+		"not_subscribed":         "subscribe to the thread first",
+		"webhook_not_found":      "webhook not found",
+		"delivery_not_found":     "webhook delivery not found",
+		"webhook_disabled":       "webhook is disabled",
+		"notification_not_found": "notification not found",
+
+		"field.integer":          "{0} must be an integer",
+		"field.positive_integer": "{0} must be a positive integer",
+		"field.taken":            "{0} is already taken",
+		"field.http_url":         "{0} must be an http or https URL",
+		"field.unknown_event":    "{0} names an unknown event",
+		"field.admin_only":       "{0} names an event only admins may subscribe to",
+		"field.unknown_post":     "{0} names an unknown post",
+		"field.invalid_tag":      "{0} must be letters, digits and underscores, with a letter",
+		"field.duration":         "{0} must be a duration, such as 24h",
+
+		// rules of the OpenAPI schema, see openapi.RequestValidator
+		"field.required":  "{0} is required",
+		"field.type":      "{0} has the wrong type",
+		"field.minLength": "{0} must be at least {1} characters long",
+		"field.maxLength": "{0} must be at most {1} characters long",
+		"field.minimum":   "{0} must be at least {1}",
+		"field.maximum":   "{0} must be at most {1}",
+		"field.minItems":  "{0} must have at least {1} items",
+		"field.maxItems":  "{0} must have at most {1} items",
+		"field.pattern":   "{0} contains characters that are not allowed",
+		"field.format":    "{0} is not in the expected format",
+		"field.enum":      "{0} is not one of the allowed values",
+	},
+	"ja": {
+		"validation_failed":      "リクエストの検証に失敗しました",
+		"post_not_found":         "投稿が見つかりません",
+		"user_not_found":         "ユーザーが見つかりません",
+		"unauthorized":           "認証が必要です",
+		"invalid_credentials":    "メールアドレスまたはパスワードが正しくありません",
+		"invalid_role":           "無効なロールです",
+		"user_locked":            "ユーザーはロックされています",
+		"token_revoked":          "トークンは無効化されています",
+		"user_has_posts":         "ユーザーにはまだ投稿があります",
+		"invalid_filter":         "userとbeforeのどちらか一方だけを指定してください",
+		"not_post_owner":         "この投稿は別のユーザーのものです",
+		"already_exists":         "既に存在します",
+		"query_too_deep":         "クエリのネストが{0}階層を超えています",
+		"query_too_complex":      "クエリの複雑度{0}が上限の{1}を超えています",
+		"too_many_subscriptions": "購読数が多すぎます",
+		"not_subscribed":         "先にスレッドを購読してください",
+		"webhook_not_found":      "Webhookが見つかりません",
+		"delivery_not_found":     "Webhookの配信が見つかりません",
+		"webhook_disabled":       "Webhookは無効になっています",
+		"notification_not_found": "通知が見つかりません",
+
+		"field.integer":          "{0}は整数でなければなりません",
+		"field.positive_integer": "{0}は正の整数でなければなりません",
+		"field.taken":            "{0}は既に使用されています",
+		"field.http_url":         "{0}はhttpまたはhttpsのURLでなければなりません",
+		"field.unknown_event":    "{0}に不明なイベントが含まれています",
+		"field.admin_only":       "{0}に管理者だけが購読できるイベントが含まれています",
+		"field.unknown_post":     "{0}に存在しない投稿が指定されています",
+		"field.invalid_tag":      "{0}は文字、数字、アンダースコアで構成し、文字を1つ以上含めなければなりません",
+		"field.duration":         "{0}は24hのような期間でなければなりません",
+
+		"field.required":  "{0}は必須です",
+		"field.type":      "{0}の型が正しくありません",
+		"field.minLength": "{0}は{1}文字以上でなければなりません",
+		"field.maxLength": "{0}は{1}文字以下でなければなりません",
+		"field.minimum":   "{0}は{1}以上でなければなりません",
+		"field.maximum":   "{0}は{1}以下でなければなりません",
+		"field.minItems":  "{0}には{1}個以上の項目が必要です",
+		"field.maxItems":  "{0}の項目は{1}個以下でなければなりません",
+		"field.pattern":   "{0}に使用できない文字が含まれています",
+		"field.format":    "{0}の形式が正しくありません",
+		"field.enum":      "{0}は許可された値ではありません",
+	},
+}
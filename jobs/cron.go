@@ -0,0 +1,155 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression: minute, hour, day of month, month
+// and day of week. Fields are "*", numbers, ranges "a-b", steps "*/n" or
+// "a-b/n", and lists of those separated by commas. Days of week go from 0,
+// Sunday, to 6; 7 is Sunday too. As in cron, a day matches if either the
+// day of month or the day of week matches when both are restricted.
+//
+// @yearly, @monthly, @weekly, @daily and @hourly stand for the usual
+// expressions.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny are set for fields that are "*"
+	domAny, dowAny bool
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseSchedule parses a cron expression
+func ParseSchedule(spec string) (Schedule, error) {
+	expr := spec
+	if d, ok := descriptors[strings.TrimSpace(spec)]; ok {
+		expr = d
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("cron expression %q: want 5 fields, got %d", spec, len(fields))
+	}
+
+	var s Schedule
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return Schedule{}, fmt.Errorf("cron expression %q: minute: %w", spec, err)
+	}
+	if s.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return Schedule{}, fmt.Errorf("cron expression %q: hour: %w", spec, err)
+	}
+	if s.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return Schedule{}, fmt.Errorf("cron expression %q: day of month: %w", spec, err)
+	}
+	if s.month, err = parseField(fields[3], 1, 12); err != nil {
+		return Schedule{}, fmt.Errorf("cron expression %q: month: %w", spec, err)
+	}
+	if s.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return Schedule{}, fmt.Errorf("cron expression %q: day of week: %w", spec, err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = fields[2] == "*"
+	s.dowAny = fields[4] == "*"
+	return s, nil
+}
+
+// parseField returns the set of values a field matches, as bits
+func parseField(field string, lo, hi int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepText)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+		}
+
+		from, to := lo, hi
+		if rng != "*" {
+			first, last, isRange := strings.Cut(rng, "-")
+			var err error
+			if from, err = parseValue(first, lo, hi); err != nil {
+				return 0, err
+			}
+			to = from
+			if isRange {
+				if to, err = parseValue(last, lo, hi); err != nil {
+					return 0, err
+				}
+				if to < from {
+					return 0, fmt.Errorf("invalid range %q", rng)
+				}
+			} else if hasStep {
+				// "a/n" is "a-hi/n"
+				to = hi
+			}
+		}
+		for v := from; v <= to; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func parseValue(s string, lo, hi int) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < lo || v > hi {
+		return 0, fmt.Errorf("invalid value %q, want %d to %d", s, lo, hi)
+	}
+	return v, nil
+}
+
+// Next returns the first time after t the schedule matches, in t's
+// location, or the zero time if it matches none within five years, which
+// happens for days such as February 30
+func (s Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case !has(s.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !has(s.hour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !has(s.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, t.Day())
+	dow := has(s.dow, int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<v) != 0
+}
@@ -0,0 +1,61 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// a Wednesday
+	from := time.Date(2024, 5, 1, 12, 34, 56, 0, time.UTC)
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 5, 1, 12, 35, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 5, 1, 12, 45, 0, 0, time.UTC)},
+		{"30 * * * *", time.Date(2024, 5, 1, 13, 30, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, 5, 2, 3, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * 1,5", time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// either day matches when both are restricted
+		{"0 0 15 * 5", time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.spec, func(t *testing.T) {
+			s, err := ParseSchedule(tc.spec)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, s.Next(from))
+		})
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@often",
+	} {
+		_, err := ParseSchedule(spec)
+		require.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,123 @@
+// Package jobs runs the background jobs queued in the jobs table.
+//
+// Jobs are queued with Enqueue, usually in the transaction of the change
+// they follow from, so that they are queued if and only if the change is
+// made: the table is the outbox of the changes. Any number of workers, in
+// the server or in separate worker processes, run them. A worker leases the
+// jobs it claims, and a job whose worker died is claimed again once the
+// lease runs out, so a job may run more than once and handlers should be
+// idempotent.
+//
+// A job that keeps failing is retried with exponential backoff until it has
+// been attempted MaxAttempts times. Then it is dead: it stays in the table,
+// for ops to look into and requeue.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+)
+
+// Statuses of a job
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+// DefaultMaxAttempts is how often a job is attempted unless it says otherwise
+const DefaultMaxAttempts = 10
+
+// ErrDuplicate is returned by Enqueue for a job with the unique key of one
+// queued before
+var ErrDuplicate = errors.New("jobs: a job with this unique key was queued before")
+
+// Job describes a job to queue
+type Job struct {
+	Kind string
+	// Payload is passed to the handler of the kind as JSON
+	Payload interface{}
+	// RunAt is when the job is due. Zero is now.
+	RunAt time.Time
+	// MaxAttempts is how often the job is attempted before it is dead.
+	// Zero is DefaultMaxAttempts.
+	MaxAttempts int32
+	// UniqueKey, if set, keeps the job from being queued again as long as
+	// the one queued with the key is in the table
+	UniqueKey string
+}
+
+// Enqueue queues job in q, which is the transaction of the change the job
+// follows from when there is one
+func Enqueue(ctx context.Context, q db.Querier, job Job) (db.Job, error) {
+	payload, err := json.Marshal(job.Payload)
+	if err != nil {
+		return db.Job{}, err
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+
+	queued, err := q.CreateJob(ctx, db.CreateJobParams{
+		Kind:        job.Kind,
+		Payload:     payload,
+		UniqueKey:   sql.NullString{String: job.UniqueKey, Valid: job.UniqueKey != ""},
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt.UTC(),
+	})
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return db.Job{}, ErrDuplicate
+	}
+	return queued, err
+}
+
+// Handler runs a job of the kind it is registered for. A job whose handler
+// returns an error is retried, unless the error is Permanent.
+type Handler func(ctx context.Context, job db.Job) error
+
+// permanentError is an error retrying does not help with
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as one retrying does not help with, such as a payload
+// that cannot be decoded. The job fails at once.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// IsPermanent reports whether err was marked by Permanent
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// KindPurge is the kind of the jobs deleting succeeded jobs, which are only
+// kept for a while to look into. Dead ones are kept until requeued.
+const KindPurge = "jobs.purge"
+
+// Purge returns the handler of the KindPurge jobs, deleting the jobs that
+// succeeded longer than retention ago
+func Purge(store db.Querier, retention time.Duration) Handler {
+	return func(ctx context.Context, job db.Job) error {
+		n, err := store.DeleteSucceededJobs(ctx, time.Now().UTC().Add(-retention))
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "jobs purged", slog.Int64("count", n))
+		return nil
+	}
+}
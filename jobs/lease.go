@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// The helpers below are shared with the other workers leasing rows of a
+// table, such as the webhook deliverer
+
+// MaxErrorLength bounds the error recorded for a failed attempt
+const MaxErrorLength = 500
+
+// ErrorText returns the message of err, cut to MaxErrorLength bytes
+func ErrorText(err error) string {
+	msg := err.Error()
+	if len(msg) > MaxErrorLength {
+		msg = strings.ToValidUTF8(msg[:MaxErrorLength], "")
+	}
+	return msg
+}
+
+// Backoff returns the delay before the attempt following the given number
+// of attempts: base, doubled with every further attempt, up to max
+func Backoff(base, max time.Duration, attempts int32) time.Duration {
+	d := base
+	for i := int32(1); i < attempts && d < max; i++ {
+		d *= 2
+	}
+	return min(d, max)
+}
+
+// LeaseUntil returns when the lease on rows claimed at now runs out, for
+// attempts bounded by timeout. It leaves them a minute to be recorded.
+func LeaseUntil(now time.Time, timeout time.Duration) time.Time {
+	return now.Add(timeout + time.Minute)
+}
+
+// Poll calls claim until ctx is done: at once again as long as it returns
+// true, because more may be due, and otherwise at the next tick of interval
+// or when wake receives, which may be nil
+func Poll(ctx context.Context, interval time.Duration, wake <-chan struct{}, claim func() bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if claim() && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
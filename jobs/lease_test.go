@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range []time.Duration{time.Second, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		require.Equal(t, want, Backoff(time.Second, 5*time.Second, int32(attempts)), "attempts %d", attempts)
+	}
+}
+
+func TestErrorText(t *testing.T) {
+	require.Equal(t, "boom", ErrorText(errors.New("boom")))
+
+	// a multibyte rune across the bound is dropped rather than cut
+	msg := ErrorText(errors.New(strings.Repeat("a", MaxErrorLength-1) + "é"))
+	require.Len(t, msg, MaxErrorLength-1)
+	require.True(t, utf8.ValidString(msg))
+}
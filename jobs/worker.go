@@ -0,0 +1,306 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/metrics"
+)
+
+// Options tune a Worker. Zero values are replaced by the defaults.
+type Options struct {
+	// PollInterval is how often due jobs are looked for
+	PollInterval time.Duration
+	// Backoff is the delay before the first retry. It doubles with every
+	// further one, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.PollInterval == 0 {
+		o.PollInterval = time.Second
+	}
+	if o.Backoff == 0 {
+		o.Backoff = 10 * time.Second
+	}
+	if o.MaxBackoff == 0 {
+		o.MaxBackoff = time.Hour
+	}
+	return o
+}
+
+// HandlerOptions tune how the jobs of a kind are run. Zero values are
+// replaced by the defaults.
+type HandlerOptions struct {
+	// Concurrency is how many jobs of the kind a worker runs at once
+	Concurrency int
+	// Timeout bounds each attempt
+	Timeout time.Duration
+}
+
+func (o HandlerOptions) withDefaults() HandlerOptions {
+	if o.Concurrency == 0 {
+		o.Concurrency = 1
+	}
+	if o.Timeout == 0 {
+		o.Timeout = time.Minute
+	}
+	return o
+}
+
+type handler struct {
+	kind string
+	fn   Handler
+	opts HandlerOptions
+}
+
+type schedule struct {
+	name  string
+	sched Schedule
+	job   Job
+}
+
+// Worker runs the jobs of the kinds it has handlers for, each kind in a
+// pool of its own, and queues the scheduled ones
+type Worker struct {
+	store     db.Querier
+	opts      Options
+	handlers  []handler
+	schedules []schedule
+	now       func() time.Time
+}
+
+// NewWorker returns a worker running the jobs queued in store
+func NewWorker(store db.Querier, opts Options) *Worker {
+	return &Worker{store: store, opts: opts.withDefaults(), now: time.Now}
+}
+
+// Handle registers the handler of the jobs of a kind. It must be called
+// before Run.
+func (w *Worker) Handle(kind string, fn Handler, opts HandlerOptions) {
+	w.handlers = append(w.handlers, handler{kind: kind, fn: fn, opts: opts.withDefaults()})
+	// expose every series from the start instead of after the first run
+	for _, result := range []string{metrics.JobSucceeded, metrics.JobRetried, metrics.JobDead} {
+		metrics.JobRuns.WithLabelValues(kind, result)
+	}
+}
+
+// Schedule queues job at every time spec, a cron expression evaluated in
+// UTC, matches. name identifies the schedule: every worker queuing it for
+// the same time queues the same job, so it runs once however many workers
+// there are. It must be called before Run.
+func (w *Worker) Schedule(name, spec string, job Job) error {
+	sched, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	w.schedules = append(w.schedules, schedule{name: name, sched: sched, job: job})
+	return nil
+}
+
+// Run runs due jobs and queues scheduled ones until ctx is done, then waits
+// for the jobs in flight
+func (w *Worker) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, h := range w.handlers {
+		wg.Add(1)
+		go func(h handler) {
+			defer wg.Done()
+			w.runPool(ctx, h)
+		}(h)
+	}
+	if len(w.schedules) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.runSchedules(ctx)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// runPool runs the jobs of one kind, up to its concurrency at once
+func (w *Worker) runPool(ctx context.Context, h handler) {
+	slots := make(chan struct{}, h.opts.Concurrency)
+	// freed wakes the pool when a job ends, so the slot is filled without
+	// waiting for the next tick
+	freed := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	Poll(ctx, w.opts.PollInterval, freed, func() bool {
+		free := cap(slots) - len(slots)
+		if free == 0 {
+			return false
+		}
+		jobs, err := w.claim(ctx, h, free)
+		if err != nil && ctx.Err() == nil {
+			// retried at the next tick
+			slog.ErrorContext(ctx, "cannot claim jobs", slog.String("kind", h.kind), slog.String("error", err.Error()))
+		}
+		for _, job := range jobs {
+			slots <- struct{}{}
+			wg.Add(1)
+			go func(job db.Job) {
+				defer wg.Done()
+				// claimed, so finish the attempt even if asked to stop
+				w.attempt(context.WithoutCancel(ctx), h, job)
+				<-slots
+				select {
+				case freed <- struct{}{}:
+				default:
+				}
+			}(job)
+		}
+		return len(jobs) == free
+	})
+}
+
+func (w *Worker) claim(ctx context.Context, h handler, max int) ([]db.Job, error) {
+	now := w.now().UTC()
+	return w.store.ClaimJobs(ctx, db.ClaimJobsParams{
+		LeaseUntil: LeaseUntil(now, h.opts.Timeout),
+		Kind:       h.kind,
+		Now:        now,
+		MaxCount:   int32(max),
+	})
+}
+
+// RunDue claims the due jobs of a kind, up to its concurrency, runs them
+// and returns how many it ran
+func (w *Worker) RunDue(ctx context.Context, kind string) (int, error) {
+	for _, h := range w.handlers {
+		if h.kind != kind {
+			continue
+		}
+		jobs, err := w.claim(ctx, h, h.opts.Concurrency)
+		if err != nil {
+			return 0, err
+		}
+		var wg sync.WaitGroup
+		for _, job := range jobs {
+			wg.Add(1)
+			go func(job db.Job) {
+				defer wg.Done()
+				w.attempt(context.WithoutCancel(ctx), h, job)
+			}(job)
+		}
+		wg.Wait()
+		return len(jobs), nil
+	}
+	return 0, fmt.Errorf("jobs: no handler for kind %q", kind)
+}
+
+func (w *Worker) attempt(ctx context.Context, h handler, job db.Job) {
+	arg := db.ReleaseJobParams{
+		ID:       job.ID,
+		Attempts: job.Attempts,
+		Status:   StatusSucceeded,
+		RunAt:    job.RunAt,
+	}
+
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		// claimed again every time because its worker died running it
+		err = Permanent(errors.New("lease ran out on every attempt"))
+	} else {
+		err = w.call(ctx, h, job)
+	}
+
+	now := w.now().UTC()
+	switch {
+	case err == nil:
+		arg.FinishedAt = sql.NullTime{Time: now, Valid: true}
+		metrics.JobRuns.WithLabelValues(h.kind, metrics.JobSucceeded).Inc()
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		arg.Status = StatusDead
+		arg.LastError = ErrorText(err)
+		arg.FinishedAt = sql.NullTime{Time: now, Valid: true}
+		metrics.JobRuns.WithLabelValues(h.kind, metrics.JobDead).Inc()
+		slog.ErrorContext(ctx, "job dead", slog.String("kind", h.kind), slog.Int64("id", job.ID), slog.Int("attempts", int(job.Attempts)), slog.String("error", err.Error()))
+	default:
+		arg.Status = StatusPending
+		arg.LastError = ErrorText(err)
+		arg.RunAt = now.Add(Backoff(w.opts.Backoff, w.opts.MaxBackoff, job.Attempts))
+		metrics.JobRuns.WithLabelValues(h.kind, metrics.JobRetried).Inc()
+		slog.WarnContext(ctx, "job failed, retrying", slog.String("kind", h.kind), slog.Int64("id", job.ID), slog.Int("attempts", int(job.Attempts)), slog.String("error", err.Error()))
+	}
+
+	if err := w.store.ReleaseJob(ctx, arg); err != nil {
+		// attempted again when the lease runs out
+		slog.ErrorContext(ctx, "cannot release job", slog.String("kind", h.kind), slog.Int64("id", job.ID), slog.String("error", err.Error()))
+	}
+}
+
+// call runs the handler, turning a panic into an error so one bad job
+// cannot take the worker down
+func (w *Worker) call(ctx context.Context, h handler, job db.Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, h.opts.Timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return h.fn(ctx, job)
+}
+
+// runSchedules queues the scheduled jobs as they fall due
+func (w *Worker) runSchedules(ctx context.Context) {
+	now := w.now().UTC()
+	next := make([]time.Time, len(w.schedules))
+	for i, s := range w.schedules {
+		next[i] = s.sched.Next(now)
+	}
+
+	for {
+		var first time.Time
+		for _, t := range next {
+			if !t.IsZero() && (first.IsZero() || t.Before(first)) {
+				first = t
+			}
+		}
+		if first.IsZero() {
+			// none ever matches
+			<-ctx.Done()
+			return
+		}
+
+		timer := time.NewTimer(first.Sub(w.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		w.enqueueScheduled(ctx, w.now().UTC(), next)
+	}
+}
+
+// enqueueScheduled queues the scheduled jobs due at now, next holding the
+// time each schedule is next due at, and moves those on
+func (w *Worker) enqueueScheduled(ctx context.Context, now time.Time, next []time.Time) {
+	for i, s := range w.schedules {
+		if next[i].IsZero() || next[i].After(now) {
+			continue
+		}
+		job := s.job
+		job.RunAt = next[i]
+		job.UniqueKey = s.name + "@" + strconv.FormatInt(next[i].Unix(), 10)
+		_, err := Enqueue(ctx, w.store, job)
+		if err != nil && !errors.Is(err, ErrDuplicate) {
+			// skipped until the next time
+			slog.ErrorContext(ctx, "cannot queue scheduled job", slog.String("schedule", s.name), slog.String("error", err.Error()))
+		}
+		next[i] = s.sched.Next(now)
+	}
+}
@@ -0,0 +1,273 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+const testKind = "test.kind"
+
+// clock is a time the tests move by hand
+type clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *clock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *clock) add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newTestWorker(store db.Querier, fn Handler) (*Worker, *clock) {
+	c := &clock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	w := NewWorker(store, Options{Backoff: time.Minute, MaxBackoff: 3 * time.Minute})
+	w.now = c.now
+	w.Handle(testKind, fn, HandlerOptions{})
+	return w, c
+}
+
+func enqueue(t *testing.T, store db.Querier, c *clock, job Job) db.Job {
+	job.Kind = testKind
+	job.RunAt = c.now()
+	queued, err := Enqueue(context.Background(), store, job)
+	require.NoError(t, err)
+	return queued
+}
+
+func runDue(t *testing.T, w *Worker) int {
+	n, err := w.RunDue(context.Background(), testKind)
+	require.NoError(t, err)
+	return n
+}
+
+func getJob(t *testing.T, store db.Querier, id int64) db.Job {
+	job, err := store.GetJob(context.Background(), id)
+	require.NoError(t, err)
+	return job
+}
+
+func TestRunSucceeds(t *testing.T) {
+	store := memory.NewStore()
+	var got map[string]string
+	w, c := newTestWorker(store, func(ctx context.Context, job db.Job) error {
+		return json.Unmarshal(job.Payload, &got)
+	})
+	job := enqueue(t, store, c, Job{Payload: map[string]string{"a": "b"}})
+	require.Equal(t, StatusPending, job.Status)
+	require.EqualValues(t, DefaultMaxAttempts, job.MaxAttempts)
+
+	require.Equal(t, 1, runDue(t, w))
+	require.Equal(t, map[string]string{"a": "b"}, got)
+	job = getJob(t, store, job.ID)
+	require.Equal(t, StatusSucceeded, job.Status)
+	require.EqualValues(t, 1, job.Attempts)
+	require.True(t, job.FinishedAt.Valid)
+
+	require.Zero(t, runDue(t, w))
+}
+
+func TestRetriesWithBackoff(t *testing.T) {
+	store := memory.NewStore()
+	w, c := newTestWorker(store, func(ctx context.Context, job db.Job) error {
+		return errors.New("down")
+	})
+	job := enqueue(t, store, c, Job{})
+
+	for _, backoff := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		require.Equal(t, 1, runDue(t, w))
+		job = getJob(t, store, job.ID)
+		require.Equal(t, StatusPending, job.Status)
+		require.Equal(t, "down", job.LastError)
+		require.Equal(t, c.now().Add(backoff), job.RunAt.UTC())
+
+		// not due before
+		c.add(backoff - time.Second)
+		require.Zero(t, runDue(t, w))
+		c.add(time.Second)
+	}
+}
+
+func TestDeadAfterMaxAttempts(t *testing.T) {
+	store := memory.NewStore()
+	w, c := newTestWorker(store, func(ctx context.Context, job db.Job) error {
+		return errors.New("down")
+	})
+	job := enqueue(t, store, c, Job{MaxAttempts: 2})
+
+	require.Equal(t, 1, runDue(t, w))
+	c.add(time.Minute)
+	require.Equal(t, 1, runDue(t, w))
+	job = getJob(t, store, job.ID)
+	require.Equal(t, StatusDead, job.Status)
+	require.EqualValues(t, 2, job.Attempts)
+	require.True(t, job.FinishedAt.Valid)
+
+	c.add(time.Hour)
+	require.Zero(t, runDue(t, w))
+
+	// requeued by ops
+	_, err := store.RequeueJob(context.Background(), db.RequeueJobParams{ID: job.ID, RunAt: c.now()})
+	require.NoError(t, err)
+	require.Equal(t, 1, runDue(t, w))
+}
+
+func TestPermanentErrorFailsAtOnce(t *testing.T) {
+	store := memory.NewStore()
+	w, c := newTestWorker(store, func(ctx context.Context, job db.Job) error {
+		return Permanent(errors.New("bad payload"))
+	})
+	job := enqueue(t, store, c, Job{})
+
+	require.Equal(t, 1, runDue(t, w))
+	job = getJob(t, store, job.ID)
+	require.Equal(t, StatusDead, job.Status)
+	require.Equal(t, "bad payload", job.LastError)
+}
+
+func TestPanicIsRetried(t *testing.T) {
+	store := memory.NewStore()
+	w, c := newTestWorker(store, func(ctx context.Context, job db.Job) error {
+		panic("boom")
+	})
+	job := enqueue(t, store, c, Job{})
+
+	require.Equal(t, 1, runDue(t, w))
+	job = getJob(t, store, job.ID)
+	require.Equal(t, StatusPending, job.Status)
+	require.Equal(t, "handler panicked: boom", job.LastError)
+}
+
+func TestLeaseRanOut(t *testing.T) {
+	store := memory.NewStore()
+	var calls int
+	w, c := newTestWorker(store, func(ctx context.Context, job db.Job) error {
+		calls++
+		return nil
+	})
+	job := enqueue(t, store, c, Job{MaxAttempts: 1})
+
+	// claimed by a worker that died
+	_, err := store.ClaimJobs(context.Background(), db.ClaimJobsParams{
+		LeaseUntil: c.now().Add(time.Minute),
+		Kind:       testKind,
+		Now:        c.now(),
+		MaxCount:   1,
+	})
+	require.NoError(t, err)
+	require.Zero(t, runDue(t, w))
+
+	c.add(time.Minute)
+	require.Equal(t, 1, runDue(t, w))
+	require.Zero(t, calls)
+	job = getJob(t, store, job.ID)
+	require.Equal(t, StatusDead, job.Status)
+	require.Equal(t, "lease ran out on every attempt", job.LastError)
+}
+
+func TestRunLimitsConcurrency(t *testing.T) {
+	store := memory.NewStore()
+	var running, most, done atomic.Int32
+	w := NewWorker(store, Options{PollInterval: 10 * time.Millisecond})
+	w.Handle(testKind, func(ctx context.Context, job db.Job) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+		}
+		time.Sleep(20 * time.Millisecond)
+		done.Add(1)
+		return nil
+	}, HandlerOptions{Concurrency: 2})
+	for i := 0; i < 6; i++ {
+		_, err := Enqueue(context.Background(), store, Job{Kind: testKind})
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		require.NoError(t, w.Run(ctx))
+	}()
+	require.Eventually(t, func() bool { return done.Load() == 6 }, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-stopped
+	require.EqualValues(t, 2, most.Load())
+}
+
+func TestRunDueUnknownKind(t *testing.T) {
+	w := NewWorker(memory.NewStore(), Options{})
+	_, err := w.RunDue(context.Background(), testKind)
+	require.Error(t, err)
+}
+
+func TestScheduledJobsQueuedOnce(t *testing.T) {
+	store := memory.NewStore()
+	c := &clock{t: time.Date(2024, 5, 1, 12, 0, 30, 0, time.UTC)}
+	var workers []*Worker
+	for i := 0; i < 2; i++ {
+		w := NewWorker(store, Options{})
+		w.now = c.now
+		require.NoError(t, w.Schedule("tick", "*/5 * * * *", Job{Kind: testKind}))
+		workers = append(workers, w)
+	}
+	require.Error(t, workers[0].Schedule("bad", "* * *", Job{Kind: testKind}))
+
+	due := time.Date(2024, 5, 1, 12, 5, 0, 0, time.UTC)
+	for _, w := range workers {
+		next := []time.Time{w.schedules[0].sched.Next(c.now())}
+		require.Equal(t, due, next[0])
+
+		// not yet due
+		w.enqueueScheduled(context.Background(), due.Add(-time.Second), next)
+		require.Equal(t, due, next[0])
+
+		w.enqueueScheduled(context.Background(), due, next)
+		require.Equal(t, due.Add(5*time.Minute), next[0])
+	}
+
+	pending, err := store.ListJobsByStatus(context.Background(), db.ListJobsByStatusParams{
+		Status: StatusPending,
+		ID:     1 << 62,
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, due, pending[0].RunAt.UTC())
+	require.Equal(t, "tick@1714565100", pending[0].UniqueKey.String)
+}
+
+func TestPurge(t *testing.T) {
+	store := memory.NewStore()
+	w, c := newTestWorker(store, func(ctx context.Context, job db.Job) error {
+		return nil
+	})
+	// c is fixed in the past
+	old := enqueue(t, store, c, Job{})
+	require.Equal(t, 1, runDue(t, w))
+	dead := enqueue(t, store, c, Job{MaxAttempts: 1})
+	w.handlers[0].fn = func(ctx context.Context, job db.Job) error {
+		return errors.New("down")
+	}
+	require.Equal(t, 1, runDue(t, w))
+
+	require.NoError(t, Purge(store, time.Hour)(context.Background(), db.Job{}))
+	_, err := store.GetJob(context.Background(), old.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+	require.Equal(t, StatusDead, getJob(t, store, dead.ID).Status)
+}
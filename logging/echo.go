@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxRequestIDLen bounds request IDs accepted from clients
+const maxRequestIDLen = 128
+
+// RequestIDMiddleware takes the request ID from the X-Request-ID header, or
+// generates one, echoes it in the response and stores it in the request
+// context for logging
+func RequestIDMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			id := req.Header.Get(echo.HeaderXRequestID)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			c.Response().Header().Set(echo.HeaderXRequestID, id)
+			c.SetRequest(req.WithContext(WithRequestID(req.Context(), id)))
+			return next(c)
+		}
+	}
+}
+
+// validRequestID accepts printable ASCII only, so a client cannot inject
+// anything odd into our logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// AccessLog writes one record per request to logger. Requests for which
+// skip returns true are not logged.
+func AccessLog(logger *slog.Logger, skip func(c echo.Context) bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if skip != nil && skip(c) {
+				return next(c)
+			}
+
+			start := time.Now()
+			err := next(c)
+
+			log := func() {
+				req := c.Request()
+				res := c.Response()
+				level := slog.LevelInfo
+				if res.Status >= 500 {
+					level = slog.LevelError
+				}
+				logger.LogAttrs(req.Context(), level, "request",
+					slog.String("method", req.Method),
+					slog.String("uri", redactQuery(req.URL)),
+					slog.String("route", c.Path()),
+					slog.Int("status", res.Status),
+					slog.Int64("bytes", res.Size),
+					slog.Duration("latency", time.Since(start)),
+					slog.String("remote_ip", c.RealIP()),
+				)
+			}
+			if err != nil && !c.Response().Committed {
+				// the error response is written further out; log it once its
+				// status is final
+				c.Response().Before(log)
+			} else {
+				log()
+			}
+			return err
+		}
+	}
+}
+
+// redactQuery returns the request URI with sensitive query values replaced
+func redactQuery(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.RequestURI()
+	}
+	q := u.Query()
+	for key := range q {
+		if IsSensitive(key) {
+			q[key] = []string{Redacted}
+		}
+	}
+	redacted := *u
+	redacted.RawQuery = q.Encode()
+	return redacted.RequestURI()
+}
@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDMetadata is the gRPC counterpart of the X-Request-ID header
+const requestIDMetadata = "x-request-id"
+
+// UnaryAccessLog takes the request ID from the x-request-id metadata, or
+// generates one, sends it back in the header and writes one record per call
+// to logger, like RequestIDMiddleware and AccessLog do for HTTP
+func UnaryAccessLog(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = withGRPCRequestID(ctx)
+		start := time.Now()
+		res, err := handler(ctx, req)
+		logCall(ctx, logger, info.FullMethod, start, err)
+		return res, err
+	}
+}
+
+// StreamAccessLog is UnaryAccessLog for streams, logged when they end
+func StreamAccessLog(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := withGRPCRequestID(ss.Context())
+		start := time.Now()
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		logCall(ctx, logger, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func withGRPCRequestID(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var id string
+	if values := md.Get(requestIDMetadata); len(values) > 0 {
+		id = values[0]
+	}
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+	// only fails if the header was sent already, which it cannot have been
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, id))
+	return WithRequestID(ctx, id)
+}
+
+func logCall(ctx context.Context, logger *slog.Logger, method string, start time.Time, err error) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	if serverError(code) {
+		level = slog.LevelError
+	}
+	remote := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		remote = p.Addr.String()
+	}
+	logger.LogAttrs(ctx, level, "grpc request",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+		slog.String("remote_addr", remote),
+	)
+}
+
+// serverError tells the codes that are the server's fault, the 5xx of gRPC
+func serverError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.Internal, codes.Unimplemented, codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// serverStream overrides the context of a stream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
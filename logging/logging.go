@@ -0,0 +1,103 @@
+// Package logging configures log/slog for the server: JSON output, a
+// configurable level, request IDs taken from the context and redaction of
+// credentials.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Redacted replaces the value of sensitive attributes
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are never written, compared
+// case-insensitively
+var sensitiveKeys = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"set-cookie":    true,
+	"password":      true,
+	"token":         true,
+	"secret":        true,
+	"access_token":  true,
+}
+
+// IsSensitive reports whether values under key must not be logged
+func IsSensitive(key string) bool {
+	return sensitiveKeys[strings.ToLower(key)]
+}
+
+// New returns a logger writing to w in the given format ("json" or "text")
+// that drops records below level ("debug", "info", "warn" or "error")
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{
+		Level:       lvl,
+		ReplaceAttr: redact,
+	}
+
+	var h slog.Handler
+	switch format {
+	case "", "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+	return slog.New(contextHandler{h}), nil
+}
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if IsSensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+// contextHandler adds the request ID and trace ID found in the context to
+// every record, so callers only have to use the *Context logging functions
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
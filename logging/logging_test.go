@@ -0,0 +1,194 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func decode(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &rec))
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := New(&bytes.Buffer{}, "loud", "json")
+	require.Error(t, err)
+	_, err = New(&bytes.Buffer{}, "info", "xml")
+	require.Error(t, err)
+}
+
+func TestLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "warn", "json")
+	require.NoError(t, err)
+
+	logger.Info("dropped")
+	logger.Warn("kept")
+	records := decode(t, &buf)
+	require.Len(t, records, 1)
+	require.Equal(t, "kept", records[0]["msg"])
+}
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", "json")
+	require.NoError(t, err)
+
+	logger.Info("login", "email", "a@example.com", "password", "hunter2", "Authorization", "Bearer abc")
+	logger.WithGroup("req").Info("headers", "authorization", "Bearer abc")
+
+	out := buf.String()
+	require.NotContains(t, out, "hunter2")
+	require.NotContains(t, out, "Bearer abc")
+	require.Contains(t, out, "a@example.com")
+}
+
+func TestRequestIDInContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", "json")
+	require.NoError(t, err)
+
+	ctx := WithRequestID(context.Background(), "abc-123")
+	logger.With("component", "test").InfoContext(ctx, "hello")
+	logger.Info("no context")
+
+	records := decode(t, &buf)
+	require.Equal(t, "abc-123", records[0]["request_id"])
+	require.Equal(t, "test", records[0]["component"])
+	require.NotContains(t, records[1], "request_id")
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	cases := []struct {
+		name     string
+		header   string
+		expectID func(t *testing.T, id string)
+	}{
+		{
+			name:   "echoes client id",
+			header: "client-id-1",
+			expectID: func(t *testing.T, id string) {
+				require.Equal(t, "client-id-1", id)
+			},
+		},
+		{
+			name:   "generates id",
+			header: "",
+			expectID: func(t *testing.T, id string) {
+				require.Len(t, id, 32)
+			},
+		},
+		{
+			name:   "replaces invalid id",
+			header: "bad id\nwith newline",
+			expectID: func(t *testing.T, id string) {
+				require.Len(t, id, 32)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			e.Use(RequestIDMiddleware())
+			var seen string
+			e.GET("/", func(c echo.Context) error {
+				seen = RequestID(c.Request().Context())
+				return c.NoContent(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set(echo.HeaderXRequestID, tc.header)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(echo.HeaderXRequestID)
+			tc.expectID(t, id)
+			require.Equal(t, id, seen)
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", "json")
+	require.NoError(t, err)
+
+	e := echo.New()
+	e.Use(RequestIDMiddleware(), AccessLog(logger, func(c echo.Context) bool { return c.Path() == "/healthz" }))
+	e.GET("/posts/:postId", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.GET("/healthz", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.GET("/boom", func(c echo.Context) error { return echo.NewHTTPError(http.StatusInternalServerError) })
+
+	for _, path := range []string{"/posts/1?token=secret-value&page=2", "/healthz", "/boom"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(echo.HeaderXRequestID, "rid")
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	records := decode(t, &buf)
+	require.Len(t, records, 2)
+	require.Equal(t, "/posts/:postId", records[0]["route"])
+	require.Equal(t, "rid", records[0]["request_id"])
+	require.Equal(t, slog.LevelInfo.String(), records[0]["level"])
+	require.NotContains(t, records[0]["uri"], "secret-value")
+	require.Contains(t, records[0]["uri"], "page=2")
+	require.Equal(t, float64(http.StatusInternalServerError), records[1]["status"])
+	require.Equal(t, slog.LevelError.String(), records[1]["level"])
+}
+
+func TestGRPCAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", "json")
+	require.NoError(t, err)
+	interceptor := UnaryAccessLog(logger)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "rid"))
+	var seen string
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Get"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		seen = RequestID(ctx)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "rid", seen)
+
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Get"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		seen = RequestID(ctx)
+		return nil, status.Error(codes.Internal, "boom")
+	})
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.Len(t, seen, 32)
+
+	records := decode(t, &buf)
+	require.Len(t, records, 2)
+	require.Equal(t, "/svc/Get", records[0]["method"])
+	require.Equal(t, "OK", records[0]["code"])
+	require.Equal(t, "rid", records[0]["request_id"])
+	require.Equal(t, slog.LevelInfo.String(), records[0]["level"])
+	require.Equal(t, "Internal", records[1]["code"])
+	require.Equal(t, seen, records[1]["request_id"])
+	require.Equal(t, slog.LevelError.String(), records[1]["level"])
+}
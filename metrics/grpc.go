@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	grpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "Number of gRPC calls by method and status code.",
+	}, []string{"method", "code"})
+	grpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "Latency of gRPC calls by method and status code. Streams last until they end.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+)
+
+// UnaryServerInterceptor records count and latency of every unary call
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		res, err := handler(ctx, req)
+		observeGRPC(info.FullMethod, start, err)
+		return res, err
+	}
+}
+
+// StreamServerInterceptor records count and duration of every stream
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observeGRPC(info.FullMethod, start, err)
+		return err
+	}
+}
+
+func observeGRPC(method string, start time.Time, err error) {
+	labels := prometheus.Labels{
+		"method": method,
+		"code":   status.Code(err).String(),
+	}
+	grpcRequests.With(labels).Inc()
+	grpcDuration.With(labels).Observe(time.Since(start).Seconds())
+}
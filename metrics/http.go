@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by route, method and status.",
+	}, []string{"method", "route", "status"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// Middleware records count and latency of every request. Requests are
+// labelled with the route pattern, not the raw path, to bound cardinality.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			observe := func() {
+				route := c.Path()
+				if route == "" {
+					route = "unmatched"
+				}
+				labels := prometheus.Labels{
+					"method": c.Request().Method,
+					"route":  route,
+					"status": strconv.Itoa(c.Response().Status),
+				}
+				httpRequests.With(labels).Inc()
+				httpDuration.With(labels).Observe(time.Since(start).Seconds())
+			}
+			if err != nil && !c.Response().Committed {
+				// the error response is written further out; observe it once
+				// its status is final
+				c.Response().Before(observe)
+			} else {
+				observe()
+			}
+			return err
+		}
+	}
+}
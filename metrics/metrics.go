@@ -0,0 +1,110 @@
+// Package metrics exposes Prometheus metrics on /metrics.
+//
+// Everything is registered on Registry rather than the global default
+// registry, so tests can inspect values without interference from other
+// packages.
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "bulletin_board"
+
+// Registry holds every metric of the server
+var Registry = prometheus.NewRegistry()
+
+// Business counters, incremented by the usecases
+var (
+	Signups = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "signups_total",
+		Help:      "Number of users that signed up.",
+	})
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Number of login attempts by result.",
+	}, []string{"result"})
+	PostsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_created_total",
+		Help:      "Number of posts created.",
+	})
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Number of webhook delivery attempts by result.",
+	}, []string{"result"})
+	JobRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_runs_total",
+		Help:      "Number of background job attempts by kind and result.",
+	}, []string{"kind", "result"})
+)
+
+// Values of the result label of Logins
+const (
+	LoginSucceeded = "succeeded"
+	LoginFailed    = "failed"
+)
+
+// Values of the result label of WebhookDeliveries. A failed attempt is
+// retried, unless it was the last one.
+const (
+	DeliverySucceeded = "succeeded"
+	DeliveryRetried   = "retried"
+	DeliveryFailed    = "failed"
+)
+
+// Values of the result label of JobRuns. A failed attempt is retried, unless
+// it was the last one or retrying is pointless, and the job is dead.
+const (
+	JobSucceeded = "succeeded"
+	JobRetried   = "retried"
+	JobDead      = "dead"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Signups,
+		Logins,
+		PostsCreated,
+		WebhookDeliveries,
+		JobRuns,
+		httpRequests,
+		httpDuration,
+		grpcRequests,
+		grpcDuration,
+		queryDuration,
+	)
+	// expose both series from the start instead of after the first login
+	Logins.WithLabelValues(LoginSucceeded)
+	Logins.WithLabelValues(LoginFailed)
+	for _, result := range []string{DeliverySucceeded, DeliveryRetried, DeliveryFailed} {
+		WebhookDeliveries.WithLabelValues(result)
+	}
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDB exports the connection pool statistics of conn
+func RegisterDB(conn *sql.DB, name string) error {
+	err := Registry.Register(collectors.NewDBStatsCollector(conn, name))
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		return nil
+	}
+	return err
+}
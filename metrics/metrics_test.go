@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/db/sqlite"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStoreObservesQueries(t *testing.T) {
+	store := NewStore(memory.NewStore())
+
+	before := testutil.CollectAndCount(queryDuration, "bulletin_board_db_query_duration_seconds")
+	_, err := store.CreateUser(context.Background(), db.CreateUserParams{UserStrID: "metrics", Email: "metrics@example.com", Password: "x"})
+	require.NoError(t, err)
+	_, err = store.GetUser(context.Background(), 12345)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	require.Equal(t, before+2, testutil.CollectAndCount(queryDuration))
+
+	expected := `bulletin_board_db_query_duration_seconds_count{outcome="not_found",query="GetUser"} 1`
+	body := scrape(t)
+	require.Contains(t, body, expected)
+	require.Contains(t, body, `bulletin_board_db_query_duration_seconds_count{outcome="ok",query="CreateUser"}`)
+}
+
+func TestMiddleware(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware())
+	e.GET("/posts/:postId", func(c echo.Context) error {
+		if c.Param("postId") == "0" {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, path := range []string{"/posts/1", "/posts/2", "/posts/0", "/nowhere"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	require.Equal(t, 2.0, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/posts/:postId", "200")))
+	require.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/posts/:postId", "404")))
+
+	body := scrape(t)
+	require.NotContains(t, body, `route="/posts/1"`)
+	require.NotContains(t, body, `route="/nowhere"`)
+}
+
+func TestGRPCInterceptors(t *testing.T) {
+	unary := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/svc/Get"}
+	for _, err := range []error{nil, nil, status.Error(codes.NotFound, "nope")} {
+		_, got := unary(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, err
+		})
+		require.Equal(t, err, got)
+	}
+	stream := StreamServerInterceptor()
+	require.NoError(t, stream(nil, nil, &grpc.StreamServerInfo{FullMethod: "/svc/Watch"}, func(srv interface{}, ss grpc.ServerStream) error {
+		return nil
+	}))
+
+	require.Equal(t, 2.0, testutil.ToFloat64(grpcRequests.WithLabelValues("/svc/Get", "OK")))
+	require.Equal(t, 1.0, testutil.ToFloat64(grpcRequests.WithLabelValues("/svc/Get", "NotFound")))
+	require.Equal(t, 1.0, testutil.ToFloat64(grpcRequests.WithLabelValues("/svc/Watch", "OK")))
+}
+
+func TestBusinessCounters(t *testing.T) {
+	body := scrape(t)
+	require.Contains(t, body, `bulletin_board_logins_total{result="failed"}`)
+	require.Contains(t, body, `bulletin_board_signups_total`)
+	require.Contains(t, body, `bulletin_board_posts_created_total`)
+}
+
+func TestRegisterDB(t *testing.T) {
+	conn, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, RegisterDB(conn, "test"))
+	require.NoError(t, RegisterDB(conn, "test"))
+	require.Contains(t, scrape(t), `go_sql_open_connections{db_name="test"}`)
+}
+
+func scrape(t *testing.T) string {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
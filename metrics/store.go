@@ -0,0 +1,350 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "db_query_duration_seconds",
+	Help:      "Latency of db.Querier methods by query and outcome.",
+	Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+}, []string{"query", "outcome"})
+
+// Store wraps a db.Store and records the latency of every query
+type Store struct {
+	next db.Store
+}
+
+var _ db.Store = (*Store)(nil)
+
+// NewStore returns next instrumented with query metrics
+func NewStore(next db.Store) db.Store {
+	return &Store{next: next}
+}
+
+// ExecTx records the transaction as a whole, and the queries fn runs in it
+func (s *Store) ExecTx(ctx context.Context, fn func(db.Store) error) (err error) {
+	defer func(start time.Time) { observe("ExecTx", start, err) }(time.Now())
+	return s.next.ExecTx(ctx, func(tx db.Store) error {
+		return fn(&Store{next: tx})
+	})
+}
+
+// observe records a query that started at start and failed with err.
+// Not found is an expected answer, so it gets its own outcome.
+func observe(query string, start time.Time, err error) {
+	outcome := "ok"
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		outcome = "not_found"
+	case err != nil:
+		outcome = "error"
+	}
+	queryDuration.WithLabelValues(query, outcome).Observe(time.Since(start).Seconds())
+}
+
+func (s *Store) ClaimJobs(ctx context.Context, arg db.ClaimJobsParams) (jobs []db.Job, err error) {
+	defer func(start time.Time) { observe("ClaimJobs", start, err) }(time.Now())
+	return s.next.ClaimJobs(ctx, arg)
+}
+
+func (s *Store) ClaimWebhookDeliveries(ctx context.Context, arg db.ClaimWebhookDeliveriesParams) (deliveries []db.WebhookDelivery, err error) {
+	defer func(start time.Time) { observe("ClaimWebhookDeliveries", start, err) }(time.Now())
+	return s.next.ClaimWebhookDeliveries(ctx, arg)
+}
+
+func (s *Store) CountUnreadNotifications(ctx context.Context, userID uint) (n int64, err error) {
+	defer func(start time.Time) { observe("CountUnreadNotifications", start, err) }(time.Now())
+	return s.next.CountUnreadNotifications(ctx, userID)
+}
+
+func (s *Store) CreateJob(ctx context.Context, arg db.CreateJobParams) (job db.Job, err error) {
+	defer func(start time.Time) { observe("CreateJob", start, err) }(time.Now())
+	return s.next.CreateJob(ctx, arg)
+}
+
+func (s *Store) CreateNotification(ctx context.Context, arg db.CreateNotificationParams) (notification db.Notification, err error) {
+	defer func(start time.Time) { observe("CreateNotification", start, err) }(time.Now())
+	return s.next.CreateNotification(ctx, arg)
+}
+
+func (s *Store) CreatePost(ctx context.Context, arg db.CreatePostParams) (post db.Post, err error) {
+	defer func(start time.Time) { observe("CreatePost", start, err) }(time.Now())
+	return s.next.CreatePost(ctx, arg)
+}
+
+func (s *Store) CreatePostEvent(ctx context.Context, arg db.CreatePostEventParams) (event db.PostEvent, err error) {
+	defer func(start time.Time) { observe("CreatePostEvent", start, err) }(time.Now())
+	return s.next.CreatePostEvent(ctx, arg)
+}
+
+func (s *Store) CreatePostMention(ctx context.Context, arg db.CreatePostMentionParams) (err error) {
+	defer func(start time.Time) { observe("CreatePostMention", start, err) }(time.Now())
+	return s.next.CreatePostMention(ctx, arg)
+}
+
+func (s *Store) CreatePostTag(ctx context.Context, arg db.CreatePostTagParams) (err error) {
+	defer func(start time.Time) { observe("CreatePostTag", start, err) }(time.Now())
+	return s.next.CreatePostTag(ctx, arg)
+}
+
+func (s *Store) CreateUser(ctx context.Context, arg db.CreateUserParams) (user db.User, err error) {
+	defer func(start time.Time) { observe("CreateUser", start, err) }(time.Now())
+	return s.next.CreateUser(ctx, arg)
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, arg db.CreateWebhookParams) (webhook db.Webhook, err error) {
+	defer func(start time.Time) { observe("CreateWebhook", start, err) }(time.Now())
+	return s.next.CreateWebhook(ctx, arg)
+}
+
+func (s *Store) CreateWebhookDelivery(ctx context.Context, arg db.CreateWebhookDeliveryParams) (delivery db.WebhookDelivery, err error) {
+	defer func(start time.Time) { observe("CreateWebhookDelivery", start, err) }(time.Now())
+	return s.next.CreateWebhookDelivery(ctx, arg)
+}
+
+func (s *Store) DeletePost(ctx context.Context, id uint) (err error) {
+	defer func(start time.Time) { observe("DeletePost", start, err) }(time.Now())
+	return s.next.DeletePost(ctx, id)
+}
+
+func (s *Store) DeletePostMentions(ctx context.Context, postID uint) (err error) {
+	defer func(start time.Time) { observe("DeletePostMentions", start, err) }(time.Now())
+	return s.next.DeletePostMentions(ctx, postID)
+}
+
+func (s *Store) DeletePostTags(ctx context.Context, postID uint) (err error) {
+	defer func(start time.Time) { observe("DeletePostTags", start, err) }(time.Now())
+	return s.next.DeletePostTags(ctx, postID)
+}
+
+func (s *Store) DeletePostsByUser(ctx context.Context, userID uint) (posts []db.Post, err error) {
+	defer func(start time.Time) { observe("DeletePostsByUser", start, err) }(time.Now())
+	return s.next.DeletePostsByUser(ctx, userID)
+}
+
+func (s *Store) DeletePostsCreatedBefore(ctx context.Context, createdAt time.Time) (posts []db.Post, err error) {
+	defer func(start time.Time) { observe("DeletePostsCreatedBefore", start, err) }(time.Now())
+	return s.next.DeletePostsCreatedBefore(ctx, createdAt)
+}
+
+func (s *Store) DeleteSucceededJobs(ctx context.Context, before time.Time) (n int64, err error) {
+	defer func(start time.Time) { observe("DeleteSucceededJobs", start, err) }(time.Now())
+	return s.next.DeleteSucceededJobs(ctx, before)
+}
+
+func (s *Store) DeleteUser(ctx context.Context, id uint) (err error) {
+	defer func(start time.Time) { observe("DeleteUser", start, err) }(time.Now())
+	return s.next.DeleteUser(ctx, id)
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, id int64) (err error) {
+	defer func(start time.Time) { observe("DeleteWebhook", start, err) }(time.Now())
+	return s.next.DeleteWebhook(ctx, id)
+}
+
+func (s *Store) GetJob(ctx context.Context, id int64) (job db.Job, err error) {
+	defer func(start time.Time) { observe("GetJob", start, err) }(time.Now())
+	return s.next.GetJob(ctx, id)
+}
+
+func (s *Store) GetLastPostEventID(ctx context.Context) (id int64, err error) {
+	defer func(start time.Time) { observe("GetLastPostEventID", start, err) }(time.Now())
+	return s.next.GetLastPostEventID(ctx)
+}
+
+func (s *Store) GetNotification(ctx context.Context, id int64) (notification db.Notification, err error) {
+	defer func(start time.Time) { observe("GetNotification", start, err) }(time.Now())
+	return s.next.GetNotification(ctx, id)
+}
+
+func (s *Store) GetPost(ctx context.Context, id uint) (post db.Post, err error) {
+	defer func(start time.Time) { observe("GetPost", start, err) }(time.Now())
+	return s.next.GetPost(ctx, id)
+}
+
+func (s *Store) GetUser(ctx context.Context, id uint) (user db.User, err error) {
+	defer func(start time.Time) { observe("GetUser", start, err) }(time.Now())
+	return s.next.GetUser(ctx, id)
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (user db.User, err error) {
+	defer func(start time.Time) { observe("GetUserByEmail", start, err) }(time.Now())
+	return s.next.GetUserByEmail(ctx, email)
+}
+
+func (s *Store) GetUserByUserStrId(ctx context.Context, userStrID string) (user db.User, err error) {
+	defer func(start time.Time) { observe("GetUserByUserStrId", start, err) }(time.Now())
+	return s.next.GetUserByUserStrId(ctx, userStrID)
+}
+
+func (s *Store) GetUserStrIdById(ctx context.Context, id uint) (userStrID string, err error) {
+	defer func(start time.Time) { observe("GetUserStrIdById", start, err) }(time.Now())
+	return s.next.GetUserStrIdById(ctx, id)
+}
+
+func (s *Store) GetWebhook(ctx context.Context, id int64) (webhook db.Webhook, err error) {
+	defer func(start time.Time) { observe("GetWebhook", start, err) }(time.Now())
+	return s.next.GetWebhook(ctx, id)
+}
+
+func (s *Store) GetWebhookDelivery(ctx context.Context, id int64) (delivery db.WebhookDelivery, err error) {
+	defer func(start time.Time) { observe("GetWebhookDelivery", start, err) }(time.Now())
+	return s.next.GetWebhookDelivery(ctx, id)
+}
+
+func (s *Store) ListEnabledWebhooks(ctx context.Context) (webhooks []db.Webhook, err error) {
+	defer func(start time.Time) { observe("ListEnabledWebhooks", start, err) }(time.Now())
+	return s.next.ListEnabledWebhooks(ctx)
+}
+
+func (s *Store) ListJobsByStatus(ctx context.Context, arg db.ListJobsByStatusParams) (jobs []db.Job, err error) {
+	defer func(start time.Time) { observe("ListJobsByStatus", start, err) }(time.Now())
+	return s.next.ListJobsByStatus(ctx, arg)
+}
+
+func (s *Store) ListNotifications(ctx context.Context, arg db.ListNotificationsParams) (notifications []db.Notification, err error) {
+	defer func(start time.Time) { observe("ListNotifications", start, err) }(time.Now())
+	return s.next.ListNotifications(ctx, arg)
+}
+
+func (s *Store) ListPostEventsAfter(ctx context.Context, arg db.ListPostEventsAfterParams) (events []db.PostEvent, err error) {
+	defer func(start time.Time) { observe("ListPostEventsAfter", start, err) }(time.Now())
+	return s.next.ListPostEventsAfter(ctx, arg)
+}
+
+func (s *Store) ListPostMentions(ctx context.Context, postIds []int64) (mentions []db.PostMention, err error) {
+	defer func(start time.Time) { observe("ListPostMentions", start, err) }(time.Now())
+	return s.next.ListPostMentions(ctx, postIds)
+}
+
+func (s *Store) ListPostTags(ctx context.Context, postIds []int64) (tags []db.ListPostTagsRow, err error) {
+	defer func(start time.Time) { observe("ListPostTags", start, err) }(time.Now())
+	return s.next.ListPostTags(ctx, postIds)
+}
+
+func (s *Store) ListPosts(ctx context.Context, arg db.ListPostsParams) (posts []db.Post, err error) {
+	defer func(start time.Time) { observe("ListPosts", start, err) }(time.Now())
+	return s.next.ListPosts(ctx, arg)
+}
+
+func (s *Store) ListPostsByTag(ctx context.Context, arg db.ListPostsByTagParams) (posts []db.Post, err error) {
+	defer func(start time.Time) { observe("ListPostsByTag", start, err) }(time.Now())
+	return s.next.ListPostsByTag(ctx, arg)
+}
+
+func (s *Store) ListRepliesByParentIDs(ctx context.Context, arg db.ListRepliesByParentIDsParams) (posts []db.Post, err error) {
+	defer func(start time.Time) { observe("ListRepliesByParentIDs", start, err) }(time.Now())
+	return s.next.ListRepliesByParentIDs(ctx, arg)
+}
+
+func (s *Store) ListReplies(ctx context.Context, arg db.ListRepliesParams) (posts []db.Post, err error) {
+	defer func(start time.Time) { observe("ListReplies", start, err) }(time.Now())
+	return s.next.ListReplies(ctx, arg)
+}
+
+func (s *Store) ListTrendingTags(ctx context.Context, arg db.ListTrendingTagsParams) (tags []db.ListTrendingTagsRow, err error) {
+	defer func(start time.Time) { observe("ListTrendingTags", start, err) }(time.Now())
+	return s.next.ListTrendingTags(ctx, arg)
+}
+
+func (s *Store) ListUsers(ctx context.Context, arg db.ListUsersParams) (users []db.User, err error) {
+	defer func(start time.Time) { observe("ListUsers", start, err) }(time.Now())
+	return s.next.ListUsers(ctx, arg)
+}
+
+func (s *Store) ListUsersByIDs(ctx context.Context, ids []int64) (users []db.User, err error) {
+	defer func(start time.Time) { observe("ListUsersByIDs", start, err) }(time.Now())
+	return s.next.ListUsersByIDs(ctx, ids)
+}
+
+func (s *Store) ListWebhookDeliveries(ctx context.Context, arg db.ListWebhookDeliveriesParams) (deliveries []db.WebhookDelivery, err error) {
+	defer func(start time.Time) { observe("ListWebhookDeliveries", start, err) }(time.Now())
+	return s.next.ListWebhookDeliveries(ctx, arg)
+}
+
+func (s *Store) ListWebhooksByUser(ctx context.Context, userID uint) (webhooks []db.Webhook, err error) {
+	defer func(start time.Time) { observe("ListWebhooksByUser", start, err) }(time.Now())
+	return s.next.ListWebhooksByUser(ctx, userID)
+}
+
+func (s *Store) MarkAllNotificationsRead(ctx context.Context, arg db.MarkAllNotificationsReadParams) (n int64, err error) {
+	defer func(start time.Time) { observe("MarkAllNotificationsRead", start, err) }(time.Now())
+	return s.next.MarkAllNotificationsRead(ctx, arg)
+}
+
+func (s *Store) MarkNotificationRead(ctx context.Context, arg db.MarkNotificationReadParams) (notification db.Notification, err error) {
+	defer func(start time.Time) { observe("MarkNotificationRead", start, err) }(time.Now())
+	return s.next.MarkNotificationRead(ctx, arg)
+}
+
+func (s *Store) RecordWebhookFailure(ctx context.Context, arg db.RecordWebhookFailureParams) (webhook db.Webhook, err error) {
+	defer func(start time.Time) { observe("RecordWebhookFailure", start, err) }(time.Now())
+	return s.next.RecordWebhookFailure(ctx, arg)
+}
+
+func (s *Store) RecordWebhookSuccess(ctx context.Context, id int64) (webhook db.Webhook, err error) {
+	defer func(start time.Time) { observe("RecordWebhookSuccess", start, err) }(time.Now())
+	return s.next.RecordWebhookSuccess(ctx, id)
+}
+
+func (s *Store) ReleaseJob(ctx context.Context, arg db.ReleaseJobParams) (err error) {
+	defer func(start time.Time) { observe("ReleaseJob", start, err) }(time.Now())
+	return s.next.ReleaseJob(ctx, arg)
+}
+
+func (s *Store) RequeueJob(ctx context.Context, arg db.RequeueJobParams) (job db.Job, err error) {
+	defer func(start time.Time) { observe("RequeueJob", start, err) }(time.Now())
+	return s.next.RequeueJob(ctx, arg)
+}
+
+func (s *Store) RevokeUserTokens(ctx context.Context, id uint) (user db.User, err error) {
+	defer func(start time.Time) { observe("RevokeUserTokens", start, err) }(time.Now())
+	return s.next.RevokeUserTokens(ctx, id)
+}
+
+func (s *Store) SearchTags(ctx context.Context, arg db.SearchTagsParams) (tags []db.SearchTagsRow, err error) {
+	defer func(start time.Time) { observe("SearchTags", start, err) }(time.Now())
+	return s.next.SearchTags(ctx, arg)
+}
+
+func (s *Store) UpdatePost(ctx context.Context, arg db.UpdatePostParams) (post db.Post, err error) {
+	defer func(start time.Time) { observe("UpdatePost", start, err) }(time.Now())
+	return s.next.UpdatePost(ctx, arg)
+}
+
+func (s *Store) UpdateUser(ctx context.Context, arg db.UpdateUserParams) (user db.User, err error) {
+	defer func(start time.Time) { observe("UpdateUser", start, err) }(time.Now())
+	return s.next.UpdateUser(ctx, arg)
+}
+
+func (s *Store) UpdateUserLockedAt(ctx context.Context, arg db.UpdateUserLockedAtParams) (user db.User, err error) {
+	defer func(start time.Time) { observe("UpdateUserLockedAt", start, err) }(time.Now())
+	return s.next.UpdateUserLockedAt(ctx, arg)
+}
+
+func (s *Store) UpdateUserRole(ctx context.Context, arg db.UpdateUserRoleParams) (user db.User, err error) {
+	defer func(start time.Time) { observe("UpdateUserRole", start, err) }(time.Now())
+	return s.next.UpdateUserRole(ctx, arg)
+}
+
+func (s *Store) UpdateWebhook(ctx context.Context, arg db.UpdateWebhookParams) (webhook db.Webhook, err error) {
+	defer func(start time.Time) { observe("UpdateWebhook", start, err) }(time.Now())
+	return s.next.UpdateWebhook(ctx, arg)
+}
+
+func (s *Store) UpdateWebhookDelivery(ctx context.Context, arg db.UpdateWebhookDeliveryParams) (delivery db.WebhookDelivery, err error) {
+	defer func(start time.Time) { observe("UpdateWebhookDelivery", start, err) }(time.Now())
+	return s.next.UpdateWebhookDelivery(ctx, arg)
+}
+
+func (s *Store) UpsertTag(ctx context.Context, name string) (tag db.Tag, err error) {
+	defer func(start time.Time) { observe("UpsertTag", start, err) }(time.Now())
+	return s.next.UpsertTag(ctx, name)
+}
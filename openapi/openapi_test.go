@@ -0,0 +1,311 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/graph"
+	"github.com/PenginAction/go-BulletinBoard/health"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/graphql-go/graphql"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+// schemas lists the Go type behind every schema in the document
+var schemas = map[string]interface{}{
+	"CreateUserRequest":           dto.CreateUserRequest{},
+	"CreateUserResponse":          dto.CreateUserResponse{},
+	"LoginRequest":                dto.LoginRequest{},
+	"LoginResponse":               dto.LoginResponse{},
+	"CreatePostRequest":           dto.CreatePostRequest{},
+	"UpdatePostRequest":           dto.UpdatePostRequest{},
+	"PostResponse":                dto.PostResponse{},
+	"MentionEntity":               dto.MentionEntity{},
+	"TagResponse":                 dto.TagResponse{},
+	"CreateWebhookRequest":        dto.CreateWebhookRequest{},
+	"UpdateWebhookRequest":        dto.UpdateWebhookRequest{},
+	"WebhookResponse":             dto.WebhookResponse{},
+	"WebhookDeliveryResponse":     dto.WebhookDeliveryResponse{},
+	"NotificationResponse":        dto.NotificationResponse{},
+	"UnreadNotificationsResponse": dto.UnreadNotificationsResponse{},
+	"Problem":                     dto.Problem{},
+	"FieldError":                  dto.FieldError{},
+	"HealthReport":                health.Report{},
+	"HealthResult":                health.Result{},
+	"GraphQLRequest":              graph.Request{},
+	"GraphQLResponse":             graphql.Result{},
+}
+
+func TestLoad(t *testing.T) {
+	doc, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, "3.1.0", doc.OpenAPI)
+}
+
+func TestSchemasMatchTypes(t *testing.T) {
+	doc, err := Load()
+	require.NoError(t, err)
+
+	var names []string
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		require.Contains(t, schemas, name, "schema %s has no Go type in the test", name)
+	}
+
+	for name, v := range schemas {
+		t.Run(name, func(t *testing.T) {
+			ref, ok := doc.Components.Schemas[name]
+			require.True(t, ok, "schema %s missing from openapi.json", name)
+			schema := ref.Value
+
+			fields := jsonFields(reflect.TypeOf(v))
+			var props []string
+			for prop := range schema.Properties {
+				props = append(props, prop)
+			}
+			sort.Strings(props)
+			var goNames []string
+			for field := range fields {
+				goNames = append(goNames, field)
+			}
+			sort.Strings(goNames)
+			require.Equal(t, goNames, props, "properties of %s", name)
+
+			for prop, typ := range fields {
+				require.Equal(t, schemaType(typ), schema.Properties[prop].Value.Type, "type of %s.%s", name, prop)
+			}
+			for _, prop := range schema.Required {
+				require.NotContains(t, omitempty(reflect.TypeOf(v)), prop, "%s.%s is required but omitted when empty", name, prop)
+			}
+		})
+	}
+}
+
+func TestRequestValidator(t *testing.T) {
+	doc, err := Load()
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		method  string
+		route   string
+		target  string
+		body    string
+		status  int
+		checkFn func(t *testing.T, err error)
+	}{
+		{
+			name:   "valid body",
+			method: http.MethodPost,
+			route:  "/signup",
+			target: "/signup",
+			body:   `{"user_str_id":"abc","email":"a@example.com","password":"secret"}`,
+		},
+		{
+			name:   "invalid body",
+			method: http.MethodPost,
+			route:  "/signup",
+			target: "/signup",
+			body:   `{"user_str_id":"a-b","email":"a@example.com"}`,
+			checkFn: func(t *testing.T, err error) {
+				e, ok := usecase.AsError(err)
+				require.True(t, ok)
+				require.Equal(t, usecase.KindValidation, e.Kind)
+				codes := map[string]string{}
+				for _, f := range e.Fields {
+					codes[f.Field] = f.Code
+				}
+				require.Equal(t, map[string]string{"user_str_id": "pattern", "password": "required"}, codes)
+			},
+		},
+		{
+			name:   "wrong type",
+			method: http.MethodPost,
+			route:  "/posts",
+			target: "/posts",
+			body:   `{"text":42}`,
+			checkFn: func(t *testing.T, err error) {
+				e, ok := usecase.AsError(err)
+				require.True(t, ok)
+				require.Equal(t, []string{"text"}, fieldNames(e))
+				require.Equal(t, "type", e.Fields[0].Code)
+			},
+		},
+		{
+			// the id comes from the path only
+			name:   "unknown property",
+			method: http.MethodPut,
+			route:  "/posts/:postId",
+			target: "/posts/1",
+			body:   `{"id":2,"text":"edited"}`,
+			checkFn: func(t *testing.T, err error) {
+				e, ok := usecase.AsError(err)
+				require.True(t, ok)
+				require.Equal(t, []string{"body"}, fieldNames(e))
+				require.Contains(t, e.Fields[0].Message, `"id"`)
+			},
+		},
+		{
+			name:   "invalid query",
+			method: http.MethodGet,
+			route:  "/posts",
+			target: "/posts?page_id=11&page_size=5",
+			checkFn: func(t *testing.T, err error) {
+				e, ok := usecase.AsError(err)
+				require.True(t, ok)
+				require.Equal(t, []string{"page_id"}, fieldNames(e))
+				require.Equal(t, "maximum", e.Fields[0].Code)
+				require.Equal(t, []string{"10"}, e.Fields[0].Params)
+			},
+		},
+		{
+			name:   "invalid path parameter",
+			method: http.MethodGet,
+			route:  "/posts/:postId",
+			target: "/posts/abc",
+			checkFn: func(t *testing.T, err error) {
+				e, ok := usecase.AsError(err)
+				require.True(t, ok)
+				require.Equal(t, []string{"postId"}, fieldNames(e))
+			},
+		},
+		{
+			name:   "malformed json",
+			method: http.MethodPost,
+			route:  "/login",
+			target: "/login",
+			body:   `{"email":`,
+			checkFn: func(t *testing.T, err error) {
+				var he *echo.HTTPError
+				require.ErrorAs(t, err, &he)
+				require.Equal(t, http.StatusBadRequest, he.Code)
+			},
+		},
+		{
+			name:   "undocumented route",
+			method: http.MethodGet,
+			route:  "/nowhere",
+			target: "/nowhere",
+		},
+	}
+
+	e := echo.New()
+	for i := range cases {
+		tc := cases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.body != "" {
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath(tc.route)
+			if tc.route == "/posts/:postId" {
+				c.SetParamNames("postId")
+				c.SetParamValues(strings.TrimPrefix(tc.target, "/posts/"))
+			}
+
+			called := false
+			err := RequestValidator(doc)(func(c echo.Context) error {
+				called = true
+				// the body must still be readable by the handler
+				if tc.body != "" {
+					var body map[string]interface{}
+					require.NoError(t, json.NewDecoder(c.Request().Body).Decode(&body))
+				}
+				return nil
+			})(c)
+
+			if tc.checkFn == nil {
+				require.NoError(t, err)
+				require.True(t, called)
+				return
+			}
+			require.Error(t, err)
+			require.False(t, called)
+			tc.checkFn(t, err)
+		})
+	}
+}
+
+func TestSpecPath(t *testing.T) {
+	require.Equal(t, "/posts", SpecPath("/posts"))
+	require.Equal(t, "/posts/{postId}", SpecPath("/posts/:postId"))
+	require.Equal(t, "/a/{b}/c/{d}", SpecPath("/a/:b/c/:d"))
+}
+
+func fieldNames(e *usecase.Error) []string {
+	var names []string
+	for _, f := range e.Fields {
+		names = append(names, f.Field)
+	}
+	return names
+}
+
+// jsonFields returns the Go type of every field t is marshalled with, by
+// JSON name
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func omitempty(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		name, opts, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if strings.Contains(opts, "omitempty") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func schemaType(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return openapi3.TypeString
+	}
+	if t == reflect.TypeOf(json.RawMessage{}) {
+		// documented as the object it holds
+		return openapi3.TypeObject
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return openapi3.TypeString
+	case reflect.Bool:
+		return openapi3.TypeBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return openapi3.TypeInteger
+	case reflect.Float32, reflect.Float64:
+		return openapi3.TypeNumber
+	case reflect.Slice, reflect.Array:
+		return openapi3.TypeArray
+	}
+	return openapi3.TypeObject
+}
@@ -0,0 +1,188 @@
+package openapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/labstack/echo/v4"
+)
+
+// RequestValidator rejects requests whose parameters or body do not match
+// the operation documented for the matched route, before the handler binds
+// them. Routes are looked up with the document's base path removed, so the
+// same middleware serves versioned routes and their unversioned aliases.
+// Routes missing from doc are passed through; the router tests make sure
+// there are none.
+func RequestValidator(doc *openapi3.T) echo.MiddlewareFunc {
+	base := BasePath(doc)
+	options := &openapi3filter.Options{
+		// tokens are checked by the JWT middleware
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		MultiError:         true,
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			path := SpecPath(strings.TrimPrefix(ctx.Path(), base))
+			item := doc.Paths.Find(path)
+			if item == nil {
+				return next(ctx)
+			}
+			operation := item.GetOperation(req.Method)
+			if operation == nil {
+				return next(ctx)
+			}
+
+			params := make(map[string]string, len(ctx.ParamNames()))
+			for i, name := range ctx.ParamNames() {
+				params[name] = ctx.ParamValues()[i]
+			}
+			err := openapi3filter.ValidateRequest(req.Context(), &openapi3filter.RequestValidationInput{
+				Request:    req,
+				PathParams: params,
+				Route: &routers.Route{
+					Spec:      doc,
+					Path:      path,
+					PathItem:  item,
+					Method:    req.Method,
+					Operation: operation,
+				},
+				Options: options,
+			})
+			if err != nil {
+				return requestError(err)
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// SpecPath converts an echo route such as /posts/:postId into the path
+// template used by the document, /posts/{postId}
+func SpecPath(route string) string {
+	segments := strings.Split(route, "/")
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") {
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// requestError turns the errors reported by openapi3filter into the same
+// validation error the handlers return, so clients see one error shape
+func requestError(err error) error {
+	var fields []dto.FieldError
+	for _, e := range unwrapMulti(err) {
+		var re *openapi3filter.RequestError
+		if !errors.As(e, &re) {
+			return e
+		}
+
+		field := "body"
+		if re.Parameter != nil {
+			field = re.Parameter.Name
+		} else if re.Err == nil {
+			// the body could not be matched to a documented media type
+			return echo.NewHTTPError(http.StatusUnsupportedMediaType, re.Reason)
+		}
+
+		if errors.Is(re.Err, openapi3filter.ErrInvalidRequired) {
+			fields = append(fields, dto.FieldError{Field: field, Code: "required", Message: field + " is required"})
+			continue
+		}
+
+		schemaErrs := schemaErrors(re.Err)
+		if len(schemaErrs) == 0 {
+			if re.Parameter == nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "request body could not be decoded")
+			}
+			fields = append(fields, dto.FieldError{Field: field, Code: "type", Message: field + " " + re.Reason})
+			continue
+		}
+		for _, se := range schemaErrs {
+			name := field
+			if pointer := se.JSONPointer(); re.Parameter == nil && len(pointer) > 0 {
+				name = strings.Join(pointer, ".")
+			}
+			fields = append(fields, dto.FieldError{Field: name, Code: se.SchemaField, Message: name + ": " + se.Reason, Params: schemaBound(se)})
+		}
+	}
+
+	return &usecase.Error{
+		Kind:    usecase.KindValidation,
+		Code:    "validation_failed",
+		Message: "request validation failed",
+		Fields:  fields,
+		Err:     err,
+	}
+}
+
+// schemaBound returns the bound a schema error broke, to fill in the
+// translated message, or nothing if the rule has none worth naming
+func schemaBound(se *openapi3.SchemaError) []string {
+	schema := se.Schema
+	var bound string
+	switch se.SchemaField {
+	case "minimum":
+		if schema.Min == nil || schema.ExclusiveMin {
+			return nil
+		}
+		bound = strconv.FormatFloat(*schema.Min, 'f', -1, 64)
+	case "maximum":
+		if schema.Max == nil || schema.ExclusiveMax {
+			return nil
+		}
+		bound = strconv.FormatFloat(*schema.Max, 'f', -1, 64)
+	case "minLength":
+		bound = strconv.FormatUint(schema.MinLength, 10)
+	case "maxLength":
+		if schema.MaxLength == nil {
+			return nil
+		}
+		bound = strconv.FormatUint(*schema.MaxLength, 10)
+	case "minItems":
+		bound = strconv.FormatUint(schema.MinItems, 10)
+	case "maxItems":
+		if schema.MaxItems == nil {
+			return nil
+		}
+		bound = strconv.FormatUint(*schema.MaxItems, 10)
+	default:
+		return nil
+	}
+	return []string{bound}
+}
+
+// unwrapMulti flattens nested MultiErrors. It deliberately does not look
+// through other wrappers, which would lose the RequestError around them.
+func unwrapMulti(err error) []error {
+	me, ok := err.(openapi3.MultiError)
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, err := range me {
+		errs = append(errs, unwrapMulti(err)...)
+	}
+	return errs
+}
+
+func schemaErrors(err error) []*openapi3.SchemaError {
+	var errs []*openapi3.SchemaError
+	for _, err := range unwrapMulti(err) {
+		var se *openapi3.SchemaError
+		if errors.As(err, &se) {
+			errs = append(errs, se)
+		}
+	}
+	return errs
+}
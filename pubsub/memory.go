@@ -0,0 +1,86 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory is a PubSub for a single instance, and for tests
+type Memory struct {
+	mu     sync.RWMutex
+	topics map[string]map[*subscription]struct{}
+}
+
+type subscription struct {
+	fn func(msg []byte)
+}
+
+var _ PubSub = (*Memory)(nil)
+
+// NewMemory returns a PubSub delivering messages within this process
+func NewMemory() *Memory {
+	return &Memory{topics: map[string]map[*subscription]struct{}{}}
+}
+
+func (ps *Memory) Publish(ctx context.Context, topic string, msg []byte) error {
+	if err := checkMessage(topic, msg); err != nil {
+		return err
+	}
+	ps.deliver(topic, msg)
+	return nil
+}
+
+func (ps *Memory) Subscribe(topic string, fn func(msg []byte)) (func(), error) {
+	return ps.subscribe(topic, fn), nil
+}
+
+// Lose tells every subscriber that messages may have been lost, like a
+// transport does after reconnecting
+func (ps *Memory) Lose() {
+	ps.mu.RLock()
+	var subs []*subscription
+	for _, topic := range ps.topics {
+		for sub := range topic {
+			subs = append(subs, sub)
+		}
+	}
+	ps.mu.RUnlock()
+
+	for _, sub := range subs {
+		sub.fn(nil)
+	}
+}
+
+func (ps *Memory) deliver(topic string, msg []byte) {
+	ps.mu.RLock()
+	subs := make([]*subscription, 0, len(ps.topics[topic]))
+	for sub := range ps.topics[topic] {
+		subs = append(subs, sub)
+	}
+	ps.mu.RUnlock()
+
+	// outside the lock, so subscribers may publish or unsubscribe
+	for _, sub := range subs {
+		sub.fn(msg)
+	}
+}
+
+func (ps *Memory) subscribe(topic string, fn func(msg []byte)) func() {
+	sub := &subscription{fn: fn}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.topics[topic] == nil {
+		ps.topics[topic] = map[*subscription]struct{}{}
+	}
+	ps.topics[topic][sub] = struct{}{}
+
+	return func() {
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+		delete(ps.topics[topic], sub)
+		if len(ps.topics[topic]) == 0 {
+			delete(ps.topics, topic)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package pubsub
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	ps := NewMemory()
+
+	var received1, received2 []string
+	unsubscribe1, err := ps.Subscribe("a", func(msg []byte) { received1 = append(received1, string(msg)) })
+	require.NoError(t, err)
+	unsubscribe2, err := ps.Subscribe("a", func(msg []byte) { received2 = append(received2, string(msg)) })
+	require.NoError(t, err)
+	defer unsubscribe2()
+
+	require.NoError(t, ps.Publish(context.Background(), "a", []byte("1")))
+	require.NoError(t, ps.Publish(context.Background(), "b", []byte("2")))
+	unsubscribe1()
+	require.NoError(t, ps.Publish(context.Background(), "a", []byte("3")))
+
+	require.Equal(t, []string{"1"}, received1)
+	require.Equal(t, []string{"1", "3"}, received2)
+}
+
+func TestMemoryLose(t *testing.T) {
+	ps := NewMemory()
+
+	var received [][]byte
+	unsubscribe, err := ps.Subscribe("a", func(msg []byte) { received = append(received, msg) })
+	require.NoError(t, err)
+	defer unsubscribe()
+
+	ps.Lose()
+	require.Equal(t, [][]byte{nil}, received)
+}
+
+func TestMemoryRejectsWhatPostgresWould(t *testing.T) {
+	ps := NewMemory()
+
+	msg := strings.Repeat("a", maxPayload-len("topic\n"))
+	require.NoError(t, ps.Publish(context.Background(), "topic", []byte(msg)))
+	require.ErrorIs(t, ps.Publish(context.Background(), "topic", []byte(msg+"a")), ErrTooLarge)
+	require.ErrorIs(t, ps.Publish(context.Background(), "topic", []byte{0xff}), ErrNotText)
+}
@@ -0,0 +1,126 @@
+package pubsub
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// channel is the notification channel all topics share
+	channel = "bulletinboard"
+	// minReconnectInterval and maxReconnectInterval bound the backoff between
+	// attempts to re-establish a lost connection
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+	// pingInterval is how often an idle connection is checked, so a dead one
+	// is noticed and replaced
+	pingInterval = 90 * time.Second
+)
+
+// Postgres is a PubSub over LISTEN/NOTIFY.
+//
+// All topics share one notification channel and the topic is sent along
+// with the message, so subscribing costs no round trip to the database and
+// cannot block while it is unreachable. Each instance receives every message
+// and drops those of topics nobody subscribed to on it.
+//
+// The listening connection is re-established when lost. Subscribers are then
+// called with a nil message, as anything published meanwhile was missed.
+type Postgres struct {
+	conn     *sql.DB
+	listener *pq.Listener
+	local    *Memory
+	closed   atomic.Bool
+	done     chan struct{}
+}
+
+var _ PubSub = (*Postgres)(nil)
+
+// NewPostgres returns a PubSub publishing through conn and listening on a
+// connection of its own to dsn. It starts listening in the background.
+func NewPostgres(conn *sql.DB, dsn string) *Postgres {
+	ps := &Postgres{
+		conn:  conn,
+		local: NewMemory(),
+		done:  make(chan struct{}),
+	}
+	ps.listener = pq.NewListener(dsn, minReconnectInterval, maxReconnectInterval, logListenerEvent)
+
+	go func() {
+		// blocks until the first connection is made
+		if err := ps.listener.Listen(channel); err != nil && !ps.closed.Load() {
+			slog.Error("cannot listen for notifications", slog.String("error", err.Error()))
+		}
+	}()
+	go ps.run()
+	return ps
+}
+
+func (ps *Postgres) Publish(ctx context.Context, topic string, msg []byte) error {
+	if err := checkMessage(topic, msg); err != nil {
+		return err
+	}
+	_, err := ps.conn.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, topic+"\n"+string(msg))
+	return err
+}
+
+func (ps *Postgres) Subscribe(topic string, fn func(msg []byte)) (func(), error) {
+	return ps.local.subscribe(topic, fn), nil
+}
+
+// Close stops listening
+func (ps *Postgres) Close() error {
+	ps.closed.Store(true)
+	err := ps.listener.Close()
+	<-ps.done
+	return err
+}
+
+func (ps *Postgres) run() {
+	defer close(ps.done)
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case n, ok := <-ps.listener.Notify:
+			if !ok {
+				// closed
+				return
+			}
+			if n == nil {
+				ps.local.Lose()
+				continue
+			}
+			topic, msg, ok := strings.Cut(n.Extra, "\n")
+			if !ok {
+				continue
+			}
+			ps.local.deliver(topic, []byte(msg))
+		case <-ticker.C:
+			// waits for the server, which must not hold up notifications
+			go func() {
+				if err := ps.listener.Ping(); err != nil {
+					slog.Warn("notification connection is down", slog.String("error", err.Error()))
+				}
+			}()
+		}
+	}
+}
+
+func logListenerEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventDisconnected:
+		slog.Warn("lost notification connection", slog.String("error", err.Error()))
+	case pq.ListenerEventConnectionAttemptFailed:
+		slog.Error("cannot connect for notifications", slog.String("error", err.Error()))
+	case pq.ListenerEventReconnected:
+		slog.Info("notification connection re-established")
+	}
+}
@@ -0,0 +1,88 @@
+package pubsub
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/config"
+	"github.com/stretchr/testify/require"
+
+	_ "github.com/lib/pq"
+)
+
+func newTestPostgres(t *testing.T) *Postgres {
+	cfg, err := config.LoadConfig("..")
+	require.NoError(t, err)
+	conn, err := sql.Open(cfg.DBDriver, cfg.DBSource)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	if err := conn.Ping(); err != nil {
+		t.Skip("postgres not available:", err)
+	}
+
+	ps := NewPostgres(conn, cfg.DBSource)
+	t.Cleanup(func() { ps.Close() })
+	return ps
+}
+
+// receive subscribes to topic, publishing through ps until the listener is
+// up and the first message arrives
+func receive(t *testing.T, sub, pub *Postgres, topic string) <-chan string {
+	received := make(chan string, 16)
+	unsubscribe, err := sub.Subscribe(topic, func(msg []byte) { received <- string(msg) })
+	require.NoError(t, err)
+	t.Cleanup(unsubscribe)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		require.NoError(t, pub.Publish(context.Background(), topic, []byte("ready")))
+		select {
+		case <-received:
+			// later readies are skipped by the callers
+			return received
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	t.Fatal("not listening")
+	return nil
+}
+
+func next(t *testing.T, received <-chan string) string {
+	for {
+		select {
+		case msg := <-received:
+			if msg != "ready" {
+				return msg
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no message")
+		}
+	}
+}
+
+func TestPostgresAcrossInstances(t *testing.T) {
+	ps1, ps2 := newTestPostgres(t), newTestPostgres(t)
+	received1 := receive(t, ps1, ps1, "a")
+	received2 := receive(t, ps2, ps1, "a")
+	other := receive(t, ps2, ps2, "b")
+
+	require.NoError(t, ps1.Publish(context.Background(), "a", []byte(`{"n":1}`)))
+	require.Equal(t, `{"n":1}`, next(t, received1))
+	require.Equal(t, `{"n":1}`, next(t, received2))
+
+	require.NoError(t, ps2.Publish(context.Background(), "b", []byte("2")))
+	require.Equal(t, "2", next(t, other))
+}
+
+func TestPostgresLargestMessage(t *testing.T) {
+	ps := newTestPostgres(t)
+	received := receive(t, ps, ps, "topic")
+
+	msg := strings.Repeat("a", maxPayload-len("topic\n"))
+	require.NoError(t, ps.Publish(context.Background(), "topic", []byte(msg)))
+	require.Equal(t, msg, next(t, received))
+	require.ErrorIs(t, ps.Publish(context.Background(), "topic", []byte(msg+"a")), ErrTooLarge)
+}
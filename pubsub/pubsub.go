@@ -0,0 +1,53 @@
+// Package pubsub carries messages between the instances of the server.
+//
+// Postgres delivers them through LISTEN/NOTIFY, so every instance sharing a
+// database sees them. Memory delivers them within one process, for a single
+// instance and for tests.
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"unicode/utf8"
+)
+
+// maxPayload is the size of the largest NOTIFY payload Postgres accepts.
+// Memory enforces it too, so what works on one transport works on the
+// other.
+const maxPayload = 7999
+
+var (
+	// ErrTooLarge is returned by Publish when the topic and message exceed
+	// what the transport can carry. Publishers should send less, for
+	// example a reference to the data instead of the data.
+	ErrTooLarge = errors.New("pubsub: message too large")
+	// ErrNotText is returned by Publish when the message is not UTF-8 text
+	ErrNotText = errors.New("pubsub: message is not UTF-8 text")
+)
+
+// PubSub carries messages between instances. Delivery is at most once and
+// only to subscribers present when a message is published.
+type PubSub interface {
+	// Publish sends msg to every subscriber of topic, this instance's
+	// included
+	Publish(ctx context.Context, topic string, msg []byte) error
+	// Subscribe calls fn with every message published to topic until the
+	// returned function is called. fn must not block, and Subscribe must not
+	// call it before returning.
+	//
+	// fn is called with a nil message when messages may have been lost, for
+	// example after the connection to the database was re-established.
+	// Subscribers should then catch up from wherever the data is stored.
+	Subscribe(topic string, fn func(msg []byte)) (func(), error)
+}
+
+func checkMessage(topic string, msg []byte) error {
+	// topics travel along with the message, see Postgres
+	if len(topic)+1+len(msg) > maxPayload {
+		return ErrTooLarge
+	}
+	if !utf8.Valid(msg) {
+		return ErrNotText
+	}
+	return nil
+}
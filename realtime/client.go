@@ -0,0 +1,170 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/i18n"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait is how long a client may take to accept a frame
+	writeWait = 10 * time.Second
+	// pongWait is how long a client may stay silent, pongs included
+	pongWait = 60 * time.Second
+	// pingPeriod must be shorter than pongWait
+	pingPeriod = pongWait * 9 / 10
+	// maxMessageSize bounds the frames clients send
+	maxMessageSize = 4096
+	// sendBuffer is how many messages a client may fall behind before it is
+	// disconnected
+	sendBuffer = 64
+	// maxThreads bounds the subscriptions of one connection
+	maxThreads = 50
+	// typingInterval throttles the typing messages of one connection
+	typingInterval = time.Second
+)
+
+// client is one connection. Its reader runs in the goroutine that called
+// Hub.Serve, its writer in a goroutine of its own.
+type client struct {
+	hub   *Hub
+	conn  *websocket.Conn
+	user  User
+	trans ut.Translator
+
+	send chan []byte
+	done chan struct{}
+	once sync.Once
+
+	// threads is guarded by hub.mu
+	threads map[uint]bool
+	// lastTyping is only used by the reader
+	lastTyping map[uint]time.Time
+}
+
+// deliver queues msg without blocking; a client whose buffer is full is
+// disconnected rather than holding up the others
+func (c *client) deliver(msg []byte) {
+	select {
+	case c.send <- msg:
+	case <-c.done:
+	default:
+		c.close()
+	}
+}
+
+func (c *client) deliverMessage(msg Message) {
+	frame, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	c.deliver(frame)
+}
+
+func (c *client) deliverError(err error) {
+	e, ok := usecase.AsError(err)
+	if !ok {
+		e = &usecase.Error{Code: "internal_error", Message: "internal server error"}
+	}
+	data := Error{
+		Code:    e.Code,
+		Message: i18n.Message(c.trans, e.Code, e.Message),
+	}
+	for _, f := range e.Fields {
+		f.Message = i18n.FieldMessage(c.trans, f)
+		data.Errors = append(data.Errors, f)
+	}
+	c.deliverMessage(newMessage(TypeError, 0, data))
+}
+
+// close makes the writer send a close frame and hang up
+func (c *client) close() {
+	c.once.Do(func() { close(c.done) })
+}
+
+func (c *client) readPump(ctx context.Context) {
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, frame, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+
+		var msg Message
+		if err := json.Unmarshal(frame, &msg); err != nil {
+			c.deliverError(usecase.NewValidationError("message", "format", "message must be a JSON object"))
+			continue
+		}
+		if err := c.handle(ctx, msg); err != nil {
+			c.deliverError(err)
+		}
+	}
+}
+
+func (c *client) handle(ctx context.Context, msg Message) error {
+	switch msg.Type {
+	case TypeSubscribe, TypeUnsubscribe, TypeTyping:
+	default:
+		return usecase.NewValidationError("type", "enum", "type must be subscribe, unsubscribe or typing")
+	}
+	if msg.Thread == 0 {
+		return usecase.NewValidationError("thread", "positive_integer", "thread must be a positive integer")
+	}
+
+	switch msg.Type {
+	case TypeSubscribe:
+		return c.hub.subscribe(ctx, c, msg.Thread)
+	case TypeUnsubscribe:
+		c.hub.unsubscribe(c, msg.Thread)
+		return nil
+	case TypeTyping:
+		if time.Since(c.lastTyping[msg.Thread]) < typingInterval {
+			return nil
+		}
+		c.lastTyping[msg.Thread] = time.Now()
+		return c.hub.typing(ctx, c, msg.Thread)
+	}
+	return nil
+}
+
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case frame := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""),
+				time.Now().Add(writeWait))
+			return
+		}
+	}
+}
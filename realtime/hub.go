@@ -0,0 +1,526 @@
+package realtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/pubsub"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// presenceInterval is how often every instance announces its viewers.
+	// Viewers not announced for presenceTTL are dropped, so the viewers of an
+	// instance that died disappear.
+	presenceInterval = 15 * time.Second
+	presenceTTL      = 3 * presenceInterval
+	// eventBatch is how many post events are read from the log at a time
+	eventBatch = 100
+)
+
+// Kinds of the envelopes hubs exchange
+const (
+	// kindHere carries the complete set of an instance's viewers of a thread
+	kindHere = "here"
+	// kindHello asks the other instances to announce their viewers
+	kindHello  = "hello"
+	kindTyping = "typing"
+)
+
+// envelope is what hubs publish to the topic of a thread
+type envelope struct {
+	Kind     string `json:"kind"`
+	Instance string `json:"instance"`
+	Users    []User `json:"users,omitempty"`
+}
+
+// Hub tracks the connections subscribed to each thread and sends them the
+// changes, presence and typing of the thread
+type Hub struct {
+	ps       pubsub.PubSub
+	events   usecase.IPostEventUsecase
+	posts    usecase.IPostUsecase
+	instance string
+
+	mu      sync.Mutex
+	clients map[*client]struct{}
+	rooms   map[uint]*room
+	closed  bool
+
+	presenceInterval time.Duration
+	presenceTTL      time.Duration
+}
+
+// room is a thread with subscribers on this instance
+type room struct {
+	clients     map[*client]struct{}
+	remote      map[string]remoteViewers
+	viewers     []User
+	unsubscribe func()
+}
+
+// remoteViewers are the viewers another instance last announced
+type remoteViewers struct {
+	users []User
+	seen  time.Time
+}
+
+// NewHub returns a hub exchanging presence through ps. Post changes are read
+// from events, and subscriptions are checked against posts.
+func NewHub(ps pubsub.PubSub, events usecase.IPostEventUsecase, posts usecase.IPostUsecase) *Hub {
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	return &Hub{
+		ps:               ps,
+		events:           events,
+		posts:            posts,
+		instance:         hex.EncodeToString(id),
+		clients:          map[*client]struct{}{},
+		rooms:            map[uint]*room{},
+		presenceInterval: presenceInterval,
+		presenceTTL:      presenceTTL,
+	}
+}
+
+// Run sends post changes to the subscribers of their thread and keeps
+// presence up to date, until ctx is done or the event log is closed
+func (h *Hub) Run(ctx context.Context) error {
+	lastID, err := h.events.LastPostEventID(ctx)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(h.presenceInterval)
+	defer ticker.Stop()
+	for {
+		// taken before reading so an event logged meanwhile is not missed
+		wake := h.events.Wait()
+
+		events, err := h.events.ListPostEvents(ctx, lastID, eventBatch)
+		if err != nil && ctx.Err() == nil {
+			// retried at the next tick
+			slog.ErrorContext(ctx, "cannot read post events", slog.String("error", err.Error()))
+		}
+		for _, e := range events {
+			h.broadcast(e.PostID, Message{Type: e.Type, Thread: e.PostID, Data: e.Data}, 0)
+			if parent := parentID(e.Data); parent != 0 {
+				h.broadcast(parent, Message{Type: e.Type, Thread: parent, Data: e.Data}, 0)
+			}
+			lastID = e.ID
+		}
+		if len(events) == eventBatch {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-h.events.Done():
+			return nil
+		case <-wake:
+		case <-ticker.C:
+			// also catches up with events whose publication was lost
+			h.refreshPresence(ctx)
+		}
+	}
+}
+
+// Notify sends the notifications among events to the connections of the
+// users notified. It is meant to be subscribed to the EventPublisher.
+func (h *Hub) Notify(e usecase.DomainEvent) {
+	if e.Type != usecase.NotificationCreated {
+		return
+	}
+	// a notification that lost its data on the way is only listed
+	var notification dto.NotificationResponse
+	if e.Truncated || json.Unmarshal(e.Data, &notification) != nil {
+		return
+	}
+	frame, err := json.Marshal(Message{Type: TypeNotification, Data: e.Data})
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		if c.user.ID == notification.UserID {
+			c.deliver(frame)
+		}
+	}
+}
+
+// Serve serves a WebSocket connection of user until it is closed. Error
+// messages are translated with trans.
+func (h *Hub) Serve(ctx context.Context, conn *websocket.Conn, user User, trans ut.Translator) {
+	c := &client{
+		hub:        h,
+		conn:       conn,
+		user:       user,
+		trans:      trans,
+		send:       make(chan []byte, sendBuffer),
+		done:       make(chan struct{}),
+		threads:    map[uint]bool{},
+		lastTyping: map[uint]time.Time{},
+	}
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		c.close()
+		c.writePump()
+		return
+	}
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+
+	go c.writePump()
+	c.readPump(ctx)
+
+	h.mu.Lock()
+	delete(h.clients, c)
+	var left []uint
+	for thread := range c.threads {
+		left = append(left, thread)
+	}
+	h.mu.Unlock()
+	for _, thread := range left {
+		h.unsubscribe(c, thread)
+	}
+	c.close()
+}
+
+// Close disconnects every client. Later connections are closed immediately.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for c := range h.clients {
+		c.close()
+	}
+}
+
+func (h *Hub) subscribe(ctx context.Context, c *client, thread uint) error {
+	if _, err := h.posts.GetPostById(ctx, thread); err != nil {
+		return err
+	}
+
+	// a subscription to the topic of the thread, released unless it is
+	// handed to a new room
+	var sub func()
+	defer func() {
+		if sub != nil {
+			sub()
+		}
+	}()
+
+	h.mu.Lock()
+	for {
+		if c.threads[thread] {
+			h.mu.Unlock()
+			return nil
+		}
+		if len(c.threads) >= maxThreads {
+			h.mu.Unlock()
+			return ErrTooManySubscriptions
+		}
+		if _, ok := h.rooms[thread]; ok || sub != nil {
+			break
+		}
+		// the PubSub is not called under h.mu, so a slow transport holds
+		// up no other client; the room is looked up again once subscribed
+		h.mu.Unlock()
+		var err error
+		sub, err = h.ps.Subscribe(topic(thread), func(msg []byte) {
+			h.receive(thread, msg)
+		})
+		if err != nil {
+			return err
+		}
+		h.mu.Lock()
+	}
+
+	r, ok := h.rooms[thread]
+	if !ok {
+		r = &room{
+			clients:     map[*client]struct{}{},
+			remote:      map[string]remoteViewers{},
+			unsubscribe: sub,
+		}
+		h.rooms[thread] = r
+		sub = nil
+	}
+	joined := !r.hasUser(c.user.ID)
+	r.clients[c] = struct{}{}
+	c.threads[thread] = true
+	h.updateViewers(thread, r, c)
+	local := r.localUsers()
+	c.deliverMessage(newMessage(TypeSubscribed, thread, Presence{Viewers: r.viewers}))
+	h.mu.Unlock()
+
+	if !ok {
+		h.publish(ctx, thread, envelope{Kind: kindHello})
+	}
+	if joined {
+		h.publish(ctx, thread, envelope{Kind: kindHere, Users: local})
+	}
+	return nil
+}
+
+func (h *Hub) unsubscribe(c *client, thread uint) {
+	h.mu.Lock()
+	r, ok := h.rooms[thread]
+	if !ok || !c.threads[thread] {
+		h.mu.Unlock()
+		return
+	}
+	delete(r.clients, c)
+	delete(c.threads, thread)
+	c.deliverMessage(newMessage(TypeUnsubscribed, thread, nil))
+
+	left := !r.hasUser(c.user.ID)
+	local := r.localUsers()
+	empty := len(r.clients) == 0
+	if empty {
+		delete(h.rooms, thread)
+	} else {
+		h.updateViewers(thread, r, nil)
+	}
+	h.mu.Unlock()
+
+	if empty {
+		// outside h.mu, as in subscribe
+		r.unsubscribe()
+	}
+
+	if left {
+		// the connection may be gone, so its context is not used
+		h.publish(context.Background(), thread, envelope{Kind: kindHere, Users: local})
+	}
+}
+
+func (h *Hub) typing(ctx context.Context, c *client, thread uint) error {
+	h.mu.Lock()
+	subscribed := c.threads[thread]
+	h.mu.Unlock()
+	if !subscribed {
+		return ErrNotSubscribed
+	}
+
+	h.broadcast(thread, newMessage(TypeTyping, thread, Typing{User: c.user}), c.user.ID)
+	h.publish(ctx, thread, envelope{Kind: kindTyping, Users: []User{c.user}})
+	return nil
+}
+
+// receive handles an envelope published by another instance
+func (h *Hub) receive(thread uint, frame []byte) {
+	if frame == nil {
+		// envelopes were lost, so the viewers this instance knows of may be
+		// stale; called by the PubSub, so publish without blocking it
+		go h.resync(thread)
+		return
+	}
+
+	var env envelope
+	if err := json.Unmarshal(frame, &env); err != nil || env.Instance == h.instance {
+		return
+	}
+
+	switch env.Kind {
+	case kindHere:
+		h.mu.Lock()
+		if r, ok := h.rooms[thread]; ok {
+			if len(env.Users) == 0 {
+				delete(r.remote, env.Instance)
+			} else {
+				r.remote[env.Instance] = remoteViewers{users: env.Users, seen: time.Now()}
+			}
+			h.updateViewers(thread, r, nil)
+		}
+		h.mu.Unlock()
+	case kindHello:
+		h.mu.Lock()
+		var local []User
+		if r, ok := h.rooms[thread]; ok {
+			local = r.localUsers()
+		}
+		h.mu.Unlock()
+		if len(local) > 0 {
+			// called by the PubSub, so publish without blocking it
+			go h.publish(context.Background(), thread, envelope{Kind: kindHere, Users: local})
+		}
+	case kindTyping:
+		if len(env.Users) == 1 {
+			h.broadcast(thread, newMessage(TypeTyping, thread, Typing{User: env.Users[0]}), env.Users[0].ID)
+		}
+	}
+}
+
+// resync asks the other instances for their viewers of thread, and
+// announces those of this instance
+func (h *Hub) resync(thread uint) {
+	h.mu.Lock()
+	r, ok := h.rooms[thread]
+	var local []User
+	if ok {
+		local = r.localUsers()
+	}
+	h.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	ctx := context.Background()
+	h.publish(ctx, thread, envelope{Kind: kindHello})
+	h.publish(ctx, thread, envelope{Kind: kindHere, Users: local})
+}
+
+// refreshPresence announces this instance's viewers of every thread and
+// drops the viewers other instances stopped announcing
+func (h *Hub) refreshPresence(ctx context.Context) {
+	announce := map[uint][]User{}
+
+	h.mu.Lock()
+	for thread, r := range h.rooms {
+		for instance, v := range r.remote {
+			if time.Since(v.seen) > h.presenceTTL {
+				delete(r.remote, instance)
+			}
+		}
+		h.updateViewers(thread, r, nil)
+		announce[thread] = r.localUsers()
+	}
+	h.mu.Unlock()
+
+	for thread, users := range announce {
+		h.publish(ctx, thread, envelope{Kind: kindHere, Users: users})
+	}
+}
+
+// updateViewers recomputes the viewers of r and tells its clients if they
+// changed, except skip, which is told separately. Callers must hold h.mu.
+func (h *Hub) updateViewers(thread uint, r *room, skip *client) {
+	seen := map[uint]bool{}
+	var viewers []User
+	add := func(users []User) {
+		for _, u := range users {
+			if !seen[u.ID] {
+				seen[u.ID] = true
+				viewers = append(viewers, u)
+			}
+		}
+	}
+	add(r.localUsers())
+	for _, v := range r.remote {
+		add(v.users)
+	}
+	sort.Slice(viewers, func(i, j int) bool { return viewers[i].ID < viewers[j].ID })
+	if viewers == nil {
+		viewers = []User{}
+	}
+
+	if equalUsers(viewers, r.viewers) {
+		return
+	}
+	r.viewers = viewers
+	msg := newMessage(TypePresence, thread, Presence{Viewers: viewers})
+	for c := range r.clients {
+		if c != skip {
+			c.deliverMessage(msg)
+		}
+	}
+}
+
+// broadcast sends msg to the clients subscribed to thread, except those of
+// the user with id skip
+func (h *Hub) broadcast(thread uint, msg Message, skip uint) {
+	frame, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r, ok := h.rooms[thread]
+	if !ok {
+		return
+	}
+	for c := range r.clients {
+		if skip == 0 || c.user.ID != skip {
+			c.deliver(frame)
+		}
+	}
+}
+
+// publish sends env to the other instances. It must not be called with h.mu
+// held, as the PubSub may deliver to this hub synchronously.
+func (h *Hub) publish(ctx context.Context, thread uint, env envelope) {
+	env.Instance = h.instance
+	frame, err := json.Marshal(env)
+	if err == nil {
+		err = h.ps.Publish(ctx, topic(thread), frame)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "cannot publish to other instances", slog.String("kind", env.Kind), slog.String("error", err.Error()))
+	}
+}
+
+// localUsers returns the users with a connection subscribed to r
+func (r *room) localUsers() []User {
+	seen := map[uint]bool{}
+	users := []User{}
+	for c := range r.clients {
+		if !seen[c.user.ID] {
+			seen[c.user.ID] = true
+			users = append(users, c.user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+func (r *room) hasUser(id uint) bool {
+	for c := range r.clients {
+		if c.user.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// parentID returns the post a post event's data replies to, if any
+func parentID(data json.RawMessage) uint {
+	var post struct {
+		ParentID uint `json:"parent_id"`
+	}
+	json.Unmarshal(data, &post)
+	return post.ParentID
+}
+
+func topic(thread uint) string {
+	return "thread." + strconv.FormatUint(uint64(thread), 10)
+}
+
+func equalUsers(a, b []User) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,494 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/i18n"
+	"github.com/PenginAction/go-BulletinBoard/pubsub"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+// cluster runs hubs sharing one store and one PubSub, like instances of the
+// server sharing a database
+type cluster struct {
+	store     db.Store
+	ps        *pubsub.Memory
+	publisher *usecase.EventPublisher
+	events    *usecase.PostEventLog
+	posts     usecase.IPostUsecase
+}
+
+func newCluster(t *testing.T) *cluster {
+	store := memory.NewStore()
+	ps := pubsub.NewMemory()
+	publisher, err := usecase.NewEventPublisher(ps)
+	require.NoError(t, err)
+	events := usecase.NewPostEventLog(usecase.NewPostUsecase(store), store, publisher)
+	t.Cleanup(events.Close)
+	return &cluster{store: store, ps: ps, publisher: publisher, events: events, posts: events}
+}
+
+// instance starts a hub and a server handing it the connections of the user
+// whose id and name are in the query
+func (cl *cluster) instance(t *testing.T) (*Hub, *httptest.Server) {
+	return cl.instanceWith(t, cl.ps)
+}
+
+// instanceWith starts an instance exchanging presence through ps
+func (cl *cluster) instanceWith(t *testing.T, ps pubsub.PubSub) (*Hub, *httptest.Server) {
+	hub := NewHub(ps, cl.events, cl.posts)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go hub.Run(ctx)
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.URL.Query().Get("id"))
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		hub.Serve(r.Context(), conn, User{ID: uint(id), UserStrID: r.URL.Query().Get("name")}, i18n.FromAcceptLanguage(""))
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(hub.Close)
+	return hub, server
+}
+
+func (cl *cluster) createUser(t *testing.T) User {
+	user, err := cl.store.CreateUser(context.Background(), db.CreateUserParams{
+		UserStrID: utils.RandomUserStrID(),
+		Email:     utils.RandomEmail(),
+		Password:  utils.RandomString(60),
+	})
+	require.NoError(t, err)
+	return User{ID: user.ID, UserStrID: user.UserStrID}
+}
+
+func (cl *cluster) createPost(t *testing.T, user User) dto.PostResponse {
+	post, err := cl.posts.CreatePost(context.Background(), dto.CreatePostRequest{UserID: user.ID, Text: utils.RandomString(10)})
+	require.NoError(t, err)
+	return post
+}
+
+func (cl *cluster) updatePost(t *testing.T, id uint) {
+	_, err := cl.posts.UpdatePost(context.Background(), dto.UpdatePostRequest{ID: id, Text: "updated"})
+	require.NoError(t, err)
+}
+
+type testConn struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func dial(t *testing.T, server *httptest.Server, user User) *testConn {
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "?id=" + strconv.Itoa(int(user.ID)) + "&name=" + user.UserStrID
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &testConn{t: t, conn: conn}
+}
+
+func (tc *testConn) send(typ string, thread uint) {
+	require.NoError(tc.t, tc.conn.WriteJSON(Message{Type: typ, Thread: thread}))
+}
+
+func (tc *testConn) read() Message {
+	tc.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg Message
+	require.NoError(tc.t, tc.conn.ReadJSON(&msg))
+	return msg
+}
+
+// expect reads until a message of type typ, skipping presence updates
+// that depend on timing
+func (tc *testConn) expect(typ string, thread uint) Message {
+	for {
+		msg := tc.read()
+		if msg.Type == TypePresence && typ != TypePresence {
+			continue
+		}
+		require.Equal(tc.t, typ, msg.Type, string(msg.Data))
+		require.Equal(tc.t, thread, msg.Thread)
+		return msg
+	}
+}
+
+// expectViewers reads presence updates until the viewers are users
+func (tc *testConn) expectViewers(thread uint, users ...User) {
+	for {
+		msg := tc.expect(TypePresence, thread)
+		var p Presence
+		require.NoError(tc.t, json.Unmarshal(msg.Data, &p))
+		if len(p.Viewers) == len(users) {
+			require.Equal(tc.t, users, p.Viewers)
+			return
+		}
+	}
+}
+
+func (tc *testConn) subscribe(thread uint) []User {
+	tc.send(TypeSubscribe, thread)
+	msg := tc.expect(TypeSubscribed, thread)
+	var p Presence
+	require.NoError(tc.t, json.Unmarshal(msg.Data, &p))
+	return p.Viewers
+}
+
+func TestSubscribePresence(t *testing.T) {
+	cl := newCluster(t)
+	_, server := cl.instance(t)
+	alice, bob := cl.createUser(t), cl.createUser(t)
+	thread := cl.createPost(t, alice).ID
+
+	a := dial(t, server, alice)
+	require.Equal(t, []User{alice}, a.subscribe(thread))
+
+	b := dial(t, server, bob)
+	require.Equal(t, []User{alice, bob}, b.subscribe(thread))
+	a.expectViewers(thread, alice, bob)
+
+	b.send(TypeUnsubscribe, thread)
+	b.expect(TypeUnsubscribed, thread)
+	a.expectViewers(thread, alice)
+}
+
+func TestSecondConnectionOfSameUser(t *testing.T) {
+	cl := newCluster(t)
+	_, server := cl.instance(t)
+	alice := cl.createUser(t)
+	thread := cl.createPost(t, alice).ID
+
+	a1 := dial(t, server, alice)
+	a1.subscribe(thread)
+	a2 := dial(t, server, alice)
+	require.Equal(t, []User{alice}, a2.subscribe(thread))
+
+	// alice still views the thread from a1
+	a2.conn.Close()
+	cl.updatePost(t, thread)
+	a1.expect(usecase.PostUpdated, thread)
+}
+
+// slowPubSub holds the subscriptions to topic until release is closed
+type slowPubSub struct {
+	pubsub.PubSub
+	topic   string
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (ps *slowPubSub) Subscribe(topic string, fn func(msg []byte)) (func(), error) {
+	if topic == ps.topic {
+		close(ps.entered)
+		<-ps.release
+	}
+	return ps.PubSub.Subscribe(topic, fn)
+}
+
+func TestSlowSubscribeHoldsUpNoOne(t *testing.T) {
+	cl := newCluster(t)
+	alice, bob := cl.createUser(t), cl.createUser(t)
+	thread := cl.createPost(t, alice).ID
+	other := cl.createPost(t, bob).ID
+	ps := &slowPubSub{PubSub: cl.ps, topic: topic(thread), entered: make(chan struct{}), release: make(chan struct{})}
+	_, server := cl.instanceWith(t, ps)
+	// let the instance shut down if the test fails
+	t.Cleanup(func() {
+		select {
+		case <-ps.release:
+		default:
+			close(ps.release)
+		}
+	})
+
+	b := dial(t, server, bob)
+	b.subscribe(other)
+
+	a := dial(t, server, alice)
+	a.send(TypeSubscribe, thread)
+	<-ps.entered
+
+	// the changes of other threads are still sent
+	cl.updatePost(t, other)
+	b.expect(usecase.PostUpdated, other)
+
+	close(ps.release)
+	a.expect(TypeSubscribed, thread)
+}
+
+func TestPostEventsReachSubscribers(t *testing.T) {
+	cl := newCluster(t)
+	_, server := cl.instance(t)
+	alice := cl.createUser(t)
+	thread := cl.createPost(t, alice).ID
+	other := cl.createPost(t, alice).ID
+
+	a := dial(t, server, alice)
+	a.subscribe(thread)
+
+	// not subscribed, so not received
+	cl.updatePost(t, other)
+	cl.updatePost(t, thread)
+	msg := a.expect(usecase.PostUpdated, thread)
+	var post dto.PostResponse
+	require.NoError(t, json.Unmarshal(msg.Data, &post))
+	require.Equal(t, "updated", post.Text)
+
+	require.NoError(t, cl.posts.DeletePost(context.Background(), thread))
+	a.expect(usecase.PostDeleted, thread)
+}
+
+func TestRepliesReachParentThread(t *testing.T) {
+	cl := newCluster(t)
+	_, server := cl.instance(t)
+	alice := cl.createUser(t)
+	thread := cl.createPost(t, alice).ID
+
+	a := dial(t, server, alice)
+	a.subscribe(thread)
+
+	reply, err := cl.posts.CreatePost(context.Background(), dto.CreatePostRequest{UserID: alice.ID, Text: "reply", ParentID: thread})
+	require.NoError(t, err)
+	msg := a.expect(usecase.PostCreated, thread)
+	var post dto.PostResponse
+	require.NoError(t, json.Unmarshal(msg.Data, &post))
+	require.Equal(t, reply.ID, post.ID)
+	require.Equal(t, thread, post.ParentID)
+
+	require.NoError(t, cl.posts.DeletePost(context.Background(), reply.ID))
+	msg = a.expect(usecase.PostDeleted, thread)
+	var deleted dto.DeletedPost
+	require.NoError(t, json.Unmarshal(msg.Data, &deleted))
+	require.Equal(t, dto.DeletedPost{ID: reply.ID, ParentID: thread}, deleted)
+}
+
+func TestNotificationsReachUser(t *testing.T) {
+	cl := newCluster(t)
+	hub, server := cl.instance(t)
+	t.Cleanup(cl.publisher.Subscribe(hub.Notify))
+	alice, bob := cl.createUser(t), cl.createUser(t)
+	thread := cl.createPost(t, alice).ID
+
+	var conns []*testConn
+	for _, user := range []User{alice, alice, bob} {
+		c := dial(t, server, user)
+		// served once subscribed
+		c.subscribe(thread)
+		conns = append(conns, c)
+	}
+
+	publish := func(n dto.NotificationResponse) {
+		data, err := json.Marshal(n)
+		require.NoError(t, err)
+		require.NoError(t, cl.publisher.Publish(context.Background(), usecase.DomainEvent{Type: usecase.NotificationCreated, Data: data}))
+	}
+	publish(dto.NotificationResponse{ID: 1, UserID: alice.ID})
+	publish(dto.NotificationResponse{ID: 2, UserID: bob.ID})
+
+	for i, id := range []int64{1, 1, 2} {
+		msg := conns[i].expect(TypeNotification, 0)
+		var n dto.NotificationResponse
+		require.NoError(t, json.Unmarshal(msg.Data, &n))
+		require.Equal(t, id, n.ID)
+	}
+}
+
+func TestTyping(t *testing.T) {
+	cl := newCluster(t)
+	_, server := cl.instance(t)
+	alice, bob := cl.createUser(t), cl.createUser(t)
+	thread := cl.createPost(t, alice).ID
+
+	a := dial(t, server, alice)
+	a.subscribe(thread)
+	b := dial(t, server, bob)
+	b.subscribe(thread)
+
+	a.send(TypeTyping, thread)
+	// throttled
+	a.send(TypeTyping, thread)
+	msg := b.expect(TypeTyping, thread)
+	var typing Typing
+	require.NoError(t, json.Unmarshal(msg.Data, &typing))
+	require.Equal(t, alice, typing.User)
+
+	// alice does not hear her own typing, and bob heard it once
+	cl.updatePost(t, thread)
+	a.expect(usecase.PostUpdated, thread)
+	b.expect(usecase.PostUpdated, thread)
+}
+
+func TestPresenceAcrossInstances(t *testing.T) {
+	cl := newCluster(t)
+	_, server1 := cl.instance(t)
+	_, server2 := cl.instance(t)
+	alice, bob := cl.createUser(t), cl.createUser(t)
+	thread := cl.createPost(t, alice).ID
+
+	a := dial(t, server1, alice)
+	a.subscribe(thread)
+	b := dial(t, server2, bob)
+	b.subscribe(thread)
+	// bob's hub learns about alice when it says hello
+	b.expectViewers(thread, alice, bob)
+	a.expectViewers(thread, alice, bob)
+
+	b.send(TypeTyping, thread)
+	a.expect(TypeTyping, thread)
+
+	// both hubs read the shared event log
+	cl.updatePost(t, thread)
+	a.expect(usecase.PostUpdated, thread)
+	b.expect(usecase.PostUpdated, thread)
+
+	b.conn.Close()
+	a.expectViewers(thread, alice)
+}
+
+func TestPresenceResyncsAfterLoss(t *testing.T) {
+	cl := newCluster(t)
+	hub1, server1 := cl.instance(t)
+	_, server2 := cl.instance(t)
+	alice, bob := cl.createUser(t), cl.createUser(t)
+	thread := cl.createPost(t, alice).ID
+
+	a := dial(t, server1, alice)
+	a.subscribe(thread)
+	b := dial(t, server2, bob)
+	b.subscribe(thread)
+	a.expectViewers(thread, alice, bob)
+
+	// as if bob's announcements had been lost
+	hub1.mu.Lock()
+	r := hub1.rooms[thread]
+	r.remote = map[string]remoteViewers{}
+	hub1.updateViewers(thread, r, nil)
+	hub1.mu.Unlock()
+	a.expectViewers(thread, alice)
+
+	cl.ps.Lose()
+	a.expectViewers(thread, alice, bob)
+}
+
+func TestPresenceExpires(t *testing.T) {
+	cl := newCluster(t)
+	hub, server := cl.instance(t)
+	hub.presenceTTL = 0
+	alice := cl.createUser(t)
+	thread := cl.createPost(t, alice).ID
+
+	a := dial(t, server, alice)
+	a.subscribe(thread)
+
+	// an instance that announces a viewer and dies
+	ghost := User{ID: alice.ID + 1, UserStrID: "ghost"}
+	frame, err := json.Marshal(envelope{Kind: kindHere, Instance: "dead", Users: []User{ghost}})
+	require.NoError(t, err)
+	require.NoError(t, cl.ps.Publish(context.Background(), topic(thread), frame))
+	a.expectViewers(thread, alice, ghost)
+
+	hub.refreshPresence(context.Background())
+	a.expectViewers(thread, alice)
+}
+
+func TestErrors(t *testing.T) {
+	cl := newCluster(t)
+	_, server := cl.instance(t)
+	alice := cl.createUser(t)
+	thread := cl.createPost(t, alice).ID
+
+	a := dial(t, server, alice)
+	testCases := []struct {
+		name   string
+		frame  string
+		code   string
+		fields []string
+	}{
+		{"NotJSON", "hello", "validation_failed", []string{"message"}},
+		{"UnknownType", `{"type":"shout","thread":1}`, "validation_failed", []string{"type"}},
+		{"NoThread", `{"type":"subscribe"}`, "validation_failed", []string{"thread"}},
+		{"UnknownThread", `{"type":"subscribe","thread":` + strconv.Itoa(int(thread)+100) + `}`, "post_not_found", nil},
+		{"TypingNotSubscribed", `{"type":"typing","thread":` + strconv.Itoa(int(thread)) + `}`, "not_subscribed", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, a.conn.WriteMessage(websocket.TextMessage, []byte(tc.frame)))
+			msg := a.expect(TypeError, 0)
+			var e Error
+			require.NoError(t, json.Unmarshal(msg.Data, &e))
+			require.Equal(t, tc.code, e.Code)
+			require.NotEmpty(t, e.Message)
+			var fields []string
+			for _, f := range e.Errors {
+				fields = append(fields, f.Field)
+			}
+			require.Equal(t, tc.fields, fields)
+		})
+	}
+
+	// the connection survives errors
+	a.subscribe(thread)
+}
+
+func TestTooManySubscriptions(t *testing.T) {
+	cl := newCluster(t)
+	_, server := cl.instance(t)
+	alice := cl.createUser(t)
+
+	a := dial(t, server, alice)
+	for i := 0; i < maxThreads; i++ {
+		a.subscribe(cl.createPost(t, alice).ID)
+	}
+	a.send(TypeSubscribe, cl.createPost(t, alice).ID)
+	msg := a.expect(TypeError, 0)
+	require.Contains(t, string(msg.Data), ErrTooManySubscriptions.Code)
+}
+
+func TestCloseDisconnects(t *testing.T) {
+	cl := newCluster(t)
+	hub, server := cl.instance(t)
+	alice := cl.createUser(t)
+
+	a := dial(t, server, alice)
+	// wait until the hub has the connection
+	a.send(TypeTyping, 1)
+	a.expect(TypeError, 0)
+	hub.Close()
+
+	a.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := a.conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+
+	// later connections are closed at once
+	b := dial(t, server, alice)
+	b.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = b.conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+}
+
+func TestSlowClientDisconnected(t *testing.T) {
+	c := &client{send: make(chan []byte, 1), done: make(chan struct{})}
+	c.deliver([]byte("1"))
+	c.deliver([]byte("2"))
+
+	select {
+	case <-c.done:
+	default:
+		t.Fatal("client with a full buffer is still connected")
+	}
+}
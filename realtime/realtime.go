@@ -0,0 +1,87 @@
+// Package realtime serves the WebSocket channel at /ws.
+//
+// Clients subscribe to threads and receive the changes made to them, the
+// users currently viewing them and who is typing. A thread is identified by
+// a post, and its changes are those of the post and of its direct replies.
+// Every connection also receives the notifications of its user, wherever
+// they were created.
+//
+// Each connection is served by a reader and a writer goroutine, and the Hub
+// fans messages out to the connections subscribed to a thread. Post changes
+// are read from the post event log, which every instance reads on its own as
+// soon as an event is published. Presence and typing are not stored
+// anywhere: the hubs of all instances exchange them through a pubsub.PubSub.
+package realtime
+
+import (
+	"encoding/json"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+)
+
+// Types of the messages clients send
+const (
+	TypeSubscribe   = "subscribe"
+	TypeUnsubscribe = "unsubscribe"
+	TypeTyping      = "typing"
+)
+
+// Types of the messages sent to clients, besides the post event types of
+// the usecase package
+const (
+	// TypeSubscribed confirms a subscription, with the current viewers
+	TypeSubscribed   = "subscribed"
+	TypeUnsubscribed = "unsubscribed"
+	// TypePresence is sent with the viewers of a thread whenever they change
+	TypePresence = "presence"
+	// TypeNotification is sent, outside of any thread, with every
+	// dto.NotificationResponse of the user
+	TypeNotification = "notification"
+	TypeError        = "error"
+)
+
+// Message is a frame exchanged with clients
+type Message struct {
+	Type   string          `json:"type"`
+	Thread uint            `json:"thread,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// User is someone viewing or typing in a thread
+type User struct {
+	ID        uint   `json:"id"`
+	UserStrID string `json:"user_str_id"`
+}
+
+// Presence is the data of subscribed and presence messages
+type Presence struct {
+	Viewers []User `json:"viewers"`
+}
+
+// Typing is the data of typing messages
+type Typing struct {
+	User User `json:"user"`
+}
+
+// Error is the data of error messages. Code and Errors are the same as in
+// the problem responses of the REST API.
+type Error struct {
+	Code    string           `json:"code"`
+	Message string           `json:"message"`
+	Errors  []dto.FieldError `json:"errors,omitempty"`
+}
+
+var (
+	ErrTooManySubscriptions = &usecase.Error{Kind: usecase.KindValidation, Code: "too_many_subscriptions", Message: "too many subscriptions"}
+	ErrNotSubscribed        = &usecase.Error{Kind: usecase.KindValidation, Code: "not_subscribed", Message: "subscribe to the thread first"}
+)
+
+func newMessage(typ string, thread uint, data interface{}) Message {
+	msg := Message{Type: typ, Thread: thread}
+	if data != nil {
+		// the data types above always marshal
+		msg.Data, _ = json.Marshal(data)
+	}
+	return msg
+}
@@ -0,0 +1,42 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Headers announcing that an endpoint is going away
+const (
+	// HeaderDeprecation is defined by RFC 9745
+	HeaderDeprecation = "Deprecation"
+	// HeaderSunset is defined by RFC 8594
+	HeaderSunset = "Sunset"
+	HeaderLink   = "Link"
+)
+
+// Deprecated marks every response as coming from a deprecated endpoint: the
+// Deprecation header carries since, the Sunset header carries sunset unless
+// it is zero, and a successor-version link points at the same path under
+// successorPrefix.
+func Deprecated(since, sunset time.Time, successorPrefix string) echo.MiddlewareFunc {
+	deprecation := fmt.Sprintf("@%d", since.Unix())
+	var sunsetValue string
+	if !sunset.IsZero() {
+		sunsetValue = sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			header := ctx.Response().Header()
+			header.Set(HeaderDeprecation, deprecation)
+			if sunsetValue != "" {
+				header.Set(HeaderSunset, sunsetValue)
+			}
+			header.Add(HeaderLink, fmt.Sprintf(`<%s%s>; rel="successor-version"`, successorPrefix, ctx.Request().URL.Path))
+			return next(ctx)
+		}
+	}
+}
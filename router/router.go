@@ -1,49 +1,155 @@
 package router
 
 import (
+	"log/slog"
+	"time"
+
 	"github.com/PenginAction/go-BulletinBoard/config"
 	"github.com/PenginAction/go-BulletinBoard/controller"
 	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/logging"
+	"github.com/PenginAction/go-BulletinBoard/metrics"
+	"github.com/PenginAction/go-BulletinBoard/openapi"
+	"github.com/PenginAction/go-BulletinBoard/tracing"
 	"github.com/PenginAction/go-BulletinBoard/utils"
-	"github.com/go-playground/validator"
 	"github.com/golang-jwt/jwt/v5"
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
-func NewRouter(uc controller.IUserController, pc controller.IPostController, cfg config.Config) *echo.Echo {
+// V1Prefix is where version 1 of the API is mounted
+const V1Prefix = "/api/v1"
+
+// legacyDeprecation is when the unversioned paths were deprecated in favour
+// of V1Prefix
+var legacyDeprecation = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+func NewRouter(uc controller.IUserController, pc controller.IPostController, sc controller.IPostStreamController, gc controller.IGraphQLController, wc controller.IRealtimeController, whc controller.IWebhookController, nc controller.INotificationController, tc controller.ITagController, hc controller.IHealthController, cfg config.Config) *echo.Echo {
 	e := echo.New()
+	e.HTTPErrorHandler = controller.HTTPErrorHandler
+	e.Use(tracing.Middleware())
+	e.Use(logging.RequestIDMiddleware())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins:     []string{cfg.FE_URL},
-		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
+		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, echo.HeaderXRequestID, controller.HeaderLastEventID},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
 		AllowCredentials: true,
+		ExposeHeaders:    []string{echo.HeaderXRequestID, HeaderDeprecation, HeaderSunset, HeaderLink},
 	}))
-	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
-		Format: "time=${time_rfc3339_nano}, method=${method}, uri=${uri}, status=${status}\n",
+	// probes hit these every few seconds and would drown the access log
+	e.Use(logging.AccessLog(slog.Default(), func(c echo.Context) bool {
+		return c.Path() == "/healthz" || c.Path() == "/readyz" || c.Path() == "/metrics"
 	}))
-	e.Validator = &utils.CustomValidator{Validator: validator.New()}
+	e.Use(metrics.Middleware())
+	e.Validator = &utils.CustomValidator{Validator: utils.Validator()}
 
-	e.POST("/signup", uc.Signup)
-	e.POST("/login", uc.Login)
-	// r.POST("/logout", uc.Logout)
+	// operational endpoints are not part of the versioned API
+	e.GET("/healthz", hc.Healthz)
+	e.GET("/readyz", hc.Readyz)
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
 
-	p := e.Group("/posts")
+	r := newRoutes(uc, pc, sc, whc, nc, tc, cfg)
+	r.v1(e.Group(V1Prefix))
+	// the unversioned paths predate /api/v1 and stay until the sunset date
+	r.v1(e.Group(""), Deprecated(legacyDeprecation, cfg.LegacyAPISunset, V1Prefix))
+	// GraphQL evolves its schema instead of being versioned
+	e.POST("/graphql", gc.Query, chain(r.auth, r.validate)...)
+	e.GET("/ws", wc.Connect, chain(r.socketAuth, r.validate)...)
+
+	return e
+}
 
-	config := echojwt.Config{
+// routes holds the handlers and middleware the API versions are built from.
+// Middleware is created once here and shared, so mounting another version
+// only has to say which handlers it serves.
+type routes struct {
+	uc controller.IUserController
+	pc controller.IPostController
+	sc controller.IPostStreamController
+	wc controller.IWebhookController
+	nc controller.INotificationController
+	tc controller.ITagController
+	// auth rejects requests without a valid bearer token of a live user
+	auth []echo.MiddlewareFunc
+	// socketAuth is auth for WebSocket upgrades. Browsers cannot set headers
+	// on those, so the token may also come in the access_token parameter.
+	socketAuth []echo.MiddlewareFunc
+	// validate checks requests against the v1 OpenAPI document
+	validate echo.MiddlewareFunc
+}
+
+func newRoutes(uc controller.IUserController, pc controller.IPostController, sc controller.IPostStreamController, wc controller.IWebhookController, nc controller.INotificationController, tc controller.ITagController, cfg config.Config) *routes {
+	doc, err := openapi.Load()
+	if err != nil {
+		// the document is embedded and validated by the openapi tests
+		panic(err)
+	}
+
+	jwtConfig := echojwt.Config{
 		NewClaimsFunc: func(c echo.Context) jwt.Claims {
 			return new(dto.JwtCustomClaims)
 		},
 		SigningKey: []byte(cfg.SECRET),
 	}
+	socketConfig := jwtConfig
+	socketConfig.TokenLookup = "header:Authorization:Bearer ,query:access_token"
 
-	p.Use(echojwt.WithConfig(config))
-	p.GET("", pc.GetAllPosts)
-	p.GET("/:postId", pc.GetPostById)
-	p.POST("", pc.CreatePost)
-	p.PUT("/:postId", pc.UpdatePost)
-	p.DELETE("/:postId", pc.DeletePost)
+	return &routes{
+		uc:         uc,
+		pc:         pc,
+		sc:         sc,
+		wc:         wc,
+		nc:         nc,
+		tc:         tc,
+		auth:       []echo.MiddlewareFunc{echojwt.WithConfig(jwtConfig), uc.Authenticate},
+		socketAuth: []echo.MiddlewareFunc{echojwt.WithConfig(socketConfig), uc.Authenticate},
+		validate:   openapi.RequestValidator(doc),
+	}
+}
 
-	return e
+// v1 mounts version 1 of the API on g. mw runs in front of every route.
+// Middleware is attached per route rather than with g.Use, which would make
+// the group catch every unmatched path under its prefix.
+func (r *routes) v1(g *echo.Group, mw ...echo.MiddlewareFunc) {
+	public := chain(mw, r.validate)
+	// validate after authenticating so anonymous callers get 401, not 400
+	private := chain(chain(mw, r.auth...), r.validate)
+
+	g.GET("/openapi.json", openapi.SpecHandler, mw...)
+	g.GET("/docs", openapi.DocsHandler, mw...)
+
+	g.POST("/signup", r.uc.Signup, public...)
+	g.POST("/login", r.uc.Login, public...)
+	// g.POST("/logout", r.uc.Logout)
+
+	g.GET("/posts", r.pc.GetAllPosts, private...)
+	g.GET("/posts/stream", r.sc.Stream, private...)
+	g.GET("/posts/:postId", r.pc.GetPostById, private...)
+	g.POST("/posts", r.pc.CreatePost, private...)
+	g.PUT("/posts/:postId", r.pc.UpdatePost, private...)
+	g.DELETE("/posts/:postId", r.pc.DeletePost, private...)
+
+	g.GET("/webhooks", r.wc.ListWebhooks, private...)
+	g.POST("/webhooks", r.wc.CreateWebhook, private...)
+	g.GET("/webhooks/:webhookId", r.wc.GetWebhook, private...)
+	g.PUT("/webhooks/:webhookId", r.wc.UpdateWebhook, private...)
+	g.DELETE("/webhooks/:webhookId", r.wc.DeleteWebhook, private...)
+	g.GET("/webhooks/:webhookId/deliveries", r.wc.ListDeliveries, private...)
+	g.POST("/webhooks/:webhookId/deliveries/:deliveryId/redeliver", r.wc.Redeliver, private...)
+
+	g.GET("/me/notifications", r.nc.ListNotifications, private...)
+	g.GET("/me/notifications/unread-count", r.nc.CountUnread, private...)
+	g.POST("/me/notifications/read", r.nc.MarkAllRead, private...)
+	g.POST("/me/notifications/:notificationId/read", r.nc.MarkRead, private...)
+
+	g.GET("/tags/autocomplete", r.tc.Autocomplete, private...)
+	g.GET("/tags/trending", r.tc.Trending, private...)
+	g.GET("/tags/:tag/posts", r.tc.ListPosts, private...)
+}
+
+// chain returns mw followed by more, without sharing mw's backing array
+func chain(mw []echo.MiddlewareFunc, more ...echo.MiddlewareFunc) []echo.MiddlewareFunc {
+	out := make([]echo.MiddlewareFunc, 0, len(mw)+len(more))
+	return append(append(out, mw...), more...)
 }
@@ -0,0 +1,172 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/config"
+	"github.com/PenginAction/go-BulletinBoard/controller"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/graph"
+	"github.com/PenginAction/go-BulletinBoard/health"
+	"github.com/PenginAction/go-BulletinBoard/openapi"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+func newTestRouter() *echo.Echo {
+	uc := controller.NewUserController(nil)
+	pc := controller.NewPostController(nil)
+	hc := controller.NewHealthController(health.NewChecker())
+	return NewRouter(uc, pc, controller.NewPostStreamController(nil, 0, 0), newTestGraphQLController(), controller.NewRealtimeController(nil, nil, ""), controller.NewWebhookController(nil), controller.NewNotificationController(nil), controller.NewTagController(nil), hc, config.Config{SECRET: "secret"})
+}
+
+func newTestGraphQLController() controller.IGraphQLController {
+	server, err := graph.NewServer(nil, nil, graph.Limits{})
+	if err != nil {
+		panic(err)
+	}
+	return controller.NewGraphQLController(server)
+}
+
+// TestRoutesDocumented fails when a route is added to or removed from the
+// router without updating openapi.json. Every versioned route must also be
+// served at its deprecated unversioned alias.
+func TestRoutesDocumented(t *testing.T) {
+	doc, err := openapi.Load()
+	require.NoError(t, err)
+	require.Equal(t, V1Prefix, openapi.BasePath(doc))
+
+	var routes []string
+	for _, r := range newTestRouter().Routes() {
+		if r.Method == echo.RouteNotFound {
+			continue
+		}
+		routes = append(routes, r.Method+" "+openapi.SpecPath(r.Path))
+	}
+	sort.Strings(routes)
+
+	var expected []string
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			if len(item.Servers) > 0 {
+				expected = append(expected, method+" "+strings.TrimSuffix(item.Servers[0].URL, "/")+path)
+				continue
+			}
+			expected = append(expected, method+" "+V1Prefix+path, method+" "+path)
+		}
+	}
+	sort.Strings(expected)
+
+	require.Equal(t, expected, routes)
+}
+
+func TestSpecServed(t *testing.T) {
+	e := newTestRouter()
+
+	for _, path := range []string{"/api/v1/openapi.json", "/api/v1/docs"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code, path)
+	}
+}
+
+func TestRequestsValidatedAgainstSpec(t *testing.T) {
+	e := newTestRouter()
+
+	body := strings.NewReader(`{"user_str_id":"a-b","email":"a@example.com","password":"secret"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/signup", body)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, dto.MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+
+	var problem dto.Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	require.Equal(t, "validation_failed", problem.Code)
+	require.Equal(t, []dto.FieldError{{Field: "user_str_id", Code: "pattern", Message: "user_str_id contains characters that are not allowed"}}, problem.Errors)
+
+	// anonymous requests are rejected before their body is looked at
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/posts", strings.NewReader(`{"text":42}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ viewer { id } }"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestLegacyAliasesDeprecated(t *testing.T) {
+	sunset := time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+	uc := controller.NewUserController(nil)
+	pc := controller.NewPostController(nil)
+	hc := controller.NewHealthController(health.NewChecker())
+	e := NewRouter(uc, pc, controller.NewPostStreamController(nil, 0, 0), newTestGraphQLController(), controller.NewRealtimeController(nil, nil, ""), controller.NewWebhookController(nil), controller.NewNotificationController(nil), controller.NewTagController(nil), hc, config.Config{SECRET: "secret", LegacyAPISunset: sunset})
+
+	cases := []struct {
+		path       string
+		deprecated bool
+	}{
+		{path: "/api/v1/posts?page_id=1&page_size=5"},
+		{path: "/posts?page_id=1&page_size=5", deprecated: true},
+		{path: "/healthz"},
+		{path: "/no/such/path"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if !tc.deprecated {
+				require.Empty(t, rec.Header().Get(HeaderDeprecation))
+				require.Empty(t, rec.Header().Get(HeaderSunset))
+				return
+			}
+			// the headers are sent even when the request itself fails
+			require.Equal(t, http.StatusUnauthorized, rec.Code)
+			require.Equal(t, fmt.Sprintf("@%d", legacyDeprecation.Unix()), rec.Header().Get(HeaderDeprecation))
+			require.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", rec.Header().Get(HeaderSunset))
+			require.Equal(t, `</api/v1/posts>; rel="successor-version"`, rec.Header().Get(HeaderLink))
+		})
+	}
+}
+
+// TestErrorsReachTracing checks that the middlewares inside tracing pass
+// errors on rather than writing the response themselves
+func TestErrorsReachTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	rec := httptest.NewRecorder()
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, V1Prefix+"/nowhere", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Contains(t, spans[0].Attributes(), semconv.HTTPStatusCode(http.StatusNotFound))
+	require.Len(t, spans[0].Events(), 1)
+	require.Equal(t, "exception", spans[0].Events()[0].Name)
+}
@@ -0,0 +1,105 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	pb "github.com/PenginAction/go-BulletinBoard/proto/bulletinboard/v1"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// publicMethods can be called without a token
+var publicMethods = map[string]bool{
+	pb.UserService_SignUp_FullMethodName: true,
+	pb.UserService_Login_FullMethodName:  true,
+}
+
+// authenticator checks the bearer token in the authorization metadata of
+// every call to a method that is not public
+type authenticator struct {
+	uu     usecase.IUserUsecase
+	secret []byte
+}
+
+func (a *authenticator) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	claims, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(withClaims(ctx, claims), req)
+}
+
+func (a *authenticator) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if publicMethods[info.FullMethod] {
+		return handler(srv, ss)
+	}
+	claims, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: ss, ctx: withClaims(ss.Context(), claims)})
+}
+
+// authenticate verifies the token the way the REST API does: the signature
+// and expiry first, then that the user may still use it
+func (a *authenticator) authenticate(ctx context.Context) (*dto.JwtCustomClaims, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, usecase.ErrUnauthorized
+	}
+	raw, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, usecase.ErrUnauthorized
+	}
+
+	claims := new(dto.JwtCustomClaims)
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, usecase.ErrUnauthorized.With(err)
+	}
+
+	if err := a.uu.Authenticate(ctx, claims); err != nil {
+		// same rule as IUserController.Authenticate
+		if e, ok := usecase.AsError(err); ok && e.Kind != usecase.KindUnauthorized {
+			return nil, usecase.ErrUnauthorized.With(err)
+		}
+		return nil, err
+	}
+	return claims, nil
+}
+
+type claimsKey struct{}
+
+func withClaims(ctx context.Context, claims *dto.JwtCustomClaims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// claimsFrom returns the claims of the token the call was authenticated
+// with
+func claimsFrom(ctx context.Context) (*dto.JwtCustomClaims, error) {
+	claims, ok := ctx.Value(claimsKey{}).(*dto.JwtCustomClaims)
+	if !ok {
+		return nil, usecase.ErrUnauthorized
+	}
+	return claims, nil
+}
+
+// serverStream replaces the context of a stream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,141 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+
+	"github.com/PenginAction/go-BulletinBoard/i18n"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	ut "github.com/go-playground/universal-translator"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// errorDomain is the domain of the ErrorInfo attached to every error
+const errorDomain = "bulletinboard"
+
+func unaryErrors(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	res, err := handler(ctx, req)
+	if err != nil {
+		return nil, statusFor(ctx, info.FullMethod, err)
+	}
+	return res, nil
+}
+
+func streamErrors(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := handler(srv, ss); err != nil {
+		return statusFor(ss.Context(), info.FullMethod, err)
+	}
+	return nil
+}
+
+// unaryRecover turns a panic in a handler into an INTERNAL error, so it
+// fails the call instead of the whole server
+func unaryRecover(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(ctx, info.FullMethod, r)
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func streamRecover(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(ss.Context(), info.FullMethod, r)
+		}
+	}()
+	return handler(srv, ss)
+}
+
+func recovered(ctx context.Context, method string, r interface{}) error {
+	slog.ErrorContext(ctx, "grpc handler panicked", slog.String("method", method), slog.Any("panic", r), slog.String("stack", string(debug.Stack())))
+	return newStatus(codes.Internal, "internal_error", "internal server error", nil)
+}
+
+// statusFor describes err to the client. Domain errors keep their code as
+// the reason of an ErrorInfo and their field errors as a BadRequest, with
+// messages translated for the accept-language metadata. Anything else is
+// logged and reported as INTERNAL without detail.
+func statusFor(ctx context.Context, method string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	trans := translator(ctx)
+
+	if e, ok := usecase.AsError(err); ok {
+		var violations []*errdetails.BadRequest_FieldViolation
+		for _, f := range e.Fields {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{
+				Field:       f.Field,
+				Description: i18n.FieldMessage(trans, f),
+			})
+		}
+		return newStatus(kindCode(e.Kind), e.Code, i18n.Message(trans, e.Code, e.Message), violations)
+	}
+
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(verrs))
+		for _, fe := range verrs {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{
+				Field:       fe.Field(),
+				Description: fe.Translate(trans),
+			})
+		}
+		msg := i18n.Message(trans, "validation_failed", "request validation failed")
+		return newStatus(codes.InvalidArgument, "validation_failed", msg, violations)
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	slog.ErrorContext(ctx, "grpc request failed", slog.String("method", method), slog.String("error", err.Error()))
+	return newStatus(codes.Internal, "internal_error", "internal server error", nil)
+}
+
+func newStatus(code codes.Code, reason, msg string, violations []*errdetails.BadRequest_FieldViolation) error {
+	st := status.New(code, msg)
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain}}
+	if len(violations) > 0 {
+		details = append(details, &errdetails.BadRequest{FieldViolations: violations})
+	}
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+func kindCode(kind usecase.Kind) codes.Code {
+	switch kind {
+	case usecase.KindNotFound:
+		return codes.NotFound
+	case usecase.KindConflict:
+		return codes.AlreadyExists
+	case usecase.KindForbidden:
+		return codes.PermissionDenied
+	case usecase.KindUnauthorized:
+		return codes.Unauthenticated
+	case usecase.KindValidation:
+		return codes.InvalidArgument
+	}
+	return codes.Internal
+}
+
+// translator picks the translator for the accept-language metadata, the
+// gRPC counterpart of the Accept-Language header
+func translator(ctx context.Context) ut.Translator {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return i18n.FromAcceptLanguage(strings.Join(md.Get("accept-language"), ","))
+}
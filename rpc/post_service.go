@@ -0,0 +1,191 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	pb "github.com/PenginAction/go-BulletinBoard/proto/bulletinboard/v1"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// postService applies the same ownership rules as the post controller
+type postService struct {
+	pb.UnimplementedPostServiceServer
+	pu   usecase.IPostUsecase
+	feed *usecase.PostFeed
+}
+
+func (s *postService) CreatePost(ctx context.Context, in *pb.CreatePostRequest) (*pb.Post, error) {
+	claims, err := claimsFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := dto.CreatePostRequest{
+		UserID:   claims.ID,
+		Text:     in.GetText(),
+		ParentID: uint(in.GetParentId()),
+		Tags:     in.GetTags(),
+	}
+	if err := utils.Validator().Struct(req); err != nil {
+		return nil, err
+	}
+
+	post, err := s.pu.CreatePost(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return newPost(post), nil
+}
+
+func (s *postService) GetPost(ctx context.Context, in *pb.GetPostRequest) (*pb.Post, error) {
+	claims, err := claimsFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := postID(in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.pu.GetPostById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.UserID != claims.ID && !claims.IsModerator() {
+		return nil, usecase.ErrNotPostOwner
+	}
+	return newPost(post), nil
+}
+
+func (s *postService) ListPosts(ctx context.Context, in *pb.ListPostsRequest) (*pb.ListPostsResponse, error) {
+	req := dto.AllPostsRequest{PageID: in.GetPageId(), PageSize: in.GetPageSize()}
+	if err := utils.Validator().Struct(req); err != nil {
+		return nil, err
+	}
+
+	posts, err := s.pu.GetAllPosts(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	res := &pb.ListPostsResponse{Posts: make([]*pb.Post, 0, len(posts))}
+	for _, post := range posts {
+		res.Posts = append(res.Posts, newPost(post))
+	}
+	return res, nil
+}
+
+func (s *postService) UpdatePost(ctx context.Context, in *pb.UpdatePostRequest) (*pb.Post, error) {
+	claims, err := claimsFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := postID(in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	req := dto.UpdatePostRequest{ID: id, Text: in.GetText()}
+	if err := utils.Validator().Struct(req); err != nil {
+		return nil, err
+	}
+
+	post, err := s.pu.GetPostById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.UserID != claims.ID {
+		return nil, usecase.ErrNotPostOwner
+	}
+
+	post, err = s.pu.UpdatePost(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return newPost(post), nil
+}
+
+func (s *postService) DeletePost(ctx context.Context, in *pb.DeletePostRequest) (*emptypb.Empty, error) {
+	claims, err := claimsFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := postID(in.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.pu.GetPostById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if post.UserID != claims.ID && !claims.IsModerator() {
+		return nil, usecase.ErrNotPostOwner
+	}
+
+	if err := s.pu.DeletePost(ctx, id); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *postService) WatchPosts(in *pb.WatchPostsRequest, stream pb.PostService_WatchPostsServer) error {
+	ctx := stream.Context()
+	posts, cancel := s.feed.Subscribe()
+	defer cancel()
+	// the headers tell the client it will receive every post created from now
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case post, ok := <-posts:
+			if !ok {
+				if s.feed.Closed() {
+					return status.Error(codes.Unavailable, "server is shutting down")
+				}
+				return status.Error(codes.ResourceExhausted, "client fell behind the feed of new posts")
+			}
+			if err := stream.Send(newPost(post)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// postID is the counterpart of the :postId path parameter check
+func postID(id uint64) (uint, error) {
+	if id == 0 {
+		return 0, usecase.NewValidationError("id", "positive_integer", "id must be a positive integer")
+	}
+	return uint(id), nil
+}
+
+func newPost(post dto.PostResponse) *pb.Post {
+	res := &pb.Post{
+		Id:        uint64(post.ID),
+		UserId:    uint64(post.UserID),
+		UserStrId: post.UserStrID,
+		Text:      post.Text,
+		CreatedAt: timestamppb.New(post.CreatedAt),
+		ParentId:  uint64(post.ParentID),
+		Tags:      post.Tags,
+	}
+	for _, m := range post.Mentions {
+		res.Mentions = append(res.Mentions, &pb.MentionEntity{
+			UserId: uint64(m.UserID),
+			Start:  int32(m.Start),
+			End:    int32(m.End),
+		})
+	}
+	return res
+}
@@ -0,0 +1,54 @@
+// Package rpc serves the user and post services over gRPC, on a port of its
+// own next to the REST API.
+//
+// The services call the same usecases the REST controllers do. Tokens are
+// checked by interceptors the same way the JWT middleware and
+// IUserController.Authenticate check them, and domain errors are mapped to
+// status codes in one place, like controller.HTTPErrorHandler does for HTTP.
+// The service definitions live in proto/bulletinboard/v1.
+package rpc
+
+import (
+	"log/slog"
+
+	"github.com/PenginAction/go-BulletinBoard/logging"
+	"github.com/PenginAction/go-BulletinBoard/metrics"
+	pb "github.com/PenginAction/go-BulletinBoard/proto/bulletinboard/v1"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// NewServer returns a gRPC server with the user and post services
+// registered. Tokens are verified with secret. New posts are streamed from
+// feed, which should wrap pu.
+//
+// Calls are traced, counted and logged like HTTP requests, with the status
+// code the client gets. A panic fails only the call it happened in.
+func NewServer(uu usecase.IUserUsecase, pu usecase.IPostUsecase, feed *usecase.PostFeed, secret string) *grpc.Server {
+	a := &authenticator{uu: uu, secret: []byte(secret)}
+
+	s := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			logging.UnaryAccessLog(slog.Default()),
+			metrics.UnaryServerInterceptor(),
+			unaryErrors,
+			unaryRecover,
+			a.unary,
+		),
+		grpc.ChainStreamInterceptor(
+			logging.StreamAccessLog(slog.Default()),
+			metrics.StreamServerInterceptor(),
+			streamErrors,
+			streamRecover,
+			a.stream,
+		),
+	)
+	pb.RegisterUserServiceServer(s, &userService{uu: uu})
+	pb.RegisterPostServiceServer(s, &postService{pu: pu, feed: feed})
+	// lets tools such as grpcurl discover the services
+	reflection.Register(s)
+	return s
+}
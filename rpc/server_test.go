@@ -0,0 +1,482 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/config"
+	"github.com/PenginAction/go-BulletinBoard/controller"
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/graph"
+	"github.com/PenginAction/go-BulletinBoard/health"
+	pb "github.com/PenginAction/go-BulletinBoard/proto/bulletinboard/v1"
+	"github.com/PenginAction/go-BulletinBoard/pubsub"
+	"github.com/PenginAction/go-BulletinBoard/realtime"
+	"github.com/PenginAction/go-BulletinBoard/router"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testEnv serves the same usecases over gRPC and REST, so every test can
+// check that both APIs agree
+type testEnv struct {
+	users pb.UserServiceClient
+	posts pb.PostServiceClient
+	rest  *httptest.Server
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	// tokens are signed with the secret of app.env, like utils.CreateValidToken
+	cfg, err := config.LoadConfig("../")
+	require.NoError(t, err)
+
+	store := memory.NewStore()
+	uu := usecase.NewUserUsecase(store)
+	ps := pubsub.NewMemory()
+	publisher, err := usecase.NewEventPublisher(ps)
+	require.NoError(t, err)
+	events := usecase.NewPostEventLog(usecase.NewPostUsecase(store), store, publisher)
+	feed := usecase.NewPostFeed(events, publisher)
+
+	graphServer, err := graph.NewServer(uu, feed, graph.Limits{})
+	require.NoError(t, err)
+	e := router.NewRouter(
+		controller.NewUserController(uu),
+		controller.NewPostController(feed),
+		controller.NewPostStreamController(events, time.Second, time.Second),
+		controller.NewGraphQLController(graphServer),
+		controller.NewRealtimeController(realtime.NewHub(ps, events, feed), uu, ""),
+		controller.NewWebhookController(usecase.NewWebhookUsecase(store)),
+		controller.NewNotificationController(usecase.NewNotificationUsecase(store, publisher)),
+		controller.NewTagController(usecase.NewTagUsecase(store)),
+		controller.NewHealthController(health.NewChecker()),
+		cfg,
+	)
+	rest := httptest.NewServer(e)
+	t.Cleanup(rest.Close)
+
+	lis := bufconn.Listen(1 << 20)
+	s := NewServer(uu, feed, feed, cfg.SECRET)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return &testEnv{
+		users: pb.NewUserServiceClient(conn),
+		posts: pb.NewPostServiceClient(conn),
+		rest:  rest,
+	}
+}
+
+// signUp registers a random user over gRPC and returns its id and token
+func (env *testEnv) signUp(t *testing.T) (uint64, string) {
+	email, password := utils.RandomEmail(), utils.RandomString(10)
+	res, err := env.users.SignUp(context.Background(), &pb.SignUpRequest{
+		UserStrId: utils.RandomUserStrID(),
+		Email:     email,
+		Password:  password,
+	})
+	require.NoError(t, err)
+
+	login, err := env.users.Login(context.Background(), &pb.LoginRequest{Email: email, Password: password})
+	require.NoError(t, err)
+	return res.GetUser().GetId(), login.GetToken()
+}
+
+// do calls the REST API and decodes the response body into out, unless out
+// is nil
+func (env *testEnv) do(t *testing.T, method, path, token string, body, out interface{}) int {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req, err := http.NewRequest(method, env.rest.URL+router.V1Prefix+path, &buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := env.rest.Client().Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	if out != nil {
+		require.NoError(t, json.NewDecoder(res.Body).Decode(out))
+	}
+	return res.StatusCode
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+// reason returns the reason of the ErrorInfo attached to err
+func reason(t *testing.T, err error) string {
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			require.Equal(t, errorDomain, info.GetDomain())
+			return info.GetReason()
+		}
+	}
+	t.Fatalf("no ErrorInfo in %v", err)
+	return ""
+}
+
+func requirePost(t *testing.T, rest dto.PostResponse, post *pb.Post) {
+	require.Equal(t, uint64(rest.ID), post.GetId())
+	require.Equal(t, uint64(rest.UserID), post.GetUserId())
+	require.Equal(t, rest.UserStrID, post.GetUserStrId())
+	require.Equal(t, rest.Text, post.GetText())
+	require.WithinDuration(t, rest.CreatedAt, post.GetCreatedAt().AsTime(), time.Microsecond)
+	require.Equal(t, uint64(rest.ParentID), post.GetParentId())
+	require.Len(t, post.GetMentions(), len(rest.Mentions))
+	for i, m := range rest.Mentions {
+		require.Equal(t, uint64(m.UserID), post.GetMentions()[i].GetUserId())
+		require.Equal(t, int32(m.Start), post.GetMentions()[i].GetStart())
+		require.Equal(t, int32(m.End), post.GetMentions()[i].GetEnd())
+	}
+	require.Equal(t, rest.Tags, post.GetTags())
+}
+
+func TestUsersParity(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	userStrID, email, password := utils.RandomUserStrID(), utils.RandomEmail(), utils.RandomString(10)
+	res, err := env.users.SignUp(ctx, &pb.SignUpRequest{UserStrId: userStrID, Email: email, Password: password})
+	require.NoError(t, err)
+	require.NotZero(t, res.GetUser().GetId())
+	require.Equal(t, userStrID, res.GetUser().GetUserStrId())
+	require.Equal(t, email, res.GetUser().GetEmail())
+
+	var login dto.LoginResponse
+	code := env.do(t, http.MethodPost, "/login", "", dto.LoginRequest{Email: email, Password: password}, &login)
+	require.Equal(t, http.StatusOK, code)
+	require.NotEmpty(t, login.Token)
+
+	// a token issued by either API is accepted by the other
+	_, err = env.posts.ListPosts(withToken(login.Token), &pb.ListPostsRequest{PageId: 1, PageSize: 10})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		rest   func() (int, dto.Problem)
+		grpc   func() error
+		status int
+		code   codes.Code
+	}{
+		{
+			name: "DuplicateUser",
+			rest: func() (int, dto.Problem) {
+				var p dto.Problem
+				return env.do(t, http.MethodPost, "/signup", "", dto.CreateUserRequest{UserStrID: userStrID, Email: email, Password: password}, &p), p
+			},
+			grpc: func() error {
+				_, err := env.users.SignUp(ctx, &pb.SignUpRequest{UserStrId: userStrID, Email: email, Password: password})
+				return err
+			},
+			status: http.StatusConflict,
+			code:   codes.AlreadyExists,
+		},
+		{
+			name: "WrongPassword",
+			rest: func() (int, dto.Problem) {
+				var p dto.Problem
+				return env.do(t, http.MethodPost, "/login", "", dto.LoginRequest{Email: email, Password: "wrong-password"}, &p), p
+			},
+			grpc: func() error {
+				_, err := env.users.Login(ctx, &pb.LoginRequest{Email: email, Password: "wrong-password"})
+				return err
+			},
+			status: http.StatusUnauthorized,
+			code:   codes.Unauthenticated,
+		},
+		{
+			name: "InvalidEmail",
+			rest: func() (int, dto.Problem) {
+				var p dto.Problem
+				return env.do(t, http.MethodPost, "/signup", "", dto.CreateUserRequest{UserStrID: utils.RandomUserStrID(), Email: "invalid", Password: password}, &p), p
+			},
+			grpc: func() error {
+				_, err := env.users.SignUp(ctx, &pb.SignUpRequest{UserStrId: utils.RandomUserStrID(), Email: "invalid", Password: password})
+				return err
+			},
+			status: http.StatusBadRequest,
+			code:   codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, problem := tc.rest()
+			require.Equal(t, tc.status, code)
+
+			err := tc.grpc()
+			require.Equal(t, tc.code, status.Code(err))
+			// OpenAPI validation rejects some requests before the usecase,
+			// under a code of its own
+			if problem.Code != "validation_failed" && problem.Code != "invalid_request" {
+				require.Equal(t, problem.Code, reason(t, err))
+			}
+		})
+	}
+}
+
+func TestPostsParity(t *testing.T) {
+	env := newTestEnv(t)
+	_, token := env.signUp(t)
+	ctx := withToken(token)
+
+	created, err := env.posts.CreatePost(ctx, &pb.CreatePostRequest{Text: "from grpc"})
+	require.NoError(t, err)
+
+	var post dto.PostResponse
+	require.Equal(t, http.StatusOK, env.do(t, http.MethodGet, fmt.Sprintf("/posts/%d", created.GetId()), token, nil, &post))
+	requirePost(t, post, created)
+
+	require.Equal(t, http.StatusCreated, env.do(t, http.MethodPost, "/posts", token, map[string]string{"text": "from rest"}, &post))
+	got, err := env.posts.GetPost(ctx, &pb.GetPostRequest{Id: uint64(post.ID)})
+	require.NoError(t, err)
+	requirePost(t, post, got)
+
+	reply, err := env.posts.CreatePost(ctx, &pb.CreatePostRequest{
+		Text:     "reply to @" + created.GetUserStrId(),
+		ParentId: created.GetId(),
+		Tags:     []string{"#Go", "grpc"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, created.GetId(), reply.GetParentId())
+	require.Len(t, reply.GetMentions(), 1)
+	require.Equal(t, []string{"go", "grpc"}, reply.GetTags())
+	require.Equal(t, http.StatusOK, env.do(t, http.MethodGet, fmt.Sprintf("/posts/%d", reply.GetId()), token, nil, &post))
+	requirePost(t, post, reply)
+
+	var posts []dto.PostResponse
+	require.Equal(t, http.StatusOK, env.do(t, http.MethodGet, "/posts?page_id=1&page_size=10", token, nil, &posts))
+	list, err := env.posts.ListPosts(ctx, &pb.ListPostsRequest{PageId: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Len(t, list.GetPosts(), len(posts))
+	for i := range posts {
+		requirePost(t, posts[i], list.GetPosts()[i])
+	}
+
+	updated, err := env.posts.UpdatePost(ctx, &pb.UpdatePostRequest{Id: created.GetId(), Text: "updated"})
+	require.NoError(t, err)
+	// parent_id, mentions and tags are omitted when empty, so decode into
+	// a fresh value
+	post = dto.PostResponse{}
+	require.Equal(t, http.StatusOK, env.do(t, http.MethodGet, fmt.Sprintf("/posts/%d", created.GetId()), token, nil, &post))
+	requirePost(t, post, updated)
+
+	require.Equal(t, http.StatusNoContent, env.do(t, http.MethodDelete, fmt.Sprintf("/posts/%d", created.GetId()), token, nil, nil))
+	_, err = env.posts.GetPost(ctx, &pb.GetPostRequest{Id: created.GetId()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestPostsErrorParity(t *testing.T) {
+	env := newTestEnv(t)
+	_, owner := env.signUp(t)
+	_, other := env.signUp(t)
+
+	post, err := env.posts.CreatePost(withToken(owner), &pb.CreatePostRequest{Text: "mine"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   interface{}
+		grpc   func(ctx context.Context) error
+		status int
+		code   codes.Code
+	}{
+		{
+			name:   "NoToken",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/posts/%d", post.GetId()),
+			grpc: func(ctx context.Context) error {
+				_, err := env.posts.GetPost(ctx, &pb.GetPostRequest{Id: post.GetId()})
+				return err
+			},
+			status: http.StatusUnauthorized,
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "InvalidToken",
+			method: http.MethodGet,
+			path:   fmt.Sprintf("/posts/%d", post.GetId()),
+			token:  "invalid",
+			grpc: func(ctx context.Context) error {
+				_, err := env.posts.GetPost(ctx, &pb.GetPostRequest{Id: post.GetId()})
+				return err
+			},
+			status: http.StatusUnauthorized,
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "NotOwner",
+			method: http.MethodPut,
+			path:   fmt.Sprintf("/posts/%d", post.GetId()),
+			token:  other,
+			body:   map[string]string{"text": "theirs"},
+			grpc: func(ctx context.Context) error {
+				_, err := env.posts.UpdatePost(ctx, &pb.UpdatePostRequest{Id: post.GetId(), Text: "theirs"})
+				return err
+			},
+			status: http.StatusForbidden,
+			code:   codes.PermissionDenied,
+		},
+		{
+			name:   "NotFound",
+			method: http.MethodDelete,
+			path:   fmt.Sprintf("/posts/%d", post.GetId()+1000),
+			token:  owner,
+			grpc: func(ctx context.Context) error {
+				_, err := env.posts.DeletePost(ctx, &pb.DeletePostRequest{Id: post.GetId() + 1000})
+				return err
+			},
+			status: http.StatusNotFound,
+			code:   codes.NotFound,
+		},
+		{
+			name:   "EmptyText",
+			method: http.MethodPost,
+			path:   "/posts",
+			token:  owner,
+			body:   map[string]string{"text": ""},
+			grpc: func(ctx context.Context) error {
+				_, err := env.posts.CreatePost(ctx, &pb.CreatePostRequest{Text: ""})
+				return err
+			},
+			status: http.StatusBadRequest,
+			code:   codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.status, env.do(t, tc.method, tc.path, tc.token, tc.body, nil))
+
+			ctx := context.Background()
+			if tc.token != "" {
+				ctx = withToken(tc.token)
+			}
+			require.Equal(t, tc.code, status.Code(tc.grpc(ctx)))
+		})
+	}
+}
+
+func TestValidationDetails(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, err := env.users.SignUp(context.Background(), &pb.SignUpRequest{UserStrId: utils.RandomUserStrID(), Email: "invalid", Password: "secret"})
+	require.Equal(t, "validation_failed", reason(t, err))
+
+	st, _ := status.FromError(err)
+	var fields []string
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				fields = append(fields, v.GetField())
+				require.NotEmpty(t, v.GetDescription())
+			}
+		}
+	}
+	require.Equal(t, []string{"email"}, fields)
+}
+
+func TestWatchPosts(t *testing.T) {
+	env := newTestEnv(t)
+	_, token := env.signUp(t)
+
+	ctx, cancel := context.WithTimeout(withToken(token), 5*time.Second)
+	defer cancel()
+	stream, err := env.posts.WatchPosts(ctx, &pb.WatchPostsRequest{})
+	require.NoError(t, err)
+	// wait until the server has subscribed
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	var post dto.PostResponse
+	require.Equal(t, http.StatusCreated, env.do(t, http.MethodPost, "/posts", token, map[string]string{"text": "watched"}, &post))
+
+	got, err := stream.Recv()
+	require.NoError(t, err)
+	requirePost(t, post, got)
+}
+
+func TestWatchPostsUnauthenticated(t *testing.T) {
+	env := newTestEnv(t)
+
+	stream, err := env.posts.WatchPosts(context.Background(), &pb.WatchPostsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestCallsTraced(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	env := newTestEnv(t)
+
+	var header metadata.MD
+	_, err := env.users.Login(context.Background(), &pb.LoginRequest{Email: utils.RandomEmail(), Password: "secret"}, grpc.Header(&header))
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.Len(t, header.Get("x-request-id"), 1)
+
+	// the usecases join the trace of the call
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	login, server := spans[0], spans[1]
+	require.Equal(t, "bulletinboard.v1.UserService/Login", server.Name())
+	require.Equal(t, trace.SpanKindServer, server.SpanKind())
+	require.Equal(t, "UserUsecase.Login", login.Name())
+	require.Equal(t, server.SpanContext().SpanID(), login.Parent().SpanID())
+}
+
+func TestRecoverPanics(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: pb.PostService_GetPost_FullMethodName}
+	_, err := unaryRecover(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	require.Equal(t, codes.Internal, status.Code(err))
+	require.Equal(t, "internal_error", reason(t, err))
+
+	err = streamRecover(nil, &serverStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: pb.PostService_WatchPosts_FullMethodName}, func(srv interface{}, ss grpc.ServerStream) error {
+		panic("boom")
+	})
+	require.Equal(t, codes.Internal, status.Code(err))
+}
@@ -0,0 +1,54 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	pb "github.com/PenginAction/go-BulletinBoard/proto/bulletinboard/v1"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type userService struct {
+	pb.UnimplementedUserServiceServer
+	uu usecase.IUserUsecase
+}
+
+func (s *userService) SignUp(ctx context.Context, in *pb.SignUpRequest) (*pb.SignUpResponse, error) {
+	req := dto.CreateUserRequest{
+		UserStrID: in.GetUserStrId(),
+		Email:     in.GetEmail(),
+		Password:  in.GetPassword(),
+	}
+	if err := utils.Validator().Struct(req); err != nil {
+		return nil, err
+	}
+
+	user, err := s.uu.SignUp(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.SignUpResponse{User: &pb.User{
+		Id:        uint64(user.ID),
+		UserStrId: user.UserStrID,
+		Email:     user.Email,
+		CreatedAt: timestamppb.New(user.CreatedAt),
+	}}, nil
+}
+
+func (s *userService) Login(ctx context.Context, in *pb.LoginRequest) (*pb.LoginResponse, error) {
+	req := dto.LoginRequest{
+		Email:    in.GetEmail(),
+		Password: in.GetPassword(),
+	}
+	if err := utils.Validator().Struct(req); err != nil {
+		return nil, err
+	}
+
+	token, err := s.uu.Login(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.LoginResponse{Token: token}, nil
+}
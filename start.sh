@@ -3,7 +3,7 @@
 set -e
 
 echo "run db migration"
-/app/migrate -path /app/migration -database "$DB_SOURCE" -verbose up
+/app/main migrate up
 
 echo "start the app"
 exec "$@"
\ No newline at end of file
@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware starts a server span for every request, continuing the trace
+// given in the traceparent header, and stores it in the request context so
+// usecases and the Store join the same trace. It writes the response of
+// errors returned further in, so it must be the outermost middleware.
+func Middleware() echo.MiddlewareFunc {
+	tracer := Tracer()
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			ctx, span := tracer.Start(ctx, fmt.Sprintf("%s %s", req.Method, route),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethod(req.Method),
+					semconv.HTTPRoute(route),
+				),
+			)
+			defer span.End()
+			c.SetRequest(req.WithContext(ctx))
+
+			err := next(c)
+			if err != nil {
+				// let echo write the error response so the status is final
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			if err != nil {
+				span.RecordError(err)
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Store wraps a db.Store and creates a client span for every query
+type Store struct {
+	next   db.Store
+	tracer trace.Tracer
+	system attribute.KeyValue
+}
+
+var _ db.Store = (*Store)(nil)
+
+// NewStore returns next instrumented with a span per query.
+// driver is the DB_DRIVER next talks to.
+func NewStore(next db.Store, driver string) db.Store {
+	system := semconv.DBSystemOtherSQL
+	switch driver {
+	case "postgres":
+		system = semconv.DBSystemPostgreSQL
+	case "sqlite":
+		system = semconv.DBSystemSqlite
+	}
+	return &Store{next: next, tracer: Tracer(), system: system}
+}
+
+// ExecTx spans the transaction as a whole, and the queries fn runs in it
+func (s *Store) ExecTx(ctx context.Context, fn func(db.Store) error) (err error) {
+	ctx, span := s.start(ctx, "ExecTx")
+	defer func() { end(span, err) }()
+	return s.next.ExecTx(ctx, func(tx db.Store) error {
+		return fn(&Store{next: tx, tracer: s.tracer, system: s.system})
+	})
+}
+
+func (s *Store) ClaimJobs(ctx context.Context, arg db.ClaimJobsParams) (jobs []db.Job, err error) {
+	ctx, span := s.start(ctx, "ClaimJobs")
+	defer func() { end(span, err) }()
+	return s.next.ClaimJobs(ctx, arg)
+}
+
+func (s *Store) ClaimWebhookDeliveries(ctx context.Context, arg db.ClaimWebhookDeliveriesParams) (deliveries []db.WebhookDelivery, err error) {
+	ctx, span := s.start(ctx, "ClaimWebhookDeliveries")
+	defer func() { end(span, err) }()
+	return s.next.ClaimWebhookDeliveries(ctx, arg)
+}
+
+func (s *Store) CountUnreadNotifications(ctx context.Context, userID uint) (n int64, err error) {
+	ctx, span := s.start(ctx, "CountUnreadNotifications")
+	defer func() { end(span, err) }()
+	return s.next.CountUnreadNotifications(ctx, userID)
+}
+
+func (s *Store) CreateJob(ctx context.Context, arg db.CreateJobParams) (job db.Job, err error) {
+	ctx, span := s.start(ctx, "CreateJob")
+	defer func() { end(span, err) }()
+	return s.next.CreateJob(ctx, arg)
+}
+
+func (s *Store) CreateNotification(ctx context.Context, arg db.CreateNotificationParams) (notification db.Notification, err error) {
+	ctx, span := s.start(ctx, "CreateNotification")
+	defer func() { end(span, err) }()
+	return s.next.CreateNotification(ctx, arg)
+}
+
+func (s *Store) CreatePostMention(ctx context.Context, arg db.CreatePostMentionParams) (err error) {
+	ctx, span := s.start(ctx, "CreatePostMention")
+	defer func() { end(span, err) }()
+	return s.next.CreatePostMention(ctx, arg)
+}
+
+func (s *Store) CreatePostTag(ctx context.Context, arg db.CreatePostTagParams) (err error) {
+	ctx, span := s.start(ctx, "CreatePostTag")
+	defer func() { end(span, err) }()
+	return s.next.CreatePostTag(ctx, arg)
+}
+
+func (s *Store) CreateWebhook(ctx context.Context, arg db.CreateWebhookParams) (webhook db.Webhook, err error) {
+	ctx, span := s.start(ctx, "CreateWebhook")
+	defer func() { end(span, err) }()
+	return s.next.CreateWebhook(ctx, arg)
+}
+
+func (s *Store) CreateWebhookDelivery(ctx context.Context, arg db.CreateWebhookDeliveryParams) (delivery db.WebhookDelivery, err error) {
+	ctx, span := s.start(ctx, "CreateWebhookDelivery")
+	defer func() { end(span, err) }()
+	return s.next.CreateWebhookDelivery(ctx, arg)
+}
+
+func (s *Store) DeletePostMentions(ctx context.Context, postID uint) (err error) {
+	ctx, span := s.start(ctx, "DeletePostMentions")
+	defer func() { end(span, err) }()
+	return s.next.DeletePostMentions(ctx, postID)
+}
+
+func (s *Store) DeletePostTags(ctx context.Context, postID uint) (err error) {
+	ctx, span := s.start(ctx, "DeletePostTags")
+	defer func() { end(span, err) }()
+	return s.next.DeletePostTags(ctx, postID)
+}
+
+func (s *Store) DeleteSucceededJobs(ctx context.Context, before time.Time) (n int64, err error) {
+	ctx, span := s.start(ctx, "DeleteSucceededJobs")
+	defer func() { end(span, err) }()
+	return s.next.DeleteSucceededJobs(ctx, before)
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, id int64) (err error) {
+	ctx, span := s.start(ctx, "DeleteWebhook")
+	defer func() { end(span, err) }()
+	return s.next.DeleteWebhook(ctx, id)
+}
+
+func (s *Store) GetJob(ctx context.Context, id int64) (job db.Job, err error) {
+	ctx, span := s.start(ctx, "GetJob")
+	defer func() { end(span, err) }()
+	return s.next.GetJob(ctx, id)
+}
+
+func (s *Store) GetNotification(ctx context.Context, id int64) (notification db.Notification, err error) {
+	ctx, span := s.start(ctx, "GetNotification")
+	defer func() { end(span, err) }()
+	return s.next.GetNotification(ctx, id)
+}
+
+func (s *Store) GetWebhook(ctx context.Context, id int64) (webhook db.Webhook, err error) {
+	ctx, span := s.start(ctx, "GetWebhook")
+	defer func() { end(span, err) }()
+	return s.next.GetWebhook(ctx, id)
+}
+
+func (s *Store) GetWebhookDelivery(ctx context.Context, id int64) (delivery db.WebhookDelivery, err error) {
+	ctx, span := s.start(ctx, "GetWebhookDelivery")
+	defer func() { end(span, err) }()
+	return s.next.GetWebhookDelivery(ctx, id)
+}
+
+func (s *Store) ListEnabledWebhooks(ctx context.Context) (webhooks []db.Webhook, err error) {
+	ctx, span := s.start(ctx, "ListEnabledWebhooks")
+	defer func() { end(span, err) }()
+	return s.next.ListEnabledWebhooks(ctx)
+}
+
+func (s *Store) ListJobsByStatus(ctx context.Context, arg db.ListJobsByStatusParams) (jobs []db.Job, err error) {
+	ctx, span := s.start(ctx, "ListJobsByStatus")
+	defer func() { end(span, err) }()
+	return s.next.ListJobsByStatus(ctx, arg)
+}
+
+func (s *Store) ListNotifications(ctx context.Context, arg db.ListNotificationsParams) (notifications []db.Notification, err error) {
+	ctx, span := s.start(ctx, "ListNotifications")
+	defer func() { end(span, err) }()
+	return s.next.ListNotifications(ctx, arg)
+}
+
+func (s *Store) ListPostMentions(ctx context.Context, postIds []int64) (mentions []db.PostMention, err error) {
+	ctx, span := s.start(ctx, "ListPostMentions")
+	defer func() { end(span, err) }()
+	return s.next.ListPostMentions(ctx, postIds)
+}
+
+func (s *Store) ListPostTags(ctx context.Context, postIds []int64) (tags []db.ListPostTagsRow, err error) {
+	ctx, span := s.start(ctx, "ListPostTags")
+	defer func() { end(span, err) }()
+	return s.next.ListPostTags(ctx, postIds)
+}
+
+func (s *Store) ListPostsByTag(ctx context.Context, arg db.ListPostsByTagParams) (posts []db.Post, err error) {
+	ctx, span := s.start(ctx, "ListPostsByTag")
+	defer func() { end(span, err) }()
+	return s.next.ListPostsByTag(ctx, arg)
+}
+
+func (s *Store) ListRepliesByParentIDs(ctx context.Context, arg db.ListRepliesByParentIDsParams) (posts []db.Post, err error) {
+	ctx, span := s.start(ctx, "ListRepliesByParentIDs")
+	defer func() { end(span, err) }()
+	return s.next.ListRepliesByParentIDs(ctx, arg)
+}
+
+func (s *Store) ListReplies(ctx context.Context, arg db.ListRepliesParams) (posts []db.Post, err error) {
+	ctx, span := s.start(ctx, "ListReplies")
+	defer func() { end(span, err) }()
+	return s.next.ListReplies(ctx, arg)
+}
+
+func (s *Store) ListTrendingTags(ctx context.Context, arg db.ListTrendingTagsParams) (tags []db.ListTrendingTagsRow, err error) {
+	ctx, span := s.start(ctx, "ListTrendingTags")
+	defer func() { end(span, err) }()
+	return s.next.ListTrendingTags(ctx, arg)
+}
+
+func (s *Store) ListWebhookDeliveries(ctx context.Context, arg db.ListWebhookDeliveriesParams) (deliveries []db.WebhookDelivery, err error) {
+	ctx, span := s.start(ctx, "ListWebhookDeliveries")
+	defer func() { end(span, err) }()
+	return s.next.ListWebhookDeliveries(ctx, arg)
+}
+
+func (s *Store) ListWebhooksByUser(ctx context.Context, userID uint) (webhooks []db.Webhook, err error) {
+	ctx, span := s.start(ctx, "ListWebhooksByUser")
+	defer func() { end(span, err) }()
+	return s.next.ListWebhooksByUser(ctx, userID)
+}
+
+func (s *Store) MarkAllNotificationsRead(ctx context.Context, arg db.MarkAllNotificationsReadParams) (n int64, err error) {
+	ctx, span := s.start(ctx, "MarkAllNotificationsRead")
+	defer func() { end(span, err) }()
+	return s.next.MarkAllNotificationsRead(ctx, arg)
+}
+
+func (s *Store) MarkNotificationRead(ctx context.Context, arg db.MarkNotificationReadParams) (notification db.Notification, err error) {
+	ctx, span := s.start(ctx, "MarkNotificationRead")
+	defer func() { end(span, err) }()
+	return s.next.MarkNotificationRead(ctx, arg)
+}
+
+func (s *Store) RecordWebhookFailure(ctx context.Context, arg db.RecordWebhookFailureParams) (webhook db.Webhook, err error) {
+	ctx, span := s.start(ctx, "RecordWebhookFailure")
+	defer func() { end(span, err) }()
+	return s.next.RecordWebhookFailure(ctx, arg)
+}
+
+func (s *Store) RecordWebhookSuccess(ctx context.Context, id int64) (webhook db.Webhook, err error) {
+	ctx, span := s.start(ctx, "RecordWebhookSuccess")
+	defer func() { end(span, err) }()
+	return s.next.RecordWebhookSuccess(ctx, id)
+}
+
+func (s *Store) ReleaseJob(ctx context.Context, arg db.ReleaseJobParams) (err error) {
+	ctx, span := s.start(ctx, "ReleaseJob")
+	defer func() { end(span, err) }()
+	return s.next.ReleaseJob(ctx, arg)
+}
+
+func (s *Store) RequeueJob(ctx context.Context, arg db.RequeueJobParams) (job db.Job, err error) {
+	ctx, span := s.start(ctx, "RequeueJob")
+	defer func() { end(span, err) }()
+	return s.next.RequeueJob(ctx, arg)
+}
+
+func (s *Store) SearchTags(ctx context.Context, arg db.SearchTagsParams) (tags []db.SearchTagsRow, err error) {
+	ctx, span := s.start(ctx, "SearchTags")
+	defer func() { end(span, err) }()
+	return s.next.SearchTags(ctx, arg)
+}
+
+func (s *Store) UpdateWebhook(ctx context.Context, arg db.UpdateWebhookParams) (webhook db.Webhook, err error) {
+	ctx, span := s.start(ctx, "UpdateWebhook")
+	defer func() { end(span, err) }()
+	return s.next.UpdateWebhook(ctx, arg)
+}
+
+func (s *Store) UpdateWebhookDelivery(ctx context.Context, arg db.UpdateWebhookDeliveryParams) (delivery db.WebhookDelivery, err error) {
+	ctx, span := s.start(ctx, "UpdateWebhookDelivery")
+	defer func() { end(span, err) }()
+	return s.next.UpdateWebhookDelivery(ctx, arg)
+}
+
+func (s *Store) UpsertTag(ctx context.Context, name string) (tag db.Tag, err error) {
+	ctx, span := s.start(ctx, "UpsertTag")
+	defer func() { end(span, err) }()
+	return s.next.UpsertTag(ctx, name)
+}
+
+func (s *Store) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "db."+query,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(s.system, semconv.DBOperation(query)),
+	)
+}
+
+// end finishes span. Not found is an expected answer, not a failure.
+func end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *Store) CreatePost(ctx context.Context, arg db.CreatePostParams) (post db.Post, err error) {
+	ctx, span := s.start(ctx, "CreatePost")
+	defer func() { end(span, err) }()
+	return s.next.CreatePost(ctx, arg)
+}
+
+func (s *Store) CreatePostEvent(ctx context.Context, arg db.CreatePostEventParams) (event db.PostEvent, err error) {
+	ctx, span := s.start(ctx, "CreatePostEvent")
+	defer func() { end(span, err) }()
+	return s.next.CreatePostEvent(ctx, arg)
+}
+
+func (s *Store) CreateUser(ctx context.Context, arg db.CreateUserParams) (user db.User, err error) {
+	ctx, span := s.start(ctx, "CreateUser")
+	defer func() { end(span, err) }()
+	return s.next.CreateUser(ctx, arg)
+}
+
+func (s *Store) DeletePost(ctx context.Context, id uint) (err error) {
+	ctx, span := s.start(ctx, "DeletePost")
+	defer func() { end(span, err) }()
+	return s.next.DeletePost(ctx, id)
+}
+
+func (s *Store) DeletePostsByUser(ctx context.Context, userID uint) (posts []db.Post, err error) {
+	ctx, span := s.start(ctx, "DeletePostsByUser")
+	defer func() { end(span, err) }()
+	return s.next.DeletePostsByUser(ctx, userID)
+}
+
+func (s *Store) DeletePostsCreatedBefore(ctx context.Context, createdAt time.Time) (posts []db.Post, err error) {
+	ctx, span := s.start(ctx, "DeletePostsCreatedBefore")
+	defer func() { end(span, err) }()
+	return s.next.DeletePostsCreatedBefore(ctx, createdAt)
+}
+
+func (s *Store) DeleteUser(ctx context.Context, id uint) (err error) {
+	ctx, span := s.start(ctx, "DeleteUser")
+	defer func() { end(span, err) }()
+	return s.next.DeleteUser(ctx, id)
+}
+
+func (s *Store) GetLastPostEventID(ctx context.Context) (id int64, err error) {
+	ctx, span := s.start(ctx, "GetLastPostEventID")
+	defer func() { end(span, err) }()
+	return s.next.GetLastPostEventID(ctx)
+}
+
+func (s *Store) GetPost(ctx context.Context, id uint) (post db.Post, err error) {
+	ctx, span := s.start(ctx, "GetPost")
+	defer func() { end(span, err) }()
+	return s.next.GetPost(ctx, id)
+}
+
+func (s *Store) GetUser(ctx context.Context, id uint) (user db.User, err error) {
+	ctx, span := s.start(ctx, "GetUser")
+	defer func() { end(span, err) }()
+	return s.next.GetUser(ctx, id)
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (user db.User, err error) {
+	ctx, span := s.start(ctx, "GetUserByEmail")
+	defer func() { end(span, err) }()
+	return s.next.GetUserByEmail(ctx, email)
+}
+
+func (s *Store) GetUserByUserStrId(ctx context.Context, userStrID string) (user db.User, err error) {
+	ctx, span := s.start(ctx, "GetUserByUserStrId")
+	defer func() { end(span, err) }()
+	return s.next.GetUserByUserStrId(ctx, userStrID)
+}
+
+func (s *Store) GetUserStrIdById(ctx context.Context, id uint) (userStrID string, err error) {
+	ctx, span := s.start(ctx, "GetUserStrIdById")
+	defer func() { end(span, err) }()
+	return s.next.GetUserStrIdById(ctx, id)
+}
+
+func (s *Store) ListPostEventsAfter(ctx context.Context, arg db.ListPostEventsAfterParams) (events []db.PostEvent, err error) {
+	ctx, span := s.start(ctx, "ListPostEventsAfter")
+	defer func() { end(span, err) }()
+	return s.next.ListPostEventsAfter(ctx, arg)
+}
+
+func (s *Store) ListPosts(ctx context.Context, arg db.ListPostsParams) (posts []db.Post, err error) {
+	ctx, span := s.start(ctx, "ListPosts")
+	defer func() { end(span, err) }()
+	return s.next.ListPosts(ctx, arg)
+}
+
+func (s *Store) ListUsers(ctx context.Context, arg db.ListUsersParams) (users []db.User, err error) {
+	ctx, span := s.start(ctx, "ListUsers")
+	defer func() { end(span, err) }()
+	return s.next.ListUsers(ctx, arg)
+}
+
+func (s *Store) ListUsersByIDs(ctx context.Context, ids []int64) (users []db.User, err error) {
+	ctx, span := s.start(ctx, "ListUsersByIDs")
+	defer func() { end(span, err) }()
+	return s.next.ListUsersByIDs(ctx, ids)
+}
+
+func (s *Store) RevokeUserTokens(ctx context.Context, id uint) (user db.User, err error) {
+	ctx, span := s.start(ctx, "RevokeUserTokens")
+	defer func() { end(span, err) }()
+	return s.next.RevokeUserTokens(ctx, id)
+}
+
+func (s *Store) UpdatePost(ctx context.Context, arg db.UpdatePostParams) (post db.Post, err error) {
+	ctx, span := s.start(ctx, "UpdatePost")
+	defer func() { end(span, err) }()
+	return s.next.UpdatePost(ctx, arg)
+}
+
+func (s *Store) UpdateUser(ctx context.Context, arg db.UpdateUserParams) (user db.User, err error) {
+	ctx, span := s.start(ctx, "UpdateUser")
+	defer func() { end(span, err) }()
+	return s.next.UpdateUser(ctx, arg)
+}
+
+func (s *Store) UpdateUserLockedAt(ctx context.Context, arg db.UpdateUserLockedAtParams) (user db.User, err error) {
+	ctx, span := s.start(ctx, "UpdateUserLockedAt")
+	defer func() { end(span, err) }()
+	return s.next.UpdateUserLockedAt(ctx, arg)
+}
+
+func (s *Store) UpdateUserRole(ctx context.Context, arg db.UpdateUserRoleParams) (user db.User, err error) {
+	ctx, span := s.start(ctx, "UpdateUserRole")
+	defer func() { end(span, err) }()
+	return s.next.UpdateUserRole(ctx, arg)
+}
@@ -0,0 +1,92 @@
+// Package tracing sets up OpenTelemetry tracing: the tracer provider and
+// exporter selected in config.Config, W3C trace context propagation, an Echo
+// middleware creating a span per request and a tracing db.Store decorator.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/PenginAction/go-BulletinBoard/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName identifies the spans created by this module
+const InstrumentationName = "github.com/PenginAction/go-BulletinBoard"
+
+const serviceName = "bulletin-board"
+
+// Exporters selectable with TRACING_EXPORTER
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Tracer returns the tracer used for spans of this module
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Setup installs the global tracer provider and propagator. The returned
+// function flushes pending spans and must be called before exiting.
+//
+// Propagation is set up even when no exporter is configured, so trace
+// context received from callers is still passed on.
+func Setup(ctx context.Context, cfg config.Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.TracingExporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		var err error
+		exporter, err = stdouttrace.New()
+		if err != nil {
+			return nil, err
+		}
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{}
+		if cfg.OTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.OTLPEndpoint))
+		}
+		if cfg.OTLPInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		var err error
+		exporter, err = otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.TracingExporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// follow the caller's decision when there is one
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TracingSampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PenginAction/go-BulletinBoard/config"
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+	return recorder
+}
+
+func TestMiddlewareContinuesTrace(t *testing.T) {
+	recorder := newRecorder(t)
+	store := NewStore(memory.NewStore(), "memory")
+
+	e := echo.New()
+	e.Use(Middleware())
+	e.GET("/posts/:postId", func(c echo.Context) error {
+		_, err := store.GetPost(c.Request().Context(), 1)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/posts/1", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	query, server := spans[0], spans[1]
+
+	require.Equal(t, "GET /posts/:postId", server.Name())
+	require.Equal(t, trace.SpanKindServer, server.SpanKind())
+	require.Equal(t, traceID, server.SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	// 4xx are the client's fault
+	require.Equal(t, codes.Unset, server.Status().Code)
+
+	require.Equal(t, "db.GetPost", query.Name())
+	require.Equal(t, server.SpanContext().SpanID(), query.Parent().SpanID())
+	// not found is not an error of the query
+	require.Equal(t, codes.Unset, query.Status().Code)
+}
+
+func TestStoreRecordsErrors(t *testing.T) {
+	recorder := newRecorder(t)
+	store := NewStore(memory.NewStore(), "memory")
+
+	_, err := store.ListPosts(context.Background(), db.ListPostsParams{Limit: -1})
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Len(t, spans[0].Events(), 1)
+}
+
+func TestSetup(t *testing.T) {
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+
+	for _, exporter := range []string{"", ExporterNone, ExporterStdout} {
+		shutdown, err := Setup(context.Background(), config.Config{TracingExporter: exporter, TracingSampleRatio: 1})
+		require.NoError(t, err)
+		require.NoError(t, shutdown(context.Background()))
+	}
+
+	_, err := Setup(context.Background(), config.Config{TracingExporter: "zipkin"})
+	require.Error(t, err)
+}
@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+)
+
+// Kind classifies an Error by what the caller did wrong, independent of
+// the transport. The controller layer maps kinds to HTTP status codes.
+type Kind int
+
+const (
+	KindNotFound Kind = iota + 1
+	KindConflict
+	KindForbidden
+	KindUnauthorized
+	KindValidation
+)
+
+// Error is a domain error with a stable, machine readable code.
+// Errors of any other type are treated as internal failures.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []dto.FieldError
+	// Err is the underlying cause. It is only logged, never shown to clients.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is match any *Error with the same code, so sentinels can
+// be returned wrapped with a cause or with field details
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// With returns a copy of e wrapping cause
+func (e *Error) With(cause error) *Error {
+	c := *e
+	c.Err = cause
+	return &c
+}
+
+// NewValidationError returns a validation error for a single field. params
+// fill in the translated message after the field name, see dto.FieldError.
+func NewValidationError(field, code, message string, params ...string) *Error {
+	return &Error{
+		Kind:    KindValidation,
+		Code:    "validation_failed",
+		Message: "request validation failed",
+		Fields:  []dto.FieldError{{Field: field, Code: code, Message: message, Params: params}},
+	}
+}
+
+// AsError returns the *Error in err's chain, if any
+func AsError(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+// notFound translates a missing row into the domain error sentinel and
+// passes every other error through
+func notFound(err error, sentinel *Error) error {
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return sentinel.With(err)
+	}
+	return err
+}
+
+// conflictFields names the request field behind each unique constraint, so a
+// duplicate can be reported against the input the client has to change
+var conflictFields = map[string]string{
+	"users_email_key":       "email",
+	"users_user_str_id_key": "user_str_id",
+}
+
+// classify translates database errors the client can act on into domain
+// errors and passes every other error through
+func classify(err error) error {
+	switch db.ErrorCode(err) {
+	case db.UniqueViolation:
+		e := ErrAlreadyExists.With(err)
+		if field, ok := conflictFields[db.ErrorConstraint(err)]; ok {
+			e.Fields = []dto.FieldError{{Field: field, Code: "taken", Message: field + " is already taken"}}
+		}
+		return e
+	}
+	return err
+}
+
+var (
+	ErrPostNotFound  = &Error{Kind: KindNotFound, Code: "post_not_found", Message: "post not found"}
+	ErrUserNotFound  = &Error{Kind: KindNotFound, Code: "user_not_found", Message: "user not found"}
+	ErrUnauthorized  = &Error{Kind: KindUnauthorized, Code: "unauthorized", Message: "authentication required"}
+	ErrInvalidRole   = &Error{Kind: KindValidation, Code: "invalid_role", Message: "invalid role"}
+	ErrUserLocked    = &Error{Kind: KindForbidden, Code: "user_locked", Message: "user is locked"}
+	ErrTokenRevoked  = &Error{Kind: KindUnauthorized, Code: "token_revoked", Message: "token has been revoked"}
+	ErrUserHasPosts  = &Error{Kind: KindConflict, Code: "user_has_posts", Message: "user still has posts"}
+	ErrInvalidFilter = &Error{Kind: KindValidation, Code: "invalid_filter", Message: "exactly one of user and before must be given"}
+	ErrNotPostOwner  = &Error{Kind: KindForbidden, Code: "not_post_owner", Message: "post belongs to another user"}
+	ErrAlreadyExists = &Error{Kind: KindConflict, Code: "already_exists", Message: "already exists"}
+	// ErrWebhookNotFound is also returned for the webhooks of other users
+	ErrWebhookNotFound  = &Error{Kind: KindNotFound, Code: "webhook_not_found", Message: "webhook not found"}
+	ErrDeliveryNotFound = &Error{Kind: KindNotFound, Code: "delivery_not_found", Message: "webhook delivery not found"}
+	ErrWebhookDisabled  = &Error{Kind: KindConflict, Code: "webhook_disabled", Message: "webhook is disabled"}
+	// ErrNotificationNotFound is also returned for the notifications of
+	// other users
+	ErrNotificationNotFound = &Error{Kind: KindNotFound, Code: "notification_not_found", Message: "notification not found"}
+	// ErrInvalidCredentials is returned for both an unknown email and a wrong
+	// password, so login cannot be used to find out who has an account
+	ErrInvalidCredentials = &Error{Kind: KindUnauthorized, Code: "invalid_credentials", Message: "email or password is incorrect"}
+
+	errUnknownParent = NewValidationError("parent_id", "unknown_post", "parent_id names an unknown post")
+	errInvalidTags   = NewValidationError("tags", "invalid_tag", "tags must be letters, digits and underscores, with a letter")
+)
@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/PenginAction/go-BulletinBoard/pubsub"
+)
+
+// eventTopic is the topic domain events are published to
+const eventTopic = "events"
+
+// EventsMissed is the type of the event subscribers get when events may have
+// been lost on the way, for example while the connection carrying them was
+// down. They should catch up from the store.
+const EventsMissed = "events.missed"
+
+// DomainEvent is a change made on any instance of the server
+type DomainEvent struct {
+	Type   string          `json:"type"`
+	PostID uint            `json:"post_id,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	// Truncated is set when Data was dropped because the event was too
+	// large for the transport. Subscribers load the data themselves.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// EventPublisher publishes domain events to every instance sharing its
+// PubSub, and hands the events published by any of them to the subscribers
+// in this process
+type EventPublisher struct {
+	ps          pubsub.PubSub
+	unsubscribe func()
+
+	mu   sync.RWMutex
+	subs map[*eventSubscription]struct{}
+}
+
+type eventSubscription struct {
+	fn func(DomainEvent)
+}
+
+// NewEventPublisher returns a publisher exchanging events through ps
+func NewEventPublisher(ps pubsub.PubSub) (*EventPublisher, error) {
+	p := &EventPublisher{
+		ps:   ps,
+		subs: map[*eventSubscription]struct{}{},
+	}
+	unsubscribe, err := ps.Subscribe(eventTopic, p.receive)
+	if err != nil {
+		return nil, err
+	}
+	p.unsubscribe = unsubscribe
+	return p, nil
+}
+
+// Publish sends e to the subscribers of every instance, this one included.
+// An event too large for the transport is sent without its data.
+func (p *EventPublisher) Publish(c context.Context, e DomainEvent) error {
+	msg, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	err = p.ps.Publish(c, eventTopic, msg)
+	if !errors.Is(err, pubsub.ErrTooLarge) {
+		return err
+	}
+
+	e.Data = nil
+	e.Truncated = true
+	if msg, err = json.Marshal(e); err != nil {
+		return err
+	}
+	return p.ps.Publish(c, eventTopic, msg)
+}
+
+// Subscribe calls fn with every event published from now on, until the
+// returned function is called. fn must not block.
+func (p *EventPublisher) Subscribe(fn func(DomainEvent)) func() {
+	sub := &eventSubscription{fn: fn}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subs[sub] = struct{}{}
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.subs, sub)
+	}
+}
+
+// Close stops receiving events
+func (p *EventPublisher) Close() {
+	p.unsubscribe()
+}
+
+func (p *EventPublisher) receive(msg []byte) {
+	var e DomainEvent
+	if msg == nil {
+		e.Type = EventsMissed
+	} else if err := json.Unmarshal(msg, &e); err != nil {
+		slog.Error("cannot decode domain event", slog.String("error", err.Error()))
+		return
+	}
+
+	p.mu.RLock()
+	subs := make([]*eventSubscription, 0, len(p.subs))
+	for sub := range p.subs {
+		subs = append(subs, sub)
+	}
+	p.mu.RUnlock()
+
+	for _, sub := range subs {
+		sub.fn(e)
+	}
+}
@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/PenginAction/go-BulletinBoard/pubsub"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPublisher(t *testing.T) *EventPublisher {
+	publisher, err := NewEventPublisher(pubsub.NewMemory())
+	require.NoError(t, err)
+	t.Cleanup(publisher.Close)
+	return publisher
+}
+
+func TestEventPublisherAcrossInstances(t *testing.T) {
+	ps := pubsub.NewMemory()
+	publisher1, err := NewEventPublisher(ps)
+	require.NoError(t, err)
+	publisher2, err := NewEventPublisher(ps)
+	require.NoError(t, err)
+
+	var received1, received2 []DomainEvent
+	publisher1.Subscribe(func(e DomainEvent) { received1 = append(received1, e) })
+	unsubscribe := publisher2.Subscribe(func(e DomainEvent) { received2 = append(received2, e) })
+
+	event := DomainEvent{Type: PostCreated, PostID: 2, Data: json.RawMessage(`{"id":2}`)}
+	require.NoError(t, publisher1.Publish(context.Background(), event))
+	require.Equal(t, []DomainEvent{event}, received1)
+	require.Equal(t, []DomainEvent{event}, received2)
+
+	unsubscribe()
+	publisher2.Close()
+	require.NoError(t, publisher2.Publish(context.Background(), event))
+	require.Len(t, received1, 2)
+	require.Len(t, received2, 1)
+}
+
+func TestEventPublisherTruncatesLargeEvents(t *testing.T) {
+	publisher := newTestPublisher(t)
+	var received []DomainEvent
+	publisher.Subscribe(func(e DomainEvent) { received = append(received, e) })
+
+	data, err := json.Marshal(strings.Repeat("a", 10000))
+	require.NoError(t, err)
+	require.NoError(t, publisher.Publish(context.Background(), DomainEvent{Type: PostCreated, PostID: 2, Data: data}))
+	require.Equal(t, []DomainEvent{{Type: PostCreated, PostID: 2, Truncated: true}}, received)
+}
+
+func TestEventPublisherReportsMissedEvents(t *testing.T) {
+	ps := pubsub.NewMemory()
+	publisher, err := NewEventPublisher(ps)
+	require.NoError(t, err)
+	defer publisher.Close()
+
+	var received []DomainEvent
+	publisher.Subscribe(func(e DomainEvent) { received = append(received, e) })
+
+	ps.Lose()
+	require.Equal(t, []DomainEvent{{Type: EventsMissed}}, received)
+}
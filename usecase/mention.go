@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+)
+
+// maxMentions bounds how many users a post can mention, so a post cannot
+// be used to notify everyone
+const maxMentions = 10
+
+// mentionPattern matches @ followed by a user_str_id, which is
+// alphanumeric, unless the @ is part of a word, as in an email address
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9]+)\b`)
+
+// mention is an @user_str_id in a text. Start and end are offsets in
+// code points, the @ included and end excluded.
+type mention struct {
+	userStrID  string
+	start, end int
+}
+
+// parseMentions returns every mention in text of the first maxMentions
+// user_str_ids mentioned
+func parseMentions(text string) []mention {
+	if !strings.Contains(text, "@") {
+		return nil
+	}
+	var mentions []mention
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		name := text[m[2]:m[3]]
+		if !seen[name] {
+			if len(seen) == maxMentions {
+				continue
+			}
+			seen[name] = true
+		}
+		// the @ is the byte before the name
+		start := utf8.RuneCountInString(text[:m[2]-1])
+		mentions = append(mentions, mention{
+			userStrID: name,
+			start:     start,
+			end:       start + 1 + len(name),
+		})
+	}
+	return mentions
+}
+
+// linkMentions stores the mentions in the text of post of the users that
+// exist, and returns them. known maps the user_str_ids the post mentioned
+// before to the users they were resolved to then, so the mentions of users
+// renamed since stay theirs.
+func linkMentions(c context.Context, tx db.Querier, post db.Post, known map[string]uint) ([]dto.MentionEntity, error) {
+	var entities []dto.MentionEntity
+	ids := map[string]uint{}
+	for _, m := range parseMentions(post.Text) {
+		id, ok := ids[m.userStrID]
+		if !ok {
+			if id, ok = known[m.userStrID]; !ok {
+				user, err := tx.GetUserByUserStrId(c, m.userStrID)
+				if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+					return nil, err
+				}
+				id = user.ID
+			}
+			ids[m.userStrID] = id
+		}
+		if id == 0 {
+			continue
+		}
+		err := tx.CreatePostMention(c, db.CreatePostMentionParams{
+			PostID:      post.ID,
+			UserID:      id,
+			StartOffset: int32(m.start),
+			EndOffset:   int32(m.end),
+		})
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, newMentionEntity(db.PostMention{UserID: id, StartOffset: int32(m.start), EndOffset: int32(m.end)}))
+	}
+	return entities, nil
+}
+
+// knownMentions maps the user_str_ids text mentions to the users of
+// mentions, as they were resolved when the text was stored
+func knownMentions(text string, mentions []db.PostMention) map[string]uint {
+	known := map[string]uint{}
+	runes := []rune(text)
+	for _, m := range mentions {
+		if int(m.EndOffset) <= len(runes) && m.StartOffset < m.EndOffset {
+			// the name, without the @
+			known[string(runes[m.StartOffset+1:m.EndOffset])] = m.UserID
+		}
+	}
+	return known
+}
+
+func newMentionEntity(mention db.PostMention) dto.MentionEntity {
+	return dto.MentionEntity{
+		UserID: mention.UserID,
+		Start:  int(mention.StartOffset),
+		End:    int(mention.EndOffset),
+	}
+}
@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMentions(t *testing.T) {
+	require.Equal(t, []mention{
+		{userStrID: "bob", start: 0, end: 4},
+		{userStrID: "Carol2", start: 10, end: 17},
+		{userStrID: "bob", start: 19, end: 23},
+	}, parseMentions("@bob, hí (@Carol2) @bob a@b.com @@dave @e_f"))
+	require.Empty(t, parseMentions("no mentions @ all"))
+
+	text := ""
+	for i := 0; i < maxMentions+5; i++ {
+		text += " @user" + string(rune('a'+i))
+	}
+	text += " @usera"
+	mentions := parseMentions(text)
+	require.Len(t, mentions, maxMentions+1)
+	require.Equal(t, "usera", mentions[maxMentions].userStrID)
+}
+
+func TestPostMentions(t *testing.T) {
+	store := memory.NewStore()
+	pu := NewPostUsecase(store)
+	alice, bob := createTestUser(t, store, dto.RoleUser), createTestUser(t, store, dto.RoleUser)
+	ctx := context.Background()
+
+	text := "hé @" + bob.UserStrID + " @nobody"
+	post, err := pu.CreatePost(ctx, dto.CreatePostRequest{UserID: alice.ID, Text: text})
+	require.NoError(t, err)
+	want := []dto.MentionEntity{{UserID: bob.ID, Start: 3, End: 4 + len(bob.UserStrID)}}
+	require.Equal(t, want, post.Mentions)
+
+	got, err := pu.GetPostById(ctx, post.ID)
+	require.NoError(t, err)
+	require.Equal(t, want, got.Mentions)
+
+	// renaming bob keeps the mention his, even when the post is edited
+	renamed := "renamed" + bob.UserStrID
+	_, err = store.UpdateUser(ctx, db.UpdateUserParams{ID: bob.ID, UserStrID: renamed, Email: bob.Email, Password: bob.Password})
+	require.NoError(t, err)
+	got, err = pu.GetPostById(ctx, post.ID)
+	require.NoError(t, err)
+	require.Equal(t, want, got.Mentions)
+
+	updated, err := pu.UpdatePost(ctx, dto.UpdatePostRequest{ID: post.ID, Text: "ok " + text + " @" + renamed})
+	require.NoError(t, err)
+	require.Equal(t, []dto.MentionEntity{
+		{UserID: bob.ID, Start: 6, End: 7 + len(bob.UserStrID)},
+		{UserID: bob.ID, Start: 16 + len(bob.UserStrID), End: 17 + len(bob.UserStrID) + len(renamed)},
+	}, updated.Mentions)
+
+	posts, err := pu.ListPosts(ctx, dto.ListPostsRequest{Limit: 100})
+	require.NoError(t, err)
+	for _, p := range posts {
+		if p.ID == post.ID {
+			require.Equal(t, updated.Mentions, p.Mentions)
+		}
+	}
+
+	// mentions go with the text
+	updated, err = pu.UpdatePost(ctx, dto.UpdatePostRequest{ID: post.ID, Text: "no one"})
+	require.NoError(t, err)
+	require.Empty(t, updated.Mentions)
+	mentions, err := store.ListPostMentions(ctx, []int64{int64(post.ID)})
+	require.NoError(t, err)
+	require.Empty(t, mentions)
+}
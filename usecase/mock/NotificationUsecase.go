@@ -0,0 +1,110 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: usecase/notification_usecase.go
+
+// Package mock_usecase is a generated GoMock package.
+package mock_usecase
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dto "github.com/PenginAction/go-BulletinBoard/dto"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockINotificationUsecase is a mock of INotificationUsecase interface.
+type MockINotificationUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockINotificationUsecaseMockRecorder
+}
+
+// MockINotificationUsecaseMockRecorder is the mock recorder for MockINotificationUsecase.
+type MockINotificationUsecaseMockRecorder struct {
+	mock *MockINotificationUsecase
+}
+
+// NewMockINotificationUsecase creates a new mock instance.
+func NewMockINotificationUsecase(ctrl *gomock.Controller) *MockINotificationUsecase {
+	mock := &MockINotificationUsecase{ctrl: ctrl}
+	mock.recorder = &MockINotificationUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockINotificationUsecase) EXPECT() *MockINotificationUsecaseMockRecorder {
+	return m.recorder
+}
+
+// CountUnread mocks base method.
+func (m *MockINotificationUsecase) CountUnread(c context.Context, userID uint) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUnread", c, userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUnread indicates an expected call of CountUnread.
+func (mr *MockINotificationUsecaseMockRecorder) CountUnread(c, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUnread", reflect.TypeOf((*MockINotificationUsecase)(nil).CountUnread), c, userID)
+}
+
+// ListNotifications mocks base method.
+func (m *MockINotificationUsecase) ListNotifications(c context.Context, req dto.ListNotificationsRequest) ([]dto.NotificationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNotifications", c, req)
+	ret0, _ := ret[0].([]dto.NotificationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNotifications indicates an expected call of ListNotifications.
+func (mr *MockINotificationUsecaseMockRecorder) ListNotifications(c, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNotifications", reflect.TypeOf((*MockINotificationUsecase)(nil).ListNotifications), c, req)
+}
+
+// MarkAllRead mocks base method.
+func (m *MockINotificationUsecase) MarkAllRead(c context.Context, userID uint) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkAllRead", c, userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkAllRead indicates an expected call of MarkAllRead.
+func (mr *MockINotificationUsecaseMockRecorder) MarkAllRead(c, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAllRead", reflect.TypeOf((*MockINotificationUsecase)(nil).MarkAllRead), c, userID)
+}
+
+// MarkRead mocks base method.
+func (m *MockINotificationUsecase) MarkRead(c context.Context, userID uint, id int64) (dto.NotificationResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkRead", c, userID, id)
+	ret0, _ := ret[0].(dto.NotificationResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkRead indicates an expected call of MarkRead.
+func (mr *MockINotificationUsecaseMockRecorder) MarkRead(c, userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkRead", reflect.TypeOf((*MockINotificationUsecase)(nil).MarkRead), c, userID, id)
+}
+
+// NotifyPost mocks base method.
+func (m *MockINotificationUsecase) NotifyPost(c context.Context, post dto.PostResponse) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NotifyPost", c, post)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// NotifyPost indicates an expected call of NotifyPost.
+func (mr *MockINotificationUsecaseMockRecorder) NotifyPost(c, post interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotifyPost", reflect.TypeOf((*MockINotificationUsecase)(nil).NotifyPost), c, post)
+}
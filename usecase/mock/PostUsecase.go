@@ -94,6 +94,51 @@ func (mr *MockIPostUsecaseMockRecorder) GetPostById(c, id interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPostById", reflect.TypeOf((*MockIPostUsecase)(nil).GetPostById), c, id)
 }
 
+// ListPosts mocks base method.
+func (m *MockIPostUsecase) ListPosts(c context.Context, req dto.ListPostsRequest) ([]dto.PostResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPosts", c, req)
+	ret0, _ := ret[0].([]dto.PostResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPosts indicates an expected call of ListPosts.
+func (mr *MockIPostUsecaseMockRecorder) ListPosts(c, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPosts", reflect.TypeOf((*MockIPostUsecase)(nil).ListPosts), c, req)
+}
+
+// ListReplies mocks base method.
+func (m *MockIPostUsecase) ListReplies(c context.Context, req dto.ListRepliesRequest) (map[uint][]dto.PostResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListReplies", c, req)
+	ret0, _ := ret[0].(map[uint][]dto.PostResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListReplies indicates an expected call of ListReplies.
+func (mr *MockIPostUsecaseMockRecorder) ListReplies(c, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListReplies", reflect.TypeOf((*MockIPostUsecase)(nil).ListReplies), c, req)
+}
+
+// PurgePosts mocks base method.
+func (m *MockIPostUsecase) PurgePosts(c context.Context, req dto.PurgePostsRequest) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgePosts", c, req)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgePosts indicates an expected call of PurgePosts.
+func (mr *MockIPostUsecaseMockRecorder) PurgePosts(c, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgePosts", reflect.TypeOf((*MockIPostUsecase)(nil).PurgePosts), c, req)
+}
+
 // UpdatePost mocks base method.
 func (m *MockIPostUsecase) UpdatePost(c context.Context, req dto.UpdatePostRequest) (dto.PostResponse, error) {
 	m.ctrl.T.Helper()
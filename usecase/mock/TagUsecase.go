@@ -0,0 +1,81 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: usecase/tag_usecase.go
+
+// Package mock_usecase is a generated GoMock package.
+package mock_usecase
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dto "github.com/PenginAction/go-BulletinBoard/dto"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockITagUsecase is a mock of ITagUsecase interface.
+type MockITagUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockITagUsecaseMockRecorder
+}
+
+// MockITagUsecaseMockRecorder is the mock recorder for MockITagUsecase.
+type MockITagUsecaseMockRecorder struct {
+	mock *MockITagUsecase
+}
+
+// NewMockITagUsecase creates a new mock instance.
+func NewMockITagUsecase(ctrl *gomock.Controller) *MockITagUsecase {
+	mock := &MockITagUsecase{ctrl: ctrl}
+	mock.recorder = &MockITagUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockITagUsecase) EXPECT() *MockITagUsecaseMockRecorder {
+	return m.recorder
+}
+
+// ListPostsByTag mocks base method.
+func (m *MockITagUsecase) ListPostsByTag(c context.Context, req dto.ListTagPostsRequest) ([]dto.PostResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPostsByTag", c, req)
+	ret0, _ := ret[0].([]dto.PostResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPostsByTag indicates an expected call of ListPostsByTag.
+func (mr *MockITagUsecaseMockRecorder) ListPostsByTag(c, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPostsByTag", reflect.TypeOf((*MockITagUsecase)(nil).ListPostsByTag), c, req)
+}
+
+// SearchTags mocks base method.
+func (m *MockITagUsecase) SearchTags(c context.Context, req dto.SearchTagsRequest) ([]dto.TagResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchTags", c, req)
+	ret0, _ := ret[0].([]dto.TagResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchTags indicates an expected call of SearchTags.
+func (mr *MockITagUsecaseMockRecorder) SearchTags(c, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchTags", reflect.TypeOf((*MockITagUsecase)(nil).SearchTags), c, req)
+}
+
+// TrendingTags mocks base method.
+func (m *MockITagUsecase) TrendingTags(c context.Context, req dto.TrendingTagsRequest) ([]dto.TagResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrendingTags", c, req)
+	ret0, _ := ret[0].([]dto.TagResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TrendingTags indicates an expected call of TrendingTags.
+func (mr *MockITagUsecaseMockRecorder) TrendingTags(c, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrendingTags", reflect.TypeOf((*MockITagUsecase)(nil).TrendingTags), c, req)
+}
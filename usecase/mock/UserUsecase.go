@@ -35,6 +35,49 @@ func (m *MockIUserUsecase) EXPECT() *MockIUserUsecaseMockRecorder {
 	return m.recorder
 }
 
+// Authenticate mocks base method.
+func (m *MockIUserUsecase) Authenticate(c context.Context, claims *dto.JwtCustomClaims) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", c, claims)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockIUserUsecaseMockRecorder) Authenticate(c, claims interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockIUserUsecase)(nil).Authenticate), c, claims)
+}
+
+// DeleteUser mocks base method.
+func (m *MockIUserUsecase) DeleteUser(c context.Context, userStrID string, purgePosts bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", c, userStrID, purgePosts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockIUserUsecaseMockRecorder) DeleteUser(c, userStrID, purgePosts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockIUserUsecase)(nil).DeleteUser), c, userStrID, purgePosts)
+}
+
+// GetUsersByIDs mocks base method.
+func (m *MockIUserUsecase) GetUsersByIDs(c context.Context, ids []uint) ([]dto.UserResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsersByIDs", c, ids)
+	ret0, _ := ret[0].([]dto.UserResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsersByIDs indicates an expected call of GetUsersByIDs.
+func (mr *MockIUserUsecaseMockRecorder) GetUsersByIDs(c, ids interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersByIDs", reflect.TypeOf((*MockIUserUsecase)(nil).GetUsersByIDs), c, ids)
+}
+
 // Login mocks base method.
 func (m *MockIUserUsecase) Login(c context.Context, req dto.LoginRequest) (string, error) {
 	m.ctrl.T.Helper()
@@ -50,6 +93,51 @@ func (mr *MockIUserUsecaseMockRecorder) Login(c, req interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockIUserUsecase)(nil).Login), c, req)
 }
 
+// RevokeTokens mocks base method.
+func (m *MockIUserUsecase) RevokeTokens(c context.Context, userStrID string) (dto.UserResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeTokens", c, userStrID)
+	ret0, _ := ret[0].(dto.UserResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeTokens indicates an expected call of RevokeTokens.
+func (mr *MockIUserUsecaseMockRecorder) RevokeTokens(c, userStrID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeTokens", reflect.TypeOf((*MockIUserUsecase)(nil).RevokeTokens), c, userStrID)
+}
+
+// SetLocked mocks base method.
+func (m *MockIUserUsecase) SetLocked(c context.Context, userStrID string, locked bool) (dto.UserResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetLocked", c, userStrID, locked)
+	ret0, _ := ret[0].(dto.UserResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetLocked indicates an expected call of SetLocked.
+func (mr *MockIUserUsecaseMockRecorder) SetLocked(c, userStrID, locked interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLocked", reflect.TypeOf((*MockIUserUsecase)(nil).SetLocked), c, userStrID, locked)
+}
+
+// SetRole mocks base method.
+func (m *MockIUserUsecase) SetRole(c context.Context, userStrID, role string) (dto.UserResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRole", c, userStrID, role)
+	ret0, _ := ret[0].(dto.UserResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetRole indicates an expected call of SetRole.
+func (mr *MockIUserUsecaseMockRecorder) SetRole(c, userStrID, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRole", reflect.TypeOf((*MockIUserUsecase)(nil).SetRole), c, userStrID, role)
+}
+
 // SignUp mocks base method.
 func (m *MockIUserUsecase) SignUp(c context.Context, req dto.CreateUserRequest) (dto.CreateUserResponse, error) {
 	m.ctrl.T.Helper()
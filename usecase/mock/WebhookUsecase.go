@@ -0,0 +1,154 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: usecase/webhook_usecase.go
+
+// Package mock_usecase is a generated GoMock package.
+package mock_usecase
+
+import (
+	context "context"
+	reflect "reflect"
+
+	dto "github.com/PenginAction/go-BulletinBoard/dto"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIWebhookUsecase is a mock of IWebhookUsecase interface.
+type MockIWebhookUsecase struct {
+	ctrl     *gomock.Controller
+	recorder *MockIWebhookUsecaseMockRecorder
+}
+
+// MockIWebhookUsecaseMockRecorder is the mock recorder for MockIWebhookUsecase.
+type MockIWebhookUsecaseMockRecorder struct {
+	mock *MockIWebhookUsecase
+}
+
+// NewMockIWebhookUsecase creates a new mock instance.
+func NewMockIWebhookUsecase(ctrl *gomock.Controller) *MockIWebhookUsecase {
+	mock := &MockIWebhookUsecase{ctrl: ctrl}
+	mock.recorder = &MockIWebhookUsecaseMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIWebhookUsecase) EXPECT() *MockIWebhookUsecaseMockRecorder {
+	return m.recorder
+}
+
+// CreateWebhook mocks base method.
+func (m *MockIWebhookUsecase) CreateWebhook(c context.Context, req dto.CreateWebhookRequest) (dto.WebhookResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhook", c, req)
+	ret0, _ := ret[0].(dto.WebhookResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhook indicates an expected call of CreateWebhook.
+func (mr *MockIWebhookUsecaseMockRecorder) CreateWebhook(c, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhook", reflect.TypeOf((*MockIWebhookUsecase)(nil).CreateWebhook), c, req)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockIWebhookUsecase) DeleteWebhook(c context.Context, userID uint, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", c, userID, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockIWebhookUsecaseMockRecorder) DeleteWebhook(c, userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockIWebhookUsecase)(nil).DeleteWebhook), c, userID, id)
+}
+
+// Dispatch mocks base method.
+func (m *MockIWebhookUsecase) Dispatch(c context.Context, typ string, ownerID uint, data interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Dispatch", c, typ, ownerID, data)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Dispatch indicates an expected call of Dispatch.
+func (mr *MockIWebhookUsecaseMockRecorder) Dispatch(c, typ, ownerID, data interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Dispatch", reflect.TypeOf((*MockIWebhookUsecase)(nil).Dispatch), c, typ, ownerID, data)
+}
+
+// GetWebhook mocks base method.
+func (m *MockIWebhookUsecase) GetWebhook(c context.Context, userID uint, id int64) (dto.WebhookResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhook", c, userID, id)
+	ret0, _ := ret[0].(dto.WebhookResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhook indicates an expected call of GetWebhook.
+func (mr *MockIWebhookUsecaseMockRecorder) GetWebhook(c, userID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhook", reflect.TypeOf((*MockIWebhookUsecase)(nil).GetWebhook), c, userID, id)
+}
+
+// ListWebhookDeliveries mocks base method.
+func (m *MockIWebhookUsecase) ListWebhookDeliveries(c context.Context, req dto.ListWebhookDeliveriesRequest) ([]dto.WebhookDeliveryResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhookDeliveries", c, req)
+	ret0, _ := ret[0].([]dto.WebhookDeliveryResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhookDeliveries indicates an expected call of ListWebhookDeliveries.
+func (mr *MockIWebhookUsecaseMockRecorder) ListWebhookDeliveries(c, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhookDeliveries", reflect.TypeOf((*MockIWebhookUsecase)(nil).ListWebhookDeliveries), c, req)
+}
+
+// ListWebhooks mocks base method.
+func (m *MockIWebhookUsecase) ListWebhooks(c context.Context, userID uint) ([]dto.WebhookResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhooks", c, userID)
+	ret0, _ := ret[0].([]dto.WebhookResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhooks indicates an expected call of ListWebhooks.
+func (mr *MockIWebhookUsecaseMockRecorder) ListWebhooks(c, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhooks", reflect.TypeOf((*MockIWebhookUsecase)(nil).ListWebhooks), c, userID)
+}
+
+// Redeliver mocks base method.
+func (m *MockIWebhookUsecase) Redeliver(c context.Context, userID uint, webhookID, deliveryID int64) (dto.WebhookDeliveryResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Redeliver", c, userID, webhookID, deliveryID)
+	ret0, _ := ret[0].(dto.WebhookDeliveryResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Redeliver indicates an expected call of Redeliver.
+func (mr *MockIWebhookUsecaseMockRecorder) Redeliver(c, userID, webhookID, deliveryID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Redeliver", reflect.TypeOf((*MockIWebhookUsecase)(nil).Redeliver), c, userID, webhookID, deliveryID)
+}
+
+// UpdateWebhook mocks base method.
+func (m *MockIWebhookUsecase) UpdateWebhook(c context.Context, req dto.UpdateWebhookRequest) (dto.WebhookResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebhook", c, req)
+	ret0, _ := ret[0].(dto.WebhookResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateWebhook indicates an expected call of UpdateWebhook.
+func (mr *MockIWebhookUsecaseMockRecorder) UpdateWebhook(c, req interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebhook", reflect.TypeOf((*MockIWebhookUsecase)(nil).UpdateWebhook), c, req)
+}
@@ -0,0 +1,223 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/tracing"
+)
+
+// NotificationCreated is the type of the domain event published for every
+// notification, with the dto.NotificationResponse as data
+const NotificationCreated = "notification.created"
+
+// maxNotificationsPage bounds how many notifications are listed at once
+const maxNotificationsPage = 100
+
+type INotificationUsecase interface {
+	ListNotifications(c context.Context, req dto.ListNotificationsRequest) ([]dto.NotificationResponse, error)
+	CountUnread(c context.Context, userID uint) (int64, error)
+	MarkRead(c context.Context, userID uint, id int64) (dto.NotificationResponse, error)
+	// MarkAllRead marks every notification of the user read and returns how
+	// many were unread
+	MarkAllRead(c context.Context, userID uint) (int64, error)
+	// NotifyPost notifies the author of the post replied to and the users
+	// mentioned in post. Each is notified once per post and type, however
+	// often the post is edited or this is retried.
+	NotifyPost(c context.Context, post dto.PostResponse) error
+}
+
+type notificationUsecase struct {
+	store     db.Store
+	publisher *EventPublisher
+	now       func() time.Time
+}
+
+// NewNotificationUsecase returns the notification usecase. Notifications
+// are published to publisher as they are created, so they can be delivered
+// live by any instance.
+func NewNotificationUsecase(store db.Store, publisher *EventPublisher) INotificationUsecase {
+	return &notificationUsecase{store: store, publisher: publisher, now: time.Now}
+}
+
+func (nu *notificationUsecase) ListNotifications(c context.Context, req dto.ListNotificationsRequest) ([]dto.NotificationResponse, error) {
+	c, span := tracing.Tracer().Start(c, "NotificationUsecase.ListNotifications")
+	defer span.End()
+
+	if req.Limit < 1 {
+		return []dto.NotificationResponse{}, NewValidationError("limit", "minimum", "limit must be at least 1", "1")
+	}
+	if req.Limit > maxNotificationsPage {
+		return []dto.NotificationResponse{}, NewValidationError("limit", "maximum", "limit must be at most 100", strconv.Itoa(maxNotificationsPage))
+	}
+
+	before := req.Before
+	if before == 0 {
+		before = math.MaxInt64
+	}
+	notifications, err := nu.store.ListNotifications(c, db.ListNotificationsParams{
+		UserID: req.UserID,
+		ID:     before,
+		Limit:  req.Limit,
+	})
+	if err != nil {
+		return []dto.NotificationResponse{}, err
+	}
+
+	// look the actors up in one query rather than one per notification
+	var ids []int64
+	seen := map[uint]bool{}
+	for _, notification := range notifications {
+		if !seen[notification.ActorID] {
+			seen[notification.ActorID] = true
+			ids = append(ids, int64(notification.ActorID))
+		}
+	}
+	userStrIds := map[uint]string{}
+	if len(ids) > 0 {
+		users, err := nu.store.ListUsersByIDs(c, ids)
+		if err != nil {
+			return []dto.NotificationResponse{}, err
+		}
+		for _, u := range users {
+			userStrIds[u.ID] = u.UserStrID
+		}
+	}
+
+	res := make([]dto.NotificationResponse, 0, len(notifications))
+	for _, notification := range notifications {
+		res = append(res, newNotificationResponse(notification, userStrIds[notification.ActorID]))
+	}
+	return res, nil
+}
+
+func (nu *notificationUsecase) CountUnread(c context.Context, userID uint) (int64, error) {
+	c, span := tracing.Tracer().Start(c, "NotificationUsecase.CountUnread")
+	defer span.End()
+
+	return nu.store.CountUnreadNotifications(c, userID)
+}
+
+func (nu *notificationUsecase) MarkRead(c context.Context, userID uint, id int64) (dto.NotificationResponse, error) {
+	c, span := tracing.Tracer().Start(c, "NotificationUsecase.MarkRead")
+	defer span.End()
+
+	notification, err := nu.store.GetNotification(c, id)
+	if err != nil {
+		return dto.NotificationResponse{}, notFound(err, ErrNotificationNotFound)
+	}
+	if notification.UserID != userID {
+		return dto.NotificationResponse{}, ErrNotificationNotFound
+	}
+	notification, err = nu.store.MarkNotificationRead(c, db.MarkNotificationReadParams{
+		ReadAt: nu.now().UTC(),
+		ID:     id,
+	})
+	if err != nil {
+		return dto.NotificationResponse{}, notFound(err, ErrNotificationNotFound)
+	}
+	actor, err := nu.store.GetUserStrIdById(c, notification.ActorID)
+	if err != nil {
+		return dto.NotificationResponse{}, err
+	}
+	return newNotificationResponse(notification, actor), nil
+}
+
+func (nu *notificationUsecase) MarkAllRead(c context.Context, userID uint) (int64, error) {
+	c, span := tracing.Tracer().Start(c, "NotificationUsecase.MarkAllRead")
+	defer span.End()
+
+	return nu.store.MarkAllNotificationsRead(c, db.MarkAllNotificationsReadParams{
+		ReadAt: nu.now().UTC(),
+		UserID: userID,
+	})
+}
+
+func (nu *notificationUsecase) NotifyPost(c context.Context, post dto.PostResponse) error {
+	c, span := tracing.Tracer().Start(c, "NotificationUsecase.NotifyPost")
+	defer span.End()
+
+	notified := map[uint]bool{post.UserID: true}
+	if post.ParentID != 0 {
+		parent, err := nu.store.GetPost(c, post.ParentID)
+		if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+			return err
+		}
+		// a parent deleted meanwhile has no author left to tell
+		if err == nil && !notified[parent.UserID] {
+			notified[parent.UserID] = true
+			if err := nu.notify(c, parent.UserID, dto.NotificationReply, post); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, mention := range post.Mentions {
+		// the author of the parent is told of the reply already
+		if notified[mention.UserID] {
+			continue
+		}
+		notified[mention.UserID] = true
+		if err := nu.notify(c, mention.UserID, dto.NotificationMention, post); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notify creates a notification of post for the user with id userID and
+// publishes it, unless they were notified already
+func (nu *notificationUsecase) notify(c context.Context, userID uint, typ string, post dto.PostResponse) error {
+	notification, err := nu.store.CreateNotification(c, db.CreateNotificationParams{
+		UserID:  userID,
+		Type:    typ,
+		ActorID: post.UserID,
+		PostID:  post.ID,
+	})
+	if errors.Is(err, db.ErrRecordNotFound) {
+		// notified already
+		return nil
+	}
+	if db.ErrorCode(err) == db.ForeignKeyViolation {
+		// the post or either user was deleted meanwhile
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// delivered live on a best effort basis: the notification is listed
+	// either way
+	data, err := json.Marshal(newNotificationResponse(notification, post.UserStrID))
+	if err == nil {
+		err = nu.publisher.Publish(c, DomainEvent{Type: NotificationCreated, PostID: post.ID, Data: data})
+	}
+	if err != nil {
+		slog.ErrorContext(c, "cannot publish notification", slog.Int64("id", notification.ID), slog.String("error", err.Error()))
+	}
+	return nil
+}
+
+func newNotificationResponse(notification db.Notification, actorUserStrID string) dto.NotificationResponse {
+	res := dto.NotificationResponse{
+		ID:             notification.ID,
+		UserID:         notification.UserID,
+		Type:           notification.Type,
+		ActorID:        notification.ActorID,
+		ActorUserStrID: actorUserStrID,
+		PostID:         notification.PostID,
+		Read:           notification.ReadAt.Valid,
+		CreatedAt:      notification.CreatedAt,
+	}
+	if notification.ReadAt.Valid {
+		res.ReadAt = &notification.ReadAt.Time
+	}
+	return res
+}
@@ -0,0 +1,202 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/jobs"
+	"github.com/PenginAction/go-BulletinBoard/pubsub"
+	"github.com/stretchr/testify/require"
+)
+
+// notificationTest notifies through the outbox, as the server does
+type notificationTest struct {
+	store  db.Store
+	posts  IPostUsecase
+	nu     INotificationUsecase
+	worker *jobs.Worker
+	// published receives the notifications published
+	published chan DomainEvent
+}
+
+func newNotificationTest(t *testing.T) *notificationTest {
+	store := memory.NewStore()
+	publisher, err := NewEventPublisher(pubsub.NewMemory())
+	require.NoError(t, err)
+	t.Cleanup(publisher.Close)
+	published := make(chan DomainEvent, 10)
+	publisher.Subscribe(func(e DomainEvent) { published <- e })
+
+	nu := NewNotificationUsecase(store, publisher)
+	worker := jobs.NewWorker(store, jobs.Options{})
+	worker.Handle(JobNotify, HandleNotify(nu), jobs.HandlerOptions{})
+	return &notificationTest{
+		store:     store,
+		posts:     NewPostUsecase(store, JobNotify),
+		nu:        nu,
+		worker:    worker,
+		published: published,
+	}
+}
+
+func (nt *notificationTest) post(t *testing.T, user db.User, parentID uint, text string) dto.PostResponse {
+	post, err := nt.posts.CreatePost(context.Background(), dto.CreatePostRequest{UserID: user.ID, Text: text, ParentID: parentID})
+	require.NoError(t, err)
+	nt.runJobs(t)
+	return post
+}
+
+func (nt *notificationTest) runJobs(t *testing.T) {
+	for {
+		n, err := nt.worker.RunDue(context.Background(), JobNotify)
+		require.NoError(t, err)
+		if n == 0 {
+			return
+		}
+	}
+}
+
+func (nt *notificationTest) list(t *testing.T, user db.User) []dto.NotificationResponse {
+	list, err := nt.nu.ListNotifications(context.Background(), dto.ListNotificationsRequest{UserID: user.ID, Limit: 100})
+	require.NoError(t, err)
+	return list
+}
+
+func TestNotifyReply(t *testing.T) {
+	nt := newNotificationTest(t)
+	alice, bob := createTestUser(t, nt.store, dto.RoleUser), createTestUser(t, nt.store, dto.RoleUser)
+
+	parent := nt.post(t, alice, 0, "hello")
+	reply := nt.post(t, bob, parent.ID, "hi")
+	require.Equal(t, parent.ID, reply.ParentID)
+
+	list := nt.list(t, alice)
+	require.Len(t, list, 1)
+	require.Equal(t, dto.NotificationReply, list[0].Type)
+	require.Equal(t, alice.ID, list[0].UserID)
+	require.Equal(t, bob.ID, list[0].ActorID)
+	require.Equal(t, bob.UserStrID, list[0].ActorUserStrID)
+	require.Equal(t, reply.ID, list[0].PostID)
+	require.False(t, list[0].Read)
+
+	select {
+	case e := <-nt.published:
+		require.Equal(t, NotificationCreated, e.Type)
+		var published dto.NotificationResponse
+		require.NoError(t, json.Unmarshal(e.Data, &published))
+		require.Equal(t, list[0].ID, published.ID)
+		require.Equal(t, alice.ID, published.UserID)
+	case <-time.After(time.Second):
+		t.Fatal("notification not published")
+	}
+
+	// replying to yourself notifies nobody
+	nt.post(t, alice, parent.ID, "thanks")
+	require.Len(t, nt.list(t, alice), 1)
+}
+
+func TestNotifyMentions(t *testing.T) {
+	nt := newNotificationTest(t)
+	alice, bob, carol := createTestUser(t, nt.store, dto.RoleUser), createTestUser(t, nt.store, dto.RoleUser), createTestUser(t, nt.store, dto.RoleUser)
+
+	post := nt.post(t, alice, 0, "@"+bob.UserStrID+" and @"+carol.UserStrID+", @"+bob.UserStrID+" again, @nobody, @"+alice.UserStrID+" and mail@"+carol.UserStrID)
+	for _, user := range []db.User{bob, carol} {
+		list := nt.list(t, user)
+		require.Len(t, list, 1, user.UserStrID)
+		require.Equal(t, dto.NotificationMention, list[0].Type)
+		require.Equal(t, post.ID, list[0].PostID)
+	}
+	require.Empty(t, nt.list(t, alice))
+
+	// edits notify those mentioned since, and only them
+	_, err := nt.posts.UpdatePost(context.Background(), dto.UpdatePostRequest{ID: post.ID, Text: post.Text + " @" + alice.UserStrID})
+	require.NoError(t, err)
+	nt.runJobs(t)
+	require.Len(t, nt.list(t, bob), 1)
+
+	// the author replied to is not told twice
+	reply := nt.post(t, carol, post.ID, "@"+alice.UserStrID+" sure")
+	list := nt.list(t, alice)
+	require.Len(t, list, 1)
+	require.Equal(t, dto.NotificationReply, list[0].Type)
+	require.Equal(t, reply.ID, list[0].PostID)
+}
+
+func TestReplyToUnknownPost(t *testing.T) {
+	nt := newNotificationTest(t)
+	alice := createTestUser(t, nt.store, dto.RoleUser)
+	parent := nt.post(t, alice, 0, "hello")
+	require.NoError(t, nt.posts.DeletePost(context.Background(), parent.ID))
+
+	_, err := nt.posts.CreatePost(context.Background(), dto.CreatePostRequest{UserID: alice.ID, Text: "hi", ParentID: parent.ID})
+	requireFieldError(t, err, "parent_id", "unknown_post")
+}
+
+func TestMarkNotificationsRead(t *testing.T) {
+	nt := newNotificationTest(t)
+	alice, bob := createTestUser(t, nt.store, dto.RoleUser), createTestUser(t, nt.store, dto.RoleUser)
+	parent := nt.post(t, alice, 0, "hello")
+	nt.post(t, bob, parent.ID, "one")
+	nt.post(t, bob, parent.ID, "two")
+	nt.post(t, alice, 0, "@"+bob.UserStrID)
+	ctx := context.Background()
+
+	count, err := nt.nu.CountUnread(ctx, alice.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, count)
+	list := nt.list(t, alice)
+
+	// others' notifications are not found
+	_, err = nt.nu.MarkRead(ctx, bob.ID, list[0].ID)
+	require.ErrorIs(t, err, ErrNotificationNotFound)
+	_, err = nt.nu.MarkRead(ctx, alice.ID, 1<<40)
+	require.ErrorIs(t, err, ErrNotificationNotFound)
+
+	read, err := nt.nu.MarkRead(ctx, alice.ID, list[0].ID)
+	require.NoError(t, err)
+	require.True(t, read.Read)
+	require.NotNil(t, read.ReadAt)
+	require.Equal(t, bob.UserStrID, read.ActorUserStrID)
+	count, err = nt.nu.CountUnread(ctx, alice.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count)
+
+	n, err := nt.nu.MarkAllRead(ctx, alice.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+	count, err = nt.nu.CountUnread(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Zero(t, count)
+	// bob's are his own
+	count, err = nt.nu.CountUnread(ctx, bob.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count)
+}
+
+func TestListNotificationsPages(t *testing.T) {
+	nt := newNotificationTest(t)
+	alice, bob := createTestUser(t, nt.store, dto.RoleUser), createTestUser(t, nt.store, dto.RoleUser)
+	parent := nt.post(t, alice, 0, "hello")
+	for i := 0; i < 3; i++ {
+		nt.post(t, bob, parent.ID, "reply")
+	}
+	ctx := context.Background()
+
+	page, err := nt.nu.ListNotifications(ctx, dto.ListNotificationsRequest{UserID: alice.ID, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Greater(t, page[0].ID, page[1].ID)
+	page, err = nt.nu.ListNotifications(ctx, dto.ListNotificationsRequest{UserID: alice.ID, Before: page[1].ID, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+
+	_, err = nt.nu.ListNotifications(ctx, dto.ListNotificationsRequest{UserID: alice.ID, Limit: 0})
+	requireFieldError(t, err, "limit", "minimum")
+	_, err = nt.nu.ListNotifications(ctx, dto.ListNotificationsRequest{UserID: alice.ID, Limit: maxNotificationsPage + 1})
+	requireFieldError(t, err, "limit", "maximum")
+}
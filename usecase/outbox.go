@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/jobs"
+)
+
+// Kinds of the jobs the usecases queue and handle
+const (
+	// JobDispatchWebhooks dispatches an OutboxEvent to the webhooks
+	// subscribed to it
+	JobDispatchWebhooks = "webhooks.dispatch"
+	// JobPurgePosts runs a dto.PurgePostsRequest
+	JobPurgePosts = "posts.purge"
+	// JobNotify creates the notifications of an OutboxEvent of a post
+	// created or updated
+	JobNotify = "notifications.create"
+)
+
+// OutboxEvent is the payload of the jobs queued for a change: the event
+// type, the user the change concerns and what the change made
+type OutboxEvent struct {
+	Type    string          `json:"type"`
+	OwnerID uint            `json:"owner_id"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// enqueue queues a job of each kind for an event, in tx, the transaction
+// making the change
+func enqueue(c context.Context, tx db.Querier, kinds []string, typ string, ownerID uint, data interface{}) error {
+	if len(kinds) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	event := OutboxEvent{Type: typ, OwnerID: ownerID, Data: raw}
+	for _, kind := range kinds {
+		if _, err := jobs.Enqueue(c, tx, jobs.Job{Kind: kind, Payload: event}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// logPostEvent writes an event of a post to the post event log in tx, the
+// transaction making the change, so that the event is logged if and only if
+// the change is made. The log is held until tx ends, so it is written last.
+func logPostEvent(c context.Context, tx db.Querier, typ string, postID uint, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.CreatePostEvent(c, db.CreatePostEventParams{
+		Type:    typ,
+		PostID:  postID,
+		Payload: payload,
+	})
+	return err
+}
+
+// logPurgedPosts queues the jobs of a post.deleted event for each of posts,
+// deleted in bulk, and logs the events, in tx, the transaction deleting them
+func logPurgedPosts(c context.Context, tx db.Querier, kinds []string, posts []db.Post) error {
+	for _, post := range posts {
+		if err := enqueue(c, tx, kinds, PostDeleted, post.UserID, newDeletedPost(post)); err != nil {
+			return err
+		}
+	}
+	for _, post := range posts {
+		if err := logPostEvent(c, tx, PostDeleted, post.ID, newDeletedPost(post)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleDispatchWebhooks returns the handler of the JobDispatchWebhooks jobs
+func HandleDispatchWebhooks(webhooks IWebhookUsecase) jobs.Handler {
+	return func(ctx context.Context, job db.Job) error {
+		var event OutboxEvent
+		if err := json.Unmarshal(job.Payload, &event); err != nil {
+			return jobs.Permanent(err)
+		}
+		return webhooks.Dispatch(ctx, event.Type, event.OwnerID, event.Data)
+	}
+}
+
+// HandleNotify returns the handler of the JobNotify jobs
+func HandleNotify(notifications INotificationUsecase) jobs.Handler {
+	return func(ctx context.Context, job db.Job) error {
+		var event OutboxEvent
+		if err := json.Unmarshal(job.Payload, &event); err != nil {
+			return jobs.Permanent(err)
+		}
+		if event.Type != PostCreated && event.Type != PostUpdated {
+			return nil
+		}
+		var post dto.PostResponse
+		if err := json.Unmarshal(event.Data, &post); err != nil {
+			return jobs.Permanent(err)
+		}
+		return notifications.NotifyPost(ctx, post)
+	}
+}
+
+// HandlePurgePosts returns the handler of the JobPurgePosts jobs
+func HandlePurgePosts(posts IPostUsecase) jobs.Handler {
+	return func(ctx context.Context, job db.Job) error {
+		var req dto.PurgePostsRequest
+		if err := json.Unmarshal(job.Payload, &req); err != nil {
+			return jobs.Permanent(err)
+		}
+		_, err := posts.PurgePosts(ctx, req)
+		return permanent(err)
+	}
+}
+
+// permanent marks domain errors, such as a user not found, as permanent:
+// they fail the same way however often the job is retried
+func permanent(err error) error {
+	if _, ok := AsError(err); ok {
+		return jobs.Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,316 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
+	mockdb "github.com/PenginAction/go-BulletinBoard/db/mock"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/jobs"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func pendingJobs(t *testing.T, store db.Store) []db.Job {
+	list, err := store.ListJobsByStatus(context.Background(), db.ListJobsByStatusParams{
+		Status: jobs.StatusPending,
+		ID:     math.MaxInt64,
+		Limit:  100,
+	})
+	require.NoError(t, err)
+	return list
+}
+
+func TestPostChangesDispatchedThroughOutbox(t *testing.T) {
+	store := memory.NewStore()
+	wu := NewWebhookUsecase(store)
+	pu := NewPostUsecase(store, JobDispatchWebhooks)
+	user := createTestUser(t, store, dto.RoleUser)
+	webhook := createTestWebhook(t, wu, user, PostCreated, PostDeleted)
+
+	worker := jobs.NewWorker(store, jobs.Options{})
+	worker.Handle(JobDispatchWebhooks, HandleDispatchWebhooks(wu), jobs.HandlerOptions{})
+
+	post, err := pu.CreatePost(context.Background(), dto.CreatePostRequest{UserID: user.ID, Text: utils.RandomString(20)})
+	require.NoError(t, err)
+	queuedJobs := pendingJobs(t, store)
+	require.Len(t, queuedJobs, 1)
+	var event OutboxEvent
+	require.NoError(t, json.Unmarshal(queuedJobs[0].Payload, &event))
+	require.Equal(t, PostCreated, event.Type)
+	require.Equal(t, user.ID, event.OwnerID)
+
+	// nothing is delivered until the job runs
+	require.Empty(t, queued(t, store, webhook.ID))
+	n, err := worker.RunDue(context.Background(), JobDispatchWebhooks)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	deliveries := queued(t, store, webhook.ID)
+	require.Len(t, deliveries, 1)
+	var payload dto.WebhookEvent
+	require.NoError(t, json.Unmarshal(deliveries[0].Payload, &payload))
+	var data dto.PostResponse
+	require.NoError(t, json.Unmarshal(payload.Data, &data))
+	require.Equal(t, post.ID, data.ID)
+
+	// the update is queued too, but the webhook is not subscribed to it
+	_, err = pu.UpdatePost(context.Background(), dto.UpdatePostRequest{ID: post.ID, Text: "edited"})
+	require.NoError(t, err)
+	require.NoError(t, pu.DeletePost(context.Background(), post.ID))
+	n, err = worker.RunDue(context.Background(), JobDispatchWebhooks)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	n, err = worker.RunDue(context.Background(), JobDispatchWebhooks)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	deliveries = queued(t, store, webhook.ID)
+	require.Len(t, deliveries, 2)
+	require.Equal(t, PostDeleted, deliveries[1].EventType)
+}
+
+func TestReplyDispatchedThroughOutbox(t *testing.T) {
+	store := memory.NewStore()
+	wu := NewWebhookUsecase(store)
+	pu := NewPostUsecase(store, JobDispatchWebhooks)
+	author := createTestUser(t, store, dto.RoleUser)
+	replier := createTestUser(t, store, dto.RoleUser)
+	authorHook := createTestWebhook(t, wu, author, PostReplied)
+	replierHook := createTestWebhook(t, wu, replier, PostReplied)
+
+	worker := jobs.NewWorker(store, jobs.Options{})
+	worker.Handle(JobDispatchWebhooks, HandleDispatchWebhooks(wu), jobs.HandlerOptions{})
+	runJobs := func() {
+		for {
+			n, err := worker.RunDue(context.Background(), JobDispatchWebhooks)
+			require.NoError(t, err)
+			if n == 0 {
+				return
+			}
+		}
+	}
+
+	parent, err := pu.CreatePost(context.Background(), dto.CreatePostRequest{UserID: author.ID, Text: "hello"})
+	require.NoError(t, err)
+	runJobs()
+	require.Empty(t, queued(t, store, authorHook.ID))
+
+	reply, err := pu.CreatePost(context.Background(), dto.CreatePostRequest{UserID: replier.ID, Text: "hi", ParentID: parent.ID})
+	require.NoError(t, err)
+	runJobs()
+
+	// the reply concerns the author replied to, not the one replying
+	require.Empty(t, queued(t, store, replierHook.ID))
+	deliveries := queued(t, store, authorHook.ID)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, PostReplied, deliveries[0].EventType)
+	var payload dto.WebhookEvent
+	require.NoError(t, json.Unmarshal(deliveries[0].Payload, &payload))
+	var data dto.PostResponse
+	require.NoError(t, json.Unmarshal(payload.Data, &data))
+	require.Equal(t, reply.ID, data.ID)
+	require.Equal(t, parent.ID, data.ParentID)
+}
+
+func TestFailedChangesQueueNothing(t *testing.T) {
+	store := memory.NewStore()
+	pu := NewPostUsecase(store, JobDispatchWebhooks)
+
+	_, err := pu.UpdatePost(context.Background(), dto.UpdatePostRequest{ID: 1, Text: "edited"})
+	require.ErrorIs(t, err, ErrPostNotFound)
+	require.ErrorIs(t, pu.DeletePost(context.Background(), 1), ErrPostNotFound)
+	require.Empty(t, pendingJobs(t, store))
+}
+
+func TestPostEventsLoggedWithChanges(t *testing.T) {
+	store := memory.NewStore()
+	pu := NewPostUsecase(store)
+	user := createTestUser(t, store, dto.RoleUser)
+
+	post, err := pu.CreatePost(context.Background(), dto.CreatePostRequest{UserID: user.ID, Text: "hello"})
+	require.NoError(t, err)
+	post, err = pu.UpdatePost(context.Background(), dto.UpdatePostRequest{ID: post.ID, Text: "edited"})
+	require.NoError(t, err)
+	require.NoError(t, pu.DeletePost(context.Background(), post.ID))
+
+	// failed changes log nothing
+	_, err = pu.CreatePost(context.Background(), dto.CreatePostRequest{UserID: user.ID, Text: "hi", ParentID: post.ID})
+	require.Error(t, err)
+	require.ErrorIs(t, pu.DeletePost(context.Background(), post.ID), ErrPostNotFound)
+
+	events, err := store.ListPostEventsAfter(context.Background(), db.ListPostEventsAfterParams{ID: 0, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	for i, typ := range []string{PostCreated, PostUpdated, PostDeleted} {
+		require.Equal(t, typ, events[i].Type)
+		require.Equal(t, post.ID, events[i].PostID)
+	}
+	var data dto.PostResponse
+	require.NoError(t, json.Unmarshal(events[1].Payload, &data))
+	require.Equal(t, post.Text, data.Text)
+}
+
+func TestReplyDeletedWithParent(t *testing.T) {
+	store := memory.NewStore()
+	pu := NewPostUsecase(store, JobDispatchWebhooks)
+	user := createTestUser(t, store, dto.RoleUser)
+	parent, err := pu.CreatePost(context.Background(), dto.CreatePostRequest{UserID: user.ID, Text: "hello"})
+	require.NoError(t, err)
+	reply, err := pu.CreatePost(context.Background(), dto.CreatePostRequest{UserID: user.ID, Text: "hi", ParentID: parent.ID})
+	require.NoError(t, err)
+	last, err := store.GetLastPostEventID(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, pu.DeletePost(context.Background(), reply.ID))
+	_, err = pu.PurgePosts(context.Background(), dto.PurgePostsRequest{UserStrID: user.UserStrID})
+	require.NoError(t, err)
+
+	// only the events of the reply name a parent
+	want := []dto.DeletedPost{{ID: reply.ID, ParentID: parent.ID}, {ID: parent.ID}}
+	events, err := store.ListPostEventsAfter(context.Background(), db.ListPostEventsAfterParams{ID: last, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, events, len(want))
+	for i, e := range events {
+		var data dto.DeletedPost
+		require.NoError(t, json.Unmarshal(e.Payload, &data))
+		require.Equal(t, want[i], data)
+	}
+
+	var queued []dto.DeletedPost
+	for _, job := range pendingJobs(t, store) {
+		var event OutboxEvent
+		require.NoError(t, json.Unmarshal(job.Payload, &event))
+		if event.Type == PostDeleted {
+			var data dto.DeletedPost
+			require.NoError(t, json.Unmarshal(event.Data, &data))
+			queued = append(queued, data)
+		}
+	}
+	require.ElementsMatch(t, want, queued)
+}
+
+func TestPurgedPostsLoggedAndQueued(t *testing.T) {
+	store := memory.NewStore()
+	pu := NewPostUsecase(store, JobDispatchWebhooks)
+	user := createTestUser(t, store, dto.RoleUser)
+	other := createTestUser(t, store, dto.RoleUser)
+
+	var purged []uint
+	for i := 0; i < 2; i++ {
+		post, err := pu.CreatePost(context.Background(), dto.CreatePostRequest{UserID: user.ID, Text: utils.RandomString(20)})
+		require.NoError(t, err)
+		purged = append(purged, post.ID)
+	}
+	_, err := pu.CreatePost(context.Background(), dto.CreatePostRequest{UserID: other.ID, Text: utils.RandomString(20)})
+	require.NoError(t, err)
+	last, err := store.GetLastPostEventID(context.Background())
+	require.NoError(t, err)
+
+	n, err := pu.PurgePosts(context.Background(), dto.PurgePostsRequest{UserStrID: user.UserStrID})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+
+	events, err := store.ListPostEventsAfter(context.Background(), db.ListPostEventsAfterParams{ID: last, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	for i, e := range events {
+		require.Equal(t, PostDeleted, e.Type)
+		require.Equal(t, purged[i], e.PostID)
+	}
+
+	var deleted []OutboxEvent
+	for _, job := range pendingJobs(t, store) {
+		var event OutboxEvent
+		require.NoError(t, json.Unmarshal(job.Payload, &event))
+		if event.Type == PostDeleted {
+			deleted = append(deleted, event)
+		}
+	}
+	require.Len(t, deleted, 2)
+	for _, event := range deleted {
+		require.Equal(t, user.ID, event.OwnerID)
+	}
+}
+
+func TestDeleteUserLogsPurgedPosts(t *testing.T) {
+	store := memory.NewStore()
+	pu := NewPostUsecase(store)
+	uu := NewUserUsecase(store, JobDispatchWebhooks)
+	user := createTestUser(t, store, dto.RoleUser)
+	post, err := pu.CreatePost(context.Background(), dto.CreatePostRequest{UserID: user.ID, Text: utils.RandomString(20)})
+	require.NoError(t, err)
+	last, err := store.GetLastPostEventID(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, uu.DeleteUser(context.Background(), user.UserStrID, true))
+
+	events, err := store.ListPostEventsAfter(context.Background(), db.ListPostEventsAfterParams{ID: last, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, PostDeleted, events[0].Type)
+	require.Equal(t, post.ID, events[0].PostID)
+	_, err = store.GetUser(context.Background(), user.ID)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func TestPostEventFailureFailsChange(t *testing.T) {
+	user, _ := RandomUser(t)
+	post := RandomPost(user.ID)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	expectTx(store)
+	store.EXPECT().GetPost(gomock.Any(), post.ID).Return(post, nil)
+	store.EXPECT().DeletePost(gomock.Any(), post.ID).Return(nil)
+	store.EXPECT().CreatePostEvent(gomock.Any(), gomock.Any()).Return(db.PostEvent{}, errors.New("connection reset"))
+
+	// rolled back, so no reader ever sees the post deleted while it is not
+	err := NewPostUsecase(store).DeletePost(context.Background(), post.ID)
+	require.EqualError(t, err, "connection reset")
+}
+
+func TestSignUpDispatchedThroughOutbox(t *testing.T) {
+	store := memory.NewStore()
+	uu := NewUserUsecase(store, JobDispatchWebhooks)
+
+	user, err := uu.SignUp(context.Background(), dto.CreateUserRequest{
+		UserStrID: utils.RandomUserStrID(),
+		Email:     utils.RandomEmail(),
+		Password:  "secret",
+	})
+	require.NoError(t, err)
+	queuedJobs := pendingJobs(t, store)
+	require.Len(t, queuedJobs, 1)
+	require.Equal(t, JobDispatchWebhooks, queuedJobs[0].Kind)
+	var event OutboxEvent
+	require.NoError(t, json.Unmarshal(queuedJobs[0].Payload, &event))
+	require.Equal(t, UserSignedUp, event.Type)
+	require.Equal(t, user.ID, event.OwnerID)
+}
+
+func TestHandlePurgePosts(t *testing.T) {
+	store := memory.NewStore()
+	pu := NewPostUsecase(store)
+	user := createTestUser(t, store, dto.RoleUser)
+	post, err := pu.CreatePost(context.Background(), dto.CreatePostRequest{UserID: user.ID, Text: utils.RandomString(20)})
+	require.NoError(t, err)
+
+	handle := HandlePurgePosts(pu)
+	payload, err := json.Marshal(dto.PurgePostsRequest{UserStrID: user.UserStrID})
+	require.NoError(t, err)
+	require.NoError(t, handle(context.Background(), db.Job{Payload: payload}))
+	_, err = pu.GetPostById(context.Background(), post.ID)
+	require.ErrorIs(t, err, ErrPostNotFound)
+
+	// retrying does not help with either
+	err = handle(context.Background(), db.Job{Payload: []byte(`{}`)})
+	require.True(t, jobs.IsPermanent(err))
+	err = handle(context.Background(), db.Job{Payload: []byte(`[`)})
+	require.True(t, jobs.IsPermanent(err))
+}
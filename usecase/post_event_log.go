@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/tracing"
+)
+
+// Types of post events. They double as the event names of the SSE stream.
+const (
+	PostCreated = "post.created"
+	PostUpdated = "post.updated"
+	PostDeleted = "post.deleted"
+)
+
+type IPostEventUsecase interface {
+	// ListPostEvents returns up to limit events logged after the event with
+	// id after, oldest first
+	ListPostEvents(c context.Context, after int64, limit int32) ([]dto.PostEvent, error)
+	// LastPostEventID returns the id of the latest event, or 0 if there is
+	// none
+	LastPostEventID(c context.Context) (int64, error)
+	// Wait returns a channel that is closed the next time an event is logged,
+	// by this instance or another one publishing to the same EventPublisher
+	Wait() <-chan struct{}
+	// Done returns a channel that is closed when the log is closed
+	Done() <-chan struct{}
+}
+
+// PostEventLog reads the post event log, so readers can follow the changes
+// and resume after the last event they saw. The post usecase writes the
+// events, in the transaction of each post created, updated or deleted,
+// including those deleted in bulk. Events get their ids in the order their
+// transactions commit, so a reader never skips an event committed late.
+//
+// It wraps an IPostUsecase, and wakes the readers when a change made through
+// it is committed. The change is then published, so the readers of other
+// instances wake up too.
+type PostEventLog struct {
+	next        IPostUsecase
+	store       db.Querier
+	publisher   *EventPublisher
+	unsubscribe func()
+
+	mu     sync.Mutex
+	wake   chan struct{}
+	done   chan struct{}
+	closed bool
+}
+
+var (
+	_ IPostUsecase      = (*PostEventLog)(nil)
+	_ IPostEventUsecase = (*PostEventLog)(nil)
+)
+
+// NewPostEventLog returns next with its changes published to publisher, and
+// the log in store
+func NewPostEventLog(next IPostUsecase, store db.Querier, publisher *EventPublisher) *PostEventLog {
+	l := &PostEventLog{
+		next:      next,
+		store:     store,
+		publisher: publisher,
+		wake:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	l.unsubscribe = publisher.Subscribe(func(e DomainEvent) {
+		// the event is in the log already; readers read it from there
+		if e.Type == EventsMissed || isPostEvent(e.Type) {
+			l.wakeReaders()
+		}
+	})
+	return l
+}
+
+func isPostEvent(typ string) bool {
+	switch typ {
+	case PostCreated, PostUpdated, PostDeleted:
+		return true
+	}
+	return false
+}
+
+func (l *PostEventLog) ListPostEvents(c context.Context, after int64, limit int32) ([]dto.PostEvent, error) {
+	c, span := tracing.Tracer().Start(c, "PostEventLog.ListPostEvents")
+	defer span.End()
+
+	events, err := l.store.ListPostEventsAfter(c, db.ListPostEventsAfterParams{ID: after, Limit: limit})
+	if err != nil {
+		return []dto.PostEvent{}, err
+	}
+	res := make([]dto.PostEvent, 0, len(events))
+	for _, e := range events {
+		res = append(res, dto.PostEvent{
+			ID:        e.ID,
+			Type:      e.Type,
+			PostID:    e.PostID,
+			Data:      e.Payload,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	return res, nil
+}
+
+func (l *PostEventLog) LastPostEventID(c context.Context) (int64, error) {
+	return l.store.GetLastPostEventID(c)
+}
+
+func (l *PostEventLog) Wait() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.wake
+}
+
+func (l *PostEventLog) Done() <-chan struct{} {
+	return l.done
+}
+
+// Close tells the readers to stop, for example when the server shuts down
+func (l *PostEventLog) Close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.closed {
+		l.closed = true
+		l.unsubscribe()
+		close(l.done)
+	}
+}
+
+// announce wakes the readers waiting for an event, once the change logging
+// it is committed, and publishes it
+func (l *PostEventLog) announce(c context.Context, typ string, postID uint, data interface{}) {
+	// not left to the publisher, which may be unable to publish
+	l.wakeReaders()
+
+	// the change is made, so publish it even if the caller has gone away
+	c = context.WithoutCancel(c)
+	var (
+		payload json.RawMessage
+		err     error
+	)
+	if data != nil {
+		payload, err = json.Marshal(data)
+	}
+	if err == nil {
+		err = l.publisher.Publish(c, DomainEvent{Type: typ, PostID: postID, Data: payload})
+	}
+	if err != nil {
+		// other instances find the event when they next poll the log
+		slog.ErrorContext(c, "cannot publish post event", slog.String("type", typ), slog.Uint64("post_id", uint64(postID)), slog.String("error", err.Error()))
+	}
+}
+
+func (l *PostEventLog) wakeReaders() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
+
+func (l *PostEventLog) CreatePost(c context.Context, req dto.CreatePostRequest) (dto.PostResponse, error) {
+	post, err := l.next.CreatePost(c, req)
+	if err != nil {
+		return post, err
+	}
+	l.announce(c, PostCreated, post.ID, post)
+	return post, nil
+}
+
+func (l *PostEventLog) GetPostById(c context.Context, id uint) (dto.PostResponse, error) {
+	return l.next.GetPostById(c, id)
+}
+
+func (l *PostEventLog) GetAllPosts(c context.Context, req dto.AllPostsRequest) ([]dto.PostResponse, error) {
+	return l.next.GetAllPosts(c, req)
+}
+
+func (l *PostEventLog) ListPosts(c context.Context, req dto.ListPostsRequest) ([]dto.PostResponse, error) {
+	return l.next.ListPosts(c, req)
+}
+
+func (l *PostEventLog) ListReplies(c context.Context, req dto.ListRepliesRequest) (map[uint][]dto.PostResponse, error) {
+	return l.next.ListReplies(c, req)
+}
+
+func (l *PostEventLog) UpdatePost(c context.Context, req dto.UpdatePostRequest) (dto.PostResponse, error) {
+	post, err := l.next.UpdatePost(c, req)
+	if err != nil {
+		return post, err
+	}
+	l.announce(c, PostUpdated, post.ID, post)
+	return post, nil
+}
+
+func (l *PostEventLog) DeletePost(c context.Context, id uint) error {
+	if err := l.next.DeletePost(c, id); err != nil {
+		return err
+	}
+	l.announce(c, PostDeleted, id, dto.DeletedPost{ID: id})
+	return nil
+}
+
+func (l *PostEventLog) PurgePosts(c context.Context, req dto.PurgePostsRequest) (int64, error) {
+	n, err := l.next.PurgePosts(c, req)
+	if err != nil || n == 0 {
+		return n, err
+	}
+	// one announcement for them all; readers find each post in the log
+	l.announce(c, PostDeleted, 0, nil)
+	return n, nil
+}
@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	mockdb "github.com/PenginAction/go-BulletinBoard/db/mock"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	mock_usecase "github.com/PenginAction/go-BulletinBoard/usecase/mock"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func randomPostResponse() dto.PostResponse {
+	return dto.PostResponse{
+		ID:        utils.RandomInt(1, 1000),
+		UserID:    utils.RandomInt(1, 1000),
+		UserStrID: utils.RandomUserStrID(),
+		Text:      utils.RandomString(15),
+	}
+}
+
+func requireClosed(t *testing.T, ch <-chan struct{}) {
+	select {
+	case <-ch:
+	default:
+		t.Fatal("channel is open")
+	}
+}
+
+func requireOpen(t *testing.T, ch <-chan struct{}) {
+	select {
+	case <-ch:
+		t.Fatal("channel is closed")
+	default:
+	}
+}
+
+func TestPostEventLogWakesOnChanges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	post := randomPostResponse()
+	next := mock_usecase.NewMockIPostUsecase(ctrl)
+	// the events are written by next, in the transactions of the changes
+	store := mockdb.NewMockStore(ctrl)
+	log := NewPostEventLog(next, store, newTestPublisher(t))
+
+	createReq := dto.CreatePostRequest{UserID: post.UserID, Text: post.Text}
+	next.EXPECT().CreatePost(gomock.Any(), createReq).Return(post, nil)
+
+	wake := log.Wait()
+	res, err := log.CreatePost(context.Background(), createReq)
+	require.NoError(t, err)
+	require.Equal(t, post, res)
+	requireClosed(t, wake)
+
+	updateReq := dto.UpdatePostRequest{ID: post.ID, Text: "updated"}
+	post.Text = updateReq.Text
+	next.EXPECT().UpdatePost(gomock.Any(), updateReq).Return(post, nil)
+
+	wake = log.Wait()
+	_, err = log.UpdatePost(context.Background(), updateReq)
+	require.NoError(t, err)
+	requireClosed(t, wake)
+
+	next.EXPECT().DeletePost(gomock.Any(), post.ID).Return(nil)
+
+	wake = log.Wait()
+	require.NoError(t, log.DeletePost(context.Background(), post.ID))
+	requireClosed(t, wake)
+}
+
+func TestPostEventLogWakesOnPurges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	next := mock_usecase.NewMockIPostUsecase(ctrl)
+	publisher := newTestPublisher(t)
+	log := NewPostEventLog(next, mockdb.NewMockStore(ctrl), publisher)
+	defer log.Close()
+
+	var published []DomainEvent
+	defer publisher.Subscribe(func(e DomainEvent) { published = append(published, e) })()
+
+	req := dto.PurgePostsRequest{UserStrID: utils.RandomUserStrID()}
+	next.EXPECT().PurgePosts(gomock.Any(), req).Return(int64(0), nil)
+
+	// nothing purged, nothing to read
+	wake := log.Wait()
+	_, err := log.PurgePosts(context.Background(), req)
+	require.NoError(t, err)
+	requireOpen(t, wake)
+
+	next.EXPECT().PurgePosts(gomock.Any(), req).Return(int64(3), nil)
+
+	n, err := log.PurgePosts(context.Background(), req)
+	require.NoError(t, err)
+	require.EqualValues(t, 3, n)
+	requireClosed(t, wake)
+	require.Equal(t, []DomainEvent{{Type: PostDeleted}}, published)
+}
+
+func TestPostEventLogSkipsFailedChanges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	next := mock_usecase.NewMockIPostUsecase(ctrl)
+	log := NewPostEventLog(next, mockdb.NewMockStore(ctrl), newTestPublisher(t))
+
+	next.EXPECT().UpdatePost(gomock.Any(), gomock.Any()).Return(dto.PostResponse{}, ErrPostNotFound)
+
+	wake := log.Wait()
+	_, err := log.UpdatePost(context.Background(), dto.UpdatePostRequest{ID: 1, Text: "updated"})
+	require.ErrorIs(t, err, ErrPostNotFound)
+	requireOpen(t, wake)
+}
+
+func TestPostEventLogListPostEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	log := NewPostEventLog(nil, store, newTestPublisher(t))
+
+	event := db.PostEvent{ID: 8, Type: PostDeleted, PostID: 3, Payload: json.RawMessage(`{"id":3}`)}
+	store.EXPECT().
+		ListPostEventsAfter(gomock.Any(), db.ListPostEventsAfterParams{ID: 7, Limit: 10}).
+		Return([]db.PostEvent{event}, nil)
+
+	events, err := log.ListPostEvents(context.Background(), 7, 10)
+	require.NoError(t, err)
+	require.Equal(t, []dto.PostEvent{{ID: 8, Type: PostDeleted, PostID: 3, Data: event.Payload}}, events)
+}
+
+func TestPostEventLogClose(t *testing.T) {
+	log := NewPostEventLog(nil, nil, newTestPublisher(t))
+	requireOpen(t, log.Done())
+
+	log.Close()
+	requireClosed(t, log.Done())
+	// closing twice is harmless
+	log.Close()
+}
+
+func TestPostEventLogPublishes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	post := randomPostResponse()
+	next := mock_usecase.NewMockIPostUsecase(ctrl)
+	store := mockdb.NewMockStore(ctrl)
+	publisher := newTestPublisher(t)
+	log := NewPostEventLog(next, store, publisher)
+	defer log.Close()
+
+	var published []DomainEvent
+	defer publisher.Subscribe(func(e DomainEvent) { published = append(published, e) })()
+
+	next.EXPECT().CreatePost(gomock.Any(), gomock.Any()).Return(post, nil)
+	_, err := log.CreatePost(context.Background(), dto.CreatePostRequest{UserID: post.UserID, Text: post.Text})
+	require.NoError(t, err)
+
+	data, err := json.Marshal(post)
+	require.NoError(t, err)
+	require.Equal(t, []DomainEvent{{Type: PostCreated, PostID: post.ID, Data: data}}, published)
+}
+
+func TestPostEventLogWakesOnPublishedEvents(t *testing.T) {
+	publisher := newTestPublisher(t)
+	log := NewPostEventLog(nil, nil, publisher)
+
+	// logged by another instance
+	wake := log.Wait()
+	require.NoError(t, publisher.Publish(context.Background(), DomainEvent{Type: PostUpdated, PostID: 1}))
+	requireClosed(t, wake)
+
+	wake = log.Wait()
+	require.NoError(t, publisher.Publish(context.Background(), DomainEvent{Type: "user.created"}))
+	requireOpen(t, wake)
+
+	log.Close()
+	require.NoError(t, publisher.Publish(context.Background(), DomainEvent{Type: PostUpdated, PostID: 1}))
+	requireOpen(t, wake)
+}
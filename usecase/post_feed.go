@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"github.com/PenginAction/go-BulletinBoard/dto"
+)
+
+// feedBuffer is how many posts a subscriber may fall behind before it is
+// dropped, so one slow reader cannot hold up the writers
+const feedBuffer = 64
+
+// PostFeed wraps an IPostUsecase and hands the posts created on any instance
+// to the current subscribers. It learns about them from the post.created
+// events of the publisher, so next must publish them, as PostEventLog does.
+type PostFeed struct {
+	next        IPostUsecase
+	unsubscribe func()
+
+	mu     sync.Mutex
+	subs   map[chan dto.PostResponse]struct{}
+	closed bool
+}
+
+var _ IPostUsecase = (*PostFeed)(nil)
+
+// NewPostFeed returns next with a feed of the posts created events
+// published to publisher tell about
+func NewPostFeed(next IPostUsecase, publisher *EventPublisher) *PostFeed {
+	f := &PostFeed{
+		next: next,
+		subs: map[chan dto.PostResponse]struct{}{},
+	}
+	f.unsubscribe = publisher.Subscribe(f.receive)
+	return f
+}
+
+// Subscribe returns a channel receiving the posts created from now on, and a
+// function ending the subscription. The channel is closed when the
+// subscription ends, or early if the subscriber falls feedBuffer posts
+// behind.
+func (f *PostFeed) Subscribe() (<-chan dto.PostResponse, func()) {
+	ch := make(chan dto.PostResponse, feedBuffer)
+
+	f.mu.Lock()
+	if f.closed {
+		close(ch)
+	} else {
+		f.subs[ch] = struct{}{}
+	}
+	f.mu.Unlock()
+
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subs[ch]; ok {
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close ends every subscription, for example when the server shuts down.
+// Later subscriptions end immediately.
+func (f *PostFeed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.closed {
+		f.unsubscribe()
+	}
+	f.closed = true
+	for ch := range f.subs {
+		delete(f.subs, ch)
+		close(ch)
+	}
+}
+
+// Closed reports whether Close has been called
+func (f *PostFeed) Closed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+func (f *PostFeed) receive(e DomainEvent) {
+	if e.Type != PostCreated {
+		return
+	}
+	if e.Truncated {
+		// receive must not block
+		go func() {
+			post, err := f.next.GetPostById(context.Background(), e.PostID)
+			if err != nil {
+				slog.Error("cannot load created post", slog.Uint64("post_id", uint64(e.PostID)), slog.String("error", err.Error()))
+				return
+			}
+			f.publish(post)
+		}()
+		return
+	}
+
+	var post dto.PostResponse
+	if err := json.Unmarshal(e.Data, &post); err != nil {
+		slog.Error("cannot decode created post", slog.Uint64("post_id", uint64(e.PostID)), slog.String("error", err.Error()))
+		return
+	}
+	f.publish(post)
+}
+
+func (f *PostFeed) publish(post dto.PostResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for ch := range f.subs {
+		select {
+		case ch <- post:
+		default:
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (f *PostFeed) CreatePost(c context.Context, req dto.CreatePostRequest) (dto.PostResponse, error) {
+	return f.next.CreatePost(c, req)
+}
+
+func (f *PostFeed) GetPostById(c context.Context, id uint) (dto.PostResponse, error) {
+	return f.next.GetPostById(c, id)
+}
+
+func (f *PostFeed) GetAllPosts(c context.Context, req dto.AllPostsRequest) ([]dto.PostResponse, error) {
+	return f.next.GetAllPosts(c, req)
+}
+
+func (f *PostFeed) ListPosts(c context.Context, req dto.ListPostsRequest) ([]dto.PostResponse, error) {
+	return f.next.ListPosts(c, req)
+}
+
+func (f *PostFeed) ListReplies(c context.Context, req dto.ListRepliesRequest) (map[uint][]dto.PostResponse, error) {
+	return f.next.ListReplies(c, req)
+}
+
+func (f *PostFeed) UpdatePost(c context.Context, req dto.UpdatePostRequest) (dto.PostResponse, error) {
+	return f.next.UpdatePost(c, req)
+}
+
+func (f *PostFeed) DeletePost(c context.Context, id uint) error {
+	return f.next.DeletePost(c, id)
+}
+
+func (f *PostFeed) PurgePosts(c context.Context, req dto.PurgePostsRequest) (int64, error) {
+	return f.next.PurgePosts(c, req)
+}
@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	mockdb "github.com/PenginAction/go-BulletinBoard/db/mock"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	mock_usecase "github.com/PenginAction/go-BulletinBoard/usecase/mock"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFeed returns a feed over a log of posts, expecting posts to be
+// created through it
+func newTestFeed(t *testing.T, posts int) *PostFeed {
+	user, _ := RandomUser(t)
+	ctrl := gomock.NewController(t)
+
+	store := mockdb.NewMockStore(ctrl)
+	expectTx(store)
+	for i := 0; i < posts; i++ {
+		post := RandomPost(user.ID)
+		store.EXPECT().CreatePost(gomock.Any(), gomock.Any()).Return(post, nil)
+	}
+	store.EXPECT().
+		GetUserStrIdById(gomock.Any(), gomock.Any()).
+		Times(posts).
+		Return(utils.RandomString(10), nil)
+	store.EXPECT().
+		CreatePostEvent(gomock.Any(), gomock.Any()).
+		Times(posts).
+		Return(db.PostEvent{ID: 1}, nil)
+
+	publisher := newTestPublisher(t)
+	return NewPostFeed(NewPostEventLog(NewPostUsecase(store), store, publisher), publisher)
+}
+
+func TestPostFeedPublishes(t *testing.T) {
+	feed := newTestFeed(t, 1)
+	posts, cancel := feed.Subscribe()
+	defer cancel()
+
+	created, err := feed.CreatePost(context.Background(), dto.CreatePostRequest{UserID: 1, Text: "hello"})
+	require.NoError(t, err)
+
+	post := <-posts
+	require.Equal(t, created, post)
+
+	cancel()
+	_, ok := <-posts
+	require.False(t, ok)
+}
+
+func TestPostFeedDropsSlowSubscribers(t *testing.T) {
+	feed := newTestFeed(t, feedBuffer+1)
+	posts, cancel := feed.Subscribe()
+	defer cancel()
+
+	for i := 0; i < feedBuffer+1; i++ {
+		_, err := feed.CreatePost(context.Background(), dto.CreatePostRequest{UserID: 1, Text: "hello"})
+		require.NoError(t, err)
+	}
+
+	received := 0
+	for range posts {
+		received++
+	}
+	require.Equal(t, feedBuffer, received)
+	require.False(t, feed.Closed())
+}
+
+func TestPostFeedClose(t *testing.T) {
+	feed := newTestFeed(t, 0)
+	posts, cancel := feed.Subscribe()
+	defer cancel()
+
+	feed.Close()
+	_, ok := <-posts
+	require.False(t, ok)
+	require.True(t, feed.Closed())
+
+	later, cancelLater := feed.Subscribe()
+	defer cancelLater()
+	_, ok = <-later
+	require.False(t, ok)
+}
+
+func TestPostFeedSeesOtherInstances(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	post := randomPostResponse()
+	next := mock_usecase.NewMockIPostUsecase(ctrl)
+	publisher := newTestPublisher(t)
+	feed := NewPostFeed(next, publisher)
+	posts, cancel := feed.Subscribe()
+	defer cancel()
+
+	data, err := json.Marshal(post)
+	require.NoError(t, err)
+	require.NoError(t, publisher.Publish(context.Background(), DomainEvent{Type: PostUpdated, PostID: post.ID, Data: data}))
+	require.NoError(t, publisher.Publish(context.Background(), DomainEvent{Type: PostCreated, PostID: post.ID, Data: data}))
+	require.Equal(t, post, <-posts)
+
+	// too large to be sent along
+	next.EXPECT().GetPostById(gomock.Any(), post.ID).Return(post, nil)
+	require.NoError(t, publisher.Publish(context.Background(), DomainEvent{Type: PostCreated, PostID: post.ID, Truncated: true}))
+	require.Equal(t, post, <-posts)
+}
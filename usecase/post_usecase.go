@@ -2,124 +2,387 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
 
 	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
 	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/metrics"
+	"github.com/PenginAction/go-BulletinBoard/tracing"
 )
 
 type IPostUsecase interface {
 	CreatePost(c context.Context, req dto.CreatePostRequest) (dto.PostResponse, error)
 	GetPostById(c context.Context, id uint) (dto.PostResponse, error)
 	GetAllPosts(c context.Context, req dto.AllPostsRequest) ([]dto.PostResponse, error)
+	ListPosts(c context.Context, req dto.ListPostsRequest) ([]dto.PostResponse, error)
+	// ListReplies returns the replies to each of the posts, by post id, in
+	// one query
+	ListReplies(c context.Context, req dto.ListRepliesRequest) (map[uint][]dto.PostResponse, error)
 	UpdatePost(c context.Context, req dto.UpdatePostRequest) (dto.PostResponse, error)
 	DeletePost(c context.Context, id uint) error
+	PurgePosts(c context.Context, req dto.PurgePostsRequest) (int64, error)
 }
 
 type postUsecase struct {
-	postRepository db.Querier
+	postRepository db.Store
+	// outbox holds the kinds of the jobs queued for every post created,
+	// updated or deleted
+	outbox []string
 }
 
-func NewPostUsecase(postRepository db.Querier) IPostUsecase {
-	return &postUsecase{postRepository}
+// NewPostUsecase returns the post usecase. Every post created, updated or
+// deleted is logged to the post event log, and queues a job of each of the
+// outbox kinds with an OutboxEvent as payload, in the transaction making the
+// change.
+func NewPostUsecase(postRepository db.Store, outbox ...string) IPostUsecase {
+	return &postUsecase{postRepository, outbox}
 }
 
 func (pu *postUsecase) CreatePost(c context.Context, req dto.CreatePostRequest) (dto.PostResponse, error) {
+	c, span := tracing.Tracer().Start(c, "PostUsecase.CreatePost")
+	defer span.End()
+
+	explicit, err := normalizeTags(req.Tags)
+	if err != nil {
+		return dto.PostResponse{}, err
+	}
+
 	newPost := db.CreatePostParams{
 		UserID: req.UserID,
 		Text:   req.Text,
 	}
-	post, err := pu.postRepository.CreatePost(c, newPost)
-	if err != nil {
-		return dto.PostResponse{}, err
-	}
-	userStrId, err := pu.postRepository.GetUserStrIdById(c, post.UserID)
+	var rep dto.PostResponse
+	err = pu.postRepository.ExecTx(c, func(tx db.Store) error {
+		var parent db.Post
+		if req.ParentID != 0 {
+			var err error
+			if parent, err = tx.GetPost(c, req.ParentID); err != nil {
+				return notFound(err, errUnknownParent)
+			}
+			newPost.ParentID = sql.NullInt64{Int64: int64(req.ParentID), Valid: true}
+		}
+		post, err := tx.CreatePost(c, newPost)
+		if err != nil {
+			return err
+		}
+		userStrId, err := tx.GetUserStrIdById(c, post.UserID)
+		if err != nil {
+			return err
+		}
+
+		rep = newPostResponse(post, userStrId)
+		if rep.Mentions, err = linkMentions(c, tx, post, nil); err != nil {
+			return err
+		}
+		if rep.Tags, err = linkTags(c, tx, post.ID, postTags(post.Text, explicit)); err != nil {
+			return err
+		}
+		if err := enqueue(c, tx, pu.outbox, PostCreated, post.UserID, rep); err != nil {
+			return err
+		}
+		if req.ParentID != 0 {
+			if err := enqueue(c, tx, pu.outbox, PostReplied, parent.UserID, rep); err != nil {
+				return err
+			}
+		}
+		return logPostEvent(c, tx, PostCreated, post.ID, rep)
+	})
 	if err != nil {
 		return dto.PostResponse{}, err
 	}
-
-	rep := dto.PostResponse{
-		ID:        post.ID,
-		UserID:    post.UserID,
-		UserStrID: userStrId,
-		Text:      post.Text,
-		CreatedAt: post.CreatedAt,
-	}
+	metrics.PostsCreated.Inc()
 
 	return rep, nil
 }
 
 func (pu *postUsecase) GetPostById(c context.Context, id uint) (dto.PostResponse, error) {
+	c, span := tracing.Tracer().Start(c, "PostUsecase.GetPostById")
+	defer span.End()
+
 	post, err := pu.postRepository.GetPost(c, id)
 	if err != nil {
-		return dto.PostResponse{}, err
+		return dto.PostResponse{}, notFound(err, ErrPostNotFound)
 	}
 	userStrId, err := pu.postRepository.GetUserStrIdById(c, post.UserID)
 	if err != nil {
 		return dto.PostResponse{}, err
 	}
-	resPost := dto.PostResponse{
-		ID:        post.ID,
-		UserID:    post.UserID,
-		UserStrID: userStrId,
-		Text:      post.Text,
-		CreatedAt: post.CreatedAt,
+	res := []dto.PostResponse{newPostResponse(post, userStrId)}
+	if err := loadEntities(c, pu.postRepository, res); err != nil {
+		return dto.PostResponse{}, err
 	}
-	return resPost, nil
+	return res[0], nil
 }
 
 func (pu *postUsecase) GetAllPosts(c context.Context, req dto.AllPostsRequest) ([]dto.PostResponse, error) {
-	arg := db.ListPostsParams{
+	c, span := tracing.Tracer().Start(c, "PostUsecase.GetAllPosts")
+	defer span.End()
+
+	return pu.ListPosts(c, dto.ListPostsRequest{
 		Limit:  req.PageSize,
 		Offset: (req.PageID - 1) * req.PageSize,
+	})
+}
+
+func (pu *postUsecase) ListPosts(c context.Context, req dto.ListPostsRequest) ([]dto.PostResponse, error) {
+	c, span := tracing.Tracer().Start(c, "PostUsecase.ListPosts")
+	defer span.End()
+
+	var (
+		posts []db.Post
+		err   error
+	)
+	if req.ParentID != 0 {
+		posts, err = pu.postRepository.ListReplies(c, db.ListRepliesParams{
+			ParentID: sql.NullInt64{Int64: int64(req.ParentID), Valid: true},
+			Limit:    req.Limit,
+			Offset:   req.Offset,
+		})
+	} else {
+		posts, err = pu.postRepository.ListPosts(c, db.ListPostsParams{
+			Limit:  req.Limit,
+			Offset: req.Offset,
+		})
 	}
-	posts, err := pu.postRepository.ListPosts(c, arg)
 	if err != nil {
 		return []dto.PostResponse{}, err
 	}
-	resPosts := []dto.PostResponse{}
-	for _, v := range posts {
-		userStrId, err := pu.postRepository.GetUserStrIdById(c, v.UserID)
-		if err != nil {
-			return []dto.PostResponse{}, err
-		}
-		p := dto.PostResponse{
-			ID:        v.ID,
-			UserID:    v.UserID,
-			UserStrID: userStrId,
-			Text:      v.Text,
-			CreatedAt: v.CreatedAt,
-		}
-		resPosts = append(resPosts, p)
+	return postResponses(c, pu.postRepository, posts)
+}
+
+func (pu *postUsecase) ListReplies(c context.Context, req dto.ListRepliesRequest) (map[uint][]dto.PostResponse, error) {
+	c, span := tracing.Tracer().Start(c, "PostUsecase.ListReplies")
+	defer span.End()
+
+	ids := make([]int64, 0, len(req.ParentIDs))
+	for _, id := range req.ParentIDs {
+		ids = append(ids, int64(id))
 	}
-	return resPosts, nil
+	posts, err := pu.postRepository.ListRepliesByParentIDs(c, db.ListRepliesByParentIDsParams{
+		ParentIds: ids,
+		Limit:     req.Limit,
+		Offset:    req.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := postResponses(c, pu.postRepository, posts)
+	if err != nil {
+		return nil, err
+	}
+	replies := make(map[uint][]dto.PostResponse, len(req.ParentIDs))
+	for _, reply := range res {
+		replies[reply.ParentID] = append(replies[reply.ParentID], reply)
+	}
+	return replies, nil
 }
 
 func (pu *postUsecase) UpdatePost(c context.Context, req dto.UpdatePostRequest) (dto.PostResponse, error) {
+	c, span := tracing.Tracer().Start(c, "PostUsecase.UpdatePost")
+	defer span.End()
+
 	renewPost := db.UpdatePostParams{
 		ID:   req.ID,
 		Text: req.Text,
 	}
-	post, err := pu.postRepository.UpdatePost(c, renewPost)
+	var resPost dto.PostResponse
+	err := pu.postRepository.ExecTx(c, func(tx db.Store) error {
+		old, err := tx.GetPost(c, req.ID)
+		if err != nil {
+			return notFound(err, ErrPostNotFound)
+		}
+		// the tags given explicitly stay, whatever the text now says
+		tags, err := tx.ListPostTags(c, []int64{int64(old.ID)})
+		if err != nil {
+			return err
+		}
+		var oldTags []string
+		for _, tag := range tags {
+			oldTags = append(oldTags, tag.Name)
+		}
+		if len(oldTags) > 0 {
+			if err := tx.DeletePostTags(c, old.ID); err != nil {
+				return err
+			}
+		}
+		// the users mentioned before stay mentioned, even if renamed since
+		var known map[string]uint
+		if strings.Contains(old.Text, "@") {
+			mentions, err := tx.ListPostMentions(c, []int64{int64(old.ID)})
+			if err != nil {
+				return err
+			}
+			known = knownMentions(old.Text, mentions)
+			if err := tx.DeletePostMentions(c, old.ID); err != nil {
+				return err
+			}
+		}
+
+		post, err := tx.UpdatePost(c, renewPost)
+		if err != nil {
+			return notFound(err, ErrPostNotFound)
+		}
+		userStrId, err := tx.GetUserStrIdById(c, post.UserID)
+		if err != nil {
+			return err
+		}
+		resPost = newPostResponse(post, userStrId)
+		if resPost.Mentions, err = linkMentions(c, tx, post, known); err != nil {
+			return err
+		}
+		if resPost.Tags, err = linkTags(c, tx, post.ID, postTags(post.Text, explicitTags(old.Text, oldTags))); err != nil {
+			return err
+		}
+		if err := enqueue(c, tx, pu.outbox, PostUpdated, post.UserID, resPost); err != nil {
+			return err
+		}
+		return logPostEvent(c, tx, PostUpdated, post.ID, resPost)
+	})
 	if err != nil {
 		return dto.PostResponse{}, err
 	}
-	userStrId, err := pu.postRepository.GetUserStrIdById(c, post.UserID)
+	return resPost, nil
+}
+
+func (pu *postUsecase) DeletePost(c context.Context, id uint) error {
+	c, span := tracing.Tracer().Start(c, "PostUsecase.DeletePost")
+	defer span.End()
+
+	return pu.postRepository.ExecTx(c, func(tx db.Store) error {
+		// the owner decides who may hear of it, and is gone with the post
+		post, err := tx.GetPost(c, id)
+		if err != nil {
+			return notFound(err, ErrPostNotFound)
+		}
+		if err := tx.DeletePost(c, id); err != nil {
+			return err
+		}
+		if err := enqueue(c, tx, pu.outbox, PostDeleted, post.UserID, newDeletedPost(post)); err != nil {
+			return err
+		}
+		return logPostEvent(c, tx, PostDeleted, id, newDeletedPost(post))
+	})
+}
+
+func (pu *postUsecase) PurgePosts(c context.Context, req dto.PurgePostsRequest) (int64, error) {
+	c, span := tracing.Tracer().Start(c, "PostUsecase.PurgePosts")
+	defer span.End()
+
+	if (req.UserStrID == "") == req.Before.IsZero() {
+		return 0, ErrInvalidFilter
+	}
+
+	var posts []db.Post
+	err := pu.postRepository.ExecTx(c, func(tx db.Store) error {
+		var err error
+		if req.UserStrID != "" {
+			var user db.User
+			user, err = tx.GetUserByUserStrId(c, req.UserStrID)
+			if err != nil {
+				return notFound(err, ErrUserNotFound)
+			}
+			posts, err = tx.DeletePostsByUser(c, user.ID)
+		} else {
+			posts, err = tx.DeletePostsCreatedBefore(c, req.Before)
+		}
+		if err != nil {
+			return err
+		}
+		return logPurgedPosts(c, tx, pu.outbox, posts)
+	})
 	if err != nil {
-		return dto.PostResponse{}, err
+		return 0, err
 	}
-	resPost := dto.PostResponse{
-		ID:        post.ID,
-		UserID:    post.UserID,
-		UserStrID: userStrId,
-		Text:      post.Text,
-		CreatedAt: post.CreatedAt,
+	n := int64(len(posts))
+	slog.InfoContext(c, "posts purged", slog.String("user_str_id", req.UserStrID), slog.Time("before", req.Before), slog.Int64("count", n))
+	return n, nil
+}
+
+// newDeletedPost returns the data of the post.deleted event of post
+func newDeletedPost(post db.Post) dto.DeletedPost {
+	return dto.DeletedPost{ID: post.ID, ParentID: uint(post.ParentID.Int64)}
+}
+
+// postResponses returns the responses of posts, with the authors, mentions
+// and tags looked up in one query each rather than one per post
+func postResponses(c context.Context, store db.Querier, posts []db.Post) ([]dto.PostResponse, error) {
+	var ids []int64
+	seen := map[uint]bool{}
+	for _, v := range posts {
+		if !seen[v.UserID] {
+			seen[v.UserID] = true
+			ids = append(ids, int64(v.UserID))
+		}
 	}
-	return resPost, nil
+	userStrIds := map[uint]string{}
+	if len(ids) > 0 {
+		users, err := store.ListUsersByIDs(c, ids)
+		if err != nil {
+			return []dto.PostResponse{}, err
+		}
+		for _, u := range users {
+			userStrIds[u.ID] = u.UserStrID
+		}
+	}
+
+	resPosts := []dto.PostResponse{}
+	for _, v := range posts {
+		resPosts = append(resPosts, newPostResponse(v, userStrIds[v.UserID]))
+	}
+	if err := loadEntities(c, store, resPosts); err != nil {
+		return []dto.PostResponse{}, err
+	}
+	return resPosts, nil
 }
 
-func (pu *postUsecase) DeletePost(c context.Context, id uint) error {
-	if err := pu.postRepository.DeletePost(c, id); err != nil {
+// loadEntities sets the mentions and tags of posts, looked up in one query
+// each
+func loadEntities(c context.Context, store db.Querier, posts []dto.PostResponse) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	var ids, mentioning []int64
+	index := map[uint]int{}
+	for i, post := range posts {
+		ids = append(ids, int64(post.ID))
+		index[post.ID] = i
+		// only a text with an @ can mention anyone
+		if strings.Contains(post.Text, "@") {
+			mentioning = append(mentioning, int64(post.ID))
+		}
+	}
+
+	if len(mentioning) > 0 {
+		mentions, err := store.ListPostMentions(c, mentioning)
+		if err != nil {
+			return err
+		}
+		for _, mention := range mentions {
+			post := &posts[index[mention.PostID]]
+			post.Mentions = append(post.Mentions, newMentionEntity(mention))
+		}
+	}
+
+	tags, err := store.ListPostTags(c, ids)
+	if err != nil {
 		return err
 	}
+	for _, tag := range tags {
+		post := &posts[index[tag.PostID]]
+		post.Tags = append(post.Tags, tag.Name)
+	}
 	return nil
 }
+
+func newPostResponse(post db.Post, userStrID string) dto.PostResponse {
+	return dto.PostResponse{
+		ID:        post.ID,
+		UserID:    post.UserID,
+		UserStrID: userStrID,
+		Text:      post.Text,
+		ParentID:  uint(post.ParentID.Int64),
+		CreatedAt: post.CreatedAt,
+	}
+}
@@ -2,8 +2,13 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
 	mockdb "github.com/PenginAction/go-BulletinBoard/db/mock"
 	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
 	"github.com/PenginAction/go-BulletinBoard/dto"
@@ -24,6 +29,7 @@ func TestCreatePost(t *testing.T) {
 	}
 
 	store := mockdb.NewMockStore(ctrl)
+	expectTx(store)
 	store.EXPECT().
 		CreatePost(gomock.Any(), gomock.Eq(arg)).
 		Times(1).
@@ -33,6 +39,10 @@ func TestCreatePost(t *testing.T) {
 		GetUserStrIdById(gomock.Any(), gomock.Eq(post.UserID)).
 		Times(1).
 		Return(utils.RandomString(10), nil)
+	store.EXPECT().
+		CreatePostEvent(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.PostEvent{ID: 1}, nil)
 
 	req := dto.CreatePostRequest{
 		UserID: post.UserID,
@@ -64,21 +74,29 @@ func TestGetPost(t *testing.T) {
 		Times(1).
 		Return(utils.RandomString(10), nil)
 
+	store.EXPECT().
+		ListPostTags(gomock.Any(), gomock.Eq([]int64{int64(post.ID)})).
+		Times(1).
+		Return([]db.ListPostTagsRow{{PostID: post.ID, Name: "go"}}, nil)
+
 	pu := NewPostUsecase(store)
 	res, err := pu.GetPostById(context.Background(), post.ID)
 	require.NoError(t, err)
 
 	require.Equal(t, post.ID, res.ID)
 	require.Equal(t, post.Text, res.Text)
+	require.Equal(t, []string{"go"}, res.Tags)
 }
 
 func TestGetAllPosts(t *testing.T) {
 	user, _ := RandomUser(t)
+	user.ID = utils.RandomInt(1, 1000)
 
 	n := 5
 	posts := make([]db.Post, n)
 	for i := 0; i < n; i++ {
 		posts[i] = RandomPost(user.ID)
+		posts[i].UserID = user.ID
 	}
 
 	arg := db.ListPostsParams{
@@ -95,10 +113,16 @@ func TestGetAllPosts(t *testing.T) {
 		Times(1).
 		Return(posts, nil)
 
+	// every post has the same author, who is looked up once
 	store.EXPECT().
-		GetUserStrIdById(gomock.Any(), gomock.Any()).
-		Times(n).
-		Return(utils.RandomString(10), nil)
+		ListUsersByIDs(gomock.Any(), gomock.Eq([]int64{int64(user.ID)})).
+		Times(1).
+		Return([]db.User{user}, nil)
+
+	store.EXPECT().
+		ListPostTags(gomock.Any(), gomock.Len(n)).
+		Times(1).
+		Return([]db.ListPostTagsRow{}, nil)
 
 	req := dto.AllPostsRequest{
 		PageID:   1,
@@ -114,7 +138,73 @@ func TestGetAllPosts(t *testing.T) {
 	for i, post := range res {
 		require.Equal(t, posts[i].ID, post.ID)
 		require.Equal(t, posts[i].Text, post.Text)
+		require.Equal(t, user.UserStrID, post.UserStrID)
+	}
+}
+
+func TestListReplies(t *testing.T) {
+	store := memory.NewStore()
+	pu := NewPostUsecase(store)
+	user := createTestUser(t, store, dto.RoleUser)
+	ctx := context.Background()
+
+	parent, err := pu.CreatePost(ctx, dto.CreatePostRequest{UserID: user.ID, Text: "hello"})
+	require.NoError(t, err)
+	var ids []uint
+	for i := 0; i < 3; i++ {
+		reply, err := pu.CreatePost(ctx, dto.CreatePostRequest{UserID: user.ID, Text: "#reply", ParentID: parent.ID})
+		require.NoError(t, err)
+		ids = append(ids, reply.ID)
+	}
+	_, err = pu.CreatePost(ctx, dto.CreatePostRequest{UserID: user.ID, Text: "unrelated"})
+	require.NoError(t, err)
+
+	replies, err := pu.ListPosts(ctx, dto.ListPostsRequest{ParentID: parent.ID, Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, replies, 2)
+	for i, reply := range replies {
+		require.Equal(t, ids[i+1], reply.ID)
+		require.Equal(t, parent.ID, reply.ParentID)
+		require.Equal(t, user.UserStrID, reply.UserStrID)
+		require.Equal(t, []string{"reply"}, reply.Tags)
+	}
+}
+
+func TestListRepliesOfPosts(t *testing.T) {
+	store := memory.NewStore()
+	pu := NewPostUsecase(store)
+	user := createTestUser(t, store, dto.RoleUser)
+	ctx := context.Background()
+
+	var parents []uint
+	replies := map[uint][]uint{}
+	for i := 0; i < 3; i++ {
+		parent, err := pu.CreatePost(ctx, dto.CreatePostRequest{UserID: user.ID, Text: "hello"})
+		require.NoError(t, err)
+		parents = append(parents, parent.ID)
 	}
+	// the last post has no replies
+	for i := 0; i < 3; i++ {
+		for _, parent := range parents[:2] {
+			reply, err := pu.CreatePost(ctx, dto.CreatePostRequest{UserID: user.ID, Text: "#reply", ParentID: parent})
+			require.NoError(t, err)
+			replies[parent] = append(replies[parent], reply.ID)
+		}
+	}
+
+	res, err := pu.ListReplies(ctx, dto.ListRepliesRequest{ParentIDs: parents, Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	for _, parent := range parents[:2] {
+		require.Len(t, res[parent], 2)
+		for i, reply := range res[parent] {
+			require.Equal(t, replies[parent][i+1], reply.ID)
+			require.Equal(t, parent, reply.ParentID)
+			require.Equal(t, user.UserStrID, reply.UserStrID)
+			require.Equal(t, []string{"reply"}, reply.Tags)
+		}
+	}
+	require.Empty(t, res[parents[2]])
 }
 
 func TestUpdatePost(t *testing.T) {
@@ -129,6 +219,15 @@ func TestUpdatePost(t *testing.T) {
 	}
 
 	store := mockdb.NewMockStore(ctrl)
+	expectTx(store)
+	store.EXPECT().
+		GetPost(gomock.Any(), gomock.Eq(post.ID)).
+		Times(1).
+		Return(post, nil)
+	store.EXPECT().
+		ListPostTags(gomock.Any(), gomock.Eq([]int64{int64(post.ID)})).
+		Times(1).
+		Return([]db.ListPostTagsRow{}, nil)
 	store.EXPECT().
 		UpdatePost(gomock.Any(), gomock.Eq(arg)).
 		Times(1).
@@ -138,6 +237,10 @@ func TestUpdatePost(t *testing.T) {
 		GetUserStrIdById(gomock.Any(), gomock.Eq(post.UserID)).
 		Times(1).
 		Return(utils.RandomString(10), nil)
+	store.EXPECT().
+		CreatePostEvent(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.PostEvent{ID: 1}, nil)
 
 	req := dto.UpdatePostRequest{
 		ID:   post.ID,
@@ -159,16 +262,106 @@ func TestDeletePost(t *testing.T) {
 	defer ctrl.Finish()
 
 	store := mockdb.NewMockStore(ctrl)
+	expectTx(store)
+	store.EXPECT().
+		GetPost(gomock.Any(), gomock.Eq(post.ID)).
+		Times(1).
+		Return(post, nil)
 	store.EXPECT().
 		DeletePost(gomock.Any(), gomock.Eq(post.ID)).
 		Times(1).
 		Return(nil)
+	store.EXPECT().
+		CreatePostEvent(gomock.Any(), gomock.Eq(db.CreatePostEventParams{
+			Type:    PostDeleted,
+			PostID:  post.ID,
+			Payload: json.RawMessage(fmt.Sprintf(`{"id":%d}`, post.ID)),
+		})).
+		Times(1).
+		Return(db.PostEvent{ID: 1}, nil)
 
 	pu := NewPostUsecase(store)
 	err := pu.DeletePost(context.Background(), post.ID)
 	require.NoError(t, err)
 }
 
+func TestPurgePosts(t *testing.T) {
+	user, _ := RandomUser(t)
+	user.ID = utils.RandomInt(1, 1000)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	before := time.Now().Add(-24 * time.Hour)
+	posts := make([]db.Post, 5)
+	for i := range posts {
+		posts[i] = RandomPost(user.ID)
+		posts[i].ID = uint(i + 1)
+	}
+
+	store := mockdb.NewMockStore(ctrl)
+	expectTx(store)
+	store.EXPECT().
+		GetUserByUserStrId(gomock.Any(), gomock.Eq(user.UserStrID)).
+		Times(1).
+		Return(user, nil)
+	store.EXPECT().
+		DeletePostsByUser(gomock.Any(), gomock.Eq(user.ID)).
+		Times(1).
+		Return(posts[:2], nil)
+	store.EXPECT().
+		DeletePostsCreatedBefore(gomock.Any(), gomock.Eq(before)).
+		Times(1).
+		Return(posts, nil)
+	// one event for each post deleted
+	store.EXPECT().
+		CreatePostEvent(gomock.Any(), gomock.Any()).
+		Times(7).
+		DoAndReturn(func(_ context.Context, arg db.CreatePostEventParams) (db.PostEvent, error) {
+			require.Equal(t, PostDeleted, arg.Type)
+			require.JSONEq(t, fmt.Sprintf(`{"id":%d}`, arg.PostID), string(arg.Payload))
+			return db.PostEvent{ID: 1}, nil
+		})
+
+	pu := NewPostUsecase(store)
+
+	n, err := pu.PurgePosts(context.Background(), dto.PurgePostsRequest{UserStrID: user.UserStrID})
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+
+	n, err = pu.PurgePosts(context.Background(), dto.PurgePostsRequest{Before: before})
+	require.NoError(t, err)
+	require.EqualValues(t, 5, n)
+
+	_, err = pu.PurgePosts(context.Background(), dto.PurgePostsRequest{})
+	require.ErrorIs(t, err, ErrInvalidFilter)
+
+	_, err = pu.PurgePosts(context.Background(), dto.PurgePostsRequest{UserStrID: user.UserStrID, Before: before})
+	require.ErrorIs(t, err, ErrInvalidFilter)
+}
+
+func TestPurgePostsFails(t *testing.T) {
+	user, _ := RandomUser(t)
+	user.ID = utils.RandomInt(1, 1000)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	expectTx(store)
+	store.EXPECT().
+		GetUserByUserStrId(gomock.Any(), gomock.Eq(user.UserStrID)).
+		Times(1).
+		Return(user, nil)
+	store.EXPECT().
+		DeletePostsByUser(gomock.Any(), gomock.Eq(user.ID)).
+		Times(1).
+		Return(nil, errors.New("connection reset"))
+
+	pu := NewPostUsecase(store)
+	n, err := pu.PurgePosts(context.Background(), dto.PurgePostsRequest{UserStrID: user.UserStrID})
+	require.EqualError(t, err, "connection reset")
+	require.Zero(t, n)
+}
+
 func RandomPost(userID uint) db.Post {
 	post := db.Post{
 		UserID: utils.RandomInt(1, 1000),
@@ -177,3 +370,13 @@ func RandomPost(userID uint) db.Post {
 
 	return post
 }
+
+// expectTx makes store run the transactions asked of it on itself
+func expectTx(store *mockdb.MockStore) {
+	store.EXPECT().
+		ExecTx(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ context.Context, fn func(db.Store) error) error {
+			return fn(store)
+		})
+}
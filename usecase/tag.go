@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+)
+
+const (
+	// maxTags bounds how many tags a post can have
+	maxTags = 10
+	// maxTagLength bounds the length of a tag, in code points
+	maxTagLength = 50
+)
+
+var (
+	// hashtagPattern matches # followed by a tag, unless the # is part of a
+	// word, an HTML character reference or a URL fragment
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#/])#([\p{L}\p{N}_]+)`)
+	// tagPattern is what a normalized tag is made of: letters, digits and
+	// underscores, at least one of them a letter
+	tagPattern = regexp.MustCompile(`^[\p{L}\p{N}_]*\p{L}[\p{L}\p{N}_]*$`)
+	// tagPrefixPattern is what the start of a tag is made of
+	tagPrefixPattern = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+)
+
+// normalizeTag returns tag lower cased, without a leading #, and whether
+// that is a valid tag
+func normalizeTag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	return tag, tagPattern.MatchString(tag) && utf8.RuneCountInString(tag) <= maxTagLength
+}
+
+// normalizeTags returns the tags given explicitly with a post, normalized
+// and deduplicated, or a validation error
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > maxTags {
+		return nil, NewValidationError("tags", "maxItems", "tags must have at most 10 items", strconv.Itoa(maxTags))
+	}
+	var names []string
+	for _, tag := range tags {
+		name, ok := normalizeTag(tag)
+		if !ok {
+			return nil, errInvalidTags
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// postTags returns the tags of a post with the text and the tags given
+// explicitly, these first, up to maxTags
+func postTags(text string, explicit []string) []string {
+	names := slices.Clone(explicit)
+	for _, m := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		if len(names) == maxTags {
+			break
+		}
+		// hashtags that are not valid tags, as #1, are not tags
+		if name, ok := normalizeTag(m[1]); ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// linkTags tags the post with id postID with names, creating the tags as
+// needed, and returns the names sorted as they are listed
+func linkTags(c context.Context, tx db.Querier, postID uint, names []string) ([]string, error) {
+	for _, name := range names {
+		tag, err := tx.UpsertTag(c, name)
+		if err != nil {
+			return nil, err
+		}
+		if err := tx.CreatePostTag(c, db.CreatePostTagParams{PostID: postID, TagID: tag.ID}); err != nil {
+			return nil, err
+		}
+	}
+	sorted := slices.Clone(names)
+	slices.Sort(sorted)
+	return sorted, nil
+}
+
+// explicitTags returns the tags of a post that its text does not explain,
+// so that those given explicitly survive edits of the text. Tags given
+// explicitly that the text has too cannot be told apart, and follow the
+// text.
+func explicitTags(text string, tags []string) []string {
+	inText := postTags(text, nil)
+	var explicit []string
+	for _, tag := range tags {
+		if !slices.Contains(inText, tag) {
+			explicit = append(explicit, tag)
+		}
+	}
+	return explicit
+}
+
+// escapeLike escapes the LIKE wildcards in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
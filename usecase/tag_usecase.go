@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/tracing"
+)
+
+const (
+	// maxTagsPage bounds how many posts or tags are listed at once
+	maxTagsPage = 100
+	// maxTrendingWindow bounds how far back trending tags are counted
+	maxTrendingWindow = 30 * 24 * time.Hour
+)
+
+type ITagUsecase interface {
+	ListPostsByTag(c context.Context, req dto.ListTagPostsRequest) ([]dto.PostResponse, error)
+	// SearchTags suggests the tags starting with a prefix, for autocomplete
+	SearchTags(c context.Context, req dto.SearchTagsRequest) ([]dto.TagResponse, error)
+	// TrendingTags returns the tags of the most posts created within a
+	// sliding window ending now
+	TrendingTags(c context.Context, req dto.TrendingTagsRequest) ([]dto.TagResponse, error)
+}
+
+type tagUsecase struct {
+	store db.Store
+	now   func() time.Time
+}
+
+func NewTagUsecase(store db.Store) ITagUsecase {
+	return &tagUsecase{store: store, now: time.Now}
+}
+
+func (tu *tagUsecase) ListPostsByTag(c context.Context, req dto.ListTagPostsRequest) ([]dto.PostResponse, error) {
+	c, span := tracing.Tracer().Start(c, "TagUsecase.ListPostsByTag")
+	defer span.End()
+
+	tag, ok := normalizeTag(req.Tag)
+	if !ok {
+		return []dto.PostResponse{}, NewValidationError("tag", "invalid_tag", "tag must be letters, digits and underscores, with a letter")
+	}
+	if err := checkTagsPage(req.Limit); err != nil {
+		return []dto.PostResponse{}, err
+	}
+
+	before := req.Before
+	if before == 0 {
+		before = math.MaxInt64
+	}
+	posts, err := tu.store.ListPostsByTag(c, db.ListPostsByTagParams{
+		Name:  tag,
+		ID:    before,
+		Limit: req.Limit,
+	})
+	if err != nil {
+		return []dto.PostResponse{}, err
+	}
+	return postResponses(c, tu.store, posts)
+}
+
+func (tu *tagUsecase) SearchTags(c context.Context, req dto.SearchTagsRequest) ([]dto.TagResponse, error) {
+	c, span := tracing.Tracer().Start(c, "TagUsecase.SearchTags")
+	defer span.End()
+
+	prefix := strings.ToLower(strings.TrimPrefix(req.Prefix, "#"))
+	if prefix == "" {
+		return []dto.TagResponse{}, NewValidationError("prefix", "required", "prefix is required")
+	}
+	// a prefix is a tag yet to be finished, so it may lack a letter
+	if !tagPrefixPattern.MatchString(prefix) || utf8.RuneCountInString(prefix) > maxTagLength {
+		return []dto.TagResponse{}, NewValidationError("prefix", "invalid_tag", "prefix must be letters, digits and underscores")
+	}
+	if err := checkTagsPage(req.Limit); err != nil {
+		return []dto.TagResponse{}, err
+	}
+
+	tags, err := tu.store.SearchTags(c, db.SearchTagsParams{
+		Name:  escapeLike(prefix) + "%",
+		Limit: req.Limit,
+	})
+	if err != nil {
+		return []dto.TagResponse{}, err
+	}
+	res := make([]dto.TagResponse, 0, len(tags))
+	for _, tag := range tags {
+		res = append(res, dto.TagResponse(tag))
+	}
+	return res, nil
+}
+
+func (tu *tagUsecase) TrendingTags(c context.Context, req dto.TrendingTagsRequest) ([]dto.TagResponse, error) {
+	c, span := tracing.Tracer().Start(c, "TagUsecase.TrendingTags")
+	defer span.End()
+
+	if req.Window < time.Minute {
+		return []dto.TagResponse{}, NewValidationError("window", "minimum", "window must be at least 1m", "1m")
+	}
+	if req.Window > maxTrendingWindow {
+		return []dto.TagResponse{}, NewValidationError("window", "maximum", "window must be at most 720h", "720h")
+	}
+	if err := checkTagsPage(req.Limit); err != nil {
+		return []dto.TagResponse{}, err
+	}
+
+	tags, err := tu.store.ListTrendingTags(c, db.ListTrendingTagsParams{
+		CreatedAt: tu.now().Add(-req.Window).UTC(),
+		Limit:     req.Limit,
+	})
+	if err != nil {
+		return []dto.TagResponse{}, err
+	}
+	res := make([]dto.TagResponse, 0, len(tags))
+	for _, tag := range tags {
+		res = append(res, dto.TagResponse(tag))
+	}
+	return res, nil
+}
+
+func checkTagsPage(limit int32) error {
+	if limit < 1 {
+		return NewValidationError("limit", "minimum", "limit must be at least 1", "1")
+	}
+	if limit > maxTagsPage {
+		return NewValidationError("limit", "maximum", "limit must be at most 100", strconv.Itoa(maxTagsPage))
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostTagsFromText(t *testing.T) {
+	require.Equal(t, []string{"go", "日本語", "a_1"},
+		postTags("#Go and #go, #1 &#39; http://x/#frag a#b #日本語 (#A_1)", nil))
+	require.Equal(t, []string{"news", "go"}, postTags("#go #news", []string{"news"}))
+
+	text := ""
+	for i := 0; i < maxTags+5; i++ {
+		text += " #tag" + string(rune('a'+i))
+	}
+	require.Len(t, postTags(text, nil), maxTags)
+	require.Equal(t, []string{"x"}, explicitTags(text, []string{"taga", "x"}))
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := normalizeTags([]string{"#Go", "go", "日本語"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"go", "日本語"}, tags)
+
+	for _, tag := range []string{"", "#", "42", "a-b", "a b", strings.Repeat("a", maxTagLength+1)} {
+		_, err = normalizeTags([]string{tag})
+		requireFieldError(t, err, "tags", "invalid_tag")
+	}
+	_, err = normalizeTags(make([]string, maxTags+1))
+	requireFieldError(t, err, "tags", "maxItems")
+}
+
+func TestPostTags(t *testing.T) {
+	store := memory.NewStore()
+	pu := NewPostUsecase(store)
+	user := createTestUser(t, store, dto.RoleUser)
+	ctx := context.Background()
+
+	post, err := pu.CreatePost(ctx, dto.CreatePostRequest{UserID: user.ID, Text: "about #Go", Tags: []string{"#News", "go"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"go", "news"}, post.Tags)
+	got, err := pu.GetPostById(ctx, post.ID)
+	require.NoError(t, err)
+	require.Equal(t, post.Tags, got.Tags)
+
+	// the tags given explicitly survive edits, those of the text follow it,
+	// go included as it was in the text
+	updated, err := pu.UpdatePost(ctx, dto.UpdatePostRequest{ID: post.ID, Text: "about #rust"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"news", "rust"}, updated.Tags)
+	updated, err = pu.UpdatePost(ctx, dto.UpdatePostRequest{ID: post.ID, Text: "about nothing"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"news"}, updated.Tags)
+
+	posts, err := pu.ListPosts(ctx, dto.ListPostsRequest{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, posts, 1)
+	require.Equal(t, updated.Tags, posts[0].Tags)
+
+	_, err = pu.CreatePost(ctx, dto.CreatePostRequest{UserID: user.ID, Text: "x", Tags: []string{"no tag"}})
+	requireFieldError(t, err, "tags", "invalid_tag")
+}
+
+func TestListPostsByTag(t *testing.T) {
+	store := memory.NewStore()
+	pu := NewPostUsecase(store)
+	tu := NewTagUsecase(store)
+	alice, bob := createTestUser(t, store, dto.RoleUser), createTestUser(t, store, dto.RoleUser)
+	ctx := context.Background()
+
+	var ids []uint
+	for _, user := range []struct{ id uint }{{alice.ID}, {bob.ID}, {alice.ID}} {
+		post, err := pu.CreatePost(ctx, dto.CreatePostRequest{UserID: user.id, Text: "#Go @" + bob.UserStrID})
+		require.NoError(t, err)
+		ids = append(ids, post.ID)
+	}
+	_, err := pu.CreatePost(ctx, dto.CreatePostRequest{UserID: alice.ID, Text: "#rust"})
+	require.NoError(t, err)
+
+	// newest first, with authors, mentions and tags
+	page, err := tu.ListPostsByTag(ctx, dto.ListTagPostsRequest{Tag: "#GO", Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Equal(t, ids[2], page[0].ID)
+	require.Equal(t, ids[1], page[1].ID)
+	require.Equal(t, bob.UserStrID, page[1].UserStrID)
+	require.Equal(t, []string{"go"}, page[1].Tags)
+	require.Len(t, page[1].Mentions, 1)
+
+	page, err = tu.ListPostsByTag(ctx, dto.ListTagPostsRequest{Tag: "go", Before: page[1].ID, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, ids[0], page[0].ID)
+
+	page, err = tu.ListPostsByTag(ctx, dto.ListTagPostsRequest{Tag: "unused", Limit: 2})
+	require.NoError(t, err)
+	require.Empty(t, page)
+
+	_, err = tu.ListPostsByTag(ctx, dto.ListTagPostsRequest{Tag: "a-b", Limit: 2})
+	requireFieldError(t, err, "tag", "invalid_tag")
+	_, err = tu.ListPostsByTag(ctx, dto.ListTagPostsRequest{Tag: "go", Limit: maxTagsPage + 1})
+	requireFieldError(t, err, "limit", "maximum")
+}
+
+func TestSearchTags(t *testing.T) {
+	store := memory.NewStore()
+	pu := NewPostUsecase(store)
+	tu := NewTagUsecase(store)
+	user := createTestUser(t, store, dto.RoleUser)
+	ctx := context.Background()
+
+	for _, text := range []string{"#golang #go_1", "#golang #gox1", "#rust"} {
+		_, err := pu.CreatePost(ctx, dto.CreatePostRequest{UserID: user.ID, Text: text})
+		require.NoError(t, err)
+	}
+
+	tags, err := tu.SearchTags(ctx, dto.SearchTagsRequest{Prefix: "#GO", Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, []dto.TagResponse{{Name: "golang", PostCount: 2}, {Name: "go_1", PostCount: 1}, {Name: "gox1", PostCount: 1}}, tags)
+
+	// _ is not a wildcard
+	tags, err = tu.SearchTags(ctx, dto.SearchTagsRequest{Prefix: "go_", Limit: 10})
+	require.NoError(t, err)
+	require.Equal(t, []dto.TagResponse{{Name: "go_1", PostCount: 1}}, tags)
+
+	_, err = tu.SearchTags(ctx, dto.SearchTagsRequest{Prefix: "#", Limit: 10})
+	requireFieldError(t, err, "prefix", "required")
+	_, err = tu.SearchTags(ctx, dto.SearchTagsRequest{Prefix: "g%", Limit: 10})
+	requireFieldError(t, err, "prefix", "invalid_tag")
+	_, err = tu.SearchTags(ctx, dto.SearchTagsRequest{Prefix: "go", Limit: 0})
+	requireFieldError(t, err, "limit", "minimum")
+}
+
+func TestTrendingTags(t *testing.T) {
+	store := memory.NewStore()
+	pu := NewPostUsecase(store)
+	tu := NewTagUsecase(store)
+	user := createTestUser(t, store, dto.RoleUser)
+	ctx := context.Background()
+
+	for _, text := range []string{"#go #rust", "#go", "#zig #rust", "#go"} {
+		_, err := pu.CreatePost(ctx, dto.CreatePostRequest{UserID: user.ID, Text: text})
+		require.NoError(t, err)
+	}
+
+	tags, err := tu.TrendingTags(ctx, dto.TrendingTagsRequest{Window: time.Hour, Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, []dto.TagResponse{{Name: "go", PostCount: 3}, {Name: "rust", PostCount: 2}}, tags)
+
+	// the window slides past the posts
+	tu.(*tagUsecase).now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	tags, err = tu.TrendingTags(ctx, dto.TrendingTagsRequest{Window: time.Hour, Limit: 2})
+	require.NoError(t, err)
+	require.Empty(t, tags)
+
+	_, err = tu.TrendingTags(ctx, dto.TrendingTagsRequest{Window: time.Second, Limit: 2})
+	requireFieldError(t, err, "window", "minimum")
+	_, err = tu.TrendingTags(ctx, dto.TrendingTagsRequest{Window: maxTrendingWindow + time.Hour, Limit: 2})
+	requireFieldError(t, err, "window", "maximum")
+}
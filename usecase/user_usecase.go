@@ -2,26 +2,57 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
 
 	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
 	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/metrics"
+	"github.com/PenginAction/go-BulletinBoard/tracing"
 	"github.com/PenginAction/go-BulletinBoard/utils"
 )
 
 type IUserUsecase interface {
 	SignUp(c context.Context, req dto.CreateUserRequest) (dto.CreateUserResponse, error)
 	Login(c context.Context, req dto.LoginRequest) (string, error)
+	Authenticate(c context.Context, claims *dto.JwtCustomClaims) error
+	GetUsersByIDs(c context.Context, ids []uint) ([]dto.UserResponse, error)
+	SetRole(c context.Context, userStrID string, role string) (dto.UserResponse, error)
+	SetLocked(c context.Context, userStrID string, locked bool) (dto.UserResponse, error)
+	RevokeTokens(c context.Context, userStrID string) (dto.UserResponse, error)
+	DeleteUser(c context.Context, userStrID string, purgePosts bool) error
 }
 
+// dummyHash is compared against when a login names an unknown email
+var dummyHash = sync.OnceValue(func() string {
+	hash, err := utils.HashPassword("not a real password")
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
 type userUsecase struct {
-	userRepository db.Querier
+	userRepository db.Store
+	// outbox holds the kinds of the jobs queued for every user signing up,
+	// and for every post deleted with its user
+	outbox []string
 }
 
-func NewUserUsecase(userRepository db.Querier) IUserUsecase {
-	return &userUsecase{userRepository}
+// NewUserUsecase returns the user usecase. Every user signing up, and every
+// post deleted with its user, queues a job of each of the outbox kinds, with
+// an OutboxEvent as payload, in the transaction making the change.
+func NewUserUsecase(userRepository db.Store, outbox ...string) IUserUsecase {
+	return &userUsecase{userRepository, outbox}
 }
 
 func (uu *userUsecase) SignUp(c context.Context, req dto.CreateUserRequest) (dto.CreateUserResponse, error) {
+	c, span := tracing.Tracer().Start(c, "UserUsecase.SignUp")
+	defer span.End()
+
 	hashPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
 		return dto.CreateUserResponse{}, err
@@ -33,35 +64,236 @@ func (uu *userUsecase) SignUp(c context.Context, req dto.CreateUserRequest) (dto
 		Password:  hashPassword,
 	}
 
-	user, err := uu.userRepository.CreateUser(c, newUser)
+	var rep dto.CreateUserResponse
+	err = uu.userRepository.ExecTx(c, func(tx db.Store) error {
+		user, err := tx.CreateUser(c, newUser)
+		if err != nil {
+			return classify(err)
+		}
+
+		rep = dto.CreateUserResponse{
+			ID:        user.ID,
+			UserStrID: user.UserStrID,
+			Email:     user.Email,
+			CreatedAt: user.CreatedAt,
+		}
+		return enqueue(c, tx, uu.outbox, UserSignedUp, user.ID, rep)
+	})
 	if err != nil {
 		return dto.CreateUserResponse{}, err
 	}
-
-	rep := dto.CreateUserResponse{
-		ID:        user.ID,
-		UserStrID: user.UserStrID,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt,
-	}
+	metrics.Signups.Inc()
 
 	return rep, nil
 }
 
-func (uu *userUsecase) Login(c context.Context, req dto.LoginRequest) (string, error) {
+func (uu *userUsecase) Login(c context.Context, req dto.LoginRequest) (token string, err error) {
+	c, span := tracing.Tracer().Start(c, "UserUsecase.Login")
+	defer span.End()
+
+	defer func() {
+		if err != nil {
+			metrics.Logins.WithLabelValues(metrics.LoginFailed).Inc()
+		} else {
+			metrics.Logins.WithLabelValues(metrics.LoginSucceeded).Inc()
+		}
+	}()
+
 	user, err := uu.userRepository.GetUserByEmail(c, req.Email)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		// spend as long as a real password check so the response time does
+		// not tell whether the email is registered
+		utils.CheckPassword(req.Password, dummyHash())
+		return "", ErrInvalidCredentials.With(err)
+	}
 	if err != nil {
 		return "", err
 	}
 
 	err = utils.CheckPassword(req.Password, user.Password)
 	if err != nil {
-		return "", err
+		slog.WarnContext(c, "login with wrong password", slog.Uint64("user_id", uint64(user.ID)))
+		return "", ErrInvalidCredentials.With(err)
+	}
+
+	if user.LockedAt.Valid {
+		slog.WarnContext(c, "login of locked user", slog.Uint64("user_id", uint64(user.ID)))
+		return "", ErrUserLocked
 	}
 
-	token, err := utils.CreateValidToken(user.ID)
+	token, err = utils.CreateValidToken(user.ID)
 	if err != nil {
 		return "", err
 	}
 	return token, nil
 }
+
+// Authenticate checks that the token holder still exists, is not locked and
+// has not had its tokens revoked since the token was issued. On success the
+// claims carry the user's current role.
+func (uu *userUsecase) Authenticate(c context.Context, claims *dto.JwtCustomClaims) error {
+	c, span := tracing.Tracer().Start(c, "UserUsecase.Authenticate")
+	defer span.End()
+
+	user, err := uu.userRepository.GetUser(c, claims.ID)
+	if err != nil {
+		// the token holder has been deleted
+		return notFound(err, ErrUnauthorized)
+	}
+
+	if user.LockedAt.Valid {
+		return ErrUserLocked
+	}
+
+	if user.TokensRevokedAt.Valid {
+		// iat only has second precision, so a token issued in the same second
+		// as the revocation is treated as revoked
+		if claims.IssuedAt == nil || claims.IssuedAt.Unix() <= user.TokensRevokedAt.Time.Unix() {
+			slog.DebugContext(c, "revoked token rejected", slog.Uint64("user_id", uint64(user.ID)))
+			return ErrTokenRevoked
+		}
+	}
+
+	claims.Role = user.Role
+	return nil
+}
+
+// GetUsersByIDs returns the users with the given ids in one query, ordered by
+// id. Ids of users that do not exist are skipped.
+func (uu *userUsecase) GetUsersByIDs(c context.Context, ids []uint) ([]dto.UserResponse, error) {
+	c, span := tracing.Tracer().Start(c, "UserUsecase.GetUsersByIDs")
+	defer span.End()
+
+	arg := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		arg = append(arg, int64(id))
+	}
+	users, err := uu.userRepository.ListUsersByIDs(c, arg)
+	if err != nil {
+		return nil, err
+	}
+	rep := make([]dto.UserResponse, 0, len(users))
+	for _, user := range users {
+		rep = append(rep, newUserResponse(user))
+	}
+	return rep, nil
+}
+
+func (uu *userUsecase) SetRole(c context.Context, userStrID string, role string) (dto.UserResponse, error) {
+	c, span := tracing.Tracer().Start(c, "UserUsecase.SetRole")
+	defer span.End()
+
+	switch role {
+	case dto.RoleUser, dto.RoleModerator, dto.RoleAdmin:
+	default:
+		return dto.UserResponse{}, ErrInvalidRole
+	}
+
+	user, err := uu.userRepository.GetUserByUserStrId(c, userStrID)
+	if err != nil {
+		return dto.UserResponse{}, notFound(err, ErrUserNotFound)
+	}
+
+	arg := db.UpdateUserRoleParams{
+		ID:   user.ID,
+		Role: role,
+	}
+	user, err = uu.userRepository.UpdateUserRole(c, arg)
+	if err != nil {
+		return dto.UserResponse{}, err
+	}
+	slog.InfoContext(c, "user role changed", slog.String("user_str_id", userStrID), slog.String("role", role))
+	return newUserResponse(user), nil
+}
+
+func (uu *userUsecase) SetLocked(c context.Context, userStrID string, locked bool) (dto.UserResponse, error) {
+	c, span := tracing.Tracer().Start(c, "UserUsecase.SetLocked")
+	defer span.End()
+
+	user, err := uu.userRepository.GetUserByUserStrId(c, userStrID)
+	if err != nil {
+		return dto.UserResponse{}, notFound(err, ErrUserNotFound)
+	}
+
+	arg := db.UpdateUserLockedAtParams{
+		ID: user.ID,
+	}
+	if locked {
+		arg.LockedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	user, err = uu.userRepository.UpdateUserLockedAt(c, arg)
+	if err != nil {
+		return dto.UserResponse{}, err
+	}
+	slog.InfoContext(c, "user lock changed", slog.String("user_str_id", userStrID), slog.Bool("locked", locked))
+	return newUserResponse(user), nil
+}
+
+func (uu *userUsecase) RevokeTokens(c context.Context, userStrID string) (dto.UserResponse, error) {
+	c, span := tracing.Tracer().Start(c, "UserUsecase.RevokeTokens")
+	defer span.End()
+
+	user, err := uu.userRepository.GetUserByUserStrId(c, userStrID)
+	if err != nil {
+		return dto.UserResponse{}, notFound(err, ErrUserNotFound)
+	}
+
+	user, err = uu.userRepository.RevokeUserTokens(c, user.ID)
+	if err != nil {
+		return dto.UserResponse{}, err
+	}
+	slog.InfoContext(c, "user tokens revoked", slog.String("user_str_id", userStrID))
+	return newUserResponse(user), nil
+}
+
+func (uu *userUsecase) DeleteUser(c context.Context, userStrID string, purgePosts bool) error {
+	c, span := tracing.Tracer().Start(c, "UserUsecase.DeleteUser")
+	defer span.End()
+
+	user, err := uu.userRepository.GetUserByUserStrId(c, userStrID)
+	if err != nil {
+		return notFound(err, ErrUserNotFound)
+	}
+
+	var posts []db.Post
+	err = uu.userRepository.ExecTx(c, func(tx db.Store) error {
+		// the user is deleted with the posts or not at all
+		if purgePosts {
+			var err error
+			if posts, err = tx.DeletePostsByUser(c, user.ID); err != nil {
+				return err
+			}
+			if err := logPurgedPosts(c, tx, uu.outbox, posts); err != nil {
+				return err
+			}
+		}
+		err := tx.DeleteUser(c, user.ID)
+		if db.ErrorCode(err) == db.ForeignKeyViolation {
+			return ErrUserHasPosts
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if purgePosts {
+		slog.InfoContext(c, "posts purged", slog.String("user_str_id", userStrID), slog.Int("count", len(posts)))
+	}
+	slog.InfoContext(c, "user deleted", slog.String("user_str_id", userStrID))
+	return nil
+}
+
+func newUserResponse(user db.User) dto.UserResponse {
+	rep := dto.UserResponse{
+		ID:        user.ID,
+		UserStrID: user.UserStrID,
+		Email:     user.Email,
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt,
+	}
+	if user.LockedAt.Valid {
+		lockedAt := user.LockedAt.Time
+		rep.LockedAt = &lockedAt
+	}
+	return rep
+}
@@ -2,15 +2,21 @@ package usecase
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	mockdb "github.com/PenginAction/go-BulletinBoard/db/mock"
 	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
 	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/metrics"
 	"github.com/PenginAction/go-BulletinBoard/utils"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -54,6 +60,7 @@ func TestSignUp(t *testing.T) {
 	}
 
 	store := mockdb.NewMockStore(ctrl)
+	expectTx(store)
 	store.EXPECT().
 		CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
 		Times(1).
@@ -73,6 +80,48 @@ func TestSignUp(t *testing.T) {
 	require.Equal(t, user.Email, res.Email)
 }
 
+func TestSignUpConflict(t *testing.T) {
+	cases := []struct {
+		constraint string
+		field      string
+	}{
+		{constraint: "users_email_key", field: "email"},
+		{constraint: "users_user_str_id_key", field: "user_str_id"},
+	}
+
+	for i := range cases {
+		tc := cases[i]
+		t.Run(tc.field, func(t *testing.T) {
+			user, password := RandomUser(t)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			expectTx(store)
+			store.EXPECT().
+				CreateUser(gomock.Any(), gomock.Any()).
+				Times(1).
+				Return(db.User{}, &pq.Error{Code: db.UniqueViolation, Constraint: tc.constraint})
+
+			req := dto.CreateUserRequest{
+				UserStrID: user.UserStrID,
+				Email:     user.Email,
+				Password:  password,
+			}
+
+			uu := NewUserUsecase(store)
+			_, err := uu.SignUp(context.Background(), req)
+			require.ErrorIs(t, err, ErrAlreadyExists)
+
+			e, ok := AsError(err)
+			require.True(t, ok)
+			require.Equal(t, KindConflict, e.Kind)
+			require.Len(t, e.Fields, 1)
+			require.Equal(t, tc.field, e.Fields[0].Field)
+		})
+	}
+}
+
 func TestLogin(t *testing.T) {
 	user, password := RandomUser(t)
 	ctrl := gomock.NewController(t)
@@ -95,6 +144,214 @@ func TestLogin(t *testing.T) {
 	require.NotEmpty(t, token)
 }
 
+func TestLoginInvalidCredentials(t *testing.T) {
+	user, password := RandomUser(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+		Times(1).
+		Return(user, nil)
+	store.EXPECT().
+		GetUserByEmail(gomock.Any(), gomock.Eq("unknown@example.com")).
+		Times(1).
+		Return(db.User{}, sql.ErrNoRows)
+
+	uu := NewUserUsecase(store)
+	_, wrongPassword := uu.Login(context.Background(), dto.LoginRequest{Email: user.Email, Password: password + "x"})
+	require.ErrorIs(t, wrongPassword, ErrInvalidCredentials)
+
+	_, unknownEmail := uu.Login(context.Background(), dto.LoginRequest{Email: "unknown@example.com", Password: password})
+	require.ErrorIs(t, unknownEmail, ErrInvalidCredentials)
+
+	// the client must not be able to tell the two apart
+	e1, _ := AsError(wrongPassword)
+	e2, _ := AsError(unknownEmail)
+	require.Equal(t, e1.Kind, e2.Kind)
+	require.Equal(t, e1.Code, e2.Code)
+	require.Equal(t, e1.Message, e2.Message)
+}
+
+func TestLoginLockedUser(t *testing.T) {
+	user, password := RandomUser(t)
+	user.LockedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+		Times(1).
+		Return(user, nil)
+
+	req := dto.LoginRequest{
+		Email:    user.Email,
+		Password: password,
+	}
+
+	failed := testutil.ToFloat64(metrics.Logins.WithLabelValues(metrics.LoginFailed))
+
+	uu := NewUserUsecase(store)
+	token, err := uu.Login(context.Background(), req)
+	require.ErrorIs(t, err, ErrUserLocked)
+	require.Empty(t, token)
+
+	require.Equal(t, failed+1, testutil.ToFloat64(metrics.Logins.WithLabelValues(metrics.LoginFailed)))
+}
+
+func TestAuthenticate(t *testing.T) {
+	issuedAt := time.Now().Add(-time.Hour)
+
+	cases := []struct {
+		name    string
+		modify  func(user *db.User)
+		wantErr error
+	}{
+		{
+			name:   "active user",
+			modify: func(user *db.User) {},
+		},
+		{
+			name: "locked user",
+			modify: func(user *db.User) {
+				user.LockedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			},
+			wantErr: ErrUserLocked,
+		},
+		{
+			name: "tokens revoked after issue",
+			modify: func(user *db.User) {
+				user.TokensRevokedAt = sql.NullTime{Time: issuedAt.Add(time.Minute), Valid: true}
+			},
+			wantErr: ErrTokenRevoked,
+		},
+		{
+			name: "tokens revoked before issue",
+			modify: func(user *db.User) {
+				user.TokensRevokedAt = sql.NullTime{Time: issuedAt.Add(-time.Minute), Valid: true}
+			},
+		},
+	}
+
+	for i := range cases {
+		tc := cases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			user, _ := RandomUser(t)
+			user.ID = utils.RandomInt(1, 1000)
+			user.Role = dto.RoleModerator
+			tc.modify(&user)
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user.ID)).
+				Times(1).
+				Return(user, nil)
+
+			claims := &dto.JwtCustomClaims{
+				ID: user.ID,
+				RegisteredClaims: jwt.RegisteredClaims{
+					IssuedAt: jwt.NewNumericDate(issuedAt),
+				},
+			}
+
+			uu := NewUserUsecase(store)
+			err := uu.Authenticate(context.Background(), claims)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, dto.RoleModerator, claims.Role)
+		})
+	}
+}
+
+func TestSetRole(t *testing.T) {
+	user, _ := RandomUser(t)
+	user.ID = utils.RandomInt(1, 1000)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	promoted := user
+	promoted.Role = dto.RoleModerator
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUserByUserStrId(gomock.Any(), gomock.Eq(user.UserStrID)).
+		Times(1).
+		Return(user, nil)
+	store.EXPECT().
+		UpdateUserRole(gomock.Any(), gomock.Eq(db.UpdateUserRoleParams{ID: user.ID, Role: dto.RoleModerator})).
+		Times(1).
+		Return(promoted, nil)
+
+	uu := NewUserUsecase(store)
+	res, err := uu.SetRole(context.Background(), user.UserStrID, dto.RoleModerator)
+	require.NoError(t, err)
+	require.Equal(t, dto.RoleModerator, res.Role)
+
+	_, err = uu.SetRole(context.Background(), user.UserStrID, "superuser")
+	require.ErrorIs(t, err, ErrInvalidRole)
+}
+
+func TestDeleteUserWithPosts(t *testing.T) {
+	user, _ := RandomUser(t)
+	user.ID = utils.RandomInt(1, 1000)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// the posts and the user are deleted in the same transaction
+	store := mockdb.NewMockStore(ctrl)
+	tx := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ExecTx(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ context.Context, fn func(db.Store) error) error {
+			return fn(tx)
+		})
+	store.EXPECT().
+		GetUserByUserStrId(gomock.Any(), gomock.Eq(user.UserStrID)).
+		Times(2).
+		Return(user, nil)
+	tx.EXPECT().
+		DeleteUser(gomock.Any(), gomock.Eq(user.ID)).
+		Times(1).
+		Return(&pq.Error{Code: db.ForeignKeyViolation})
+
+	uu := NewUserUsecase(store)
+	err := uu.DeleteUser(context.Background(), user.UserStrID, false)
+	require.ErrorIs(t, err, ErrUserHasPosts)
+
+	posts := make([]db.Post, 3)
+	for i := range posts {
+		posts[i] = RandomPost(user.ID)
+		posts[i].ID = uint(i + 1)
+	}
+	tx.EXPECT().
+		DeletePostsByUser(gomock.Any(), gomock.Eq(user.ID)).
+		Times(1).
+		Return(posts, nil)
+	tx.EXPECT().
+		CreatePostEvent(gomock.Any(), gomock.Any()).
+		Times(3).
+		DoAndReturn(func(_ context.Context, arg db.CreatePostEventParams) (db.PostEvent, error) {
+			require.Equal(t, PostDeleted, arg.Type)
+			return db.PostEvent{ID: 1}, nil
+		})
+	tx.EXPECT().
+		DeleteUser(gomock.Any(), gomock.Eq(user.ID)).
+		Times(1).
+		Return(nil)
+
+	err = uu.DeleteUser(context.Background(), user.UserStrID, true)
+	require.NoError(t, err)
+}
+
 func RandomUser(t *testing.T) (user db.User, password string) {
 	password = utils.RandomString(6)
 	hashedPassword, err := utils.HashPassword(password)
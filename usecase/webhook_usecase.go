@@ -0,0 +1,426 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/tracing"
+)
+
+// UserSignedUp is the type of the event of a new user
+const UserSignedUp = "user.signed_up"
+
+// PostReplied is the type of the event of a reply, which concerns the
+// author of the post replied to rather than that of the reply
+const PostReplied = "post.replied"
+
+// Statuses of webhook deliveries
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// webhookEvents are the events webhooks can subscribe to, mapped to whether
+// only admins may. Webhooks of other users only get the events of their
+// owner's posts; those of admins get every event.
+var webhookEvents = map[string]bool{
+	PostCreated:  false,
+	PostUpdated:  false,
+	PostDeleted:  false,
+	PostReplied:  false,
+	UserSignedUp: true,
+}
+
+// maxDeliveriesPage bounds how many deliveries are listed at once
+const maxDeliveriesPage = 100
+
+type IWebhookUsecase interface {
+	CreateWebhook(c context.Context, req dto.CreateWebhookRequest) (dto.WebhookResponse, error)
+	ListWebhooks(c context.Context, userID uint) ([]dto.WebhookResponse, error)
+	GetWebhook(c context.Context, userID uint, id int64) (dto.WebhookResponse, error)
+	UpdateWebhook(c context.Context, req dto.UpdateWebhookRequest) (dto.WebhookResponse, error)
+	DeleteWebhook(c context.Context, userID uint, id int64) error
+	ListWebhookDeliveries(c context.Context, req dto.ListWebhookDeliveriesRequest) ([]dto.WebhookDeliveryResponse, error)
+	// Redeliver queues the payload of a delivery again, whatever became of
+	// it
+	Redeliver(c context.Context, userID uint, webhookID, deliveryID int64) (dto.WebhookDeliveryResponse, error)
+	// Dispatch queues a delivery of an event concerning the user with id
+	// ownerID to every enabled webhook subscribed to it and allowed to see
+	// it. The deliveries are queued all together or not at all.
+	Dispatch(c context.Context, typ string, ownerID uint, data interface{}) error
+}
+
+type webhookUsecase struct {
+	store db.Store
+	now   func() time.Time
+}
+
+func NewWebhookUsecase(store db.Store) IWebhookUsecase {
+	return &webhookUsecase{store: store, now: time.Now}
+}
+
+func (wu *webhookUsecase) CreateWebhook(c context.Context, req dto.CreateWebhookRequest) (dto.WebhookResponse, error) {
+	c, span := tracing.Tracer().Start(c, "WebhookUsecase.CreateWebhook")
+	defer span.End()
+
+	events, err := wu.checkWebhook(c, req.UserID, req.URL, req.Events)
+	if err != nil {
+		return dto.WebhookResponse{}, err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return dto.WebhookResponse{}, err
+	}
+
+	webhook, err := wu.store.CreateWebhook(c, db.CreateWebhookParams{
+		UserID: req.UserID,
+		Url:    req.URL,
+		Secret: secret,
+		Events: events,
+	})
+	if err != nil {
+		return dto.WebhookResponse{}, err
+	}
+	res := newWebhookResponse(webhook)
+	res.Secret = webhook.Secret
+	return res, nil
+}
+
+func (wu *webhookUsecase) ListWebhooks(c context.Context, userID uint) ([]dto.WebhookResponse, error) {
+	c, span := tracing.Tracer().Start(c, "WebhookUsecase.ListWebhooks")
+	defer span.End()
+
+	webhooks, err := wu.store.ListWebhooksByUser(c, userID)
+	if err != nil {
+		return []dto.WebhookResponse{}, err
+	}
+	res := make([]dto.WebhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		res = append(res, newWebhookResponse(webhook))
+	}
+	return res, nil
+}
+
+func (wu *webhookUsecase) GetWebhook(c context.Context, userID uint, id int64) (dto.WebhookResponse, error) {
+	c, span := tracing.Tracer().Start(c, "WebhookUsecase.GetWebhook")
+	defer span.End()
+
+	webhook, err := wu.getWebhook(c, userID, id)
+	if err != nil {
+		return dto.WebhookResponse{}, err
+	}
+	return newWebhookResponse(webhook), nil
+}
+
+func (wu *webhookUsecase) UpdateWebhook(c context.Context, req dto.UpdateWebhookRequest) (dto.WebhookResponse, error) {
+	c, span := tracing.Tracer().Start(c, "WebhookUsecase.UpdateWebhook")
+	defer span.End()
+
+	webhook, err := wu.getWebhook(c, req.UserID, req.ID)
+	if err != nil {
+		return dto.WebhookResponse{}, err
+	}
+	events, err := wu.checkWebhook(c, req.UserID, req.URL, req.Events)
+	if err != nil {
+		return dto.WebhookResponse{}, err
+	}
+
+	arg := db.UpdateWebhookParams{
+		ID:           webhook.ID,
+		Url:          req.URL,
+		Events:       events,
+		DisabledAt:   webhook.DisabledAt,
+		FailureCount: webhook.FailureCount,
+	}
+	if req.Active != nil {
+		if *req.Active {
+			arg.DisabledAt = sql.NullTime{}
+			arg.FailureCount = 0
+		} else if !webhook.DisabledAt.Valid {
+			arg.DisabledAt = sql.NullTime{Time: wu.now().UTC(), Valid: true}
+		}
+	}
+	webhook, err = wu.store.UpdateWebhook(c, arg)
+	if err != nil {
+		return dto.WebhookResponse{}, notFound(err, ErrWebhookNotFound)
+	}
+	return newWebhookResponse(webhook), nil
+}
+
+func (wu *webhookUsecase) DeleteWebhook(c context.Context, userID uint, id int64) error {
+	c, span := tracing.Tracer().Start(c, "WebhookUsecase.DeleteWebhook")
+	defer span.End()
+
+	if _, err := wu.getWebhook(c, userID, id); err != nil {
+		return err
+	}
+	return wu.store.DeleteWebhook(c, id)
+}
+
+func (wu *webhookUsecase) ListWebhookDeliveries(c context.Context, req dto.ListWebhookDeliveriesRequest) ([]dto.WebhookDeliveryResponse, error) {
+	c, span := tracing.Tracer().Start(c, "WebhookUsecase.ListWebhookDeliveries")
+	defer span.End()
+
+	if req.Limit < 1 {
+		return []dto.WebhookDeliveryResponse{}, NewValidationError("limit", "minimum", "limit must be at least 1", "1")
+	}
+	if req.Limit > maxDeliveriesPage {
+		return []dto.WebhookDeliveryResponse{}, NewValidationError("limit", "maximum", "limit must be at most 100", strconv.Itoa(maxDeliveriesPage))
+	}
+	if _, err := wu.getWebhook(c, req.UserID, req.WebhookID); err != nil {
+		return []dto.WebhookDeliveryResponse{}, err
+	}
+
+	before := req.Before
+	if before == 0 {
+		before = math.MaxInt64
+	}
+	deliveries, err := wu.store.ListWebhookDeliveries(c, db.ListWebhookDeliveriesParams{
+		WebhookID: req.WebhookID,
+		ID:        before,
+		Limit:     req.Limit,
+	})
+	if err != nil {
+		return []dto.WebhookDeliveryResponse{}, err
+	}
+	res := make([]dto.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		res = append(res, newWebhookDeliveryResponse(delivery))
+	}
+	return res, nil
+}
+
+func (wu *webhookUsecase) Redeliver(c context.Context, userID uint, webhookID, deliveryID int64) (dto.WebhookDeliveryResponse, error) {
+	c, span := tracing.Tracer().Start(c, "WebhookUsecase.Redeliver")
+	defer span.End()
+
+	webhook, err := wu.getWebhook(c, userID, webhookID)
+	if err != nil {
+		return dto.WebhookDeliveryResponse{}, err
+	}
+	delivery, err := wu.store.GetWebhookDelivery(c, deliveryID)
+	if err != nil {
+		return dto.WebhookDeliveryResponse{}, notFound(err, ErrDeliveryNotFound)
+	}
+	if delivery.WebhookID != webhook.ID {
+		return dto.WebhookDeliveryResponse{}, ErrDeliveryNotFound
+	}
+	if webhook.DisabledAt.Valid {
+		return dto.WebhookDeliveryResponse{}, ErrWebhookDisabled
+	}
+
+	// the same payload, so receivers can tell it is the same event
+	delivery, err = wu.store.CreateWebhookDelivery(c, db.CreateWebhookDeliveryParams{
+		WebhookID: webhook.ID,
+		EventType: delivery.EventType,
+		Payload:   delivery.Payload,
+	})
+	if db.ErrorCode(err) == db.ForeignKeyViolation {
+		// deleted meanwhile
+		return dto.WebhookDeliveryResponse{}, ErrWebhookNotFound.With(err)
+	}
+	if err != nil {
+		return dto.WebhookDeliveryResponse{}, err
+	}
+	return newWebhookDeliveryResponse(delivery), nil
+}
+
+func (wu *webhookUsecase) Dispatch(c context.Context, typ string, ownerID uint, data interface{}) error {
+	c, span := tracing.Tracer().Start(c, "WebhookUsecase.Dispatch")
+	defer span.End()
+
+	webhooks, err := wu.store.ListEnabledWebhooks(c)
+	if err != nil {
+		return err
+	}
+	var subscribed []db.Webhook
+	for _, webhook := range webhooks {
+		if hasEvent(webhook.Events, typ) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	admins, err := wu.admins(c, subscribed)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	id, err := newEventID()
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(dto.WebhookEvent{
+		ID:        id,
+		Event:     typ,
+		CreatedAt: wu.now().UTC(),
+		Data:      raw,
+	})
+	if err != nil {
+		return err
+	}
+
+	// queued all together: a dispatch failing half way, on a webhook
+	// deleted meanwhile for one, is retried as a whole without delivering
+	// twice
+	return wu.store.ExecTx(c, func(tx db.Store) error {
+		for _, webhook := range subscribed {
+			if !admins[webhook.UserID] && (webhookEvents[typ] || webhook.UserID != ownerID) {
+				continue
+			}
+			_, err := tx.CreateWebhookDelivery(c, db.CreateWebhookDeliveryParams{
+				WebhookID: webhook.ID,
+				EventType: typ,
+				Payload:   payload,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// getWebhook returns the webhook with the given id if it belongs to the
+// user
+func (wu *webhookUsecase) getWebhook(c context.Context, userID uint, id int64) (db.Webhook, error) {
+	webhook, err := wu.store.GetWebhook(c, id)
+	if err != nil {
+		return db.Webhook{}, notFound(err, ErrWebhookNotFound)
+	}
+	if webhook.UserID != userID {
+		return db.Webhook{}, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// checkWebhook validates what a user asks a webhook of theirs to do and
+// returns the events as stored
+func (wu *webhookUsecase) checkWebhook(c context.Context, userID uint, rawURL string, events []string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", NewValidationError("url", "http_url", "url must be an http or https URL")
+	}
+
+	user, err := wu.store.GetUser(c, userID)
+	if err != nil {
+		return "", notFound(err, ErrUserNotFound)
+	}
+	seen := map[string]bool{}
+	var names []string
+	for _, event := range events {
+		adminOnly, ok := webhookEvents[event]
+		if !ok {
+			return "", NewValidationError("events", "unknown_event", "events names an unknown event")
+		}
+		if adminOnly && user.Role != dto.RoleAdmin {
+			return "", NewValidationError("events", "admin_only", "events names an event only admins may subscribe to")
+		}
+		if !seen[event] {
+			seen[event] = true
+			names = append(names, event)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ","), nil
+}
+
+// admins returns which of the owners of webhooks are admins
+func (wu *webhookUsecase) admins(c context.Context, webhooks []db.Webhook) (map[uint]bool, error) {
+	var ids []int64
+	seen := map[uint]bool{}
+	for _, webhook := range webhooks {
+		if !seen[webhook.UserID] {
+			seen[webhook.UserID] = true
+			ids = append(ids, int64(webhook.UserID))
+		}
+	}
+	users, err := wu.store.ListUsersByIDs(c, ids)
+	if err != nil {
+		return nil, err
+	}
+	admins := map[uint]bool{}
+	for _, user := range users {
+		admins[user.ID] = user.Role == dto.RoleAdmin
+	}
+	return admins, nil
+}
+
+func hasEvent(events, typ string) bool {
+	for _, event := range strings.Split(events, ",") {
+		if event == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// newSecret returns a key to sign deliveries with
+func newSecret() (string, error) {
+	return randomHex(32)
+}
+
+func newEventID() (string, error) {
+	return randomHex(16)
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func newWebhookResponse(webhook db.Webhook) dto.WebhookResponse {
+	res := dto.WebhookResponse{
+		ID:           webhook.ID,
+		URL:          webhook.Url,
+		Events:       strings.Split(webhook.Events, ","),
+		Active:       !webhook.DisabledAt.Valid,
+		FailureCount: webhook.FailureCount,
+		CreatedAt:    webhook.CreatedAt,
+	}
+	if webhook.DisabledAt.Valid {
+		res.DisabledAt = &webhook.DisabledAt.Time
+	}
+	return res
+}
+
+func newWebhookDeliveryResponse(delivery db.WebhookDelivery) dto.WebhookDeliveryResponse {
+	res := dto.WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		WebhookID:      delivery.WebhookID,
+		Event:          delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		Payload:        delivery.Payload,
+	}
+	if delivery.Status == DeliveryPending {
+		res.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if delivery.DeliveredAt.Valid {
+		res.DeliveredAt = &delivery.DeliveredAt.Time
+	}
+	return res
+}
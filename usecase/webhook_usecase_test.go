@@ -0,0 +1,310 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/dto"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+	"github.com/stretchr/testify/require"
+)
+
+func createTestUser(t *testing.T, store db.Store, role string) db.User {
+	user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		UserStrID: utils.RandomUserStrID(),
+		Email:     utils.RandomEmail(),
+		Password:  utils.RandomString(60),
+	})
+	require.NoError(t, err)
+	if role != dto.RoleUser {
+		user, err = store.UpdateUserRole(context.Background(), db.UpdateUserRoleParams{ID: user.ID, Role: role})
+		require.NoError(t, err)
+	}
+	return user
+}
+
+func createTestWebhook(t *testing.T, wu IWebhookUsecase, user db.User, events ...string) dto.WebhookResponse {
+	webhook, err := wu.CreateWebhook(context.Background(), dto.CreateWebhookRequest{
+		UserID: user.ID,
+		URL:    "https://example.com/" + utils.RandomString(6),
+		Events: events,
+	})
+	require.NoError(t, err)
+	return webhook
+}
+
+// queued returns the deliveries queued for a webhook, oldest first
+func queued(t *testing.T, store db.Store, webhookID int64) []db.WebhookDelivery {
+	deliveries, err := store.ListWebhookDeliveries(context.Background(), db.ListWebhookDeliveriesParams{
+		WebhookID: webhookID,
+		ID:        1 << 62,
+		Limit:     100,
+	})
+	require.NoError(t, err)
+	for i, j := 0, len(deliveries)-1; i < j; i, j = i+1, j-1 {
+		deliveries[i], deliveries[j] = deliveries[j], deliveries[i]
+	}
+	return deliveries
+}
+
+func requireFieldError(t *testing.T, err error, field, code string) {
+	e, ok := AsError(err)
+	require.True(t, ok, "%v", err)
+	require.Equal(t, KindValidation, e.Kind)
+	require.Len(t, e.Fields, 1)
+	require.Equal(t, field, e.Fields[0].Field)
+	require.Equal(t, code, e.Fields[0].Code)
+}
+
+func TestCreateWebhook(t *testing.T) {
+	store := memory.NewStore()
+	wu := NewWebhookUsecase(store)
+	user := createTestUser(t, store, dto.RoleUser)
+
+	webhook, err := wu.CreateWebhook(context.Background(), dto.CreateWebhookRequest{
+		UserID: user.ID,
+		URL:    "https://example.com/hook",
+		Events: []string{PostUpdated, PostCreated, PostUpdated},
+	})
+	require.NoError(t, err)
+	require.NotZero(t, webhook.ID)
+	require.Equal(t, "https://example.com/hook", webhook.URL)
+	require.Equal(t, []string{PostCreated, PostUpdated}, webhook.Events)
+	require.True(t, webhook.Active)
+	require.Len(t, webhook.Secret, 64)
+
+	// the secret is only shown once
+	got, err := wu.GetWebhook(context.Background(), user.ID, webhook.ID)
+	require.NoError(t, err)
+	require.Empty(t, got.Secret)
+	require.Equal(t, webhook.Events, got.Events)
+
+	list, err := wu.ListWebhooks(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Empty(t, list[0].Secret)
+
+	// other users cannot tell it exists
+	other := createTestUser(t, store, dto.RoleAdmin)
+	_, err = wu.GetWebhook(context.Background(), other.ID, webhook.ID)
+	require.ErrorIs(t, err, ErrWebhookNotFound)
+	require.ErrorIs(t, wu.DeleteWebhook(context.Background(), other.ID, webhook.ID), ErrWebhookNotFound)
+	list, err = wu.ListWebhooks(context.Background(), other.ID)
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	require.NoError(t, wu.DeleteWebhook(context.Background(), user.ID, webhook.ID))
+	_, err = wu.GetWebhook(context.Background(), user.ID, webhook.ID)
+	require.ErrorIs(t, err, ErrWebhookNotFound)
+}
+
+func TestCreateWebhookInvalid(t *testing.T) {
+	store := memory.NewStore()
+	wu := NewWebhookUsecase(store)
+	user := createTestUser(t, store, dto.RoleUser)
+	admin := createTestUser(t, store, dto.RoleAdmin)
+
+	cases := []struct {
+		name   string
+		user   db.User
+		url    string
+		events []string
+		field  string
+		code   string
+	}{
+		{"NotHTTP", user, "ftp://example.com/hook", []string{PostCreated}, "url", "http_url"},
+		{"NoHost", user, "https:///hook", []string{PostCreated}, "url", "http_url"},
+		{"UnknownEvent", user, "https://example.com/hook", []string{"post.liked"}, "events", "unknown_event"},
+		{"AdminOnly", user, "https://example.com/hook", []string{PostCreated, UserSignedUp}, "events", "admin_only"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := wu.CreateWebhook(context.Background(), dto.CreateWebhookRequest{
+				UserID: tc.user.ID,
+				URL:    tc.url,
+				Events: tc.events,
+			})
+			requireFieldError(t, err, tc.field, tc.code)
+		})
+	}
+
+	webhook := createTestWebhook(t, wu, admin, UserSignedUp)
+	require.Equal(t, []string{UserSignedUp}, webhook.Events)
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	store := memory.NewStore()
+	wu := NewWebhookUsecase(store)
+	user := createTestUser(t, store, dto.RoleUser)
+	webhook := createTestWebhook(t, wu, user, PostCreated)
+	req := dto.UpdateWebhookRequest{
+		ID:     webhook.ID,
+		UserID: user.ID,
+		URL:    "http://example.org/hook",
+		Events: []string{PostDeleted},
+	}
+
+	updated, err := wu.UpdateWebhook(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, "http://example.org/hook", updated.URL)
+	require.Equal(t, []string{PostDeleted}, updated.Events)
+	require.True(t, updated.Active)
+
+	inactive := false
+	req.Active = &inactive
+	updated, err = wu.UpdateWebhook(context.Background(), req)
+	require.NoError(t, err)
+	require.False(t, updated.Active)
+	require.NotNil(t, updated.DisabledAt)
+
+	// left out, it stays disabled
+	req.Active = nil
+	updated, err = wu.UpdateWebhook(context.Background(), req)
+	require.NoError(t, err)
+	require.False(t, updated.Active)
+
+	// enabled again with a clean slate
+	for i := 0; i < 3; i++ {
+		_, err = store.RecordWebhookFailure(context.Background(), db.RecordWebhookFailureParams{ID: webhook.ID, DisableAfter: 100})
+		require.NoError(t, err)
+	}
+	active := true
+	req.Active = &active
+	updated, err = wu.UpdateWebhook(context.Background(), req)
+	require.NoError(t, err)
+	require.True(t, updated.Active)
+	require.Nil(t, updated.DisabledAt)
+	require.Zero(t, updated.FailureCount)
+
+	req.UserID = createTestUser(t, store, dto.RoleUser).ID
+	_, err = wu.UpdateWebhook(context.Background(), req)
+	require.ErrorIs(t, err, ErrWebhookNotFound)
+}
+
+func TestDispatch(t *testing.T) {
+	store := memory.NewStore()
+	wu := NewWebhookUsecase(store)
+	owner := createTestUser(t, store, dto.RoleUser)
+	other := createTestUser(t, store, dto.RoleUser)
+	admin := createTestUser(t, store, dto.RoleAdmin)
+
+	ownHook := createTestWebhook(t, wu, owner, PostCreated)
+	otherHook := createTestWebhook(t, wu, other, PostCreated)
+	adminHook := createTestWebhook(t, wu, admin, PostCreated, UserSignedUp)
+	unsubscribed := createTestWebhook(t, wu, owner, PostDeleted)
+	disabled := createTestWebhook(t, wu, admin, PostCreated)
+	inactive := false
+	_, err := wu.UpdateWebhook(context.Background(), dto.UpdateWebhookRequest{
+		ID: disabled.ID, UserID: admin.ID, URL: "https://example.com/", Events: []string{PostCreated}, Active: &inactive,
+	})
+	require.NoError(t, err)
+
+	post := dto.PostResponse{ID: 7, UserID: owner.ID, Text: "hello"}
+	require.NoError(t, wu.Dispatch(context.Background(), PostCreated, owner.ID, post))
+
+	// users hear of their own posts, admins of every post
+	require.Len(t, queued(t, store, ownHook.ID), 1)
+	require.Empty(t, queued(t, store, otherHook.ID))
+	require.Len(t, queued(t, store, adminHook.ID), 1)
+	require.Empty(t, queued(t, store, unsubscribed.ID))
+	require.Empty(t, queued(t, store, disabled.ID))
+
+	// one event, delivered to both
+	delivery := queued(t, store, ownHook.ID)[0]
+	require.Equal(t, PostCreated, delivery.EventType)
+	require.Equal(t, DeliveryPending, delivery.Status)
+	require.JSONEq(t, string(delivery.Payload), string(queued(t, store, adminHook.ID)[0].Payload))
+
+	var event dto.WebhookEvent
+	require.NoError(t, json.Unmarshal(delivery.Payload, &event))
+	require.Len(t, event.ID, 32)
+	require.Equal(t, PostCreated, event.Event)
+	require.NotZero(t, event.CreatedAt)
+	var data dto.PostResponse
+	require.NoError(t, json.Unmarshal(event.Data, &data))
+	require.Equal(t, post, data)
+
+	// only admins hear of sign-ups, even of the user signing up
+	require.NoError(t, wu.Dispatch(context.Background(), UserSignedUp, other.ID, dto.CreateUserResponse{ID: other.ID}))
+	require.Len(t, queued(t, store, adminHook.ID), 2)
+	require.Empty(t, queued(t, store, otherHook.ID))
+}
+
+func TestRedeliver(t *testing.T) {
+	store := memory.NewStore()
+	wu := NewWebhookUsecase(store)
+	user := createTestUser(t, store, dto.RoleUser)
+	webhook := createTestWebhook(t, wu, user, PostCreated)
+	require.NoError(t, wu.Dispatch(context.Background(), PostCreated, user.ID, dto.PostResponse{ID: 1, UserID: user.ID}))
+	original := queued(t, store, webhook.ID)[0]
+	_, err := store.UpdateWebhookDelivery(context.Background(), db.UpdateWebhookDeliveryParams{
+		ID:            original.ID,
+		Status:        DeliveryFailed,
+		Attempts:      8,
+		NextAttemptAt: original.NextAttemptAt,
+	})
+	require.NoError(t, err)
+
+	redelivery, err := wu.Redeliver(context.Background(), user.ID, webhook.ID, original.ID)
+	require.NoError(t, err)
+	require.Greater(t, redelivery.ID, original.ID)
+	require.Equal(t, DeliveryPending, redelivery.Status)
+	require.Zero(t, redelivery.Attempts)
+	require.Equal(t, PostCreated, redelivery.Event)
+	require.JSONEq(t, string(original.Payload), string(redelivery.Payload))
+
+	deliveries, err := wu.ListWebhookDeliveries(context.Background(), dto.ListWebhookDeliveriesRequest{
+		WebhookID: webhook.ID,
+		UserID:    user.ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	require.Equal(t, redelivery.ID, deliveries[0].ID)
+	require.Equal(t, DeliveryFailed, deliveries[1].Status)
+	require.Nil(t, deliveries[1].NextAttemptAt)
+
+	// of another webhook
+	otherHook := createTestWebhook(t, wu, user, PostCreated)
+	_, err = wu.Redeliver(context.Background(), user.ID, otherHook.ID, original.ID)
+	require.ErrorIs(t, err, ErrDeliveryNotFound)
+	_, err = wu.Redeliver(context.Background(), createTestUser(t, store, dto.RoleUser).ID, webhook.ID, original.ID)
+	require.ErrorIs(t, err, ErrWebhookNotFound)
+
+	inactive := false
+	_, err = wu.UpdateWebhook(context.Background(), dto.UpdateWebhookRequest{
+		ID: webhook.ID, UserID: user.ID, URL: "https://example.com/", Events: []string{PostCreated}, Active: &inactive,
+	})
+	require.NoError(t, err)
+	_, err = wu.Redeliver(context.Background(), user.ID, webhook.ID, original.ID)
+	require.ErrorIs(t, err, ErrWebhookDisabled)
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	store := memory.NewStore()
+	wu := NewWebhookUsecase(store)
+	user := createTestUser(t, store, dto.RoleUser)
+	webhook := createTestWebhook(t, wu, user, PostCreated)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, wu.Dispatch(context.Background(), PostCreated, user.ID, dto.PostResponse{ID: uint(i + 1)}))
+	}
+	req := dto.ListWebhookDeliveriesRequest{WebhookID: webhook.ID, UserID: user.ID, Limit: 2}
+
+	page, err := wu.ListWebhookDeliveries(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Greater(t, page[0].ID, page[1].ID)
+
+	req.Before = page[1].ID
+	rest, err := wu.ListWebhookDeliveries(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	require.Less(t, rest[0].ID, page[1].ID)
+
+	req.Limit = 101
+	_, err = wu.ListWebhookDeliveries(context.Background(), req)
+	requireFieldError(t, err, "limit", "maximum")
+}
@@ -17,6 +17,7 @@ func CreateValidToken(userID uint) (string, error) {
 	claims := &dto.JwtCustomClaims{
 		ID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 12)),
 		},
 	}
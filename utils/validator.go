@@ -1,19 +1,46 @@
 package utils
 
 import (
-	"net/http"
+	"reflect"
+	"strings"
+	"sync"
 
-	"github.com/go-playground/validator"
-	"github.com/labstack/echo/v4"
+	"github.com/PenginAction/go-BulletinBoard/i18n"
+	"gopkg.in/go-playground/validator.v9"
 )
 
 type CustomValidator struct {
 	Validator *validator.Validate
 }
 
-func (cv *CustomValidator) Validate(i interface{}) error {
-	if err := cv.Validator.Struct(i); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+// Validator returns the process-wide validator. It reports fields by the name
+// clients send them under (the json or form tag) rather than the Go field
+// name and has messages registered for every language the i18n package
+// supports. The translations can only be registered once, so the instance is
+// shared; validator.Validate is safe for concurrent use.
+var Validator = sync.OnceValue(func() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return f.Name
+	})
+	if err := i18n.RegisterValidator(v); err != nil {
+		// the built-in translations are static, so this is a programming error
+		panic(err)
 	}
-	return nil
+	return v
+})
+
+// Validate returns validator.ValidationErrors when i is invalid, which the
+// HTTP error handler turns into per-field problem details
+func (cv *CustomValidator) Validate(i interface{}) error {
+	return cv.Validator.Struct(i)
 }
@@ -0,0 +1,314 @@
+// Package webhook delivers the events queued for webhooks.
+//
+// Deliveries are rows of the webhook_deliveries table. Any number of
+// workers, on any number of instances, may deliver them: a worker leases
+// the deliveries it claims, and a delivery whose worker died is claimed
+// again once the lease runs out. Receivers may therefore see an event more
+// than once, and should use its id to tell.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/jobs"
+	"github.com/PenginAction/go-BulletinBoard/metrics"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+)
+
+// Headers sent with every delivery
+const (
+	// HeaderID is the id of the delivery, unlike the id of the event in the
+	// body, which its redeliveries share
+	HeaderID    = "X-Webhook-Id"
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderTimestamp is the Unix time the attempt was signed at. Receivers
+	// should reject old ones, so a captured request cannot be replayed.
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// ErrNotPublic is the cause of the failure of a delivery to an address
+// outside of the public internet, unless private networks are allowed
+var ErrNotPublic = errors.New("webhook: address is not public")
+
+// Options tune a Worker. Zero values are replaced by the defaults.
+type Options struct {
+	// Timeout bounds each attempt
+	Timeout time.Duration
+	// AllowPrivateNetworks lets webhooks call loopback, private and
+	// link-local addresses. Without it they cannot be used to reach the
+	// network the server runs in.
+	AllowPrivateNetworks bool
+	// PollInterval is how often due deliveries are looked for
+	PollInterval time.Duration
+	// BatchSize is how many deliveries are attempted at once
+	BatchSize int32
+	// MaxAttempts is how often a delivery is attempted before it fails
+	MaxAttempts int32
+	// Backoff is the delay before the first retry. It doubles with every
+	// further one, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// DisableAfter is how many attempts in a row may fail before the
+	// webhook is disabled
+	DisableAfter int32
+}
+
+func (o Options) withDefaults() Options {
+	if o.Timeout == 0 {
+		o.Timeout = 10 * time.Second
+	}
+	if o.PollInterval == 0 {
+		o.PollInterval = 2 * time.Second
+	}
+	if o.BatchSize == 0 {
+		o.BatchSize = 10
+	}
+	if o.MaxAttempts == 0 {
+		o.MaxAttempts = 8
+	}
+	if o.Backoff == 0 {
+		o.Backoff = 30 * time.Second
+	}
+	if o.MaxBackoff == 0 {
+		o.MaxBackoff = time.Hour
+	}
+	if o.DisableAfter == 0 {
+		o.DisableAfter = 20
+	}
+	return o
+}
+
+// Worker delivers the deliveries that are due
+type Worker struct {
+	store  db.Querier
+	client *http.Client
+	opts   Options
+	now    func() time.Time
+}
+
+// NewWorker returns a worker delivering the deliveries queued in store
+func NewWorker(store db.Querier, opts Options) *Worker {
+	opts = opts.withDefaults()
+
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivateNetworks {
+		// checked on the address dialed rather than the host in the URL,
+		// which may resolve to anything by the time it is used
+		dialer.Control = checkAddress
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			// a proxy would dial on our behalf, past checkAddress
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: opts.Timeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     time.Minute,
+		},
+		// the endpoint is the URL registered, not wherever it points to
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &Worker{store: store, client: client, opts: opts, now: time.Now}
+}
+
+// Run delivers due deliveries until ctx is done, then waits for the
+// attempts in flight
+func (w *Worker) Run(ctx context.Context) error {
+	jobs.Poll(ctx, w.opts.PollInterval, nil, func() bool {
+		n, err := w.DeliverDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			// retried at the next tick
+			slog.ErrorContext(ctx, "cannot claim webhook deliveries", slog.String("error", err.Error()))
+		}
+		return n == int(w.opts.BatchSize)
+	})
+	return nil
+}
+
+// DeliverDue attempts a batch of the deliveries that are due and returns
+// how many it attempted
+func (w *Worker) DeliverDue(ctx context.Context) (int, error) {
+	now := w.now().UTC()
+	deliveries, err := w.store.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+		// long enough for every attempt of the batch to end
+		LeaseUntil: jobs.LeaseUntil(now, 2*w.opts.Timeout),
+		Now:        now,
+		MaxCount:   w.opts.BatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery db.WebhookDelivery) {
+			defer wg.Done()
+			// claimed, so finish the attempt even if asked to stop
+			w.attempt(context.WithoutCancel(ctx), delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+func (w *Worker) attempt(ctx context.Context, delivery db.WebhookDelivery) {
+	webhook, err := w.store.GetWebhook(ctx, delivery.WebhookID)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		// deleted, and the delivery with it
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "cannot load webhook", slog.Int64("webhook_id", delivery.WebhookID), slog.String("error", err.Error()))
+		return
+	}
+
+	arg := db.UpdateWebhookDeliveryParams{
+		ID:             delivery.ID,
+		Status:         usecase.DeliveryFailed,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		ResponseStatus: delivery.ResponseStatus,
+		LastError:      "webhook is disabled",
+	}
+	if webhook.DisabledAt.Valid {
+		// its owner may redeliver once it is enabled again
+		w.update(ctx, arg)
+		return
+	}
+
+	status, err := w.send(ctx, webhook, delivery)
+	now := w.now().UTC()
+	arg.Attempts++
+	arg.ResponseStatus = int32(status)
+	if err == nil {
+		arg.Status = usecase.DeliverySucceeded
+		arg.LastError = ""
+		arg.DeliveredAt = sql.NullTime{Time: now, Valid: true}
+		metrics.WebhookDeliveries.WithLabelValues(metrics.DeliverySucceeded).Inc()
+		w.update(ctx, arg)
+		if _, err := w.store.RecordWebhookSuccess(ctx, webhook.ID); err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+			slog.ErrorContext(ctx, "cannot record webhook success", slog.Int64("webhook_id", webhook.ID), slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	arg.LastError = jobs.ErrorText(err)
+	webhook, err = w.store.RecordWebhookFailure(ctx, db.RecordWebhookFailureParams{
+		DisableAfter: w.opts.DisableAfter,
+		ID:           webhook.ID,
+	})
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		slog.ErrorContext(ctx, "cannot record webhook failure", slog.Int64("webhook_id", delivery.WebhookID), slog.String("error", err.Error()))
+	}
+	if webhook.DisabledAt.Valid {
+		slog.WarnContext(ctx, "webhook disabled after repeated failures", slog.Int64("webhook_id", webhook.ID), slog.Int("failures", int(webhook.FailureCount)))
+	}
+
+	if arg.Attempts >= w.opts.MaxAttempts || webhook.DisabledAt.Valid {
+		metrics.WebhookDeliveries.WithLabelValues(metrics.DeliveryFailed).Inc()
+	} else {
+		arg.Status = usecase.DeliveryPending
+		arg.NextAttemptAt = now.Add(jobs.Backoff(w.opts.Backoff, w.opts.MaxBackoff, arg.Attempts))
+		metrics.WebhookDeliveries.WithLabelValues(metrics.DeliveryRetried).Inc()
+	}
+	w.update(ctx, arg)
+}
+
+func (w *Worker) update(ctx context.Context, arg db.UpdateWebhookDeliveryParams) {
+	_, err := w.store.UpdateWebhookDelivery(ctx, arg)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		// attempted again when the lease runs out
+		slog.ErrorContext(ctx, "cannot update webhook delivery", slog.Int64("id", arg.ID), slog.String("error", err.Error()))
+	}
+}
+
+// send posts the delivery to the webhook and returns the status of the
+// response, if there was one. Only 2xx responses succeed.
+func (w *Worker) send(ctx context.Context, webhook db.Webhook, delivery db.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, w.opts.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(w.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "BulletinBoard-Webhook/1.0")
+	req.Header.Set(HeaderID, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	// read a little, so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("endpoint responded with %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// Sign returns the signature of a delivery: "sha256=" followed by the hex
+// HMAC-SHA256, keyed with the webhook's secret, of the timestamp, a dot and
+// the body. Receivers compute it the same way and compare in constant time.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkAddress refuses connections to addresses outside of the public
+// internet
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublic(ip) {
+		return fmt.Errorf("%w: %s", ErrNotPublic, host)
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func isPublic(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified() &&
+		!sharedAddressSpace.Contains(ip)
+}
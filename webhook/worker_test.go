@@ -0,0 +1,340 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PenginAction/go-BulletinBoard/db/memory"
+	db "github.com/PenginAction/go-BulletinBoard/db/sqlc"
+	"github.com/PenginAction/go-BulletinBoard/usecase"
+	"github.com/PenginAction/go-BulletinBoard/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver is an endpoint recording the requests it gets and answering
+// with the statuses it is given, the last one repeatedly
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		r.mu.Lock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		status := r.statuses[0]
+		if len(r.statuses) > 1 {
+			r.statuses = r.statuses[1:]
+		}
+		r.mu.Unlock()
+
+		if status >= 300 && status < 400 {
+			w.Header().Set("Location", "http://example.com/")
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// setup is a store with a webhook of a new user calling url and a clock
+// workers can be set to
+type setup struct {
+	store   db.Store
+	webhook db.Webhook
+	now     time.Time
+}
+
+func newSetup(t *testing.T, url string) *setup {
+	store := memory.NewStore()
+	user, err := store.CreateUser(context.Background(), db.CreateUserParams{
+		UserStrID: utils.RandomUserStrID(),
+		Email:     utils.RandomEmail(),
+		Password:  utils.RandomString(60),
+	})
+	require.NoError(t, err)
+	webhook, err := store.CreateWebhook(context.Background(), db.CreateWebhookParams{
+		UserID: user.ID,
+		Url:    url,
+		Secret: utils.RandomString(32),
+		Events: usecase.PostCreated,
+	})
+	require.NoError(t, err)
+	return &setup{store: store, webhook: webhook, now: time.Now()}
+}
+
+func (s *setup) worker(opts Options) *Worker {
+	w := NewWorker(s.store, opts)
+	w.now = func() time.Time { return s.now }
+	return w
+}
+
+func (s *setup) queue(t *testing.T) db.WebhookDelivery {
+	delivery, err := s.store.CreateWebhookDelivery(context.Background(), db.CreateWebhookDeliveryParams{
+		WebhookID: s.webhook.ID,
+		EventType: usecase.PostCreated,
+		Payload:   json.RawMessage(`{"id":"abc","event":"post.created","data":{"text":"hi"}}`),
+	})
+	require.NoError(t, err)
+	// the store has a clock of its own
+	if delivery.NextAttemptAt.After(s.now) {
+		s.now = delivery.NextAttemptAt
+	}
+	return delivery
+}
+
+func (s *setup) delivery(t *testing.T, id int64) db.WebhookDelivery {
+	delivery, err := s.store.GetWebhookDelivery(context.Background(), id)
+	require.NoError(t, err)
+	return delivery
+}
+
+func (s *setup) deliverDue(t *testing.T, w *Worker) int {
+	n, err := w.DeliverDue(context.Background())
+	require.NoError(t, err)
+	return n
+}
+
+func TestDeliverySigned(t *testing.T) {
+	r := newReceiver(t, http.StatusNoContent)
+	s := newSetup(t, r.URL)
+	w := s.worker(Options{AllowPrivateNetworks: true})
+	queued := s.queue(t)
+
+	require.Equal(t, 1, s.deliverDue(t, w))
+	require.Equal(t, 1, r.count())
+
+	req, body := r.requests[0], r.bodies[0]
+	require.Equal(t, http.MethodPost, req.Method)
+	require.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	require.JSONEq(t, string(queued.Payload), string(body))
+	require.Equal(t, strconv.FormatInt(queued.ID, 10), req.Header.Get(HeaderID))
+	require.Equal(t, usecase.PostCreated, req.Header.Get(HeaderEvent))
+	timestamp := req.Header.Get(HeaderTimestamp)
+	require.Equal(t, strconv.FormatInt(s.now.Unix(), 10), timestamp)
+	require.Equal(t, Sign(s.webhook.Secret, timestamp, body), req.Header.Get(HeaderSignature))
+	require.NotEqual(t, Sign("other secret", timestamp, body), req.Header.Get(HeaderSignature))
+
+	delivery := s.delivery(t, queued.ID)
+	require.Equal(t, usecase.DeliverySucceeded, delivery.Status)
+	require.EqualValues(t, 1, delivery.Attempts)
+	require.EqualValues(t, http.StatusNoContent, delivery.ResponseStatus)
+	require.Empty(t, delivery.LastError)
+	require.True(t, delivery.DeliveredAt.Valid)
+
+	// delivered once
+	require.Zero(t, s.deliverDue(t, w))
+	require.Equal(t, 1, r.count())
+}
+
+func TestSign(t *testing.T) {
+	// what a receiver computes
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(`1700000000.{"id":"abc"}`))
+	require.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), Sign("secret", "1700000000", []byte(`{"id":"abc"}`)))
+
+	require.NotEqual(t, Sign("secret", "1", []byte("body")), Sign("secret", "2", []byte("body")))
+	require.NotEqual(t, Sign("secret", "1", []byte("body")), Sign("other", "1", []byte("body")))
+}
+
+func TestDeliveryRetriedWithBackoff(t *testing.T) {
+	r := newReceiver(t, http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusOK)
+	s := newSetup(t, r.URL)
+	w := s.worker(Options{AllowPrivateNetworks: true, Backoff: time.Minute})
+	queued := s.queue(t)
+
+	require.Equal(t, 1, s.deliverDue(t, w))
+	delivery := s.delivery(t, queued.ID)
+	require.Equal(t, usecase.DeliveryPending, delivery.Status)
+	require.EqualValues(t, 1, delivery.Attempts)
+	require.EqualValues(t, http.StatusInternalServerError, delivery.ResponseStatus)
+	require.Contains(t, delivery.LastError, "500")
+	require.WithinDuration(t, s.now.Add(time.Minute), delivery.NextAttemptAt, time.Millisecond)
+
+	// not due yet
+	require.Zero(t, s.deliverDue(t, w))
+
+	s.now = s.now.Add(time.Minute)
+	require.Equal(t, 1, s.deliverDue(t, w))
+	delivery = s.delivery(t, queued.ID)
+	require.EqualValues(t, 2, delivery.Attempts)
+	// twice as long
+	require.WithinDuration(t, s.now.Add(2*time.Minute), delivery.NextAttemptAt, time.Millisecond)
+
+	webhook, err := s.store.GetWebhook(context.Background(), s.webhook.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, webhook.FailureCount)
+
+	s.now = s.now.Add(2 * time.Minute)
+	require.Equal(t, 1, s.deliverDue(t, w))
+	delivery = s.delivery(t, queued.ID)
+	require.Equal(t, usecase.DeliverySucceeded, delivery.Status)
+	require.EqualValues(t, 3, delivery.Attempts)
+	require.Equal(t, 3, r.count())
+
+	// a success makes up for the failures
+	webhook, err = s.store.GetWebhook(context.Background(), s.webhook.ID)
+	require.NoError(t, err)
+	require.Zero(t, webhook.FailureCount)
+}
+
+func TestDeliveryGivesUp(t *testing.T) {
+	r := newReceiver(t, http.StatusBadRequest)
+	s := newSetup(t, r.URL)
+	w := s.worker(Options{AllowPrivateNetworks: true, MaxAttempts: 3, Backoff: time.Second})
+	queued := s.queue(t)
+
+	for i := 0; i < 3; i++ {
+		require.Equal(t, 1, s.deliverDue(t, w))
+		s.now = s.now.Add(time.Hour)
+	}
+	delivery := s.delivery(t, queued.ID)
+	require.Equal(t, usecase.DeliveryFailed, delivery.Status)
+	require.EqualValues(t, 3, delivery.Attempts)
+	require.False(t, delivery.DeliveredAt.Valid)
+
+	require.Zero(t, s.deliverDue(t, w))
+	require.Equal(t, 3, r.count())
+}
+
+func TestWebhookDisabledAfterFailures(t *testing.T) {
+	r := newReceiver(t, http.StatusGone)
+	s := newSetup(t, r.URL)
+	w := s.worker(Options{AllowPrivateNetworks: true, DisableAfter: 2, BatchSize: 1})
+	first := s.queue(t)
+	second := s.queue(t)
+	third := s.queue(t)
+
+	require.Equal(t, 1, s.deliverDue(t, w))
+	require.Equal(t, 1, s.deliverDue(t, w))
+
+	webhook, err := s.store.GetWebhook(context.Background(), s.webhook.ID)
+	require.NoError(t, err)
+	require.True(t, webhook.DisabledAt.Valid)
+	require.EqualValues(t, 2, webhook.FailureCount)
+	require.Equal(t, usecase.DeliveryPending, s.delivery(t, first.ID).Status)
+	require.Equal(t, usecase.DeliveryFailed, s.delivery(t, second.ID).Status)
+
+	// the rest fail without being sent
+	s.now = s.now.Add(time.Hour)
+	require.Equal(t, 1, s.deliverDue(t, w))
+	require.Equal(t, 1, s.deliverDue(t, w))
+	require.Equal(t, 2, r.count())
+	for _, queued := range []db.WebhookDelivery{first, third} {
+		delivery := s.delivery(t, queued.ID)
+		require.Equal(t, usecase.DeliveryFailed, delivery.Status)
+		require.Equal(t, "webhook is disabled", delivery.LastError)
+	}
+}
+
+func TestPrivateNetworksBlocked(t *testing.T) {
+	r := newReceiver(t, http.StatusOK)
+	s := newSetup(t, r.URL)
+	w := s.worker(Options{})
+	queued := s.queue(t)
+
+	require.Equal(t, 1, s.deliverDue(t, w))
+	require.Zero(t, r.count())
+
+	delivery := s.delivery(t, queued.ID)
+	require.Equal(t, usecase.DeliveryPending, delivery.Status)
+	require.Zero(t, delivery.ResponseStatus)
+	require.Contains(t, delivery.LastError, ErrNotPublic.Error())
+}
+
+func TestRedirectsNotFollowed(t *testing.T) {
+	target := newReceiver(t, http.StatusOK)
+	r := newReceiver(t, http.StatusFound)
+	r.Config.Handler = http.RedirectHandler(target.URL, http.StatusFound)
+	s := newSetup(t, r.URL)
+	w := s.worker(Options{AllowPrivateNetworks: true})
+	queued := s.queue(t)
+
+	require.Equal(t, 1, s.deliverDue(t, w))
+	require.Zero(t, target.count())
+
+	delivery := s.delivery(t, queued.ID)
+	require.Equal(t, usecase.DeliveryPending, delivery.Status)
+	require.EqualValues(t, http.StatusFound, delivery.ResponseStatus)
+}
+
+func TestDeliveryTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	r := newReceiver(t, http.StatusOK)
+	r.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-release
+	})
+	// closed before the server, which waits for its handlers
+	defer close(release)
+	s := newSetup(t, r.URL)
+	w := s.worker(Options{AllowPrivateNetworks: true, Timeout: 50 * time.Millisecond})
+	queued := s.queue(t)
+
+	require.Equal(t, 1, s.deliverDue(t, w))
+	delivery := s.delivery(t, queued.ID)
+	require.Equal(t, usecase.DeliveryPending, delivery.Status)
+	require.Contains(t, delivery.LastError, "deadline exceeded")
+}
+
+func TestIsPublic(t *testing.T) {
+	for host, public := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fc00::1":         false,
+		"0.0.0.0":         false,
+		"100.64.0.1":      false,
+		"224.0.0.1":       false,
+	} {
+		t.Run(host, func(t *testing.T) {
+			require.Equal(t, public, isPublic(net.ParseIP(host)))
+		})
+	}
+}
+
+func TestRunStops(t *testing.T) {
+	s := newSetup(t, "http://example.com/")
+	w := s.worker(Options{PollInterval: time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return")
+	}
+}